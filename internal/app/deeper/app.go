@@ -18,6 +18,7 @@ import (
 	"github.com/smirnoffmg/deeper/internal/pkg/database"
 	"github.com/smirnoffmg/deeper/internal/pkg/http"
 	"github.com/smirnoffmg/deeper/internal/pkg/metrics"
+	"github.com/smirnoffmg/deeper/internal/pkg/seal"
 )
 
 // App represents the main application with all its components
@@ -102,7 +103,11 @@ func provideDatabase(cfg *config.Config) (*database.Database, error) {
 	}
 
 	dbPath := filepath.Join(homeDir, ".deeper", "deeper.db")
-	return database.NewDatabase(dbPath)
+	secret, err := seal.Load(cfg.DBPassphrase, cfg.DBKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database key: %w", err)
+	}
+	return database.Open(dbPath, secret)
 }
 
 // provideRepository provides a database repository
@@ -0,0 +1,214 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/audit"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+)
+
+var (
+	auditFormat string
+	auditOut    string
+)
+
+var (
+	auditCmd = &cobra.Command{
+		Use:   "audit",
+		Short: "Export and verify the audit log of a scan's outbound contacts",
+		Long: `Every scan keeps an audit log of each outbound HTTP request, DNS lookup
+and WHOIS query its plugins make: when, which plugin, the destination
+host, method and URL with credentials redacted, the status and the bytes
+sent and received.
+
+Entries are hash-chained onto the scan's input, --operator and
+--engagement, so editing, removing or reordering an entry, or changing
+whose authority the scan ran under, is detected by "deeper audit verify"
+and flagged in every export.
+
+Examples:
+  deeper scan jdoe@acme.com --operator jsmith --engagement ENG-114
+  deeper audit export 42 -o scan42-audit.json
+  deeper audit export 42 --format csv > scan42-audit.csv
+  deeper audit verify 42 43`,
+	}
+
+	auditExportCmd = &cobra.Command{
+		Use:   "export <scan-id>",
+		Short: "Export a scan's audit log as JSON or CSV",
+		Long: `Export writes a scan's audit log. JSON carries the scan, with its
+operator and engagement reference, the genesis and head hashes of the
+chain and whether it verified; CSV carries the entries only. A log that
+fails verification is still exported, and the command then fails.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scanID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid scan id %q: %w", args[0], err)
+			}
+			return withRepo(func(repo *database.Repository) error {
+				return runAuditExport(repo, scanID, auditFormat, auditOut)
+			})
+		},
+	}
+
+	auditVerifyCmd = &cobra.Command{
+		Use:   "verify <scan-id>...",
+		Short: "Check that scans' audit logs are intact",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scanIDs, err := parseScanIDs(args)
+			if err != nil {
+				return err
+			}
+			return withRepo(func(repo *database.Repository) error {
+				return runAuditVerify(os.Stdout, repo, scanIDs)
+			})
+		},
+	}
+)
+
+func init() {
+	auditExportCmd.Flags().StringVar(&auditFormat, "format", "json", "export format (json, csv)")
+	auditExportCmd.Flags().StringVarP(&auditOut, "out", "o", "", "write to this file instead of stdout")
+	auditCmd.AddCommand(auditExportCmd)
+	auditCmd.AddCommand(auditVerifyCmd)
+}
+
+// auditExport is the JSON export of a scan's audit log.
+type auditExport struct {
+	Scan     database.ScanSession `json:"scan"`
+	Genesis  string               `json:"genesis"`
+	Head     string               `json:"head"`
+	Verified bool                 `json:"verified"`
+	Problem  string               `json:"problem,omitempty"`
+	Entries  []audit.Entry        `json:"entries"`
+}
+
+// loadAuditLog returns a scan's audit log and verifies its chain. A
+// broken chain is reported in the export, not as an error.
+func loadAuditLog(repo *database.Repository, scanID int64) (*auditExport, error) {
+	session, err := repo.GetScanSession(scanID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, fmt.Errorf("scan %d not found", scanID)
+	}
+	entries, err := repo.GetAuditLog(scanID)
+	if err != nil {
+		return nil, err
+	}
+
+	auditLog := &auditExport{Scan: *session, Genesis: session.AuditGenesis(), Entries: entries}
+	auditLog.Head = auditLog.Genesis
+	if len(entries) > 0 {
+		auditLog.Head = entries[len(entries)-1].Hash
+	}
+	if err := session.VerifyAuditLog(entries); err != nil {
+		auditLog.Problem = err.Error()
+	} else {
+		auditLog.Verified = true
+	}
+	return auditLog, nil
+}
+
+func runAuditExport(repo *database.Repository, scanID int64, format, outPath string) error {
+	if format != "json" && format != "csv" {
+		return fmt.Errorf("unsupported audit export format: %s", format)
+	}
+	auditLog, err := loadAuditLog(repo, scanID)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if outPath != "" {
+		f, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", outPath, err)
+		}
+		defer func() { _ = f.Close() }()
+		w = f
+	}
+	if format == "csv" {
+		err = writeAuditCSV(w, auditLog.Entries)
+	} else {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(auditLog)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	if outPath != "" {
+		log.Info().Msgf("Exported %d audit entries of scan %d to %s", len(auditLog.Entries), scanID, outPath)
+	}
+
+	if !auditLog.Verified {
+		return fmt.Errorf("audit log of scan %d failed verification: %s", scanID, auditLog.Problem)
+	}
+	return nil
+}
+
+func writeAuditCSV(w io.Writer, entries []audit.Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{
+		"scan_id", "seq", "time", "plugin", "kind", "host", "method", "url", "status",
+		"bytes_sent", "bytes_received", "error", "prev_hash", "hash",
+	}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := cw.Write([]string{
+			strconv.FormatInt(e.ScanID, 10), strconv.FormatInt(e.Seq, 10), e.Time.Format(audit.TimeFormat),
+			e.Plugin, string(e.Kind), e.Host, e.Method, e.URL, e.Status,
+			strconv.FormatInt(e.BytesSent, 10), strconv.FormatInt(e.BytesReceived, 10), e.Error, e.PrevHash, e.Hash,
+		}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func runAuditVerify(w io.Writer, repo *database.Repository, scanIDs []int64) error {
+	failed := 0
+	for _, scanID := range scanIDs {
+		auditLog, err := loadAuditLog(repo, scanID)
+		if err != nil {
+			return err
+		}
+		if !auditLog.Verified {
+			failed++
+			_, _ = fmt.Fprintf(w, "Scan %d: TAMPERED: %s\n", scanID, auditLog.Problem)
+			continue
+		}
+		_, _ = fmt.Fprintf(w, "Scan %d: %d entries, chain intact, head %s\n", scanID, len(auditLog.Entries), auditLog.Head)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d audit logs failed verification", failed, len(scanIDs))
+	}
+	return nil
+}
+
+// scanOperator is who scans run on behalf of: --operator, or the user
+// running deeper.
+func scanOperator() string {
+	if operator != "" {
+		return operator
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
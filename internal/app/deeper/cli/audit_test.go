@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/audit"
+)
+
+func TestAuditCommands_ExportAndVerify(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+
+	session, err := repo.CreateScanSessionFor("jdoe@acme.com", "jsmith", "ENG-114")
+	require.NoError(t, err)
+	require.NoError(t, repo.AppendAuditEntry(&audit.Entry{
+		ScanID: session.ID, Time: time.Now(), Plugin: "GravatarPlugin", Kind: audit.KindHTTP,
+		Host: "gravatar.com", Method: "GET", URL: "https://gravatar.com/x.json", Status: "200",
+	}))
+
+	path := filepath.Join(t.TempDir(), "audit.json")
+	require.NoError(t, runAuditExport(repo, session.ID, "json", path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var exported auditExport
+	require.NoError(t, json.Unmarshal(data, &exported))
+	assert.True(t, exported.Verified)
+	assert.Equal(t, "ENG-114", exported.Scan.Engagement)
+	require.Len(t, exported.Entries, 1)
+	assert.Equal(t, exported.Entries[0].Hash, exported.Head)
+
+	csvPath := filepath.Join(t.TempDir(), "audit.csv")
+	require.NoError(t, runAuditExport(repo, session.ID, "csv", csvPath))
+	data, err = os.ReadFile(csvPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "gravatar.com,GET,https://gravatar.com/x.json,200")
+	assert.Error(t, runAuditExport(repo, session.ID, "xml", ""))
+
+	var buf bytes.Buffer
+	require.NoError(t, runAuditVerify(&buf, repo, []int64{session.ID}))
+	assert.Contains(t, buf.String(), "1 entries, chain intact")
+
+	// Re-recording the scan under another engagement breaks the chain.
+	db, err := createDatabase()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	_, err = db.GetDB().Exec(`UPDATE scan_sessions SET engagement = 'ENG-999' WHERE id = ?`, session.ID)
+	require.NoError(t, err)
+	buf.Reset()
+	assert.Error(t, runAuditVerify(&buf, repo, []int64{session.ID}))
+	assert.Contains(t, buf.String(), "TAMPERED")
+	assert.Error(t, runAuditExport(repo, session.ID, "json", filepath.Join(t.TempDir(), "tampered.json")))
+}
@@ -0,0 +1,331 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+)
+
+var (
+	caseOwner         string
+	caseAuthorization string
+	caseTags          []string
+	caseSeeds         []string
+	caseNote          string
+)
+
+var (
+	caseCmd = &cobra.Command{
+		Use:   "case",
+		Short: "Group scans, seeds, notes and tags into an investigation",
+		Long: `A case is one investigation: the scans and seeds that belong to it, free
+text notes, tags, the analyst who owns it and what authorizes it, such as
+a ticket or engagement reference.
+
+"deeper scan --case <name>" files a scan under a case as it runs, and
+"deeper export --case" and "deeper report --case" work on every scan of a
+case as one unit. A case is named by name or ID.
+
+Examples:
+  deeper case create acme-2026 --owner jsmith --authorization ENG-114 --tag phishing
+  deeper case add acme-2026 41 42 --seed jdoe@acme.com --note "MX is on a bulletproof host"
+  deeper case list
+  deeper case show acme-2026 --output json
+  deeper case close acme-2026 --note "handed over to legal"`,
+	}
+
+	caseCreateCmd = &cobra.Command{
+		Use:   "create <name>",
+		Short: "Open a new case",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withRepo(func(repo *database.Repository) error {
+				return runCaseCreate(repo, args[0])
+			})
+		},
+	}
+
+	caseAddCmd = &cobra.Command{
+		Use:   "add <case> [scan-id...]",
+		Short: "Add scans, seeds, notes or tags to a case",
+		Long: `Add files scans under a case, along with their inputs as seeds. --seed
+records inputs still to scan, --note adds a note and --tag tags the case.
+A closed case takes notes and tags but no more scans or seeds.`,
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scanIDs, err := parseScanIDs(args[1:])
+			if err != nil {
+				return err
+			}
+			if len(scanIDs) == 0 && len(caseSeeds) == 0 && len(caseTags) == 0 && caseNote == "" {
+				return fmt.Errorf("nothing to add: pass scan ids, --seed, --tag or --note")
+			}
+			return withRepo(func(repo *database.Repository) error {
+				return runCaseAdd(repo, args[0], scanIDs)
+			})
+		},
+	}
+
+	caseListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List cases",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withRepo(func(repo *database.Repository) error {
+				cases, err := repo.GetCases()
+				if err != nil {
+					return err
+				}
+				return writeCases(os.Stdout, cases, output)
+			})
+		},
+	}
+
+	caseShowCmd = &cobra.Command{
+		Use:   "show <case>",
+		Short: "Show a case with its scans, seeds and notes",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withRepo(func(repo *database.Repository) error {
+				c, err := findCase(repo, args[0])
+				if err != nil {
+					return err
+				}
+				return showCase(os.Stdout, repo, c, output)
+			})
+		},
+	}
+
+	caseCloseCmd = &cobra.Command{
+		Use:   "close <case>",
+		Short: "Close a case",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withRepo(func(repo *database.Repository) error {
+				c, err := findCase(repo, args[0])
+				if err != nil {
+					return err
+				}
+				if caseNote != "" {
+					if _, err := repo.AddCaseNote(c.ID, caseNote); err != nil {
+						return err
+					}
+				}
+				if err := repo.CloseCase(c.ID); err != nil {
+					return err
+				}
+				log.Info().Msgf("Closed case %s", c.Name)
+				return nil
+			})
+		},
+	}
+)
+
+func init() {
+	caseCreateCmd.Flags().StringVar(&caseOwner, "owner", "", "analyst who owns the case")
+	caseCreateCmd.Flags().StringVar(&caseAuthorization, "authorization", "", "what authorizes the investigation, e.g. a ticket or engagement reference")
+	caseCreateCmd.Flags().StringSliceVar(&caseTags, "tag", nil, "tag the case (repeatable)")
+	caseCreateCmd.Flags().StringSliceVar(&caseSeeds, "seed", nil, "input the investigation starts from (repeatable)")
+	caseCreateCmd.Flags().StringVar(&caseNote, "note", "", "add a note")
+
+	caseAddCmd.Flags().StringSliceVar(&caseTags, "tag", nil, "tag the case (repeatable)")
+	caseAddCmd.Flags().StringSliceVar(&caseSeeds, "seed", nil, "input the investigation starts from (repeatable)")
+	caseAddCmd.Flags().StringVar(&caseNote, "note", "", "add a note")
+
+	caseCloseCmd.Flags().StringVar(&caseNote, "note", "", "add a closing note")
+
+	caseCmd.AddCommand(caseCreateCmd)
+	caseCmd.AddCommand(caseAddCmd)
+	caseCmd.AddCommand(caseListCmd)
+	caseCmd.AddCommand(caseShowCmd)
+	caseCmd.AddCommand(caseCloseCmd)
+}
+
+func withRepo(fn func(repo *database.Repository) error) (err error) {
+	db, err := createDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() { err = errors.Join(err, db.Close()) }()
+	return fn(database.NewRepository(db))
+}
+
+// findCase resolves a case by name, or by ID when no case has that name.
+func findCase(repo *database.Repository, ref string) (*database.Case, error) {
+	c, err := repo.GetCaseByName(ref)
+	if err != nil {
+		return nil, err
+	}
+	if c == nil {
+		if id, parseErr := strconv.ParseInt(strings.TrimPrefix(ref, "#"), 10, 64); parseErr == nil {
+			if c, err = repo.GetCase(id); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if c == nil {
+		return nil, fmt.Errorf("case %q not found", ref)
+	}
+	return c, nil
+}
+
+// caseScanIDs returns the scans of the named case, for commands that
+// accept --case in place of scan ids.
+func caseScanIDs(repo *database.Repository, ref string) ([]int64, error) {
+	c, err := findCase(repo, ref)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.ScanIDs) == 0 {
+		return nil, fmt.Errorf("case %q has no scans", c.Name)
+	}
+	return c.ScanIDs, nil
+}
+
+func runCaseCreate(repo *database.Repository, name string) error {
+	c := &database.Case{Name: name, Owner: caseOwner, Authorization: caseAuthorization, Tags: caseTags, Seeds: caseSeeds}
+	if err := repo.CreateCase(c); err != nil {
+		return err
+	}
+	if caseNote != "" {
+		if _, err := repo.AddCaseNote(c.ID, caseNote); err != nil {
+			return err
+		}
+	}
+	log.Info().Msgf("Created case %s (#%d)", c.Name, c.ID)
+	return nil
+}
+
+func runCaseAdd(repo *database.Repository, ref string, scanIDs []int64) error {
+	c, err := findCase(repo, ref)
+	if err != nil {
+		return err
+	}
+	if len(scanIDs) > 0 {
+		if err := repo.AddCaseScans(c.ID, scanIDs...); err != nil {
+			return err
+		}
+	}
+	if len(caseSeeds) > 0 {
+		if err := repo.AddCaseSeeds(c.ID, caseSeeds...); err != nil {
+			return err
+		}
+	}
+	if len(caseTags) > 0 {
+		if err := repo.AddCaseTags(c.ID, caseTags...); err != nil {
+			return err
+		}
+	}
+	if caseNote != "" {
+		if _, err := repo.AddCaseNote(c.ID, caseNote); err != nil {
+			return err
+		}
+	}
+	log.Info().Msgf("Updated case %s", c.Name)
+	return nil
+}
+
+func writeCases(w io.Writer, cases []database.Case, format string) error {
+	switch format {
+	case "json":
+		if cases == nil {
+			cases = []database.Case{}
+		}
+		return writeGraphJSON(w, cases)
+	case "table":
+		if len(cases) == 0 {
+			_, _ = fmt.Fprintln(w, "No cases")
+			return nil
+		}
+		table := newGraphTable(w, []string{"ID", "Name", "Status", "Owner", "Scans", "Seeds", "Tags", "Created"})
+		for _, c := range cases {
+			table.Append([]string{
+				strconv.FormatInt(c.ID, 10),
+				c.Name,
+				c.Status,
+				c.Owner,
+				strconv.Itoa(len(c.ScanIDs)),
+				strconv.Itoa(len(c.Seeds)),
+				strings.Join(c.Tags, ", "),
+				c.CreatedAt.Format("2006-01-02"),
+			})
+		}
+		table.Render()
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for case list: %s", format)
+	}
+}
+
+func showCase(w io.Writer, repo *database.Repository, c *database.Case, format string) error {
+	scans := make([]database.ScanSession, 0, len(c.ScanIDs))
+	for _, id := range c.ScanIDs {
+		session, err := repo.GetScanSession(id)
+		if err != nil {
+			return err
+		}
+		if session != nil {
+			scans = append(scans, *session)
+		}
+	}
+
+	switch format {
+	case "json":
+		return writeGraphJSON(w, struct {
+			*database.Case
+			Scans []database.ScanSession `json:"scans"`
+		}{c, scans})
+	case "table":
+		_, _ = fmt.Fprintf(w, "Case #%d %s (%s), created %s\n", c.ID, c.Name, c.Status, c.CreatedAt.Format("2006-01-02"))
+		if c.ClosedAt != nil {
+			_, _ = fmt.Fprintf(w, "Closed: %s\n", c.ClosedAt.Format("2006-01-02"))
+		}
+		_, _ = fmt.Fprintf(w, "Owner: %s\nAuthorization: %s\n", orNone(c.Owner), orNone(c.Authorization))
+		if len(c.Tags) > 0 {
+			_, _ = fmt.Fprintf(w, "Tags: %s\n", strings.Join(c.Tags, ", "))
+		}
+		if len(c.Seeds) > 0 {
+			_, _ = fmt.Fprintf(w, "Seeds: %s\n", strings.Join(c.Seeds, ", "))
+		}
+
+		if len(scans) > 0 {
+			_, _ = fmt.Fprintln(w)
+			table := newGraphTable(w, []string{"Scan", "Input", "Started", "Status", "Traces"})
+			for _, s := range scans {
+				table.Append([]string{
+					strconv.FormatInt(s.ID, 10),
+					s.Input,
+					s.StartedAt.Format("2006-01-02 15:04"),
+					s.Status,
+					strconv.Itoa(s.UniqueTraces),
+				})
+			}
+			table.Render()
+		}
+
+		if len(c.Notes) > 0 {
+			_, _ = fmt.Fprintln(w, "\nNotes:")
+			for _, note := range c.Notes {
+				_, _ = fmt.Fprintf(w, "  %s  %s\n", note.CreatedAt.Format("2006-01-02 15:04"), note.Text)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for case show: %s", format)
+	}
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+)
+
+func TestCaseCommands_CreateAddShow(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+
+	caseOwner, caseAuthorization, caseTags, caseSeeds, caseNote = "jsmith", "ENG-114", []string{"phishing"}, nil, "opened on request of legal"
+	t.Cleanup(func() { caseOwner, caseAuthorization, caseTags, caseSeeds, caseNote = "", "", nil, nil, "" })
+	require.NoError(t, runCaseCreate(repo, "acme-2026"))
+	assert.Error(t, runCaseCreate(repo, "acme-2026"))
+
+	scan, err := repo.CreateScanSession("jdoe@acme.com")
+	require.NoError(t, err)
+	caseTags, caseSeeds, caseNote = nil, []string{"acme.com"}, ""
+	require.NoError(t, runCaseAdd(repo, "acme-2026", []int64{scan.ID}))
+
+	byName, err := findCase(repo, "acme-2026")
+	require.NoError(t, err)
+	byID, err := findCase(repo, "#1")
+	require.NoError(t, err)
+	assert.Equal(t, byName.ID, byID.ID)
+	_, err = findCase(repo, "nope")
+	assert.Error(t, err)
+
+	ids, err := caseScanIDs(repo, "acme-2026")
+	require.NoError(t, err)
+	assert.Equal(t, []int64{scan.ID}, ids)
+
+	var buf bytes.Buffer
+	require.NoError(t, showCase(&buf, repo, byName, "table"))
+	out := buf.String()
+	assert.Contains(t, out, "Case #1 acme-2026 (open)")
+	assert.Contains(t, out, "Authorization: ENG-114")
+	assert.Contains(t, out, "Seeds: jdoe@acme.com, acme.com")
+	assert.Contains(t, out, "opened on request of legal")
+
+	buf.Reset()
+	cases, err := repo.GetCases()
+	require.NoError(t, err)
+	require.NoError(t, writeCases(&buf, cases, "json"))
+	assert.Contains(t, buf.String(), `"authorization": "ENG-114"`)
+
+	require.NoError(t, repo.CloseCase(byName.ID))
+	assert.ErrorContains(t, runCaseAdd(repo, "acme-2026", []int64{scan.ID}), "closed")
+
+	empty := &database.Case{Name: "empty"}
+	require.NoError(t, repo.CreateCase(empty))
+	_, err = caseScanIDs(repo, "empty")
+	assert.ErrorContains(t, err, "no scans")
+}
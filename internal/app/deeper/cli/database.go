@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,14 +14,18 @@ import (
 
 var (
 	databaseCmd = &cobra.Command{
-		Use:   "database",
-		Short: "Manage database operations",
-		Long: `Manage database operations including statistics, cleanup, and maintenance.
+		Use:     "database",
+		Aliases: []string{"db"},
+		Short:   "Manage database operations",
+		Long: `Manage database operations including statistics, cleanup, retention,
+erasure and maintenance.
 
 Examples:
   deeper database stats
   deeper database cleanup
-  deeper database info`,
+  deeper database info
+  deeper db retention
+  deeper db erase --trace jdoe@acme.com --reference DSAR-2031`,
 	}
 
 	databaseStatsCmd = &cobra.Command{
@@ -51,13 +56,13 @@ func init() {
 	databaseCmd.AddCommand(databaseInfoCmd)
 }
 
-func runDatabaseStats(cmd *cobra.Command, args []string) error {
+func runDatabaseStats(cmd *cobra.Command, args []string) (err error) {
 	// Create database connection
 	db, err := createDatabase()
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
-	defer func() { _ = db.Close() }()
+	defer func() { err = errors.Join(err, db.Close()) }()
 
 	// Get database statistics
 	stats, err := db.Stats()
@@ -79,13 +84,13 @@ func runDatabaseStats(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func runDatabaseCleanup(cmd *cobra.Command, args []string) error {
+func runDatabaseCleanup(cmd *cobra.Command, args []string) (err error) {
 	// Create database connection
 	db, err := createDatabase()
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
-	defer func() { _ = db.Close() }()
+	defer func() { err = errors.Join(err, db.Close()) }()
 
 	// Create repository and cache
 	repo := database.NewRepository(db)
@@ -100,13 +105,13 @@ func runDatabaseCleanup(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
-func runDatabaseInfo(cmd *cobra.Command, args []string) error {
+func runDatabaseInfo(cmd *cobra.Command, args []string) (err error) {
 	// Create database connection
 	db, err := createDatabase()
 	if err != nil {
 		return fmt.Errorf("failed to connect to database: %w", err)
 	}
-	defer func() { _ = db.Close() }()
+	defer func() { err = errors.Join(err, db.Close()) }()
 
 	// Get database path
 	dbPath := db.GetPath()
@@ -123,22 +128,47 @@ func runDatabaseInfo(cmd *cobra.Command, args []string) error {
 	fmt.Printf("Size: %s\n", formatBytes(fileInfo.Size()))
 	fmt.Printf("Created: %s\n", fileInfo.ModTime().Format(time.RFC3339))
 	fmt.Printf("Permissions: %s\n", fileInfo.Mode().String())
+	if db.Encrypted() {
+		fmt.Println("Encryption: on")
+	} else {
+		fmt.Println("Encryption: off")
+	}
 
 	return nil
 }
 
+// closeEngineDatabase closes the database opened by createEngine, sealing
+// it if it is encrypted; Execute calls it once the command has finished
+// and fails the command if the database could not be saved.
+var closeEngineDatabase = func() error { return nil }
+
 func createDatabase() (*database.Database, error) {
-	// Get home directory
-	homeDir, err := os.UserHomeDir()
+	dbPath, err := databasePath()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+		return nil, err
+	}
+	secret, err := databaseSecret()
+	if err != nil {
+		return nil, err
 	}
-
-	// Create database path
-	dbPath := filepath.Join(homeDir, ".deeper", "deeper.db")
 
 	// Create database connection
-	return database.NewDatabase(dbPath)
+	db, err := database.Open(dbPath, secret)
+	if errors.Is(err, database.ErrEncrypted) {
+		return nil, fmt.Errorf("%w: set DEEPER_DB_PASSPHRASE or DEEPER_DB_KEY_FILE", err)
+	}
+	if errors.Is(err, database.ErrNotEncrypted) {
+		return nil, fmt.Errorf("%w: run \"deeper db rekey\" to encrypt it", err)
+	}
+	return db, err
+}
+
+func databasePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".deeper", "deeper.db"), nil
 }
 
 func formatBytes(bytes int64) string {
@@ -0,0 +1,177 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/graphreport"
+	"github.com/smirnoffmg/deeper/internal/app/deeper/scandiff"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+)
+
+var (
+	diffReport bool
+	diffNoOpen bool
+)
+
+// diffCmd compares two scans
+var diffCmd = &cobra.Command{
+	Use:   "diff <old-scan> <new-scan>",
+	Short: "Show what changed between two scans",
+	Long: `Diff compares two scans, usually of the same subject at different times,
+and lists the traces and edges that appeared or disappeared: traces grouped
+by type, edges by the plugin that produced them. --output json prints the
+whole diff for scripts and alerting.
+
+--report renders the newer scan's graph report in diff mode, with added,
+removed and unchanged traces in different colours.
+
+"deeper scan --diff-against last" runs the same comparison right after a
+scan, against the previous scan of the same input.
+
+Examples:
+  deeper diff 41 42
+  deeper diff 41 42 --output json | jq '.summary'
+  deeper diff 41 42 --report`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		scanIDs, err := parseScanIDs(args)
+		if err != nil {
+			return err
+		}
+		db, err := createDatabase()
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer func() { err = errors.Join(err, db.Close()) }()
+		repo := database.NewRepository(db)
+
+		d, err := scandiff.Load(repo, scanIDs[0], scanIDs[1])
+		if err != nil {
+			return err
+		}
+		if err := writeDiff(os.Stdout, d, output); err != nil {
+			return err
+		}
+		if diffReport {
+			path, err := writeGraphReport(repo, d.New.ID, d, fmt.Sprintf("diff-%d-%d.html", d.Old.ID, d.New.ID), !diffNoOpen)
+			if err != nil {
+				return err
+			}
+			if path != "" {
+				log.Info().Msgf("Diff report: %s", path)
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	diffCmd.Flags().BoolVar(&diffReport, "report", false, "also write the graph report of the newer scan in diff mode")
+	diffCmd.Flags().BoolVar(&diffNoOpen, "no-open", false, "do not auto-open the diff report in a browser")
+}
+
+// resolveDiffAgainst turns a --diff-against value into the scan to compare
+// with: "last" is the previous completed scan of the same input. It
+// returns nil when "last" has nothing to compare with.
+func resolveDiffAgainst(repo *database.Repository, session *database.ScanSession, against string) (*int64, error) {
+	if against == "last" {
+		previous, err := repo.GetPreviousScan(session.Input, session.ID)
+		if err != nil {
+			return nil, err
+		}
+		if previous == nil {
+			return nil, nil
+		}
+		return &previous.ID, nil
+	}
+	id, err := strconv.ParseInt(against, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --diff-against %q: use \"last\" or a scan id", against)
+	}
+	return &id, nil
+}
+
+func writeDiff(w io.Writer, d *scandiff.Diff, format string) error {
+	switch format {
+	case "json":
+		return writeGraphJSON(w, d)
+	case "table":
+		printDiff(w, d)
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for diff: %s", format)
+	}
+}
+
+func printDiff(w io.Writer, d *scandiff.Diff) {
+	s := d.Summary
+	_, _ = fmt.Fprintf(w, "Scan %d (%s, %s) -> scan %d (%s, %s)\n",
+		d.Old.ID, d.Old.Input, d.Old.StartedAt.Format("2006-01-02"),
+		d.New.ID, d.New.Input, d.New.StartedAt.Format("2006-01-02"))
+	_, _ = fmt.Fprintf(w, "Traces: +%d -%d, %d unchanged. Edges: +%d -%d, %d unchanged.\n",
+		s.AddedTraces, s.RemovedTraces, s.UnchangedTraces, s.AddedEdges, s.RemovedEdges, s.UnchangedEdges)
+	if !d.Changed() {
+		return
+	}
+
+	if s.AddedTraces+s.RemovedTraces > 0 {
+		_, _ = fmt.Fprintln(w)
+		table := newGraphTable(w, []string{"", "Type", "Trace"})
+		for _, change := range d.Types {
+			for _, t := range change.Added {
+				table.Append([]string{"+", string(change.Type), t.Value})
+			}
+			for _, t := range change.Removed {
+				table.Append([]string{"-", string(change.Type), t.Value})
+			}
+		}
+		table.Render()
+	}
+
+	if s.AddedEdges+s.RemovedEdges > 0 {
+		_, _ = fmt.Fprintln(w)
+		table := newGraphTable(w, []string{"", "Plugin", "From", "To"})
+		for _, change := range d.Plugins {
+			for _, e := range change.Added {
+				table.Append([]string{"+", change.Plugin, e.From.Value, e.To.Value})
+			}
+			for _, e := range change.Removed {
+				table.Append([]string{"-", change.Plugin, e.From.Value, e.To.Value})
+			}
+		}
+		table.Render()
+	}
+}
+
+// reportDiff maps a scan diff to the graph report's diff mode.
+func reportDiff(d *scandiff.Diff) *graphreport.Diff {
+	rd := &graphreport.Diff{
+		AgainstScanID:    d.Old.ID,
+		AgainstInput:     d.Old.Input,
+		AgainstStartedAt: d.Old.StartedAt.Format("2006-01-02"),
+		Added:            []int64{},
+		AddedEdges:       []graphreport.Edge{},
+		RemovedNodes:     []graphreport.Node{},
+		RemovedEdges:     []graphreport.Edge{},
+	}
+	for _, t := range d.AddedTraces() {
+		rd.Added = append(rd.Added, t.ID)
+	}
+	for _, t := range d.RemovedTraces() {
+		rd.RemovedNodes = append(rd.RemovedNodes, graphreport.Node{ID: t.ID, Label: t.Value, Type: string(t.Type)})
+	}
+	for _, e := range d.AddedEdges() {
+		rd.AddedEdges = append(rd.AddedEdges, graphreport.Edge{From: e.From.ID, To: e.To.ID, Label: e.Plugin})
+	}
+	for _, e := range d.RemovedEdges() {
+		rd.RemovedEdges = append(rd.RemovedEdges, graphreport.Edge{From: e.From.ID, To: e.To.ID, Label: e.Plugin})
+	}
+	return rd
+}
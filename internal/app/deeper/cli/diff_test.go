@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/scandiff"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/state"
+)
+
+func TestDiffScan_AgainstLastScanOfSameInput(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+
+	user := entities.Trace{Value: "jdoe", Type: entities.Username}
+	record := func(input string, discoveries ...entities.Discovery) *database.ScanSession {
+		session, err := repo.CreateScanSession(input)
+		require.NoError(t, err)
+		seedID, err := repo.GetOrCreateTrace(user)
+		require.NoError(t, err)
+		require.NoError(t, repo.InsertEdge(&database.TraceEdge{
+			ChildTraceID: seedID, PluginName: database.SeedPluginName, ScanID: session.ID, DiscoveredAt: time.Now(),
+		}))
+		require.NoError(t, repo.PersistDiscoveries(session.ID, discoveries))
+		session.Status = "completed"
+		require.NoError(t, repo.UpdateScanSession(session))
+		return session
+	}
+
+	first := record("jdoe", entities.Discovery{Parent: user, PluginName: "GitHubProfilePlugin", Child: entities.Trace{Value: "jdoe@old.example", Type: entities.Email}})
+	record("someone-else")
+	latest := record("jdoe", entities.Discovery{Parent: user, PluginName: "GitHubProfilePlugin", Child: entities.Trace{Value: "jdoe@new.example", Type: entities.Email}})
+
+	d, err := diffScan(repo, latest, "last")
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	assert.Equal(t, first.ID, d.Old.ID)
+	assert.Equal(t, 1, d.Summary.AddedTraces)
+	assert.Equal(t, 1, d.Summary.RemovedTraces)
+
+	var buf bytes.Buffer
+	printDiff(&buf, d)
+	assert.Contains(t, buf.String(), "jdoe@new.example")
+	assert.Contains(t, buf.String(), "Traces: +1 -1, 1 unchanged")
+
+	path, err := writeGraphReport(repo, latest.ID, d, "diff.html", false)
+	require.NoError(t, err)
+	html, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(html), `"against_scan_id":`+strconv.FormatInt(first.ID, 10))
+
+	none, err := diffScan(repo, first, "last")
+	require.NoError(t, err)
+	assert.Nil(t, none, "nothing earlier to compare with")
+
+	_, err = diffScan(repo, latest, "yesterday")
+	assert.Error(t, err)
+}
+
+// TestDiffScan_RescanIsUnchanged is a regression test: a rescan used to
+// be answered from the deduplication cache and found only its seed.
+func TestDiffScan_RescanIsUnchanged(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	original := state.ActivePlugins[entities.Domain]
+	t.Cleanup(func() { state.ActivePlugins[entities.Domain] = original })
+	state.ActivePlugins[entities.Domain] = nil
+	require.NoError(t, (&subdomainPlugin{}).Register())
+
+	eng, repo, err := createEngine()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = eng.Shutdown(5 * time.Second) })
+
+	seeds, err := parseScanSeeds("acme.com", "")
+	require.NoError(t, err)
+	scan := func() *database.ScanSession {
+		session, err := repo.CreateScanSession("acme.com")
+		require.NoError(t, err)
+		_, err = runScan(context.Background(), eng, repo, session, seeds, scanOptions("last"))
+		require.NoError(t, err)
+		return session
+	}
+
+	first := scan()
+	second := scan()
+
+	d, err := diffScan(repo, second, "last")
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	assert.Equal(t, first.ID, d.Old.ID)
+	assert.Zero(t, d.Summary.AddedTraces)
+	assert.Zero(t, d.Summary.RemovedTraces)
+	assert.Zero(t, d.Summary.AddedEdges)
+	assert.Zero(t, d.Summary.RemovedEdges)
+}
+
+func TestReportDiff_MapsChanges(t *testing.T) {
+	d := &scandiff.Diff{
+		Old: scandiff.Scan{ID: 41, Input: "jdoe"},
+		Types: []scandiff.TypeChange{{
+			Type:    entities.Email,
+			Added:   []scandiff.Trace{{ID: 4, Value: "new@example.com", Type: entities.Email}},
+			Removed: []scandiff.Trace{{ID: 2, Value: "old@example.com", Type: entities.Email}},
+		}},
+		Plugins: []scandiff.PluginChange{{
+			Plugin:  "GitHubProfilePlugin",
+			Removed: []scandiff.Edge{{From: scandiff.Trace{ID: 1}, To: scandiff.Trace{ID: 2}, Plugin: "GitHubProfilePlugin"}},
+		}},
+	}
+
+	rd := reportDiff(d)
+	assert.Equal(t, int64(41), rd.AgainstScanID)
+	assert.Equal(t, []int64{4}, rd.Added)
+	require.Len(t, rd.RemovedNodes, 1)
+	assert.Equal(t, "old@example.com", rd.RemovedNodes[0].Label)
+	require.Len(t, rd.RemovedEdges, 1)
+	assert.Equal(t, int64(2), rd.RemovedEdges[0].To)
+	assert.Empty(t, rd.AddedEdges)
+}
@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/config"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/seal"
+)
+
+// sealedExt marks graph reports sealed with the database secret.
+const sealedExt = ".sealed"
+
+var (
+	rekeyDecrypt bool
+	decryptOut   string
+)
+
+var (
+	databaseRekeyCmd = &cobra.Command{
+		Use:   "rekey",
+		Short: "Encrypt, re-encrypt or decrypt the database and stored reports",
+		Long: `Rekey rewrites the database and the graph reports in ~/.deeper/reports
+under a new key. The current key comes from DEEPER_DB_PASSPHRASE or
+DEEPER_DB_KEY_FILE, and none means the data is in plaintext; the new key
+comes from DEEPER_DB_NEW_PASSPHRASE or DEEPER_DB_NEW_KEY_FILE. With
+--decrypt the data is written back in plaintext.
+
+Stop other deeper processes first. Encrypting a plaintext database
+replaces the file, but the filesystem may keep the old plaintext blocks
+until they are overwritten.
+
+Examples:
+  DEEPER_DB_NEW_KEY_FILE=~/.deeper/deeper.key deeper db rekey
+  DEEPER_DB_KEY_FILE=~/.deeper/deeper.key DEEPER_DB_NEW_PASSPHRASE=... deeper db rekey
+  DEEPER_DB_PASSPHRASE=... deeper db rekey --decrypt`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, err := databaseSecret()
+			if err != nil {
+				return err
+			}
+			to, err := seal.Load(os.Getenv("DEEPER_DB_NEW_PASSPHRASE"), os.Getenv("DEEPER_DB_NEW_KEY_FILE"))
+			if err != nil {
+				return fmt.Errorf("invalid new key: %w", err)
+			}
+			switch {
+			case rekeyDecrypt && to != nil:
+				return fmt.Errorf("--decrypt takes no new key")
+			case !rekeyDecrypt && to == nil:
+				return fmt.Errorf("set DEEPER_DB_NEW_PASSPHRASE or DEEPER_DB_NEW_KEY_FILE, or pass --decrypt")
+			case from == nil && to == nil:
+				return fmt.Errorf("the database is not encrypted")
+			}
+
+			dbPath, err := databasePath()
+			if err != nil {
+				return err
+			}
+			return runRekey(os.Stdout, dbPath, filepath.Join(filepath.Dir(dbPath), "reports"), from, to)
+		},
+	}
+
+	databaseDecryptCmd = &cobra.Command{
+		Use:   "decrypt <file>",
+		Short: "Decrypt a sealed graph report",
+		Long: `Decrypt writes the plaintext of a file sealed with the database key,
+such as a graph report saved by a scan of an encrypted database.
+
+Examples:
+  deeper db decrypt ~/.deeper/reports/scan-42.html.sealed -o scan-42.html`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			secret, err := databaseSecret()
+			if err != nil {
+				return err
+			}
+			if secret == nil {
+				return fmt.Errorf("set DEEPER_DB_PASSPHRASE or DEEPER_DB_KEY_FILE")
+			}
+			plaintext, err := secret.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			if decryptOut == "" {
+				_, err = os.Stdout.Write(plaintext)
+				return err
+			}
+			return os.WriteFile(decryptOut, plaintext, 0o600)
+		},
+	}
+)
+
+func init() {
+	databaseRekeyCmd.Flags().BoolVar(&rekeyDecrypt, "decrypt", false, "write the data back in plaintext")
+	databaseDecryptCmd.Flags().StringVarP(&decryptOut, "out", "o", "", "write to this file instead of stdout")
+	databaseCmd.AddCommand(databaseRekeyCmd)
+	databaseCmd.AddCommand(databaseDecryptCmd)
+}
+
+var secretCache struct {
+	sync.Mutex
+	passphrase, keyFile string
+	secret              *seal.Secret
+}
+
+// databaseSecret returns the secret the database and graph reports are
+// sealed with, from DEEPER_DB_PASSPHRASE or DEEPER_DB_KEY_FILE, or nil if
+// they are stored in plaintext. The secret is kept for the process, so a
+// passphrase is stretched once.
+func databaseSecret() (*seal.Secret, error) {
+	cfg := config.LoadConfig()
+
+	secretCache.Lock()
+	defer secretCache.Unlock()
+	if secretCache.secret != nil && secretCache.passphrase == cfg.DBPassphrase && secretCache.keyFile == cfg.DBKeyFile {
+		return secretCache.secret, nil
+	}
+	secret, err := seal.Load(cfg.DBPassphrase, cfg.DBKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database key: %w", err)
+	}
+	secretCache.passphrase, secretCache.keyFile, secretCache.secret = cfg.DBPassphrase, cfg.DBKeyFile, secret
+	return secret, nil
+}
+
+// runRekey rewrites the database at dbPath and the graph reports in
+// reportsDir from one secret to another, either of which may be nil for
+// plaintext.
+func runRekey(w io.Writer, dbPath, reportsDir string, from, to *seal.Secret) error {
+	if err := database.Rekey(dbPath, from, to); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(reportsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read reports directory: %w", err)
+	}
+	reports := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(reportsDir, name)
+		sealed := strings.HasSuffix(name, sealedExt)
+		if sealed != (from != nil) {
+			continue
+		}
+
+		var plaintext []byte
+		if sealed {
+			plaintext, err = from.ReadFile(path)
+		} else {
+			plaintext, err = os.ReadFile(path)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read report: %w", err)
+		}
+
+		target := strings.TrimSuffix(path, sealedExt)
+		perm := os.FileMode(0o644)
+		if to != nil {
+			target += sealedExt
+			perm = 0o600
+		}
+		if err := to.WriteFile(target, plaintext, perm); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		if target != path {
+			if err := os.Remove(path); err != nil {
+				return fmt.Errorf("failed to remove old report: %w", err)
+			}
+		}
+		reports++
+	}
+
+	switch {
+	case to == nil:
+		_, _ = fmt.Fprintf(w, "Decrypted %s and %d reports\n", dbPath, reports)
+	case from == nil:
+		_, _ = fmt.Fprintf(w, "Encrypted %s and %d reports\n", dbPath, reports)
+	default:
+		_, _ = fmt.Fprintf(w, "Re-encrypted %s and %d reports under the new key\n", dbPath, reports)
+	}
+	return nil
+}
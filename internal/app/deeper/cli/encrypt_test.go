@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/export"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/seal"
+)
+
+func TestEncryptedDatabase_ReportsRekeyAndExport(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	keyFile := filepath.Join(t.TempDir(), "deeper.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef"), 0o600))
+	t.Setenv("DEEPER_DB_KEY_FILE", keyFile)
+
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+	session, err := repo.CreateScanSession("jdoe@acme.com")
+	require.NoError(t, err)
+	require.NoError(t, repo.PersistDiscoveries(session.ID, []entities.Discovery{{
+		Parent:     entities.Trace{Value: "jdoe@acme.com", Type: entities.Email},
+		PluginName: "GravatarPlugin",
+		Child:      entities.Trace{Value: "jdoe", Type: entities.Username},
+	}}))
+
+	// The graph report is sealed along with the database.
+	report, err := saveGraphReport(repo, session.ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, sealedExt, filepath.Ext(report))
+	data, err := os.ReadFile(report)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "jdoe")
+	require.NoError(t, closeEngineDatabase())
+
+	dbPath := filepath.Join(home, ".deeper", "deeper.db")
+	sealed, err := seal.IsSealedFile(dbPath)
+	require.NoError(t, err)
+	assert.True(t, sealed)
+
+	// An export of the encrypted database imports into a plaintext one.
+	exported := filepath.Join(t.TempDir(), "case.mtgx")
+	require.NoError(t, runExport([]int64{session.ID}, "", export.FormatMaltego, export.Options{}, exported))
+
+	// Rekeying with no new key decrypts the database and reports.
+	from, err := databaseSecret()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, runRekey(&buf, dbPath, filepath.Join(home, ".deeper", "reports"), from, nil))
+	assert.Equal(t, "Decrypted "+dbPath+" and 1 reports\n", buf.String())
+	sealed, err = seal.IsSealedFile(dbPath)
+	require.NoError(t, err)
+	assert.False(t, sealed)
+	data, err = os.ReadFile(strings.TrimSuffix(report, sealedExt))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "jdoe")
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DEEPER_DB_KEY_FILE", "")
+	require.NoError(t, runImportMaltego(exported, false))
+	_, repo, err = createEngine()
+	require.NoError(t, err)
+	nodes, _, err := repo.GetScanGraph(1)
+	require.NoError(t, err)
+	values := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		values = append(values, node.Value)
+	}
+	assert.ElementsMatch(t, []string{"jdoe@acme.com", "jdoe"}, values)
+}
+
+func TestWithRepo_ReturnsCloseError(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	keyFile := filepath.Join(t.TempDir(), "deeper.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef"), 0o600))
+	t.Setenv("DEEPER_DB_KEY_FILE", keyFile)
+
+	// Closing seals the database, so a failure to save it is the
+	// command's error.
+	err := withRepo(func(repo *database.Repository) error {
+		require.NoError(t, withRepo(func(other *database.Repository) error {
+			_, err := other.CreateScanSession("other")
+			return err
+		}))
+		_, err := repo.CreateScanSession("jdoe@acme.com")
+		return err
+	})
+	assert.ErrorContains(t, err, "changed by another process")
+}
+
+func TestCreateEngine_ReturnsCloseError(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	keyFile := filepath.Join(t.TempDir(), "deeper.key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("0123456789abcdef0123456789abcdef"), 0o600))
+	t.Setenv("DEEPER_DB_KEY_FILE", keyFile)
+
+	// scan, serve, watch, ui and import maltego save the database when
+	// Execute closes it, so a failure to save it fails the command.
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+	require.NoError(t, withRepo(func(other *database.Repository) error {
+		_, err := other.CreateScanSession("other")
+		return err
+	}))
+	_, err = repo.CreateScanSession("jdoe@acme.com")
+	require.NoError(t, err)
+	assert.ErrorContains(t, closeEngineDatabase(), "changed by another process")
+}
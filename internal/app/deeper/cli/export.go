@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/export"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+)
+
+var (
+	exportFormat string
+	exportOut    string
+	exportAuthor string
+	exportTLP    string
+	exportCase   string
+)
+
+// exportCmd writes scan graphs in interchange formats
+var exportCmd = &cobra.Command{
+	Use:   "export [scan-id...] | --case <case>",
+	Short: "Export scan graphs for Gephi, yEd, Graphviz, Neo4j, Maltego or STIX platforms",
+	Long: `Export writes the discovery graph of one or more scans in a graph
+interchange format. Nodes carry the trace value, type and metadata; edges
+carry the plugin, scan and discovery time. Given several scans, the export
+is their union: a trace reached by several scans appears once. --case
+exports every scan of a case.
+
+Formats:
+  graphml   GraphML, for yEd and Gephi
+  gexf      GEXF 1.3, for Gephi
+  dot       Graphviz DOT
+  cypher    Cypher MERGE statements for Neo4j; safe to load repeatedly
+  stix      STIX 2.1 bundle for MISP, OpenCTI and other threat-intel
+            platforms; each scan becomes a report, each edge a relationship
+  maltego   MTGX archive for Maltego, with standard Maltego entity types;
+            "deeper import maltego" reads it back
+
+Examples:
+  deeper export 42 --format gexf --out scan42.gexf
+  deeper export 42 43 --format graphml > union.graphml
+  deeper export --case acme-2026 --format gexf -o acme.gexf
+  deeper export 42 --format cypher | cypher-shell -u neo4j -p secret
+  deeper export 42 --format maltego -o scan42.mtgx
+  deeper export 42 --format stix --author "ACME CTI" --tlp green -o scan42.json`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if exportCase != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scanIDs, err := parseScanIDs(args)
+		if err != nil {
+			return err
+		}
+		opts := export.Options{Author: exportAuthor, TLP: exportTLP}
+		return runExport(scanIDs, exportCase, export.Format(exportFormat), opts, exportOut)
+	},
+}
+
+func init() {
+	exportCmd.Flags().StringVar(&exportFormat, "format", string(export.FormatGraphML), "export format (graphml, gexf, dot, cypher, stix, maltego)")
+	exportCmd.Flags().StringVarP(&exportOut, "out", "o", "", "write to this file instead of stdout")
+	exportCmd.Flags().StringVar(&exportAuthor, "author", "", "organization STIX objects are attributed to (default deeper)")
+	exportCmd.Flags().StringVar(&exportTLP, "tlp", export.DefaultTLP, "TLP marking of STIX objects (white, green, amber, red)")
+	exportCmd.Flags().StringVar(&exportCase, "case", "", "export every scan of this case")
+}
+
+// parseScanIDs parses scan ID arguments.
+func parseScanIDs(args []string) ([]int64, error) {
+	ids := make([]int64, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scan id %q: %w", arg, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func runExport(scanIDs []int64, caseRef string, format export.Format, opts export.Options, outPath string) (err error) {
+	db, err := createDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() { err = errors.Join(err, db.Close()) }()
+	repo := database.NewRepository(db)
+
+	if caseRef != "" {
+		if scanIDs, err = caseScanIDs(repo, caseRef); err != nil {
+			return err
+		}
+	}
+	graph, err := export.Load(repo, scanIDs)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if outPath != "" {
+		f, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", outPath, err)
+		}
+		defer func() { _ = f.Close() }()
+		w = f
+	}
+
+	if err := export.Write(w, graph, format, opts); err != nil {
+		return err
+	}
+	if outPath != "" {
+		log.Info().Msgf("Exported %d traces and %d edges to %s", len(graph.Nodes), len(graph.Edges), outPath)
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/export"
+	"github.com/smirnoffmg/deeper/internal/app/deeper/graphnav"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+)
+
+var (
+	graphReachableHops int
+	graphSubgraphHops  int
+	graphSubgraphFmt   string
+	graphSubgraphOut   string
+)
+
+var (
+	graphCmd = &cobra.Command{
+		Use:   "graph",
+		Short: "Navigate a scan's discovery graph",
+		Long: `Navigate the discovery graph stored for a scan.
+
+A trace is given by value ("jdoe@example.com"), as "type:value" when the
+value alone is ambiguous ("username:jdoe"), or by ID ("#17"). Every
+command prints a table, or JSON with --output json.
+
+Examples:
+  deeper graph path 42 93.184.216.34
+  deeper graph reachable 42 jdoe@example.com --hops 2
+  deeper graph neighbors 42 username:jdoe --output json
+  deeper graph subgraph 42 example.com --hops 2 --format graphml -o slice.graphml`,
+	}
+
+	graphPathCmd = &cobra.Command{
+		Use:   "path <scan-id> <trace>",
+		Short: "Explain how the scan reached a trace",
+		Long: `Path shows a shortest discovery chain from a seed to the trace: each
+step and the plugin that produced it.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withScanGraph(args, func(sg *scanGraph, t database.Trace) error {
+				return showGraphPath(sg, t)
+			})
+		},
+	}
+
+	graphReachableCmd = &cobra.Command{
+		Use:   "reachable <scan-id> <trace>",
+		Short: "List the traces discovered from a trace",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withScanGraph(args, func(sg *scanGraph, t database.Trace) error {
+				return showGraphReachable(sg, t, graphReachableHops)
+			})
+		},
+	}
+
+	graphNeighborsCmd = &cobra.Command{
+		Use:   "neighbors <scan-id> <trace>",
+		Short: "Show the traces a trace came from and led to",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withScanGraph(args, func(sg *scanGraph, t database.Trace) error {
+				return showGraphNeighbors(sg, t)
+			})
+		},
+	}
+
+	graphSubgraphCmd = &cobra.Command{
+		Use:   "subgraph <scan-id> <trace>",
+		Short: "Cut out the part of the graph around a trace",
+		Long: `Subgraph takes every trace within --hops edges of the given one, in
+either direction, and the edges among them. It prints them as a table or
+JSON, or with --format writes them in any "deeper export" format.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withScanGraph(args, func(sg *scanGraph, t database.Trace) error {
+				return showGraphSubgraph(sg, t, graphSubgraphHops, graphSubgraphFmt, graphSubgraphOut)
+			})
+		},
+	}
+)
+
+func init() {
+	graphReachableCmd.Flags().IntVar(&graphReachableHops, "hops", 3, "maximum number of edges to follow")
+	graphSubgraphCmd.Flags().IntVar(&graphSubgraphHops, "hops", 1, "maximum distance from the trace, in edges")
+	graphSubgraphCmd.Flags().StringVar(&graphSubgraphFmt, "format", "", "write in an export format (graphml, gexf, dot, cypher, stix, maltego)")
+	graphSubgraphCmd.Flags().StringVarP(&graphSubgraphOut, "out", "o", "", "write to this file instead of stdout")
+
+	graphCmd.AddCommand(graphPathCmd)
+	graphCmd.AddCommand(graphReachableCmd)
+	graphCmd.AddCommand(graphNeighborsCmd)
+	graphCmd.AddCommand(graphSubgraphCmd)
+}
+
+// scanGraph is a loaded scan and its indexed graph.
+type scanGraph struct {
+	repo    *database.Repository
+	session *database.ScanSession
+	graph   *graphnav.Graph
+}
+
+// withScanGraph loads the scan named by args[0], resolves the trace
+// reference in args[1] and calls fn with both.
+func withScanGraph(args []string, fn func(*scanGraph, database.Trace) error) (err error) {
+	scanID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid scan id %q: %w", args[0], err)
+	}
+
+	db, err := createDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() { err = errors.Join(err, db.Close()) }()
+	repo := database.NewRepository(db)
+
+	session, err := repo.GetScanSession(scanID)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return fmt.Errorf("scan %d not found", scanID)
+	}
+	nodes, edges, err := repo.GetScanGraph(scanID)
+	if err != nil {
+		return fmt.Errorf("failed to load scan graph: %w", err)
+	}
+
+	sg := &scanGraph{repo: repo, session: session, graph: graphnav.New(nodes, edges)}
+	trace, err := sg.graph.Find(args[1])
+	if err != nil {
+		return err
+	}
+	return fn(sg, trace)
+}
+
+type graphTraceJSON struct {
+	TraceID int64  `json:"trace_id"`
+	Value   string `json:"value"`
+	Type    string `json:"type"`
+	Hop     int    `json:"hop"`
+}
+
+func (sg *scanGraph) traceJSON(t database.Trace) graphTraceJSON {
+	return graphTraceJSON{TraceID: t.ID, Value: t.Value, Type: string(t.Type), Hop: sg.graph.Hop(t.ID)}
+}
+
+func writeGraphJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func newGraphTable(w io.Writer, header []string) *tablewriter.Table {
+	table := tablewriter.NewWriter(w)
+	table.SetHeader(header)
+	table.SetBorder(true)
+	table.SetAutoWrapText(false)
+	return table
+}
+
+func showGraphPath(sg *scanGraph, t database.Trace) error {
+	ancestry, err := sg.repo.GetDiscoveryPath(sg.session.ID, t.ID)
+	if err != nil {
+		return err
+	}
+	chain := sg.graph.Chain(t.ID, ancestry)
+	reached := len(chain) > 0 && sg.graph.IsSeed(chain[0].TraceID)
+
+	switch output {
+	case "json":
+		return writeGraphJSON(os.Stdout, struct {
+			ScanID  int64           `json:"scan_id"`
+			Trace   graphTraceJSON  `json:"trace"`
+			Reached bool            `json:"reached_from_seed"`
+			Path    []graphnav.Step `json:"path"`
+		}{sg.session.ID, sg.traceJSON(t), reached, chain})
+	case "table":
+		table := newGraphTable(os.Stdout, []string{"Step", "Trace", "Type", "Found by"})
+		for i, step := range chain {
+			foundBy := step.Plugin
+			if i == 0 {
+				foundBy = "(seed)"
+				if !reached {
+					foundBy = "(not reached from a seed)"
+				}
+			}
+			table.Append([]string{strconv.Itoa(i), step.Value, string(step.Type), foundBy})
+		}
+		table.Render()
+		if parents, _ := sg.graph.Neighbors(t.ID); len(parents) > 1 {
+			fmt.Printf("\n%s was found %d ways; see \"deeper graph neighbors\" for all of them.\n", t.Value, len(parents))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for graph: %s", output)
+	}
+}
+
+func showGraphReachable(sg *scanGraph, t database.Trace, hops int) error {
+	reachable, err := sg.repo.GetReachableTraces(sg.session.ID, t.ID, hops)
+	if err != nil {
+		return err
+	}
+	// The start trace itself is hop 0.
+	found := make([]database.ReachableTrace, 0, len(reachable))
+	for _, r := range reachable {
+		if r.TraceID != t.ID {
+			found = append(found, r)
+		}
+	}
+
+	switch output {
+	case "json":
+		return writeGraphJSON(os.Stdout, struct {
+			ScanID  int64                     `json:"scan_id"`
+			From    graphTraceJSON            `json:"from"`
+			MaxHops int                       `json:"max_hops"`
+			Traces  []database.ReachableTrace `json:"traces"`
+		}{sg.session.ID, sg.traceJSON(t), hops, found})
+	case "table":
+		if len(found) == 0 {
+			fmt.Printf("Nothing was discovered from %s within %d hops\n", t.Value, hops)
+			return nil
+		}
+		table := newGraphTable(os.Stdout, []string{"Hops", "Trace", "Type"})
+		for _, r := range found {
+			table.Append([]string{strconv.Itoa(r.Hops), r.Value, string(r.Type)})
+		}
+		table.Render()
+		fmt.Printf("\n%d traces discovered from %s within %d hops\n", len(found), t.Value, hops)
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for graph: %s", output)
+	}
+}
+
+type graphNeighborJSON struct {
+	graphTraceJSON
+	Plugin string `json:"plugin"`
+}
+
+func showGraphNeighbors(sg *scanGraph, t database.Trace) error {
+	parents, children := sg.graph.Neighbors(t.ID)
+
+	switch output {
+	case "json":
+		toJSON := func(ns []graphnav.Neighbor) []graphNeighborJSON {
+			out := make([]graphNeighborJSON, 0, len(ns))
+			for _, n := range ns {
+				out = append(out, graphNeighborJSON{graphTraceJSON: sg.traceJSON(n.Trace), Plugin: n.Plugin})
+			}
+			return out
+		}
+		return writeGraphJSON(os.Stdout, struct {
+			ScanID   int64               `json:"scan_id"`
+			Trace    graphTraceJSON      `json:"trace"`
+			Seed     bool                `json:"seed"`
+			Parents  []graphNeighborJSON `json:"parents"`
+			Children []graphNeighborJSON `json:"children"`
+		}{sg.session.ID, sg.traceJSON(t), sg.graph.IsSeed(t.ID), toJSON(parents), toJSON(children)})
+	case "table":
+		if sg.graph.IsSeed(t.ID) {
+			fmt.Printf("%s is a seed of scan %d\n\n", t.Value, sg.session.ID)
+		}
+		if len(parents) == 0 && len(children) == 0 {
+			fmt.Printf("%s has no neighbors\n", t.Value)
+			return nil
+		}
+		table := newGraphTable(os.Stdout, []string{"Direction", "Trace", "Type", "Plugin"})
+		for _, n := range parents {
+			table.Append([]string{"from", n.Trace.Value, string(n.Trace.Type), n.Plugin})
+		}
+		for _, n := range children {
+			table.Append([]string{"to", n.Trace.Value, string(n.Trace.Type), n.Plugin})
+		}
+		table.Render()
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for graph: %s", output)
+	}
+}
+
+func showGraphSubgraph(sg *scanGraph, t database.Trace, hops int, format, outPath string) error {
+	nodes, edges := sg.graph.Subgraph(t.ID, hops)
+
+	var w io.Writer = os.Stdout
+	if outPath != "" {
+		f, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", outPath, err)
+		}
+		defer func() { _ = f.Close() }()
+		w = f
+	}
+
+	if format != "" {
+		g := export.FromScan(*sg.session, nodes, edges)
+		if err := export.Write(w, g, export.Format(format), export.Options{}); err != nil {
+			return err
+		}
+		if outPath != "" {
+			log.Info().Msgf("Exported %d traces and %d edges to %s", len(g.Nodes), len(g.Edges), outPath)
+		}
+		return nil
+	}
+
+	traces := make(map[int64]database.Trace, len(nodes))
+	for _, n := range nodes {
+		traces[n.ID] = n
+	}
+
+	switch output {
+	case "json":
+		type edgeJSON struct {
+			From   int64  `json:"from"`
+			To     int64  `json:"to"`
+			Plugin string `json:"plugin"`
+		}
+		out := struct {
+			ScanID int64            `json:"scan_id"`
+			Center graphTraceJSON   `json:"center"`
+			Hops   int              `json:"hops"`
+			Nodes  []graphTraceJSON `json:"nodes"`
+			Edges  []edgeJSON       `json:"edges"`
+		}{ScanID: sg.session.ID, Center: sg.traceJSON(t), Hops: hops, Nodes: []graphTraceJSON{}, Edges: []edgeJSON{}}
+		for _, n := range nodes {
+			out.Nodes = append(out.Nodes, sg.traceJSON(n))
+		}
+		for _, e := range edges {
+			if e.ParentTraceID != nil {
+				out.Edges = append(out.Edges, edgeJSON{From: *e.ParentTraceID, To: e.ChildTraceID, Plugin: e.PluginName})
+			}
+		}
+		return writeGraphJSON(w, out)
+	case "table":
+		table := newGraphTable(w, []string{"From", "Plugin", "To"})
+		for _, e := range edges {
+			if e.ParentTraceID == nil {
+				continue
+			}
+			from, to := traces[*e.ParentTraceID], traces[e.ChildTraceID]
+			table.Append([]string{
+				fmt.Sprintf("%s (%s)", from.Value, from.Type),
+				e.PluginName,
+				fmt.Sprintf("%s (%s)", to.Value, to.Type),
+			})
+		}
+		table.Render()
+		_, _ = fmt.Fprintf(w, "\n%d traces within %d hops of %s\n", len(nodes), hops, t.Value)
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for graph: %s", output)
+	}
+}
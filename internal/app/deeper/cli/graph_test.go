@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestGraphSubgraph_WritesExportFormat(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+	session, err := repo.CreateScanSession("jdoe")
+	require.NoError(t, err)
+
+	user := entities.Trace{Value: "jdoe", Type: entities.Username}
+	email := entities.Trace{Value: "jdoe@example.com", Type: entities.Email}
+	domain := entities.Trace{Value: "example.com", Type: entities.Domain}
+	rootID, err := repo.GetOrCreateTrace(user)
+	require.NoError(t, err)
+	require.NoError(t, repo.InsertEdge(&database.TraceEdge{
+		ChildTraceID: rootID, PluginName: database.SeedPluginName, ScanID: session.ID, DiscoveredAt: time.Now(),
+	}))
+	require.NoError(t, repo.PersistDiscoveries(session.ID, []entities.Discovery{
+		{Parent: user, PluginName: "GitHubProfilePlugin", Child: email},
+		{Parent: email, PluginName: "EmailDomainPlugin", Child: domain},
+		{Parent: domain, PluginName: "DNSResolverPlugin", Child: entities.Trace{Value: "93.184.216.34", Type: entities.IpAddr}},
+	}))
+
+	out := filepath.Join(t.TempDir(), "slice.dot")
+	args := []string{strconv.FormatInt(session.ID, 10), "jdoe@example.com"}
+	require.NoError(t, withScanGraph(args, func(sg *scanGraph, trace database.Trace) error {
+		return showGraphSubgraph(sg, trace, 1, "dot", out)
+	}))
+
+	contents, err := os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Contains(t, string(contents), "jdoe@example.com")
+	assert.Contains(t, string(contents), "example.com")
+	assert.Contains(t, string(contents), "EmailDomainPlugin")
+	assert.NotContains(t, string(contents), "93.184.216.34", "two hops away")
+
+	err = withScanGraph([]string{args[0], "nobody"}, func(*scanGraph, database.Trace) error { return nil })
+	assert.ErrorContains(t, err, "not found")
+}
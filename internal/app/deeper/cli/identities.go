@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/graphreport"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/identity"
+)
+
+var identitiesRecompute bool
+
+// identitiesCmd shows the identity clusters resolved for a scan
+var identitiesCmd = &cobra.Command{
+	Use:   "identities [scan-id]",
+	Short: "Show who is who in a scan: traces grouped into identity clusters",
+	Long: `Identities groups a scan's emails, usernames, profiles and keys into
+identity clusters, each believed to belong to one person or organization.
+
+Clusters are formed only from strong links the account owner asserted
+themselves: a GitHub profile's listed email, Keybase proofs, Gravatar
+verified accounts and published SSH/PGP keys. Weak links such as matching
+display names or similar usernames are listed as evidence between clusters
+but never merge them.
+
+Clusters are computed when a scan completes. Use --recompute to resolve
+them again from the stored graph, e.g. for scans made before clustering
+existed.
+
+Examples:
+  deeper identities 42
+  deeper identities 42 --output json
+  deeper identities 42 --recompute`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scanID, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid scan id %q: %w", args[0], err)
+		}
+		return showIdentities(scanID, identitiesRecompute)
+	},
+}
+
+func init() {
+	identitiesCmd.Flags().BoolVar(&identitiesRecompute, "recompute", false, "resolve clusters again from the stored scan graph")
+}
+
+// resolveIdentities clusters a scan's stored graph and saves the result,
+// replacing any clusters saved for the scan before.
+func resolveIdentities(repo *database.Repository, scanID int64) ([]database.IdentityCluster, []database.IdentityLink, error) {
+	nodes, edges, err := repo.GetScanGraph(scanID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load scan graph: %w", err)
+	}
+	clusters, links := identity.Resolve(nodes, edges)
+	if err := repo.SaveIdentities(scanID, clusters, links); err != nil {
+		return nil, nil, fmt.Errorf("failed to save identities: %w", err)
+	}
+	return clusters, links, nil
+}
+
+func showIdentities(scanID int64, recompute bool) (err error) {
+	db, err := createDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() { err = errors.Join(err, db.Close()) }()
+	repo := database.NewRepository(db)
+
+	session, err := repo.GetScanSession(scanID)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return fmt.Errorf("scan %d not found", scanID)
+	}
+
+	clusters, links, err := repo.GetIdentities(scanID)
+	if err != nil {
+		return err
+	}
+	if recompute || len(clusters) == 0 {
+		if clusters, links, err = resolveIdentities(repo, scanID); err != nil {
+			return err
+		}
+	}
+
+	nodes, _, err := repo.GetScanGraph(scanID)
+	if err != nil {
+		return fmt.Errorf("failed to load scan graph: %w", err)
+	}
+	traces := make(map[int64]database.Trace, len(nodes))
+	for _, n := range nodes {
+		traces[n.ID] = n
+	}
+
+	switch output {
+	case "json":
+		return outputIdentitiesJSON(clusters, links, traces)
+	case "table":
+		printIdentitiesTable(clusters, links, traces)
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for identities: %s", output)
+	}
+}
+
+type identityMemberJSON struct {
+	TraceID int64  `json:"trace_id"`
+	Value   string `json:"value"`
+	Type    string `json:"type"`
+}
+
+type identityLinkJSON struct {
+	From     identityMemberJSON `json:"from"`
+	To       identityMemberJSON `json:"to"`
+	Strength string             `json:"strength"`
+	Reason   string             `json:"reason"`
+	Plugin   string             `json:"plugin,omitempty"`
+}
+
+type identityClusterJSON struct {
+	ID      int64                `json:"id"`
+	Label   string               `json:"label"`
+	Members []identityMemberJSON `json:"members"`
+	Links   []identityLinkJSON   `json:"links"`
+}
+
+func outputIdentitiesJSON(clusters []database.IdentityCluster, links []database.IdentityLink, traces map[int64]database.Trace) error {
+	member := func(id int64) identityMemberJSON {
+		t := traces[id]
+		return identityMemberJSON{TraceID: id, Value: t.Value, Type: string(t.Type)}
+	}
+
+	out := make([]identityClusterJSON, 0, len(clusters))
+	for _, c := range clusters {
+		entry := identityClusterJSON{ID: c.ID, Label: c.Label, Members: []identityMemberJSON{}, Links: []identityLinkJSON{}}
+		for _, id := range c.MemberIDs {
+			entry.Members = append(entry.Members, member(id))
+		}
+		for _, l := range identity.ClusterLinks(c, links) {
+			entry.Links = append(entry.Links, identityLinkJSON{
+				From:     member(l.FromTraceID),
+				To:       member(l.ToTraceID),
+				Strength: l.Strength,
+				Reason:   l.Reason,
+				Plugin:   l.PluginName,
+			})
+		}
+		out = append(out, entry)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+func printIdentitiesTable(clusters []database.IdentityCluster, links []database.IdentityLink, traces map[int64]database.Trace) {
+	if len(clusters) == 0 {
+		fmt.Println("No identities found")
+		return
+	}
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Identity", "Members", "Evidence"})
+	table.SetBorder(true)
+	table.SetRowLine(true)
+	table.SetAutoWrapText(false)
+
+	for _, c := range clusters {
+		var members []string
+		for _, id := range c.MemberIDs {
+			t := traces[id]
+			members = append(members, fmt.Sprintf("%s: %s", t.Type, t.Value))
+		}
+		table.Append([]string{
+			c.Label,
+			strings.Join(members, "\n"),
+			strings.Join(identity.DescribeLinks(identity.ClusterLinks(c, links), traces), "\n"),
+		})
+	}
+	table.Render()
+
+	fmt.Printf("\nSummary: %d identities from %d identifiers\n", len(clusters), countMembers(clusters))
+}
+
+func countMembers(clusters []database.IdentityCluster) int {
+	n := 0
+	for _, c := range clusters {
+		n += len(c.MemberIDs)
+	}
+	return n
+}
+
+// buildIdentityReport maps stored clusters to graphreport identities.
+// Singleton clusters with no links carry no information worth showing and
+// are left out.
+func buildIdentityReport(clusters []database.IdentityCluster, links []database.IdentityLink, nodes []database.Trace) []graphreport.Identity {
+	traces := make(map[int64]database.Trace, len(nodes))
+	for _, n := range nodes {
+		traces[n.ID] = n
+	}
+
+	var identities []graphreport.Identity
+	for _, c := range clusters {
+		related := identity.ClusterLinks(c, links)
+		if len(c.MemberIDs) < 2 && len(related) == 0 {
+			continue
+		}
+		identities = append(identities, graphreport.Identity{
+			Label:    c.Label,
+			Members:  c.MemberIDs,
+			Evidence: identity.DescribeLinks(related, traces),
+		})
+	}
+	return identities
+}
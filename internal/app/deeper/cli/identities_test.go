@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestResolveIdentities_SavesClustersAndFeedsGraphReport(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+
+	session, err := repo.CreateScanSession("jdoe")
+	require.NoError(t, err)
+
+	user := entities.Trace{Value: "jdoe", Type: entities.Username}
+	require.NoError(t, repo.PersistDiscoveries(session.ID, []entities.Discovery{
+		{Parent: user, PluginName: "GitHubProfilePlugin", Child: entities.Trace{Value: "jdoe@example.com", Type: entities.Email}},
+		{Parent: user, PluginName: "GitHubProfilePlugin", Child: entities.Trace{Value: "John Doe", Type: entities.Name}},
+	}))
+
+	clusters, links, err := resolveIdentities(repo, session.ID)
+	require.NoError(t, err)
+	require.Len(t, clusters, 1)
+	assert.Equal(t, "John Doe", clusters[0].Label)
+	require.Len(t, links, 1)
+
+	stored, _, err := repo.GetIdentities(session.ID)
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+
+	path, err := saveGraphReport(repo, session.ID, false)
+	require.NoError(t, err)
+	contents, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(contents), "profile_listed_account via GitHubProfilePlugin")
+}
+
+func TestBuildIdentityReport_SkipsUnlinkedSingletons(t *testing.T) {
+	nodes := []database.Trace{
+		{ID: 1, Value: "jdoe", Type: entities.Username},
+		{ID: 2, Value: "jdoe@example.com", Type: entities.Email},
+		{ID: 3, Value: "loner", Type: entities.Username},
+	}
+	clusters := []database.IdentityCluster{
+		{Label: "jdoe@example.com", MemberIDs: []int64{1, 2}},
+		{Label: "loner", MemberIDs: []int64{3}},
+	}
+	links := []database.IdentityLink{
+		{FromTraceID: 1, ToTraceID: 2, Strength: database.IdentityLinkStrong, Reason: "profile_listed_account", PluginName: "GitHubProfilePlugin"},
+	}
+
+	identities := buildIdentityReport(clusters, links, nodes)
+
+	require.Len(t, identities, 1)
+	assert.Equal(t, []int64{1, 2}, identities[0].Members)
+	assert.Equal(t, []string{"strong: jdoe -> jdoe@example.com (profile_listed_account via GitHubProfilePlugin)"}, identities[0].Evidence)
+}
@@ -0,0 +1,159 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/export"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+var importScan bool
+
+var (
+	importCmd = &cobra.Command{
+		Use:   "import",
+		Short: "Import graphs from other tools",
+		Long: `Import records a graph made in another tool as a deeper scan, keeping
+its entities as traces and its links as edges.
+
+Examples:
+  deeper import maltego investigation.mtgx
+  deeper import maltego investigation.mtgx --scan`,
+	}
+
+	importMaltegoCmd = &cobra.Command{
+		Use:   "maltego <file>",
+		Short: "Import a Maltego graph (.mtgx or .graphml)",
+		Long: `Import a Maltego graph as a new scan. Every entity becomes a seed trace
+and every link an edge, so the graph keeps its structure in deeper. Links
+drawn in Maltego are recorded under the __maltego__ pseudo-plugin; links
+from a deeper export keep their original plugin.
+
+With --scan, deeper then scans from the imported entities, adding what its
+plugins find to the same scan.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runImportMaltego(args[0], importScan)
+		},
+	}
+)
+
+func init() {
+	importMaltegoCmd.Flags().BoolVar(&importScan, "scan", false, "scan from the imported entities after importing")
+	importCmd.AddCommand(importMaltegoCmd)
+}
+
+func runImportMaltego(path string, scan bool) error {
+	graph, err := export.ReadMaltego(path)
+	if err != nil {
+		return err
+	}
+	if len(graph.Nodes) == 0 {
+		return fmt.Errorf("no entities found in %s", path)
+	}
+
+	eng, repo, err := createEngine()
+	if err != nil {
+		return err
+	}
+
+	session, err := repo.CreateScanSessionFor("maltego:"+filepath.Base(path), scanOperator(), engagement)
+	if err != nil {
+		return fmt.Errorf("failed to create scan session: %w", err)
+	}
+	// A scan writes the seed edges itself.
+	if err := importGraph(repo, session.ID, graph, !scan); err != nil {
+		return err
+	}
+	log.Info().Msgf("Imported %d entities and %d links from %s", len(graph.Nodes), len(graph.Edges), path)
+
+	traceCount := len(graph.Nodes)
+	if scan {
+		seeds := make([]entities.Seed, 0, len(graph.Nodes))
+		for _, n := range graph.Nodes {
+			seeds = append(seeds, entities.Seed{Trace: entities.Trace{Value: n.Value, Type: n.Type}})
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		stopEvents := watchScanEvents(eng.Events(), output)
+		traces, err := eng.ProcessSeeds(ctx, seeds, session.ID)
+		if streamErr := stopEvents(); streamErr != nil {
+			log.Error().Err(streamErr).Msg("Failed to stream scan events")
+		}
+		if err != nil {
+			completedAt := time.Now()
+			session.CompletedAt = &completedAt
+			session.Status = "failed"
+			_ = repo.UpdateScanSession(session)
+			return fmt.Errorf("failed to process input: %w", err)
+		}
+		traceCount = len(traces)
+	}
+
+	completedAt := time.Now()
+	session.CompletedAt = &completedAt
+	session.Status = "completed"
+	session.UniqueTraces = traceCount
+	session.TotalTraces = traceCount
+	if session.Errors, err = repo.CountFailedPluginRuns(session.ID); err != nil {
+		return err
+	}
+	if err := repo.UpdateScanSession(session); err != nil {
+		return fmt.Errorf("failed to update scan session: %w", err)
+	}
+
+	if _, _, err := resolveIdentities(repo, session.ID); err != nil {
+		log.Warn().Err(err).Msg("Failed to resolve identities")
+	}
+
+	fmt.Printf("Imported %s as scan %d (%d traces)\n", filepath.Base(path), session.ID, traceCount)
+	return nil
+}
+
+// importGraph records an imported graph under scanID: its nodes as traces,
+// with seed edges when seedEdges is set, and its edges as discoveries.
+func importGraph(repo *database.Repository, scanID int64, graph *export.Graph, seedEdges bool) error {
+	traces := make(map[int64]entities.Trace, len(graph.Nodes))
+	for _, n := range graph.Nodes {
+		trace := entities.Trace{Value: n.Value, Type: n.Type}
+		traces[n.ID] = trace
+
+		traceID, err := repo.GetOrCreateTrace(trace)
+		if err != nil {
+			return fmt.Errorf("failed to persist trace: %w", err)
+		}
+		if !seedEdges {
+			continue
+		}
+		if err := repo.InsertEdge(&database.TraceEdge{
+			ChildTraceID: traceID,
+			PluginName:   database.SeedPluginName,
+			ScanID:       scanID,
+			DiscoveredAt: time.Now(),
+		}); err != nil {
+			return fmt.Errorf("failed to persist seed edge: %w", err)
+		}
+	}
+
+	discoveries := make([]entities.Discovery, 0, len(graph.Edges))
+	for _, e := range graph.Edges {
+		discoveries = append(discoveries, entities.Discovery{
+			Parent:     traces[e.From],
+			PluginName: e.Plugin,
+			Child:      traces[e.To],
+		})
+	}
+	if err := repo.PersistDiscoveries(scanID, discoveries); err != nil {
+		return fmt.Errorf("failed to persist discoveries: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/export"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestRunImportMaltego_KeepsLinkStructure(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var buf bytes.Buffer
+	require.NoError(t, export.WriteMaltego(&buf, &export.Graph{
+		Nodes: []export.Node{
+			{ID: 1, Value: "example.com", Type: entities.Domain},
+			{ID: 2, Value: "www.example.com", Type: entities.Subdomain},
+			{ID: 3, Value: "admin@example.com", Type: entities.Email},
+		},
+		Edges: []export.Edge{{From: 1, To: 2, Plugin: "CrtShPlugin"}},
+	}))
+	path := filepath.Join(t.TempDir(), "case.mtgx")
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+	operator, engagement = "jsmith", "ENG-114"
+	t.Cleanup(func() { operator, engagement = "", "" })
+	require.NoError(t, runImportMaltego(path, false))
+
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+	session, err := repo.GetScanSession(1)
+	require.NoError(t, err)
+	require.NotNil(t, session)
+	assert.Equal(t, "maltego:case.mtgx", session.Input)
+	assert.Equal(t, "completed", session.Status)
+	assert.Equal(t, "jsmith", session.Operator)
+	assert.Equal(t, "ENG-114", session.Engagement)
+
+	nodes, edges, err := repo.GetScanGraph(session.ID)
+	require.NoError(t, err)
+	assert.Len(t, nodes, 3)
+	var seeds, links int
+	for _, e := range edges {
+		if e.PluginName == database.SeedPluginName {
+			seeds++
+		} else {
+			links++
+			assert.Equal(t, "CrtShPlugin", e.PluginName)
+		}
+	}
+	assert.Equal(t, 3, seeds, "every imported entity is a seed")
+	assert.Equal(t, 1, links)
+}
@@ -2,11 +2,15 @@ package cli
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"time"
 
 	"github.com/olekukonko/tablewriter"
+	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
 	"github.com/smirnoffmg/deeper/internal/pkg/metrics"
@@ -19,6 +23,10 @@ var (
 		Long: `Display comprehensive metrics and statistics about the application's performance,
 including trace processing, plugin execution, and system health.
 
+The counters cover this process only. To watch a long scan, "deeper watch
+run", "deeper serve" or "deeper ui", pass --metrics-addr to it and scrape
+/metrics there with Prometheus.
+
 Examples:
   deeper metrics
   deeper metrics --format json
@@ -36,6 +44,33 @@ func init() {
 	metricsCmd.Flags().BoolVar(&metricsLive, "live", false, "display live metrics updates")
 }
 
+// serveMetrics serves the collector at /metrics on addr, in the Prometheus
+// text format, until the returned func is called. An empty addr serves
+// nothing. The endpoint has no authentication, so keep it on localhost or
+// a private network.
+func serveMetrics(addr string, collector metrics.Collector) (func(), error) {
+	if addr == "" {
+		return func() {}, nil
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler(collector))
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error().Err(err).Msg("Metrics server stopped")
+		}
+	}()
+	log.Info().Msgf("Serving metrics on http://%s/metrics", listener.Addr())
+	return func() { _ = srv.Close() }, nil
+}
+
 func runMetrics(cmd *cobra.Command, args []string) error {
 	collector := metrics.GetGlobalMetrics()
 	summary := collector.GetSummary()
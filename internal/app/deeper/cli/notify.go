@@ -0,0 +1,137 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/watch"
+	"github.com/smirnoffmg/deeper/internal/pkg/config"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
+	"github.com/smirnoffmg/deeper/internal/pkg/notify"
+)
+
+var (
+	notifyCmd = &cobra.Command{
+		Use:   "notify",
+		Short: "Manage notification sinks",
+		Long: `Notification sinks push findings out as they happen: scans finishing,
+failing or running out of time, plugin failures, new high-confidence traces
+(emails, keys, names, domains and the like) and watch alerts.
+
+Sinks are configured in ~/.deeper/notify.json, or the file named by
+DEEPER_NOTIFY_CONFIG. Each is a generic webhook (a JSON POST signed with
+HMAC-SHA256 in X-Deeper-Signature when it has a secret), a Slack or
+Mattermost incoming webhook, SMTP email, an NDJSON file or a command that
+reads the notification on stdin. Each sink can filter by notification
+kind, trace type and plugin, and retries failed deliveries with backoff:
+
+  {"sinks": [
+    {"type": "webhook", "url": "https://siem.example/hook", "secret": "s3cret"},
+    {"type": "slack", "url": "https://hooks.slack.com/services/...",
+     "kinds": ["scan_finished", "watch_alert"]},
+    {"type": "email", "smtp": "smtp.example.com:587", "from": "deeper@example.com",
+     "to": ["soc@example.com"], "username": "deeper", "password": "...",
+     "kinds": ["trace_found"], "trace_types": ["ssh_key", "pgp_key"]},
+    {"type": "exec", "command": ["/usr/local/bin/page-oncall"],
+     "kinds": ["scan_failed"], "retries": 5, "backoff": "10s"}
+  ]}
+
+Kinds: scan_finished, scan_failed, budget_exhausted, plugin_failed,
+trace_found, watch_alert.`,
+	}
+
+	notifyTestCmd = &cobra.Command{
+		Use:   "test",
+		Short: "Send a test notification to every configured sink",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			notifier, err := createNotifier()
+			if err != nil {
+				return err
+			}
+			return testNotifier(cmd.Context(), os.Stdout, notifier)
+		},
+	}
+)
+
+func init() {
+	notifyCmd.AddCommand(notifyTestCmd)
+}
+
+// createNotifier loads the configured notification sinks.
+func createNotifier() (*notify.Notifier, error) {
+	path := config.LoadConfig().NotifyConfigPath
+	if path == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(homeDir, ".deeper", "notify.json")
+	}
+	return notify.Load(path)
+}
+
+// testNotifier sends a test notification to each sink, bypassing filters
+// and retries, and reports how each delivery went.
+func testNotifier(ctx context.Context, w io.Writer, notifier *notify.Notifier) error {
+	routes := notifier.Routes()
+	if len(routes) == 0 {
+		return fmt.Errorf("no notification sinks configured")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	note := notify.Notification{Kind: notify.Test, Time: time.Now(), Title: "Test notification"}
+	failed := 0
+	table := newGraphTable(w, []string{"Sink", "Result"})
+	for _, route := range routes {
+		result := "ok"
+		if err := route.Sink.Send(ctx, note); err != nil {
+			result = err.Error()
+			failed++
+		}
+		table.Append([]string{route.Sink.Name(), result})
+	}
+	table.Render()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sinks failed", failed, len(routes))
+	}
+	return nil
+}
+
+// scanFailedNotification reports a scan that ended in an error, which the
+// engine has no event for.
+func scanFailedNotification(session *database.ScanSession, err error) notify.Notification {
+	return notify.Notification{
+		Kind:   notify.ScanFailed,
+		Time:   time.Now(),
+		Title:  fmt.Sprintf("Scan %d of %s failed", session.ID, session.Input),
+		ScanID: session.ID,
+		Error:  err.Error(),
+	}
+}
+
+// alertNotifications turns a watch run's alerts into notifications.
+func alertNotifications(r watch.Result) []notify.Notification {
+	notes := make([]notify.Notification, 0, len(r.Alerts))
+	for _, a := range r.Alerts {
+		notes = append(notes, notify.Notification{
+			Kind:    notify.WatchAlert,
+			Time:    a.CreatedAt,
+			Title:   fmt.Sprintf("Watch #%d (%s): %s %s", a.WatchID, r.Watch.Input, a.Kind, a.Value),
+			ScanID:  a.ScanID,
+			Trace:   &events.TraceRef{Value: a.Value, Type: a.Type},
+			WatchID: a.WatchID,
+			Alert:   a.Kind,
+		})
+	}
+	return notes
+}
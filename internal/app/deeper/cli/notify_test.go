@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/watch"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/notify"
+)
+
+func TestTestNotifier(t *testing.T) {
+	var kinds []string
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		kinds = append(kinds, r.Header.Get(notify.EventHeader))
+	}))
+	defer ok.Close()
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer broken.Close()
+
+	var buf bytes.Buffer
+	assert.ErrorContains(t, testNotifier(context.Background(), &buf, notify.New()), "no notification sinks")
+
+	notifier, err := notify.FromConfig(notify.Config{Sinks: []notify.SinkConfig{
+		{Type: "webhook", Name: "siem", URL: ok.URL},
+		{Type: "slack", Name: "chat", URL: broken.URL, Retries: 5},
+	}})
+	require.NoError(t, err)
+	err = testNotifier(context.Background(), &buf, notifier)
+	assert.ErrorContains(t, err, "1 of 2 sinks failed")
+	assert.Equal(t, []string{notify.Test}, kinds)
+	assert.Contains(t, buf.String(), "siem")
+	assert.Contains(t, buf.String(), "502 Bad Gateway")
+}
+
+func TestCreateNotifier_UsesConfigPath(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DEEPER_NOTIFY_CONFIG", "")
+	notifier, err := createNotifier()
+	require.NoError(t, err)
+	assert.Empty(t, notifier.Routes())
+
+	t.Setenv("DEEPER_NOTIFY_CONFIG", t.TempDir())
+	_, err = createNotifier()
+	assert.Error(t, err)
+}
+
+func TestAlertNotifications(t *testing.T) {
+	r := watch.Result{
+		Watch: database.Watch{ID: 3, Input: "acme.com"},
+		Alerts: []database.WatchAlert{{
+			WatchID: 3, ScanID: 9, PreviousScanID: 7, Kind: database.AlertNewSubdomain,
+			Value: "vpn.acme.com", Type: entities.Subdomain, CreatedAt: time.Now(),
+		}},
+	}
+	notes := alertNotifications(r)
+	require.Len(t, notes, 1)
+	assert.Equal(t, notify.WatchAlert, notes[0].Kind)
+	assert.Equal(t, database.AlertNewSubdomain, notes[0].Alert)
+	assert.Equal(t, int64(9), notes[0].ScanID)
+	assert.Equal(t, entities.Subdomain, notes[0].Trace.Type)
+	assert.Contains(t, notes[0].Title, "vpn.acme.com")
+
+	failed := scanFailedNotification(&database.ScanSession{ID: 4, Input: "acme.com"}, assert.AnError)
+	assert.Equal(t, notify.ScanFailed, failed.Kind)
+	assert.Equal(t, assert.AnError.Error(), failed.Error)
+}
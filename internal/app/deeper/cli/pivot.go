@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/graphnav"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+var overlapAll bool
+
+// pivotTypes are the trace types that tie two subjects together when they
+// turn up in both investigations: each names one mailbox, host, key or
+// site owner rather than something many people share.
+var pivotTypes = map[entities.TraceType]bool{
+	entities.Email: true, entities.Phone: true, entities.IpAddr: true,
+	entities.SSHKey: true, entities.PGPKey: true, entities.AnalyticsID: true,
+	entities.BitcoinAddress: true, entities.PayPalAccount: true,
+	entities.Domain: true, entities.Subdomain: true, entities.Certificates: true,
+}
+
+// pivotCmd lists the scans that reached a trace
+var pivotCmd = &cobra.Command{
+	Use:   "pivot <trace>",
+	Short: "List every scan that reached a trace and how",
+	Long: `Pivot looks a trace up across every stored scan and shows, for each
+scan that reached it, the discovery chain from that scan's seed.
+
+A trace is given by value ("jdoe@example.com"), as "type:value" when the
+value alone is ambiguous ("username:jdoe"), or by ID ("#17").
+
+Examples:
+  deeper pivot jdoe@example.com
+  deeper pivot analytics_id:UA-12345678-1 --output json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return withRepo(func(repo *database.Repository) error {
+			return runPivot(repo, args[0])
+		})
+	},
+}
+
+// overlapCmd shows the traces two scans have in common
+var overlapCmd = &cobra.Command{
+	Use:   "overlap <scan-a> <scan-b>",
+	Short: "Show the traces two scans have in common",
+	Long: `Overlap compares two scans and lists the traces both reached. By
+default it shows the ones that tie two subjects together: emails, phone
+numbers, IPs, domains, SSH and PGP keys, certificates, payment accounts and
+analytics IDs. --all lists every shared trace.
+
+Examples:
+  deeper overlap 41 42
+  deeper overlap 41 42 --all --output json`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		scanIDs, err := parseScanIDs(args)
+		if err != nil {
+			return err
+		}
+		return withRepo(func(repo *database.Repository) error {
+			return runOverlap(repo, scanIDs[0], scanIDs[1], overlapAll)
+		})
+	},
+}
+
+func init() {
+	overlapCmd.Flags().BoolVar(&overlapAll, "all", false, "list every shared trace, not only strong pivots")
+}
+
+type pivotScanJSON struct {
+	ScanID    int64           `json:"scan_id"`
+	Input     string          `json:"input"`
+	StartedAt time.Time       `json:"started_at"`
+	Hop       int             `json:"hop"`
+	Path      []graphnav.Step `json:"path"`
+}
+
+func runPivot(repo *database.Repository, ref string) error {
+	trace, err := graphnav.Resolve(repo, ref)
+	if err != nil {
+		return err
+	}
+	sessions, err := repo.GetTraceScans(trace.ID)
+	if err != nil {
+		return err
+	}
+
+	scans := make([]pivotScanJSON, 0, len(sessions))
+	for _, session := range sessions {
+		nodes, edges, err := repo.GetScanGraph(session.ID)
+		if err != nil {
+			return fmt.Errorf("failed to load scan graph: %w", err)
+		}
+		ancestry, err := repo.GetDiscoveryPath(session.ID, trace.ID)
+		if err != nil {
+			return err
+		}
+		graph := graphnav.New(nodes, edges)
+		scans = append(scans, pivotScanJSON{
+			ScanID:    session.ID,
+			Input:     session.Input,
+			StartedAt: session.StartedAt,
+			Hop:       graph.Hop(trace.ID),
+			Path:      graph.Chain(trace.ID, ancestry),
+		})
+	}
+
+	switch output {
+	case "json":
+		return writeGraphJSON(os.Stdout, struct {
+			TraceID int64           `json:"trace_id"`
+			Value   string          `json:"value"`
+			Type    string          `json:"type"`
+			Scans   []pivotScanJSON `json:"scans"`
+		}{trace.ID, trace.Value, string(trace.Type), scans})
+	case "table":
+		if len(scans) == 0 {
+			fmt.Printf("No scan reached %s (%s)\n", trace.Value, trace.Type)
+			return nil
+		}
+		table := newGraphTable(os.Stdout, []string{"Scan", "Started", "Input", "Hops", "Path"})
+		for _, s := range scans {
+			table.Append([]string{
+				strconv.FormatInt(s.ScanID, 10),
+				s.StartedAt.Format("2006-01-02 15:04"),
+				s.Input,
+				strconv.Itoa(s.Hop),
+				describeChain(s.Path),
+			})
+		}
+		table.Render()
+		fmt.Printf("\n%s (%s) was reached by %d scans\n", trace.Value, trace.Type, len(scans))
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for pivot: %s", output)
+	}
+}
+
+// describeChain renders a discovery chain on one line, plugins in brackets.
+func describeChain(chain []graphnav.Step) string {
+	var b strings.Builder
+	for i, step := range chain {
+		if i > 0 {
+			fmt.Fprintf(&b, " -[%s]-> ", step.Plugin)
+		}
+		b.WriteString(step.Value)
+	}
+	return b.String()
+}
+
+type overlapTraceJSON struct {
+	TraceID int64  `json:"trace_id"`
+	Value   string `json:"value"`
+	Type    string `json:"type"`
+	HopA    int    `json:"hop_a"`
+	HopB    int    `json:"hop_b"`
+}
+
+func runOverlap(repo *database.Repository, scanA, scanB int64, all bool) error {
+	graphs := make([]*graphnav.Graph, 2)
+	for i, id := range []int64{scanA, scanB} {
+		session, err := repo.GetScanSession(id)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return fmt.Errorf("scan %d not found", id)
+		}
+		nodes, edges, err := repo.GetScanGraph(id)
+		if err != nil {
+			return fmt.Errorf("failed to load scan graph: %w", err)
+		}
+		graphs[i] = graphnav.New(nodes, edges)
+	}
+
+	shared, err := repo.GetSharedTraces(scanA, scanB)
+	if err != nil {
+		return err
+	}
+	traces := make([]overlapTraceJSON, 0, len(shared))
+	for _, t := range shared {
+		if !all && !pivotTypes[t.Type] {
+			continue
+		}
+		traces = append(traces, overlapTraceJSON{
+			TraceID: t.ID,
+			Value:   t.Value,
+			Type:    string(t.Type),
+			HopA:    graphs[0].Hop(t.ID),
+			HopB:    graphs[1].Hop(t.ID),
+		})
+	}
+	hidden := len(shared) - len(traces)
+
+	switch output {
+	case "json":
+		return writeGraphJSON(os.Stdout, struct {
+			ScanA  int64              `json:"scan_a"`
+			ScanB  int64              `json:"scan_b"`
+			Shared []overlapTraceJSON `json:"shared"`
+			Hidden int                `json:"hidden"`
+		}{scanA, scanB, traces, hidden})
+	case "table":
+		if len(traces) == 0 {
+			fmt.Printf("Scans %d and %d share no pivot traces", scanA, scanB)
+		} else {
+			table := newGraphTable(os.Stdout, []string{"Type", "Trace", fmt.Sprintf("Hops in %d", scanA), fmt.Sprintf("Hops in %d", scanB)})
+			for _, t := range traces {
+				table.Append([]string{t.Type, t.Value, strconv.Itoa(t.HopA), strconv.Itoa(t.HopB)})
+			}
+			table.Render()
+			fmt.Printf("\nScans %d and %d share %d traces", scanA, scanB, len(traces))
+		}
+		if hidden > 0 {
+			fmt.Printf(" (%d more of other types, see --all)", hidden)
+		}
+		fmt.Println()
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for overlap: %s", output)
+	}
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/graphnav"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestResolve_ResolvesReferencesAcrossDatabase(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+
+	userID, err := repo.GetOrCreateTrace(entities.Trace{Value: "jdoe", Type: entities.Username})
+	require.NoError(t, err)
+	_, err = repo.GetOrCreateTrace(entities.Trace{Value: "jdoe", Type: entities.Github})
+	require.NoError(t, err)
+
+	_, err = graphnav.Resolve(repo, "jdoe")
+	assert.ErrorContains(t, err, "ambiguous")
+
+	trace, err := graphnav.Resolve(repo, "username:jdoe")
+	require.NoError(t, err)
+	assert.Equal(t, userID, trace.ID)
+
+	trace, err = graphnav.Resolve(repo, "#"+strconv.FormatInt(userID, 10))
+	require.NoError(t, err)
+	assert.Equal(t, entities.Username, trace.Type)
+
+	_, err = graphnav.Resolve(repo, "nobody")
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestBuildOverlays_CutsPriorScanAroundSharedTraces(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+
+	alice := entities.Trace{Value: "alice", Type: entities.Username}
+	bob := entities.Trace{Value: "bob", Type: entities.Username}
+	email := entities.Trace{Value: "ops@example.com", Type: entities.Email}
+	domain := entities.Trace{Value: "example.com", Type: entities.Domain}
+	ip := entities.Trace{Value: "93.184.216.34", Type: entities.IpAddr}
+
+	record := func(seed entities.Trace, discoveries ...entities.Discovery) int64 {
+		session, err := repo.CreateScanSession(seed.Value)
+		require.NoError(t, err)
+		seedID, err := repo.GetOrCreateTrace(seed)
+		require.NoError(t, err)
+		require.NoError(t, repo.InsertEdge(&database.TraceEdge{
+			ChildTraceID: seedID, PluginName: database.SeedPluginName, ScanID: session.ID, DiscoveredAt: time.Now(),
+		}))
+		require.NoError(t, repo.PersistDiscoveries(session.ID, discoveries))
+		return session.ID
+	}
+
+	prior := record(alice,
+		entities.Discovery{Parent: alice, PluginName: "GitHubProfilePlugin", Child: email},
+		entities.Discovery{Parent: email, PluginName: "EmailDomainPlugin", Child: domain},
+		entities.Discovery{Parent: domain, PluginName: "DNSResolverPlugin", Child: ip},
+	)
+	current := record(bob,
+		entities.Discovery{Parent: bob, PluginName: "GitHubProfilePlugin", Child: email},
+	)
+
+	_, edges, err := repo.GetScanGraph(current)
+	require.NoError(t, err)
+	overlays, err := buildOverlays(repo, current, edges)
+	require.NoError(t, err)
+	require.Len(t, overlays, 1)
+
+	overlay := overlays[0]
+	assert.Equal(t, prior, overlay.ScanID)
+	assert.Equal(t, "alice", overlay.Input)
+	require.Len(t, overlay.Shared, 1)
+
+	var labels []string
+	for _, n := range overlay.Nodes {
+		labels = append(labels, n.Label)
+	}
+	assert.ElementsMatch(t, []string{"alice", "ops@example.com", "example.com"}, labels, "one hop around the shared email")
+	assert.Len(t, overlay.Edges, 2)
+}
@@ -46,10 +46,21 @@ var pluginsInfoCmd = &cobra.Command{
 
 // pluginsTypesCmd lists all supported trace types
 var pluginsTypesCmd = &cobra.Command{
-	Use:   "types",
+	Use:   "types [value]",
 	Short: "List all supported trace types",
-	Long:  `List all trace types that can be processed by the available plugins.`,
+	Long: `List all trace types that can be processed by the available plugins,
+along with the input shape that makes a scan seed be classified as each type.
+
+Given a value, explain how "deeper scan" would classify it instead.
+
+Examples:
+  deeper plugins types
+  deeper plugins types @handle`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 1 {
+			return explainClassification(args[0])
+		}
 		return listTraceTypes()
 	},
 }
@@ -140,35 +151,18 @@ func listTraceTypes() error {
 	fmt.Println("Supported Trace Types:")
 	fmt.Println("======================")
 
-	// Get all trace types from the entities package
-	allTraceTypes := []entities.TraceType{
-		entities.Email, entities.Phone, entities.Address, entities.IpAddr,
-		entities.Domain, entities.Url, entities.Username, entities.Name,
-		entities.Company, entities.Alias, entities.DateOfBirth, entities.Gender,
-		entities.Nationality, entities.MacAddr, entities.SSHKey, entities.PGPKey,
-		entities.BitcoinAddress, entities.PayPalAccount, entities.MedicalRecordNumber,
-		entities.InsurancePolicy, entities.ExifData, entities.FileTimestamp,
-		entities.Geolocation, entities.ForumRegistrations, entities.CommentsAndPosts,
-		entities.NewsMentions, entities.CourtRecords, entities.Patents,
-		entities.Publications, entities.EducationalInstitution, entities.Workplace,
-		entities.Certificates, entities.ConferenceParticipation, entities.SocialGeneric,
-		entities.Twitter, entities.Github, entities.Linkedin, entities.Instagram,
-		entities.Facebook, entities.TikTok, entities.Reddit, entities.YouTube,
-		entities.Pinterest, entities.Snapchat, entities.Tumblr, entities.Repository,
-		entities.DnsRecordA, entities.DnsRecordAAAA, entities.DnsRecordMX,
-		entities.DnsRecordNS, entities.DnsRecordTXT, entities.DnsRecordCNAME,
-		entities.DnsRecordSOA, entities.DnsRecordPTR, entities.DnsRecordSRV,
-		entities.DnsRecordCAA, entities.Whois, entities.Subdomain, entities.ASN,
-		entities.Netblock, entities.Host, entities.IPRange,
+	patterns := make(map[entities.TraceType][]string)
+	for _, c := range entities.Classifiers() {
+		patterns[c.Type] = append(patterns[c.Type], c.Description)
 	}
 
 	// Create table showing which trace types have plugins
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"Trace Type", "Status", "Plugin Count"})
+	table.SetHeader([]string{"Trace Type", "Status", "Plugin Count", "Detected From Input"})
 	table.SetBorder(true)
 
 	supported := 0
-	for _, traceType := range allTraceTypes {
+	for _, traceType := range entities.AllTraceTypes {
 		pluginCount := len(state.ActivePlugins[traceType])
 		status := "❌ Not Supported"
 		if pluginCount > 0 {
@@ -180,11 +174,51 @@ func listTraceTypes() error {
 			string(traceType),
 			status,
 			fmt.Sprintf("%d", pluginCount),
+			strings.Join(patterns[traceType], "; "),
 		})
 	}
 
 	table.Render()
 
-	fmt.Printf("\nSummary: %d/%d trace types have plugin support\n", supported, len(allTraceTypes))
+	fmt.Printf("\nSummary: %d/%d trace types have plugin support\n", supported, len(entities.AllTraceTypes))
+	fmt.Println("Types without a detected input shape can still be scanned as \"type:value\" or with --type.")
+	fmt.Println("Unrecognized input defaults to username.")
+	return nil
+}
+
+// explainClassification shows every classifier a value matches and the
+// seed traces "deeper scan" would start from for it.
+func explainClassification(value string) error {
+	fmt.Printf("Classification of %q:\n", value)
+	fmt.Println("======================")
+
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader([]string{"Trace Type", "Matches", "Pattern"})
+	table.SetBorder(true)
+	for _, c := range entities.Classifiers() {
+		match := ""
+		if c.Matches(value) {
+			match = "✅"
+		}
+		table.Append([]string{string(c.Type), match, c.Description})
+	}
+	table.Render()
+
+	seeds, err := entities.ParseSeeds(value)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	for _, seed := range seeds {
+		note := ""
+		if seed.Guessed {
+			note = " (guess)"
+		}
+		fmt.Printf("Seed: %s%s\n", seed.Trace, note)
+	}
+	if len(seeds) > 1 {
+		fmt.Printf("Ambiguous: scanning as %d types. Use \"type:value\" or --type to pick one.\n", len(seeds))
+	}
 	return nil
 }
@@ -0,0 +1,219 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
+)
+
+// progressRedrawInterval caps how often the live view repaints; plugin
+// events arrive in bursts of hundreds and repainting each one just flickers.
+const progressRedrawInterval = 200 * time.Millisecond
+
+// streamNDJSON writes every event from ch to w as one JSON object per line
+// until ch is closed. Each line is flushed as it is written so `deeper scan
+// --output ndjson | jq` sees discoveries while the scan runs.
+func streamNDJSON(w io.Writer, ch <-chan events.Event) error {
+	encoder := json.NewEncoder(w)
+	var firstErr error
+	for ev := range ch {
+		// Keep draining after a write error (e.g. a closed pipe): the bus
+		// blocks publishers until subscribers accept each event.
+		if firstErr != nil {
+			continue
+		}
+		if err := encoder.Encode(ev); err != nil {
+			firstErr = fmt.Errorf("failed to write event: %w", err)
+		}
+	}
+	return firstErr
+}
+
+// pluginProgress is one plugin's row in the progress view.
+type pluginProgress struct {
+	running  int
+	finished int
+	failed   int
+	found    int
+}
+
+// scanProgress accumulates running counts from a scan's events.
+type scanProgress struct {
+	started   time.Time
+	traces    int
+	byType    map[entities.TraceType]int
+	byPlugin  map[string]*pluginProgress
+	exhausted bool
+}
+
+func newScanProgress() *scanProgress {
+	return &scanProgress{
+		started:  time.Now(),
+		byType:   make(map[entities.TraceType]int),
+		byPlugin: make(map[string]*pluginProgress),
+	}
+}
+
+func (p *scanProgress) plugin(name string) *pluginProgress {
+	pp, ok := p.byPlugin[name]
+	if !ok {
+		pp = &pluginProgress{}
+		p.byPlugin[name] = pp
+	}
+	return pp
+}
+
+func (p *scanProgress) apply(ev events.Event) {
+	switch ev.Type {
+	case events.TraceDiscovered:
+		p.traces++
+		if ev.Trace != nil {
+			p.byType[ev.Trace.Type]++
+		}
+	case events.PluginStarted:
+		p.plugin(ev.Plugin).running++
+	case events.PluginFinished:
+		pp := p.plugin(ev.Plugin)
+		pp.running--
+		pp.finished++
+		pp.found += ev.Count
+	case events.PluginFailed:
+		pp := p.plugin(ev.Plugin)
+		pp.running--
+		pp.failed++
+	case events.BudgetExhausted:
+		p.exhausted = true
+	}
+}
+
+// render draws the summary line and two tables: traces by type and plugin
+// activity, each sorted by count so the busiest rows stay on top.
+func (p *scanProgress) render(w io.Writer) {
+	status := ""
+	if p.exhausted {
+		status = " (budget exhausted, finishing up)"
+	}
+	_, _ = fmt.Fprintf(w, "Scanning for %s: %d traces found%s\n",
+		time.Since(p.started).Round(time.Second), p.traces, status)
+
+	if len(p.byType) > 0 {
+		types := make([]string, 0, len(p.byType))
+		for t := range p.byType {
+			types = append(types, string(t))
+		}
+		sort.Slice(types, func(i, j int) bool {
+			ci, cj := p.byType[entities.TraceType(types[i])], p.byType[entities.TraceType(types[j])]
+			if ci != cj {
+				return ci > cj
+			}
+			return types[i] < types[j]
+		})
+
+		table := tablewriter.NewWriter(w)
+		table.SetHeader([]string{"Trace Type", "Found"})
+		for _, t := range types {
+			table.Append([]string{t, fmt.Sprintf("%d", p.byType[entities.TraceType(t)])})
+		}
+		table.Render()
+	}
+
+	if len(p.byPlugin) > 0 {
+		names := make([]string, 0, len(p.byPlugin))
+		for name := range p.byPlugin {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			ri, rj := p.byPlugin[names[i]], p.byPlugin[names[j]]
+			if ri.finished+ri.failed != rj.finished+rj.failed {
+				return ri.finished+ri.failed > rj.finished+rj.failed
+			}
+			return names[i] < names[j]
+		})
+
+		table := tablewriter.NewWriter(w)
+		table.SetHeader([]string{"Plugin", "Running", "Done", "Failed", "Traces"})
+		for _, name := range names {
+			pp := p.byPlugin[name]
+			table.Append([]string{
+				name,
+				fmt.Sprintf("%d", max(pp.running, 0)),
+				fmt.Sprintf("%d", pp.finished),
+				fmt.Sprintf("%d", pp.failed),
+				fmt.Sprintf("%d", pp.found),
+			})
+		}
+		table.Render()
+	}
+}
+
+// liveProgress repaints a scanProgress in place on a terminal. It is also
+// an io.Writer for the logger: a log line erases the current frame, prints
+// above it and repaints, so logs and the live view don't garble each other.
+type liveProgress struct {
+	mu       sync.Mutex
+	out      io.Writer
+	progress *scanProgress
+	lines    int // height of the frame currently on screen
+	lastDraw time.Time
+}
+
+func newLiveProgress(out io.Writer) *liveProgress {
+	return &liveProgress{out: out, progress: newScanProgress()}
+}
+
+// consume applies events from ch until it is closed, then leaves the final
+// frame on screen.
+func (l *liveProgress) consume(ch <-chan events.Event) {
+	for ev := range ch {
+		l.mu.Lock()
+		l.progress.apply(ev)
+		if time.Since(l.lastDraw) >= progressRedrawInterval {
+			l.redrawLocked()
+		}
+		l.mu.Unlock()
+	}
+
+	l.mu.Lock()
+	l.redrawLocked()
+	l.mu.Unlock()
+}
+
+// Write implements io.Writer for log output.
+func (l *liveProgress) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.clearLocked()
+	n, err := l.out.Write(p)
+	l.redrawLocked()
+	return n, err
+}
+
+func (l *liveProgress) clearLocked() {
+	if l.lines > 0 {
+		// Move the cursor to the frame's first line and clear to the end
+		// of the screen.
+		_, _ = fmt.Fprintf(l.out, "\x1b[%dA\x1b[J", l.lines)
+		l.lines = 0
+	}
+}
+
+func (l *liveProgress) redrawLocked() {
+	var frame bytes.Buffer
+	l.progress.render(&frame)
+
+	l.clearLocked()
+	_, _ = l.out.Write(frame.Bytes())
+	l.lines = strings.Count(frame.String(), "\n")
+	l.lastDraw = time.Now()
+}
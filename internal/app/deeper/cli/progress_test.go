@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
+)
+
+func TestStreamNDJSON_OneObjectPerLine(t *testing.T) {
+	ch := make(chan events.Event, 2)
+	ch <- events.Event{Type: events.TraceDiscovered, ScanID: 7, Trace: events.Ref(entities.Trace{Value: "a@b.com", Type: entities.Email})}
+	ch <- events.Event{Type: events.PluginFailed, ScanID: 7, Plugin: "p", Error: "boom"}
+	close(ch)
+
+	var buf bytes.Buffer
+	require.NoError(t, streamNDJSON(&buf, ch))
+
+	scanner := bufio.NewScanner(&buf)
+	var lines []map[string]any
+	for scanner.Scan() {
+		var obj map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &obj))
+		lines = append(lines, obj)
+	}
+	require.Len(t, lines, 2)
+	assert.Equal(t, "trace_discovered", lines[0]["type"])
+	assert.Equal(t, map[string]any{"value": "a@b.com", "type": "email"}, lines[0]["trace"])
+	assert.NotContains(t, lines[0], "error", "empty fields are omitted")
+	assert.Equal(t, "boom", lines[1]["error"])
+}
+
+func TestScanProgress_CountsByTypeAndPlugin(t *testing.T) {
+	p := newScanProgress()
+	email := events.Ref(entities.Trace{Value: "a@b.com", Type: entities.Email})
+	for _, ev := range []events.Event{
+		{Type: events.TraceDiscovered, Trace: email},
+		{Type: events.PluginStarted, Plugin: "GravatarPlugin", Trace: email},
+		{Type: events.PluginStarted, Plugin: "WhoisPlugin", Trace: email},
+		{Type: events.PluginFinished, Plugin: "GravatarPlugin", Trace: email, Count: 3},
+		{Type: events.PluginFailed, Plugin: "WhoisPlugin", Trace: email, Error: "timeout"},
+		{Type: events.BudgetExhausted},
+	} {
+		p.apply(ev)
+	}
+
+	assert.Equal(t, 1, p.traces)
+	assert.Equal(t, 1, p.byType[entities.Email])
+	assert.Equal(t, pluginProgress{finished: 1, found: 3}, *p.byPlugin["GravatarPlugin"])
+	assert.Equal(t, pluginProgress{failed: 1}, *p.byPlugin["WhoisPlugin"])
+
+	var buf bytes.Buffer
+	p.render(&buf)
+	out := buf.String()
+	assert.Contains(t, out, "1 traces found")
+	assert.Contains(t, out, "budget exhausted")
+	assert.Contains(t, out, "GravatarPlugin")
+	assert.Contains(t, strings.ToLower(out), "trace type")
+}
+
+func TestLiveProgress_LogLinesPrintAboveFrame(t *testing.T) {
+	var buf bytes.Buffer
+	live := newLiveProgress(&buf)
+
+	ch := make(chan events.Event, 1)
+	ch <- events.Event{Type: events.TraceDiscovered, Trace: events.Ref(entities.Trace{Value: "x", Type: entities.Username})}
+	close(ch)
+	live.consume(ch)
+	require.Positive(t, live.lines)
+
+	_, err := live.Write([]byte("log line\n"))
+	require.NoError(t, err)
+
+	out := buf.String()
+	logAt := strings.Index(out, "log line")
+	require.NotEqual(t, -1, logAt)
+	assert.Contains(t, out[:logAt], "\x1b[", "frame is erased before the log line")
+	assert.Contains(t, out[logAt:], "1 traces found", "frame is redrawn after it")
+}
@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/report"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+)
+
+var (
+	reportFormat        string
+	reportOut           string
+	reportTemplate      string
+	reportPrintTemplate bool
+	reportCase          string
+)
+
+// reportCmd renders the narrative report of a scan
+var reportCmd = &cobra.Command{
+	Use:   "report <scan-id> | --case <case>",
+	Short: "Write an investigation report for a scan",
+	Long: `Report turns a scan into a deliverable document: an executive summary,
+the seed, identities and infrastructure found, a breakdown by trace type,
+the discovery path of each key finding, plugin coverage and an appendix of
+every trace. --case reports on a whole case instead: the union of its
+scans, with the case's owner, authorization, scans and notes.
+
+Reports are Markdown or standalone HTML (print the HTML to get a PDF). The
+format follows --format, or else the --out extension. Both are rendered
+from Go templates that can be replaced to brand the report: pass
+--template, or put report.md.tmpl / report.html.tmpl in
+~/.deeper/templates. --print-template prints the built-in template to
+start from.
+
+Examples:
+  deeper report 42 > report.md
+  deeper report 42 --out report.html
+  deeper report --case acme-2026 -o acme.html
+  deeper report --print-template --format html > ~/.deeper/templates/report.html.tmpl
+  deeper report 42 --format html --template acme.html.tmpl -o acme-42.html`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if reportPrintTemplate || reportCase != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format := report.Format(reportFormat)
+		if !cmd.Flags().Changed("format") && reportOut != "" {
+			format = report.FormatFromPath(reportOut)
+		}
+
+		if reportPrintTemplate {
+			text, err := report.DefaultTemplate(format)
+			if err != nil {
+				return err
+			}
+			fmt.Print(text)
+			return nil
+		}
+
+		if reportCase != "" {
+			return runReport(0, reportCase, format, reportTemplate, reportOut)
+		}
+		scanIDs, err := parseScanIDs(args)
+		if err != nil {
+			return err
+		}
+		return runReport(scanIDs[0], "", format, reportTemplate, reportOut)
+	},
+}
+
+func init() {
+	reportCmd.Flags().StringVar(&reportFormat, "format", string(report.FormatMarkdown), "report format (markdown, html)")
+	reportCmd.Flags().StringVarP(&reportOut, "out", "o", "", "write to this file instead of stdout")
+	reportCmd.Flags().StringVar(&reportTemplate, "template", "", "render with this template instead of the built-in one")
+	reportCmd.Flags().StringVar(&reportCase, "case", "", "report on every scan of this case")
+	reportCmd.Flags().BoolVar(&reportPrintTemplate, "print-template", false, "print the built-in template for --format and exit")
+}
+
+// runReport writes the report of a scan, or of the case caseRef names.
+func runReport(scanID int64, caseRef string, format report.Format, templatePath, outPath string) (err error) {
+	text, err := reportTemplateText(format, templatePath)
+	if err != nil {
+		return err
+	}
+
+	db, err := createDatabase()
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() { err = errors.Join(err, db.Close()) }()
+
+	repo := database.NewRepository(db)
+	var data *report.Data
+	if caseRef != "" {
+		c, err := findCase(repo, caseRef)
+		if err != nil {
+			return err
+		}
+		data, err = report.LoadCase(repo, c)
+		if err != nil {
+			return err
+		}
+	} else {
+		if data, err = report.Load(repo, scanID); err != nil {
+			return err
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if outPath != "" {
+		f, err := os.Create(outPath)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %w", outPath, err)
+		}
+		defer func() { _ = f.Close() }()
+		w = f
+	}
+
+	if err := report.Render(w, data, format, text); err != nil {
+		return err
+	}
+	if outPath != "" {
+		log.Info().Msgf("Report written to %s", outPath)
+	}
+	return nil
+}
+
+// reportTemplateText returns the template to render with: the given file,
+// else the user's override in ~/.deeper/templates, else "" for the
+// built-in one.
+func reportTemplateText(format report.Format, templatePath string) (string, error) {
+	if templatePath != "" {
+		b, err := os.ReadFile(templatePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read template: %w", err)
+		}
+		return string(b), nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", nil
+	}
+	b, err := os.ReadFile(filepath.Join(homeDir, ".deeper", "templates", report.TemplateName(format)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read template: %w", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,199 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/config"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+var (
+	retentionDryRun bool
+	eraseTrace      string
+	eraseType       string
+	eraseReference  string
+	eraseDryRun     bool
+)
+
+var (
+	databaseRetentionCmd = &cobra.Command{
+		Use:   "retention",
+		Short: "Delete scans and cached results older than the retention policy",
+		Long: `Retention deletes scans older than their retention period, with their
+edges, identities, alerts and audit logs, the traces no remaining scan
+reaches, and cached plugin results older than the cache period. It leaves
+a tombstone recording what was deleted and vacuums the database so the
+data is gone from disk.
+
+Periods are set in ~/.deeper/retention.json, or the file named by
+DEEPER_RETENTION_CONFIG; without one everything is kept. Scans of a case
+named by a policy are kept for its period, scans of a case with a tag
+named by a policy for the longest such period, and other scans for
+"days". A period of 0 keeps scans forever, e.g. under legal hold:
+
+  {
+    "days": 90,
+    "cache_days": 7,
+    "policies": [
+      {"case": "acme-dd", "days": 365},
+      {"tag": "legal-hold", "days": 0}
+    ]
+  }
+
+Examples:
+  deeper db retention --dry-run
+  deeper db retention`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadRetentionConfig()
+			if err != nil {
+				return err
+			}
+			return withRepo(func(repo *database.Repository) error {
+				return runRetention(os.Stdout, repo, cfg, time.Now(), retentionDryRun)
+			})
+		},
+	}
+
+	databaseEraseCmd = &cobra.Command{
+		Use:   "erase --trace <value>",
+		Short: "Erase a data subject's traces from every scan",
+		Long: `Erase removes a data subject's traces and everything reachable only
+through them, across all scans. Scans of the subject are deleted outright;
+in other scans, what was also found another way is kept. The subject's
+case seeds, watches and cached plugin results go too.
+
+Erasure leaves a tombstone with the --reference of the request, the
+--operator, and how much was deleted, but not the subject, and vacuums
+the database so the data is gone from disk. Audit logs of scans that are
+kept stay intact as the record of what was contacted.
+
+Examples:
+  deeper db erase --trace jdoe@acme.com --reference DSAR-2031 --dry-run
+  deeper db erase --trace jdoe --type username --reference DSAR-2031`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if eraseTrace == "" {
+				return fmt.Errorf("--trace is required")
+			}
+			req := database.Erasure{
+				Value:     eraseTrace,
+				Type:      entities.TraceType(eraseType),
+				Reference: eraseReference,
+				Operator:  scanOperator(),
+			}
+			return withRepo(func(repo *database.Repository) error {
+				return runErase(os.Stdout, repo, req, time.Now(), eraseDryRun)
+			})
+		},
+	}
+
+	databaseTombstonesCmd = &cobra.Command{
+		Use:   "tombstones",
+		Short: "List the records left by retention and erasure",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withRepo(func(repo *database.Repository) error {
+				return runTombstones(os.Stdout, repo)
+			})
+		},
+	}
+)
+
+func init() {
+	databaseRetentionCmd.Flags().BoolVar(&retentionDryRun, "dry-run", false, "report what would be deleted without deleting it")
+	databaseEraseCmd.Flags().StringVar(&eraseTrace, "trace", "", "the data subject's trace value")
+	databaseEraseCmd.Flags().StringVar(&eraseType, "type", "", "only erase the value as this trace type")
+	databaseEraseCmd.Flags().StringVar(&eraseReference, "reference", "", "the erasure request being answered, kept in the tombstone")
+	databaseEraseCmd.Flags().BoolVar(&eraseDryRun, "dry-run", false, "report what would be erased without erasing it")
+	databaseCmd.AddCommand(databaseRetentionCmd)
+	databaseCmd.AddCommand(databaseEraseCmd)
+	databaseCmd.AddCommand(databaseTombstonesCmd)
+}
+
+func loadRetentionConfig() (*database.RetentionConfig, error) {
+	path := config.LoadConfig().RetentionConfigPath
+	if path == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(homeDir, ".deeper", "retention.json")
+	}
+	return database.LoadRetentionConfig(path)
+}
+
+func runRetention(w io.Writer, repo *database.Repository, cfg *database.RetentionConfig, now time.Time, dryRun bool) error {
+	tomb, err := repo.ApplyRetention(cfg, now, dryRun)
+	if err != nil {
+		return err
+	}
+	if tomb == nil {
+		_, _ = fmt.Fprintln(w, "Nothing has expired")
+		return nil
+	}
+	return reportDeletion(w, repo, tomb, dryRun)
+}
+
+func runErase(w io.Writer, repo *database.Repository, req database.Erasure, now time.Time, dryRun bool) error {
+	tomb, err := repo.EraseTrace(req, now, dryRun)
+	if err != nil {
+		return err
+	}
+	return reportDeletion(w, repo, tomb, dryRun)
+}
+
+// reportDeletion prints what a tombstone records and, unless this was a
+// dry run, vacuums the database.
+func reportDeletion(w io.Writer, repo *database.Repository, tomb *database.Tombstone, dryRun bool) error {
+	verb := "Deleted"
+	if dryRun {
+		verb = "Would delete"
+	}
+	_, _ = fmt.Fprintf(w, "%s %d scans, %d traces, %d edges and %d cache entries\n",
+		verb, len(tomb.Scans), tomb.Traces, tomb.Edges, tomb.CacheEntries)
+	if dryRun {
+		return nil
+	}
+	if err := repo.Vacuum(); err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "Recorded tombstone %d\n", tomb.ID)
+	return nil
+}
+
+func runTombstones(w io.Writer, repo *database.Repository) error {
+	tombstones, err := repo.GetTombstones()
+	if err != nil {
+		return err
+	}
+	if len(tombstones) == 0 {
+		_, _ = fmt.Fprintln(w, "No tombstones")
+		return nil
+	}
+
+	table := newGraphTable(w, []string{"ID", "Kind", "When", "Operator", "Reference", "Scans", "Traces", "Edges", "Cache"})
+	for _, t := range tombstones {
+		table.Append([]string{
+			strconv.FormatInt(t.ID, 10),
+			t.Kind,
+			t.CreatedAt.Format(time.RFC3339),
+			t.Operator,
+			t.Reference,
+			strconv.Itoa(len(t.Scans)),
+			strconv.Itoa(t.Traces),
+			strconv.Itoa(t.Edges),
+			strconv.Itoa(t.CacheEntries),
+		})
+	}
+	table.Render()
+	return nil
+}
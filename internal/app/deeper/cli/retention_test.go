@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestRetentionCommands(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+
+	session, err := repo.CreateScanSession("jdoe@acme.com")
+	require.NoError(t, err)
+	subject := entities.Trace{Value: "jdoe@acme.com", Type: entities.Email}
+	require.NoError(t, repo.PersistDiscoveries(session.ID, []entities.Discovery{
+		{Parent: subject, PluginName: "GravatarPlugin", Child: entities.Trace{Value: "jdoe", Type: entities.Username}},
+	}))
+
+	cfg, err := loadRetentionConfig()
+	require.NoError(t, err)
+	var buf bytes.Buffer
+	require.NoError(t, runRetention(&buf, repo, cfg, time.Now(), false))
+	assert.Equal(t, "Nothing has expired\n", buf.String())
+
+	req := database.Erasure{Value: "jdoe@acme.com", Reference: "DSAR-2031", Operator: "dpo"}
+	buf.Reset()
+	require.NoError(t, runErase(&buf, repo, req, time.Now(), true))
+	assert.Equal(t, "Would delete 1 scans, 2 traces, 1 edges and 0 cache entries\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, runErase(&buf, repo, req, time.Now(), false))
+	assert.Contains(t, buf.String(), "Deleted 1 scans, 2 traces, 1 edges and 0 cache entries\nRecorded tombstone 1\n")
+	assert.Error(t, runErase(&buf, repo, req, time.Now(), false))
+
+	buf.Reset()
+	require.NoError(t, runTombstones(&buf, repo))
+	assert.Contains(t, buf.String(), "DSAR-2031")
+	assert.NotContains(t, buf.String(), "jdoe")
+}
@@ -1,9 +1,9 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"path/filepath"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -18,13 +18,18 @@ import (
 )
 
 var (
-	cfgFile     string
-	logLevel    string
-	timeout     time.Duration
-	concurrency int
-	rateLimit   int
-	output      string
-	verbose     bool
+	cfgFile      string
+	logLevel     string
+	timeout      time.Duration
+	concurrency  int
+	rateLimit    int
+	output       string
+	verbose      bool
+	metricsAddr  string
+	otelEndpoint string
+	otelFile     string
+	operator     string
+	engagement   string
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -43,14 +48,18 @@ Examples:
   deeper plugins list
   deeper health`,
 	Args: cobra.MinimumNArgs(0),
-	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 		setupLogging()
+		return setupTelemetry()
 	},
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	err = errors.Join(err, closeEngineDatabase())
+	stopTelemetry()
+	if err != nil {
 		log.Error().Err(err).Msg("Command execution failed")
 		os.Exit(1)
 	}
@@ -65,8 +74,13 @@ func init() {
 	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 5*time.Minute, "operation timeout")
 	rootCmd.PersistentFlags().IntVar(&concurrency, "concurrency", 10, "maximum concurrent operations")
 	rootCmd.PersistentFlags().IntVar(&rateLimit, "rate-limit", 5, "requests per second")
-	rootCmd.PersistentFlags().StringVar(&output, "output", "table", "output format (table, json, csv)")
+	rootCmd.PersistentFlags().StringVar(&output, "output", "table", "output format (table, json, csv, ndjson)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().StringVar(&operator, "operator", "", "who scans run on behalf of, recorded in their audit log (default the current user)")
+	rootCmd.PersistentFlags().StringVar(&engagement, "engagement", "", "engagement or ticket reference authorizing scans, recorded in their audit log")
+	rootCmd.PersistentFlags().StringVar(&otelEndpoint, "otel-endpoint", "", "export OpenTelemetry spans to this OTLP/HTTP collector, e.g. http://localhost:4318")
+	rootCmd.PersistentFlags().StringVar(&otelFile, "otel-file", "", "append OpenTelemetry spans to this file as OTLP JSON")
+	rootCmd.PersistentFlags().StringVar(&metricsAddr, "metrics-addr", "", "serve Prometheus metrics at /metrics on this address during scan, watch run, serve and ui")
 
 	// Add subcommands
 	rootCmd.AddCommand(scanCmd)
@@ -75,6 +89,21 @@ func init() {
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(metricsCmd)
 	rootCmd.AddCommand(databaseCmd)
+	rootCmd.AddCommand(identitiesCmd)
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(reportCmd)
+	rootCmd.AddCommand(graphCmd)
+	rootCmd.AddCommand(pivotCmd)
+	rootCmd.AddCommand(overlapCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(caseCmd)
+	rootCmd.AddCommand(verdictCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(notifyCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(auditCmd)
+	rootCmd.AddCommand(uiCmd)
 }
 
 func initConfig() {
@@ -111,16 +140,11 @@ func createEngine() (*engine.Engine, *database.Repository, error) {
 
 	metricsCollector := metrics.GetGlobalMetrics()
 
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get home directory: %w", err)
-	}
-
-	dbPath := filepath.Join(homeDir, ".deeper", "deeper.db")
-	db, err := database.NewDatabase(dbPath)
+	db, err := createDatabase()
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create database: %w", err)
 	}
+	closeEngineDatabase = db.Close
 
 	repo := database.NewRepository(db)
 	cache := database.NewCache(repo)
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+)
+
+var (
+	scanShowCmd = &cobra.Command{
+		Use:   "show <scan-id>",
+		Short: "Show a scan with the coverage of each plugin",
+		Long: `Show summarizes a scan and every plugin run in it: how often each
+plugin answered with traces, answered with nothing, failed, was held back
+by an open circuit breaker or a rate limit, turned the trace down, or was
+answered from the cache. A plugin whose runs failed found nothing because
+it broke, not because there was nothing to find; "deeper scan errors"
+lists why.
+
+Examples:
+  deeper scan show 42
+  deeper scan show 42 --output json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scanID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid scan id %q: %w", args[0], err)
+			}
+			return withRepo(func(repo *database.Repository) error {
+				return showScan(os.Stdout, repo, scanID, output)
+			})
+		},
+	}
+
+	scanErrorsCmd = &cobra.Command{
+		Use:   "errors <scan-id>",
+		Short: "List the plugin runs that failed in a scan",
+		Long: `Errors lists each plugin run in a scan that failed, was refused by an
+open circuit breaker or gave up on a rate limit, with the trace it ran
+against and why.
+
+Examples:
+  deeper scan errors 42
+  deeper scan errors 42 --output json | jq -r '.[].error_type' | sort | uniq -c`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			scanID, err := strconv.ParseInt(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid scan id %q: %w", args[0], err)
+			}
+			return withRepo(func(repo *database.Repository) error {
+				return showScanErrors(os.Stdout, repo, scanID, output)
+			})
+		},
+	}
+)
+
+func init() {
+	scanCmd.AddCommand(scanShowCmd)
+	scanCmd.AddCommand(scanErrorsCmd)
+}
+
+// failedRunOutcomes are the run outcomes "deeper scan errors" lists.
+var failedRunOutcomes = []string{database.RunError, database.RunCircuitOpen, database.RunRateLimited}
+
+func findScan(repo *database.Repository, scanID int64) (*database.ScanSession, error) {
+	session, err := repo.GetScanSession(scanID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, fmt.Errorf("scan %d not found", scanID)
+	}
+	return session, nil
+}
+
+func showScan(w io.Writer, repo *database.Repository, scanID int64, format string) error {
+	session, err := findScan(repo, scanID)
+	if err != nil {
+		return err
+	}
+	coverage, err := repo.GetPluginCoverage(scanID)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		return writeGraphJSON(w, struct {
+			Scan     *database.ScanSession     `json:"scan"`
+			Coverage []database.PluginCoverage `json:"coverage"`
+		}{session, coverage})
+	case "table":
+		_, _ = fmt.Fprintf(w, "Scan #%d %s (%s), started %s\n", session.ID, session.Input, session.Status,
+			session.StartedAt.Format("2006-01-02 15:04"))
+		if session.CompletedAt != nil {
+			_, _ = fmt.Fprintf(w, "Completed: %s\n", session.CompletedAt.Format("2006-01-02 15:04"))
+		}
+		if session.Operator != "" || session.Engagement != "" {
+			_, _ = fmt.Fprintf(w, "Operator: %s\nEngagement: %s\n", orNone(session.Operator), orNone(session.Engagement))
+		}
+		_, _ = fmt.Fprintf(w, "Traces: %d\nPlugin errors: %d\n", session.UniqueTraces, session.Errors)
+
+		if len(coverage) == 0 {
+			_, _ = fmt.Fprintln(w, "\nNo plugin runs recorded")
+			return nil
+		}
+		_, _ = fmt.Fprintln(w)
+		header := []string{"Plugin", "Runs"}
+		header = append(header, database.RunOutcomes...)
+		header = append(header, "Traces", "Time")
+		table := newGraphTable(w, header)
+		failures := 0
+		for _, c := range coverage {
+			row := []string{c.PluginName, strconv.Itoa(c.Runs)}
+			for _, outcome := range database.RunOutcomes {
+				row = append(row, strconv.Itoa(c.Outcomes[outcome]))
+			}
+			row = append(row, strconv.Itoa(c.Children), (time.Duration(c.DurationMS) * time.Millisecond).String())
+			table.Append(row)
+			failures += c.Failures()
+		}
+		table.Render()
+		if failures > 0 {
+			_, _ = fmt.Fprintf(w, "%d plugin runs failed, see \"deeper scan errors %d\"\n", failures, session.ID)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for scan show: %s", format)
+	}
+}
+
+func showScanErrors(w io.Writer, repo *database.Repository, scanID int64, format string) error {
+	if _, err := findScan(repo, scanID); err != nil {
+		return err
+	}
+	runs, err := repo.GetPluginRuns(scanID, failedRunOutcomes...)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case "json":
+		if runs == nil {
+			runs = []database.PluginRun{}
+		}
+		return writeGraphJSON(w, runs)
+	case "table":
+		if len(runs) == 0 {
+			_, _ = fmt.Fprintf(w, "No failed plugin runs in scan %d\n", scanID)
+			return nil
+		}
+		table := newGraphTable(w, []string{"Trace", "Type", "Plugin", "Outcome", "Error type", "Error"})
+		for _, run := range runs {
+			table.Append([]string{run.Value, string(run.Type), run.PluginName, run.Outcome, orNone(run.ErrorType), run.Error})
+		}
+		table.Render()
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for scan errors: %s", format)
+	}
+}
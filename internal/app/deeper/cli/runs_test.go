@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestScanShowAndErrors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+
+	session, err := repo.CreateScanSession("jdoe@acme.com")
+	require.NoError(t, err)
+	email := entities.Trace{Value: "jdoe@acme.com", Type: entities.Email}
+	require.NoError(t, repo.RecordPluginRuns(session.ID, []database.PluginRun{
+		{Value: email.Value, Type: email.Type, PluginName: "GravatarPlugin", Outcome: database.RunOK, Children: 2, DurationMS: 1500},
+		{Value: email.Value, Type: email.Type, PluginName: "HIBPPlugin", Outcome: database.RunError, ErrorType: "http_401", Error: "hibp request failed: status 401"},
+		{Value: email.Value, Type: email.Type, PluginName: "KeybasePlugin", Outcome: database.RunEmpty},
+	}))
+
+	var buf bytes.Buffer
+	require.NoError(t, showScan(&buf, repo, session.ID, "table"))
+	assert.Contains(t, buf.String(), "Scan #1 jdoe@acme.com")
+	assert.Contains(t, buf.String(), "GravatarPlugin")
+	assert.Contains(t, buf.String(), "1.5s")
+	assert.Contains(t, buf.String(), `1 plugin runs failed, see "deeper scan errors 1"`)
+
+	buf.Reset()
+	require.NoError(t, showScan(&buf, repo, session.ID, "json"))
+	var shown struct {
+		Coverage []database.PluginCoverage `json:"coverage"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &shown))
+	require.Len(t, shown.Coverage, 3)
+	assert.Equal(t, 1, shown.Coverage[1].Outcomes[database.RunError])
+
+	buf.Reset()
+	require.NoError(t, showScanErrors(&buf, repo, session.ID, "table"))
+	assert.Contains(t, buf.String(), "hibp request failed: status 401")
+	assert.NotContains(t, buf.String(), "KeybasePlugin")
+
+	other, err := repo.CreateScanSession("jdoe")
+	require.NoError(t, err)
+	buf.Reset()
+	require.NoError(t, showScanErrors(&buf, repo, other.ID, "json"))
+	assert.Equal(t, "[]\n", buf.String())
+
+	assert.EqualError(t, showScan(&buf, repo, 99, "table"), "scan 99 not found")
+}
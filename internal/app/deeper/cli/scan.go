@@ -2,18 +2,27 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
+	"github.com/mattn/go-isatty"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 
+	"github.com/smirnoffmg/deeper/internal/app/deeper/engine"
 	"github.com/smirnoffmg/deeper/internal/app/deeper/graphreport"
+	"github.com/smirnoffmg/deeper/internal/app/deeper/results"
+	"github.com/smirnoffmg/deeper/internal/app/deeper/scandiff"
 	"github.com/smirnoffmg/deeper/internal/pkg/browser"
 	"github.com/smirnoffmg/deeper/internal/pkg/database"
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
+	"github.com/smirnoffmg/deeper/internal/pkg/notify"
 )
 
 var (
@@ -21,6 +30,9 @@ var (
 	scanFilters []string
 	scanSave    string
 	scanNoOpen  bool
+	scanType    string
+	scanDiff    string
+	scanCase    string
 )
 
 // scanCmd represents the scan command
@@ -31,51 +43,133 @@ var scanCmd = &cobra.Command{
 discovers related traces using various plugins. The scan follows traces
 recursively to build a comprehensive profile.
 
+The input type is inferred from its shape. Prefix the input with a trace
+type ("type:value") or pass --type to set it explicitly. An ambiguous value
+such as "@handle" is scanned once per plausible type, and each of those seed
+traces is marked as a guess; see "deeper plugins types <value>".
+
+While the scan runs, table output shows a live progress view on the
+terminal. --output ndjson instead streams every scan event (trace
+discovered, plugin started/finished/failed, budget exhausted) to stdout as
+one JSON object per line, ready to pipe into jq or a SIEM.
+
+--diff-against compares the finished scan with an earlier one, "last"
+being the previous scan of the same input, and opens the graph report in
+diff mode; see "deeper diff". The scan then runs every plugin afresh rather
+than skipping what the earlier scan already ran.
+
+--case files the scan under a case, see "deeper case". The scan then runs
+under the case's authorization unless --engagement says otherwise.
+
+Every outbound contact the scan makes is recorded in its audit log under
+--operator and --engagement, see "deeper audit".
+
+Configured notification sinks hear about the scan as it runs, see
+"deeper notify".
+
+Every plugin run is recorded with its outcome, so a plugin that broke
+can be told apart from one that found nothing; see "deeper scan show" and
+"deeper scan errors". To scan the literal input "show" or "errors", give
+its type, as in "username:show".
+
 Examples:
   deeper scan username123
+  deeper scan instagram:@handle
+  deeper scan --type username john.doe
   deeper scan test@example.com --depth 3
   deeper scan github.com --output json --save results.json
-  deeper scan user@domain.com --filter="repository,social"`,
+  deeper scan user@domain.com --filter="repository,social"
+  deeper scan test@example.com --output ndjson | jq 'select(.type == "trace_discovered")'
+  deeper scan jdoe --diff-against last
+  deeper scan jdoe@acme.com --case acme-2026`,
 	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		input := args[0]
 
 		log.Info().Msgf("Starting scan for input: %s", input)
 
+		seeds, err := parseScanSeeds(input, scanType)
+		if err != nil {
+			return err
+		}
+		if scanSave != "" {
+			// Fail before scanning rather than after minutes of work.
+			if _, err := results.FormatFromPath(scanSave); err != nil {
+				return err
+			}
+		}
+		if scanDiff != "" && scanDiff != "last" {
+			if _, err := strconv.ParseInt(scanDiff, 10, 64); err != nil {
+				return fmt.Errorf("invalid --diff-against %q: use \"last\" or a scan id", scanDiff)
+			}
+		}
+
 		eng, repo, err := createEngine()
 		if err != nil {
 			return err
 		}
+		notifier, err := createNotifier()
+		if err != nil {
+			return err
+		}
 		display := createDisplay()
+		stopMetrics, err := serveMetrics(metricsAddr, eng)
+		if err != nil {
+			return err
+		}
+		defer stopMetrics()
 
-		session, err := repo.CreateScanSession(input)
+		var scanCaseID int64
+		scanEngagement := engagement
+		if scanCase != "" {
+			c, err := findCase(repo, scanCase)
+			if err != nil {
+				return err
+			}
+			if c.Status == database.CaseClosed {
+				return fmt.Errorf("case %q is closed", c.Name)
+			}
+			scanCaseID = c.ID
+			if scanEngagement == "" {
+				scanEngagement = c.Authorization
+			}
+		}
+
+		session, err := repo.CreateScanSessionFor(input, scanOperator(), scanEngagement)
 		if err != nil {
 			return fmt.Errorf("failed to create scan session: %w", err)
 		}
+		if scanCaseID != 0 {
+			if err := repo.AddCaseScans(scanCaseID, session.ID); err != nil {
+				return fmt.Errorf("failed to add scan to case: %w", err)
+			}
+		}
 
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
+		stopEvents := watchScanEvents(eng.Events(), output)
+		stopNotify := notifier.Follow(eng.Events())
 		startTime := time.Now()
-		traces, err := eng.ProcessInput(ctx, input, session.ID)
-		completedAt := time.Now()
-		session.CompletedAt = &completedAt
-		if err != nil {
-			session.Status = "failed"
-			_ = repo.UpdateScanSession(session)
-			return fmt.Errorf("failed to process input: %w", err)
+		traces, err := runScan(ctx, eng, repo, session, seeds, scanOptions(scanDiff))
+		if streamErr := stopEvents(); streamErr != nil {
+			log.Error().Err(streamErr).Msg("Failed to stream scan events")
 		}
-
-		session.Status = "completed"
-		session.UniqueTraces = len(traces)
-		session.TotalTraces = len(traces)
-		if err := repo.UpdateScanSession(session); err != nil {
-			return fmt.Errorf("failed to update scan session: %w", err)
+		stopNotify()
+		if err != nil {
+			notifier.NotifyAll([]notify.Notification{scanFailedNotification(session, err)})
+			return err
 		}
 
 		processingTime := time.Since(startTime)
 		log.Info().Msgf("Scan completed in %v", processingTime)
 
+		if clusters, _, err := resolveIdentities(repo, session.ID); err != nil {
+			log.Warn().Err(err).Msg("Failed to resolve identities")
+		} else {
+			log.Info().Msgf("Resolved %d identity clusters", len(clusters))
+		}
+
 		// Apply filters if specified
 		if len(scanFilters) > 0 {
 			traces = applyFilters(traces, scanFilters)
@@ -88,34 +182,62 @@ Examples:
 
 		// Display results
 		if len(traces) == 0 {
-			fmt.Println("No traces found")
+			if output != "ndjson" {
+				fmt.Println("No traces found")
+			}
 			return nil
 		}
 
 		log.Info().Msgf("Found %d traces", len(traces))
 
+		doc, err := results.Load(repo, session.ID)
+		if err != nil {
+			return fmt.Errorf("failed to build results: %w", err)
+		}
+		doc = doc.FilterTypes(scanFilters)
+
 		// Output results based on format
 		switch output {
 		case "table":
-			display.PrintTracesAsTable(traces)
+			display.PrintTracesAsTable(traces, traceVerdicts(doc))
 		case "json":
-			return outputTracesJSON(traces)
+			err = results.WriteJSON(os.Stdout, doc)
 		case "csv":
-			return outputTracesCSV(traces)
+			err = results.WriteCSV(os.Stdout, doc)
+		case "ndjson":
+			// Already streamed event by event while the scan ran.
 		default:
-			return fmt.Errorf("unsupported output format: %s", output)
+			err = fmt.Errorf("unsupported output format: %s", output)
+		}
+		if err != nil {
+			return err
 		}
 
 		// Save results if requested
 		if scanSave != "" {
-			if err := saveResults(traces, scanSave); err != nil {
+			if err := results.SaveFile(doc, scanSave); err != nil {
 				log.Error().Err(err).Msgf("Failed to save results to %s", scanSave)
 				return err
 			}
 			log.Info().Msgf("Results saved to %s", scanSave)
 		}
 
-		graphPath, err := saveGraphReport(repo, session.ID, !scanNoOpen)
+		d, err := diffScan(repo, session, scanDiff)
+		if err != nil {
+			return err
+		}
+		if d != nil {
+			if output == "table" {
+				fmt.Println()
+				printDiff(os.Stdout, d)
+			} else {
+				s := d.Summary
+				log.Info().Msgf("Since scan %d: %+d/-%d traces, %+d/-%d edges (deeper diff %d %d for details)",
+					d.Old.ID, s.AddedTraces, s.RemovedTraces, s.AddedEdges, s.RemovedEdges, d.Old.ID, d.New.ID)
+			}
+		}
+
+		graphPath, err := writeGraphReport(repo, session.ID, d, fmt.Sprintf("scan-%d.html", session.ID), !scanNoOpen)
 		if err != nil {
 			log.Error().Err(err).Msg("Failed to generate graph report")
 			return err
@@ -131,8 +253,138 @@ Examples:
 func init() {
 	scanCmd.Flags().IntVar(&scanDepth, "depth", 0, "maximum scan depth (0 for unlimited)")
 	scanCmd.Flags().StringSliceVar(&scanFilters, "filter", []string{}, "filter results by trace types (comma-separated)")
-	scanCmd.Flags().StringVar(&scanSave, "save", "", "save results to file")
+	scanCmd.Flags().StringVar(&scanSave, "save", "", "save results to file; format from extension (.json, .csv)")
 	scanCmd.Flags().BoolVar(&scanNoOpen, "no-open", false, "do not auto-open the graph report in a browser")
+	scanCmd.Flags().StringVar(&scanType, "type", "", "treat the input as this trace type instead of guessing it")
+	scanCmd.Flags().StringVar(&scanCase, "case", "", "file the scan under this case")
+	scanCmd.Flags().StringVar(&scanDiff, "diff-against", "", `compare with an earlier scan when done: a scan id, or "last" for the previous scan of this input`)
+}
+
+// watchScanEvents starts rendering the engine's events for the chosen output
+// format and returns a function that stops once the scan is over, waiting
+// for every buffered event to be written.
+func watchScanEvents(bus *events.Bus, format string) func() error {
+	switch {
+	case format == "ndjson":
+		ch, unsubscribe := bus.Subscribe(256)
+		done := make(chan error, 1)
+		go func() { done <- streamNDJSON(os.Stdout, ch) }()
+		return func() error {
+			unsubscribe()
+			return <-done
+		}
+
+	case format == "table" && isatty.IsTerminal(os.Stderr.Fd()):
+		live := newLiveProgress(os.Stderr)
+		previous := log.Logger
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: live, TimeFormat: "15:04:05"})
+
+		ch, unsubscribe := bus.Subscribe(256)
+		done := make(chan struct{})
+		go func() {
+			live.consume(ch)
+			close(done)
+		}()
+		return func() error {
+			unsubscribe()
+			<-done
+			log.Logger = previous
+			return nil
+		}
+	}
+
+	return func() error { return nil }
+}
+
+// scanOptions returns the engine options of a scan. A scan diffed against
+// an earlier one must not be answered from the deduplication cache, or it
+// finds only its seeds and the diff reports everything else as removed.
+func scanOptions(diffAgainst string) engine.Options {
+	return engine.Options{Fresh: diffAgainst != ""}
+}
+
+// diffScan compares a finished scan with the one --diff-against names. It
+// returns nil when there is nothing to compare with.
+func diffScan(repo *database.Repository, session *database.ScanSession, against string) (*scandiff.Diff, error) {
+	if against == "" {
+		return nil, nil
+	}
+	oldID, err := resolveDiffAgainst(repo, session, against)
+	if err != nil {
+		return nil, err
+	}
+	if oldID == nil {
+		log.Info().Msgf("No earlier scan of %s to diff against", session.Input)
+		return nil, nil
+	}
+	return scandiff.Load(repo, *oldID, session.ID)
+}
+
+// runScan scans seeds into session and records how the scan ended,
+// counting the plugin runs that failed as the scan's errors. A scan whose
+// context is cancelled, rather than timed out, keeps what it found and is
+// recorded as cancelled.
+func runScan(ctx context.Context, eng *engine.Engine, repo *database.Repository, session *database.ScanSession, seeds []entities.Seed, opts engine.Options) ([]entities.Trace, error) {
+	traces, err := eng.ProcessSeedsWithOptions(ctx, seeds, session.ID, opts)
+	completedAt := time.Now()
+	session.CompletedAt = &completedAt
+	if failed, countErr := repo.CountFailedPluginRuns(session.ID); countErr != nil {
+		log.Warn().Err(countErr).Msgf("Failed to count plugin errors of scan %d", session.ID)
+	} else {
+		session.Errors = failed
+	}
+	if err != nil {
+		session.Status = "failed"
+		_ = repo.UpdateScanSession(session)
+		return nil, fmt.Errorf("failed to process input: %w", err)
+	}
+
+	session.Status = "completed"
+	if errors.Is(ctx.Err(), context.Canceled) {
+		session.Status = "cancelled"
+	}
+	session.UniqueTraces = len(traces)
+	session.TotalTraces = len(traces)
+	if err := repo.UpdateScanSession(session); err != nil {
+		return nil, fmt.Errorf("failed to update scan session: %w", err)
+	}
+	return traces, nil
+}
+
+// parseScanSeeds resolves the scan input into seed traces. An explicit
+// --type wins over both a "type:value" prefix and shape-based guessing.
+func parseScanSeeds(input, traceType string) ([]entities.Seed, error) {
+	if traceType != "" {
+		return entities.TypedSeeds(entities.TraceType(traceType), input)
+	}
+	return entities.ParseSeeds(input)
+}
+
+// markVerdicts copies the analysts' verdicts onto graph report nodes and
+// edges.
+func markVerdicts(nodes []graphreport.Node, edges []graphreport.Edge, verdicts *database.VerdictIndex) {
+	for i, n := range nodes {
+		if v, ok := verdicts.Trace(n.ID); ok {
+			nodes[i].Verdict = v.Verdict
+		}
+	}
+	for i, e := range edges {
+		if v, ok := verdicts.Edge(e.To, e.Label); ok {
+			edges[i].Verdict = v.Verdict
+		}
+	}
+}
+
+// traceVerdicts maps the traces of a results document to their verdicts,
+// for the scan table.
+func traceVerdicts(doc *results.Document) map[entities.Trace]string {
+	verdicts := make(map[entities.Trace]string)
+	for _, n := range doc.Nodes {
+		if n.Verdict != "" {
+			verdicts[entities.Trace{Value: n.Value, Type: n.Type}] = n.Verdict
+		}
+	}
+	return verdicts
 }
 
 // buildGraphReport maps stored graph rows to graphreport's presentation
@@ -160,6 +412,12 @@ func buildGraphReport(nodes []database.Trace, edges []database.TraceEdge) ([]gra
 // file under ~/.deeper/reports and optionally opens it in the browser. It
 // returns an empty path (no error) when the scan recorded no traces.
 func saveGraphReport(repo *database.Repository, sessionID int64, openInBrowser bool) (string, error) {
+	return writeGraphReport(repo, sessionID, nil, fmt.Sprintf("scan-%d.html", sessionID), openInBrowser)
+}
+
+// writeGraphReport is saveGraphReport with the file name chosen by the
+// caller and, given a diff against an earlier scan, the report in diff mode.
+func writeGraphReport(repo *database.Repository, sessionID int64, d *scandiff.Diff, name string, openInBrowser bool) (string, error) {
 	nodes, edges, err := repo.GetScanGraph(sessionID)
 	if err != nil {
 		return "", fmt.Errorf("failed to load scan graph: %w", err)
@@ -168,8 +426,32 @@ func saveGraphReport(repo *database.Repository, sessionID int64, openInBrowser b
 		return "", nil
 	}
 
+	clusters, links, err := repo.GetIdentities(sessionID)
+	if err != nil {
+		return "", fmt.Errorf("failed to load identities: %w", err)
+	}
+
+	overlays, err := buildOverlays(repo, sessionID, edges)
+	if err != nil {
+		return "", err
+	}
+
+	verdicts, err := repo.GetVerdicts()
+	if err != nil {
+		return "", err
+	}
 	reportNodes, reportEdges := buildGraphReport(nodes, edges)
-	html, err := graphreport.Render(reportNodes, reportEdges)
+	markVerdicts(reportNodes, reportEdges, database.NewVerdictIndex(verdicts))
+	report := graphreport.Report{
+		Nodes:      reportNodes,
+		Edges:      reportEdges,
+		Identities: buildIdentityReport(clusters, links, nodes),
+		Overlays:   overlays,
+	}
+	if d != nil {
+		report.Diff = reportDiff(d)
+	}
+	html, err := graphreport.RenderReport(report)
 	if err != nil {
 		return "", fmt.Errorf("failed to render graph report: %w", err)
 	}
@@ -183,7 +465,20 @@ func saveGraphReport(repo *database.Repository, sessionID int64, openInBrowser b
 		return "", fmt.Errorf("failed to create reports directory: %w", err)
 	}
 
-	path := filepath.Join(reportsDir, fmt.Sprintf("scan-%d.html", sessionID))
+	// With an encrypted database the report is sealed too, and can be read
+	// with "deeper db decrypt" rather than opened.
+	secret, err := databaseSecret()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(reportsDir, name)
+	if secret != nil {
+		path += sealedExt
+		if err := secret.WriteFile(path, []byte(html), 0o600); err != nil {
+			return "", fmt.Errorf("failed to write graph report: %w", err)
+		}
+		return path, nil
+	}
 	if err := os.WriteFile(path, []byte(html), 0o644); err != nil {
 		return "", fmt.Errorf("failed to write graph report: %w", err)
 	}
@@ -197,6 +492,77 @@ func saveGraphReport(repo *database.Repository, sessionID int64, openInBrowser b
 	return path, nil
 }
 
+// maxOverlays caps how many prior scans a graph report can overlay; the
+// ones sharing the most traces win.
+const maxOverlays = 5
+
+// buildOverlays finds the earlier scans that reached some of this scan's
+// traces and cuts each one's graph down to the shared traces and their
+// direct neighbors, leaving out edges the current scan has too.
+func buildOverlays(repo *database.Repository, sessionID int64, current []database.TraceEdge) ([]graphreport.Overlay, error) {
+	overlaps, err := repo.GetPriorOverlaps(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(overlaps) > maxOverlays {
+		overlaps = overlaps[:maxOverlays]
+	}
+
+	type edgeKey struct {
+		from, to int64
+		plugin   string
+	}
+	drawn := make(map[edgeKey]bool, len(current))
+	for _, e := range current {
+		if e.ParentTraceID != nil {
+			drawn[edgeKey{*e.ParentTraceID, e.ChildTraceID, e.PluginName}] = true
+		}
+	}
+
+	overlays := make([]graphreport.Overlay, 0, len(overlaps))
+	for _, o := range overlaps {
+		nodes, edges, err := repo.GetScanGraph(o.Scan.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load scan graph: %w", err)
+		}
+
+		keep := make(map[int64]bool, len(o.Shared))
+		for _, id := range o.Shared {
+			keep[id] = true
+		}
+		var kept []database.TraceEdge
+		for _, e := range edges {
+			if e.ParentTraceID == nil || drawn[edgeKey{*e.ParentTraceID, e.ChildTraceID, e.PluginName}] {
+				continue
+			}
+			if keep[*e.ParentTraceID] || keep[e.ChildTraceID] {
+				kept = append(kept, e)
+			}
+		}
+		for _, e := range kept {
+			keep[*e.ParentTraceID] = true
+			keep[e.ChildTraceID] = true
+		}
+		var keptNodes []database.Trace
+		for _, n := range nodes {
+			if keep[n.ID] {
+				keptNodes = append(keptNodes, n)
+			}
+		}
+
+		overlayNodes, overlayEdges := buildGraphReport(keptNodes, kept)
+		overlays = append(overlays, graphreport.Overlay{
+			ScanID:    o.Scan.ID,
+			Input:     o.Scan.Input,
+			StartedAt: o.Scan.StartedAt.Format("2006-01-02"),
+			Shared:    o.Shared,
+			Nodes:     overlayNodes,
+			Edges:     overlayEdges,
+		})
+	}
+	return overlays, nil
+}
+
 func applyFilters(traces []entities.Trace, filters []string) []entities.Trace {
 	if len(filters) == 0 {
 		return traces
@@ -224,34 +590,3 @@ func limitDepth(traces []entities.Trace, maxDepth int) []entities.Trace {
 	log.Info().Msgf("Depth limiting not yet implemented, returning all %d traces", len(traces))
 	return traces
 }
-
-func outputTracesJSON(traces []entities.Trace) error {
-	fmt.Printf("[\n")
-	for i, trace := range traces {
-		fmt.Printf("  {\n")
-		fmt.Printf("    \"value\": \"%s\",\n", trace.Value)
-		fmt.Printf("    \"type\": \"%s\"\n", trace.Type)
-		if i < len(traces)-1 {
-			fmt.Printf("  },\n")
-		} else {
-			fmt.Printf("  }\n")
-		}
-	}
-	fmt.Printf("]\n")
-	return nil
-}
-
-func outputTracesCSV(traces []entities.Trace) error {
-	fmt.Println("value,type")
-	for _, trace := range traces {
-		fmt.Printf("\"%s\",\"%s\"\n", trace.Value, trace.Type)
-	}
-	return nil
-}
-
-func saveResults(traces []entities.Trace, filename string) error {
-	// Implementation for saving results to file
-	// This would write to the specified file in the requested format
-	log.Info().Msgf("Saving %d traces to %s (not yet implemented)", len(traces), filename)
-	return nil
-}
@@ -64,6 +64,22 @@ func TestBuildGraphReport_MapsNodesAndEdges(t *testing.T) {
 	}, reportEdges)
 }
 
+func TestMarkVerdicts_CopiesTraceAndEdgeVerdicts(t *testing.T) {
+	nodes := []graphreport.Node{{ID: 1, Label: "admin"}, {ID: 2, Label: "https://example.com/admin"}}
+	edges := []graphreport.Edge{{From: 1, To: 2, Label: "SherlockPlugin"}, {From: 1, To: 2, Label: "OtherPlugin"}}
+	index := database.NewVerdictIndex([]database.Verdict{
+		{TraceID: 1, Verdict: database.VerdictIrrelevant},
+		{TraceID: 2, PluginName: "SherlockPlugin", Verdict: database.VerdictFalsePositive},
+	})
+
+	markVerdicts(nodes, edges, index)
+
+	assert.Equal(t, database.VerdictIrrelevant, nodes[0].Verdict)
+	assert.Empty(t, nodes[1].Verdict, "an edge verdict does not mark the trace")
+	assert.Equal(t, database.VerdictFalsePositive, edges[0].Verdict)
+	assert.Empty(t, edges[1].Verdict)
+}
+
 func TestBuildGraphReport_SkipsSeedEdgeWithNilParent(t *testing.T) {
 	nodes := []database.Trace{{ID: 1, Value: "root.com", Type: entities.Domain}}
 	edges := []database.TraceEdge{
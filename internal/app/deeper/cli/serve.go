@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/engine"
+	"github.com/smirnoffmg/deeper/internal/app/deeper/server"
+	"github.com/smirnoffmg/deeper/internal/pkg/config"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/notify"
+	"github.com/smirnoffmg/deeper/internal/pkg/state"
+)
+
+var (
+	serveListen   string
+	serveMaxScans int
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the HTTP API for starting and querying scans",
+	Long: `Serve runs deeper as a REST/JSON server so other tools can start scans
+and read their results without the CLI. Scans run in the background, at
+most --max-scans at a time on a shared worker pool; --timeout caps how long
+each may run. Their events stream live as Server-Sent Events.
+
+Requests need a bearer token from DEEPER_API_TOKENS (comma-separated).
+Without one, serve generates a token for this run and logs it. The API is
+described by the OpenAPI document at /api/v1/openapi.json.
+
+Examples:
+  DEEPER_API_TOKENS=s3cret deeper serve --listen :8080
+  curl -H "Authorization: Bearer s3cret" -d '{"input": "jdoe@example.com", "max_depth": 3}' localhost:8080/api/v1/scans
+  curl -N -H "Authorization: Bearer s3cret" localhost:8080/api/v1/scans/42/events
+  curl -H "Authorization: Bearer s3cret" "localhost:8080/api/v1/scans/42/export?format=gexf" -o scan42.gexf`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		eng, repo, err := createEngine()
+		if err != nil {
+			return err
+		}
+		notifier, err := createNotifier()
+		if err != nil {
+			return err
+		}
+		tokens, err := apiTokens()
+		if err != nil {
+			return err
+		}
+		stopNotify := notifier.Follow(eng.Events())
+		defer stopNotify()
+		stopMetrics, err := serveMetrics(metricsAddr, eng)
+		if err != nil {
+			return err
+		}
+		defer stopMetrics()
+
+		srv := server.New(repo, eng.Events(), apiScan(eng, repo, notifier), server.Config{
+			Tokens:      tokens,
+			MaxScans:    serveMaxScans,
+			ScanTimeout: timeout,
+			Plugins:     registeredPlugins(),
+			Expand:      eng.Expand,
+			Operator:    scanOperator(),
+			Engagement:  engagement,
+		})
+		return serveAPI(serveListen, srv, srv.Handler(), func(addr string) {
+			log.Info().Msgf("Serving the API on %s", addr)
+		})
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveListen, "listen", "127.0.0.1:8080", "address to listen on")
+	serveCmd.Flags().IntVar(&serveMaxScans, "max-scans", 4, "how many scans run at once; further scans queue")
+}
+
+// serveAPI serves handler on addr until interrupted, then cancels srv's
+// scans and shuts down gracefully. ready is called with the address once
+// the listener is open.
+func serveAPI(addr string, srv *server.Server, handler http.Handler, ready func(addr string)) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		srv.Close()
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	httpServer := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() { served <- httpServer.Serve(listener) }()
+	ready(listener.Addr().String())
+
+	select {
+	case err := <-served:
+		srv.Close()
+		return fmt.Errorf("failed to serve API: %w", err)
+	case <-ctx.Done():
+	}
+
+	log.Info().Msg("Shutting down; cancelling running scans")
+	// Ending the scans first also ends their event streams, which
+	// Shutdown would otherwise wait for.
+	srv.Close()
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("failed to shut down API server: %w", err)
+	}
+	return nil
+}
+
+// apiTokens returns the configured API tokens, or a token generated for
+// this run.
+func apiTokens() ([]string, error) {
+	if tokens := config.LoadConfig().APITokens; len(tokens) > 0 {
+		return tokens, nil
+	}
+	token, err := newAPIToken()
+	if err != nil {
+		return nil, err
+	}
+	log.Warn().Msgf("DEEPER_API_TOKENS is not set; this run accepts the token %s", token)
+	return []string{token}, nil
+}
+
+// newAPIToken generates a random API token.
+func newAPIToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API token: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// registeredPlugins returns the names of every registered plugin.
+func registeredPlugins() map[string]bool {
+	names := make(map[string]bool)
+	for _, plugins := range state.ActivePlugins {
+		for _, p := range plugins {
+			names[p.String()] = true
+		}
+	}
+	return names
+}
+
+// apiScan runs the API's scans the way "deeper scan" does, resolving
+// identities afterwards and notifying of failures.
+func apiScan(eng *engine.Engine, repo *database.Repository, notifier *notify.Notifier) server.ScanFunc {
+	return func(ctx context.Context, session *database.ScanSession, seeds []entities.Seed, opts engine.Options) error {
+		if _, err := runScan(ctx, eng, repo, session, seeds, opts); err != nil {
+			notifier.NotifyAll([]notify.Notification{scanFailedNotification(session, err)})
+			return err
+		}
+		if _, _, err := resolveIdentities(repo, session.ID); err != nil {
+			log.Warn().Err(err).Msgf("Failed to resolve identities of scan %d", session.ID)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokens(t *testing.T) {
+	t.Setenv("DEEPER_API_TOKENS", "a,b")
+	tokens, err := apiTokens()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, tokens)
+
+	t.Setenv("DEEPER_API_TOKENS", "")
+	first, err := apiTokens()
+	require.NoError(t, err)
+	second, err := apiTokens()
+	require.NoError(t, err)
+	require.Len(t, first, 1)
+	assert.Len(t, first[0], 48)
+	assert.NotEqual(t, first, second, "a fresh token every run")
+}
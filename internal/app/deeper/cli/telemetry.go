@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/config"
+	"github.com/smirnoffmg/deeper/internal/pkg/telemetry"
+)
+
+// stopTelemetry flushes exported spans; Execute calls it once the command
+// has finished.
+var stopTelemetry = func() {}
+
+// setupTelemetry starts exporting spans when --otel-endpoint, --otel-file
+// or their environment variables name a destination.
+func setupTelemetry() error {
+	cfg := config.LoadConfig()
+	endpoint, file := cfg.OTLPEndpoint, cfg.SpanFile
+	if otelEndpoint != "" {
+		endpoint = otelEndpoint
+	}
+	if otelFile != "" {
+		file = otelFile
+	}
+
+	var exporters []telemetry.Exporter
+	if endpoint != "" {
+		exporter, err := telemetry.NewOTLPExporter(endpoint, cfg.OTLPHeaders)
+		if err != nil {
+			return err
+		}
+		exporters = append(exporters, exporter)
+	}
+	if file != "" {
+		exporter, err := telemetry.NewFileExporter(file)
+		if err != nil {
+			return err
+		}
+		exporters = append(exporters, exporter)
+	}
+	if len(exporters) == 0 {
+		return nil
+	}
+
+	provider := telemetry.NewProvider(cfg.ServiceName, exporters...)
+	telemetry.SetProvider(provider)
+	stopTelemetry = func() {
+		telemetry.SetProvider(nil)
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		if err := provider.Shutdown(ctx); err != nil {
+			log.Warn().Err(err).Msg("Failed to flush spans")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package cli
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/server"
+	"github.com/smirnoffmg/deeper/internal/app/deeper/ui"
+	"github.com/smirnoffmg/deeper/internal/pkg/browser"
+	"github.com/smirnoffmg/deeper/internal/pkg/config"
+)
+
+var (
+	uiListen    string
+	uiMaxScans  int
+	uiNoBrowser bool
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Open the web UI for running and exploring scans",
+	Long: `UI serves deeper's built-in web interface together with the HTTP API it
+runs on, and opens it in the browser. From there you can start a scan and
+watch its graph grow live, click a trace for its metadata, evidence and
+discovery path, run chosen plugins against a single trace, mark verdicts,
+and browse past scans and cases.
+
+The UI is embedded in the binary and works offline. It listens on
+localhost by default; each run generates a token that is handed to the
+browser in the URL fragment, and tokens from DEEPER_API_TOKENS work too.
+
+Examples:
+  deeper ui
+  deeper ui --listen 127.0.0.1:9000 --no-browser`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		eng, repo, err := createEngine()
+		if err != nil {
+			return err
+		}
+		notifier, err := createNotifier()
+		if err != nil {
+			return err
+		}
+		token, err := newAPIToken()
+		if err != nil {
+			return err
+		}
+		stopNotify := notifier.Follow(eng.Events())
+		defer stopNotify()
+		stopMetrics, err := serveMetrics(metricsAddr, eng)
+		if err != nil {
+			return err
+		}
+		defer stopMetrics()
+
+		srv := server.New(repo, eng.Events(), apiScan(eng, repo, notifier), server.Config{
+			Tokens:      append([]string{token}, config.LoadConfig().APITokens...),
+			MaxScans:    uiMaxScans,
+			ScanTimeout: timeout,
+			Plugins:     registeredPlugins(),
+			Expand:      eng.Expand,
+			Operator:    scanOperator(),
+			Engagement:  engagement,
+		})
+		return serveAPI(uiListen, srv, uiHandler(srv.Handler()), func(addr string) {
+			url := fmt.Sprintf("http://%s/#token=%s", addr, token)
+			log.Info().Msgf("Serving the UI on %s", url)
+			if uiNoBrowser {
+				return
+			}
+			if err := browser.Open(url); err != nil {
+				log.Warn().Err(err).Msg("Failed to open the browser; open the URL above instead")
+			}
+		})
+	},
+}
+
+func init() {
+	uiCmd.Flags().StringVar(&uiListen, "listen", "127.0.0.1:8090", "address to listen on")
+	uiCmd.Flags().BoolVar(&uiNoBrowser, "no-browser", false, "print the URL instead of opening the browser")
+	uiCmd.Flags().IntVar(&uiMaxScans, "max-scans", 4, "how many scans run at once; further scans queue")
+}
+
+// uiHandler serves the UI next to the API it calls.
+func uiHandler(api http.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/api/", api)
+	mux.Handle("/", ui.Handler())
+	return mux
+}
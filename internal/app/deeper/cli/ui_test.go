@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUIHandler_RoutesAPIAndPage(t *testing.T) {
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	handler := uiHandler(api)
+
+	for path, want := range map[string]int{
+		"/":               http.StatusOK,
+		"/app.js":         http.StatusOK,
+		"/api/v1/scans":   http.StatusTeapot,
+		"/api/v1/plugins": http.StatusTeapot,
+		"/no-such-asset":  http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, want, rec.Code, path)
+	}
+}
@@ -0,0 +1,181 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/graphnav"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+)
+
+var (
+	verdictPlugin string
+	verdictNote   string
+)
+
+var (
+	verdictCmd = &cobra.Command{
+		Use:   "verdict",
+		Short: "Mark traces as confirmed, false positives or irrelevant",
+		Long: `A verdict records an analyst's judgement on a trace, or with --plugin on
+the edges one plugin draws into it, along with an optional note.
+
+Verdicts feed back into later scans: a false positive is no longer
+recorded, and an irrelevant trace is kept but not expanded. A confirmed
+trace is only marked. Every output, export and report shows verdicts.
+
+A trace is given by value, as "type:value" when the value alone is
+ambiguous, or by ID ("#17").
+
+Examples:
+  deeper verdict set jdoe@example.com confirmed --note "matches HR record"
+  deeper verdict set github:jdoe false-positive --plugin UsernameSweepPlugin
+  deeper verdict set cdn.example.com irrelevant
+  deeper verdict clear cdn.example.com
+  deeper verdict list --output json`,
+	}
+
+	verdictSetCmd = &cobra.Command{
+		Use:   "set <trace> <confirmed|false-positive|irrelevant>",
+		Short: "Set the verdict on a trace or a plugin's edges into it",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withRepo(func(repo *database.Repository) error {
+				return runVerdictSet(repo, args[0], args[1])
+			})
+		},
+	}
+
+	verdictClearCmd = &cobra.Command{
+		Use:   "clear <trace>",
+		Short: "Remove the verdict on a trace or a plugin's edges into it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withRepo(func(repo *database.Repository) error {
+				return runVerdictClear(repo, args[0])
+			})
+		},
+	}
+
+	verdictListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List verdicts",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withRepo(func(repo *database.Repository) error {
+				verdicts, err := repo.GetVerdicts()
+				if err != nil {
+					return err
+				}
+				return writeVerdicts(os.Stdout, verdicts, output)
+			})
+		},
+	}
+)
+
+func init() {
+	verdictSetCmd.Flags().StringVar(&verdictPlugin, "plugin", "", "judge only this plugin's edges into the trace")
+	verdictSetCmd.Flags().StringVar(&verdictNote, "note", "", "why the verdict was given")
+	verdictClearCmd.Flags().StringVar(&verdictPlugin, "plugin", "", "clear the verdict on this plugin's edges")
+
+	verdictCmd.AddCommand(verdictSetCmd)
+	verdictCmd.AddCommand(verdictClearCmd)
+	verdictCmd.AddCommand(verdictListCmd)
+}
+
+// parseVerdict accepts a verdict as written on the command line, where
+// "false-positive" reads better than the stored "false_positive".
+func parseVerdict(s string) (string, error) {
+	v := strings.ReplaceAll(strings.ToLower(s), "-", "_")
+	if !database.IsVerdict(v) {
+		return "", fmt.Errorf("unknown verdict %q (use confirmed, false-positive or irrelevant)", s)
+	}
+	return v, nil
+}
+
+func runVerdictSet(repo *database.Repository, ref, verdict string) error {
+	v, err := parseVerdict(verdict)
+	if err != nil {
+		return err
+	}
+	t, err := graphnav.Resolve(repo, ref)
+	if err != nil {
+		return err
+	}
+	if verdictPlugin != "" {
+		found, err := repo.HasEdgeFrom(t.ID, verdictPlugin)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return fmt.Errorf("%s never discovered %s:%s", verdictPlugin, t.Type, t.Value)
+		}
+	}
+
+	if err := repo.SetVerdict(&database.Verdict{TraceID: t.ID, PluginName: verdictPlugin, Verdict: v, Note: verdictNote}); err != nil {
+		return err
+	}
+	log.Info().Msgf("Marked %s as %s", verdictTarget(t, verdictPlugin), strings.ReplaceAll(v, "_", " "))
+	return nil
+}
+
+func runVerdictClear(repo *database.Repository, ref string) error {
+	t, err := graphnav.Resolve(repo, ref)
+	if err != nil {
+		return err
+	}
+	cleared, err := repo.ClearVerdict(t.ID, verdictPlugin)
+	if err != nil {
+		return err
+	}
+	if !cleared {
+		return fmt.Errorf("no verdict on %s", verdictTarget(t, verdictPlugin))
+	}
+	log.Info().Msgf("Cleared the verdict on %s", verdictTarget(t, verdictPlugin))
+	return nil
+}
+
+func verdictTarget(t *database.Trace, pluginName string) string {
+	target := fmt.Sprintf("%s:%s", t.Type, t.Value)
+	if pluginName != "" {
+		target = fmt.Sprintf("%s edges into %s", pluginName, target)
+	}
+	return target
+}
+
+func writeVerdicts(w io.Writer, verdicts []database.Verdict, format string) error {
+	switch format {
+	case "json":
+		if verdicts == nil {
+			verdicts = []database.Verdict{}
+		}
+		return writeGraphJSON(w, verdicts)
+	case "table":
+		if len(verdicts) == 0 {
+			_, _ = fmt.Fprintln(w, "No verdicts")
+			return nil
+		}
+		table := newGraphTable(w, []string{"Trace", "Type", "Value", "Plugin", "Verdict", "Note", "Updated"})
+		for _, v := range verdicts {
+			table.Append([]string{
+				"#" + strconv.FormatInt(v.TraceID, 10),
+				string(v.Type),
+				v.Value,
+				orNone(v.PluginName),
+				strings.ReplaceAll(v.Verdict, "_", " "),
+				v.Note,
+				v.UpdatedAt.Format("2006-01-02"),
+			})
+		}
+		table.Render()
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for verdict list: %s", format)
+	}
+}
@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestVerdictCommands_SetClearList(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+
+	seed := entities.Trace{Value: "jdoe", Type: entities.Username}
+	session, err := repo.CreateScanSession(seed.Value)
+	require.NoError(t, err)
+	seedID, err := repo.GetOrCreateTrace(seed)
+	require.NoError(t, err)
+	require.NoError(t, repo.InsertEdge(&database.TraceEdge{ChildTraceID: seedID, PluginName: database.SeedPluginName, ScanID: session.ID, DiscoveredAt: time.Now()}))
+	require.NoError(t, repo.PersistDiscoveries(session.ID, []entities.Discovery{
+		{Parent: seed, PluginName: "GitHubProfilePlugin", Child: entities.Trace{Value: "jdoe@example.com", Type: entities.Email}},
+	}))
+
+	verdictNote = "matches HR record"
+	t.Cleanup(func() { verdictPlugin, verdictNote = "", "" })
+	require.NoError(t, runVerdictSet(repo, "jdoe@example.com", "confirmed"))
+	assert.ErrorContains(t, runVerdictSet(repo, "jdoe@example.com", "maybe"), "unknown verdict")
+
+	verdictPlugin, verdictNote = "UsernameSweepPlugin", ""
+	assert.ErrorContains(t, runVerdictSet(repo, "jdoe@example.com", "false-positive"), "never discovered")
+	verdictPlugin = "GitHubProfilePlugin"
+	require.NoError(t, runVerdictSet(repo, "jdoe@example.com", "false-positive"))
+
+	verdicts, err := repo.GetVerdicts()
+	require.NoError(t, err)
+	require.Len(t, verdicts, 2)
+	assert.Equal(t, database.VerdictConfirmed, verdicts[0].Verdict)
+	assert.Equal(t, database.VerdictFalsePositive, verdicts[1].Verdict)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeVerdicts(&buf, verdicts, "table"))
+	assert.Contains(t, buf.String(), "false positive")
+	assert.Contains(t, buf.String(), "matches HR record")
+
+	require.NoError(t, runVerdictClear(repo, "jdoe@example.com"))
+	assert.ErrorContains(t, runVerdictClear(repo, "jdoe@example.com"), "no verdict")
+	verdictPlugin = ""
+	require.NoError(t, runVerdictClear(repo, "jdoe@example.com"))
+
+	buf.Reset()
+	verdicts, err = repo.GetVerdicts()
+	require.NoError(t, err)
+	require.NoError(t, writeVerdicts(&buf, verdicts, "json"))
+	assert.Equal(t, "[]\n", buf.String())
+}
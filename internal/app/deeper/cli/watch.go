@@ -0,0 +1,342 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/engine"
+	"github.com/smirnoffmg/deeper/internal/app/deeper/watch"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/notify"
+)
+
+// minWatchInterval keeps a watch from rescanning faster than the plugins'
+// sources would tolerate.
+const minWatchInterval = time.Minute
+
+var (
+	watchEvery time.Duration
+	watchType  string
+	watchOnce  bool
+	watchPoll  time.Duration
+	watchLimit int
+)
+
+var (
+	watchCmd = &cobra.Command{
+		Use:   "watch",
+		Short: "Rescan seeds on a schedule and alert on what appears",
+		Long: `A watch keeps a seed under observation. "deeper watch run" rescans every
+watched seed on its schedule, running every plugin afresh rather than
+skipping what an earlier scan already ran, diffs each scan against the
+previous one and raises an alert for every new subdomain, email,
+published SSH or PGP key and new identity. Watches and their alert
+history are stored in the database.
+
+Examples:
+  deeper watch add acme.com --every 24h
+  deeper watch add jdoe --type username --every 168h
+  deeper watch list
+  deeper watch run
+  deeper watch run --once --output json
+  deeper watch alerts 1`,
+	}
+
+	watchAddCmd = &cobra.Command{
+		Use:   "add <seed>",
+		Short: "Watch a seed",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withRepo(func(repo *database.Repository) error {
+				return runWatchAdd(repo, args[0])
+			})
+		},
+	}
+
+	watchListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List watches",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withRepo(func(repo *database.Repository) error {
+				watches, err := repo.GetWatches()
+				if err != nil {
+					return err
+				}
+				return writeWatches(os.Stdout, watches, output)
+			})
+		},
+	}
+
+	watchRemoveCmd = &cobra.Command{
+		Use:   "remove <watch-id>",
+		Short: "Stop watching a seed",
+		Long:  `Remove deletes a watch and its alert history. Its scans are kept.`,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			id, err := parseWatchID(args[0])
+			if err != nil {
+				return err
+			}
+			return withRepo(func(repo *database.Repository) error {
+				removed, err := repo.DeleteWatch(id)
+				if err != nil {
+					return err
+				}
+				if !removed {
+					return fmt.Errorf("watch #%d not found", id)
+				}
+				log.Info().Msgf("Removed watch #%d", id)
+				return nil
+			})
+		},
+	}
+
+	watchAlertsCmd = &cobra.Command{
+		Use:   "alerts [watch-id]",
+		Short: "Show the alert history of one or every watch",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var id int64
+			if len(args) == 1 {
+				var err error
+				if id, err = parseWatchID(args[0]); err != nil {
+					return err
+				}
+			}
+			return withRepo(func(repo *database.Repository) error {
+				alerts, err := repo.GetWatchAlerts(id, watchLimit)
+				if err != nil {
+					return err
+				}
+				return writeWatchAlerts(os.Stdout, alerts, output)
+			})
+		},
+	}
+
+	watchRunCmd = &cobra.Command{
+		Use:   "run",
+		Short: "Run due watches until interrupted",
+		Long: `Run rescans each watched seed when it is due and keeps running, checking
+for due watches every --poll interval, until interrupted. --once runs the
+watches that are due now and exits, for cron and CI.
+
+Alerts are logged, stored and sent to the notification sinks (see
+"deeper notify"); --output json or ndjson also prints each one to stdout
+as a JSON object per line.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			eng, repo, err := createEngine()
+			if err != nil {
+				return err
+			}
+			notifier, err := createNotifier()
+			if err != nil {
+				return err
+			}
+			stopNotify := notifier.Follow(eng.Events())
+			defer stopNotify()
+			stopMetrics, err := serveMetrics(metricsAddr, eng)
+			if err != nil {
+				return err
+			}
+			defer stopMetrics()
+
+			ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			runner := watch.NewRunner(repo, watchScan(eng, repo, notifier))
+			report := func(r watch.Result) {
+				reportWatchRun(os.Stdout, r, output)
+				notifier.NotifyAll(alertNotifications(r))
+			}
+			if watchOnce {
+				ran, err := runner.RunDue(ctx, report)
+				if err != nil {
+					return err
+				}
+				log.Info().Msgf("Ran %d due watches", ran)
+				return nil
+			}
+			log.Info().Msgf("Watching; checking for due watches every %s", watchPoll)
+			return runner.Loop(ctx, watchPoll, report)
+		},
+	}
+)
+
+func init() {
+	watchAddCmd.Flags().DurationVar(&watchEvery, "every", 24*time.Hour, "how often to rescan the seed")
+	watchAddCmd.Flags().StringVar(&watchType, "type", "", "scan the seed as this trace type instead of guessing it")
+	watchRunCmd.Flags().BoolVar(&watchOnce, "once", false, "run the watches due now and exit")
+	watchRunCmd.Flags().DurationVar(&watchPoll, "poll", time.Minute, "how often to check for due watches")
+	watchAlertsCmd.Flags().IntVar(&watchLimit, "limit", 50, "show at most this many alerts (0 for all)")
+
+	watchCmd.AddCommand(watchAddCmd)
+	watchCmd.AddCommand(watchListCmd)
+	watchCmd.AddCommand(watchRemoveCmd)
+	watchCmd.AddCommand(watchAlertsCmd)
+	watchCmd.AddCommand(watchRunCmd)
+}
+
+func parseWatchID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid watch id %q", arg)
+	}
+	return id, nil
+}
+
+func runWatchAdd(repo *database.Repository, input string) error {
+	if watchEvery < minWatchInterval {
+		return fmt.Errorf("--every must be at least %s", minWatchInterval)
+	}
+	// Fail now rather than on the first run.
+	if _, err := parseScanSeeds(input, watchType); err != nil {
+		return err
+	}
+
+	w := &database.Watch{
+		Input:           input,
+		Type:            entities.TraceType(watchType),
+		IntervalSeconds: int64(watchEvery / time.Second),
+		NextRunAt:       time.Now(),
+	}
+	if err := repo.CreateWatch(w); err != nil {
+		return err
+	}
+	log.Info().Msgf("Watching %s every %s (watch #%d); the first scan runs on the next \"deeper watch run\"", w.Input, w.Interval(), w.ID)
+	return nil
+}
+
+// watchScan scans a watch's seed the way "deeper scan" does, within the
+// scan-wide --timeout, and resolves its identities for the alert check.
+// The scan is fresh: answered from the deduplication cache, a rescan
+// within its TTL would find nothing but the seeds.
+func watchScan(eng *engine.Engine, repo *database.Repository, notifier *notify.Notifier) watch.ScanFunc {
+	return func(ctx context.Context, w database.Watch) (*database.ScanSession, error) {
+		seeds, err := parseScanSeeds(w.Input, string(w.Type))
+		if err != nil {
+			return nil, err
+		}
+		session, err := repo.CreateScanSessionFor(w.Input, scanOperator(), engagement)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create scan session: %w", err)
+		}
+		log.Info().Msgf("Watch #%d: scanning %s (scan %d)", w.ID, w.Input, session.ID)
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if _, err := runScan(ctx, eng, repo, session, seeds, engine.Options{Fresh: true}); err != nil {
+			notifier.NotifyAll([]notify.Notification{scanFailedNotification(session, err)})
+			return nil, err
+		}
+		if _, _, err := resolveIdentities(repo, session.ID); err != nil {
+			return nil, err
+		}
+		return session, nil
+	}
+}
+
+// reportWatchRun logs the outcome of a watch run and, for JSON output,
+// prints its alerts to w one per line.
+func reportWatchRun(w io.Writer, r watch.Result, format string) {
+	switch {
+	case r.Previous == nil:
+		log.Info().Msgf("Watch #%d (%s): baseline scan %d", r.Watch.ID, r.Watch.Input, r.Scan.ID)
+	case len(r.Alerts) == 0:
+		log.Info().Msgf("Watch #%d (%s): nothing new since scan %d", r.Watch.ID, r.Watch.Input, r.Previous.ID)
+	default:
+		log.Warn().Msgf("Watch #%d (%s): %d alerts since scan %d (deeper diff %d %d for details)",
+			r.Watch.ID, r.Watch.Input, len(r.Alerts), r.Previous.ID, r.Previous.ID, r.Scan.ID)
+	}
+
+	encoder := json.NewEncoder(w)
+	for _, a := range r.Alerts {
+		if format == "json" || format == "ndjson" {
+			if err := encoder.Encode(a); err != nil {
+				log.Error().Err(err).Msg("Failed to write alert")
+			}
+			continue
+		}
+		log.Warn().Msgf("  %s: %s (%s)", a.Kind, a.Value, a.Type)
+	}
+}
+
+func writeWatches(w io.Writer, watches []database.Watch, format string) error {
+	switch format {
+	case "json":
+		if watches == nil {
+			watches = []database.Watch{}
+		}
+		return writeGraphJSON(w, watches)
+	case "table":
+		if len(watches) == 0 {
+			_, _ = fmt.Fprintln(w, "No watches")
+			return nil
+		}
+		table := newGraphTable(w, []string{"ID", "Input", "Type", "Every", "Last run", "Last scan", "Next run"})
+		for _, wt := range watches {
+			lastRun, lastScan := "-", "-"
+			if wt.LastRunAt != nil {
+				lastRun = wt.LastRunAt.Local().Format("2006-01-02 15:04")
+			}
+			if wt.LastScanID != nil {
+				lastScan = strconv.FormatInt(*wt.LastScanID, 10)
+			}
+			table.Append([]string{
+				strconv.FormatInt(wt.ID, 10),
+				wt.Input,
+				orNone(string(wt.Type)),
+				wt.Interval().String(),
+				lastRun,
+				lastScan,
+				wt.NextRunAt.Local().Format("2006-01-02 15:04"),
+			})
+		}
+		table.Render()
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for watch list: %s", format)
+	}
+}
+
+func writeWatchAlerts(w io.Writer, alerts []database.WatchAlert, format string) error {
+	switch format {
+	case "json":
+		if alerts == nil {
+			alerts = []database.WatchAlert{}
+		}
+		return writeGraphJSON(w, alerts)
+	case "table":
+		if len(alerts) == 0 {
+			_, _ = fmt.Fprintln(w, "No alerts")
+			return nil
+		}
+		table := newGraphTable(w, []string{"Raised", "Watch", "Kind", "Value", "Type", "Scans"})
+		for _, a := range alerts {
+			table.Append([]string{
+				a.CreatedAt.Local().Format("2006-01-02 15:04"),
+				strconv.FormatInt(a.WatchID, 10),
+				a.Kind,
+				a.Value,
+				string(a.Type),
+				fmt.Sprintf("%d → %d", a.PreviousScanID, a.ScanID),
+			})
+		}
+		table.Render()
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format for watch alerts: %s", format)
+	}
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/watch"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/notify"
+	"github.com/smirnoffmg/deeper/internal/pkg/state"
+)
+
+type subdomainPlugin struct{}
+
+func (p *subdomainPlugin) Register() error {
+	state.RegisterPlugin(entities.Domain, p)
+	return nil
+}
+
+func (p *subdomainPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return []entities.Trace{{Value: "www." + trace.Value, Type: entities.Subdomain}}, nil
+}
+
+func (p *subdomainPlugin) String() string {
+	return "SubdomainPlugin"
+}
+
+func TestWatchCommands_AddAndList(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	_, repo, err := createEngine()
+	require.NoError(t, err)
+
+	watchEvery, watchType = 24*time.Hour, ""
+	t.Cleanup(func() { watchEvery, watchType = 24*time.Hour, "" })
+	require.NoError(t, runWatchAdd(repo, "acme.com"))
+	assert.ErrorContains(t, runWatchAdd(repo, "acme.com"), "already watched")
+
+	watchEvery = time.Second
+	assert.ErrorContains(t, runWatchAdd(repo, "jdoe"), "at least")
+	watchEvery, watchType = time.Hour, "no_such_type"
+	assert.Error(t, runWatchAdd(repo, "jdoe"))
+
+	watches, err := repo.GetWatches()
+	require.NoError(t, err)
+	require.Len(t, watches, 1)
+	assert.Equal(t, int64(86400), watches[0].IntervalSeconds)
+
+	var buf bytes.Buffer
+	require.NoError(t, writeWatches(&buf, watches, "table"))
+	assert.Contains(t, buf.String(), "24h0m0s")
+
+	buf.Reset()
+	require.NoError(t, writeWatchAlerts(&buf, nil, "json"))
+	assert.Equal(t, "[]\n", buf.String())
+}
+
+// TestWatchScan_RescanWithinCacheTTL is a regression test: a rescan used
+// to be answered from the deduplication cache, found only the seed, and
+// the rescan after that alerted on everything as new.
+func TestWatchScan_RescanWithinCacheTTL(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	original := state.ActivePlugins[entities.Domain]
+	t.Cleanup(func() { state.ActivePlugins[entities.Domain] = original })
+	state.ActivePlugins[entities.Domain] = nil
+	require.NoError(t, (&subdomainPlugin{}).Register())
+
+	eng, repo, err := createEngine()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = eng.Shutdown(5 * time.Second) })
+
+	w := database.Watch{Input: "acme.com", Type: entities.Domain, IntervalSeconds: 60, NextRunAt: time.Now()}
+	require.NoError(t, repo.CreateWatch(&w))
+	runner := watch.NewRunner(repo, watchScan(eng, repo, notify.New()))
+
+	scanTraces := func(scanID int64) []string {
+		nodes, _, err := repo.GetScanGraph(scanID)
+		require.NoError(t, err)
+		values := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			values = append(values, n.Value)
+		}
+		return values
+	}
+
+	first, err := runner.Run(context.Background(), w)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"acme.com", "www.acme.com"}, scanTraces(first.Scan.ID))
+
+	second, err := runner.Run(context.Background(), first.Watch)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, scanTraces(first.Scan.ID), scanTraces(second.Scan.ID))
+	require.NotNil(t, second.Previous)
+	assert.Equal(t, first.Scan.ID, second.Previous.ID)
+	assert.Empty(t, second.Alerts)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"sort"
+	"strings"
 
 	"github.com/olekukonko/tablewriter"
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
@@ -21,10 +22,15 @@ func NewDisplay(output *os.File) *Display {
 	}
 }
 
-// PrintTracesAsTable displays traces in a formatted table
-func (d *Display) PrintTracesAsTable(traces []entities.Trace) {
+// PrintTracesAsTable displays traces in a formatted table. A Verdict column
+// is added when any trace carries an analyst verdict.
+func (d *Display) PrintTracesAsTable(traces []entities.Trace, verdicts map[entities.Trace]string) {
 	table := tablewriter.NewWriter(d.output)
-	table.SetHeader([]string{"Value", "Type"})
+	header := []string{"Value", "Type"}
+	if len(verdicts) > 0 {
+		header = append(header, "Verdict")
+	}
+	table.SetHeader(header)
 
 	// Sort by type for consistent output
 	sort.Slice(traces, func(i, j int) bool {
@@ -36,7 +42,11 @@ func (d *Display) PrintTracesAsTable(traces []entities.Trace) {
 		if trace.Value == "" {
 			continue
 		}
-		table.Append([]string{trace.Value, string(trace.Type)})
+		row := []string{trace.Value, string(trace.Type)}
+		if len(verdicts) > 0 {
+			row = append(row, strings.ReplaceAll(verdicts[trace], "_", " "))
+		}
+		table.Append(row)
 	}
 
 	table.Render()
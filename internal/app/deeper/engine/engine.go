@@ -3,16 +3,18 @@ package engine
 import (
 	"context"
 	"fmt"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/smirnoffmg/deeper/internal/app/deeper/processor"
+	"github.com/smirnoffmg/deeper/internal/pkg/audit"
 	"github.com/smirnoffmg/deeper/internal/pkg/config"
 	"github.com/smirnoffmg/deeper/internal/pkg/database"
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
 	"github.com/smirnoffmg/deeper/internal/pkg/metrics"
+	"github.com/smirnoffmg/deeper/internal/pkg/telemetry"
 )
 
 // Engine orchestrates the trace processing workflow
@@ -21,6 +23,7 @@ type Engine struct {
 	processor *processor.Processor
 	metrics   *metrics.MetricsCollector
 	repo      *database.Repository
+	events    *events.Bus
 }
 
 // NewEngine creates a new trace processing engine
@@ -30,46 +33,154 @@ func NewEngine(cfg *config.Config, metricsCollector *metrics.MetricsCollector, r
 		processor: processor.NewProcessor(cfg, metricsCollector, repo, cache),
 		metrics:   metricsCollector,
 		repo:      repo,
+		events:    events.NewBus(),
 	}
 }
 
-// ProcessInput processes an input string and returns all discovered traces
+// Events returns the bus every scan run by this engine publishes its
+// progress to. Events carry their scan ID, so one subscriber can follow
+// several concurrent scans.
+func (e *Engine) Events() *events.Bus {
+	return e.events
+}
+
+// ProcessInput processes an input string and returns all discovered traces.
+// The input is parsed with entities.ParseSeeds, so "type:value" input is
+// typed explicitly and an ambiguous value fans out to several guessed seeds.
 func (e *Engine) ProcessInput(ctx context.Context, input string, scanID int64) ([]entities.Trace, error) {
-	input = strings.TrimSpace(input)
-	if input == "" {
-		return nil, fmt.Errorf("scan input must not be empty")
+	seeds, err := entities.ParseSeeds(input)
+	if err != nil {
+		return nil, err
 	}
+	return e.ProcessSeeds(ctx, seeds, scanID)
+}
 
-	initialTrace := entities.NewTrace(input)
+// ProcessSeeds runs a scan from one or more already-typed seed traces and
+// returns all discovered traces, seeds included.
+func (e *Engine) ProcessSeeds(ctx context.Context, seeds []entities.Seed, scanID int64) ([]entities.Trace, error) {
+	return e.ProcessSeedsWithOptions(ctx, seeds, scanID, Options{})
+}
 
-	rootID, err := e.repo.GetOrCreateTrace(initialTrace)
-	if err != nil {
-		return nil, fmt.Errorf("failed to persist root trace: %w", err)
+// ProcessSeedsWithOptions is ProcessSeeds narrowed by opts. Scans may run
+// concurrently; they share the engine's worker pool.
+func (e *Engine) ProcessSeedsWithOptions(ctx context.Context, seeds []entities.Seed, scanID int64, opts Options) (traces []entities.Trace, err error) {
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("scan input must not be empty")
 	}
-	if err := e.repo.InsertEdge(&database.TraceEdge{
-		ChildTraceID: rootID,
-		PluginName:   database.SeedPluginName,
-		ScanID:       scanID,
-		DiscoveredAt: time.Now(),
-	}); err != nil {
-		return nil, fmt.Errorf("failed to persist seed edge: %w", err)
+	ctx, span := telemetry.Start(ctx, "scan", telemetry.WithAttributes(
+		telemetry.Int64("deeper.scan.id", scanID),
+		telemetry.Int("deeper.scan.seeds", len(seeds)),
+	))
+	defer func() {
+		span.SetAttributes(telemetry.Int("deeper.scan.traces", len(traces)))
+		span.RecordError(err)
+		span.End()
+	}()
+	if len(opts.Plugins) > 0 {
+		ctx = processor.WithPlugins(ctx, opts.Plugins)
 	}
+	if opts.Fresh {
+		ctx = processor.WithoutDeduplication(ctx)
+	}
+
+	emit := func(ev events.Event) {
+		ev.ScanID = scanID
+		e.events.Publish(ev)
+	}
+	ctx = events.WithEmitter(ctx, emit)
+	ctx = audit.WithLog(ctx, audit.NewLog(scanID, e.repo))
+	ctx = processor.WithRunRecorder(ctx, e.runRecorder(scanID))
+	emit(events.Event{Type: events.ScanStarted})
 
-	stack := []entities.Trace{initialTrace}
-	// The seed is marked seen and included in results up front: previously
-	// it was excluded from allTraces entirely (only plugin-discovered
+	// Seeds are marked seen and included in results up front: previously
+	// the seed was excluded from allTraces entirely (only plugin-discovered
 	// children were ever appended), so a scan's own starting point never
 	// appeared in its own results unless some plugin happened to
 	// rediscover the identical (value, type) pair as a "new" child
 	// elsewhere in the graph. Marking it seen here also prevents that kind
 	// of rediscovery from re-queuing and reprocessing the seed a second time.
-	seen := map[entities.Trace]bool{initialTrace: true}
-	allTraces := []entities.Trace{initialTrace}
+	seen := make(map[entities.Trace]bool, len(seeds))
+	var stack, allTraces []entities.Trace
+	// depth is each trace's distance in discovery hops from a seed.
+	depth := make(map[entities.Trace]int, len(seeds))
+
+	verdicts, err := e.repo.GetVerdicts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load verdicts: %w", err)
+	}
+	filter := newVerdictFilter(verdicts)
+	var suppressed int
+
+	for _, seed := range seeds {
+		if seen[seed.Trace] {
+			continue
+		}
+
+		rootID, err := e.repo.GetOrCreateTrace(seed.Trace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to persist root trace: %w", err)
+		}
+		pluginName := database.SeedPluginName
+		if seed.Guessed {
+			pluginName = database.SeedGuessPluginName
+			log.Info().Msgf("Ambiguous input, seeding guessed trace %v", seed.Trace)
+		}
+		if err := e.repo.InsertEdge(&database.TraceEdge{
+			ChildTraceID: rootID,
+			PluginName:   pluginName,
+			ScanID:       scanID,
+			DiscoveredAt: time.Now(),
+		}); err != nil {
+			return nil, fmt.Errorf("failed to persist seed edge: %w", err)
+		}
+
+		seen[seed.Trace] = true
+		stack = append(stack, seed.Trace)
+		allTraces = append(allTraces, seed.Trace)
+		emit(events.Event{
+			Type:    events.TraceDiscovered,
+			Trace:   events.Ref(seed.Trace),
+			Plugin:  pluginName,
+			Guessed: seed.Guessed,
+		})
+	}
+
+	seedParents, n := filter.suppress(registrableParentDiscoveries(allTraces))
+	suppressed += n
+	if err := e.repo.PersistDiscoveries(scanID, seedParents); err != nil {
+		return nil, fmt.Errorf("failed to persist discoveries: %w", err)
+	}
+	for _, d := range seedParents {
+		if !seen[d.Child] {
+			seen[d.Child] = true
+			depth[d.Child] = depth[d.Parent] + 1
+			allTraces = append(allTraces, d.Child)
+			if filter.expand(d) && opts.follows(d.Child, depth[d.Child]) {
+				stack = append(stack, d.Child)
+			}
+			emitDiscovery(emit, d)
+		}
+	}
 
 	var processedCount int
 	var errorCount int
 
 	for len(stack) > 0 {
+		if err := ctx.Err(); err != nil {
+			log.Warn().Err(err).Msgf("Scan budget exhausted with %d traces still queued", len(stack))
+			emit(events.Event{Type: events.BudgetExhausted, Count: len(stack), Error: err.Error()})
+			break
+		}
+		if opts.MaxTraces > 0 && len(allTraces) >= opts.MaxTraces {
+			log.Warn().Msgf("Trace budget of %d reached with %d traces still queued", opts.MaxTraces, len(stack))
+			emit(events.Event{
+				Type:  events.BudgetExhausted,
+				Count: len(stack),
+				Error: fmt.Sprintf("trace budget of %d reached", opts.MaxTraces),
+			})
+			break
+		}
+
 		batchSize := min(len(stack), e.config.MaxConcurrency)
 		batch := stack[:batchSize]
 		stack = stack[batchSize:]
@@ -81,6 +192,16 @@ func (e *Engine) ProcessInput(ctx context.Context, input string, scanID int64) (
 			continue
 		}
 
+		discoveries, n := filter.suppress(discoveries)
+		suppressed += n
+		children := make([]entities.Trace, 0, len(discoveries))
+		for _, d := range discoveries {
+			children = append(children, d.Child)
+		}
+		derived, n := filter.suppress(registrableParentDiscoveries(children))
+		suppressed += n
+		discoveries = append(discoveries, derived...)
+
 		if err := e.repo.PersistDiscoveries(scanID, discoveries); err != nil {
 			return nil, fmt.Errorf("failed to persist discoveries: %w", err)
 		}
@@ -88,20 +209,55 @@ func (e *Engine) ProcessInput(ctx context.Context, input string, scanID int64) (
 		for _, d := range discoveries {
 			if !seen[d.Child] {
 				seen[d.Child] = true
+				depth[d.Child] = depth[d.Parent] + 1
 				allTraces = append(allTraces, d.Child)
-				stack = append(stack, d.Child)
+				if filter.expand(d) && opts.follows(d.Child, depth[d.Child]) {
+					stack = append(stack, d.Child)
+				}
+				emitDiscovery(emit, d)
 			}
 		}
 
 		processedCount += len(batch)
 	}
 
+	if suppressed > 0 {
+		log.Info().Msgf("Suppressed %d discoveries marked false positive", suppressed)
+	}
 	log.Info().Msgf("Processing complete. Processed %d traces, found %d unique traces, %d errors",
 		processedCount, len(allTraces), errorCount)
+	emit(events.Event{Type: events.ScanFinished, Count: len(allTraces)})
+	span.SetAttributes(
+		telemetry.Int("deeper.scan.processed", processedCount),
+		telemetry.Int("deeper.scan.suppressed", suppressed),
+		telemetry.Int("deeper.scan.batch_errors", errorCount),
+	)
 
 	return allTraces, nil
 }
 
+// runRecorder returns a recorder that adds plugin runs to scanID's
+// ledger. A run that cannot be recorded is logged rather than failing the
+// scan.
+func (e *Engine) runRecorder(scanID int64) processor.RunRecorder {
+	return func(runs []database.PluginRun) {
+		if err := e.repo.RecordPluginRuns(scanID, runs); err != nil {
+			log.Error().Err(err).Msgf("Failed to record plugin runs of scan %d", scanID)
+		}
+	}
+}
+
+// emitDiscovery reports a trace the scan has not seen before. Rediscoveries
+// of known traces still get an edge in the graph but no event.
+func emitDiscovery(emit events.Emitter, d entities.Discovery) {
+	emit(events.Event{
+		Type:   events.TraceDiscovered,
+		Trace:  events.Ref(d.Child),
+		Parent: events.Ref(d.Parent),
+		Plugin: d.PluginName,
+	})
+}
+
 // processBatch processes a batch of traces concurrently, bounded by MaxConcurrency
 func (e *Engine) processBatch(ctx context.Context, traces []entities.Trace) ([]entities.Discovery, error) {
 	var (
@@ -146,6 +302,32 @@ func (e *Engine) processBatch(ctx context.Context, traces []entities.Trace) ([]e
 	return allResults, nil
 }
 
+// registrableParentDiscoveries derives a Subdomain -> registrable Domain edge
+// for every subdomain among traces, so the registrable parent is always in
+// the graph (and gets domain-level plugins such as WHOIS run against it)
+// no matter which plugin surfaced the subdomain.
+func registrableParentDiscoveries(traces []entities.Trace) []entities.Discovery {
+	var derived []entities.Discovery
+	done := make(map[entities.Trace]bool)
+	for _, trace := range traces {
+		if trace.Type != entities.Subdomain || done[trace] {
+			continue
+		}
+		done[trace] = true
+
+		parent, ok := entities.RegistrableDomain(trace.Value)
+		if !ok {
+			continue
+		}
+		derived = append(derived, entities.Discovery{
+			Parent:     trace,
+			PluginName: database.RegistrableParentPluginName,
+			Child:      entities.Trace{Value: parent, Type: entities.Domain},
+		})
+	}
+	return derived
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -168,3 +350,14 @@ func (e *Engine) GetWorkerPoolMetrics() interface{} {
 	}
 	return nil
 }
+
+// Collect writes the engine's plugin, trace and worker pool metrics, which
+// makes the engine a metrics.Collector for the Prometheus endpoint
+func (e *Engine) Collect(x *metrics.Exposition) {
+	if e.metrics != nil {
+		e.metrics.Collect(x)
+	}
+	if e.processor != nil {
+		e.processor.Collect(x)
+	}
+}
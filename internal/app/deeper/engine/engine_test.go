@@ -13,6 +13,7 @@ import (
 	"github.com/smirnoffmg/deeper/internal/pkg/config"
 	"github.com/smirnoffmg/deeper/internal/pkg/database"
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
 	"github.com/smirnoffmg/deeper/internal/pkg/metrics"
 	"github.com/smirnoffmg/deeper/internal/pkg/state"
 )
@@ -129,6 +130,40 @@ func TestEngine_ProcessInput_PersistsEdgeChain(t *testing.T) {
 	assert.GreaterOrEqual(t, len(reachable), 3)
 }
 
+func TestEngine_ProcessInput_RecordsPluginRuns(t *testing.T) {
+	original := state.ActivePlugins[testEngineTraceType]
+	t.Cleanup(func() {
+		if original == nil {
+			delete(state.ActivePlugins, testEngineTraceType)
+			return
+		}
+		state.ActivePlugins[testEngineTraceType] = original
+	})
+
+	state.ActivePlugins[testEngineTraceType] = nil
+	require.NoError(t, (&chainPlugin{name: "step1", input: "root", output: "hop2"}).Register())
+	require.NoError(t, (&chainPlugin{name: "step2", input: "hop2"}).Register())
+
+	eng, repo := setupEngine(t)
+	session, err := repo.CreateScanSession("root")
+	require.NoError(t, err)
+	_, err = eng.ProcessInput(context.Background(), "root", session.ID)
+	require.NoError(t, err)
+
+	runs, err := repo.GetPluginRuns(session.ID)
+	require.NoError(t, err)
+	got := make(map[string]string, len(runs))
+	for _, run := range runs {
+		got[run.Value+"/"+run.PluginName] = run.Outcome
+	}
+	assert.Equal(t, map[string]string{
+		"root/step1": database.RunOK,
+		"root/step2": database.RunEmpty,
+		"hop2/step1": database.RunEmpty,
+		"hop2/step2": database.RunEmpty,
+	}, got)
+}
+
 // TestEngine_ProcessInput_SeedNotReprocessedWhenRediscovered is a
 // regression test: the seed trace must be marked as already-seen up front,
 // so a plugin that happens to rediscover the exact same (value, type) pair
@@ -163,6 +198,44 @@ func TestEngine_ProcessInput_SeedNotReprocessedWhenRediscovered(t *testing.T) {
 	assert.Equal(t, 1, count, "seed must appear exactly once even if rediscovered as a child")
 }
 
+func TestEngine_ProcessInput_AppliesVerdicts(t *testing.T) {
+	original := state.ActivePlugins[testEngineTraceType]
+	t.Cleanup(func() {
+		if original == nil {
+			delete(state.ActivePlugins, testEngineTraceType)
+			return
+		}
+		state.ActivePlugins[testEngineTraceType] = original
+	})
+
+	state.ActivePlugins[testEngineTraceType] = nil
+	require.NoError(t, (&chainPlugin{name: "step1", input: "root", output: "hop2"}).Register())
+	require.NoError(t, (&chainPlugin{name: "step2", input: "hop2", output: "hop3"}).Register())
+	require.NoError(t, (&chainPlugin{name: "noisy", input: "root", output: "junk"}).Register())
+
+	eng, repo := setupEngine(t)
+	junkID, err := repo.GetOrCreateTrace(entities.Trace{Value: "junk", Type: testEngineTraceType})
+	require.NoError(t, err)
+	hop2ID, err := repo.GetOrCreateTrace(entities.Trace{Value: "hop2", Type: testEngineTraceType})
+	require.NoError(t, err)
+	require.NoError(t, repo.SetVerdict(&database.Verdict{TraceID: junkID, Verdict: database.VerdictFalsePositive}))
+	require.NoError(t, repo.SetVerdict(&database.Verdict{TraceID: hop2ID, PluginName: "step1", Verdict: database.VerdictIrrelevant}))
+
+	session, err := repo.CreateScanSession("root")
+	require.NoError(t, err)
+	traces, err := eng.ProcessInput(context.Background(), "root", session.ID)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, []entities.Trace{
+		{Value: "root", Type: testEngineTraceType},
+		{Value: "hop2", Type: testEngineTraceType},
+	}, traces, "junk is suppressed and hop2 is not expanded")
+
+	count, err := repo.CountEdges(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "seed edge and root->hop2 only")
+}
+
 func TestEngine_ProcessInput_MultiParentPersistence(t *testing.T) {
 	original := state.ActivePlugins[testEngineTraceType]
 	t.Cleanup(func() {
@@ -191,3 +264,279 @@ func TestEngine_ProcessInput_MultiParentPersistence(t *testing.T) {
 	// seed edge + 2 parent→shared-child edges
 	assert.Equal(t, 3, count)
 }
+
+func TestEngine_ProcessSeeds_GuessedSeedsFanOut(t *testing.T) {
+	eng, repo := setupEngine(t)
+	session, err := repo.CreateScanSession("@handle")
+	require.NoError(t, err)
+
+	seeds, err := entities.ParseSeeds("@handle")
+	require.NoError(t, err)
+	require.Greater(t, len(seeds), 1)
+
+	traces, err := eng.ProcessSeeds(context.Background(), seeds, session.ID)
+	require.NoError(t, err)
+	for _, seed := range seeds {
+		assert.Contains(t, traces, seed.Trace)
+	}
+
+	_, edges, err := repo.GetScanGraph(session.ID)
+	require.NoError(t, err)
+	guessed := 0
+	for _, edge := range edges {
+		if edge.ParentTraceID == nil {
+			assert.Equal(t, database.SeedGuessPluginName, edge.PluginName)
+			guessed++
+		}
+	}
+	assert.Equal(t, len(seeds), guessed)
+}
+
+func TestEngine_ProcessInput_ExplicitTypeNotGuessed(t *testing.T) {
+	eng, repo := setupEngine(t)
+	session, err := repo.CreateScanSession("instagram:@handle")
+	require.NoError(t, err)
+
+	traces, err := eng.ProcessInput(context.Background(), "instagram:@handle", session.ID)
+	require.NoError(t, err)
+	assert.Contains(t, traces, entities.Trace{Value: "@handle", Type: entities.Instagram})
+
+	_, edges, err := repo.GetScanGraph(session.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, edges)
+	assert.Equal(t, database.SeedPluginName, edges[0].PluginName)
+}
+
+func TestEngine_ProcessInput_SubdomainSeedDerivesRegistrableParent(t *testing.T) {
+	eng, repo := setupEngine(t)
+	session, err := repo.CreateScanSession("subdomain:mail.corp.example.co.uk")
+	require.NoError(t, err)
+
+	// No plugins are registered for Domain/Subdomain in this test binary,
+	// so only the derived edge can put the parent in the graph.
+	traces, err := eng.ProcessInput(context.Background(), "subdomain:mail.corp.example.co.uk", session.ID)
+	require.NoError(t, err)
+
+	parent := entities.Trace{Value: "example.co.uk", Type: entities.Domain}
+	assert.Contains(t, traces, parent)
+
+	parentID, err := repo.GetOrCreateTrace(parent)
+	require.NoError(t, err)
+	path, err := repo.GetDiscoveryPath(session.ID, parentID)
+	require.NoError(t, err)
+	require.Len(t, path, 1)
+	assert.Equal(t, database.RegistrableParentPluginName, path[0].PluginName)
+}
+
+func TestRegistrableParentDiscoveries_OnlySubdomains(t *testing.T) {
+	derived := registrableParentDiscoveries([]entities.Trace{
+		{Value: "example.com", Type: entities.Domain},
+		{Value: "www.example.com", Type: entities.Subdomain},
+		{Value: "www.example.com", Type: entities.Subdomain},
+		{Value: "*.example.com", Type: entities.Subdomain},
+		{Value: "someone", Type: entities.Username},
+	})
+
+	require.Len(t, derived, 2)
+	for _, d := range derived {
+		assert.Equal(t, entities.Trace{Value: "example.com", Type: entities.Domain}, d.Child)
+	}
+}
+
+func TestEngine_ProcessInput_PublishesEvents(t *testing.T) {
+	original := state.ActivePlugins[testEngineTraceType]
+	t.Cleanup(func() {
+		if original == nil {
+			delete(state.ActivePlugins, testEngineTraceType)
+			return
+		}
+		state.ActivePlugins[testEngineTraceType] = original
+	})
+
+	state.ActivePlugins[testEngineTraceType] = nil
+	require.NoError(t, (&chainPlugin{name: "step1", input: "root", output: "hop2"}).Register())
+
+	eng, repo := setupEngine(t)
+	session, err := repo.CreateScanSession("root")
+	require.NoError(t, err)
+
+	ch, unsubscribe := eng.Events().Subscribe(64)
+	var got []events.Event
+	done := make(chan struct{})
+	go func() {
+		for ev := range ch {
+			got = append(got, ev)
+		}
+		close(done)
+	}()
+
+	_, err = eng.ProcessInput(context.Background(), "username:root", session.ID)
+	require.NoError(t, err)
+	unsubscribe()
+	<-done
+
+	require.NotEmpty(t, got)
+	assert.Equal(t, events.ScanStarted, got[0].Type)
+	assert.Equal(t, events.ScanFinished, got[len(got)-1].Type)
+	assert.Equal(t, 2, got[len(got)-1].Count)
+
+	var discovered []string
+	var finished int
+	for _, ev := range got {
+		assert.Equal(t, session.ID, ev.ScanID)
+		switch ev.Type {
+		case events.TraceDiscovered:
+			discovered = append(discovered, ev.Trace.Value)
+			if ev.Trace.Value == "hop2" {
+				assert.Equal(t, "step1", ev.Plugin)
+				require.NotNil(t, ev.Parent)
+				assert.Equal(t, "root", ev.Parent.Value)
+			}
+		case events.PluginFinished:
+			if ev.Plugin == "step1" && ev.Trace.Value == "root" {
+				assert.Equal(t, 1, ev.Count)
+			}
+			finished++
+		}
+	}
+	assert.Equal(t, []string{"root", "hop2"}, discovered)
+	assert.Positive(t, finished)
+}
+
+func TestEngine_ProcessSeeds_CancelledContextEmitsBudgetExhausted(t *testing.T) {
+	eng, repo := setupEngine(t)
+	session, err := repo.CreateScanSession("root")
+	require.NoError(t, err)
+
+	ch, unsubscribe := eng.Events().Subscribe(64)
+	var got []events.Type
+	done := make(chan struct{})
+	go func() {
+		for ev := range ch {
+			got = append(got, ev.Type)
+		}
+		close(done)
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	traces, err := eng.ProcessInput(ctx, "username:root", session.ID)
+	require.NoError(t, err)
+	unsubscribe()
+	<-done
+
+	assert.Len(t, traces, 1, "only the seed")
+	assert.Contains(t, got, events.BudgetExhausted)
+}
+
+func TestEngine_ProcessSeedsWithOptions_NarrowsScan(t *testing.T) {
+	original := state.ActivePlugins[testEngineTraceType]
+	t.Cleanup(func() {
+		if original == nil {
+			delete(state.ActivePlugins, testEngineTraceType)
+			return
+		}
+		state.ActivePlugins[testEngineTraceType] = original
+	})
+
+	state.ActivePlugins[testEngineTraceType] = nil
+	require.NoError(t, (&chainPlugin{name: "step1", input: "root", output: "hop2"}).Register())
+	require.NoError(t, (&chainPlugin{name: "step2", input: "hop2", output: "hop3"}).Register())
+	require.NoError(t, (&chainPlugin{name: "other", input: "root", output: "side"}).Register())
+
+	seeds, err := entities.TypedSeeds(testEngineTraceType, "root")
+	require.NoError(t, err)
+	values := func(traces []entities.Trace) []string {
+		var out []string
+		for _, tr := range traces {
+			out = append(out, tr.Value)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"everything", Options{}, []string{"root", "hop2", "side", "hop3"}},
+		{"plugins", Options{Plugins: []string{"step1", "step2"}}, []string{"root", "hop2", "hop3"}},
+		{"depth", Options{MaxDepth: 1}, []string{"root", "hop2", "side"}},
+		{"types", Options{Types: []entities.TraceType{entities.Email}}, []string{"root", "hop2", "side"}},
+		{"trace budget", Options{MaxTraces: 1}, []string{"root"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng, repo := setupEngine(t)
+			session, err := repo.CreateScanSession("root")
+			require.NoError(t, err)
+
+			traces, err := eng.ProcessSeedsWithOptions(context.Background(), seeds, session.ID, tt.opts)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, values(traces))
+		})
+	}
+}
+
+func TestOptions_Validate(t *testing.T) {
+	plugins := map[string]bool{"step1": true}
+	assert.NoError(t, Options{Plugins: []string{"step1"}, Types: []entities.TraceType{entities.Email}}.Validate(plugins))
+	assert.ErrorContains(t, Options{Plugins: []string{"nope"}}.Validate(plugins), "unknown plugin")
+	assert.ErrorContains(t, Options{Types: []entities.TraceType{"nope"}}.Validate(plugins), "unknown trace type")
+	assert.Error(t, Options{MaxDepth: -1}.Validate(plugins))
+}
+
+func TestEngine_Expand_AddsOneHopToScan(t *testing.T) {
+	original := state.ActivePlugins[testEngineTraceType]
+	t.Cleanup(func() {
+		if original == nil {
+			delete(state.ActivePlugins, testEngineTraceType)
+			return
+		}
+		state.ActivePlugins[testEngineTraceType] = original
+	})
+
+	state.ActivePlugins[testEngineTraceType] = nil
+	require.NoError(t, (&chainPlugin{name: "step1", input: "root", output: "hop2"}).Register())
+	require.NoError(t, (&chainPlugin{name: "step2", input: "hop2", output: "hop3"}).Register())
+	require.NoError(t, (&chainPlugin{name: "other", input: "root", output: "side"}).Register())
+
+	eng, repo := setupEngine(t)
+	session, err := repo.CreateScanSession("root")
+	require.NoError(t, err)
+	seeds, err := entities.TypedSeeds(testEngineTraceType, "root")
+	require.NoError(t, err)
+	_, err = eng.ProcessSeedsWithOptions(context.Background(), seeds, session.ID, Options{Plugins: []string{"other"}})
+	require.NoError(t, err)
+
+	ch, unsubscribe := eng.Events().Subscribe(16)
+	var got []events.Event
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ev := range ch {
+			if ev.Type == events.TraceDiscovered {
+				got = append(got, ev)
+			}
+		}
+	}()
+
+	root := entities.Trace{Value: "root", Type: testEngineTraceType}
+	discoveries, err := eng.Expand(context.Background(), session.ID, root, []string{"step1", "other"})
+	require.NoError(t, err)
+	unsubscribe()
+	<-done
+
+	require.Len(t, discoveries, 2)
+	nodes, _, err := repo.GetScanGraph(session.ID)
+	require.NoError(t, err)
+	var values []string
+	for _, n := range nodes {
+		values = append(values, n.Value)
+	}
+	assert.ElementsMatch(t, []string{"root", "side", "hop2"}, values, "hop2 is added but not followed to hop3")
+
+	require.Len(t, got, 1, "side was already in the scan")
+	assert.Equal(t, "hop2", got[0].Trace.Value)
+	assert.Equal(t, session.ID, got[0].ScanID)
+}
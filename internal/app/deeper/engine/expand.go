@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/smirnoffmg/deeper/internal/app/deeper/processor"
+	"github.com/smirnoffmg/deeper/internal/pkg/audit"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
+	"github.com/smirnoffmg/deeper/internal/pkg/telemetry"
+)
+
+// Expand runs plugins, or every applicable plugin when plugins is empty,
+// against one trace and adds what they find to an existing scan. Unlike a
+// scan it goes a single hop: the new traces are not followed, so an
+// analyst can grow the graph one node at a time. Verdicts apply as they do
+// in scans, and traces new to the scan are published as TraceDiscovered.
+func (e *Engine) Expand(ctx context.Context, scanID int64, trace entities.Trace, plugins []string) (discoveries []entities.Discovery, err error) {
+	ctx, span := telemetry.Start(ctx, "expand", telemetry.WithAttributes(
+		telemetry.Int64("deeper.scan.id", scanID),
+		telemetry.String("deeper.trace.type", string(trace.Type)),
+		telemetry.String("deeper.trace.value", trace.Value),
+	))
+	defer func() {
+		span.SetAttributes(telemetry.Int("deeper.expand.discoveries", len(discoveries)))
+		span.RecordError(err)
+		span.End()
+	}()
+
+	if len(plugins) > 0 {
+		ctx = processor.WithPlugins(ctx, plugins)
+	}
+	emit := func(ev events.Event) {
+		ev.ScanID = scanID
+		e.events.Publish(ev)
+	}
+	ctx = events.WithEmitter(ctx, emit)
+	ctx = audit.WithLog(ctx, audit.NewLog(scanID, e.repo))
+	ctx = processor.WithRunRecorder(ctx, e.runRecorder(scanID))
+
+	nodes, _, err := e.repo.GetScanGraph(scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scan graph: %w", err)
+	}
+	known := make(map[entities.Trace]bool, len(nodes))
+	for _, n := range nodes {
+		known[entities.Trace{Value: n.Value, Type: n.Type}] = true
+	}
+
+	verdicts, err := e.repo.GetVerdicts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load verdicts: %w", err)
+	}
+	filter := newVerdictFilter(verdicts)
+
+	discoveries, err = e.processor.ProcessTrace(ctx, trace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand trace %v: %w", trace, err)
+	}
+	discoveries, suppressed := filter.suppress(discoveries)
+	children := make([]entities.Trace, 0, len(discoveries))
+	for _, d := range discoveries {
+		children = append(children, d.Child)
+	}
+	derived, n := filter.suppress(registrableParentDiscoveries(children))
+	suppressed += n
+	discoveries = append(discoveries, derived...)
+
+	if err := e.repo.PersistDiscoveries(scanID, discoveries); err != nil {
+		return nil, fmt.Errorf("failed to persist discoveries: %w", err)
+	}
+	for _, d := range discoveries {
+		if !known[d.Child] {
+			known[d.Child] = true
+			emitDiscovery(emit, d)
+		}
+	}
+
+	if suppressed > 0 {
+		log.Info().Msgf("Suppressed %d discoveries marked false positive", suppressed)
+	}
+	log.Info().Msgf("Expanded %v in scan %d: %d discoveries", trace, scanID, len(discoveries))
+	return discoveries, nil
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// Options narrows a scan. The zero value runs every plugin on every trace
+// the scan reaches, until the context ends.
+type Options struct {
+	// Plugins limits the scan to these plugins, by name; empty runs all.
+	Plugins []string `json:"plugins,omitempty"`
+	// Types is the scan's scope: only traces of these types are expanded.
+	// Seeds always are. Empty expands every type.
+	Types []entities.TraceType `json:"types,omitempty"`
+	// MaxDepth stops expanding traces this many discovery hops from a
+	// seed; 0 is unlimited.
+	MaxDepth int `json:"max_depth,omitempty"`
+	// MaxTraces ends the scan once it has found this many traces; 0 is
+	// unlimited.
+	MaxTraces int `json:"max_traces,omitempty"`
+	// Fresh runs every plugin again rather than skipping the (trace,
+	// plugin) pairs an earlier scan already ran, so that the scan finds
+	// everything there is to find now.
+	Fresh bool `json:"fresh,omitempty"`
+}
+
+// Validate checks the options against the registered plugins and trace
+// types.
+func (o Options) Validate(pluginNames map[string]bool) error {
+	for _, name := range o.Plugins {
+		if !pluginNames[name] {
+			return fmt.Errorf("unknown plugin %q", name)
+		}
+	}
+	for _, t := range o.Types {
+		if !entities.IsKnownTraceType(t) {
+			return fmt.Errorf("unknown trace type %q", t)
+		}
+	}
+	if o.MaxDepth < 0 || o.MaxTraces < 0 {
+		return fmt.Errorf("max_depth and max_traces must not be negative")
+	}
+	return nil
+}
+
+// follows reports whether a trace found depth hops from a seed is expanded.
+func (o Options) follows(t entities.Trace, depth int) bool {
+	if o.MaxDepth > 0 && depth >= o.MaxDepth {
+		return false
+	}
+	if len(o.Types) == 0 {
+		return true
+	}
+	for _, typ := range o.Types {
+		if typ == t.Type {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// verdictFilter applies analysts' verdicts from earlier scans to a scan's
+// discoveries: false positives are dropped before they reach the graph,
+// and irrelevant traces are kept but not expanded. Seeds are never
+// filtered; the analyst asked for them.
+type verdictFilter struct {
+	traces map[entities.Trace]string
+	edges  map[pluginChild]string
+}
+
+type pluginChild struct {
+	child  entities.Trace
+	plugin string
+}
+
+func newVerdictFilter(verdicts []database.Verdict) *verdictFilter {
+	f := &verdictFilter{traces: make(map[entities.Trace]string), edges: make(map[pluginChild]string)}
+	for _, v := range verdicts {
+		trace := entities.Trace{Value: v.Value, Type: v.Type}
+		if v.PluginName == "" {
+			f.traces[trace] = v.Verdict
+		} else {
+			f.edges[pluginChild{trace, v.PluginName}] = v.Verdict
+		}
+	}
+	return f
+}
+
+// verdict returns the verdict that applies to a discovery: one on the
+// plugin's edge into the child, else one on the child itself.
+func (f *verdictFilter) verdict(d entities.Discovery) string {
+	if v, ok := f.edges[pluginChild{d.Child, d.PluginName}]; ok {
+		return v
+	}
+	return f.traces[d.Child]
+}
+
+// suppress drops the discoveries marked false positive and returns the
+// rest with how many were dropped.
+func (f *verdictFilter) suppress(discoveries []entities.Discovery) ([]entities.Discovery, int) {
+	if len(f.traces) == 0 && len(f.edges) == 0 {
+		return discoveries, 0
+	}
+	kept := discoveries[:0:0]
+	for _, d := range discoveries {
+		if f.verdict(d) != database.VerdictFalsePositive {
+			kept = append(kept, d)
+		}
+	}
+	return kept, len(discoveries) - len(kept)
+}
+
+// expand reports whether a newly discovered child should be followed.
+func (f *verdictFilter) expand(d entities.Discovery) bool {
+	return f.verdict(d) != database.VerdictIrrelevant
+}
@@ -0,0 +1,100 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteCypher writes g as Cypher statements for Neo4j, one per line.
+//
+// The script is idempotent: a uniqueness constraint on Trace.key (type and
+// value, the same identity deeper uses) lets every node be MERGEd, and each
+// DISCOVERED relationship is MERGEd on its plugin and scan. Loading it
+// twice, or loading exports of overlapping scans, never duplicates
+// anything. Nodes carry only deeper's own properties, so the file can be
+// loaded into an empty database with cypher-shell.
+func WriteCypher(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+
+	_, _ = fmt.Fprintf(bw, "// deeper scan graph, scans %s\n", joinIDs(g.ScanIDs))
+	_, _ = fmt.Fprintln(bw, "CREATE CONSTRAINT deeper_trace_key IF NOT EXISTS FOR (t:Trace) REQUIRE t.key IS UNIQUE;")
+
+	for _, n := range g.Nodes {
+		props := []string{
+			"t.value = " + cypherString(n.Value),
+			"t.type = " + cypherString(string(n.Type)),
+			"t.first_seen = datetime(" + cypherString(timestamp(n.FirstSeen)) + ")",
+		}
+		if metadata := n.MetadataJSON(); metadata != "" {
+			props = append(props, "t.metadata = "+cypherString(metadata))
+		}
+		if n.Seed {
+			props = append(props, "t.seed = true")
+		}
+		if n.Verdict != "" {
+			props = append(props, "t.verdict = "+cypherString(n.Verdict))
+		}
+		_, _ = fmt.Fprintf(bw, "MERGE (t:Trace {key: %s}) SET %s;\n", cypherString(traceKey(n)), strings.Join(props, ", "))
+	}
+
+	nodes := make(map[int64]Node, len(g.Nodes))
+	for _, n := range g.Nodes {
+		nodes[n.ID] = n
+	}
+	for _, e := range g.Edges {
+		from, okFrom := nodes[e.From]
+		to, okTo := nodes[e.To]
+		if !okFrom || !okTo {
+			continue
+		}
+		verdict := ""
+		if e.Verdict != "" {
+			verdict = ", r.verdict = " + cypherString(e.Verdict)
+		}
+		_, _ = fmt.Fprintf(bw,
+			"MATCH (a:Trace {key: %s}), (b:Trace {key: %s}) MERGE (a)-[r:DISCOVERED {plugin: %s, scan_id: %d}]->(b) SET r.discovered_at = datetime(%s)%s;\n",
+			cypherString(traceKey(from)), cypherString(traceKey(to)),
+			cypherString(e.Plugin), e.ScanID, cypherString(timestamp(e.DiscoveredAt)), verdict)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// traceKey is a trace's identity independent of any database's row IDs, so
+// exports from different deeper databases merge correctly in Neo4j.
+func traceKey(n Node) string {
+	return string(n.Type) + ":" + n.Value
+}
+
+// cypherString renders s as a single-quoted Cypher string literal.
+func cypherString(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for _, r := range s {
+		switch r {
+		case '\\':
+			b.WriteString(`\\`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04x`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
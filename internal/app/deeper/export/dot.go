@@ -0,0 +1,72 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteDOT writes g as a Graphviz digraph. Nodes are labelled with their
+// type and value; edges with the plugin and scan that produced them.
+// Metadata is omitted: DOT attributes are for rendering, not data.
+func WriteDOT(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+
+	_, _ = fmt.Fprintf(bw, "digraph deeper {\n")
+	_, _ = fmt.Fprintf(bw, "  // scans: %s\n", joinIDs(g.ScanIDs))
+	_, _ = fmt.Fprintf(bw, "  rankdir=LR;\n")
+	_, _ = fmt.Fprintf(bw, "  node [shape=box, style=rounded];\n")
+
+	for _, n := range g.Nodes {
+		attrs := []string{
+			"label=" + dotQuote(string(n.Type)+"\n"+n.Value),
+			"type=" + dotQuote(string(n.Type)),
+		}
+		if n.Seed {
+			attrs = append(attrs, "penwidth=2")
+		}
+		if n.Verdict != "" {
+			attrs = append(attrs, "verdict="+dotQuote(n.Verdict))
+		}
+		_, _ = fmt.Fprintf(bw, "  %s [%s];\n", n.Key(), strings.Join(attrs, ", "))
+	}
+
+	for _, e := range g.Edges {
+		verdict := ""
+		if e.Verdict != "" {
+			verdict = ", verdict=" + dotQuote(e.Verdict)
+		}
+		_, _ = fmt.Fprintf(bw, "  %s -> %s [label=%s, plugin=%s, scan_id=%d, discovered_at=%s%s];\n",
+			Node{ID: e.From}.Key(), Node{ID: e.To}.Key(),
+			dotQuote(e.Plugin), dotQuote(e.Plugin), e.ScanID, dotQuote(timestamp(e.DiscoveredAt)), verdict)
+	}
+
+	_, _ = fmt.Fprintf(bw, "}\n")
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// dotQuote renders s as a DOT double-quoted string. Newlines become DOT's
+// centered line break; other control characters are dropped.
+func dotQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r < 0x20 || r == 0x7f:
+			// dropped
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
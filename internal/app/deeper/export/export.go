@@ -0,0 +1,270 @@
+// Package export writes stored scan graphs in interchange formats for
+// external tools: GraphML (yEd, Gephi), GEXF (Gephi), DOT (Graphviz),
+// Cypher (Neo4j), STIX 2.1 (MISP, OpenCTI) and MTGX (Maltego).
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// Format names an export format, as given to `deeper export --format`.
+type Format string
+
+const (
+	FormatGraphML Format = "graphml"
+	FormatGEXF    Format = "gexf"
+	FormatDOT     Format = "dot"
+	FormatCypher  Format = "cypher"
+	FormatSTIX    Format = "stix"
+	FormatMaltego Format = "maltego"
+)
+
+// Formats lists every supported format.
+var Formats = []Format{FormatGraphML, FormatGEXF, FormatDOT, FormatCypher, FormatSTIX, FormatMaltego}
+
+// DefaultTLP is the TLP marking of STIX exports unless another is chosen.
+const DefaultTLP = "amber"
+
+// Options tunes formats that carry provenance. Graph-tool formats ignore it.
+type Options struct {
+	// Author names the organization STIX objects are created_by; empty
+	// attributes them to deeper.
+	Author string
+	// TLP is the marking applied to STIX objects: white, green, amber or red.
+	TLP string
+}
+
+// Node is a trace in an exported graph. Traces are shared across scans, so
+// a node appears once however many of the exported scans reached it.
+type Node struct {
+	ID        int64
+	Value     string
+	Type      entities.TraceType
+	FirstSeen time.Time
+	// Seed is set when the trace was a starting point of any exported scan.
+	Seed     bool
+	Metadata map[string]interface{}
+	// Scans lists the exported scans that reached the trace.
+	Scans []int64
+	// Verdict is the analyst's verdict on the trace, if any.
+	Verdict string
+}
+
+// Key is the node's identifier in exported files. It is derived from the
+// database ID, so exports of different scans from one database agree on it.
+func (n Node) Key() string {
+	return fmt.Sprintf("t%d", n.ID)
+}
+
+// MetadataJSON returns the node's metadata as a JSON object string, or ""
+// when it has none. Formats without nested values store it this way.
+func (n Node) MetadataJSON() string {
+	if len(n.Metadata) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(n.Metadata)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Edge is one discovery in one scan. The same parent and child can be
+// joined by several edges: one per plugin and scan that found the link.
+type Edge struct {
+	From         int64
+	To           int64
+	Plugin       string
+	ScanID       int64
+	DiscoveredAt time.Time
+	// Verdict is the analyst's verdict on the plugin's edges into To, if any.
+	Verdict string
+}
+
+// Graph is the union of one or more scans' graphs.
+type Graph struct {
+	ScanIDs []int64
+	Scans   []database.ScanSession
+	Nodes   []Node
+	Edges   []Edge
+}
+
+// Load reads and merges the graphs of the given scans. Seed edges, which
+// have no parent, are not exported as edges; they mark their child as a
+// seed instead.
+func Load(repo *database.Repository, scanIDs []int64) (*Graph, error) {
+	if len(scanIDs) == 0 {
+		return nil, fmt.Errorf("no scans to export")
+	}
+
+	b := newBuilder()
+	for _, scanID := range scanIDs {
+		if b.hasScan(scanID) {
+			continue
+		}
+
+		session, err := repo.GetScanSession(scanID)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil {
+			return nil, fmt.Errorf("scan %d not found", scanID)
+		}
+
+		traces, edges, err := repo.GetScanGraph(scanID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load graph of scan %d: %w", scanID, err)
+		}
+		b.add(*session, traces, edges)
+	}
+
+	verdicts, err := repo.GetVerdicts()
+	if err != nil {
+		return nil, err
+	}
+	g := b.graph()
+	g.ApplyVerdicts(database.NewVerdictIndex(verdicts))
+	return g, nil
+}
+
+// ApplyVerdicts marks nodes and edges with the analysts' verdicts on them.
+func (g *Graph) ApplyVerdicts(verdicts *database.VerdictIndex) {
+	for i, n := range g.Nodes {
+		if v, ok := verdicts.Trace(n.ID); ok {
+			g.Nodes[i].Verdict = v.Verdict
+		}
+	}
+	for i, e := range g.Edges {
+		if v, ok := verdicts.Edge(e.To, e.Plugin); ok {
+			g.Edges[i].Verdict = v.Verdict
+		}
+	}
+}
+
+// FromScan builds the export graph of part of one scan, such as a
+// subgraph cut out of it.
+func FromScan(session database.ScanSession, traces []database.Trace, edges []database.TraceEdge) *Graph {
+	b := newBuilder()
+	b.add(session, traces, edges)
+	return b.graph()
+}
+
+type builder struct {
+	g     *Graph
+	nodes map[int64]*Node
+	scans map[int64]bool
+}
+
+func newBuilder() *builder {
+	return &builder{g: &Graph{}, nodes: make(map[int64]*Node), scans: make(map[int64]bool)}
+}
+
+func (b *builder) hasScan(scanID int64) bool {
+	return b.scans[scanID]
+}
+
+func (b *builder) add(session database.ScanSession, traces []database.Trace, edges []database.TraceEdge) {
+	b.scans[session.ID] = true
+	b.g.ScanIDs = append(b.g.ScanIDs, session.ID)
+	b.g.Scans = append(b.g.Scans, session)
+
+	for _, t := range traces {
+		n, ok := b.nodes[t.ID]
+		if !ok {
+			n = &Node{ID: t.ID, Value: t.Value, Type: t.Type, FirstSeen: t.DiscoveredAt, Metadata: t.Metadata}
+			b.nodes[t.ID] = n
+		}
+		n.Scans = append(n.Scans, session.ID)
+	}
+	for _, e := range edges {
+		if e.ParentTraceID == nil {
+			if n, ok := b.nodes[e.ChildTraceID]; ok {
+				n.Seed = true
+			}
+			continue
+		}
+		b.g.Edges = append(b.g.Edges, Edge{
+			From:         *e.ParentTraceID,
+			To:           e.ChildTraceID,
+			Plugin:       e.PluginName,
+			ScanID:       e.ScanID,
+			DiscoveredAt: e.DiscoveredAt,
+		})
+	}
+}
+
+func (b *builder) graph() *Graph {
+	for _, n := range b.nodes {
+		b.g.Nodes = append(b.g.Nodes, *n)
+	}
+	b.g.sort()
+	return b.g
+}
+
+// sort orders nodes by ID and edges by scan, then discovery time, so
+// repeated exports of the same scans are byte-identical.
+func (g *Graph) sort() {
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.SliceStable(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.ScanID != b.ScanID {
+			return a.ScanID < b.ScanID
+		}
+		if !a.DiscoveredAt.Equal(b.DiscoveredAt) {
+			return a.DiscoveredAt.Before(b.DiscoveredAt)
+		}
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Plugin < b.Plugin
+	})
+}
+
+// Write serializes g to w in the given format.
+func Write(w io.Writer, g *Graph, format Format, opts Options) error {
+	switch format {
+	case FormatGraphML:
+		return WriteGraphML(w, g)
+	case FormatGEXF:
+		return WriteGEXF(w, g)
+	case FormatDOT:
+		return WriteDOT(w, g)
+	case FormatCypher:
+		return WriteCypher(w, g)
+	case FormatSTIX:
+		return WriteSTIX(w, g, opts)
+	case FormatMaltego:
+		return WriteMaltego(w, g)
+	default:
+		names := make([]string, len(Formats))
+		for i, f := range Formats {
+			names[i] = string(f)
+		}
+		return fmt.Errorf("unsupported export format %q (supported: %s)", format, strings.Join(names, ", "))
+	}
+}
+
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatInt(id, 10)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// timestamp formats times the same way in every export format.
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
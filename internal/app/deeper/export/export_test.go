@@ -0,0 +1,386 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func testGraph() *Graph {
+	at := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	return &Graph{
+		ScanIDs: []int64{1},
+		Scans:   []database.ScanSession{{ID: 1, Input: "o'brien", StartedAt: at, Status: "completed"}},
+		Nodes: []Node{
+			{ID: 1, Value: `o'brien "the" <admin>`, Type: entities.Username, FirstSeen: at, Seed: true, Scans: []int64{1}},
+			{ID: 2, Value: "ob@example.com", Type: entities.Email, FirstSeen: at, Metadata: map[string]interface{}{"source": "profile"}, Scans: []int64{1}},
+		},
+		Edges: []Edge{{From: 1, To: 2, Plugin: "GitHubProfilePlugin", ScanID: 1, DiscoveredAt: at}},
+	}
+}
+
+func newTestRepo(t *testing.T) *database.Repository {
+	t.Helper()
+	db, err := database.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	return database.NewRepository(db)
+}
+
+func TestLoad_UnionOfScansSharesNodes(t *testing.T) {
+	repo := newTestRepo(t)
+	root := entities.Trace{Value: "root.com", Type: entities.Domain}
+
+	var scanIDs []int64
+	for _, child := range []string{"a.root.com", "b.root.com"} {
+		session, err := repo.CreateScanSession("root.com")
+		require.NoError(t, err)
+		rootID, err := repo.GetOrCreateTrace(root)
+		require.NoError(t, err)
+		require.NoError(t, repo.InsertEdge(&database.TraceEdge{ChildTraceID: rootID, PluginName: database.SeedPluginName, ScanID: session.ID, DiscoveredAt: time.Now()}))
+		require.NoError(t, repo.PersistDiscoveries(session.ID, []entities.Discovery{
+			{Parent: root, PluginName: "CrtShPlugin", Child: entities.Trace{Value: child, Type: entities.Subdomain}},
+		}))
+		scanIDs = append(scanIDs, session.ID)
+	}
+
+	graph, err := Load(repo, append(scanIDs, scanIDs[0]))
+	require.NoError(t, err)
+	assert.Equal(t, scanIDs, graph.ScanIDs, "duplicate scan IDs are ignored")
+	assert.Len(t, graph.Nodes, 3, "root.com appears once")
+	require.Len(t, graph.Edges, 2, "seed edges are not exported")
+	assert.Equal(t, scanIDs[0], graph.Edges[0].ScanID)
+	assert.Equal(t, scanIDs[1], graph.Edges[1].ScanID)
+	require.Len(t, graph.Scans, 2)
+	for _, n := range graph.Nodes {
+		assert.Equal(t, n.Value == "root.com", n.Seed)
+		if n.Value == "root.com" {
+			assert.Equal(t, scanIDs, n.Scans)
+		} else {
+			assert.Len(t, n.Scans, 1)
+		}
+	}
+
+	_, err = Load(repo, []int64{9999})
+	assert.Error(t, err)
+}
+
+func TestWriteGraphML_WellFormed(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteGraphML(&buf, testGraph()))
+
+	var doc graphmlDoc
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	require.Len(t, doc.Graph.Nodes, 2)
+	assert.Equal(t, "t1", doc.Graph.Nodes[0].ID)
+	assert.Contains(t, doc.Graph.Nodes[0].Data, graphmlData{Key: "value", Value: `o'brien "the" <admin>`})
+	assert.Contains(t, doc.Graph.Nodes[1].Data, graphmlData{Key: "metadata", Value: `{"source":"profile"}`})
+	require.Len(t, doc.Graph.Edges, 1)
+	assert.Equal(t, "t2", doc.Graph.Edges[0].Target)
+	assert.Contains(t, doc.Graph.Edges[0].Data, graphmlData{Key: "scan_id", Value: "1"})
+}
+
+func TestWriteGEXF_WellFormed(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteGEXF(&buf, testGraph()))
+
+	var doc gexfDoc
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	assert.Equal(t, "1.3", doc.Version)
+	require.Len(t, doc.Graph.Nodes, 2)
+	assert.Equal(t, `o'brien "the" <admin>`, doc.Graph.Nodes[0].Label)
+	require.Len(t, doc.Graph.Edges, 1)
+	assert.Equal(t, "GitHubProfilePlugin", doc.Graph.Edges[0].Label)
+}
+
+func TestWriteDOT_EscapesLabels(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteDOT(&buf, testGraph()))
+	out := buf.String()
+
+	assert.True(t, strings.HasPrefix(out, "digraph deeper {"))
+	assert.Contains(t, out, `label="username\no'brien \"the\" <admin>"`)
+	assert.Contains(t, out, "t1 -> t2")
+	assert.Contains(t, out, "penwidth=2")
+}
+
+func TestWriteCypher_IdempotentMerges(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteCypher(&buf, testGraph()))
+	out := buf.String()
+
+	assert.Contains(t, out, "CREATE CONSTRAINT deeper_trace_key IF NOT EXISTS")
+	assert.NotContains(t, out, "CREATE (", "only MERGE creates data")
+	assert.Contains(t, out, `MERGE (t:Trace {key: 'username:o\'brien "the" <admin>'})`)
+	assert.Contains(t, out, `t.metadata = '{"source":"profile"}'`)
+	assert.Contains(t, out, "MERGE (a)-[r:DISCOVERED {plugin: 'GitHubProfilePlugin', scan_id: 1}]->(b)")
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		if !strings.HasPrefix(line, "//") {
+			assert.True(t, strings.HasSuffix(line, ";"), line)
+		}
+	}
+}
+
+func TestCypherString(t *testing.T) {
+	assert.Equal(t, `'a\\b\'c\nd\u0001'`, cypherString("a\\b'c\nd\x01"))
+}
+
+func TestWrite_DeterministicAndRejectsUnknownFormat(t *testing.T) {
+	for _, format := range Formats {
+		var a, b bytes.Buffer
+		require.NoError(t, Write(&a, testGraph(), format, Options{}))
+		require.NoError(t, Write(&b, testGraph(), format, Options{}))
+		assert.Equal(t, a.String(), b.String(), format)
+	}
+
+	err := Write(&bytes.Buffer{}, testGraph(), "pdf", Options{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "graphml")
+}
+
+func stixObjects(t *testing.T, g *Graph, opts Options) []map[string]interface{} {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, WriteSTIX(&buf, g, opts))
+	var bundle struct {
+		Type    string                   `json:"type"`
+		ID      string                   `json:"id"`
+		Objects []map[string]interface{} `json:"objects"`
+	}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &bundle))
+	assert.Equal(t, "bundle", bundle.Type)
+	assert.True(t, strings.HasPrefix(bundle.ID, "bundle--"))
+	return bundle.Objects
+}
+
+func stixOfType(objects []map[string]interface{}, typ string) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, obj := range objects {
+		if obj["type"] == typ {
+			out = append(out, obj)
+		}
+	}
+	return out
+}
+
+func TestWriteSTIX_MapsTracesToObservables(t *testing.T) {
+	at := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	g := &Graph{
+		ScanIDs: []int64{7},
+		Scans:   []database.ScanSession{{ID: 7, Input: "example.com", StartedAt: at}},
+		Nodes: []Node{
+			{ID: 1, Value: "example.com", Type: entities.Domain, Seed: true, Scans: []int64{7}},
+			{ID: 2, Value: "93.184.216.34", Type: entities.IpAddr, Scans: []int64{7}},
+			{ID: 3, Value: "AS15133", Type: entities.ASN, Scans: []int64{7}},
+			{ID: 4, Value: "2001:db8::1", Type: entities.IpAddr, Scans: []int64{7}},
+			{ID: 5, Value: "https://github.com/octocat", Type: entities.Github, Scans: []int64{7}},
+			{ID: 6, Value: "admin@example.com", Type: entities.Email, Scans: []int64{7}},
+			{ID: 7, Value: "+1 555 0100", Type: entities.Phone, Scans: []int64{7}},
+			{ID: 8, Value: "https://example.com/about", Type: entities.Url, Scans: []int64{7}},
+		},
+		Edges: []Edge{
+			{From: 1, To: 2, Plugin: "DNSResolverPlugin", ScanID: 7, DiscoveredAt: at},
+			{From: 2, To: 3, Plugin: "IPIntelPlugin", ScanID: 7, DiscoveredAt: at},
+			{From: 1, To: 7, Plugin: "WhoisPlugin", ScanID: 7, DiscoveredAt: at},
+		},
+	}
+	objects := stixObjects(t, g, Options{Author: "ACME CTI", TLP: "green"})
+
+	domains := stixOfType(objects, "domain-name")
+	require.Len(t, domains, 1)
+	// UUIDv5 of {"value":"example.com"} in the STIX SCO namespace.
+	assert.Equal(t, "domain-name--bedb4899-d24b-5401-bc86-8f6b4cc18ec7", domains[0]["id"])
+	require.Len(t, stixOfType(objects, "ipv4-addr"), 1)
+	require.Len(t, stixOfType(objects, "ipv6-addr"), 1)
+	require.Len(t, stixOfType(objects, "email-addr"), 1)
+	require.Len(t, stixOfType(objects, "url"), 1)
+	as := stixOfType(objects, "autonomous-system")
+	require.Len(t, as, 1)
+	assert.Equal(t, float64(15133), as[0]["number"])
+	accounts := stixOfType(objects, "user-account")
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "github", accounts[0]["account_type"])
+	assert.Equal(t, "octocat", accounts[0]["account_login"])
+
+	rels := stixOfType(objects, "relationship")
+	require.Len(t, rels, 2, "edges to unmapped traces are dropped")
+	assert.Equal(t, "resolves-to", rels[0]["relationship_type"])
+	assert.Equal(t, "belongs-to", rels[1]["relationship_type"])
+	assert.Equal(t, "DNSResolverPlugin", rels[0]["x_deeper_plugin"])
+
+	var author map[string]interface{}
+	for _, identity := range stixOfType(objects, "identity") {
+		if identity["name"] == "ACME CTI" {
+			author = identity
+		}
+	}
+	require.NotNil(t, author)
+	markings := stixOfType(objects, "marking-definition")
+	require.Len(t, markings, 1)
+	assert.Equal(t, "TLP:GREEN", markings[0]["name"])
+
+	reports := stixOfType(objects, "report")
+	require.Len(t, reports, 1)
+	report := reports[0]
+	assert.Equal(t, author["id"], report["created_by_ref"])
+	assert.Equal(t, []interface{}{markings[0]["id"]}, report["object_marking_refs"])
+	assert.Len(t, report["object_refs"], 9, "seven observables and two relationships")
+	assert.Contains(t, report["description"], "1 traces with no STIX equivalent")
+	for _, rel := range rels {
+		assert.Equal(t, author["id"], rel["created_by_ref"])
+	}
+}
+
+func TestWriteSTIX_OnlyDNSResolutionIsResolvesTo(t *testing.T) {
+	at := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	g := &Graph{
+		ScanIDs: []int64{7},
+		Scans:   []database.ScanSession{{ID: 7, Input: "example.com", StartedAt: at}},
+		Nodes: []Node{
+			{ID: 1, Value: "example.com", Type: entities.Domain, Seed: true, Scans: []int64{7}},
+			{ID: 2, Value: "shop.example.com", Type: entities.Subdomain, Scans: []int64{7}},
+			{ID: 3, Value: "shops.myshopify.com.", Type: entities.DnsRecordCNAME, Scans: []int64{7}},
+		},
+		Edges: []Edge{
+			{From: 1, To: 2, Plugin: "CrtShPlugin", ScanID: 7, DiscoveredAt: at},
+			{From: 2, To: 3, Plugin: "DNSRecordsPlugin", ScanID: 7, DiscoveredAt: at},
+		},
+	}
+	objects := stixObjects(t, g, Options{})
+
+	assert.Len(t, stixOfType(objects, "domain-name"), 3)
+	types := make(map[string]string)
+	for _, rel := range stixOfType(objects, "relationship") {
+		types[rel["x_deeper_plugin"].(string)] = rel["relationship_type"].(string)
+	}
+	assert.Equal(t, map[string]string{
+		"CrtShPlugin":      "related-to",
+		"DNSRecordsPlugin": "resolves-to",
+	}, types)
+}
+
+func TestWriteSTIX_NamesBecomeIdentities(t *testing.T) {
+	g := testGraph()
+	g.Nodes = append(g.Nodes, Node{ID: 3, Value: "Jane Doe", Type: entities.Name, Scans: []int64{1}})
+	objects := stixObjects(t, g, Options{})
+
+	var classes []string
+	for _, identity := range stixOfType(objects, "identity") {
+		classes = append(classes, identity["identity_class"].(string))
+	}
+	assert.ElementsMatch(t, []string{"system", "individual"}, classes)
+	assert.Equal(t, "TLP:AMBER", stixOfType(objects, "marking-definition")[0]["name"])
+}
+
+func TestWrite_CarriesVerdicts(t *testing.T) {
+	g := testGraph()
+	g.ApplyVerdicts(database.NewVerdictIndex([]database.Verdict{
+		{TraceID: 2, Verdict: database.VerdictConfirmed},
+		{TraceID: 2, PluginName: "GitHubProfilePlugin", Verdict: database.VerdictFalsePositive},
+	}))
+	assert.Equal(t, database.VerdictConfirmed, g.Nodes[1].Verdict)
+	assert.Empty(t, g.Nodes[0].Verdict)
+	assert.Equal(t, database.VerdictFalsePositive, g.Edges[0].Verdict)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCypher(&buf, g))
+	assert.Contains(t, buf.String(), "t.verdict = 'confirmed'")
+	assert.Contains(t, buf.String(), "r.verdict = 'false_positive'")
+
+	buf.Reset()
+	require.NoError(t, WriteGraphML(&buf, g))
+	assert.Contains(t, buf.String(), `<data key="edge_verdict">false_positive</data>`)
+
+	opinions := stixOfType(stixObjects(t, g, Options{}), "opinion")
+	require.Len(t, opinions, 1)
+	assert.Equal(t, "strongly-agree", opinions[0]["opinion"])
+}
+
+func TestWriteSTIX_RejectsUnknownTLP(t *testing.T) {
+	err := WriteSTIX(&bytes.Buffer{}, testGraph(), Options{TLP: "purple"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "purple")
+}
+
+func TestMaltego_RoundTrip(t *testing.T) {
+	at := time.Date(2026, 3, 4, 5, 6, 7, 0, time.UTC)
+	g := &Graph{
+		ScanIDs: []int64{1},
+		Scans:   []database.ScanSession{{ID: 1, Input: "jdoe", StartedAt: at}},
+		Nodes: []Node{
+			{ID: 10, Value: "jdoe", Type: entities.Username},
+			{ID: 11, Value: "jdoe@example.com", Type: entities.Email},
+			{ID: 12, Value: "2001:db8::1", Type: entities.IpAddr},
+			{ID: 13, Value: "AS64496", Type: entities.ASN},
+			{ID: 14, Value: "ssh-ed25519 AAAA", Type: entities.SSHKey},
+		},
+		Edges: []Edge{
+			{From: 10, To: 11, Plugin: "GitHubProfilePlugin", ScanID: 1, DiscoveredAt: at},
+			{From: 12, To: 13, Plugin: "IPIntelPlugin", ScanID: 1, DiscoveredAt: at},
+		},
+	}
+	path := filepath.Join(t.TempDir(), "graph.mtgx")
+	var buf bytes.Buffer
+	require.NoError(t, WriteMaltego(&buf, g))
+	require.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+
+	graphml, err := mtgxGraph(buf.Bytes())
+	require.NoError(t, err)
+	assert.Contains(t, string(graphml), `<mtg:MaltegoEntity type="maltego.Alias">`)
+	assert.Contains(t, string(graphml), `<mtg:MaltegoEntity type="maltego.IPv6Address">`)
+	assert.Contains(t, string(graphml), `<mtg:Value>64496</mtg:Value>`)
+
+	read, err := ReadMaltego(path)
+	require.NoError(t, err)
+	require.Len(t, read.Nodes, 5)
+	for i, n := range read.Nodes {
+		assert.Equal(t, g.Nodes[i].Value, n.Value)
+		assert.Equal(t, g.Nodes[i].Type, n.Type)
+	}
+	require.Len(t, read.Edges, 2)
+	assert.Equal(t, Edge{From: 1, To: 2, Plugin: "GitHubProfilePlugin"}, read.Edges[0])
+	assert.Equal(t, Edge{From: 3, To: 4, Plugin: "IPIntelPlugin"}, read.Edges[1])
+}
+
+func TestReadMaltego_GraphFromMaltego(t *testing.T) {
+	const graphml = `<?xml version="1.0" encoding="UTF-8"?>
+<graphml xmlns="http://graphml.graphdrawing.org/xmlns" xmlns:mtg="http://maltego.paterva.com/xml/mtgx">
+  <graph edgedefault="directed" id="G">
+    <node id="n0"><data key="d0"><mtg:MaltegoEntity type="maltego.Domain"><mtg:Properties>
+      <mtg:Property name="fqdn" type="string"><mtg:Value>example.com</mtg:Value></mtg:Property>
+      <mtg:Property name="whois-info" type="string"><mtg:Value></mtg:Value></mtg:Property>
+    </mtg:Properties></mtg:MaltegoEntity></data></node>
+    <node id="n1"><data key="d0"><mtg:MaltegoEntity type="maltego.DNSName"><mtg:Properties>
+      <mtg:Property name="fqdn" type="string"><mtg:Value>www.example.com</mtg:Value></mtg:Property>
+    </mtg:Properties></mtg:MaltegoEntity></data></node>
+    <node id="n2"><data key="d0"><mtg:MaltegoEntity type="acme.CustomThing"><mtg:Properties>
+      <mtg:Property name="acme.value" type="string"><mtg:Value>someone@example.com</mtg:Value></mtg:Property>
+    </mtg:Properties></mtg:MaltegoEntity></data></node>
+    <node id="n3"><data key="d0"><mtg:MaltegoEntity type="maltego.Phrase"><mtg:Properties/></mtg:MaltegoEntity></data></node>
+    <edge id="e0" source="n0" target="n1"><data key="d1"><mtg:MaltegoLink type="maltego.link.transform-link"/></data></edge>
+    <edge id="e1" source="n0" target="n3"><data key="d1"><mtg:MaltegoLink type="maltego.link.manual-link"/></data></edge>
+  </graph>
+</graphml>`
+	path := filepath.Join(t.TempDir(), "graph.graphml")
+	require.NoError(t, os.WriteFile(path, []byte(graphml), 0o644))
+
+	g, err := ReadMaltego(path)
+	require.NoError(t, err)
+	require.Len(t, g.Nodes, 3, "entities without a value are skipped")
+	assert.Equal(t, entities.Domain, g.Nodes[0].Type)
+	assert.Equal(t, entities.Subdomain, g.Nodes[1].Type)
+	assert.Equal(t, entities.Email, g.Nodes[2].Type, "unknown entity types are classified by value")
+	require.Len(t, g.Edges, 1)
+	assert.Equal(t, database.MaltegoPluginName, g.Edges[0].Plugin)
+}
@@ -0,0 +1,127 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type gexfDoc struct {
+	XMLName xml.Name  `xml:"gexf"`
+	Xmlns   string    `xml:"xmlns,attr"`
+	Version string    `xml:"version,attr"`
+	Meta    gexfMeta  `xml:"meta"`
+	Graph   gexfGraph `xml:"graph"`
+}
+
+type gexfMeta struct {
+	Creator     string `xml:"creator"`
+	Description string `xml:"description"`
+}
+
+type gexfGraph struct {
+	DefaultEdgeType string           `xml:"defaultedgetype,attr"`
+	Mode            string           `xml:"mode,attr"`
+	Attributes      []gexfAttributes `xml:"attributes"`
+	Nodes           []gexfNode       `xml:"nodes>node"`
+	Edges           []gexfEdge       `xml:"edges>edge"`
+}
+
+type gexfAttributes struct {
+	Class      string          `xml:"class,attr"`
+	Attributes []gexfAttribute `xml:"attribute"`
+}
+
+type gexfAttribute struct {
+	ID    string `xml:"id,attr"`
+	Title string `xml:"title,attr"`
+	Type  string `xml:"type,attr"`
+}
+
+type gexfNode struct {
+	ID     string          `xml:"id,attr"`
+	Label  string          `xml:"label,attr"`
+	Values []gexfAttrValue `xml:"attvalues>attvalue"`
+}
+
+type gexfEdge struct {
+	ID     string          `xml:"id,attr"`
+	Source string          `xml:"source,attr"`
+	Target string          `xml:"target,attr"`
+	Label  string          `xml:"label,attr"`
+	Values []gexfAttrValue `xml:"attvalues>attvalue"`
+}
+
+type gexfAttrValue struct {
+	For   string `xml:"for,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// WriteGEXF writes g as GEXF 1.3 for Gephi. Node labels are trace values;
+// type, seed flag, first-seen time, metadata (as JSON) and any analyst
+// verdict are attributes, so Gephi can partition and color by them.
+func WriteGEXF(w io.Writer, g *Graph) error {
+	doc := gexfDoc{
+		Xmlns:   "http://gexf.net/1.3",
+		Version: "1.3",
+		Meta: gexfMeta{
+			Creator:     "deeper",
+			Description: fmt.Sprintf("deeper scan graph (scans %s)", joinIDs(g.ScanIDs)),
+		},
+		Graph: gexfGraph{
+			DefaultEdgeType: "directed",
+			Mode:            "static",
+			Attributes: []gexfAttributes{
+				{Class: "node", Attributes: []gexfAttribute{
+					{ID: "type", Title: "type", Type: "string"},
+					{ID: "seed", Title: "seed", Type: "boolean"},
+					{ID: "first_seen", Title: "first_seen", Type: "string"},
+					{ID: "metadata", Title: "metadata", Type: "string"},
+					{ID: "verdict", Title: "verdict", Type: "string"},
+				}},
+				{Class: "edge", Attributes: []gexfAttribute{
+					{ID: "plugin", Title: "plugin", Type: "string"},
+					{ID: "scan_id", Title: "scan_id", Type: "long"},
+					{ID: "discovered_at", Title: "discovered_at", Type: "string"},
+					{ID: "verdict", Title: "verdict", Type: "string"},
+				}},
+			},
+		},
+	}
+
+	for _, n := range g.Nodes {
+		node := gexfNode{ID: n.Key(), Label: n.Value, Values: []gexfAttrValue{
+			{For: "type", Value: string(n.Type)},
+			{For: "seed", Value: strconv.FormatBool(n.Seed)},
+			{For: "first_seen", Value: timestamp(n.FirstSeen)},
+		}}
+		if metadata := n.MetadataJSON(); metadata != "" {
+			node.Values = append(node.Values, gexfAttrValue{For: "metadata", Value: metadata})
+		}
+		if n.Verdict != "" {
+			node.Values = append(node.Values, gexfAttrValue{For: "verdict", Value: n.Verdict})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+
+	for i, e := range g.Edges {
+		edge := gexfEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: Node{ID: e.From}.Key(),
+			Target: Node{ID: e.To}.Key(),
+			Label:  e.Plugin,
+			Values: []gexfAttrValue{
+				{For: "plugin", Value: e.Plugin},
+				{For: "scan_id", Value: strconv.FormatInt(e.ScanID, 10)},
+				{For: "discovered_at", Value: timestamp(e.DiscoveredAt)},
+			},
+		}
+		if e.Verdict != "" {
+			edge.Values = append(edge.Values, gexfAttrValue{For: "verdict", Value: e.Verdict})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, edge)
+	}
+
+	return writeXML(w, doc)
+}
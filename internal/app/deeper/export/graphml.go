@@ -0,0 +1,119 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type graphmlDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Keys    []graphmlKey `xml:"key"`
+	Graph   graphmlGraph `xml:"graph"`
+}
+
+type graphmlKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr,omitempty"`
+}
+
+type graphmlGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+
+type graphmlNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphmlData `xml:"data"`
+}
+
+type graphmlEdge struct {
+	ID     string        `xml:"id,attr"`
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphmlData `xml:"data"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+var graphmlKeys = []graphmlKey{
+	{ID: "value", For: "node", AttrName: "value", AttrType: "string"},
+	{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+	{ID: "seed", For: "node", AttrName: "seed", AttrType: "boolean"},
+	{ID: "first_seen", For: "node", AttrName: "first_seen", AttrType: "string"},
+	{ID: "metadata", For: "node", AttrName: "metadata", AttrType: "string"},
+	{ID: "verdict", For: "node", AttrName: "verdict", AttrType: "string"},
+	{ID: "plugin", For: "edge", AttrName: "plugin", AttrType: "string"},
+	{ID: "scan_id", For: "edge", AttrName: "scan_id", AttrType: "long"},
+	{ID: "discovered_at", For: "edge", AttrName: "discovered_at", AttrType: "string"},
+	{ID: "edge_verdict", For: "edge", AttrName: "verdict", AttrType: "string"},
+}
+
+// WriteGraphML writes g as GraphML. Metadata is stored as a JSON string,
+// GraphML attributes being scalar.
+func WriteGraphML(w io.Writer, g *Graph) error {
+	doc := graphmlDoc{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys:  graphmlKeys,
+		Graph: graphmlGraph{ID: "deeper", EdgeDefault: "directed"},
+	}
+
+	for _, n := range g.Nodes {
+		node := graphmlNode{ID: n.Key(), Data: []graphmlData{
+			{Key: "value", Value: n.Value},
+			{Key: "type", Value: string(n.Type)},
+			{Key: "seed", Value: strconv.FormatBool(n.Seed)},
+			{Key: "first_seen", Value: timestamp(n.FirstSeen)},
+		}}
+		if metadata := n.MetadataJSON(); metadata != "" {
+			node.Data = append(node.Data, graphmlData{Key: "metadata", Value: metadata})
+		}
+		if n.Verdict != "" {
+			node.Data = append(node.Data, graphmlData{Key: "verdict", Value: n.Verdict})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node)
+	}
+
+	for i, e := range g.Edges {
+		edge := graphmlEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: Node{ID: e.From}.Key(),
+			Target: Node{ID: e.To}.Key(),
+			Data: []graphmlData{
+				{Key: "plugin", Value: e.Plugin},
+				{Key: "scan_id", Value: strconv.FormatInt(e.ScanID, 10)},
+				{Key: "discovered_at", Value: timestamp(e.DiscoveredAt)},
+			},
+		}
+		if e.Verdict != "" {
+			edge.Data = append(edge.Data, graphmlData{Key: "edge_verdict", Value: e.Verdict})
+		}
+		doc.Graph.Edges = append(doc.Graph.Edges, edge)
+	}
+
+	return writeXML(w, doc)
+}
+
+func writeXML(w io.Writer, doc interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode export: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,414 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+const maltegoNamespace = "http://maltego.paterva.com/xml/mtgx"
+
+// maltegoGraphPath is where an MTGX archive keeps its graph.
+const maltegoGraphPath = "Graphs/Graph1.graphml"
+
+// Maltego properties deeper adds so its own exports import back losslessly:
+// the exact trace type of an entity and the plugin behind a link.
+const (
+	maltegoTypeProperty   = "deeper.type"
+	maltegoPluginProperty = "deeper.plugin"
+)
+
+// maltegoLabelProperty is the label Maltego shows on a manual link.
+const maltegoLabelProperty = "maltego.link.manual.type"
+
+// maltegoEntity is a standard Maltego entity type and the property holding
+// its value.
+type maltegoEntity struct {
+	Type        string
+	Property    string
+	DisplayName string
+}
+
+var (
+	maltegoEmail    = maltegoEntity{"maltego.EmailAddress", "email", "Email Address"}
+	maltegoDomain   = maltegoEntity{"maltego.Domain", "fqdn", "Domain Name"}
+	maltegoDNSName  = maltegoEntity{"maltego.DNSName", "fqdn", "DNS Name"}
+	maltegoIPv4     = maltegoEntity{"maltego.IPv4Address", "ipv4-address", "IP Address"}
+	maltegoIPv6     = maltegoEntity{"maltego.IPv6Address", "ipv6-address", "IPv6 Address"}
+	maltegoNetblock = maltegoEntity{"maltego.Netblock", "ipv4-range", "IP Range"}
+	maltegoAS       = maltegoEntity{"maltego.AS", "as.number", "AS Number"}
+	maltegoURL      = maltegoEntity{"maltego.URL", "url", "URL"}
+	maltegoAlias    = maltegoEntity{"maltego.Alias", "alias", "Alias"}
+	maltegoPerson   = maltegoEntity{"maltego.Person", "person.fullname", "Full Name"}
+	maltegoCompany  = maltegoEntity{"maltego.Company", "title", "Name"}
+	maltegoPhone    = maltegoEntity{"maltego.PhoneNumber", "phonenumber", "Phone Number"}
+	maltegoLocation = maltegoEntity{"maltego.Location", "location.name", "Name"}
+	maltegoPhrase   = maltegoEntity{"maltego.Phrase", "text", "Text"}
+
+	maltegoEntityTypes = []maltegoEntity{
+		maltegoEmail, maltegoDomain, maltegoDNSName, maltegoIPv4, maltegoIPv6,
+		maltegoNetblock, maltegoAS, maltegoURL, maltegoAlias, maltegoPerson,
+		maltegoCompany, maltegoPhone, maltegoLocation, maltegoPhrase,
+	}
+)
+
+// maltegoEntities lists the Maltego type of each trace type that has one.
+// Everything else is exported as a Phrase.
+var maltegoEntities = map[entities.TraceType]maltegoEntity{
+	entities.Email:     maltegoEmail,
+	entities.Domain:    maltegoDomain,
+	entities.Subdomain: maltegoDNSName,
+	entities.Host:      maltegoDNSName,
+	entities.Netblock:  maltegoNetblock,
+	entities.IPRange:   maltegoNetblock,
+	entities.ASN:       maltegoAS,
+	entities.Url:       maltegoURL,
+	entities.Username:  maltegoAlias,
+	entities.Alias:     maltegoAlias,
+	entities.Name:      maltegoPerson,
+	entities.Company:   maltegoCompany,
+	entities.Phone:     maltegoPhone,
+	entities.Address:   maltegoLocation,
+}
+
+// maltegoTraceTypes maps Maltego entity types back to trace types for
+// graphs deeper did not write. Types missing here are classified by value.
+var maltegoTraceTypes = map[string]entities.TraceType{
+	maltegoEmail.Type:      entities.Email,
+	maltegoDomain.Type:     entities.Domain,
+	maltegoDNSName.Type:    entities.Subdomain,
+	"maltego.MXRecord":     entities.Subdomain,
+	"maltego.NSRecord":     entities.Subdomain,
+	"maltego.Website":      entities.Domain,
+	maltegoIPv4.Type:       entities.IpAddr,
+	maltegoIPv6.Type:       entities.IpAddr,
+	maltegoNetblock.Type:   entities.Netblock,
+	maltegoAS.Type:         entities.ASN,
+	maltegoURL.Type:        entities.Url,
+	maltegoAlias.Type:      entities.Username,
+	maltegoPerson.Type:     entities.Name,
+	maltegoCompany.Type:    entities.Company,
+	"maltego.Organization": entities.Company,
+	maltegoPhone.Type:      entities.Phone,
+	maltegoLocation.Type:   entities.Address,
+}
+
+// maltegoEntityFor picks the Maltego entity of a node. IP addresses need the
+// value to tell IPv4 from IPv6.
+func maltegoEntityFor(n Node) maltegoEntity {
+	if n.Type == entities.IpAddr {
+		if addr, err := netip.ParseAddr(n.Value); err == nil && addr.Is6() && !addr.Is4In6() {
+			return maltegoIPv6
+		}
+		return maltegoIPv4
+	}
+	if entity, ok := maltegoEntities[n.Type]; ok {
+		return entity
+	}
+	if _, ok := socialAccountTypes[n.Type]; ok || n.Type == entities.SocialGeneric || n.Type == entities.Repository {
+		// Social traces are profile URLs.
+		return maltegoURL
+	}
+	return maltegoPhrase
+}
+
+// maltegoValue is the value Maltego expects in the entity's main property.
+func maltegoValue(n Node) string {
+	if n.Type == entities.ASN {
+		return strings.TrimPrefix(strings.ToUpper(n.Value), "AS")
+	}
+	return n.Value
+}
+
+type mtgDoc struct {
+	XMLName  xml.Name     `xml:"graphml"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	XmlnsMtg string       `xml:"xmlns:mtg,attr"`
+	Keys     []graphmlKey `xml:"key"`
+	Graph    mtgGraph     `xml:"graph"`
+}
+
+type mtgGraph struct {
+	ID          string    `xml:"id,attr"`
+	EdgeDefault string    `xml:"edgedefault,attr"`
+	Nodes       []mtgNode `xml:"node"`
+	Edges       []mtgEdge `xml:"edge"`
+}
+
+type mtgNode struct {
+	ID   string        `xml:"id,attr"`
+	Data mtgEntityData `xml:"data"`
+}
+
+type mtgEntityData struct {
+	Key    string    `xml:"key,attr"`
+	Entity mtgObject `xml:"mtg:MaltegoEntity"`
+}
+
+type mtgEdge struct {
+	ID     string      `xml:"id,attr"`
+	Source string      `xml:"source,attr"`
+	Target string      `xml:"target,attr"`
+	Data   mtgLinkData `xml:"data"`
+}
+
+type mtgLinkData struct {
+	Key  string    `xml:"key,attr"`
+	Link mtgObject `xml:"mtg:MaltegoLink"`
+}
+
+type mtgObject struct {
+	Type       string        `xml:"type,attr"`
+	Properties []mtgProperty `xml:"mtg:Properties>mtg:Property"`
+}
+
+type mtgProperty struct {
+	Name        string `xml:"name,attr"`
+	DisplayName string `xml:"displayName,attr"`
+	Type        string `xml:"type,attr"`
+	Hidden      bool   `xml:"hidden,attr"`
+	Nullable    bool   `xml:"nullable,attr"`
+	Readonly    bool   `xml:"readonly,attr"`
+	Value       string `xml:"mtg:Value"`
+}
+
+func mtgString(name, displayName, value string, hidden bool) mtgProperty {
+	return mtgProperty{Name: name, DisplayName: displayName, Type: "string", Hidden: hidden, Nullable: true, Value: value}
+}
+
+// WriteMaltego writes g as an MTGX archive that Maltego opens as a graph.
+// Traces become standard Maltego entities (maltego.EmailAddress,
+// maltego.Domain, maltego.IPv4Address, maltego.Alias, ...) and edges become
+// links labelled with the deeper plugin that found them. Hidden properties
+// keep each trace's exact type and each link's plugin, so ReadMaltego turns
+// the file back into the same graph.
+func WriteMaltego(w io.Writer, g *Graph) error {
+	doc := mtgDoc{
+		Xmlns:    "http://graphml.graphdrawing.org/xmlns",
+		XmlnsMtg: maltegoNamespace,
+		Keys: []graphmlKey{
+			{ID: "d0", For: "node", AttrName: "MaltegoEntity"},
+			{ID: "d1", For: "edge", AttrName: "MaltegoLink"},
+		},
+		Graph: mtgGraph{ID: "G", EdgeDefault: "directed"},
+	}
+
+	for _, n := range g.Nodes {
+		entity := maltegoEntityFor(n)
+		doc.Graph.Nodes = append(doc.Graph.Nodes, mtgNode{ID: n.Key(), Data: mtgEntityData{Key: "d0", Entity: mtgObject{
+			Type: entity.Type,
+			Properties: []mtgProperty{
+				mtgString(entity.Property, entity.DisplayName, maltegoValue(n), false),
+				mtgString(maltegoTypeProperty, "deeper trace type", string(n.Type), true),
+			},
+		}}})
+	}
+
+	for i, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, mtgEdge{
+			ID:     fmt.Sprintf("e%d", i),
+			Source: Node{ID: e.From}.Key(),
+			Target: Node{ID: e.To}.Key(),
+			Data: mtgLinkData{Key: "d1", Link: mtgObject{
+				Type: "maltego.link.manual-link",
+				Properties: []mtgProperty{
+					mtgString(maltegoLabelProperty, "Label", e.Plugin, false),
+					mtgString(maltegoPluginProperty, "deeper plugin", e.Plugin, true),
+				},
+			}},
+		})
+	}
+
+	var graphml bytes.Buffer
+	if err := writeXML(&graphml, doc); err != nil {
+		return err
+	}
+
+	// A fixed timestamp keeps repeated exports byte-identical.
+	header := &zip.FileHeader{Name: maltegoGraphPath, Method: zip.Deflate}
+	if len(g.Scans) > 0 {
+		header.Modified = g.Scans[0].StartedAt.UTC()
+	}
+	zw := zip.NewWriter(w)
+	fw, err := zw.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if _, err := fw.Write(graphml.Bytes()); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// Maltego files are read without regard to XML namespaces, since Maltego
+// versions differ in how they qualify element names.
+type mtgReadDoc struct {
+	Graph struct {
+		Nodes []struct {
+			ID   string `xml:"id,attr"`
+			Data []struct {
+				Entity *mtgReadObject `xml:"MaltegoEntity"`
+			} `xml:"data"`
+		} `xml:"node"`
+		Edges []struct {
+			Source string `xml:"source,attr"`
+			Target string `xml:"target,attr"`
+			Data   []struct {
+				Link *mtgReadObject `xml:"MaltegoLink"`
+			} `xml:"data"`
+		} `xml:"edge"`
+	} `xml:"graph"`
+}
+
+type mtgReadObject struct {
+	Type       string `xml:"type,attr"`
+	Properties []struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"Value"`
+	} `xml:"Properties>Property"`
+}
+
+func (o *mtgReadObject) property(name string) string {
+	for _, p := range o.Properties {
+		if p.Name == name {
+			return strings.TrimSpace(p.Value)
+		}
+	}
+	return ""
+}
+
+// ReadMaltego reads a Maltego graph from an MTGX archive or a bare GraphML
+// file. Entities become nodes, numbered from 1 in file order, and links
+// become edges labelled with the deeper plugin that wrote them or, for
+// links made in Maltego, database.MaltegoPluginName. Entities that map to
+// the same trace collapse into one node; entities without a value are
+// skipped along with their links.
+func ReadMaltego(filePath string) (*Graph, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+	}
+	if bytes.HasPrefix(data, []byte("PK")) {
+		if data, err = mtgxGraph(data); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filePath, err)
+		}
+	}
+
+	var doc mtgReadDoc
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse Maltego graph %s: %w", filePath, err)
+	}
+
+	graph := &Graph{}
+	byTrace := make(map[entities.Trace]int64)
+	byKey := make(map[string]int64)
+	for _, n := range doc.Graph.Nodes {
+		var entity *mtgReadObject
+		for _, d := range n.Data {
+			if d.Entity != nil {
+				entity = d.Entity
+			}
+		}
+		if entity == nil {
+			continue
+		}
+		trace, ok := maltegoTrace(entity)
+		if !ok {
+			continue
+		}
+		id, ok := byTrace[trace]
+		if !ok {
+			id = int64(len(graph.Nodes) + 1)
+			byTrace[trace] = id
+			graph.Nodes = append(graph.Nodes, Node{ID: id, Value: trace.Value, Type: trace.Type})
+		}
+		byKey[n.ID] = id
+	}
+
+	for _, e := range doc.Graph.Edges {
+		from, okFrom := byKey[e.Source]
+		to, okTo := byKey[e.Target]
+		if !okFrom || !okTo || from == to {
+			continue
+		}
+		plugin := database.MaltegoPluginName
+		for _, d := range e.Data {
+			if d.Link != nil {
+				if name := d.Link.property(maltegoPluginProperty); name != "" {
+					plugin = name
+				}
+			}
+		}
+		graph.Edges = append(graph.Edges, Edge{From: from, To: to, Plugin: plugin})
+	}
+	return graph, nil
+}
+
+// maltegoTrace converts an entity to a trace: exactly, when deeper wrote
+// the entity, and otherwise through the Maltego type or the value's shape.
+func maltegoTrace(entity *mtgReadObject) (entities.Trace, bool) {
+	value := ""
+	for _, known := range maltegoEntityTypes {
+		if known.Type == entity.Type {
+			value = entity.property(known.Property)
+			break
+		}
+	}
+	if value == "" {
+		// Other entity types: take the first property with a value, which
+		// Maltego treats as the main one.
+		for _, p := range entity.Properties {
+			if !strings.HasPrefix(p.Name, "deeper.") && strings.TrimSpace(p.Value) != "" {
+				value = strings.TrimSpace(p.Value)
+				break
+			}
+		}
+	}
+	if value == "" {
+		return entities.Trace{}, false
+	}
+
+	traceType := entities.TraceType(entity.property(maltegoTypeProperty))
+	if !entities.IsKnownTraceType(traceType) {
+		var ok bool
+		if traceType, ok = maltegoTraceTypes[entity.Type]; !ok {
+			return entities.NewTrace(value), true
+		}
+	}
+	if traceType == entities.ASN {
+		value = "AS" + strings.TrimPrefix(strings.ToUpper(value), "AS")
+	}
+	return entities.Trace{Value: value, Type: traceType}, true
+}
+
+// mtgxGraph extracts the graph from an MTGX archive.
+func mtgxGraph(archive []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("not an MTGX archive: %w", err)
+	}
+	for _, f := range zr.File {
+		if path.Dir(f.Name) != "Graphs" || path.Ext(f.Name) != ".graphml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = rc.Close() }()
+		return io.ReadAll(rc)
+	}
+	return nil, fmt.Errorf("MTGX archive has no graph")
+}
@@ -0,0 +1,454 @@
+package export
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// STIX 2.1 fixes the namespace of deterministic SCO identifiers (section
+// 2.9) so every producer derives the same ID for the same observable.
+// deeperNamespace plays the same role for the objects only deeper creates:
+// its author identity, relationships, reports and bundles.
+var (
+	stixNamespace   = mustUUID("00abedb4-aa42-466c-9c01-fed23315a9b7")
+	deeperNamespace = mustUUID("5c0e3c55-9d1e-4f2a-8b8f-2f6d0b3e9a71")
+)
+
+// tlpMarkings are the predefined TLP 1.0 marking definitions from the STIX
+// 2.1 specification. Their IDs are fixed, so platforms recognise them.
+var tlpMarkings = map[string]map[string]interface{}{
+	"white": tlpMarking("613f2e26-407d-48c7-9eca-b8e91df99dc9", "white"),
+	"green": tlpMarking("34098fce-860f-48ae-8e50-ebd3cc5e41da", "green"),
+	"amber": tlpMarking("f88d31f6-486f-44da-b317-01333bde0b82", "amber"),
+	"red":   tlpMarking("5e57c739-391a-4eb3-b6be-7d15ca92d5ed", "red"),
+}
+
+func tlpMarking(id, color string) map[string]interface{} {
+	return map[string]interface{}{
+		"type":            "marking-definition",
+		"spec_version":    "2.1",
+		"id":              "marking-definition--" + id,
+		"created":         "2017-01-20T00:00:00.000Z",
+		"definition_type": "tlp",
+		"name":            "TLP:" + strings.ToUpper(color),
+		"definition":      map[string]interface{}{"tlp": color},
+	}
+}
+
+// socialAccountTypes maps social trace types, whose values are profile
+// URLs, to the STIX account_type of the account behind them.
+var socialAccountTypes = map[entities.TraceType]string{
+	entities.Twitter:   "twitter",
+	entities.Github:    "github",
+	entities.Linkedin:  "linkedin",
+	entities.Instagram: "instagram",
+	entities.Facebook:  "facebook",
+	entities.TikTok:    "tiktok",
+	entities.Reddit:    "reddit",
+	entities.YouTube:   "youtube",
+	entities.Pinterest: "pinterest",
+	entities.Snapchat:  "snapchat",
+	entities.Tumblr:    "tumblr",
+}
+
+// stixOpinions maps analyst verdicts to the STIX opinion vocabulary.
+var stixOpinions = map[string]string{
+	database.VerdictConfirmed:     "strongly-agree",
+	database.VerdictFalsePositive: "strongly-disagree",
+	database.VerdictIrrelevant:    "neutral",
+}
+
+var fingerprintPattern = regexp.MustCompile(`^(?:[0-9a-fA-F]{2}:?){20}$|^(?:[0-9a-fA-F]{2}:?){32}$`)
+
+// WriteSTIX writes g as a STIX 2.1 bundle for MISP, OpenCTI and other
+// threat-intel platforms.
+//
+// Traces become cyber observables (email-addr, domain-name, ipv4-addr,
+// ipv6-addr, url, user-account, autonomous-system, x509-certificate,
+// mac-addr) with the specification's deterministic IDs, so the same
+// observable from two exports merges on import. Names and companies become
+// identity objects. Trace types with no STIX equivalent are left out, along
+// with their edges; the scan report says how many.
+//
+// Each plugin edge becomes a relationship, and each scan a report listing
+// what it found. An analyst's verdict on a trace becomes an opinion on its
+// observable; a verdict on a plugin's edges is kept on the relationship.
+// Everything deeper authors carries created_by_ref and the TLP marking
+// chosen in opts.
+func WriteSTIX(w io.Writer, g *Graph, opts Options) error {
+	tlp := strings.ToLower(opts.TLP)
+	if tlp == "" {
+		tlp = DefaultTLP
+	}
+	marking, ok := tlpMarkings[tlp]
+	if !ok {
+		return fmt.Errorf("unsupported TLP level %q (supported: white, green, amber, red)", opts.TLP)
+	}
+	markingRefs := []string{marking["id"].(string)}
+
+	created := stixEpoch(g)
+	author := stixAuthor(opts.Author, created, markingRefs)
+	authorID := author["id"].(string)
+
+	objects := []map[string]interface{}{author, marking}
+	objectIDs := make(map[int64]string, len(g.Nodes))
+	objectTypes := make(map[int64]string, len(g.Nodes))
+	traceTypes := make(map[int64]entities.TraceType, len(g.Nodes))
+	seen := make(map[string]bool)
+	skipped := make(map[int64]int)
+	for _, n := range g.Nodes {
+		obj := stixObject(n, authorID, markingRefs)
+		if obj == nil {
+			for _, scanID := range n.Scans {
+				skipped[scanID]++
+			}
+			continue
+		}
+		id := obj["id"].(string)
+		objectIDs[n.ID] = id
+		objectTypes[n.ID] = obj["type"].(string)
+		traceTypes[n.ID] = n.Type
+		// Distinct traces can be the same observable, e.g. a domain and
+		// a host with one name.
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		objects = append(objects, obj)
+	}
+
+	scanRefs := make(map[int64][]string)
+	for _, n := range g.Nodes {
+		id, ok := objectIDs[n.ID]
+		if !ok {
+			continue
+		}
+		for _, scanID := range n.Scans {
+			scanRefs[scanID] = append(scanRefs[scanID], id)
+		}
+	}
+
+	for _, e := range g.Edges {
+		source, okSource := objectIDs[e.From]
+		target, okTarget := objectIDs[e.To]
+		if !okSource || !okTarget || source == target {
+			continue
+		}
+		at := stixTime(e.DiscoveredAt)
+		rel := map[string]interface{}{
+			"type":                "relationship",
+			"spec_version":        "2.1",
+			"id":                  "relationship--" + uuid5(deeperNamespace, fmt.Sprintf("%s|%s|%s|%d", source, target, e.Plugin, e.ScanID)),
+			"created":             at,
+			"modified":            at,
+			"relationship_type":   stixRelationshipType(objectTypes[e.From], objectTypes[e.To], traceTypes[e.To]),
+			"source_ref":          source,
+			"target_ref":          target,
+			"description":         fmt.Sprintf("Discovered by %s in deeper scan %d", e.Plugin, e.ScanID),
+			"created_by_ref":      authorID,
+			"object_marking_refs": markingRefs,
+			"x_deeper_plugin":     e.Plugin,
+			"x_deeper_scan_id":    e.ScanID,
+		}
+		if e.Verdict != "" {
+			rel["x_deeper_verdict"] = e.Verdict
+		}
+		objects = append(objects, rel)
+		scanRefs[e.ScanID] = append(scanRefs[e.ScanID], rel["id"].(string))
+	}
+
+	for _, n := range g.Nodes {
+		id, ok := objectIDs[n.ID]
+		if !ok || n.Verdict == "" {
+			continue
+		}
+		objects = append(objects, map[string]interface{}{
+			"type":                "opinion",
+			"spec_version":        "2.1",
+			"id":                  "opinion--" + uuid5(deeperNamespace, "verdict|"+id+"|"+n.Verdict),
+			"created":             created,
+			"modified":            created,
+			"opinion":             stixOpinions[n.Verdict],
+			"explanation":         "deeper analyst verdict: " + strings.ReplaceAll(n.Verdict, "_", " "),
+			"object_refs":         []string{id},
+			"created_by_ref":      authorID,
+			"object_marking_refs": markingRefs,
+			"x_deeper_verdict":    n.Verdict,
+		})
+	}
+
+	for _, session := range g.Scans {
+		refs := dedupe(scanRefs[session.ID])
+		if len(refs) == 0 {
+			// A report must reference something.
+			refs = []string{authorID}
+		}
+		published := session.StartedAt
+		if session.CompletedAt != nil {
+			published = *session.CompletedAt
+		}
+		description := fmt.Sprintf("deeper scan %d of %q.", session.ID, session.Input)
+		if n := skipped[session.ID]; n > 0 {
+			description += fmt.Sprintf(" %d traces with no STIX equivalent were left out.", n)
+		}
+		objects = append(objects, map[string]interface{}{
+			"type":                "report",
+			"spec_version":        "2.1",
+			"id":                  "report--" + uuid5(deeperNamespace, fmt.Sprintf("scan|%d|%s|%s", session.ID, session.Input, stixTime(session.StartedAt))),
+			"created":             stixTime(session.StartedAt),
+			"modified":            stixTime(published),
+			"published":           stixTime(published),
+			"name":                fmt.Sprintf("deeper scan %d: %s", session.ID, session.Input),
+			"description":         description,
+			"report_types":        []string{"observed-data"},
+			"object_refs":         refs,
+			"created_by_ref":      authorID,
+			"object_marking_refs": markingRefs,
+			"x_deeper_scan_id":    session.ID,
+		})
+	}
+
+	bundle := map[string]interface{}{
+		"type":    "bundle",
+		"id":      "bundle--" + uuid5(deeperNamespace, "bundle|"+joinIDs(g.ScanIDs)+"|"+authorID),
+		"objects": objects,
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(bundle); err != nil {
+		return fmt.Errorf("failed to encode export: %w", err)
+	}
+	return nil
+}
+
+// stixAuthor returns the identity named by created_by_ref. Without an
+// explicit author the bundle is attributed to deeper itself.
+func stixAuthor(name, created string, markingRefs []string) map[string]interface{} {
+	class := "organization"
+	if name == "" {
+		name, class = "deeper", "system"
+	}
+	return map[string]interface{}{
+		"type":                "identity",
+		"spec_version":        "2.1",
+		"id":                  "identity--" + uuid5(deeperNamespace, "author|"+class+"|"+name),
+		"created":             created,
+		"modified":            created,
+		"name":                name,
+		"identity_class":      class,
+		"object_marking_refs": markingRefs,
+	}
+}
+
+// stixObject maps a trace to a STIX object, or returns nil when its type
+// has no STIX equivalent.
+func stixObject(n Node, authorID string, markingRefs []string) map[string]interface{} {
+	switch n.Type {
+	case entities.Email:
+		return stixSCO("email-addr", markingRefs, map[string]interface{}{"value": n.Value})
+	case entities.Domain, entities.Subdomain, entities.Host:
+		return stixSCO("domain-name", markingRefs, map[string]interface{}{"value": strings.ToLower(n.Value)})
+	case entities.DnsRecordCNAME:
+		// CNAME targets are reported fully qualified, as in "shop.example.com.".
+		return stixSCO("domain-name", markingRefs, map[string]interface{}{"value": strings.ToLower(strings.TrimSuffix(n.Value, "."))})
+	case entities.IpAddr, entities.Netblock, entities.IPRange:
+		return stixAddress(n.Value, markingRefs)
+	case entities.Url, entities.SocialGeneric, entities.Repository:
+		return stixSCO("url", markingRefs, map[string]interface{}{"value": n.Value})
+	case entities.Username:
+		return stixSCO("user-account", markingRefs, map[string]interface{}{"account_login": n.Value})
+	case entities.ASN:
+		number, err := strconv.ParseInt(strings.TrimPrefix(strings.ToUpper(n.Value), "AS"), 10, 64)
+		if err != nil {
+			return nil
+		}
+		return stixSCO("autonomous-system", markingRefs, map[string]interface{}{"number": number})
+	case entities.Certificates:
+		return stixCertificate(n.Value, markingRefs)
+	case entities.MacAddr:
+		return stixSCO("mac-addr", markingRefs, map[string]interface{}{"value": strings.ToLower(n.Value)})
+	case entities.Name:
+		return stixIdentity(n, "individual", authorID, markingRefs)
+	case entities.Company:
+		return stixIdentity(n, "organization", authorID, markingRefs)
+	}
+	if accountType, ok := socialAccountTypes[n.Type]; ok {
+		login := profileLogin(n.Value)
+		if login == "" {
+			return stixSCO("url", markingRefs, map[string]interface{}{"value": n.Value})
+		}
+		return stixSCO("user-account", markingRefs, map[string]interface{}{"account_type": accountType, "account_login": login})
+	}
+	return nil
+}
+
+// stixSCO builds a cyber observable whose ID is derived from props, which
+// must be exactly the type's ID contributing properties.
+func stixSCO(scoType string, markingRefs []string, props map[string]interface{}) map[string]interface{} {
+	obj := map[string]interface{}{
+		"type":                scoType,
+		"spec_version":        "2.1",
+		"id":                  scoType + "--" + uuid5(stixNamespace, canonicalJSON(props)),
+		"object_marking_refs": markingRefs,
+	}
+	for k, v := range props {
+		obj[k] = v
+	}
+	return obj
+}
+
+func stixAddress(value string, markingRefs []string) map[string]interface{} {
+	var canonical string
+	var is4 bool
+	if addr, err := netip.ParseAddr(value); err == nil {
+		canonical, is4 = addr.String(), addr.Is4()
+	} else if prefix, err := netip.ParsePrefix(value); err == nil {
+		canonical, is4 = prefix.Masked().String(), prefix.Addr().Is4()
+	} else {
+		return nil
+	}
+	scoType := "ipv6-addr"
+	if is4 {
+		scoType = "ipv4-addr"
+	}
+	return stixSCO(scoType, markingRefs, map[string]interface{}{"value": canonical})
+}
+
+// stixCertificate maps a certificate trace. Fingerprints become hashes, the
+// property STIX derives certificate IDs from; anything else is taken as the
+// subject, which the ID is then derived from instead so exports stay stable.
+func stixCertificate(value string, markingRefs []string) map[string]interface{} {
+	if fingerprintPattern.MatchString(value) {
+		digest := strings.ToLower(strings.ReplaceAll(value, ":", ""))
+		algorithm := "SHA-1"
+		if len(digest) == 64 {
+			algorithm = "SHA-256"
+		}
+		return stixSCO("x509-certificate", markingRefs, map[string]interface{}{
+			"hashes": map[string]interface{}{algorithm: digest},
+		})
+	}
+	return stixSCO("x509-certificate", markingRefs, map[string]interface{}{"subject": value})
+}
+
+func stixIdentity(n Node, class, authorID string, markingRefs []string) map[string]interface{} {
+	at := stixTime(n.FirstSeen)
+	return map[string]interface{}{
+		"type":                "identity",
+		"spec_version":        "2.1",
+		"id":                  "identity--" + uuid5(deeperNamespace, "trace|"+class+"|"+n.Value),
+		"created":             at,
+		"modified":            at,
+		"name":                n.Value,
+		"identity_class":      class,
+		"created_by_ref":      authorID,
+		"object_marking_refs": markingRefs,
+	}
+}
+
+// stixRelationshipType names a discovery edge. DNS and ASN lookups get the
+// specific types OpenCTI and MISP understand: a domain resolves to its
+// addresses and to the name its CNAME record points at. Everything else,
+// such as a subdomain found in certificate logs, is related-to.
+func stixRelationshipType(sourceType, targetType string, target entities.TraceType) string {
+	isAddress := func(t string) bool { return t == "ipv4-addr" || t == "ipv6-addr" }
+	switch {
+	case sourceType == "domain-name" && isAddress(targetType),
+		sourceType == "domain-name" && target == entities.DnsRecordCNAME:
+		return "resolves-to"
+	case isAddress(sourceType) && targetType == "autonomous-system":
+		return "belongs-to"
+	default:
+		return "related-to"
+	}
+}
+
+// profileLogin extracts the account name from a social profile URL:
+// the last path segment, without a leading "@".
+func profileLogin(value string) string {
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return ""
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	return strings.TrimPrefix(segments[len(segments)-1], "@")
+}
+
+// stixEpoch is the creation time of objects that describe the export as a
+// whole. It is the first scan's start rather than the current time, so
+// exporting the same scans twice yields the same bundle.
+func stixEpoch(g *Graph) string {
+	var earliest time.Time
+	for _, session := range g.Scans {
+		if earliest.IsZero() || session.StartedAt.Before(earliest) {
+			earliest = session.StartedAt
+		}
+	}
+	return stixTime(earliest)
+}
+
+// stixTime formats t as a STIX timestamp: UTC with millisecond precision.
+func stixTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000Z")
+}
+
+// canonicalJSON serializes ID contributing properties as RFC 8785 requires
+// for these simple values: sorted keys, no insignificant whitespace and no
+// HTML escaping.
+func canonicalJSON(v interface{}) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	_ = encoder.Encode(v)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func uuid5(namespace [16]byte, name string) string {
+	h := sha1.New()
+	h.Write(namespace[:])
+	h.Write([]byte(name))
+	sum := h.Sum(nil)
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return formatUUID(sum[:16])
+}
+
+func formatUUID(b []byte) string {
+	s := hex.EncodeToString(b)
+	return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
+
+func mustUUID(s string) [16]byte {
+	var out [16]byte
+	b, err := hex.DecodeString(strings.ReplaceAll(s, "-", ""))
+	if err != nil || len(b) != 16 {
+		panic("invalid UUID " + s)
+	}
+	copy(out[:], b)
+	return out
+}
+
+func dedupe(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	return out
+}
@@ -0,0 +1,328 @@
+// Package graphnav answers questions about a stored scan graph: which trace
+// a user means, how the scan reached it, what surrounds it and what slice
+// of the graph lies around it.
+package graphnav
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// Graph is one scan's stored graph with each node's distance from the seeds.
+type Graph struct {
+	nodes map[int64]database.Trace
+	edges []database.TraceEdge
+	hops  map[int64]int
+}
+
+// New indexes a scan graph as returned by Repository.GetScanGraph.
+func New(nodes []database.Trace, edges []database.TraceEdge) *Graph {
+	g := &Graph{nodes: make(map[int64]database.Trace, len(nodes)), edges: edges, hops: Hops(edges)}
+	for _, n := range nodes {
+		g.nodes[n.ID] = n
+	}
+	return g
+}
+
+// Trace returns the node with the given ID.
+func (g *Graph) Trace(id int64) (database.Trace, bool) {
+	t, ok := g.nodes[id]
+	return t, ok
+}
+
+// Hop returns a node's distance from the seeds, or -1 if no seed reaches it.
+func (g *Graph) Hop(id int64) int {
+	if h, ok := g.hops[id]; ok {
+		return h
+	}
+	return -1
+}
+
+// Traces is where Resolve looks traces up: the Repository over the whole
+// database, or a Graph over one scan.
+type Traces interface {
+	GetTrace(id int64) (*database.Trace, error)
+	GetTraceByValue(value string, traceType entities.TraceType) (*database.Trace, error)
+	// GetTracesByValue returns the traces with the value, or failing
+	// that those whose value matches it ignoring case.
+	GetTracesByValue(value string) ([]database.Trace, error)
+}
+
+// Resolve resolves a trace reference given on the command line: "#<id>"
+// for a trace ID, "type:value" for an exact trace, or a bare value. A bare
+// value matching traces of several types is ambiguous and reported as such.
+func Resolve(traces Traces, ref string) (*database.Trace, error) {
+	if idText, ok := strings.CutPrefix(ref, "#"); ok {
+		if id, err := strconv.ParseInt(idText, 10, 64); err == nil {
+			t, err := traces.GetTrace(id)
+			if err != nil {
+				return nil, err
+			}
+			if t == nil {
+				return nil, fmt.Errorf("trace #%d not found", id)
+			}
+			return t, nil
+		}
+	}
+
+	if typeName, value, ok := strings.Cut(ref, ":"); ok && entities.IsKnownTraceType(entities.TraceType(typeName)) {
+		t, err := traces.GetTraceByValue(value, entities.TraceType(typeName))
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return nil, fmt.Errorf("trace %q not found", ref)
+		}
+		return t, nil
+	}
+
+	matches, err := traces.GetTracesByValue(ref)
+	if err != nil {
+		return nil, err
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("trace %q not found", ref)
+	case 1:
+		return &matches[0], nil
+	default:
+		candidates := make([]string, len(matches))
+		for i, t := range matches {
+			candidates[i] = string(t.Type) + ":" + t.Value
+		}
+		return nil, fmt.Errorf("trace %q is ambiguous, use one of: %s", ref, strings.Join(candidates, ", "))
+	}
+}
+
+// Find resolves a trace reference among the scan's traces (see Resolve).
+func (g *Graph) Find(ref string) (database.Trace, error) {
+	t, err := Resolve(scanTraces{g}, ref)
+	if err != nil {
+		return database.Trace{}, err
+	}
+	return *t, nil
+}
+
+// scanTraces looks traces up among a graph's nodes.
+type scanTraces struct {
+	g *Graph
+}
+
+func (s scanTraces) GetTrace(id int64) (*database.Trace, error) {
+	if t, ok := s.g.nodes[id]; ok {
+		return &t, nil
+	}
+	return nil, nil
+}
+
+func (s scanTraces) GetTraceByValue(value string, traceType entities.TraceType) (*database.Trace, error) {
+	matches := s.g.matching(func(t database.Trace) bool { return t.Type == traceType && t.Value == value })
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	return &matches[0], nil
+}
+
+func (s scanTraces) GetTracesByValue(value string) ([]database.Trace, error) {
+	matches := s.g.matching(func(t database.Trace) bool { return t.Value == value })
+	if len(matches) == 0 {
+		matches = s.g.matching(func(t database.Trace) bool { return strings.EqualFold(t.Value, value) })
+	}
+	return matches, nil
+}
+
+func (g *Graph) matching(match func(database.Trace) bool) []database.Trace {
+	var out []database.Trace
+	for _, t := range g.nodes {
+		if match(t) {
+			out = append(out, t)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Step is one trace on a discovery chain. Plugin produced it from the
+// previous step; it is empty on the first step, the seed.
+type Step struct {
+	TraceID int64              `json:"trace_id"`
+	Value   string             `json:"value"`
+	Type    entities.TraceType `json:"type"`
+	Plugin  string             `json:"plugin,omitempty"`
+}
+
+// Chain picks one discovery chain from a seed to target out of its
+// ancestry, as Repository.GetDiscoveryPath returns it. Where a trace was
+// reached several ways, the parent nearest the seeds is followed, so the
+// chain is a shortest one.
+func (g *Graph) Chain(target int64, ancestry []database.TraceEdge) []Step {
+	parents := make(map[int64][]database.TraceEdge)
+	for _, e := range ancestry {
+		if e.ParentTraceID != nil {
+			parents[e.ChildTraceID] = append(parents[e.ChildTraceID], e)
+		}
+	}
+
+	var chain []Step
+	visited := make(map[int64]bool)
+	current := target
+	for !visited[current] {
+		visited[current] = true
+		t := g.nodes[current]
+		step := Step{TraceID: current, Value: t.Value, Type: t.Type}
+
+		var best *database.TraceEdge
+		for i, e := range parents[current] {
+			parent := *e.ParentTraceID
+			if g.Hop(parent) < 0 || visited[parent] {
+				continue
+			}
+			if best == nil || g.Hop(parent) < g.Hop(*best.ParentTraceID) {
+				best = &parents[current][i]
+			}
+		}
+		if best != nil {
+			step.Plugin = best.PluginName
+		}
+		chain = append(chain, step)
+		if best == nil {
+			break
+		}
+		current = *best.ParentTraceID
+	}
+
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// Neighbor is a trace one edge away and the plugin on that edge.
+type Neighbor struct {
+	Trace  database.Trace
+	Plugin string
+}
+
+// Neighbors returns the traces a trace was discovered from and the traces
+// discovered from it, ordered by value. A trace with several edges to the
+// same neighbor, one per plugin, lists that neighbor once per plugin.
+func (g *Graph) Neighbors(id int64) (parents, children []Neighbor) {
+	for _, e := range g.edges {
+		if e.ParentTraceID == nil {
+			continue
+		}
+		if e.ChildTraceID == id {
+			parents = append(parents, Neighbor{Trace: g.nodes[*e.ParentTraceID], Plugin: e.PluginName})
+		}
+		if *e.ParentTraceID == id {
+			children = append(children, Neighbor{Trace: g.nodes[e.ChildTraceID], Plugin: e.PluginName})
+		}
+	}
+	sortNeighbors(parents)
+	sortNeighbors(children)
+	return parents, children
+}
+
+func sortNeighbors(ns []Neighbor) {
+	sort.Slice(ns, func(i, j int) bool {
+		if ns[i].Trace.Value != ns[j].Trace.Value {
+			return ns[i].Trace.Value < ns[j].Trace.Value
+		}
+		if ns[i].Trace.Type != ns[j].Trace.Type {
+			return ns[i].Trace.Type < ns[j].Trace.Type
+		}
+		return ns[i].Plugin < ns[j].Plugin
+	})
+}
+
+// IsSeed reports whether the trace is one of the scan's seeds.
+func (g *Graph) IsSeed(id int64) bool {
+	return g.Hop(id) == 0
+}
+
+// Subgraph returns the traces within hops edges of center, following edges
+// in both directions, and every edge among them. Seed edges into included
+// traces are kept, so exports still mark the seeds.
+func (g *Graph) Subgraph(center int64, hops int) ([]database.Trace, []database.TraceEdge) {
+	adjacent := make(map[int64][]int64)
+	for _, e := range g.edges {
+		if e.ParentTraceID == nil {
+			continue
+		}
+		adjacent[*e.ParentTraceID] = append(adjacent[*e.ParentTraceID], e.ChildTraceID)
+		adjacent[e.ChildTraceID] = append(adjacent[e.ChildTraceID], *e.ParentTraceID)
+	}
+
+	distance := map[int64]int{center: 0}
+	queue := []int64{center}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if distance[id] == hops {
+			continue
+		}
+		for _, next := range adjacent[id] {
+			if _, ok := distance[next]; !ok {
+				distance[next] = distance[id] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	nodes := make([]database.Trace, 0, len(distance))
+	for id := range distance {
+		if t, ok := g.nodes[id]; ok {
+			nodes = append(nodes, t)
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+	var edges []database.TraceEdge
+	for _, e := range g.edges {
+		if _, ok := distance[e.ChildTraceID]; !ok {
+			continue
+		}
+		if e.ParentTraceID != nil {
+			if _, ok := distance[*e.ParentTraceID]; !ok {
+				continue
+			}
+		}
+		edges = append(edges, e)
+	}
+	return nodes, edges
+}
+
+// Hops runs a breadth-first search from the seed edges and returns every
+// reached node's shortest distance from a seed.
+func Hops(edges []database.TraceEdge) map[int64]int {
+	children := make(map[int64][]int64)
+	hops := make(map[int64]int)
+	var queue []int64
+	for _, e := range edges {
+		if e.ParentTraceID == nil {
+			if _, ok := hops[e.ChildTraceID]; !ok {
+				hops[e.ChildTraceID] = 0
+				queue = append(queue, e.ChildTraceID)
+			}
+			continue
+		}
+		children[*e.ParentTraceID] = append(children[*e.ParentTraceID], e.ChildTraceID)
+	}
+
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, child := range children[id] {
+			if _, ok := hops[child]; !ok {
+				hops[child] = hops[id] + 1
+				queue = append(queue, child)
+			}
+		}
+	}
+	return hops
+}
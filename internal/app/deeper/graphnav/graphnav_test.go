@@ -0,0 +1,128 @@
+package graphnav
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func edge(parent, child int64, plugin string) database.TraceEdge {
+	e := database.TraceEdge{ChildTraceID: child, PluginName: plugin}
+	if parent != 0 {
+		e.ParentTraceID = &parent
+	}
+	return e
+}
+
+// testGraph: 1 jdoe (seed) -> 2 email -> 3 domain -> 4 ip, with a shortcut
+// 1 -> 3 and an unreached island 5 -> 6.
+func testGraph() *Graph {
+	nodes := []database.Trace{
+		{ID: 1, Value: "jdoe", Type: entities.Username},
+		{ID: 2, Value: "jdoe@example.com", Type: entities.Email},
+		{ID: 3, Value: "example.com", Type: entities.Domain},
+		{ID: 4, Value: "93.184.216.34", Type: entities.IpAddr},
+		{ID: 5, Value: "jdoe", Type: entities.Github},
+		{ID: 6, Value: "orphan", Type: entities.Name},
+	}
+	edges := []database.TraceEdge{
+		edge(0, 1, database.SeedPluginName),
+		edge(1, 2, "GitHubProfilePlugin"),
+		edge(2, 3, "EmailDomainPlugin"),
+		edge(1, 3, "WebsitePlugin"),
+		edge(3, 4, "DNSResolverPlugin"),
+		edge(5, 6, "GitHubIdentityPlugin"),
+	}
+	return New(nodes, edges)
+}
+
+func TestFind(t *testing.T) {
+	g := testGraph()
+
+	found, err := g.Find("jdoe@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), found.ID)
+
+	found, err = g.Find("EXAMPLE.com")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), found.ID, "falls back to a case-insensitive match")
+
+	_, err = g.Find("jdoe")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "username:jdoe")
+	assert.Contains(t, err.Error(), "github:jdoe")
+
+	found, err = g.Find("github:jdoe")
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), found.ID)
+
+	found, err = g.Find("#4")
+	require.NoError(t, err)
+	assert.Equal(t, "93.184.216.34", found.Value)
+
+	_, err = g.Find("#99")
+	assert.Error(t, err)
+	_, err = g.Find("nobody")
+	assert.Error(t, err)
+}
+
+func TestChain_FollowsNearestParent(t *testing.T) {
+	g := testGraph()
+	all := []database.TraceEdge{
+		edge(0, 1, database.SeedPluginName),
+		edge(1, 2, "GitHubProfilePlugin"),
+		edge(2, 3, "EmailDomainPlugin"),
+		edge(1, 3, "WebsitePlugin"),
+		edge(3, 4, "DNSResolverPlugin"),
+	}
+
+	chain := g.Chain(4, all)
+	assert.Equal(t, []Step{
+		{TraceID: 1, Value: "jdoe", Type: entities.Username},
+		{TraceID: 3, Value: "example.com", Type: entities.Domain, Plugin: "WebsitePlugin"},
+		{TraceID: 4, Value: "93.184.216.34", Type: entities.IpAddr, Plugin: "DNSResolverPlugin"},
+	}, chain)
+
+	assert.Len(t, g.Chain(1, all), 1, "a seed's chain is itself")
+	assert.Equal(t, []Step{{TraceID: 6, Value: "orphan", Type: entities.Name}}, g.Chain(6, []database.TraceEdge{edge(5, 6, "GitHubIdentityPlugin")}),
+		"parents no seed reaches are not followed")
+}
+
+func TestNeighbors(t *testing.T) {
+	g := testGraph()
+	parents, children := g.Neighbors(3)
+	require.Len(t, parents, 2)
+	assert.Equal(t, "jdoe", parents[0].Trace.Value)
+	assert.Equal(t, "WebsitePlugin", parents[0].Plugin)
+	assert.Equal(t, "jdoe@example.com", parents[1].Trace.Value)
+	require.Len(t, children, 1)
+	assert.Equal(t, "DNSResolverPlugin", children[0].Plugin)
+
+	assert.True(t, g.IsSeed(1))
+	assert.False(t, g.IsSeed(3))
+	assert.Equal(t, -1, g.Hop(6))
+}
+
+func TestSubgraph(t *testing.T) {
+	g := testGraph()
+
+	nodes, edges := g.Subgraph(2, 1)
+	var ids []int64
+	for _, n := range nodes {
+		ids = append(ids, n.ID)
+	}
+	assert.Equal(t, []int64{1, 2, 3}, ids, "both directions, one hop")
+	assert.Len(t, edges, 4, "the seed edge and the three edges among 1, 2 and 3")
+
+	nodes, _ = g.Subgraph(4, 0)
+	assert.Len(t, nodes, 1)
+}
+
+func TestHops(t *testing.T) {
+	hops := Hops(testGraph().edges)
+	assert.Equal(t, map[int64]int{1: 0, 2: 1, 3: 1, 4: 2}, hops)
+}
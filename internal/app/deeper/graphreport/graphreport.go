@@ -28,6 +28,12 @@ var visNetworkJS = func() template.JS {
 	return template.JS(b)
 }()
 
+// VisNetworkJS returns the vendored vis-network build, for other offline
+// pages that draw graphs with it.
+func VisNetworkJS() []byte {
+	return []byte(visNetworkJS)
+}
+
 // Node is a graph vertex ready for rendering. Label is untrusted (it may
 // originate from scraped, attacker-influenced data) and must only ever be
 // embedded via the JSON payload, never interpolated directly into HTML/JS.
@@ -35,6 +41,8 @@ type Node struct {
 	ID    int64  `json:"id"`
 	Label string `json:"label"`
 	Type  string `json:"type"`
+	// Verdict is the analyst's verdict on the trace, if any.
+	Verdict string `json:"verdict,omitempty"`
 }
 
 // Edge is a directed graph edge; Label is the plugin that produced it.
@@ -42,29 +50,92 @@ type Edge struct {
 	From  int64  `json:"from"`
 	To    int64  `json:"to"`
 	Label string `json:"label"`
+	// Verdict is the analyst's verdict on the plugin's edges into To.
+	Verdict string `json:"verdict,omitempty"`
+}
+
+// Identity is an identity cluster: the nodes believed to belong to one
+// person or organization. Evidence lines explain the links behind the
+// cluster and are untrusted in the same way as Node.Label.
+type Identity struct {
+	Label    string   `json:"label"`
+	Members  []int64  `json:"members"`
+	Evidence []string `json:"evidence"`
+}
+
+// Overlay is an earlier scan that reached some of the same traces. Nodes
+// and Edges are its graph around the shared traces; the report draws them
+// over the current graph when the reader switches the overlay on.
+type Overlay struct {
+	ScanID    int64   `json:"scan_id"`
+	Input     string  `json:"input"`
+	StartedAt string  `json:"started_at"`
+	Shared    []int64 `json:"shared"`
+	Nodes     []Node  `json:"nodes"`
+	Edges     []Edge  `json:"edges"`
+}
+
+// Diff compares the report's scan with an earlier one. Added lists the
+// nodes and edges the earlier scan did not have; RemovedNodes and
+// RemovedEdges are the ones only it had, drawn in diff mode.
+type Diff struct {
+	AgainstScanID    int64   `json:"against_scan_id"`
+	AgainstInput     string  `json:"against_input"`
+	AgainstStartedAt string  `json:"against_started_at"`
+	Added            []int64 `json:"added"`
+	AddedEdges       []Edge  `json:"added_edges"`
+	RemovedNodes     []Node  `json:"removed_nodes"`
+	RemovedEdges     []Edge  `json:"removed_edges"`
+}
+
+// Report is everything a graph report shows. With a Diff, the report opens
+// in diff mode.
+type Report struct {
+	Nodes      []Node
+	Edges      []Edge
+	Identities []Identity
+	Overlays   []Overlay
+	Diff       *Diff
 }
 
 type graphData struct {
-	Nodes []Node `json:"nodes"`
-	Edges []Edge `json:"edges"`
+	Nodes      []Node     `json:"nodes"`
+	Edges      []Edge     `json:"edges"`
+	Identities []Identity `json:"identities"`
+	Overlays   []Overlay  `json:"overlays"`
+	Diff       *Diff      `json:"diff"`
 }
 
 // Render produces a complete standalone HTML document visualizing the given
 // nodes and edges as an interactive node-link diagram.
 func Render(nodes []Node, edges []Edge) (string, error) {
+	return RenderReport(Report{Nodes: nodes, Edges: edges})
+}
+
+// RenderReport is Render with identity clusters and prior-scan overlays:
+// selecting a node that belongs to a cluster shows the cluster and its
+// evidence, and each overlay can be toggled onto the graph.
+func RenderReport(report Report) (string, error) {
+	nodes, edges, identities, overlays := report.Nodes, report.Edges, report.Identities, report.Overlays
 	if nodes == nil {
 		nodes = []Node{}
 	}
 	if edges == nil {
 		edges = []Edge{}
 	}
+	if identities == nil {
+		identities = []Identity{}
+	}
+	if overlays == nil {
+		overlays = []Overlay{}
+	}
 
 	// json.Marshal HTML-escapes '<', '>' and '&' by default, which is what
 	// makes it safe to drop straight into a <script> block below: an
 	// attacker-controlled value like "</script><script>..." is encoded as
 	// "</script>...", so it can neither close the surrounding
 	// script tag nor be interpreted as markup by the HTML parser.
-	payload, err := json.Marshal(graphData{Nodes: nodes, Edges: edges})
+	payload, err := json.Marshal(graphData{Nodes: nodes, Edges: edges, Identities: identities, Overlays: overlays, Diff: report.Diff})
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal graph data: %w", err)
 	}
@@ -71,6 +71,89 @@ func TestRender_EscapesMaliciousValues(t *testing.T) {
 	assert.Equal(t, malicious, got.Nodes[0].Label)
 }
 
+func TestRenderReport_EmbedsIdentities(t *testing.T) {
+	report := Report{
+		Nodes: []Node{
+			{ID: 1, Label: "jdoe", Type: "username"},
+			{ID: 2, Label: "jdoe@example.com", Type: "email"},
+		},
+		Edges: []Edge{{From: 1, To: 2, Label: "GitHubProfilePlugin"}},
+		Identities: []Identity{
+			{Label: "John Doe", Members: []int64{1, 2}, Evidence: []string{"strong: jdoe -> jdoe@example.com"}},
+		},
+	}
+
+	html, err := RenderReport(report)
+	require.NoError(t, err)
+	assert.Contains(t, html, `id="details-identity"`)
+
+	var got graphData
+	require.NoError(t, json.Unmarshal([]byte(extractGraphDataJSON(t, html)), &got))
+	assert.Equal(t, report.Identities, got.Identities)
+}
+
+func TestRender_EmptyIdentitiesIsArray(t *testing.T) {
+	html, err := Render([]Node{{ID: 1, Label: "root.com", Type: "domain"}}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, extractGraphDataJSON(t, html), `"identities":[]`)
+}
+
+func TestRenderReport_EmbedsOverlays(t *testing.T) {
+	report := Report{
+		Nodes: []Node{{ID: 2, Label: "ops@example.com", Type: "email"}},
+		Overlays: []Overlay{{
+			ScanID:    7,
+			Input:     "alice",
+			StartedAt: "2024-05-01",
+			Shared:    []int64{2},
+			Nodes:     []Node{{ID: 1, Label: "alice", Type: "username"}, {ID: 2, Label: "ops@example.com", Type: "email"}},
+			Edges:     []Edge{{From: 1, To: 2, Label: "GitHubProfilePlugin"}},
+		}},
+	}
+
+	html, err := RenderReport(report)
+	require.NoError(t, err)
+	assert.Contains(t, html, `id="overlay-list"`)
+
+	var got graphData
+	require.NoError(t, json.Unmarshal([]byte(extractGraphDataJSON(t, html)), &got))
+	assert.Equal(t, report.Overlays, got.Overlays)
+}
+
+func TestRender_EmptyOverlaysIsArray(t *testing.T) {
+	html, err := Render([]Node{{ID: 1, Label: "root.com", Type: "domain"}}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, extractGraphDataJSON(t, html), `"overlays":[]`)
+}
+
+func TestRenderReport_EmbedsDiff(t *testing.T) {
+	report := Report{
+		Nodes: []Node{{ID: 1, Label: "jdoe", Type: "username"}, {ID: 4, Label: "new@example.com", Type: "email"}},
+		Edges: []Edge{{From: 1, To: 4, Label: "GitHubProfilePlugin"}},
+		Diff: &Diff{
+			AgainstScanID:    41,
+			AgainstInput:     "jdoe",
+			AgainstStartedAt: "2024-05-01",
+			Added:            []int64{4},
+			AddedEdges:       []Edge{{From: 1, To: 4, Label: "GitHubProfilePlugin"}},
+			RemovedNodes:     []Node{{ID: 2, Label: "old@example.com", Type: "email"}},
+			RemovedEdges:     []Edge{{From: 1, To: 2, Label: "GitHubProfilePlugin"}},
+		},
+	}
+
+	html, err := RenderReport(report)
+	require.NoError(t, err)
+	assert.Contains(t, html, `id="diff-mode"`)
+
+	var got graphData
+	require.NoError(t, json.Unmarshal([]byte(extractGraphDataJSON(t, html)), &got))
+	assert.Equal(t, report.Diff, got.Diff)
+
+	html, err = Render(report.Nodes, report.Edges)
+	require.NoError(t, err)
+	assert.Contains(t, extractGraphDataJSON(t, html), `"diff":null`)
+}
+
 func extractGraphDataJSON(t *testing.T, html string) string {
 	t.Helper()
 	const marker = `id="graph-data">`
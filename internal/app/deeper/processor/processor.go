@@ -7,13 +7,16 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"github.com/smirnoffmg/deeper/internal/app/deeper/processor/tasks"
+	"github.com/smirnoffmg/deeper/internal/pkg/audit"
 	"github.com/smirnoffmg/deeper/internal/pkg/config"
 	"github.com/smirnoffmg/deeper/internal/pkg/database"
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
 	"github.com/smirnoffmg/deeper/internal/pkg/errors"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
 	"github.com/smirnoffmg/deeper/internal/pkg/metrics"
 	"github.com/smirnoffmg/deeper/internal/pkg/plugins"
 	"github.com/smirnoffmg/deeper/internal/pkg/state"
+	"github.com/smirnoffmg/deeper/internal/pkg/telemetry"
 	"github.com/smirnoffmg/deeper/internal/pkg/workerpool"
 	"golang.org/x/time/rate"
 )
@@ -72,9 +75,46 @@ func NewProcessor(cfg *config.Config, metricsCollector *metrics.MetricsCollector
 	}
 }
 
+type pluginsKey struct{}
+
+// WithPlugins returns a context whose scan runs only the named plugins.
+func WithPlugins(ctx context.Context, names []string) context.Context {
+	allowed := make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+	return context.WithValue(ctx, pluginsKey{}, allowed)
+}
+
+// pluginsFrom returns the plugins ctx's scan is limited to, or nil for all.
+func pluginsFrom(ctx context.Context) map[string]bool {
+	allowed, _ := ctx.Value(pluginsKey{}).(map[string]bool)
+	return allowed
+}
+
+type freshKey struct{}
+
+// WithoutDeduplication returns a context whose scan runs every plugin
+// again, instead of skipping the (trace, plugin) pairs the deduplication
+// cache has seen done, whose replies carry no traces.
+func WithoutDeduplication(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshKey{}, true)
+}
+
+// deduplicates reports whether ctx's scan skips tasks already done.
+func deduplicates(ctx context.Context) bool {
+	fresh, _ := ctx.Value(freshKey{}).(bool)
+	return !fresh
+}
+
 // ProcessTrace processes a single trace through all applicable plugins using worker pool
 func (p *Processor) ProcessTrace(ctx context.Context, trace entities.Trace) ([]entities.Discovery, error) {
 	startTime := time.Now()
+	ctx, span := telemetry.Start(ctx, "trace "+string(trace.Type), telemetry.WithAttributes(
+		telemetry.String("deeper.trace.type", string(trace.Type)),
+		telemetry.String("deeper.trace.value", trace.Value),
+	))
+	defer span.End()
 
 	candidatePlugins, exists := state.ActivePlugins[trace.Type]
 	if !exists || len(candidatePlugins) == 0 {
@@ -93,6 +133,22 @@ func (p *Processor) ProcessTrace(ctx context.Context, trace entities.Trace) ([]e
 	// engine.processBatch's goroutine fan-out) would otherwise consume results
 	// meant for each other's traces.
 	replyTo := make(chan *workerpool.TaskResult, len(candidatePlugins))
+	emit := events.EmitterFrom(ctx)
+	auditLog := audit.LogFrom(ctx)
+	allowed := pluginsFrom(ctx)
+
+	// Every plugin the scan offers the trace to gets a run in the ledger,
+	// including those that never got to answer.
+	var runs []database.PluginRun
+	defer func() {
+		if len(runs) > 0 {
+			runRecorderFrom(ctx)(runs)
+		}
+	}()
+	newRun := func(pluginName string) database.PluginRun {
+		return database.PluginRun{Value: trace.Value, Type: trace.Type, PluginName: pluginName, StartedAt: time.Now()}
+	}
+	pending := make(map[string]database.PluginRun, len(candidatePlugins))
 
 	// Submit tasks to worker pool
 	submittedTasks := 0
@@ -106,12 +162,18 @@ func (p *Processor) ProcessTrace(ctx context.Context, trace entities.Trace) ([]e
 			allErrors = append(allErrors, errors.NewPluginError("invalid plugin interface", nil))
 			continue
 		}
+		if allowed != nil && !allowed[pluginInterface.String()] {
+			continue
+		}
 
 		// Plugins that opt into TraceMatcher get to skip submission -- and
 		// the domain rate-limit wait bundled into Submit() -- entirely for
 		// traces they'd immediately no-op on. Plugins that don't implement
 		// it are always submitted, unchanged from before.
+		run := newRun(pluginInterface.String())
 		if matcher, ok := plugin.(plugins.TraceMatcher); ok && !matcher.Matches(trace) {
+			run.Outcome = database.RunSkipped
+			runs = append(runs, run)
 			continue
 		}
 
@@ -122,8 +184,13 @@ func (p *Processor) ProcessTrace(ctx context.Context, trace entities.Trace) ([]e
 				Trace:     trace,
 				PluginKey: pluginInterface.String(),
 				Plugin:    pluginInterface,
+				Emit:      emit,
+				Span:      span,
+				Audit:     auditLog,
+				Submitted: time.Now(),
 			},
-			ReplyTo: replyTo,
+			ReplyTo:           replyTo,
+			SkipDeduplication: !deduplicates(ctx),
 		}
 
 		// Submit task to worker pool
@@ -131,8 +198,15 @@ func (p *Processor) ProcessTrace(ctx context.Context, trace entities.Trace) ([]e
 		if err != nil {
 			log.Error().Err(err).Msgf("Failed to submit task for plugin %s", pluginInterface.String())
 			allErrors = append(allErrors, err)
+			run.DurationMS = time.Since(run.StartedAt).Milliseconds()
+			if run.Outcome = submitOutcome(err); run.Outcome == database.RunError {
+				run.ErrorType = errorType(err)
+			}
+			run.Error = err.Error()
+			runs = append(runs, run)
 			continue
 		}
+		pending[task.ID] = run
 		submittedTasks++
 	}
 
@@ -142,27 +216,48 @@ func (p *Processor) ProcessTrace(ctx context.Context, trace entities.Trace) ([]e
 		select {
 		case result = <-replyTo:
 		case <-ctx.Done():
+			// Plugins still running when the scan gave up get a failed run
+			// rather than none.
+			for _, run := range pending {
+				run.DurationMS = time.Since(run.StartedAt).Milliseconds()
+				failRun(&run, ctx.Err())
+				runs = append(runs, run)
+			}
+			span.RecordError(ctx.Err())
 			return discoveries, ctx.Err()
 		}
 
+		run := pending[result.TaskID]
+		delete(pending, result.TaskID)
+		run.DurationMS = result.Duration.Milliseconds()
 		if result.Error != nil {
 			allErrors = append(allErrors, result.Error)
+			failRun(&run, result.Error)
+			runs = append(runs, run)
 			continue
 		}
 
-		pluginResult, ok := result.Result.(pluginTraceResult)
-		if !ok {
-			continue
-		}
-		for _, newTrace := range pluginResult.Traces {
-			if newTrace.Value != "" {
-				discoveries = append(discoveries, entities.Discovery{
-					Parent:     trace,
-					PluginName: pluginResult.PluginName,
-					Child:      newTrace,
-				})
+		if pluginResult, ok := result.Result.(pluginTraceResult); ok {
+			for _, newTrace := range pluginResult.Traces {
+				if newTrace.Value != "" {
+					discoveries = append(discoveries, entities.Discovery{
+						Parent:     trace,
+						PluginName: pluginResult.PluginName,
+						Child:      newTrace,
+					})
+					run.Children++
+				}
 			}
 		}
+		switch {
+		case result.Deduplicated:
+			run.Outcome = database.RunCached
+		case run.Children > 0:
+			run.Outcome = database.RunOK
+		default:
+			run.Outcome = database.RunEmpty
+		}
+		runs = append(runs, run)
 	}
 
 	// Record final metrics
@@ -171,6 +266,11 @@ func (p *Processor) ProcessTrace(ctx context.Context, trace entities.Trace) ([]e
 	p.metrics.RecordTraceTypeMetrics(trace.Type, true, len(discoveries), totalDuration)
 	p.metrics.IncrementTracesProcessed()
 	p.metrics.IncrementTracesDiscovered()
+	span.SetAttributes(
+		telemetry.Int("deeper.trace.plugin_tasks", submittedTasks),
+		telemetry.Int("deeper.trace.discoveries", len(discoveries)),
+		telemetry.Int("deeper.trace.errors", len(allErrors)),
+	)
 
 	// If there were any errors, log them but don't fail the entire operation
 	if len(allErrors) > 0 {
@@ -215,6 +315,13 @@ func (p *Processor) GetWorkerPoolMetrics() *workerpool.Metrics {
 	return nil
 }
 
+// Collect writes the worker pool's metrics
+func (p *Processor) Collect(e *metrics.Exposition) {
+	if p.workerPool != nil {
+		p.workerPool.Collect(e)
+	}
+}
+
 // ConfigureDomainRateLimit configures rate limiting for a specific domain
 func (p *Processor) ConfigureDomainRateLimit(domain string, rateLimit float64, burst int, backoffBase, backoffMax time.Duration, maxRetries int) error {
 	if p.workerPool != nil {
@@ -255,22 +362,78 @@ func newTraceTaskHandler(metricsCollector *metrics.MetricsCollector) workerpool.
 			return nil, errors.NewPluginError("invalid plugin interface", nil)
 		}
 
+		emit := taskPayload.Emit
+		if emit == nil {
+			emit = func(events.Event) {}
+		}
+		emit(events.Event{Type: events.PluginStarted, Trace: events.Ref(taskPayload.Trace), Plugin: pluginInterface.String()})
+
 		pluginStartTime := time.Now()
-		newTraces, err := pluginInterface.FollowTrace(taskPayload.Trace)
-		metricsCollector.RecordPluginExecution(pluginInterface.String(), time.Since(pluginStartTime), err == nil)
+		// The task's span starts when it was submitted, so the rate limit
+		// and queue waits that delayed it show up inside it.
+		spanOptions := []telemetry.SpanOption{telemetry.WithAttributes(
+			telemetry.String("deeper.plugin", pluginInterface.String()),
+			telemetry.String("deeper.trace.type", string(taskPayload.Trace.Type)),
+			telemetry.Milliseconds("deeper.task.rate_limit_wait_ms", task.RateLimitWait),
+			telemetry.Milliseconds("deeper.task.queue_wait_ms", pluginStartTime.Sub(task.Created)),
+		)}
+		if !taskPayload.Submitted.IsZero() {
+			spanOptions = append(spanOptions, telemetry.WithStartTime(taskPayload.Submitted))
+		}
+		// Plugin calls are not bounded by the task's context (see
+		// workerpool's processTask), so only the span and the scan's audit
+		// log travel to them.
+		pluginCtx := audit.WithPlugin(audit.WithLog(context.Background(), taskPayload.Audit), pluginInterface.String())
+		pluginCtx, span := telemetry.Start(telemetry.ContextWithSpan(pluginCtx, taskPayload.Span),
+			"plugin "+pluginInterface.String(), spanOptions...)
+		defer span.End()
+
+		var newTraces []entities.Trace
+		var err error
+		if follower, ok := taskPayload.Plugin.(plugins.ContextFollower); ok {
+			newTraces, err = follower.FollowTraceContext(pluginCtx, taskPayload.Trace)
+		} else {
+			newTraces, err = pluginInterface.FollowTrace(taskPayload.Trace)
+		}
+		elapsed := time.Since(pluginStartTime)
+		metricsCollector.RecordPluginExecution(pluginInterface.String(), taskPayload.Trace.Type, elapsed, err == nil)
+		span.RecordError(err)
 
 		if err != nil {
 			log.Error().Err(err).Msgf("Plugin %s failed to process trace", pluginInterface.String())
+			emit(events.Event{
+				Type:       events.PluginFailed,
+				Trace:      events.Ref(taskPayload.Trace),
+				Plugin:     pluginInterface.String(),
+				DurationMS: elapsed.Milliseconds(),
+				Error:      err.Error(),
+			})
 			return nil, errors.NewPluginError("plugin processing failed", err).WithContext("plugin", pluginInterface.String())
 		}
 
+		// Hostname outputs are relabelled through the shared public-suffix
+		// classifier, so a plugin that calls every host a Domain can't route
+		// subdomains into Domain-only plugins such as WHOIS.
 		filtered := make([]entities.Trace, 0, len(newTraces))
 		for _, newTrace := range newTraces {
-			if newTrace.Value != "" {
-				filtered = append(filtered, newTrace)
+			if newTrace.Value == "" {
+				continue
+			}
+			if entities.IsHostTraceType(newTrace.Type) {
+				newTrace = entities.HostTrace(newTrace.Value, newTrace.Type)
 			}
+			filtered = append(filtered, newTrace)
 		}
 
+		span.SetAttributes(telemetry.Int("deeper.plugin.discoveries", len(filtered)))
+		emit(events.Event{
+			Type:       events.PluginFinished,
+			Trace:      events.Ref(taskPayload.Trace),
+			Plugin:     pluginInterface.String(),
+			Count:      len(filtered),
+			DurationMS: elapsed.Milliseconds(),
+		})
+
 		return pluginTraceResult{
 			PluginName: pluginInterface.String(),
 			Traces:     filtered,
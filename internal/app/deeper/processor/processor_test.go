@@ -14,6 +14,7 @@ import (
 	"github.com/smirnoffmg/deeper/internal/pkg/config"
 	"github.com/smirnoffmg/deeper/internal/pkg/database"
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
 	"github.com/smirnoffmg/deeper/internal/pkg/metrics"
 	"github.com/smirnoffmg/deeper/internal/pkg/state"
 )
@@ -285,3 +286,125 @@ func TestProcessor_ConcurrentProcessTrace_NoCrossAttribution(t *testing.T) {
 
 	assert.Empty(t, mismatches, "cross-attribution detected: %v", mismatches)
 }
+
+const hostOutputTraceType entities.TraceType = "test_host_output"
+
+// domainEverythingPlugin labels every hostname it finds as a Domain, the way
+// several plugins used to before classification was centralized.
+type domainEverythingPlugin struct{}
+
+func (p *domainEverythingPlugin) Register() error {
+	state.RegisterPlugin(hostOutputTraceType, p)
+	return nil
+}
+
+func (p *domainEverythingPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return []entities.Trace{
+		{Value: "example.co.uk", Type: entities.Domain},
+		{Value: "mail.corp.example.co.uk", Type: entities.Domain},
+		{Value: "*.example.co.uk", Type: entities.Subdomain},
+	}, nil
+}
+
+func (p *domainEverythingPlugin) String() string {
+	return "DomainEverythingPlugin"
+}
+
+func TestProcessor_ProcessTrace_RelabelsHostOutputs(t *testing.T) {
+	original := state.ActivePlugins[hostOutputTraceType]
+	t.Cleanup(func() {
+		if original == nil {
+			delete(state.ActivePlugins, hostOutputTraceType)
+			return
+		}
+		state.ActivePlugins[hostOutputTraceType] = original
+	})
+	state.ActivePlugins[hostOutputTraceType] = nil
+	require.NoError(t, (&domainEverythingPlugin{}).Register())
+
+	cfg := config.DefaultConfig()
+	cfg.WorkerPoolConfig.EnableDeduplication = false
+
+	db, err := database.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	repo := database.NewRepository(db)
+	proc := NewProcessor(cfg, metrics.GetGlobalMetrics(), repo, database.NewCache(repo))
+	defer func() { _ = proc.Shutdown(5 * time.Second) }()
+
+	results, err := proc.ProcessTrace(context.Background(), entities.Trace{Value: "seed", Type: hostOutputTraceType})
+	require.NoError(t, err)
+
+	var children []entities.Trace
+	for _, d := range results {
+		children = append(children, d.Child)
+	}
+	assert.ElementsMatch(t, []entities.Trace{
+		{Value: "example.co.uk", Type: entities.Domain},
+		{Value: "mail.corp.example.co.uk", Type: entities.Subdomain},
+		{Value: "*.example.co.uk", Type: entities.Subdomain},
+	}, children)
+}
+
+const failingTraceType entities.TraceType = "test_failing"
+
+type failingPlugin struct{}
+
+func (p *failingPlugin) Register() error {
+	state.RegisterPlugin(failingTraceType, p)
+	return nil
+}
+
+func (p *failingPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return nil, fmt.Errorf("upstream unavailable")
+}
+
+func (p *failingPlugin) String() string {
+	return "FailingPlugin"
+}
+
+func TestProcessor_ProcessTrace_EmitsPluginEvents(t *testing.T) {
+	original := state.ActivePlugins[failingTraceType]
+	t.Cleanup(func() {
+		if original == nil {
+			delete(state.ActivePlugins, failingTraceType)
+			return
+		}
+		state.ActivePlugins[failingTraceType] = original
+	})
+	state.ActivePlugins[failingTraceType] = nil
+	require.NoError(t, (&failingPlugin{}).Register())
+
+	cfg := config.DefaultConfig()
+	cfg.WorkerPoolConfig.EnableDeduplication = false
+
+	db, err := database.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	repo := database.NewRepository(db)
+	proc := NewProcessor(cfg, metrics.GetGlobalMetrics(), repo, database.NewCache(repo))
+	defer func() { _ = proc.Shutdown(5 * time.Second) }()
+
+	var (
+		mu  sync.Mutex
+		got []events.Event
+	)
+	ctx := events.WithEmitter(context.Background(), func(ev events.Event) {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, ev)
+	})
+
+	_, err = proc.ProcessTrace(ctx, entities.Trace{Value: "seed", Type: failingTraceType})
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, got, 2)
+	assert.Equal(t, events.PluginStarted, got[0].Type)
+	assert.Equal(t, events.PluginFailed, got[1].Type)
+	assert.Equal(t, "FailingPlugin", got[1].Plugin)
+	assert.Contains(t, got[1].Error, "upstream unavailable")
+}
@@ -0,0 +1,99 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"regexp"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	deepererrors "github.com/smirnoffmg/deeper/internal/pkg/errors"
+	"github.com/smirnoffmg/deeper/internal/pkg/workerpool"
+)
+
+// RunRecorder receives the plugin runs against each trace a scan
+// processes, once the trace is done. The runs carry their trace's Value
+// and Type; ScanID is left to the recorder.
+type RunRecorder func(runs []database.PluginRun)
+
+type runsKey struct{}
+
+// WithRunRecorder returns a context whose scan reports its plugin runs to
+// record.
+func WithRunRecorder(ctx context.Context, record RunRecorder) context.Context {
+	return context.WithValue(ctx, runsKey{}, record)
+}
+
+// runRecorderFrom returns ctx's run recorder, or one that drops the runs.
+func runRecorderFrom(ctx context.Context) RunRecorder {
+	if record, ok := ctx.Value(runsKey{}).(RunRecorder); ok && record != nil {
+		return record
+	}
+	return func([]database.PluginRun) {}
+}
+
+// submitOutcome is the outcome of a run the worker pool refused to queue.
+func submitOutcome(err error) string {
+	switch {
+	case errors.Is(err, workerpool.ErrCircuitBreakerOpen):
+		return database.RunCircuitOpen
+	case errors.Is(err, workerpool.ErrRateLimited):
+		return database.RunRateLimited
+	}
+	return database.RunError
+}
+
+// failRun marks run failed with err. The worker's "plugin processing
+// failed" wrapper is dropped, so the ledger keeps the plugin's own error.
+func failRun(run *database.PluginRun, err error) {
+	var deeperErr *deepererrors.DeeperError
+	if errors.As(err, &deeperErr) && deeperErr.Type == deepererrors.ErrorTypePlugin && deeperErr.Cause != nil {
+		err = deeperErr.Cause
+	}
+	run.Outcome = database.RunError
+	run.ErrorType = errorType(err)
+	run.Error = err.Error()
+}
+
+// httpStatus finds the status code plugins put in their errors, as in
+// "profile request failed: status 403".
+var httpStatus = regexp.MustCompile(`\bstatus (?:code )?(\d{3})\b`)
+
+// errorType classifies why a run failed, so that a plugin timing out can
+// be told apart from one being refused or broken.
+func errorType(err error) string {
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "cancelled"
+	case errors.As(err, &dnsErr):
+		return "dns"
+	case errors.As(err, &netErr), isNetworkError(err):
+		return "network"
+	}
+	if m := httpStatus.FindStringSubmatch(err.Error()); m != nil {
+		return "http_" + m[1]
+	}
+	return "plugin"
+}
+
+// isNetworkError reports whether any error in err's chain is a network
+// DeeperError, such as the HTTP client's.
+func isNetworkError(err error) bool {
+	for err != nil {
+		var deeperErr *deepererrors.DeeperError
+		if !errors.As(err, &deeperErr) {
+			return false
+		}
+		if deeperErr.Type == deepererrors.ErrorTypeNetwork {
+			return true
+		}
+		err = deeperErr.Cause
+	}
+	return false
+}
@@ -0,0 +1,181 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/config"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	deepererrors "github.com/smirnoffmg/deeper/internal/pkg/errors"
+	"github.com/smirnoffmg/deeper/internal/pkg/metrics"
+	"github.com/smirnoffmg/deeper/internal/pkg/state"
+)
+
+const ledgerTraceType entities.TraceType = "test_ledger"
+
+type emptyPlugin struct{}
+
+func (p *emptyPlugin) Register() error {
+	state.RegisterPlugin(ledgerTraceType, p)
+	return nil
+}
+
+func (p *emptyPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return nil, nil
+}
+
+func (p *emptyPlugin) String() string {
+	return "EmptyPlugin"
+}
+
+func TestProcessor_ProcessTrace_RecordsRuns(t *testing.T) {
+	original := state.ActivePlugins[ledgerTraceType]
+	t.Cleanup(func() {
+		if original == nil {
+			delete(state.ActivePlugins, ledgerTraceType)
+			return
+		}
+		state.ActivePlugins[ledgerTraceType] = original
+	})
+	state.ActivePlugins[ledgerTraceType] = nil
+	state.RegisterPlugin(ledgerTraceType, &matcherPlugin{name: "NonMatchingPlugin"})
+	state.RegisterPlugin(ledgerTraceType, &plainMatcherTestPlugin{name: "FoundPlugin"})
+	require.NoError(t, (&emptyPlugin{}).Register())
+	state.RegisterPlugin(ledgerTraceType, &failingPlugin{})
+
+	cfg := config.DefaultConfig()
+	cfg.WorkerPoolConfig.DeduplicationConfig.PersistentCache = false
+
+	db, err := database.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	repo := database.NewRepository(db)
+	proc := NewProcessor(cfg, metrics.GetGlobalMetrics(), repo, database.NewCache(repo))
+	defer func() { _ = proc.Shutdown(5 * time.Second) }()
+
+	process := func() map[string]database.PluginRun {
+		var recorded []database.PluginRun
+		ctx := WithRunRecorder(context.Background(), func(runs []database.PluginRun) {
+			recorded = append(recorded, runs...)
+		})
+		_, err := proc.ProcessTrace(ctx, entities.Trace{Value: "target", Type: ledgerTraceType})
+		require.NoError(t, err)
+
+		byPlugin := make(map[string]database.PluginRun)
+		for _, run := range recorded {
+			assert.Equal(t, "target", run.Value)
+			assert.Equal(t, ledgerTraceType, run.Type)
+			assert.False(t, run.StartedAt.IsZero())
+			byPlugin[run.PluginName] = run
+		}
+		return byPlugin
+	}
+
+	runs := process()
+	outcomes := make([]string, 0, len(runs))
+	for name, run := range runs {
+		outcomes = append(outcomes, name+"="+run.Outcome)
+	}
+	sort.Strings(outcomes)
+	assert.Equal(t, []string{
+		"EmptyPlugin=empty",
+		"FailingPlugin=error",
+		"FoundPlugin=ok",
+		"NonMatchingPlugin=skipped-by-matcher",
+	}, outcomes)
+	assert.Equal(t, 1, runs["FoundPlugin"].Children)
+	assert.Equal(t, "upstream unavailable", runs["FailingPlugin"].Error)
+	assert.Equal(t, "plugin", runs["FailingPlugin"].ErrorType)
+
+	// A rerun in the same process is answered by the deduplication cache.
+	runs = process()
+	assert.Equal(t, database.RunCached, runs["FoundPlugin"].Outcome)
+	assert.Equal(t, database.RunCached, runs["EmptyPlugin"].Outcome)
+	assert.Equal(t, database.RunSkipped, runs["NonMatchingPlugin"].Outcome)
+}
+
+type blockingPlugin struct {
+	release chan struct{}
+}
+
+func (p *blockingPlugin) Register() error {
+	state.RegisterPlugin(ledgerTraceType, p)
+	return nil
+}
+
+func (p *blockingPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	<-p.release
+	return nil, nil
+}
+
+func (p *blockingPlugin) String() string {
+	return "BlockingPlugin"
+}
+
+func TestProcessor_ProcessTrace_RecordsRunsOutlivingContext(t *testing.T) {
+	original := state.ActivePlugins[ledgerTraceType]
+	t.Cleanup(func() {
+		if original == nil {
+			delete(state.ActivePlugins, ledgerTraceType)
+			return
+		}
+		state.ActivePlugins[ledgerTraceType] = original
+	})
+	state.ActivePlugins[ledgerTraceType] = nil
+	blocking := &blockingPlugin{release: make(chan struct{})}
+	require.NoError(t, blocking.Register())
+
+	cfg := config.DefaultConfig()
+	cfg.WorkerPoolConfig.DeduplicationConfig.PersistentCache = false
+
+	db, err := database.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	repo := database.NewRepository(db)
+	proc := NewProcessor(cfg, metrics.GetGlobalMetrics(), repo, database.NewCache(repo))
+	defer func() { _ = proc.Shutdown(5 * time.Second) }()
+	defer close(blocking.release)
+
+	var recorded []database.PluginRun
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	ctx = WithRunRecorder(ctx, func(runs []database.PluginRun) {
+		recorded = append(recorded, runs...)
+	})
+
+	_, err = proc.ProcessTrace(ctx, entities.Trace{Value: "target", Type: ledgerTraceType})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	require.Len(t, recorded, 1)
+	assert.Equal(t, "BlockingPlugin", recorded[0].PluginName)
+	assert.Equal(t, database.RunError, recorded[0].Outcome)
+	assert.Equal(t, "timeout", recorded[0].ErrorType)
+	assert.Equal(t, "target", recorded[0].Value)
+}
+
+func TestErrorType(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{context.DeadlineExceeded, "timeout"},
+		{fmt.Errorf("lookup failed: %w", &net.DNSError{Err: "no such host", Name: "example.invalid"}), "dns"},
+		{deepererrors.NewNetworkError("request failed", fmt.Errorf("connection reset")), "network"},
+		{deepererrors.NewPluginError("plugin processing failed", deepererrors.NewNetworkError("server error", nil)), "network"},
+		{fmt.Errorf("github commits request failed: status 403"), "http_403"},
+		{fmt.Errorf("unexpected response shape"), "plugin"},
+	} {
+		assert.Equal(t, tc.want, errorType(tc.err), tc.err.Error())
+	}
+}
@@ -1,7 +1,12 @@
 package tasks
 
 import (
+	"time"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/audit"
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
+	"github.com/smirnoffmg/deeper/internal/pkg/telemetry"
 )
 
 // TraceProcessingTask represents a task for processing a trace through plugins
@@ -9,6 +14,19 @@ type TraceProcessingTask struct {
 	Trace     entities.Trace
 	PluginKey string
 	Plugin    interface{}
+	// Emit reports plugin progress to the scan that submitted the task;
+	// worker goroutines don't see the submitter's context, so it travels
+	// with the task instead.
+	Emit events.Emitter
+	// Span is the span of the trace the task belongs to, the parent of the
+	// task's own span, for the same reason.
+	Span *telemetry.Span
+	// Audit is the audit log of the scan the task belongs to, which the
+	// task's outbound contacts are recorded in.
+	Audit *audit.Log
+	// Submitted is when the task was handed to the worker pool, so the
+	// task's span covers its rate limit and queue waits.
+	Submitted time.Time
 }
 
 // GetID returns a unique identifier for the task
@@ -0,0 +1,118 @@
+package report
+
+import (
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Format names a report output format.
+type Format string
+
+const (
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+)
+
+//go:embed templates/report.md.tmpl templates/report.html.tmpl
+var templateFS embed.FS
+
+// TemplateName is the file name of a format's template, both embedded and
+// as looked up in a user's template directory.
+func TemplateName(format Format) string {
+	if format == FormatHTML {
+		return "report.html.tmpl"
+	}
+	return "report.md.tmpl"
+}
+
+// FormatFromPath picks the format for an output file: HTML for .html and
+// .htm, Markdown otherwise.
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".html", ".htm":
+		return FormatHTML
+	default:
+		return FormatMarkdown
+	}
+}
+
+// DefaultTemplate returns the built-in template of a format, the starting
+// point for a custom one.
+func DefaultTemplate(format Format) (string, error) {
+	if format != FormatMarkdown && format != FormatHTML {
+		return "", fmt.Errorf("unsupported report format %q (supported: markdown, html)", format)
+	}
+	b, err := templateFS.ReadFile("templates/" + TemplateName(format))
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// Render writes data through a template. An empty text uses the format's
+// built-in template. HTML templates escape values automatically; Markdown
+// templates escape them with the md function.
+func Render(w io.Writer, data *Data, format Format, text string) error {
+	if text == "" {
+		var err error
+		if text, err = DefaultTemplate(format); err != nil {
+			return err
+		}
+	}
+
+	switch format {
+	case FormatMarkdown:
+		tmpl, err := texttemplate.New("report").Funcs(texttemplate.FuncMap(funcs)).Parse(text)
+		if err != nil {
+			return fmt.Errorf("failed to parse report template: %w", err)
+		}
+		if err := tmpl.Execute(w, data); err != nil {
+			return fmt.Errorf("failed to render report: %w", err)
+		}
+	case FormatHTML:
+		tmpl, err := htmltemplate.New("report").Funcs(htmltemplate.FuncMap(funcs)).Parse(text)
+		if err != nil {
+			return fmt.Errorf("failed to parse report template: %w", err)
+		}
+		if err := tmpl.Execute(w, data); err != nil {
+			return fmt.Errorf("failed to render report: %w", err)
+		}
+	default:
+		return fmt.Errorf("unsupported report format %q (supported: markdown, html)", format)
+	}
+	return nil
+}
+
+// funcs are available to every report template.
+var funcs = map[string]interface{}{
+	"join": func(sep string, items []string) string { return strings.Join(items, sep) },
+	"date": func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04 UTC") },
+	"duration": func(ms int64) string {
+		return (time.Duration(ms) * time.Millisecond).Round(time.Second).String()
+	},
+	"plural": func(n int, singular, plural string) string {
+		if n == 1 {
+			return "1 " + singular
+		}
+		return fmt.Sprintf("%d %s", n, plural)
+	},
+	"md": markdownEscape,
+	// verdict spells an analyst verdict out for readers.
+	"verdict": func(v string) string { return strings.ReplaceAll(v, "_", " ") },
+}
+
+var markdownReplacer = strings.NewReplacer(
+	`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`,
+	"<", `\<`, ">", `\>`, "|", `\|`, "#", `\#`, "\r", " ", "\n", " ",
+)
+
+// markdownEscape makes s safe inside Markdown text and table cells.
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(s)
+}
@@ -0,0 +1,353 @@
+// Package report builds the narrative deliverable of a scan: an executive
+// summary, the seeds, identities and infrastructure found, a breakdown by
+// trace type, how each key finding was reached, plugin coverage and an
+// appendix of every trace. It renders through templates, Markdown and
+// standalone HTML by default, which teams can replace with their own.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/graphnav"
+	"github.com/smirnoffmg/deeper/internal/app/deeper/results"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/identity"
+)
+
+// MaxKeyFindings caps how many findings get a provenance section, so
+// reports on large scans stay readable. The appendix still lists everything.
+const MaxKeyFindings = 50
+
+// categoryExamples is how many values each category row shows.
+const categoryExamples = 5
+
+// infrastructureTypes are the trace types listed as infrastructure.
+var infrastructureTypes = map[entities.TraceType]bool{
+	entities.Domain: true, entities.Subdomain: true, entities.Host: true,
+	entities.IpAddr: true, entities.Netblock: true, entities.IPRange: true,
+	entities.ASN: true, entities.Whois: true, entities.Certificates: true,
+	entities.DnsRecordA: true, entities.DnsRecordAAAA: true, entities.DnsRecordMX: true,
+	entities.DnsRecordNS: true, entities.DnsRecordTXT: true, entities.DnsRecordCNAME: true,
+	entities.DnsRecordSOA: true, entities.DnsRecordPTR: true, entities.DnsRecordSRV: true,
+	entities.DnsRecordCAA: true,
+}
+
+// keyFindingTypes are the trace types whose discovery an analyst has to be
+// able to justify: identifiers of people and the infrastructure they run.
+var keyFindingTypes = map[entities.TraceType]bool{
+	entities.Email: true, entities.Phone: true, entities.Name: true,
+	entities.Company: true, entities.Username: true, entities.Address: true,
+	entities.SSHKey: true, entities.PGPKey: true, entities.BitcoinAddress: true,
+	entities.PayPalAccount: true, entities.Twitter: true, entities.Github: true,
+	entities.Linkedin: true, entities.Instagram: true, entities.Facebook: true,
+	entities.TikTok: true, entities.Reddit: true, entities.YouTube: true,
+	entities.Pinterest: true, entities.Snapchat: true, entities.Tumblr: true,
+	entities.SocialGeneric: true, entities.Domain: true, entities.IpAddr: true,
+	entities.ASN: true, entities.AnalyticsID: true,
+}
+
+// Data is everything a report template can show.
+type Data struct {
+	Title       string
+	GeneratedAt time.Time
+	Scan        results.Scan
+	// Case is set on a case-level report, which covers the union of the
+	// case's scans; Scan then summarizes them all.
+	Case           *Case
+	Summary        Summary
+	Seeds          []Trace
+	Identities     []Identity
+	Infrastructure []Trace
+	Categories     []Category
+	KeyFindings    []Finding
+	Plugins        []results.PluginStats
+	// Verdicts lists the analysts' verdicts on the report's traces and
+	// edges.
+	Verdicts []Verdict
+	// Traces is the appendix: every trace, nearest to the seeds first.
+	Traces []Trace
+}
+
+// Case is the investigation a case-level report covers.
+type Case struct {
+	Name          string
+	Owner         string
+	Authorization string
+	Status        string
+	Tags          []string
+	Seeds         []string
+	Notes         []database.CaseNote
+	Scans         []results.Scan
+}
+
+// Summary holds the headline numbers.
+type Summary struct {
+	Traces         int
+	Edges          int
+	Identities     int
+	Infrastructure int
+	Categories     int
+	Plugins        int
+	// MaxHop is the length of the longest shortest discovery chain.
+	MaxHop int
+}
+
+// Trace is one trace as the report shows it.
+type Trace struct {
+	ID    int64
+	Value string
+	Type  entities.TraceType
+	// Hop is the trace's distance from the seeds, or -1 if none reaches it.
+	Hop     int
+	Seed    bool
+	Guessed bool
+	// DiscoveredBy lists the plugins that found the trace, without the
+	// seed pseudo-plugins.
+	DiscoveredBy []string
+	Verdict      string
+}
+
+// Verdict is an analyst's verdict on a trace or, when Plugin is set, on
+// that plugin's edges into it.
+type Verdict struct {
+	Trace   Trace
+	Plugin  string
+	Verdict string
+	Note    string
+}
+
+// Identity is an identity cluster with the evidence behind it.
+type Identity struct {
+	Label    string
+	Members  []Trace
+	Evidence []string
+}
+
+// Category is every trace of one type.
+type Category struct {
+	Type     entities.TraceType
+	Count    int
+	Examples []string
+	Traces   []Trace
+}
+
+// Finding is a key trace and how the scan reached it.
+type Finding struct {
+	Trace
+	// Path runs from a seed to the finding.
+	Path []graphnav.Step
+}
+
+// Load gathers a stored scan's report data.
+func Load(repo *database.Repository, scanID int64) (*Data, error) {
+	doc, err := results.Load(repo, scanID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes, edges, err := repo.GetScanGraph(scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scan graph: %w", err)
+	}
+	clusters, links, err := repo.GetIdentities(scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identities: %w", err)
+	}
+	if len(clusters) == 0 {
+		// Scans from before identity resolution have none stored.
+		clusters, links = identity.Resolve(nodes, edges)
+	}
+
+	data := Build(doc, clusters, links)
+	graph := graphnav.New(nodes, edges)
+	for i := range data.KeyFindings {
+		ancestry, err := repo.GetDiscoveryPath(scanID, data.KeyFindings[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		data.KeyFindings[i].Path = graph.Chain(data.KeyFindings[i].ID, ancestry)
+	}
+	return data, nil
+}
+
+// LoadCase gathers the report data of a whole case: the union of its
+// scans' graphs, with each discovery counted once however many scans made
+// it, and identities resolved across scans.
+func LoadCase(repo *database.Repository, c *database.Case) (*Data, error) {
+	if len(c.ScanIDs) == 0 {
+		return nil, fmt.Errorf("case %q has no scans", c.Name)
+	}
+
+	reportCase := &Case{
+		Name: c.Name, Owner: c.Owner, Authorization: c.Authorization, Status: c.Status,
+		Tags: c.Tags, Seeds: c.Seeds, Notes: c.Notes,
+	}
+	union := database.ScanSession{Input: c.Name, Status: c.Status}
+	var latest *time.Time
+	nodeSet := make(map[int64]database.Trace)
+	edgeSet := make(map[string]int)
+	var nodes []database.Trace
+	var edges []database.TraceEdge
+	for i, scanID := range c.ScanIDs {
+		doc, err := results.Load(repo, scanID)
+		if err != nil {
+			return nil, err
+		}
+		reportCase.Scans = append(reportCase.Scans, doc.Scan)
+		if i == 0 || doc.Scan.StartedAt.Before(union.StartedAt) {
+			union.StartedAt = doc.Scan.StartedAt
+		}
+		if done := doc.Scan.CompletedAt; done != nil && (latest == nil || done.After(*latest)) {
+			latest = done
+		}
+		union.Errors += doc.Scan.Errors
+
+		scanNodes, scanEdges, err := repo.GetScanGraph(scanID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load scan graph: %w", err)
+		}
+		for _, n := range scanNodes {
+			if _, ok := nodeSet[n.ID]; !ok {
+				nodeSet[n.ID] = n
+				nodes = append(nodes, n)
+			}
+		}
+		for _, e := range scanEdges {
+			key := fmt.Sprintf("%d>%d:%s", parentOf(e), e.ChildTraceID, e.PluginName)
+			if j, ok := edgeSet[key]; ok {
+				if e.DiscoveredAt.Before(edges[j].DiscoveredAt) {
+					edges[j] = e
+				}
+				continue
+			}
+			edgeSet[key] = len(edges)
+			edges = append(edges, e)
+		}
+	}
+	union.CompletedAt = latest
+
+	verdicts, err := repo.GetVerdicts()
+	if err != nil {
+		return nil, err
+	}
+	doc := results.Build(union, nodes, edges)
+	doc.ApplyVerdicts(database.NewVerdictIndex(verdicts))
+
+	clusters, links := identity.Resolve(nodes, edges)
+	data := Build(doc, clusters, links)
+	data.Case = reportCase
+
+	// Every edge of the union is some scan's ancestry, so the chain can be
+	// picked from all of them.
+	graph := graphnav.New(nodes, edges)
+	for i := range data.KeyFindings {
+		data.KeyFindings[i].Path = graph.Chain(data.KeyFindings[i].ID, edges)
+	}
+	return data, nil
+}
+
+func parentOf(e database.TraceEdge) int64 {
+	if e.ParentTraceID == nil {
+		return 0
+	}
+	return *e.ParentTraceID
+}
+
+// Build assembles report data from a results document and its identities.
+// Key findings are chosen but their paths are left empty; Load fills them.
+func Build(doc *results.Document, clusters []database.IdentityCluster, links []database.IdentityLink) *Data {
+	data := &Data{
+		Title:       fmt.Sprintf("Investigation report: %s", doc.Scan.Input),
+		GeneratedAt: doc.GeneratedAt,
+		Scan:        doc.Scan,
+	}
+
+	byID := make(map[int64]Trace, len(doc.Nodes))
+	byType := make(map[entities.TraceType][]Trace)
+	for _, n := range doc.Nodes {
+		t := Trace{ID: n.ID, Value: n.Value, Type: n.Type, Hop: n.Hop, Seed: n.Seed, Guessed: n.Guessed, Verdict: n.Verdict}
+		for _, plugin := range n.DiscoveredBy {
+			if !database.IsSeedPlugin(plugin) {
+				t.DiscoveredBy = append(t.DiscoveredBy, plugin)
+			}
+		}
+		byID[t.ID] = t
+		byType[t.Type] = append(byType[t.Type], t)
+		data.Traces = append(data.Traces, t)
+
+		if t.Hop > data.Summary.MaxHop {
+			data.Summary.MaxHop = t.Hop
+		}
+		switch {
+		case t.Seed:
+			data.Seeds = append(data.Seeds, t)
+		case infrastructureTypes[t.Type]:
+			data.Infrastructure = append(data.Infrastructure, t)
+		}
+		if t.Verdict != "" {
+			data.Verdicts = append(data.Verdicts, Verdict{Trace: t, Verdict: n.Verdict, Note: n.VerdictNote})
+		}
+		if !t.Seed && keyFindingTypes[t.Type] && t.Verdict != database.VerdictFalsePositive && len(data.KeyFindings) < MaxKeyFindings {
+			data.KeyFindings = append(data.KeyFindings, Finding{Trace: t})
+		}
+	}
+
+	for traceType, traces := range byType {
+		category := Category{Type: traceType, Count: len(traces), Traces: traces}
+		for i := 0; i < len(traces) && i < categoryExamples; i++ {
+			category.Examples = append(category.Examples, traces[i].Value)
+		}
+		data.Categories = append(data.Categories, category)
+	}
+	sort.Slice(data.Categories, func(i, j int) bool {
+		a, b := data.Categories[i], data.Categories[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Type < b.Type
+	})
+
+	traces := make(map[int64]database.Trace, len(byID))
+	for id, t := range byID {
+		traces[id] = database.Trace{ID: id, Value: t.Value, Type: t.Type}
+	}
+	for _, c := range clusters {
+		related := identity.ClusterLinks(c, links)
+		if len(c.MemberIDs) < 2 && len(related) == 0 {
+			continue
+		}
+		ident := Identity{Label: c.Label, Evidence: identity.DescribeLinks(related, traces)}
+		for _, id := range c.MemberIDs {
+			if t, ok := byID[id]; ok {
+				ident.Members = append(ident.Members, t)
+			}
+		}
+		data.Identities = append(data.Identities, ident)
+	}
+
+	edgeVerdicts := make(map[string]bool)
+	for _, e := range doc.Edges {
+		key := fmt.Sprintf("%d:%s", e.To, e.Plugin)
+		if e.Verdict != "" && !edgeVerdicts[key] {
+			edgeVerdicts[key] = true
+			data.Verdicts = append(data.Verdicts, Verdict{Trace: byID[e.To], Plugin: e.Plugin, Verdict: e.Verdict, Note: e.VerdictNote})
+		}
+	}
+
+	for _, p := range doc.Plugins {
+		if !database.IsSeedPlugin(p.Name) {
+			data.Plugins = append(data.Plugins, p)
+		}
+	}
+
+	data.Summary.Traces = len(doc.Nodes)
+	data.Summary.Edges = len(doc.Edges)
+	data.Summary.Identities = len(data.Identities)
+	data.Summary.Infrastructure = len(data.Infrastructure)
+	data.Summary.Categories = len(data.Categories)
+	data.Summary.Plugins = len(data.Plugins)
+	return data
+}
@@ -0,0 +1,191 @@
+package report
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// newTestScan stores a small scan: jdoe's GitHub profile lists an email
+// and a name, the email's domain resolves to an address.
+func newTestScan(t *testing.T) (*database.Repository, int64) {
+	t.Helper()
+	db, err := database.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	repo := database.NewRepository(db)
+
+	session, err := repo.CreateScanSession("jdoe")
+	require.NoError(t, err)
+	user := entities.Trace{Value: "jdoe", Type: entities.Username}
+	email := entities.Trace{Value: "jdoe@example.com", Type: entities.Email}
+	domain := entities.Trace{Value: "example.com", Type: entities.Domain}
+
+	userID, err := repo.GetOrCreateTrace(user)
+	require.NoError(t, err)
+	require.NoError(t, repo.InsertEdge(&database.TraceEdge{ChildTraceID: userID, PluginName: database.SeedPluginName, ScanID: session.ID, DiscoveredAt: time.Now()}))
+	require.NoError(t, repo.PersistDiscoveries(session.ID, []entities.Discovery{
+		{Parent: user, PluginName: "GitHubProfilePlugin", Child: email},
+		{Parent: user, PluginName: "GitHubProfilePlugin", Child: entities.Trace{Value: "John | Doe", Type: entities.Name}},
+		{Parent: email, PluginName: "EmailDomainPlugin", Child: domain},
+		{Parent: domain, PluginName: "DNSResolverPlugin", Child: entities.Trace{Value: "93.184.216.34", Type: entities.IpAddr}},
+	}))
+
+	completed := time.Now()
+	session.CompletedAt = &completed
+	session.Status = "completed"
+	require.NoError(t, repo.UpdateScanSession(session))
+	return repo, session.ID
+}
+
+func TestLoad_BuildsSectionsAndProvenance(t *testing.T) {
+	repo, scanID := newTestScan(t)
+
+	data, err := Load(repo, scanID)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Investigation report: jdoe", data.Title)
+	require.Len(t, data.Seeds, 1)
+	assert.Equal(t, "jdoe", data.Seeds[0].Value)
+	assert.Equal(t, 5, data.Summary.Traces)
+	assert.Equal(t, 3, data.Summary.MaxHop)
+	assert.Equal(t, 3, data.Summary.Plugins, "seed pseudo-plugins are not coverage")
+	assert.Len(t, data.Infrastructure, 2)
+	assert.Len(t, data.Categories, 5)
+
+	require.Len(t, data.Identities, 1, "identities are resolved when none are stored")
+	assert.Len(t, data.Identities[0].Members, 2)
+	assert.NotEmpty(t, data.Identities[0].Evidence)
+
+	var ip *Finding
+	for i := range data.KeyFindings {
+		if data.KeyFindings[i].Type == entities.IpAddr {
+			ip = &data.KeyFindings[i]
+		}
+	}
+	require.NotNil(t, ip)
+	var path []string
+	for _, step := range ip.Path {
+		path = append(path, step.Plugin+"/"+step.Value)
+	}
+	assert.Equal(t, []string{"/jdoe", "GitHubProfilePlugin/jdoe@example.com", "EmailDomainPlugin/example.com", "DNSResolverPlugin/93.184.216.34"}, path)
+}
+
+func TestRender_MarkdownAndHTML(t *testing.T) {
+	repo, scanID := newTestScan(t)
+	data, err := Load(repo, scanID)
+	require.NoError(t, err)
+
+	var md bytes.Buffer
+	require.NoError(t, Render(&md, data, FormatMarkdown, ""))
+	out := md.String()
+	for _, heading := range []string{"## Executive summary", "## Seed", "## Identities", "## Infrastructure",
+		"## Findings by category", "## Provenance of key findings", "## Plugin coverage", "## Appendix: all traces"} {
+		assert.Contains(t, out, heading)
+	}
+	assert.Contains(t, out, "deeper found 5 traces of 5 types")
+	assert.Contains(t, out, `John \| Doe`, "table cells are escaped")
+	assert.Contains(t, out, "3. DNSResolverPlugin found 93.184.216.34 (ip_addr)")
+
+	var html bytes.Buffer
+	require.NoError(t, Render(&html, data, FormatHTML, ""))
+	assert.True(t, strings.HasPrefix(html.String(), "<!DOCTYPE html>"))
+	assert.Contains(t, html.String(), "<h2>Provenance of key findings</h2>")
+	assert.Contains(t, html.String(), "@media print")
+}
+
+func TestLoad_ShowsVerdicts(t *testing.T) {
+	repo, scanID := newTestScan(t)
+	ip, err := repo.GetOrCreateTrace(entities.Trace{Value: "93.184.216.34", Type: entities.IpAddr})
+	require.NoError(t, err)
+	domain, err := repo.GetOrCreateTrace(entities.Trace{Value: "example.com", Type: entities.Domain})
+	require.NoError(t, err)
+	require.NoError(t, repo.SetVerdict(&database.Verdict{TraceID: ip, Verdict: database.VerdictFalsePositive, Note: "shared hosting"}))
+	require.NoError(t, repo.SetVerdict(&database.Verdict{TraceID: domain, PluginName: "EmailDomainPlugin", Verdict: database.VerdictConfirmed}))
+
+	data, err := Load(repo, scanID)
+	require.NoError(t, err)
+	require.Len(t, data.Verdicts, 2)
+	for _, f := range data.KeyFindings {
+		assert.NotEqual(t, ip, f.ID, "false positives are not key findings")
+	}
+
+	var md bytes.Buffer
+	require.NoError(t, Render(&md, data, FormatMarkdown, ""))
+	assert.Contains(t, md.String(), "## Analyst verdicts")
+	assert.Contains(t, md.String(), "| 93.184.216.34 | ip_addr | the trace | false positive | shared hosting |")
+	assert.Contains(t, md.String(), "edges from EmailDomainPlugin | confirmed")
+}
+
+func TestLoadCase_UnionsScans(t *testing.T) {
+	repo, firstID := newTestScan(t)
+
+	// A second scan repeats one discovery and adds another.
+	second, err := repo.CreateScanSession("example.com")
+	require.NoError(t, err)
+	domain := entities.Trace{Value: "example.com", Type: entities.Domain}
+	domainID, err := repo.GetOrCreateTrace(domain)
+	require.NoError(t, err)
+	require.NoError(t, repo.InsertEdge(&database.TraceEdge{ChildTraceID: domainID, PluginName: database.SeedPluginName, ScanID: second.ID, DiscoveredAt: time.Now()}))
+	require.NoError(t, repo.PersistDiscoveries(second.ID, []entities.Discovery{
+		{Parent: domain, PluginName: "DNSResolverPlugin", Child: entities.Trace{Value: "93.184.216.34", Type: entities.IpAddr}},
+		{Parent: domain, PluginName: "WhoisPlugin", Child: entities.Trace{Value: "ops@example.com", Type: entities.Email}},
+	}))
+
+	c := &database.Case{Name: "acme", Owner: "jsmith", Authorization: "ENG-114"}
+	require.NoError(t, repo.CreateCase(c))
+	require.NoError(t, repo.AddCaseScans(c.ID, firstID, second.ID))
+	_, err = repo.AddCaseNote(c.ID, "same registrant as last year")
+	require.NoError(t, err)
+	c, err = repo.GetCase(c.ID)
+	require.NoError(t, err)
+
+	data, err := LoadCase(repo, c)
+	require.NoError(t, err)
+	assert.Equal(t, "Investigation report: acme", data.Title)
+	require.NotNil(t, data.Case)
+	assert.Len(t, data.Case.Scans, 2)
+	assert.Len(t, data.Seeds, 2, "both scans' seeds")
+	assert.Equal(t, 6, data.Summary.Traces)
+	assert.Equal(t, 7, data.Summary.Edges, "the shared discovery counts once")
+
+	var md bytes.Buffer
+	require.NoError(t, Render(&md, data, FormatMarkdown, ""))
+	assert.Contains(t, md.String(), "from case acme: 2 scans")
+	assert.Contains(t, md.String(), "- Authorization: ENG-114")
+	assert.Contains(t, md.String(), "same registrant as last year")
+
+	var html bytes.Buffer
+	require.NoError(t, Render(&html, data, FormatHTML, ""))
+	assert.Contains(t, html.String(), "<h2>Case</h2>")
+
+	_, err = LoadCase(repo, &database.Case{Name: "empty"})
+	assert.Error(t, err)
+}
+
+func TestRender_CustomTemplate(t *testing.T) {
+	data := &Data{Title: "ACME <case>", Summary: Summary{Traces: 1}}
+
+	var buf bytes.Buffer
+	require.NoError(t, Render(&buf, data, FormatHTML, `<h1>{{.Title}}</h1> {{plural .Summary.Traces "trace" "traces"}}`))
+	assert.Equal(t, "<h1>ACME &lt;case&gt;</h1> 1 trace", buf.String())
+
+	err := Render(&buf, data, FormatMarkdown, "{{.Missing")
+	assert.Error(t, err)
+	err = Render(&buf, data, "pdf", "")
+	assert.Error(t, err)
+}
+
+func TestFormatFromPath(t *testing.T) {
+	assert.Equal(t, FormatHTML, FormatFromPath("out/Report.HTML"))
+	assert.Equal(t, FormatMarkdown, FormatFromPath("report.md"))
+	assert.Equal(t, FormatMarkdown, FormatFromPath(""))
+}
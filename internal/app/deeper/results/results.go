@@ -0,0 +1,316 @@
+// Package results builds the versioned, machine-readable form of a scan:
+// session info, every node and edge with its provenance, and per-plugin
+// statistics. It is what `deeper scan --output json` prints and what
+// `--save` writes.
+package results
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/graphnav"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// SchemaName identifies deeper scan result documents.
+const SchemaName = "deeper.scan-result"
+
+// SchemaVersion is bumped on any change that could break a consumer:
+// removing or renaming a field, or changing a field's meaning. Adding a
+// field does not bump it.
+const SchemaVersion = 1
+
+// Document is a complete scan result.
+type Document struct {
+	Schema        string        `json:"schema"`
+	SchemaVersion int           `json:"schema_version"`
+	GeneratedAt   time.Time     `json:"generated_at"`
+	Scan          Scan          `json:"scan"`
+	Nodes         []Node        `json:"nodes"`
+	Edges         []Edge        `json:"edges"`
+	Plugins       []PluginStats `json:"plugins"`
+	Stats         Stats         `json:"stats"`
+}
+
+// Scan describes the scan session the document was built from.
+type Scan struct {
+	ID          int64      `json:"id"`
+	Input       string     `json:"input"`
+	Status      string     `json:"status"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+	DurationMS  int64      `json:"duration_ms,omitempty"`
+	Errors      int        `json:"errors"`
+}
+
+// Node is one trace in the scan graph.
+type Node struct {
+	ID    int64              `json:"id"`
+	Value string             `json:"value"`
+	Type  entities.TraceType `json:"type"`
+	// Hop is the length of the shortest discovery chain from a seed; seeds
+	// are hop 0. It is -1 for a node no seed reaches.
+	Hop     int  `json:"hop"`
+	Seed    bool `json:"seed,omitempty"`
+	Guessed bool `json:"guessed,omitempty"`
+	// FirstSeen is when the trace was first stored by any scan.
+	FirstSeen time.Time `json:"first_seen"`
+	// DiscoveredBy lists the plugins with an edge into this node.
+	DiscoveredBy []string               `json:"discovered_by"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	// Verdict is the analyst's verdict on the trace, if any.
+	Verdict     string `json:"verdict,omitempty"`
+	VerdictNote string `json:"verdict_note,omitempty"`
+}
+
+// Edge is one discovery: Plugin, run on From, produced To. From is null
+// for a seed edge.
+type Edge struct {
+	From         *int64    `json:"from"`
+	To           int64     `json:"to"`
+	Plugin       string    `json:"plugin"`
+	Hop          int       `json:"hop"`
+	DiscoveredAt time.Time `json:"discovered_at"`
+	// Verdict is the analyst's verdict on this plugin's edges into To.
+	Verdict     string `json:"verdict,omitempty"`
+	VerdictNote string `json:"verdict_note,omitempty"`
+}
+
+// PluginStats summarizes one plugin's contribution to the scan.
+type PluginStats struct {
+	Name   string `json:"name"`
+	Edges  int    `json:"edges"`
+	Traces int    `json:"traces"`
+	// NewTraces counts traces this plugin was the first to reach.
+	NewTraces int       `json:"new_traces"`
+	FirstAt   time.Time `json:"first_at"`
+	LastAt    time.Time `json:"last_at"`
+}
+
+// Stats holds document-wide totals.
+type Stats struct {
+	Nodes  int                        `json:"nodes"`
+	Edges  int                        `json:"edges"`
+	ByType map[entities.TraceType]int `json:"by_type"`
+}
+
+// Load builds the document for a stored scan.
+func Load(repo *database.Repository, scanID int64) (*Document, error) {
+	session, err := repo.GetScanSession(scanID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, fmt.Errorf("scan %d not found", scanID)
+	}
+	nodes, edges, err := repo.GetScanGraph(scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load scan graph: %w", err)
+	}
+	verdicts, err := repo.GetVerdicts()
+	if err != nil {
+		return nil, err
+	}
+	doc := Build(*session, nodes, edges)
+	doc.ApplyVerdicts(database.NewVerdictIndex(verdicts))
+	return doc, nil
+}
+
+// ApplyVerdicts marks nodes and edges with the analysts' verdicts on them.
+func (d *Document) ApplyVerdicts(verdicts *database.VerdictIndex) {
+	for i, n := range d.Nodes {
+		if v, ok := verdicts.Trace(n.ID); ok {
+			d.Nodes[i].Verdict = v.Verdict
+			d.Nodes[i].VerdictNote = v.Note
+		}
+	}
+	for i, e := range d.Edges {
+		if v, ok := verdicts.Edge(e.To, e.Plugin); ok {
+			d.Edges[i].Verdict = v.Verdict
+			d.Edges[i].VerdictNote = v.Note
+		}
+	}
+}
+
+// Build assembles a document from a session and its stored graph. Nodes
+// are ordered by hop, then type and value; edges by hop and discovery time,
+// so documents for the same scan diff cleanly.
+func Build(session database.ScanSession, nodes []database.Trace, edges []database.TraceEdge) *Document {
+	hops := graphnav.Hops(edges)
+
+	doc := &Document{
+		Schema:        SchemaName,
+		SchemaVersion: SchemaVersion,
+		GeneratedAt:   time.Now().UTC(),
+		Scan: Scan{
+			ID:          session.ID,
+			Input:       session.Input,
+			Status:      session.Status,
+			StartedAt:   session.StartedAt,
+			CompletedAt: session.CompletedAt,
+			Errors:      session.Errors,
+		},
+		Nodes:   make([]Node, 0, len(nodes)),
+		Edges:   make([]Edge, 0, len(edges)),
+		Plugins: []PluginStats{},
+		Stats:   Stats{ByType: make(map[entities.TraceType]int)},
+	}
+	if session.CompletedAt != nil {
+		doc.Scan.DurationMS = session.CompletedAt.Sub(session.StartedAt).Milliseconds()
+	}
+
+	sortedEdges := append([]database.TraceEdge(nil), edges...)
+	sort.SliceStable(sortedEdges, func(i, j int) bool {
+		return sortedEdges[i].DiscoveredAt.Before(sortedEdges[j].DiscoveredAt)
+	})
+
+	discoveredBy := make(map[int64][]string)
+	seeds := make(map[int64]bool)
+	guessed := make(map[int64]bool)
+	plugins := make(map[string]*PluginStats)
+	pluginTraces := make(map[string]map[int64]bool)
+	reached := make(map[int64]bool)
+
+	for _, e := range sortedEdges {
+		edge := Edge{To: e.ChildTraceID, Plugin: e.PluginName, DiscoveredAt: e.DiscoveredAt, Hop: hopOf(hops, e.ChildTraceID)}
+		if e.ParentTraceID != nil {
+			parent := *e.ParentTraceID
+			edge.From = &parent
+			if h, ok := hops[parent]; ok {
+				edge.Hop = h + 1
+			}
+		} else {
+			edge.Hop = 0
+			seeds[e.ChildTraceID] = true
+			if e.PluginName == database.SeedGuessPluginName {
+				guessed[e.ChildTraceID] = true
+			}
+		}
+		doc.Edges = append(doc.Edges, edge)
+
+		if database.IsSeedPlugin(e.PluginName) {
+			reached[e.ChildTraceID] = true
+			continue
+		}
+		discoveredBy[e.ChildTraceID] = appendUnique(discoveredBy[e.ChildTraceID], e.PluginName)
+
+		stats, ok := plugins[e.PluginName]
+		if !ok {
+			stats = &PluginStats{Name: e.PluginName, FirstAt: e.DiscoveredAt}
+			plugins[e.PluginName] = stats
+			pluginTraces[e.PluginName] = make(map[int64]bool)
+		}
+		stats.Edges++
+		stats.LastAt = e.DiscoveredAt
+		if !pluginTraces[e.PluginName][e.ChildTraceID] {
+			pluginTraces[e.PluginName][e.ChildTraceID] = true
+			stats.Traces++
+		}
+		if !reached[e.ChildTraceID] {
+			reached[e.ChildTraceID] = true
+			stats.NewTraces++
+		}
+	}
+	sort.SliceStable(doc.Edges, func(i, j int) bool {
+		return doc.Edges[i].Hop < doc.Edges[j].Hop
+	})
+
+	for _, n := range nodes {
+		by := discoveredBy[n.ID]
+		if by == nil {
+			by = []string{}
+		}
+		sort.Strings(by)
+		doc.Nodes = append(doc.Nodes, Node{
+			ID:           n.ID,
+			Value:        n.Value,
+			Type:         n.Type,
+			Hop:          hopOf(hops, n.ID),
+			Seed:         seeds[n.ID],
+			Guessed:      guessed[n.ID],
+			FirstSeen:    n.DiscoveredAt,
+			DiscoveredBy: by,
+			Metadata:     n.Metadata,
+		})
+		doc.Stats.ByType[n.Type]++
+	}
+	sort.SliceStable(doc.Nodes, func(i, j int) bool {
+		a, b := doc.Nodes[i], doc.Nodes[j]
+		if a.Hop != b.Hop {
+			// Unreached nodes (-1) sort last.
+			return a.Hop >= 0 && (b.Hop < 0 || a.Hop < b.Hop)
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Value < b.Value
+	})
+
+	for _, stats := range plugins {
+		doc.Plugins = append(doc.Plugins, *stats)
+	}
+	sort.Slice(doc.Plugins, func(i, j int) bool {
+		if doc.Plugins[i].Edges != doc.Plugins[j].Edges {
+			return doc.Plugins[i].Edges > doc.Plugins[j].Edges
+		}
+		return doc.Plugins[i].Name < doc.Plugins[j].Name
+	})
+
+	doc.Stats.Nodes = len(doc.Nodes)
+	doc.Stats.Edges = len(doc.Edges)
+	return doc
+}
+
+// FilterTypes keeps only nodes of the given types, and the edges between
+// kept nodes (seed edges into kept nodes included). Stats are recomputed;
+// plugin stats are left as they describe the whole scan.
+func (d *Document) FilterTypes(types []string) *Document {
+	if len(types) == 0 {
+		return d
+	}
+	keepType := make(map[entities.TraceType]bool, len(types))
+	for _, t := range types {
+		keepType[entities.TraceType(t)] = true
+	}
+
+	filtered := *d
+	filtered.Nodes = make([]Node, 0, len(d.Nodes))
+	filtered.Stats = Stats{ByType: make(map[entities.TraceType]int)}
+	kept := make(map[int64]bool)
+	for _, n := range d.Nodes {
+		if keepType[n.Type] {
+			filtered.Nodes = append(filtered.Nodes, n)
+			filtered.Stats.ByType[n.Type]++
+			kept[n.ID] = true
+		}
+	}
+
+	filtered.Edges = make([]Edge, 0, len(d.Edges))
+	for _, e := range d.Edges {
+		if kept[e.To] && (e.From == nil || kept[*e.From]) {
+			filtered.Edges = append(filtered.Edges, e)
+		}
+	}
+
+	filtered.Stats.Nodes = len(filtered.Nodes)
+	filtered.Stats.Edges = len(filtered.Edges)
+	return &filtered
+}
+
+func hopOf(hops map[int64]int, id int64) int {
+	if h, ok := hops[id]; ok {
+		return h
+	}
+	return -1
+}
+
+func appendUnique(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
@@ -0,0 +1,164 @@
+package results
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func testGraph() (database.ScanSession, []database.Trace, []database.TraceEdge) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	done := start.Add(90 * time.Second)
+	session := database.ScanSession{ID: 7, Input: `jdoe "quoted"`, Status: "completed", StartedAt: start, CompletedAt: &done}
+
+	nodes := []database.Trace{
+		{ID: 1, Value: `jdoe "quoted"`, Type: entities.Username, DiscoveredAt: start},
+		{ID: 2, Value: `back\slash@example.com`, Type: entities.Email, DiscoveredAt: start},
+		{ID: 3, Value: "example.com", Type: entities.Domain, DiscoveredAt: start},
+	}
+	one, two := int64(1), int64(2)
+	edges := []database.TraceEdge{
+		{ParentTraceID: nil, ChildTraceID: 1, PluginName: database.SeedPluginName, DiscoveredAt: start},
+		{ParentTraceID: &one, ChildTraceID: 2, PluginName: "GitHubProfilePlugin", DiscoveredAt: start.Add(time.Second)},
+		{ParentTraceID: &two, ChildTraceID: 3, PluginName: "EmailDomainPlugin", DiscoveredAt: start.Add(2 * time.Second)},
+		{ParentTraceID: &one, ChildTraceID: 3, PluginName: "GitHubProfilePlugin", DiscoveredAt: start.Add(3 * time.Second)},
+	}
+	return session, nodes, edges
+}
+
+func TestBuild_ProvenanceAndHops(t *testing.T) {
+	doc := Build(testGraph())
+
+	assert.Equal(t, SchemaName, doc.Schema)
+	assert.Equal(t, SchemaVersion, doc.SchemaVersion)
+	assert.Equal(t, int64(90000), doc.Scan.DurationMS)
+
+	require.Len(t, doc.Nodes, 3)
+	assert.Equal(t, int64(1), doc.Nodes[0].ID)
+	assert.True(t, doc.Nodes[0].Seed)
+	assert.Equal(t, 0, doc.Nodes[0].Hop)
+
+	byID := make(map[int64]Node)
+	for _, n := range doc.Nodes {
+		byID[n.ID] = n
+	}
+	assert.Equal(t, 1, byID[2].Hop)
+	assert.Equal(t, 1, byID[3].Hop, "shortest chain wins")
+	assert.Equal(t, []string{"EmailDomainPlugin", "GitHubProfilePlugin"}, byID[3].DiscoveredBy)
+
+	require.Len(t, doc.Edges, 4)
+	assert.Nil(t, doc.Edges[0].From)
+	assert.Equal(t, 2, doc.Edges[len(doc.Edges)-1].Hop, "edge from hop-1 node is hop 2")
+
+	require.Len(t, doc.Plugins, 2)
+	gh := doc.Plugins[0]
+	assert.Equal(t, "GitHubProfilePlugin", gh.Name)
+	assert.Equal(t, 2, gh.Edges)
+	assert.Equal(t, 2, gh.Traces)
+	assert.Equal(t, 1, gh.NewTraces, "example.com was reached first via the email")
+	assert.Equal(t, 1, doc.Plugins[1].NewTraces)
+
+	assert.Equal(t, Stats{Nodes: 3, Edges: 4, ByType: map[entities.TraceType]int{
+		entities.Username: 1, entities.Email: 1, entities.Domain: 1,
+	}}, doc.Stats)
+}
+
+func TestWriteJSON_EscapesValuesAndRoundTrips(t *testing.T) {
+	doc := Build(testGraph())
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteJSON(&buf, doc))
+	require.True(t, json.Valid(buf.Bytes()))
+
+	var got Document
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Equal(t, `jdoe "quoted"`, got.Scan.Input)
+	assert.Equal(t, doc.Nodes[1].Value, got.Nodes[1].Value)
+	assert.Len(t, got.Edges, 4)
+}
+
+func TestWriteCSV_QuotesValues(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(&buf, Build(testGraph())))
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 4)
+	assert.Equal(t, []string{"value", "type", "hop", "seed", "discovered_by", "first_seen", "verdict"}, records[0])
+	assert.Equal(t, `jdoe "quoted"`, records[1][0])
+}
+
+func TestFilterTypes_DropsEdgesToRemovedNodes(t *testing.T) {
+	doc := Build(testGraph()).FilterTypes([]string{"username", "domain"})
+
+	require.Len(t, doc.Nodes, 2)
+	assert.Equal(t, 2, doc.Stats.Nodes)
+	for _, e := range doc.Edges {
+		assert.NotEqual(t, int64(2), e.To)
+		if e.From != nil {
+			assert.NotEqual(t, int64(2), *e.From)
+		}
+	}
+	assert.Len(t, doc.Edges, 2, "seed edge and username -> domain")
+}
+
+func TestFormatFromPath(t *testing.T) {
+	format, err := FormatFromPath("out/Results.JSON")
+	require.NoError(t, err)
+	assert.Equal(t, FormatJSON, format)
+
+	format, err = FormatFromPath("results.csv")
+	require.NoError(t, err)
+	assert.Equal(t, FormatCSV, format)
+
+	_, err = FormatFromPath("results.txt")
+	assert.Error(t, err)
+}
+
+func TestSaveFile_WritesByExtension(t *testing.T) {
+	dir := t.TempDir()
+	doc := Build(testGraph())
+
+	path := filepath.Join(dir, "scan.json")
+	require.NoError(t, SaveFile(doc, path))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.True(t, json.Valid(data))
+
+	require.Error(t, SaveFile(doc, filepath.Join(dir, "scan.xyz")))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temp files left behind")
+}
+
+func TestLoad_FromRepository(t *testing.T) {
+	db, err := database.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	repo := database.NewRepository(db)
+
+	session, err := repo.CreateScanSession("root.com")
+	require.NoError(t, err)
+	require.NoError(t, repo.PersistDiscoveries(session.ID, []entities.Discovery{
+		{Parent: entities.Trace{Value: "root.com", Type: entities.Domain}, PluginName: "p1", Child: entities.Trace{Value: "www.root.com", Type: entities.Subdomain}},
+	}))
+
+	doc, err := Load(repo, session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, session.ID, doc.Scan.ID)
+	assert.Len(t, doc.Nodes, 2)
+	assert.Len(t, doc.Edges, 1)
+
+	_, err = Load(repo, 9999)
+	assert.Error(t, err)
+}
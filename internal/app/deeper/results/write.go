@@ -0,0 +1,117 @@
+package results
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Format is a serialization of a Document.
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+)
+
+// extensionFormats maps --save file extensions to formats.
+var extensionFormats = map[string]Format{
+	".json": FormatJSON,
+	".csv":  FormatCSV,
+}
+
+// FormatFromPath picks the format for a file from its extension.
+func FormatFromPath(path string) (Format, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if format, ok := extensionFormats[ext]; ok {
+		return format, nil
+	}
+	return "", fmt.Errorf("cannot infer output format from %q: use a .json or .csv extension", path)
+}
+
+// Write serializes doc to w in the given format.
+func Write(w io.Writer, doc *Document, format Format) error {
+	switch format {
+	case FormatJSON:
+		return WriteJSON(w, doc)
+	case FormatCSV:
+		return WriteCSV(w, doc)
+	default:
+		return fmt.Errorf("unsupported results format: %s", format)
+	}
+}
+
+// WriteJSON writes doc as indented JSON.
+func WriteJSON(w io.Writer, doc *Document) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return fmt.Errorf("failed to encode results: %w", err)
+	}
+	return nil
+}
+
+// WriteCSV writes one row per node. CSV has no room for edges, so each row
+// carries the node's hop and the plugins that discovered it instead; use
+// JSON for the full graph. The first two columns match the historical
+// value,type output.
+func WriteCSV(w io.Writer, doc *Document) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"value", "type", "hop", "seed", "discovered_by", "first_seen", "verdict"}); err != nil {
+		return fmt.Errorf("failed to write csv header: %w", err)
+	}
+	for _, n := range doc.Nodes {
+		if err := writer.Write([]string{
+			n.Value,
+			string(n.Type),
+			strconv.Itoa(n.Hop),
+			strconv.FormatBool(n.Seed),
+			strings.Join(n.DiscoveredBy, ";"),
+			n.FirstSeen.UTC().Format("2006-01-02T15:04:05Z"),
+			n.Verdict,
+		}); err != nil {
+			return fmt.Errorf("failed to write csv row: %w", err)
+		}
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write csv: %w", err)
+	}
+	return nil
+}
+
+// SaveFile writes doc to path in the format its extension names. The file
+// is written to a temporary sibling and renamed into place, so a failed
+// write never leaves a truncated result behind.
+func SaveFile(doc *Document, path string) error {
+	format, err := FormatFromPath(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if err := Write(tmp, doc, format); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
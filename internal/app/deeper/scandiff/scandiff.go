@@ -0,0 +1,263 @@
+// Package scandiff compares two scans, typically of the same subject at
+// different times: which traces and edges appeared, which disappeared and
+// how much stayed the same.
+package scandiff
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// Scan identifies one side of a diff.
+type Scan struct {
+	ID        int64     `json:"id"`
+	Input     string    `json:"input"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// Trace is a trace that appeared or disappeared.
+type Trace struct {
+	ID    int64              `json:"id"`
+	Value string             `json:"value"`
+	Type  entities.TraceType `json:"type"`
+}
+
+// Edge is a discovery that appeared or disappeared: Plugin, run on From,
+// produced To.
+type Edge struct {
+	From   Trace  `json:"from"`
+	To     Trace  `json:"to"`
+	Plugin string `json:"plugin"`
+}
+
+// TypeChange holds the traces of one type that changed.
+type TypeChange struct {
+	Type    entities.TraceType `json:"type"`
+	Added   []Trace            `json:"added"`
+	Removed []Trace            `json:"removed"`
+}
+
+// PluginChange holds the edges of one plugin that changed.
+type PluginChange struct {
+	Plugin  string `json:"plugin"`
+	Added   []Edge `json:"added"`
+	Removed []Edge `json:"removed"`
+}
+
+// Summary counts the changes.
+type Summary struct {
+	AddedTraces     int `json:"added_traces"`
+	RemovedTraces   int `json:"removed_traces"`
+	UnchangedTraces int `json:"unchanged_traces"`
+	AddedEdges      int `json:"added_edges"`
+	RemovedEdges    int `json:"removed_edges"`
+	UnchangedEdges  int `json:"unchanged_edges"`
+}
+
+// Diff is what changed from Old to New, traces grouped by type and edges
+// by plugin.
+type Diff struct {
+	Old     Scan           `json:"old"`
+	New     Scan           `json:"new"`
+	Summary Summary        `json:"summary"`
+	Types   []TypeChange   `json:"types"`
+	Plugins []PluginChange `json:"plugins"`
+}
+
+// Graph is one stored scan with its graph, as Repository.GetScanGraph
+// returns it.
+type Graph struct {
+	Session database.ScanSession
+	Nodes   []database.Trace
+	Edges   []database.TraceEdge
+}
+
+// Load reads two stored scans and compares them.
+func Load(repo *database.Repository, oldID, newID int64) (*Diff, error) {
+	graphs := make([]Graph, 2)
+	for i, id := range []int64{oldID, newID} {
+		session, err := repo.GetScanSession(id)
+		if err != nil {
+			return nil, err
+		}
+		if session == nil {
+			return nil, fmt.Errorf("scan %d not found", id)
+		}
+		nodes, edges, err := repo.GetScanGraph(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load scan graph: %w", err)
+		}
+		graphs[i] = Graph{Session: *session, Nodes: nodes, Edges: edges}
+	}
+	return Compare(graphs[0], graphs[1]), nil
+}
+
+type edgeKey struct {
+	from, to int64
+	plugin   string
+}
+
+// Compare diffs two scan graphs. Traces are the same across scans when
+// they share an ID, which Repository guarantees for equal (value, type).
+// Seed edges are not discoveries and are left out of the edge diff; a seed
+// that changed shows up as a changed trace.
+func Compare(before, after Graph) *Diff {
+	d := &Diff{
+		Old:     scanOf(before.Session),
+		New:     scanOf(after.Session),
+		Types:   []TypeChange{},
+		Plugins: []PluginChange{},
+	}
+
+	oldNodes := indexNodes(before.Nodes)
+	newNodes := indexNodes(after.Nodes)
+
+	types := make(map[entities.TraceType]*TypeChange)
+	typeChange := func(t entities.TraceType) *TypeChange {
+		if types[t] == nil {
+			types[t] = &TypeChange{Type: t, Added: []Trace{}, Removed: []Trace{}}
+		}
+		return types[t]
+	}
+	for id, n := range newNodes {
+		if _, ok := oldNodes[id]; ok {
+			d.Summary.UnchangedTraces++
+			continue
+		}
+		change := typeChange(n.Type)
+		change.Added = append(change.Added, traceOf(n))
+		d.Summary.AddedTraces++
+	}
+	for id, n := range oldNodes {
+		if _, ok := newNodes[id]; !ok {
+			change := typeChange(n.Type)
+			change.Removed = append(change.Removed, traceOf(n))
+			d.Summary.RemovedTraces++
+		}
+	}
+
+	oldEdges := indexEdges(before.Edges)
+	newEdges := indexEdges(after.Edges)
+	plugins := make(map[string]*PluginChange)
+	pluginChange := func(name string) *PluginChange {
+		if plugins[name] == nil {
+			plugins[name] = &PluginChange{Plugin: name, Added: []Edge{}, Removed: []Edge{}}
+		}
+		return plugins[name]
+	}
+	for key := range newEdges {
+		if oldEdges[key] {
+			d.Summary.UnchangedEdges++
+			continue
+		}
+		change := pluginChange(key.plugin)
+		change.Added = append(change.Added, Edge{From: traceOf(newNodes[key.from]), To: traceOf(newNodes[key.to]), Plugin: key.plugin})
+		d.Summary.AddedEdges++
+	}
+	for key := range oldEdges {
+		if !newEdges[key] {
+			change := pluginChange(key.plugin)
+			change.Removed = append(change.Removed, Edge{From: traceOf(oldNodes[key.from]), To: traceOf(oldNodes[key.to]), Plugin: key.plugin})
+			d.Summary.RemovedEdges++
+		}
+	}
+
+	for _, change := range types {
+		sortTraces(change.Added)
+		sortTraces(change.Removed)
+		d.Types = append(d.Types, *change)
+	}
+	sort.Slice(d.Types, func(i, j int) bool { return d.Types[i].Type < d.Types[j].Type })
+	for _, change := range plugins {
+		sortEdges(change.Added)
+		sortEdges(change.Removed)
+		d.Plugins = append(d.Plugins, *change)
+	}
+	sort.Slice(d.Plugins, func(i, j int) bool { return d.Plugins[i].Plugin < d.Plugins[j].Plugin })
+	return d
+}
+
+// Changed reports whether anything appeared or disappeared.
+func (d *Diff) Changed() bool {
+	s := d.Summary
+	return s.AddedTraces+s.RemovedTraces+s.AddedEdges+s.RemovedEdges > 0
+}
+
+// AddedTraces returns every added trace, ordered by type and value.
+func (d *Diff) AddedTraces() []Trace {
+	var out []Trace
+	for _, change := range d.Types {
+		out = append(out, change.Added...)
+	}
+	return out
+}
+
+// RemovedTraces returns every removed trace, ordered by type and value.
+func (d *Diff) RemovedTraces() []Trace {
+	var out []Trace
+	for _, change := range d.Types {
+		out = append(out, change.Removed...)
+	}
+	return out
+}
+
+// AddedEdges returns every added edge, ordered by plugin.
+func (d *Diff) AddedEdges() []Edge {
+	var out []Edge
+	for _, change := range d.Plugins {
+		out = append(out, change.Added...)
+	}
+	return out
+}
+
+// RemovedEdges returns every removed edge, ordered by plugin.
+func (d *Diff) RemovedEdges() []Edge {
+	var out []Edge
+	for _, change := range d.Plugins {
+		out = append(out, change.Removed...)
+	}
+	return out
+}
+
+func scanOf(s database.ScanSession) Scan {
+	return Scan{ID: s.ID, Input: s.Input, StartedAt: s.StartedAt}
+}
+
+func traceOf(t database.Trace) Trace {
+	return Trace{ID: t.ID, Value: t.Value, Type: t.Type}
+}
+
+func indexNodes(nodes []database.Trace) map[int64]database.Trace {
+	out := make(map[int64]database.Trace, len(nodes))
+	for _, n := range nodes {
+		out[n.ID] = n
+	}
+	return out
+}
+
+func indexEdges(edges []database.TraceEdge) map[edgeKey]bool {
+	out := make(map[edgeKey]bool, len(edges))
+	for _, e := range edges {
+		if e.ParentTraceID != nil {
+			out[edgeKey{*e.ParentTraceID, e.ChildTraceID, e.PluginName}] = true
+		}
+	}
+	return out
+}
+
+func sortTraces(traces []Trace) {
+	sort.Slice(traces, func(i, j int) bool { return traces[i].Value < traces[j].Value })
+}
+
+func sortEdges(edges []Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From.Value != edges[j].From.Value {
+			return edges[i].From.Value < edges[j].From.Value
+		}
+		return edges[i].To.Value < edges[j].To.Value
+	})
+}
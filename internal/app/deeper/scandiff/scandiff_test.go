@@ -0,0 +1,83 @@
+package scandiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func edge(parent, child int64, plugin string) database.TraceEdge {
+	e := database.TraceEdge{ChildTraceID: child, PluginName: plugin}
+	if parent != 0 {
+		e.ParentTraceID = &parent
+	}
+	return e
+}
+
+func TestCompare_GroupsChangesByTypeAndPlugin(t *testing.T) {
+	user := database.Trace{ID: 1, Value: "jdoe", Type: entities.Username}
+	oldEmail := database.Trace{ID: 2, Value: "jdoe@old.example", Type: entities.Email}
+	site := database.Trace{ID: 3, Value: "jdoe.dev", Type: entities.Domain}
+	newEmail := database.Trace{ID: 4, Value: "jdoe@new.example", Type: entities.Email}
+	ip := database.Trace{ID: 5, Value: "203.0.113.7", Type: entities.IpAddr}
+
+	before := Graph{
+		Session: database.ScanSession{ID: 41, Input: "jdoe"},
+		Nodes:   []database.Trace{user, oldEmail, site},
+		Edges: []database.TraceEdge{
+			edge(0, 1, database.SeedPluginName),
+			edge(1, 2, "GitHubProfilePlugin"),
+			edge(1, 3, "GitHubProfilePlugin"),
+		},
+	}
+	after := Graph{
+		Session: database.ScanSession{ID: 42, Input: "jdoe"},
+		Nodes:   []database.Trace{user, site, newEmail, ip},
+		Edges: []database.TraceEdge{
+			edge(0, 1, database.SeedPluginName),
+			edge(1, 3, "GitHubProfilePlugin"),
+			edge(1, 4, "GitHubProfilePlugin"),
+			edge(3, 5, "DNSResolverPlugin"),
+		},
+	}
+
+	d := Compare(before, after)
+
+	assert.True(t, d.Changed())
+	assert.Equal(t, Summary{
+		AddedTraces: 2, RemovedTraces: 1, UnchangedTraces: 2,
+		AddedEdges: 2, RemovedEdges: 1, UnchangedEdges: 1,
+	}, d.Summary, "the seed edge is not counted")
+
+	require.Len(t, d.Types, 2)
+	assert.Equal(t, entities.Email, d.Types[0].Type)
+	assert.Equal(t, []Trace{{ID: 4, Value: "jdoe@new.example", Type: entities.Email}}, d.Types[0].Added)
+	assert.Equal(t, []Trace{{ID: 2, Value: "jdoe@old.example", Type: entities.Email}}, d.Types[0].Removed)
+	assert.Equal(t, entities.IpAddr, d.Types[1].Type)
+	assert.Empty(t, d.Types[1].Removed)
+
+	require.Len(t, d.Plugins, 2)
+	assert.Equal(t, "DNSResolverPlugin", d.Plugins[0].Plugin)
+	assert.Equal(t, "jdoe.dev", d.Plugins[0].Added[0].From.Value)
+	assert.Equal(t, "GitHubProfilePlugin", d.Plugins[1].Plugin)
+	assert.Len(t, d.Plugins[1].Added, 1)
+	assert.Equal(t, "jdoe@old.example", d.Plugins[1].Removed[0].To.Value)
+
+	assert.Len(t, d.AddedTraces(), 2)
+	assert.Len(t, d.RemovedEdges(), 1)
+}
+
+func TestCompare_Unchanged(t *testing.T) {
+	g := Graph{
+		Nodes: []database.Trace{{ID: 1, Value: "jdoe", Type: entities.Username}},
+		Edges: []database.TraceEdge{edge(0, 1, database.SeedPluginName)},
+	}
+	d := Compare(g, g)
+	assert.False(t, d.Changed())
+	assert.NotNil(t, d.Types, "encodes as an empty list")
+	assert.Equal(t, 1, d.Summary.UnchangedTraces)
+}
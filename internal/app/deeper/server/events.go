@@ -0,0 +1,129 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
+)
+
+// sseBacklog is how many events a stream may fall behind by before it is
+// closed. The bus blocks publishers on slow subscribers, so a stalled
+// client must never hold up the scans.
+const sseBacklog = 1024
+
+// sseKeepAlive is how often an idle stream sends a comment to keep
+// proxies from timing it out.
+const sseKeepAlive = 15 * time.Second
+
+// streamEvents streams a scan's events as Server-Sent Events, each with
+// the event type as its name and the event JSON as its data, from the
+// moment the client connects. A final "end" event carries the session
+// once the scan is over; for a scan that is already over it is the only
+// event.
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	// Subscribe before looking for the job, so no event falls in between.
+	ch, unsubscribe := s.bus.Subscribe(64)
+	var once sync.Once
+	stop := func() { once.Do(unsubscribe) }
+	defer stop()
+
+	queue := make(chan events.Event, sseBacklog)
+	overflow := make(chan struct{})
+	go func() {
+		defer close(queue)
+		for ev := range ch {
+			if ev.ScanID != session.ID {
+				continue
+			}
+			select {
+			case queue <- ev:
+			default:
+				close(overflow)
+				for range ch {
+				}
+				return
+			}
+		}
+	}()
+
+	s.mu.Lock()
+	j := s.jobs[session.ID]
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	var done <-chan struct{}
+	if j != nil {
+		done = j.done
+	} else {
+		done = closedChan
+	}
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+
+	pending := queue
+	for {
+		select {
+		case ev, ok := <-pending:
+			if !ok {
+				// The relay gave up; overflow says why.
+				pending = nil
+				continue
+			}
+			writeSSE(w, string(ev.Type), ev)
+		case <-done:
+			// Everything the scan published is in the subscription by
+			// now; unsubscribing lets the relay drain it and finish.
+			stop()
+			for ev := range queue {
+				writeSSE(w, string(ev.Type), ev)
+			}
+			if final, err := s.repo.GetScanSession(session.ID); err == nil && final != nil {
+				writeSSE(w, "end", s.withState(*final))
+			}
+			flusher.Flush()
+			return
+		case <-overflow:
+			writeSSE(w, "error", map[string]string{"error": "stream fell too far behind and was closed"})
+			flusher.Flush()
+			return
+		case <-keepAlive.C:
+			_, _ = fmt.Fprint(w, ": keep-alive\n\n")
+		case <-r.Context().Done():
+			return
+		}
+		flusher.Flush()
+	}
+}
+
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+func writeSSE(w http.ResponseWriter, name string, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/export"
+	"github.com/smirnoffmg/deeper/internal/app/deeper/graphnav"
+	"github.com/smirnoffmg/deeper/internal/app/deeper/results"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+)
+
+// exportContentTypes are the media types of the export formats.
+var exportContentTypes = map[export.Format]string{
+	export.FormatGraphML: "application/graphml+xml",
+	export.FormatGEXF:    "application/gexf+xml",
+	export.FormatDOT:     "text/vnd.graphviz",
+	export.FormatCypher:  "text/plain; charset=utf-8",
+	export.FormatSTIX:    "application/stix+json;version=2.1",
+	export.FormatMaltego: "application/zip",
+}
+
+func (s *Server) getResults(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	doc, err := results.Load(s.repo, session.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, doc)
+}
+
+func (s *Server) getGraph(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	nodes, edges, err := s.repo.GetScanGraph(session.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to load scan graph: %w", err))
+		return
+	}
+	if nodes == nil {
+		nodes = []database.Trace{}
+	}
+	if edges == nil {
+		edges = []database.TraceEdge{}
+	}
+	writeJSON(w, http.StatusOK, struct {
+		ScanID int64                `json:"scan_id"`
+		Nodes  []database.Trace     `json:"nodes"`
+		Edges  []database.TraceEdge `json:"edges"`
+	}{session.ID, nodes, edges})
+}
+
+// traceJSON is a trace as the path and reachable routes describe it.
+type traceJSON struct {
+	TraceID int64  `json:"trace_id"`
+	Value   string `json:"value"`
+	Type    string `json:"type"`
+	Hop     int    `json:"hop"`
+}
+
+// withTrace loads the scan's graph and resolves the trace query parameter,
+// a value, "type:value" or "#id" as "deeper graph" takes it.
+func (s *Server) withTrace(w http.ResponseWriter, r *http.Request, fn func(*database.ScanSession, *graphnav.Graph, database.Trace)) {
+	session, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	ref := r.URL.Query().Get("trace")
+	if ref == "" {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("the trace parameter is required"))
+		return
+	}
+	nodes, edges, err := s.repo.GetScanGraph(session.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to load scan graph: %w", err))
+		return
+	}
+	graph := graphnav.New(nodes, edges)
+	trace, err := graph.Find(ref)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	fn(session, graph, trace)
+}
+
+func (s *Server) getPath(w http.ResponseWriter, r *http.Request) {
+	s.withTrace(w, r, func(session *database.ScanSession, graph *graphnav.Graph, t database.Trace) {
+		ancestry, err := s.repo.GetDiscoveryPath(session.ID, t.ID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		chain := graph.Chain(t.ID, ancestry)
+		writeJSON(w, http.StatusOK, struct {
+			ScanID  int64           `json:"scan_id"`
+			Trace   traceJSON       `json:"trace"`
+			Reached bool            `json:"reached_from_seed"`
+			Path    []graphnav.Step `json:"path"`
+		}{
+			session.ID,
+			traceJSON{t.ID, t.Value, string(t.Type), graph.Hop(t.ID)},
+			len(chain) > 0 && graph.IsSeed(chain[0].TraceID),
+			chain,
+		})
+	})
+}
+
+func (s *Server) getReachable(w http.ResponseWriter, r *http.Request) {
+	hops := 3
+	if v := r.URL.Query().Get("hops"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid hops %q", v))
+			return
+		}
+		hops = n
+	}
+
+	s.withTrace(w, r, func(session *database.ScanSession, graph *graphnav.Graph, t database.Trace) {
+		reachable, err := s.repo.GetReachableTraces(session.ID, t.ID, hops)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+		// The start trace itself is hop 0.
+		found := make([]database.ReachableTrace, 0, len(reachable))
+		for _, rt := range reachable {
+			if rt.TraceID != t.ID {
+				found = append(found, rt)
+			}
+		}
+		writeJSON(w, http.StatusOK, struct {
+			ScanID  int64                     `json:"scan_id"`
+			From    traceJSON                 `json:"from"`
+			MaxHops int                       `json:"max_hops"`
+			Traces  []database.ReachableTrace `json:"traces"`
+		}{session.ID, traceJSON{t.ID, t.Value, string(t.Type), graph.Hop(t.ID)}, hops, found})
+	})
+}
+
+func (s *Server) getExport(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	params := r.URL.Query()
+	format := export.Format(params.Get("format"))
+	if format == "" {
+		format = export.FormatGraphML
+	}
+	opts := export.Options{Author: params.Get("author"), TLP: params.Get("tlp")}
+	if opts.TLP == "" {
+		opts.TLP = export.DefaultTLP
+	}
+
+	graph, err := export.Load(s.repo, []int64{session.ID})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	// Render first so a bad format is still a clean error response.
+	var buf bytes.Buffer
+	if err := export.Write(&buf, graph, format, opts); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Content-Type", exportContentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="scan-%d.%s"`, session.ID, exportExtension(format)))
+	_, _ = w.Write(buf.Bytes())
+}
+
+func exportExtension(format export.Format) string {
+	switch format {
+	case export.FormatSTIX:
+		return "json"
+	case export.FormatMaltego:
+		return "mtgx"
+	default:
+		return string(format)
+	}
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+)
+
+// verdictRequest is the body of PUT /api/v1/verdicts.
+type verdictRequest struct {
+	TraceID int64 `json:"trace_id"`
+	// PluginName judges only this plugin's edges into the trace.
+	PluginName string `json:"plugin_name,omitempty"`
+	Verdict    string `json:"verdict"`
+	Note       string `json:"note,omitempty"`
+}
+
+func (s *Server) listVerdicts(w http.ResponseWriter, r *http.Request) {
+	verdicts, err := s.repo.GetVerdicts()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if verdicts == nil {
+		verdicts = []database.Verdict{}
+	}
+	writeJSON(w, http.StatusOK, map[string][]database.Verdict{"verdicts": verdicts})
+}
+
+// setVerdict records a verdict as "deeper verdict set" does, replacing any
+// earlier one on the same trace and plugin.
+func (s *Server) setVerdict(w http.ResponseWriter, r *http.Request) {
+	var req verdictRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid verdict request: %w", err))
+		return
+	}
+	verdict := strings.ReplaceAll(strings.ToLower(req.Verdict), "-", "_")
+	if !database.IsVerdict(verdict) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("unknown verdict %q (use confirmed, false_positive or irrelevant)", req.Verdict))
+		return
+	}
+	t, ok := s.verdictTrace(w, req.TraceID, req.PluginName)
+	if !ok {
+		return
+	}
+
+	v := &database.Verdict{TraceID: t.ID, PluginName: req.PluginName, Verdict: verdict, Note: req.Note}
+	if err := s.repo.SetVerdict(v); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	v.Value, v.Type = t.Value, t.Type
+	writeJSON(w, http.StatusOK, v)
+}
+
+// clearVerdict removes the verdict on the trace_id and, optionally,
+// plugin_name query parameters.
+func (s *Server) clearVerdict(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	id, err := strconv.ParseInt(params.Get("trace_id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid trace_id %q", params.Get("trace_id")))
+		return
+	}
+	pluginName := params.Get("plugin_name")
+	cleared, err := s.repo.ClearVerdict(id, pluginName)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if !cleared {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no verdict on trace #%d", id))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// verdictTrace loads the trace a verdict is about and, for a plugin
+// verdict, checks the plugin ever discovered it.
+func (s *Server) verdictTrace(w http.ResponseWriter, traceID int64, pluginName string) (*database.Trace, bool) {
+	t, err := s.repo.GetTrace(traceID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if t == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("trace #%d not found", traceID))
+		return nil, false
+	}
+	if pluginName != "" {
+		found, err := s.repo.HasEdgeFrom(t.ID, pluginName)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return nil, false
+		}
+		if !found {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("%s never discovered %s:%s", pluginName, t.Type, t.Value))
+			return nil, false
+		}
+	}
+	return t, true
+}
+
+func (s *Server) listCases(w http.ResponseWriter, r *http.Request) {
+	cases, err := s.repo.GetCases()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if cases == nil {
+		cases = []database.Case{}
+	}
+	writeJSON(w, http.StatusOK, map[string][]database.Case{"cases": cases})
+}
+
+// getCase returns a case by name or, failing that, by ID.
+func (s *Server) getCase(w http.ResponseWriter, r *http.Request) {
+	ref := r.PathValue("case")
+	c, err := s.repo.GetCaseByName(ref)
+	if err == nil && c == nil {
+		if id, parseErr := strconv.ParseInt(ref, 10, 64); parseErr == nil {
+			c, err = s.repo.GetCase(id)
+		}
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if c == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("case %q not found", ref))
+		return
+	}
+	writeJSON(w, http.StatusOK, c)
+}
@@ -0,0 +1,256 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/engine"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// StatusQueued is reported for a scan waiting for a free slot. It is never
+// stored: the session reads "running" until the scan ends.
+const StatusQueued = "queued"
+
+// job is a scan the server has started and not yet finished.
+type job struct {
+	cancel context.CancelFunc
+	// done is closed once the scan has ended and its session is updated.
+	done    chan struct{}
+	running bool
+}
+
+// scanRequest is the body of POST /api/v1/scans.
+type scanRequest struct {
+	Input string `json:"input"`
+	// Type scans the input as this trace type instead of guessing it.
+	Type string `json:"type,omitempty"`
+	engine.Options
+	// Timeout is the scan's deadline as a Go duration, capped at the
+	// server's scan timeout.
+	Timeout string `json:"timeout,omitempty"`
+	// Operator and Engagement record whose authority the scan runs under
+	// in its audit log, in place of the server's defaults.
+	Operator   string `json:"operator,omitempty"`
+	Engagement string `json:"engagement,omitempty"`
+}
+
+func (s *Server) listPlugins(w http.ResponseWriter, r *http.Request) {
+	names := make([]string, 0, len(s.cfg.Plugins))
+	for name := range s.cfg.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	writeJSON(w, http.StatusOK, map[string][]string{"plugins": names})
+}
+
+func (s *Server) startScan(w http.ResponseWriter, r *http.Request) {
+	var req scanRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid scan request: %w", err))
+		return
+	}
+
+	seeds, err := parseSeeds(req.Input, req.Type)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := req.Options.Validate(s.cfg.Plugins); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	timeout := s.cfg.ScanTimeout
+	if req.Timeout != "" {
+		requested, err := time.ParseDuration(req.Timeout)
+		if err != nil || requested <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid timeout %q", req.Timeout))
+			return
+		}
+		if timeout <= 0 || requested < timeout {
+			timeout = requested
+		}
+	}
+
+	if req.Operator == "" {
+		req.Operator = s.cfg.Operator
+	}
+	if req.Engagement == "" {
+		req.Engagement = s.cfg.Engagement
+	}
+	session, err := s.repo.CreateScanSessionFor(req.Input, req.Operator, req.Engagement)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to create scan session: %w", err))
+		return
+	}
+	// The scan owns session from here on.
+	created := *session
+	s.run(session, seeds, req.Options, timeout)
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/scans/%d", created.ID))
+	writeJSON(w, http.StatusAccepted, s.withState(created))
+}
+
+// parseSeeds resolves a scan's input into seed traces the way "deeper
+// scan" does: an explicit type wins over a "type:value" prefix and
+// shape-based guessing.
+func parseSeeds(input, traceType string) ([]entities.Seed, error) {
+	if input == "" {
+		return nil, fmt.Errorf("input is required")
+	}
+	if traceType != "" {
+		return entities.TypedSeeds(entities.TraceType(traceType), input)
+	}
+	return entities.ParseSeeds(input)
+}
+
+// run queues the scan and starts it once a slot is free.
+func (s *Server) run(session *database.ScanSession, seeds []entities.Seed, opts engine.Options, timeout time.Duration) {
+	ctx, cancel := context.WithCancel(s.ctx)
+	j := &job{cancel: cancel, done: make(chan struct{})}
+	s.mu.Lock()
+	s.jobs[session.ID] = j
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer func() {
+			cancel()
+			s.mu.Lock()
+			delete(s.jobs, session.ID)
+			s.mu.Unlock()
+			close(j.done)
+		}()
+
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			completedAt := time.Now()
+			session.CompletedAt = &completedAt
+			session.Status = "cancelled"
+			if err := s.repo.UpdateScanSession(session); err != nil {
+				log.Error().Err(err).Msgf("Failed to record cancelled scan %d", session.ID)
+			}
+			return
+		}
+		defer func() { <-s.slots }()
+
+		s.mu.Lock()
+		j.running = true
+		s.mu.Unlock()
+
+		if timeout > 0 {
+			var cancelTimeout context.CancelFunc
+			ctx, cancelTimeout = context.WithTimeout(ctx, timeout)
+			defer cancelTimeout()
+		}
+		log.Info().Msgf("API scan %d of %s started", session.ID, session.Input)
+		if err := s.scan(ctx, session, seeds, opts); err != nil {
+			log.Error().Err(err).Msgf("API scan %d failed", session.ID)
+			return
+		}
+		log.Info().Msgf("API scan %d %s", session.ID, session.Status)
+	}()
+}
+
+// withState reports a scan still waiting for a slot as queued.
+func (s *Server) withState(session database.ScanSession) database.ScanSession {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if j, ok := s.jobs[session.ID]; ok && !j.running {
+		session.Status = StatusQueued
+	}
+	return session
+}
+
+func (s *Server) listScans(w http.ResponseWriter, r *http.Request) {
+	query := database.ScanQuery{Limit: 50}
+	params := r.URL.Query()
+	if status := params.Get("status"); status != "" {
+		query.Status = &status
+	}
+	for name, dst := range map[string]*int{"limit": &query.Limit, "offset": &query.Offset} {
+		if v := params.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				writeError(w, http.StatusBadRequest, fmt.Errorf("invalid %s %q", name, v))
+				return
+			}
+			*dst = n
+		}
+	}
+
+	sessions, err := s.repo.GetScanSessions(query)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	scans := make([]database.ScanSession, 0, len(sessions))
+	for _, session := range sessions {
+		scans = append(scans, s.withState(session))
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"scans": scans})
+}
+
+func (s *Server) getScan(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.withState(*session))
+}
+
+func (s *Server) cancelScan(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	j, active := s.jobs[session.ID]
+	s.mu.Unlock()
+	if !active {
+		writeError(w, http.StatusConflict, fmt.Errorf("scan %d is not running", session.ID))
+		return
+	}
+
+	j.cancel()
+	select {
+	case <-j.done:
+	case <-r.Context().Done():
+		return
+	}
+	if session, ok = s.session(w, r); ok {
+		writeJSON(w, http.StatusOK, *session)
+	}
+}
+
+// session loads the scan named by the request's {id}, writing the error
+// response if there is none.
+func (s *Server) session(w http.ResponseWriter, r *http.Request) (*database.ScanSession, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid scan id %q", r.PathValue("id")))
+		return nil, false
+	}
+	session, err := s.repo.GetScanSession(id)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if session == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("scan %d not found", id))
+		return nil, false
+	}
+	return session, true
+}
@@ -0,0 +1,193 @@
+// Package server is deeper's HTTP API: it starts scans, streams their
+// events, and serves their sessions, graphs and exports to other tools,
+// along with the verdicts and cases an analyst works with.
+// Every route but the OpenAPI spec requires a bearer token.
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/engine"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
+)
+
+// OpenAPI is the API's OpenAPI 3 description, served at
+// /api/v1/openapi.json.
+//
+//go:embed openapi.json
+var OpenAPI []byte
+
+// ScanFunc runs a scan of seeds into session, narrowed by opts, and records
+// how it ended on the session. The server cancels ctx to stop the scan.
+type ScanFunc func(ctx context.Context, session *database.ScanSession, seeds []entities.Seed, opts engine.Options) error
+
+// Config configures a Server.
+type Config struct {
+	// Tokens are the bearer tokens the API accepts. A server without
+	// tokens rejects every authenticated request.
+	Tokens []string
+	// MaxScans is how many scans run at once; further scans queue. Scans
+	// share the engine's worker pool either way.
+	MaxScans int
+	// ScanTimeout is the longest a scan may run, and the deadline of
+	// scans that do not ask for a shorter one.
+	ScanTimeout time.Duration
+	// Plugins are the registered plugin names scans may be limited to.
+	Plugins map[string]bool
+	// Expand grows a finished scan one trace at a time. A server without
+	// it answers expand requests with 501 Not Implemented.
+	Expand ExpandFunc
+	// Operator and Engagement are whose authority scans run under when
+	// their request does not say.
+	Operator   string
+	Engagement string
+}
+
+// Server serves the API.
+type Server struct {
+	repo *database.Repository
+	bus  *events.Bus
+	scan ScanFunc
+	cfg  Config
+
+	slots  chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu   sync.Mutex
+	jobs map[int64]*job
+}
+
+// New creates a server that runs scans with scan and streams their events
+// from bus.
+func New(repo *database.Repository, bus *events.Bus, scan ScanFunc, cfg Config) *Server {
+	if cfg.MaxScans < 1 {
+		cfg.MaxScans = 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		repo:   repo,
+		bus:    bus,
+		scan:   scan,
+		cfg:    cfg,
+		slots:  make(chan struct{}, cfg.MaxScans),
+		ctx:    ctx,
+		cancel: cancel,
+		jobs:   make(map[int64]*job),
+	}
+}
+
+// Close cancels every queued and running scan and waits for them to wind
+// down and record how far they got.
+func (s *Server) Close() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Handler returns the API's routes.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, rt := range s.routes() {
+		handler := rt.handler
+		if rt.public {
+			mux.HandleFunc(rt.method+" "+rt.path, handler)
+			continue
+		}
+		mux.HandleFunc(rt.method+" "+rt.path, s.authenticate(handler))
+	}
+	return mux
+}
+
+type route struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+	// public routes need no token.
+	public bool
+}
+
+func (s *Server) routes() []route {
+	return []route{
+		{method: http.MethodGet, path: "/api/v1/openapi.json", handler: serveOpenAPI, public: true},
+		{method: http.MethodGet, path: "/api/v1/plugins", handler: s.listPlugins},
+		{method: http.MethodPost, path: "/api/v1/scans", handler: s.startScan},
+		{method: http.MethodGet, path: "/api/v1/scans", handler: s.listScans},
+		{method: http.MethodGet, path: "/api/v1/scans/{id}", handler: s.getScan},
+		{method: http.MethodPost, path: "/api/v1/scans/{id}/cancel", handler: s.cancelScan},
+		{method: http.MethodGet, path: "/api/v1/scans/{id}/events", handler: s.streamEvents},
+		{method: http.MethodGet, path: "/api/v1/scans/{id}/results", handler: s.getResults},
+		{method: http.MethodGet, path: "/api/v1/scans/{id}/graph", handler: s.getGraph},
+		{method: http.MethodGet, path: "/api/v1/scans/{id}/path", handler: s.getPath},
+		{method: http.MethodGet, path: "/api/v1/scans/{id}/reachable", handler: s.getReachable},
+		{method: http.MethodGet, path: "/api/v1/scans/{id}/export", handler: s.getExport},
+		{method: http.MethodGet, path: "/api/v1/scans/{id}/traces/{trace}", handler: s.getTrace},
+		{method: http.MethodPost, path: "/api/v1/scans/{id}/expand", handler: s.expandTrace},
+		{method: http.MethodGet, path: "/api/v1/verdicts", handler: s.listVerdicts},
+		{method: http.MethodPut, path: "/api/v1/verdicts", handler: s.setVerdict},
+		{method: http.MethodDelete, path: "/api/v1/verdicts", handler: s.clearVerdict},
+		{method: http.MethodGet, path: "/api/v1/cases", handler: s.listCases},
+		{method: http.MethodGet, path: "/api/v1/cases/{case}", handler: s.getCase},
+	}
+}
+
+// authenticate requires one of the configured tokens, as a bearer token
+// or, for EventSource clients that cannot set headers, an access_token
+// query parameter.
+func (s *Server) authenticate(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			token = r.URL.Query().Get("access_token")
+		}
+		if !s.validToken(token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="deeper"`)
+			writeError(w, http.StatusUnauthorized, fmt.Errorf("missing or invalid API token"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) validToken(token string) bool {
+	if token == "" {
+		return false
+	}
+	valid := false
+	for _, t := range s.cfg.Tokens {
+		// Compare against every token so timing reveals nothing.
+		if subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+func serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(OpenAPI)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg("Failed to write API response")
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,461 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/engine"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
+)
+
+const testToken = "s3cret"
+
+// fakeScan stands in for the engine: it records a small graph, publishes
+// the events a real scan would and, given a release channel, says so on
+// waiting and waits for release or cancellation before finishing.
+type fakeScan struct {
+	repo    *database.Repository
+	bus     *events.Bus
+	release chan struct{}
+	waiting chan struct{}
+	opts    chan engine.Options
+}
+
+func (f *fakeScan) run(ctx context.Context, session *database.ScanSession, seeds []entities.Seed, opts engine.Options) error {
+	if f.opts != nil {
+		f.opts <- opts
+	}
+	publish := func(ev events.Event) {
+		ev.ScanID = session.ID
+		f.bus.Publish(ev)
+	}
+	publish(events.Event{Type: events.ScanStarted})
+
+	seed := seeds[0].Trace
+	rootID, err := f.repo.GetOrCreateTrace(seed)
+	if err != nil {
+		return err
+	}
+	if err := f.repo.InsertEdge(&database.TraceEdge{ChildTraceID: rootID, PluginName: database.SeedPluginName, ScanID: session.ID, DiscoveredAt: time.Now()}); err != nil {
+		return err
+	}
+	child := entities.Trace{Value: "jdoe@example.com", Type: entities.Email}
+	if err := f.repo.PersistDiscoveries(session.ID, []entities.Discovery{{Parent: seed, PluginName: "GitHubProfilePlugin", Child: child}}); err != nil {
+		return err
+	}
+	publish(events.Event{Type: events.TraceDiscovered, Trace: events.Ref(child), Parent: events.Ref(seed), Plugin: "GitHubProfilePlugin"})
+
+	session.Status = "completed"
+	if f.release != nil {
+		f.waiting <- struct{}{}
+		select {
+		case <-f.release:
+		case <-ctx.Done():
+			session.Status = "cancelled"
+		}
+	}
+	publish(events.Event{Type: events.ScanFinished, Count: 2})
+	completedAt := time.Now()
+	session.CompletedAt = &completedAt
+	session.TotalTraces, session.UniqueTraces = 2, 2
+	return f.repo.UpdateScanSession(session)
+}
+
+func newTestServer(t *testing.T, maxScans int) (*httptest.Server, *Server, *fakeScan) {
+	t.Helper()
+	db, err := database.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	repo := database.NewRepository(db)
+
+	bus := events.NewBus()
+	fake := &fakeScan{repo: repo, bus: bus}
+	srv := New(repo, bus, fake.run, Config{
+		Tokens:      []string{"other", testToken},
+		MaxScans:    maxScans,
+		ScanTimeout: time.Minute,
+		Plugins:     map[string]bool{"GitHubProfilePlugin": true},
+	})
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(func() {
+		ts.Close()
+		srv.Close()
+	})
+	return ts, srv, fake
+}
+
+func request(t *testing.T, ts *httptest.Server, method, path, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := ts.Client().Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = resp.Body.Close() })
+	return resp
+}
+
+func decode(t *testing.T, resp *http.Response, v interface{}) {
+	t.Helper()
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+}
+
+func startScan(t *testing.T, ts *httptest.Server, body string) database.ScanSession {
+	t.Helper()
+	resp := request(t, ts, http.MethodPost, "/api/v1/scans", body)
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	var session database.ScanSession
+	decode(t, resp, &session)
+	assert.Equal(t, fmt.Sprintf("/api/v1/scans/%d", session.ID), resp.Header.Get("Location"))
+	return session
+}
+
+func waitForStatus(t *testing.T, ts *httptest.Server, id int64, status string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		var session database.ScanSession
+		decode(t, request(t, ts, http.MethodGet, fmt.Sprintf("/api/v1/scans/%d", id), ""), &session)
+		return session.Status == status
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestServer_RequiresToken(t *testing.T) {
+	ts, _, _ := newTestServer(t, 1)
+
+	resp, err := ts.Client().Get(ts.URL + "/api/v1/scans")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.Contains(t, resp.Header.Get("WWW-Authenticate"), "Bearer")
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+"/api/v1/scans", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer wrong")
+	resp, err = ts.Client().Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	resp, err = ts.Client().Get(ts.URL + "/api/v1/scans?access_token=" + testToken)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, err = ts.Client().Get(ts.URL + "/api/v1/openapi.json")
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "the spec is public")
+}
+
+func TestServer_NoTokensRejectsEverything(t *testing.T) {
+	srv := New(nil, events.NewBus(), nil, Config{})
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/scans?access_token=", nil)
+	req.Header.Set("Authorization", "Bearer ")
+	srv.Handler().ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestOpenAPI_DescribesEveryRoute(t *testing.T) {
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(OpenAPI, &spec))
+	assert.True(t, strings.HasPrefix(spec.OpenAPI, "3."))
+
+	routes := (&Server{}).routes()
+	described := 0
+	for _, rt := range routes {
+		ops, ok := spec.Paths[rt.path]
+		if assert.True(t, ok, "path %s is missing from the spec", rt.path) {
+			assert.Contains(t, ops, strings.ToLower(rt.method), "%s %s is missing from the spec", rt.method, rt.path)
+		}
+	}
+	for _, ops := range spec.Paths {
+		for method := range ops {
+			if method != "parameters" {
+				described++
+			}
+		}
+	}
+	assert.Equal(t, len(routes), described, "the spec describes no route the server lacks")
+}
+
+func TestServer_ScanLifecycle(t *testing.T) {
+	ts, _, fake := newTestServer(t, 2)
+	fake.opts = make(chan engine.Options, 1)
+
+	session := startScan(t, ts, `{"input": "username:jdoe", "plugins": ["GitHubProfilePlugin"], "types": ["email"], "max_depth": 2, "timeout": "10s"}`)
+	assert.Equal(t, "username:jdoe", session.Input)
+	assert.Equal(t, engine.Options{Plugins: []string{"GitHubProfilePlugin"}, Types: []entities.TraceType{entities.Email}, MaxDepth: 2}, <-fake.opts)
+	waitForStatus(t, ts, session.ID, "completed")
+
+	var list struct{ Scans []database.ScanSession }
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/scans?status=completed", ""), &list)
+	require.Len(t, list.Scans, 1)
+	assert.Equal(t, session.ID, list.Scans[0].ID)
+
+	base := fmt.Sprintf("/api/v1/scans/%d", session.ID)
+	var graph struct {
+		Nodes []database.Trace
+		Edges []database.TraceEdge
+	}
+	decode(t, request(t, ts, http.MethodGet, base+"/graph", ""), &graph)
+	assert.Len(t, graph.Nodes, 2)
+	assert.Len(t, graph.Edges, 2)
+
+	var path struct {
+		Reached bool `json:"reached_from_seed"`
+		Path    []struct{ Value, Plugin string }
+	}
+	decode(t, request(t, ts, http.MethodGet, base+"/path?trace=jdoe@example.com", ""), &path)
+	assert.True(t, path.Reached)
+	require.Len(t, path.Path, 2)
+	assert.Equal(t, "GitHubProfilePlugin", path.Path[1].Plugin)
+
+	var reachable struct{ Traces []database.ReachableTrace }
+	decode(t, request(t, ts, http.MethodGet, base+"/reachable?trace=username:jdoe&hops=1", ""), &reachable)
+	require.Len(t, reachable.Traces, 1)
+	assert.Equal(t, "jdoe@example.com", reachable.Traces[0].Value)
+
+	resp := request(t, ts, http.MethodGet, base+"/path?trace=nobody", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = request(t, ts, http.MethodGet, base+"/export?format=gexf", "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/gexf+xml", resp.Header.Get("Content-Type"))
+	assert.Contains(t, resp.Header.Get("Content-Disposition"), "scan-")
+	resp = request(t, ts, http.MethodGet, base+"/export?format=pdf", "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var doc map[string]interface{}
+	decode(t, request(t, ts, http.MethodGet, base+"/results", ""), &doc)
+	assert.NotEmpty(t, doc)
+
+	resp = request(t, ts, http.MethodPost, base+"/cancel", "")
+	assert.Equal(t, http.StatusConflict, resp.StatusCode, "a finished scan cannot be cancelled")
+	resp = request(t, ts, http.MethodGet, "/api/v1/scans/999", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServer_RejectsInvalidScans(t *testing.T) {
+	ts, _, _ := newTestServer(t, 1)
+	for _, body := range []string{
+		`{}`,
+		`{"input": "jdoe", "colour": "blue"}`,
+		`{"input": "jdoe", "plugins": ["NoSuchPlugin"]}`,
+		`{"input": "jdoe", "types": ["no_such_type"]}`,
+		`{"input": "jdoe", "type": "no_such_type"}`,
+		`{"input": "jdoe", "timeout": "soon"}`,
+		`{"input": "jdoe", "max_traces": -1}`,
+	} {
+		resp := request(t, ts, http.MethodPost, "/api/v1/scans", body)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+	}
+}
+
+func TestServer_QueuesAndCancelsScans(t *testing.T) {
+	ts, _, fake := newTestServer(t, 1)
+	fake.release, fake.waiting = make(chan struct{}), make(chan struct{}, 1)
+
+	running := startScan(t, ts, `{"input": "username:first"}`)
+	<-fake.waiting
+	queued := startScan(t, ts, `{"input": "username:second"}`)
+	assert.Equal(t, StatusQueued, queued.Status, "one slot, already taken")
+
+	var cancelled database.ScanSession
+	decode(t, request(t, ts, http.MethodPost, fmt.Sprintf("/api/v1/scans/%d/cancel", queued.ID), ""), &cancelled)
+	assert.Equal(t, "cancelled", cancelled.Status)
+	assert.NotNil(t, cancelled.CompletedAt)
+
+	decode(t, request(t, ts, http.MethodPost, fmt.Sprintf("/api/v1/scans/%d/cancel", running.ID), ""), &cancelled)
+	assert.Equal(t, "cancelled", cancelled.Status)
+}
+
+func TestServer_StreamsEvents(t *testing.T) {
+	ts, _, fake := newTestServer(t, 1)
+	fake.release, fake.waiting = make(chan struct{}), make(chan struct{}, 1)
+
+	session := startScan(t, ts, `{"input": "username:jdoe"}`)
+	<-fake.waiting
+
+	resp := request(t, ts, http.MethodGet, fmt.Sprintf("/api/v1/scans/%d/events", session.ID), "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	close(fake.release)
+
+	var names []string
+	var end database.ScanSession
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if name, ok := strings.CutPrefix(line, "event: "); ok {
+			names = append(names, name)
+		}
+		if data, ok := strings.CutPrefix(line, "data: "); ok && names[len(names)-1] == "end" {
+			require.NoError(t, json.Unmarshal([]byte(data), &end))
+		}
+	}
+	assert.Equal(t, []string{"scan_finished", "end"}, names, "only events after connecting")
+	assert.Equal(t, "completed", end.Status)
+
+	// A finished scan's stream is just its end event.
+	resp = request(t, ts, http.MethodGet, fmt.Sprintf("/api/v1/scans/%d/events", session.ID), "")
+	scanner = bufio.NewScanner(resp.Body)
+	names = nil
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(scanner.Text(), "event: "); ok {
+			names = append(names, name)
+		}
+	}
+	assert.Equal(t, []string{"end"}, names)
+}
+
+func TestServer_DescribesAndExpandsTraces(t *testing.T) {
+	ts, srv, _ := newTestServer(t, 1)
+	session := startScan(t, ts, `{"input": "username:jdoe"}`)
+	waitForStatus(t, ts, session.ID, "completed")
+	base := fmt.Sprintf("/api/v1/scans/%d", session.ID)
+
+	var graph struct{ Nodes []database.Trace }
+	decode(t, request(t, ts, http.MethodGet, base+"/graph", ""), &graph)
+	ids := make(map[string]int64)
+	for _, n := range graph.Nodes {
+		ids[n.Value] = n.ID
+	}
+
+	var detail struct {
+		Trace    database.Trace
+		Hop      int
+		Reached  bool `json:"reached_from_seed"`
+		Path     []struct{ Value, Plugin string }
+		Parents  []struct{ Value, Plugin string }
+		Children []struct{ Value string }
+		Verdicts []database.Verdict
+	}
+	decode(t, request(t, ts, http.MethodGet, fmt.Sprintf("%s/traces/%d", base, ids["jdoe@example.com"]), ""), &detail)
+	assert.Equal(t, "jdoe@example.com", detail.Trace.Value)
+	assert.Equal(t, 1, detail.Hop)
+	assert.True(t, detail.Reached)
+	require.Len(t, detail.Path, 2)
+	require.Len(t, detail.Parents, 1)
+	assert.Equal(t, "GitHubProfilePlugin", detail.Parents[0].Plugin)
+	assert.Empty(t, detail.Children)
+	assert.Empty(t, detail.Verdicts)
+
+	resp := request(t, ts, http.MethodGet, base+"/traces/999", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = request(t, ts, http.MethodPost, base+"/expand", `{"trace": "jdoe@example.com"}`)
+	assert.Equal(t, http.StatusNotImplemented, resp.StatusCode, "no expand func configured")
+
+	var expanded struct {
+		trace   entities.Trace
+		plugins []string
+	}
+	srv.cfg.Expand = func(ctx context.Context, scanID int64, trace entities.Trace, plugins []string) ([]entities.Discovery, error) {
+		expanded.trace, expanded.plugins = trace, plugins
+		child := entities.Trace{Value: "jdoe.example.com", Type: entities.Domain}
+		d := entities.Discovery{Parent: trace, PluginName: "GitHubProfilePlugin", Child: child}
+		return []entities.Discovery{d}, srv.repo.PersistDiscoveries(scanID, []entities.Discovery{d})
+	}
+	resp = request(t, ts, http.MethodPost, base+"/expand", `{"trace": "jdoe@example.com", "plugins": ["GitHubProfilePlugin"]}`)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var result struct {
+		TraceID     int64 `json:"trace_id"`
+		Discoveries []struct{ Value, Plugin string }
+	}
+	decode(t, resp, &result)
+	assert.Equal(t, ids["jdoe@example.com"], result.TraceID)
+	require.Len(t, result.Discoveries, 1)
+	assert.Equal(t, "jdoe.example.com", result.Discoveries[0].Value)
+	assert.Equal(t, entities.Trace{Value: "jdoe@example.com", Type: entities.Email}, expanded.trace)
+	assert.Equal(t, []string{"GitHubProfilePlugin"}, expanded.plugins)
+
+	decode(t, request(t, ts, http.MethodGet, base+"/graph", ""), &graph)
+	assert.Len(t, graph.Nodes, 3)
+
+	resp = request(t, ts, http.MethodPost, base+"/expand", `{"trace": "jdoe@example.com", "plugins": ["NoSuchPlugin"]}`)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = request(t, ts, http.MethodPost, base+"/expand", `{"trace": "nobody"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServer_Verdicts(t *testing.T) {
+	ts, _, _ := newTestServer(t, 1)
+	session := startScan(t, ts, `{"input": "username:jdoe"}`)
+	waitForStatus(t, ts, session.ID, "completed")
+
+	var detail struct{ Trace database.Trace }
+	var path struct {
+		Path []struct {
+			TraceID int64 `json:"trace_id"`
+		}
+	}
+	decode(t, request(t, ts, http.MethodGet, fmt.Sprintf("/api/v1/scans/%d/path?trace=jdoe@example.com", session.ID), ""), &path)
+	require.Len(t, path.Path, 2)
+	emailID := path.Path[1].TraceID
+
+	resp := request(t, ts, http.MethodPut, "/api/v1/verdicts", fmt.Sprintf(`{"trace_id": %d, "plugin_name": "GitHubProfilePlugin", "verdict": "false-positive", "note": "wrong person"}`, emailID))
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var v database.Verdict
+	decode(t, resp, &v)
+	assert.Equal(t, database.VerdictFalsePositive, v.Verdict)
+	assert.Equal(t, "jdoe@example.com", v.Value)
+
+	var list struct{ Verdicts []database.Verdict }
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/verdicts", ""), &list)
+	require.Len(t, list.Verdicts, 1)
+	assert.Equal(t, "wrong person", list.Verdicts[0].Note)
+
+	decode(t, request(t, ts, http.MethodGet, fmt.Sprintf("/api/v1/scans/%d/traces/%d", session.ID, emailID), ""), &detail)
+	assert.Equal(t, emailID, detail.Trace.ID)
+
+	for _, body := range []string{
+		fmt.Sprintf(`{"trace_id": %d, "verdict": "maybe"}`, emailID),
+		fmt.Sprintf(`{"trace_id": %d, "plugin_name": "OtherPlugin", "verdict": "confirmed"}`, emailID),
+	} {
+		resp = request(t, ts, http.MethodPut, "/api/v1/verdicts", body)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+	}
+	resp = request(t, ts, http.MethodPut, "/api/v1/verdicts", `{"trace_id": 999, "verdict": "confirmed"}`)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp = request(t, ts, http.MethodDelete, fmt.Sprintf("/api/v1/verdicts?trace_id=%d&plugin_name=GitHubProfilePlugin", emailID), "")
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	resp = request(t, ts, http.MethodDelete, fmt.Sprintf("/api/v1/verdicts?trace_id=%d&plugin_name=GitHubProfilePlugin", emailID), "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestServer_Cases(t *testing.T) {
+	ts, srv, _ := newTestServer(t, 1)
+	c := &database.Case{Name: "acme-breach", Owner: "analyst"}
+	require.NoError(t, srv.repo.CreateCase(c))
+
+	var list struct{ Cases []database.Case }
+	decode(t, request(t, ts, http.MethodGet, "/api/v1/cases", ""), &list)
+	require.Len(t, list.Cases, 1)
+	assert.Equal(t, "acme-breach", list.Cases[0].Name)
+
+	for _, ref := range []string{"acme-breach", fmt.Sprint(c.ID)} {
+		var got database.Case
+		decode(t, request(t, ts, http.MethodGet, "/api/v1/cases/"+ref, ""), &got)
+		assert.Equal(t, c.ID, got.ID, ref)
+	}
+	resp := request(t, ts, http.MethodGet, "/api/v1/cases/nope", "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
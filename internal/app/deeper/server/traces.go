@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/graphnav"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/identity"
+)
+
+// ExpandFunc runs plugins, or every applicable one when plugins is empty,
+// against one trace of a scan and records what they find in that scan.
+type ExpandFunc func(ctx context.Context, scanID int64, trace entities.Trace, plugins []string) ([]entities.Discovery, error)
+
+// neighborJSON is a trace one edge away and the plugin on that edge.
+type neighborJSON struct {
+	TraceID int64  `json:"trace_id"`
+	Value   string `json:"value"`
+	Type    string `json:"type"`
+	Plugin  string `json:"plugin"`
+}
+
+// identityJSON is the identity cluster a trace belongs to and the evidence
+// tying its members together.
+type identityJSON struct {
+	Label    string      `json:"label"`
+	Members  []traceJSON `json:"members"`
+	Evidence []string    `json:"evidence"`
+}
+
+// getTrace describes one trace of a scan: its metadata, how it was
+// reached, its neighbors, the verdicts on it and its identity.
+func (s *Server) getTrace(w http.ResponseWriter, r *http.Request) {
+	session, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseInt(r.PathValue("trace"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid trace id %q", r.PathValue("trace")))
+		return
+	}
+	nodes, edges, err := s.repo.GetScanGraph(session.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to load scan graph: %w", err))
+		return
+	}
+	graph := graphnav.New(nodes, edges)
+	t, ok := graph.Trace(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("trace #%d is not part of this scan", id))
+		return
+	}
+
+	ancestry, err := s.repo.GetDiscoveryPath(session.ID, t.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	chain := graph.Chain(t.ID, ancestry)
+
+	verdicts, err := s.repo.GetVerdicts()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	onTrace := []database.Verdict{}
+	for _, v := range verdicts {
+		if v.TraceID == t.ID {
+			onTrace = append(onTrace, v)
+		}
+	}
+
+	ident, err := s.traceIdentity(session.ID, t.ID, graph, nodes)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	parents, children := graph.Neighbors(t.ID)
+	writeJSON(w, http.StatusOK, struct {
+		ScanID   int64              `json:"scan_id"`
+		Trace    database.Trace     `json:"trace"`
+		Hop      int                `json:"hop"`
+		Reached  bool               `json:"reached_from_seed"`
+		Path     []graphnav.Step    `json:"path"`
+		Parents  []neighborJSON     `json:"parents"`
+		Children []neighborJSON     `json:"children"`
+		Verdicts []database.Verdict `json:"verdicts"`
+		Identity *identityJSON      `json:"identity,omitempty"`
+	}{
+		session.ID,
+		t,
+		graph.Hop(t.ID),
+		len(chain) > 0 && graph.IsSeed(chain[0].TraceID),
+		chain,
+		neighborsJSON(parents),
+		neighborsJSON(children),
+		onTrace,
+		ident,
+	})
+}
+
+// traceIdentity returns the identity cluster holding a trace, or nil when
+// the trace shares its identity with nothing else.
+func (s *Server) traceIdentity(scanID, traceID int64, graph *graphnav.Graph, nodes []database.Trace) (*identityJSON, error) {
+	clusters, links, err := s.repo.GetIdentities(scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identities: %w", err)
+	}
+	traces := make(map[int64]database.Trace, len(nodes))
+	for _, n := range nodes {
+		traces[n.ID] = n
+	}
+	for _, c := range clusters {
+		member := false
+		for _, id := range c.MemberIDs {
+			member = member || id == traceID
+		}
+		if !member {
+			continue
+		}
+		related := identity.ClusterLinks(c, links)
+		if len(c.MemberIDs) < 2 && len(related) == 0 {
+			return nil, nil
+		}
+		ident := &identityJSON{Label: c.Label, Evidence: identity.DescribeLinks(related, traces)}
+		for _, id := range c.MemberIDs {
+			t := traces[id]
+			ident.Members = append(ident.Members, traceJSON{t.ID, t.Value, string(t.Type), graph.Hop(t.ID)})
+		}
+		return ident, nil
+	}
+	return nil, nil
+}
+
+func neighborsJSON(ns []graphnav.Neighbor) []neighborJSON {
+	out := make([]neighborJSON, 0, len(ns))
+	for _, n := range ns {
+		out = append(out, neighborJSON{n.Trace.ID, n.Trace.Value, string(n.Trace.Type), n.Plugin})
+	}
+	return out
+}
+
+// expandRequest is the body of POST /api/v1/scans/{id}/expand.
+type expandRequest struct {
+	// Trace is the trace to expand, as the trace query parameter of the
+	// path route takes it.
+	Trace   string   `json:"trace"`
+	Plugins []string `json:"plugins,omitempty"`
+}
+
+// expandTrace runs plugins against one trace of a finished scan and adds
+// what they find to the scan, without following it further.
+func (s *Server) expandTrace(w http.ResponseWriter, r *http.Request) {
+	if s.cfg.Expand == nil {
+		writeError(w, http.StatusNotImplemented, fmt.Errorf("this server does not expand traces"))
+		return
+	}
+	session, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	var req expandRequest
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid expand request: %w", err))
+		return
+	}
+	for _, name := range req.Plugins {
+		if !s.cfg.Plugins[name] {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("unknown plugin %q", name))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	_, active := s.jobs[session.ID]
+	s.mu.Unlock()
+	if active {
+		writeError(w, http.StatusConflict, fmt.Errorf("scan %d is still running", session.ID))
+		return
+	}
+
+	nodes, edges, err := s.repo.GetScanGraph(session.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to load scan graph: %w", err))
+		return
+	}
+	t, err := graphnav.New(nodes, edges).Find(req.Trace)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+
+	ctx := r.Context()
+	if s.cfg.ScanTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.ScanTimeout)
+		defer cancel()
+	}
+	discoveries, err := s.cfg.Expand(ctx, session.ID, entities.Trace{Value: t.Value, Type: t.Type}, req.Plugins)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	type found struct {
+		Value  string `json:"value"`
+		Type   string `json:"type"`
+		Plugin string `json:"plugin"`
+	}
+	out := make([]found, 0, len(discoveries))
+	for _, d := range discoveries {
+		out = append(out, found{d.Child.Value, string(d.Child.Type), d.PluginName})
+	}
+	writeJSON(w, http.StatusOK, struct {
+		ScanID      int64   `json:"scan_id"`
+		Trace       int64   `json:"trace_id"`
+		Discoveries []found `json:"discoveries"`
+	}{session.ID, t.ID, out})
+}
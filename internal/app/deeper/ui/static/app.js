@@ -0,0 +1,664 @@
+// deeper's web UI. Everything the page shows comes from the API under
+// /api/v1; values are scraped, attacker-influenced data, so they only ever
+// reach the page as textContent, never as HTML.
+(function () {
+  "use strict";
+
+  var ACTIVE = { queued: true, running: true };
+  var VERDICT_COLORS = { confirmed: "#38bdf8", false_positive: "#ef4444", irrelevant: "#94a3b8" };
+  var EDGE_COLOR = "rgba(148,163,184,0.45)";
+  var SEED_BORDER = "#facc15";
+  var REFRESH_DELAY = 400;
+  var ACTIVITY_LIMIT = 200;
+
+  // ---- API token: taken from the #token= fragment, kept for this tab ----
+  var TOKEN_KEY = "deeper.token";
+  var fragment = new URLSearchParams(location.hash.slice(1));
+  if (fragment.get("token")) {
+    sessionStorage.setItem(TOKEN_KEY, fragment.get("token"));
+    fragment.delete("token");
+    history.replaceState(null, "", location.pathname + (fragment.toString() ? "#" + fragment : ""));
+  }
+  var token = sessionStorage.getItem(TOKEN_KEY) || "";
+
+  function $(id) {
+    return document.getElementById(id);
+  }
+
+  // el builds an element; text is always set as textContent.
+  function el(tag, className, text) {
+    var node = document.createElement(tag);
+    if (className) node.className = className;
+    if (text !== undefined && text !== null) node.textContent = String(text);
+    return node;
+  }
+
+  function clear(node) {
+    while (node.firstChild) node.removeChild(node.firstChild);
+  }
+
+  function cleanLabel(value) {
+    return String(value).replace(/\s+/g, " ").trim();
+  }
+  function truncate(value, max) {
+    return value.length > max ? value.slice(0, max - 1) + "…" : value;
+  }
+  function verdictText(v) {
+    return String(v).replace(/_/g, " ");
+  }
+  function when(iso) {
+    if (!iso) return "";
+    var d = new Date(iso);
+    return isNaN(d) ? iso : d.toLocaleString();
+  }
+
+  var toastTimer = null;
+  function toast(message, info) {
+    var t = $("toast");
+    t.textContent = message;
+    t.className = info ? "info" : "";
+    t.hidden = false;
+    clearTimeout(toastTimer);
+    toastTimer = setTimeout(function () { t.hidden = true; }, 5000);
+  }
+
+  function showLogin(reason) {
+    if (reason) $("login-reason").textContent = reason;
+    $("login").hidden = false;
+    $("login-form").elements.token.focus();
+  }
+
+  function api(method, path, body) {
+    var opts = { method: method, headers: { Authorization: "Bearer " + token } };
+    if (body !== undefined) {
+      opts.headers["Content-Type"] = "application/json";
+      opts.body = JSON.stringify(body);
+    }
+    return fetch(path, opts).then(function (resp) {
+      if (resp.status === 401) {
+        showLogin("The API rejected the token; paste a valid one.");
+        throw new Error("missing or invalid API token");
+      }
+      if (resp.status === 204) return null;
+      return resp.json().catch(function () { return {}; }).then(function (data) {
+        if (!resp.ok) throw new Error(data.error || resp.status + " " + resp.statusText);
+        return data;
+      });
+    });
+  }
+
+  function fail(err) {
+    toast(err.message || String(err));
+  }
+
+  // ---- activity log ----
+  function activity(text, failed) {
+    var log = $("activity-log");
+    var item = el("li", failed ? "failed" : "", new Date().toLocaleTimeString() + "  " + text);
+    log.insertBefore(item, log.firstChild);
+    while (log.childNodes.length > ACTIVITY_LIMIT) log.removeChild(log.lastChild);
+  }
+
+  // ---- graph ----
+  function hashHue(str) {
+    var h = 0;
+    for (var i = 0; i < str.length; i++) {
+      h = (h * 31 + str.charCodeAt(i)) >>> 0;
+    }
+    return h % 360;
+  }
+  var colorCache = {};
+  function colorFor(type) {
+    if (!colorCache[type]) colorCache[type] = "hsl(" + hashHue(type) + ", 62%, 58%)";
+    return colorCache[type];
+  }
+
+  var nodes = new vis.DataSet();
+  var edges = new vis.DataSet();
+  var network = new vis.Network($("network"), { nodes: nodes, edges: edges }, {
+    nodes: { shape: "dot", size: 10, font: { color: "#e6edf3", size: 11, face: "inherit" } },
+    edges: {
+      color: { color: EDGE_COLOR },
+      arrows: { to: { enabled: true, scaleFactor: 0.35 } },
+      smooth: { type: "continuous", roundness: 0.4 }
+    },
+    physics: {
+      solver: "barnesHut",
+      barnesHut: { gravitationalConstant: -4000, springLength: 130, springConstant: 0.02, avoidOverlap: 0.6 },
+      stabilization: { iterations: 200 }
+    },
+    interaction: { hover: true, tooltipDelay: 120, hideEdgesOnDrag: true }
+  });
+
+  var current = null; // the open scan session
+  var events = null; // its EventSource while it runs
+  var selected = null; // the trace shown in the details panel
+  var plugins = [];
+  var refreshTimer = null;
+  var graphTraces = {};
+
+  function tooltip(t, verdict) {
+    var wrap = el("div");
+    wrap.appendChild(el("div", "tt-type", t.type));
+    wrap.appendChild(el("div", "tt-value", cleanLabel(t.value)));
+    if (verdict) wrap.appendChild(el("div", "tt-type", "analyst: " + verdictText(verdict)));
+    return wrap;
+  }
+
+  function refreshGraph() {
+    if (!current) return Promise.resolve();
+    var scanID = current.id;
+    return Promise.all([
+      api("GET", "/api/v1/scans/" + scanID + "/graph"),
+      api("GET", "/api/v1/verdicts")
+    ]).then(function (res) {
+      if (!current || current.id !== scanID) return;
+      var graph = res[0];
+      var onTrace = {};
+      var onEdge = {};
+      res[1].verdicts.forEach(function (v) {
+        if (v.plugin_name) onEdge[v.trace_id + "|" + v.plugin_name] = v.verdict;
+        else onTrace[v.trace_id] = v.verdict;
+      });
+
+      var seeds = {};
+      var degree = {};
+      graph.edges.forEach(function (e) {
+        if (e.parent_trace_id === null) {
+          seeds[e.child_trace_id] = true;
+          return;
+        }
+        degree[e.parent_trace_id] = (degree[e.parent_trace_id] || 0) + 1;
+        degree[e.child_trace_id] = (degree[e.child_trace_id] || 0) + 1;
+      });
+
+      var fresh = 0;
+      graphTraces = {};
+      nodes.update(graph.nodes.map(function (t) {
+        graphTraces[t.id] = t;
+        if (!nodes.get(t.id)) fresh++;
+        var verdict = onTrace[t.id];
+        var fill = colorFor(t.type);
+        var border = seeds[t.id] ? SEED_BORDER : verdict ? VERDICT_COLORS[verdict] : fill;
+        return {
+          id: t.id,
+          label: truncate(cleanLabel(t.value), 26),
+          title: tooltip(t, verdict),
+          color: { background: fill, border: border },
+          borderWidth: seeds[t.id] || verdict ? 3 : 1,
+          shapeProperties: { borderDashes: verdict === "false_positive" ? [4, 3] : false },
+          size: Math.min(26, 8 + (degree[t.id] || 0) * 1.6)
+        };
+      }));
+      edges.update(graph.edges.filter(function (e) {
+        return e.parent_trace_id !== null;
+      }).map(function (e) {
+        var verdict = onEdge[e.child_trace_id + "|" + e.plugin_name];
+        return {
+          id: e.parent_trace_id + ">" + e.child_trace_id + ">" + e.plugin_name,
+          from: e.parent_trace_id,
+          to: e.child_trace_id,
+          title: el("div", "tt-value", "via " + e.plugin_name + (verdict ? " · analyst: " + verdictText(verdict) : "")),
+          dashes: verdict === "false_positive"
+        };
+      }));
+      if (fresh > 0) network.stabilize(120);
+
+      $("empty").hidden = graph.nodes.length > 0;
+      $("empty").textContent = ACTIVE[current.status] ? "Waiting for the first traces…" : "No traces recorded for this scan.";
+      $("stats").textContent = graph.nodes.length + " traces";
+      renderLegend(graph.nodes);
+    });
+  }
+
+  function scheduleRefresh() {
+    if (refreshTimer) return;
+    refreshTimer = setTimeout(function () {
+      refreshTimer = null;
+      refreshGraph().catch(fail);
+    }, REFRESH_DELAY);
+  }
+
+  function renderLegend(traces) {
+    var legend = $("legend");
+    clear(legend);
+    var counts = {};
+    traces.forEach(function (t) { counts[t.type] = (counts[t.type] || 0) + 1; });
+    Object.keys(counts).sort().forEach(function (type) {
+      var row = el("div", "row");
+      var swatch = el("div", "swatch");
+      swatch.style.background = colorFor(type);
+      row.appendChild(swatch);
+      row.appendChild(el("span", "", type + " (" + counts[type] + ")"));
+      legend.appendChild(row);
+    });
+  }
+
+  // ---- the open scan ----
+  function renderScanHeader() {
+    var status = $("scan-status");
+    if (!current) {
+      $("scan-title").textContent = "No scan open";
+      status.hidden = true;
+      $("cancel-scan").hidden = true;
+      return;
+    }
+    $("scan-title").textContent = "#" + current.id + " · " + truncate(cleanLabel(current.input), 60);
+    status.textContent = current.status;
+    status.className = "badge " + current.status;
+    status.hidden = false;
+    $("cancel-scan").hidden = !ACTIVE[current.status];
+    $("expand-form").querySelector("button").disabled = !!ACTIVE[current.status];
+  }
+
+  function openScan(id) {
+    stopFollowing();
+    hideDetails();
+    nodes.clear();
+    edges.clear();
+    graphTraces = {};
+    return api("GET", "/api/v1/scans/" + id).then(function (session) {
+      current = session;
+      history.replaceState(null, "", "#scan=" + session.id);
+      renderScanHeader();
+      markCurrentScan();
+      return refreshGraph().then(function () {
+        if (ACTIVE[session.status]) follow(session.id);
+      });
+    }).catch(fail);
+  }
+
+  function stopFollowing() {
+    if (events) {
+      events.close();
+      events = null;
+    }
+  }
+
+  function follow(id) {
+    var source = new EventSource("/api/v1/scans/" + id + "/events?access_token=" + encodeURIComponent(token));
+    events = source;
+    function parse(e) {
+      try {
+        return JSON.parse(e.data);
+      } catch (err) {
+        return {};
+      }
+    }
+    source.addEventListener("scan_started", function () {
+      current.status = "running";
+      renderScanHeader();
+      activity("scan #" + id + " started");
+    });
+    source.addEventListener("trace_discovered", function (e) {
+      var ev = parse(e);
+      if (ev.trace) activity("found " + ev.trace.type + " " + cleanLabel(ev.trace.value) + (ev.plugin ? " via " + ev.plugin : ""));
+      scheduleRefresh();
+    });
+    source.addEventListener("plugin_failed", function (e) {
+      var ev = parse(e);
+      activity(ev.plugin + " failed: " + (ev.error || "unknown error"), true);
+    });
+    source.addEventListener("budget_exhausted", function (e) {
+      var ev = parse(e);
+      activity("budget exhausted with " + ev.count + " traces queued: " + ev.error, true);
+    });
+    source.addEventListener("end", function (e) {
+      source.close();
+      if (events === source) events = null;
+      var session = parse(e);
+      if (current && current.id === session.id) {
+        current = session;
+        renderScanHeader();
+        refreshGraph().catch(fail);
+      }
+      activity("scan #" + session.id + " " + session.status);
+      loadScans();
+    });
+    source.addEventListener("error", function (e) {
+      // A named "error" event from the server means the stream was
+      // dropped for falling behind; the graph can still be reloaded.
+      if (e.data) {
+        activity(parse(e).error || "event stream closed", true);
+        source.close();
+        scheduleRefresh();
+      }
+    });
+  }
+
+  $("cancel-scan").addEventListener("click", function () {
+    if (!current) return;
+    api("POST", "/api/v1/scans/" + current.id + "/cancel").then(function (session) {
+      current = session;
+      renderScanHeader();
+      toast("Scan #" + session.id + " cancelled", true);
+    }).catch(fail);
+  });
+
+  // ---- trace details ----
+  function hideDetails() {
+    selected = null;
+    $("details").hidden = true;
+  }
+
+  function traceLink(id, text) {
+    var link = el("span", "trace-link", text);
+    link.addEventListener("click", function () {
+      network.selectNodes([id]);
+      network.focus(id, { animation: true });
+      showTrace(id);
+    });
+    return link;
+  }
+
+  function showTrace(id) {
+    if (!current) return Promise.resolve();
+    selected = id;
+    return api("GET", "/api/v1/scans/" + current.id + "/traces/" + id).then(function (d) {
+      if (selected !== id) return;
+      var t = d.trace;
+      var verdict = null;
+      d.verdicts.forEach(function (v) { if (!v.plugin_name) verdict = v.verdict; });
+      $("details-type").textContent = t.type + (d.hop === 0 ? " · seed" : " · hop " + d.hop) +
+        (verdict ? " · analyst: " + verdictText(verdict) : "");
+      $("details-value").textContent = t.value;
+
+      var path = $("details-path");
+      clear(path);
+      if (!d.reached_from_seed) path.appendChild(el("li", "muted", "not reached from a seed"));
+      d.path.forEach(function (step) {
+        var item = el("li");
+        if (step.plugin) item.appendChild(el("span", "via", "↳ " + step.plugin + " "));
+        item.appendChild(traceLink(step.trace_id, step.type + ": " + cleanLabel(step.value)));
+        path.appendChild(item);
+      });
+
+      var metadata = $("details-metadata");
+      clear(metadata);
+      var keys = Object.keys(t.metadata || {}).sort();
+      keys.forEach(function (k) {
+        var v = t.metadata[k];
+        metadata.appendChild(el("dt", "", k));
+        metadata.appendChild(el("dd", "", typeof v === "object" ? JSON.stringify(v) : v));
+      });
+      $("details-metadata-section").hidden = keys.length === 0;
+
+      renderNeighbors($("details-parents"), d.parents);
+      renderNeighbors($("details-children"), d.children);
+
+      if (d.identity) {
+        $("details-identity").textContent = cleanLabel(d.identity.label) + " (" + d.identity.members.length + " member(s))";
+        $("details-evidence").textContent = (d.identity.evidence || []).join("\n");
+        $("details-identity-section").hidden = false;
+      } else {
+        $("details-identity-section").hidden = true;
+      }
+
+      var verdicts = $("details-verdicts");
+      clear(verdicts);
+      if (d.verdicts.length === 0) verdicts.appendChild(el("li", "muted", "none"));
+      d.verdicts.forEach(function (v) {
+        var item = el("li", "", verdictText(v.verdict) + (v.plugin_name ? " on " + v.plugin_name + " edges" : ""));
+        item.style.color = VERDICT_COLORS[v.verdict];
+        if (v.note) item.appendChild(el("span", "muted", " — " + v.note));
+        verdicts.appendChild(item);
+      });
+
+      var target = $("verdict-target");
+      clear(target);
+      var whole = el("option", "", "the whole trace");
+      whole.value = "";
+      target.appendChild(whole);
+      var seen = {};
+      d.parents.forEach(function (n) {
+        if (seen[n.plugin]) return;
+        seen[n.plugin] = true;
+        var option = el("option", "", n.plugin + " edges into it");
+        option.value = n.plugin;
+        target.appendChild(option);
+      });
+      $("expand-result").textContent = "";
+      $("details").hidden = false;
+    }).catch(fail);
+  }
+
+  function renderNeighbors(list, neighbors) {
+    clear(list);
+    if (neighbors.length === 0) list.appendChild(el("li", "muted", "—"));
+    neighbors.forEach(function (n) {
+      var item = el("li");
+      item.appendChild(traceLink(n.trace_id, n.type + ": " + cleanLabel(n.value)));
+      item.appendChild(el("span", "via", " via " + n.plugin));
+      list.appendChild(item);
+    });
+  }
+
+  network.on("selectNode", function (params) {
+    showTrace(params.nodes[0]);
+  });
+  network.on("deselectNode", hideDetails);
+  $("details-close").addEventListener("click", function () {
+    network.unselectAll();
+    hideDetails();
+  });
+
+  // ---- verdicts ----
+  $("verdict-form").addEventListener("click", function (e) {
+    var button = e.target.closest("button[data-verdict]");
+    if (!button || selected === null) return;
+    var form = $("verdict-form");
+    var id = selected;
+    var pluginName = form.elements.plugin_name.value;
+    var verdict = button.getAttribute("data-verdict");
+    var done;
+    if (verdict) {
+      done = api("PUT", "/api/v1/verdicts", {
+        trace_id: id,
+        plugin_name: pluginName,
+        verdict: verdict,
+        note: form.elements.note.value
+      });
+    } else {
+      var query = "trace_id=" + id + (pluginName ? "&plugin_name=" + encodeURIComponent(pluginName) : "");
+      done = api("DELETE", "/api/v1/verdicts?" + query);
+    }
+    done.then(function () {
+      form.elements.note.value = "";
+      activity(verdict ? "marked #" + id + " " + verdictText(verdict) : "cleared the verdict on #" + id);
+      return Promise.all([refreshGraph(), showTrace(id)]);
+    }).catch(fail);
+  });
+
+  // ---- expanding one trace ----
+  function checkedPlugins(container) {
+    return Array.prototype.map.call(container.querySelectorAll("input:checked"), function (box) {
+      return box.value;
+    });
+  }
+
+  $("expand-form").addEventListener("submit", function (e) {
+    e.preventDefault();
+    if (!current || selected === null) return;
+    var id = selected;
+    var button = $("expand-form").querySelector("button");
+    button.disabled = true;
+    $("expand-result").textContent = "Running…";
+    api("POST", "/api/v1/scans/" + current.id + "/expand", {
+      trace: "#" + id,
+      plugins: checkedPlugins($("expand-plugins"))
+    }).then(function (res) {
+      var text = res.discoveries.length + " discover" + (res.discoveries.length === 1 ? "y" : "ies");
+      $("expand-result").textContent = text;
+      activity("expanded #" + id + ": " + text);
+      return refreshGraph().then(function () { return showTrace(id); });
+    }).catch(function (err) {
+      $("expand-result").textContent = "";
+      fail(err);
+    }).then(function () {
+      button.disabled = !!(current && ACTIVE[current.status]);
+    });
+  });
+
+  function renderPluginList(container) {
+    clear(container);
+    plugins.forEach(function (name) {
+      var label = el("label");
+      var box = el("input");
+      box.type = "checkbox";
+      box.value = name;
+      label.appendChild(box);
+      label.appendChild(el("span", "", name));
+      container.appendChild(label);
+    });
+  }
+
+  function loadPlugins() {
+    return api("GET", "/api/v1/plugins").then(function (res) {
+      plugins = res.plugins;
+      renderPluginList($("scan-plugins"));
+      renderPluginList($("expand-plugins"));
+    });
+  }
+
+  // ---- starting scans ----
+  $("scan-form").addEventListener("submit", function (e) {
+    e.preventDefault();
+    var form = e.target;
+    var req = { input: form.elements.input.value.trim() };
+    if (form.elements.type.value.trim()) req.type = form.elements.type.value.trim();
+    if (form.elements.max_depth.value) req.max_depth = Number(form.elements.max_depth.value);
+    if (form.elements.max_traces.value) req.max_traces = Number(form.elements.max_traces.value);
+    if (form.elements.timeout.value.trim()) req.timeout = form.elements.timeout.value.trim();
+    var chosen = checkedPlugins($("scan-plugins"));
+    if (chosen.length) req.plugins = chosen;
+
+    api("POST", "/api/v1/scans", req).then(function (session) {
+      activity("started scan #" + session.id + " of " + cleanLabel(session.input));
+      loadScans();
+      return openScan(session.id);
+    }).catch(fail);
+  });
+
+  // ---- browsing scans ----
+  function scanItem(session) {
+    var item = el("li", "", "#" + session.id + " " + cleanLabel(session.input));
+    item.setAttribute("data-scan", session.id);
+    item.appendChild(el("span", "meta", session.status + " · " + when(session.started_at) +
+      (session.unique_traces ? " · " + session.unique_traces + " traces" : "")));
+    item.addEventListener("click", function () { openScan(session.id); });
+    return item;
+  }
+
+  function markCurrentScan() {
+    document.querySelectorAll("[data-scan]").forEach(function (item) {
+      item.classList.toggle("current", !!current && item.getAttribute("data-scan") === String(current.id));
+    });
+  }
+
+  function loadScans() {
+    var status = $("scan-filter").value;
+    return api("GET", "/api/v1/scans?limit=100" + (status ? "&status=" + status : "")).then(function (res) {
+      var list = $("scan-list");
+      clear(list);
+      if (res.scans.length === 0) list.appendChild(el("li", "muted", "No scans yet"));
+      res.scans.forEach(function (session) { list.appendChild(scanItem(session)); });
+      markCurrentScan();
+    }).catch(fail);
+  }
+
+  $("scan-filter").addEventListener("change", loadScans);
+  $("refresh-scans").addEventListener("click", loadScans);
+
+  // ---- browsing cases ----
+  function loadCases() {
+    $("case-detail").hidden = true;
+    $("case-list").hidden = false;
+    return api("GET", "/api/v1/cases").then(function (res) {
+      var list = $("case-list");
+      clear(list);
+      if (res.cases.length === 0) list.appendChild(el("li", "muted", "No cases yet"));
+      res.cases.forEach(function (c) {
+        var item = el("li", "", c.name);
+        item.appendChild(el("span", "meta", c.status + " · " + (c.owner || "no owner") + " · " +
+          (c.scan_ids || []).length + " scan(s)"));
+        item.addEventListener("click", function () { showCase(c.id); });
+        list.appendChild(item);
+      });
+    }).catch(fail);
+  }
+
+  function showCase(id) {
+    return api("GET", "/api/v1/cases/" + id).then(function (c) {
+      $("case-name").textContent = c.name;
+      var fields = $("case-fields");
+      clear(fields);
+      [
+        ["Status", c.status + (c.closed_at ? " (" + when(c.closed_at) + ")" : "")],
+        ["Owner", c.owner],
+        ["Authorization", c.authorization],
+        ["Opened", when(c.created_at)],
+        ["Tags", (c.tags || []).join(", ")],
+        ["Seeds", (c.seeds || []).join("\n")]
+      ].forEach(function (f) {
+        if (!f[1]) return;
+        fields.appendChild(el("div", "field-label", f[0]));
+        fields.appendChild(el("div", "field evidence", f[1]));
+      });
+      if ((c.notes || []).length) {
+        fields.appendChild(el("div", "field-label", "Notes"));
+        c.notes.forEach(function (n) {
+          fields.appendChild(el("div", "field evidence", when(n.created_at) + "\n" + n.text));
+        });
+      }
+
+      var scans = $("case-scans");
+      clear(scans);
+      if ((c.scan_ids || []).length === 0) scans.appendChild(el("li", "muted", "No scans"));
+      return Promise.all((c.scan_ids || []).map(function (scanID) {
+        return api("GET", "/api/v1/scans/" + scanID);
+      })).then(function (sessions) {
+        sessions.forEach(function (session) { scans.appendChild(scanItem(session)); });
+        markCurrentScan();
+        $("case-list").hidden = true;
+        $("case-detail").hidden = false;
+      });
+    }).catch(fail);
+  }
+
+  $("refresh-cases").addEventListener("click", loadCases);
+  $("case-back").addEventListener("click", loadCases);
+
+  // ---- tabs ----
+  document.querySelectorAll("#tabs button").forEach(function (button) {
+    button.addEventListener("click", function () {
+      var tab = button.getAttribute("data-tab");
+      document.querySelectorAll("#tabs button").forEach(function (b) { b.classList.toggle("active", b === button); });
+      document.querySelectorAll(".tab").forEach(function (section) {
+        section.hidden = section.id !== "tab-" + tab;
+      });
+      if (tab === "scans") loadScans();
+      if (tab === "cases") loadCases();
+    });
+  });
+
+  // ---- start up ----
+  function start() {
+    $("login").hidden = true;
+    loadPlugins().then(function () {
+      loadScans();
+      var scan = new URLSearchParams(location.hash.slice(1)).get("scan");
+      if (scan) openScan(scan);
+    }).catch(fail);
+  }
+
+  $("login-form").addEventListener("submit", function (e) {
+    e.preventDefault();
+    token = e.target.elements.token.value.trim();
+    sessionStorage.setItem(TOKEN_KEY, token);
+    e.target.elements.token.value = "";
+    start();
+  });
+
+  if (token) start();
+  else showLogin();
+})();
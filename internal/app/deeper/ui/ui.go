@@ -0,0 +1,73 @@
+// Package ui is deeper's built-in web UI: a single page for starting
+// scans, watching their graphs grow, inspecting and expanding traces,
+// marking verdicts and browsing past scans and cases. It talks to the
+// HTTP API of package server and, like the graph report, embeds
+// everything it needs, so it works fully offline.
+package ui
+
+import (
+	"bytes"
+	"embed"
+	"net/http"
+	"time"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/graphreport"
+)
+
+//go:embed static
+var staticFS embed.FS
+
+// contentSecurityPolicy keeps the page to its own assets and API. Styles
+// may be inline because vis-network injects its own.
+const contentSecurityPolicy = "default-src 'none'; script-src 'self'; style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data:; connect-src 'self'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+
+// asset is a file the UI serves.
+type asset struct {
+	contentType string
+	body        []byte
+}
+
+var assets = func() map[string]asset {
+	read := func(name string) []byte {
+		b, err := staticFS.ReadFile("static/" + name)
+		if err != nil {
+			panic(err) // embedded at build time; a read failure means the build is broken
+		}
+		return b
+	}
+	return map[string]asset{
+		"/":                          {"text/html; charset=utf-8", read("index.html")},
+		"/app.js":                    {"text/javascript; charset=utf-8", read("app.js")},
+		"/app.css":                   {"text/css; charset=utf-8", read("app.css")},
+		"/vendor/vis-network.min.js": {"text/javascript; charset=utf-8", graphreport.VisNetworkJS()},
+	}
+}()
+
+// Handler serves the UI's page and assets. The page calls the API under
+// /api/v1 on the same origin, so mount it next to the server's handler.
+//
+// The page reads its API token from the URL fragment (#token=...), which
+// browsers never send to the server, and keeps it for the tab's session.
+func Handler() http.Handler {
+	started := time.Now()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a, ok := assets[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		h := w.Header()
+		h.Set("Content-Type", a.contentType)
+		h.Set("Content-Security-Policy", contentSecurityPolicy)
+		h.Set("X-Content-Type-Options", "nosniff")
+		h.Set("Referrer-Policy", "no-referrer")
+		h.Set("Cache-Control", "no-cache")
+		http.ServeContent(w, r, "", started, bytes.NewReader(a.body))
+	})
+}
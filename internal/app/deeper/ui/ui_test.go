@@ -0,0 +1,58 @@
+package ui
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func get(t *testing.T, method, path string) *http.Response {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec.Result()
+}
+
+func TestHandler_ServesAssets(t *testing.T) {
+	for path, contentType := range map[string]string{
+		"/":                          "text/html",
+		"/app.js":                    "text/javascript",
+		"/app.css":                   "text/css",
+		"/vendor/vis-network.min.js": "text/javascript",
+	} {
+		resp := get(t, http.MethodGet, path)
+		require.Equal(t, http.StatusOK, resp.StatusCode, path)
+		assert.Contains(t, resp.Header.Get("Content-Type"), contentType, path)
+		assert.Equal(t, contentSecurityPolicy, resp.Header.Get("Content-Security-Policy"), path)
+		assert.Equal(t, "nosniff", resp.Header.Get("X-Content-Type-Options"), path)
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.NotEmpty(t, body, path)
+	}
+
+	assert.Equal(t, http.StatusNotFound, get(t, http.MethodGet, "/nope").StatusCode)
+	assert.Equal(t, http.StatusMethodNotAllowed, get(t, http.MethodPost, "/").StatusCode)
+}
+
+// TestIndex_WorksOffline guards the no-CDN promise: every script and
+// stylesheet the page loads is one of its own assets, and none is inline,
+// which the content security policy would block.
+func TestIndex_WorksOffline(t *testing.T) {
+	page := string(assets["/"].body)
+	refs := regexp.MustCompile(`(?:src|href)="([^"]+)"`).FindAllStringSubmatch(page, -1)
+	require.NotEmpty(t, refs)
+	for _, ref := range refs {
+		_, ok := assets["/"+ref[1]]
+		assert.True(t, ok, "%s is not served by the UI", ref[1])
+	}
+	assert.NotContains(t, page, "<script>")
+	assert.NotContains(t, page, "<style>")
+	assert.False(t, regexp.MustCompile(`\son[a-z]+=`).MatchString(page), "inline event handlers are blocked by the CSP")
+	assert.True(t, strings.HasPrefix(page, "<!DOCTYPE html>"))
+}
@@ -0,0 +1,195 @@
+// Package watch keeps seeds under observation: it rescans each watched
+// seed on its schedule, diffs the new scan against the previous one and
+// turns what appeared into alerts.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/scandiff"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// alertKinds maps the trace types worth an alert when they appear to the
+// kind of alert they raise.
+var alertKinds = map[entities.TraceType]string{
+	entities.Subdomain: database.AlertNewSubdomain,
+	entities.Email:     database.AlertNewEmail,
+	entities.SSHKey:    database.AlertExposedKey,
+	entities.PGPKey:    database.AlertExposedKey,
+}
+
+// ScanFunc runs a completed scan of a watch's seed and returns its
+// session. It is expected to resolve the scan's identities too.
+type ScanFunc func(ctx context.Context, w database.Watch) (*database.ScanSession, error)
+
+// Result is the outcome of one watch run. Previous and Diff are nil for
+// the first scan of a seed, which has nothing to compare with.
+type Result struct {
+	Watch    database.Watch
+	Scan     *database.ScanSession
+	Previous *database.ScanSession
+	Diff     *scandiff.Diff
+	Alerts   []database.WatchAlert
+}
+
+// Runner runs due watches.
+type Runner struct {
+	repo *database.Repository
+	scan ScanFunc
+	now  func() time.Time
+}
+
+// NewRunner creates a runner that scans with scan.
+func NewRunner(repo *database.Repository, scan ScanFunc) *Runner {
+	return &Runner{repo: repo, scan: scan, now: time.Now}
+}
+
+// Loop runs due watches every poll interval until ctx is done, passing
+// each run's result to report. A failed run is logged and retried at the
+// watch's next scheduled time.
+func (r *Runner) Loop(ctx context.Context, poll time.Duration, report func(Result)) error {
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	for {
+		if _, err := r.RunDue(ctx, report); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue runs every watch that is due, one after another, passing each
+// result to report, and returns how many ran. Only database errors stop
+// it; a failed scan is logged and the watch rescheduled.
+func (r *Runner) RunDue(ctx context.Context, report func(Result)) (int, error) {
+	due, err := r.repo.GetDueWatches(r.now())
+	if err != nil {
+		return 0, err
+	}
+	ran := 0
+	for _, w := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		result, err := r.Run(ctx, w)
+		ran++
+		if err != nil {
+			log.Error().Err(err).Msgf("Watch #%d (%s) failed", w.ID, w.Input)
+			continue
+		}
+		if report != nil {
+			report(*result)
+		}
+	}
+	return ran, nil
+}
+
+// Run rescans a watch's seed now, stores the alerts the scan raises and
+// schedules the next run. The scan is compared with the watch's previous
+// scan, or for a first run with the latest earlier scan of the same input.
+func (r *Runner) Run(ctx context.Context, w database.Watch) (*Result, error) {
+	lastScanID := w.LastScanID
+	session, scanErr := r.scan(ctx, w)
+
+	ranAt := r.now()
+	w.LastRunAt = &ranAt
+	w.NextRunAt = ranAt.Add(w.Interval())
+	if scanErr == nil {
+		w.LastScanID = &session.ID
+	}
+	if err := r.repo.RecordWatchRun(&w); err != nil {
+		return nil, err
+	}
+	if scanErr != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", w.Input, scanErr)
+	}
+
+	result := &Result{Watch: w, Scan: session}
+	var previous *database.ScanSession
+	var err error
+	if lastScanID != nil {
+		previous, err = r.repo.GetScanSession(*lastScanID)
+	} else {
+		previous, err = r.repo.GetPreviousScan(session.Input, session.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if previous == nil {
+		return result, nil
+	}
+	result.Previous = previous
+
+	d, err := scandiff.Load(r.repo, previous.ID, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	result.Diff = d
+
+	clusters, _, err := r.repo.GetIdentities(session.ID)
+	if err != nil {
+		return nil, err
+	}
+	result.Alerts = Alerts(w.ID, d, clusters)
+	if err := r.repo.AddWatchAlerts(result.Alerts); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Alerts lists what a diff of a watch's scans raises: new subdomains,
+// emails and published keys, and new identities, clusters none of whose
+// members the previous scan had found.
+func Alerts(watchID int64, d *scandiff.Diff, clusters []database.IdentityCluster) []database.WatchAlert {
+	alert := func(kind string, t scandiff.Trace) database.WatchAlert {
+		return database.WatchAlert{
+			WatchID:        watchID,
+			ScanID:         d.New.ID,
+			PreviousScanID: d.Old.ID,
+			Kind:           kind,
+			TraceID:        t.ID,
+			Value:          t.Value,
+			Type:           t.Type,
+		}
+	}
+
+	alerts := []database.WatchAlert{}
+	added := make(map[int64]scandiff.Trace)
+	for _, change := range d.Types {
+		for _, t := range change.Added {
+			added[t.ID] = t
+			if kind, ok := alertKinds[t.Type]; ok {
+				alerts = append(alerts, alert(kind, t))
+			}
+		}
+	}
+
+	for _, c := range clusters {
+		if len(c.MemberIDs) == 0 {
+			continue
+		}
+		isNew := true
+		for _, id := range c.MemberIDs {
+			if _, ok := added[id]; !ok {
+				isNew = false
+				break
+			}
+		}
+		if isNew {
+			a := alert(database.AlertNewIdentity, added[c.MemberIDs[0]])
+			a.Value = c.Label
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,105 @@
+package watch
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/app/deeper/scandiff"
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestAlerts_NewSubdomainsEmailsKeysAndIdentities(t *testing.T) {
+	domain := database.Trace{ID: 1, Value: "acme.com", Type: entities.Domain}
+	sub := database.Trace{ID: 2, Value: "dev.acme.com", Type: entities.Subdomain}
+	email := database.Trace{ID: 3, Value: "ops@acme.com", Type: entities.Email}
+	key := database.Trace{ID: 4, Value: "ssh-ed25519 AAAA", Type: entities.SSHKey}
+	ip := database.Trace{ID: 5, Value: "203.0.113.7", Type: entities.IpAddr}
+
+	d := scandiff.Compare(
+		scandiff.Graph{Session: database.ScanSession{ID: 41}, Nodes: []database.Trace{domain, email}},
+		scandiff.Graph{Session: database.ScanSession{ID: 42}, Nodes: []database.Trace{domain, sub, email, key, ip}},
+	)
+	clusters := []database.IdentityCluster{
+		{Label: "ops@acme.com", MemberIDs: []int64{3, 4}},
+		{Label: "ssh-ed25519 AAAA", MemberIDs: []int64{4}},
+	}
+
+	alerts := Alerts(7, d, clusters)
+	kinds := make(map[string][]string)
+	for _, a := range alerts {
+		assert.Equal(t, int64(7), a.WatchID)
+		assert.Equal(t, int64(42), a.ScanID)
+		assert.Equal(t, int64(41), a.PreviousScanID)
+		kinds[a.Kind] = append(kinds[a.Kind], a.Value)
+	}
+	assert.Equal(t, map[string][]string{
+		database.AlertNewSubdomain: {"dev.acme.com"},
+		database.AlertExposedKey:   {"ssh-ed25519 AAAA"},
+		database.AlertNewIdentity:  {"ssh-ed25519 AAAA"},
+	}, kinds, "known emails, IPs and identities with a known member raise nothing")
+}
+
+func TestRunner_RescansDueWatchesAndStoresAlerts(t *testing.T) {
+	db, err := database.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	repo := database.NewRepository(db)
+
+	seed := entities.Trace{Value: "acme.com", Type: entities.Domain}
+	found := [][]string{{"www.acme.com"}, {"www.acme.com", "dev.acme.com"}}
+	scans := 0
+	scan := func(ctx context.Context, w database.Watch) (*database.ScanSession, error) {
+		session, err := repo.CreateScanSession(w.Input)
+		require.NoError(t, err)
+		seedID, err := repo.GetOrCreateTrace(seed)
+		require.NoError(t, err)
+		require.NoError(t, repo.InsertEdge(&database.TraceEdge{ChildTraceID: seedID, PluginName: database.SeedPluginName, ScanID: session.ID, DiscoveredAt: time.Now()}))
+		var discoveries []entities.Discovery
+		for _, sub := range found[scans] {
+			discoveries = append(discoveries, entities.Discovery{Parent: seed, PluginName: "CrtShPlugin", Child: entities.Trace{Value: sub, Type: entities.Subdomain}})
+		}
+		require.NoError(t, repo.PersistDiscoveries(session.ID, discoveries))
+		scans++
+		session.Status = "completed"
+		require.NoError(t, repo.UpdateScanSession(session))
+		return session, nil
+	}
+
+	now := time.Now()
+	runner := NewRunner(repo, scan)
+	runner.now = func() time.Time { return now }
+	require.NoError(t, repo.CreateWatch(&database.Watch{Input: "acme.com", IntervalSeconds: 3600, NextRunAt: now}))
+
+	var results []Result
+	report := func(r Result) { results = append(results, r) }
+	ran, err := runner.RunDue(context.Background(), report)
+	require.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	require.Len(t, results, 1)
+	assert.Nil(t, results[0].Previous, "the first scan is the baseline")
+	assert.Empty(t, results[0].Alerts)
+
+	ran, err = runner.RunDue(context.Background(), report)
+	require.NoError(t, err)
+	assert.Zero(t, ran, "not due again for an hour")
+
+	now = now.Add(time.Hour)
+	ran, err = runner.RunDue(context.Background(), report)
+	require.NoError(t, err)
+	assert.Equal(t, 1, ran)
+	require.Len(t, results, 2)
+	assert.Equal(t, results[0].Scan.ID, results[1].Previous.ID)
+	require.Len(t, results[1].Alerts, 1)
+	assert.Equal(t, "dev.acme.com", results[1].Alerts[0].Value)
+
+	history, err := repo.GetWatchAlerts(0, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, database.AlertNewSubdomain, history[0].Kind)
+}
@@ -0,0 +1,278 @@
+// Package audit keeps a tamper-evident log of every outbound contact a scan
+// makes: each HTTP request, DNS lookup and WHOIS query, the plugin that made
+// it and where it went. WHOIS on port 43 is the only raw TCP connection a
+// plugin opens, so there is no kind for other TCP dials; notifications to
+// the operator's own sinks are not contacts with the scan's subject and
+// are not logged.
+//
+// Entries are hash-chained. Each entry's hash covers its own fields and the
+// hash of the entry before it, and the first entry chains onto a genesis
+// hash of the scan's input, operator and engagement reference. Editing,
+// inserting, reordering or deleting an entry, or changing whose authority
+// the scan ran under, breaks every hash after the change; Verify finds the
+// first break. Deleting entries from the end of the log breaks no hash, so
+// the store also keeps the log's length and last hash with the scan, and
+// VerifyHead checks the log against them.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Kind is the protocol of an outbound contact.
+type Kind string
+
+const (
+	KindHTTP  Kind = "http"
+	KindDNS   Kind = "dns"
+	KindWHOIS Kind = "whois"
+)
+
+// Entry is one outbound contact. Method is the HTTP method, the DNS record
+// type or "QUERY" for WHOIS. Status is the HTTP status code, or "ok" or
+// "error" for other protocols. Byte counts are what deeper sent and read;
+// DNS lookups go through the system resolver and count none.
+type Entry struct {
+	ScanID        int64     `json:"scan_id"`
+	Seq           int64     `json:"seq"`
+	Time          time.Time `json:"time"`
+	Plugin        string    `json:"plugin"`
+	Kind          Kind      `json:"kind"`
+	Host          string    `json:"host"`
+	Method        string    `json:"method"`
+	URL           string    `json:"url,omitempty"`
+	Status        string    `json:"status"`
+	BytesSent     int64     `json:"bytes_sent"`
+	BytesReceived int64     `json:"bytes_received"`
+	Error         string    `json:"error,omitempty"`
+	PrevHash      string    `json:"prev_hash"`
+	Hash          string    `json:"hash"`
+}
+
+// TimeFormat is how entry times are hashed and stored: UTC with nanoseconds,
+// so a stored entry hashes the same when read back.
+const TimeFormat = time.RFC3339Nano
+
+// Genesis is the hash the first entry of a scan chains onto. It binds the
+// log to the scan and to whose authority the scan ran under.
+func Genesis(scanID int64, input, operator, engagement string) string {
+	return digest(struct {
+		ScanID     int64  `json:"scan_id"`
+		Input      string `json:"input"`
+		Operator   string `json:"operator"`
+		Engagement string `json:"engagement"`
+	}{scanID, input, operator, engagement})
+}
+
+// Chain makes e the entry after the one hashed prevHash, numbered seq, and
+// sets its hash.
+func (e *Entry) Chain(seq int64, prevHash string) {
+	e.Time = e.Time.UTC()
+	e.Seq = seq
+	e.PrevHash = prevHash
+	e.Hash = e.ComputeHash()
+}
+
+// ComputeHash returns the hash e should have given its fields and PrevHash.
+func (e *Entry) ComputeHash() string {
+	return digest(struct {
+		ScanID        int64  `json:"scan_id"`
+		Seq           int64  `json:"seq"`
+		Time          string `json:"time"`
+		Plugin        string `json:"plugin"`
+		Kind          Kind   `json:"kind"`
+		Host          string `json:"host"`
+		Method        string `json:"method"`
+		URL           string `json:"url"`
+		Status        string `json:"status"`
+		BytesSent     int64  `json:"bytes_sent"`
+		BytesReceived int64  `json:"bytes_received"`
+		Error         string `json:"error"`
+		PrevHash      string `json:"prev_hash"`
+	}{
+		e.ScanID, e.Seq, e.Time.UTC().Format(TimeFormat), e.Plugin, e.Kind, e.Host, e.Method, e.URL,
+		e.Status, e.BytesSent, e.BytesReceived, e.Error, e.PrevHash,
+	})
+}
+
+func digest(v any) string {
+	// Marshalling a struct of strings and integers cannot fail, and struct
+	// fields always encode in declaration order.
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Verify checks that entries, in order, form an unbroken chain from
+// genesis numbered from 1. The error names the first entry that does not.
+func Verify(genesis string, entries []Entry) error {
+	prev := genesis
+	for i, e := range entries {
+		seq := int64(i + 1)
+		switch {
+		case e.Seq != seq:
+			return fmt.Errorf("audit entry %d is missing: found entry %d in its place", seq, e.Seq)
+		case e.PrevHash != prev:
+			return fmt.Errorf("audit entry %d does not follow entry %d: the log was altered before it", seq, seq-1)
+		case e.ComputeHash() != e.Hash:
+			return fmt.Errorf("audit entry %d was altered: its hash does not match its contents", seq)
+		}
+		prev = e.Hash
+	}
+	return nil
+}
+
+// VerifyHead is Verify for a log recorded as count entries ending in the
+// hash head. It also finds entries cut from the end of the log, which
+// leave the rest of the chain intact.
+func VerifyHead(genesis string, entries []Entry, count int64, head string) error {
+	if err := Verify(genesis, entries); err != nil {
+		return err
+	}
+	n := int64(len(entries))
+	switch {
+	case n < count:
+		return fmt.Errorf("audit log ends at entry %d but the scan recorded %d: the log was cut short", n, count)
+	case n > count:
+		return fmt.Errorf("audit log has %d entries but the scan recorded %d", n, count)
+	case n > 0 && entries[n-1].Hash != head:
+		return fmt.Errorf("audit entry %d is not the last entry recorded with the scan", n)
+	}
+	return nil
+}
+
+// Store appends an entry to its scan's log, chaining it onto the scan's
+// last entry with Entry.Chain. Appends to one scan must be serialized.
+type Store interface {
+	AppendAuditEntry(e *Entry) error
+}
+
+// Log records the contacts of one scan.
+type Log struct {
+	scanID int64
+	store  Store
+}
+
+// NewLog returns the log of scanID, kept in store.
+func NewLog(scanID int64, store Store) *Log {
+	return &Log{scanID: scanID, store: store}
+}
+
+type logKey struct{}
+
+type pluginKey struct{}
+
+// WithLog returns a context whose outbound contacts are recorded in l.
+// The engine sets it on a scan's context and the processor carries it to
+// the plugins, like the scan's event emitter.
+func WithLog(ctx context.Context, l *Log) context.Context {
+	return context.WithValue(ctx, logKey{}, l)
+}
+
+// LogFrom returns ctx's log, or nil.
+func LogFrom(ctx context.Context) *Log {
+	l, _ := ctx.Value(logKey{}).(*Log)
+	return l
+}
+
+// WithPlugin returns a context whose contacts are attributed to plugin.
+func WithPlugin(ctx context.Context, plugin string) context.Context {
+	return context.WithValue(ctx, pluginKey{}, plugin)
+}
+
+// Record appends e to the log of ctx's scan, attributed to ctx's plugin.
+// It does nothing outside a scan. A contact that cannot be recorded is
+// logged rather than failing the plugin that made it.
+func Record(ctx context.Context, e Entry) {
+	l := LogFrom(ctx)
+	if l == nil {
+		return
+	}
+	e.ScanID = l.scanID
+	if plugin, ok := ctx.Value(pluginKey{}).(string); ok {
+		e.Plugin = plugin
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if err := l.store.AppendAuditEntry(&e); err != nil {
+		log.Error().Err(err).Msgf("Failed to record %s contact with %s in the audit log of scan %d", e.Kind, e.Host, l.scanID)
+	}
+}
+
+// HTTPStatus formats an HTTP status code for Entry.Status.
+func HTTPStatus(code int) string {
+	return strconv.Itoa(code)
+}
+
+// Outcome returns the Status and Error of a non-HTTP contact.
+func Outcome(err error) (status, message string) {
+	if err != nil {
+		return "error", err.Error()
+	}
+	return "ok", ""
+}
+
+// redacted replaces credentials in recorded URLs.
+const redacted = "REDACTED"
+
+// sensitiveParams are query parameter names, lowercased with separators
+// removed, whose values are credentials. Names ending in one of
+// sensitiveSuffixes, such as api_key or access_token, are too.
+var (
+	sensitiveParams   = []string{"auth", "pass", "passwd", "sig", "signature", "session", "sessionid"}
+	sensitiveSuffixes = []string{"key", "token", "secret", "password"}
+)
+
+// RedactURL returns u with its password and any credential-looking query
+// values replaced, keeping everything needed to tell what was requested.
+func RedactURL(u *url.URL) string {
+	r := *u
+	if r.User != nil {
+		if _, ok := r.User.Password(); ok {
+			r.User = url.UserPassword(r.User.Username(), redacted)
+		}
+	}
+	if r.RawQuery != "" {
+		query := r.Query()
+		changed := false
+		for name, values := range query {
+			if !isSensitiveParam(name) {
+				continue
+			}
+			for i := range values {
+				values[i] = redacted
+			}
+			changed = true
+		}
+		if changed {
+			r.RawQuery = query.Encode()
+		}
+	}
+	r.Fragment = ""
+	return r.String()
+}
+
+func isSensitiveParam(name string) bool {
+	normalized := strings.NewReplacer("_", "", "-", "", ".", "").Replace(strings.ToLower(name))
+	if slices.Contains(sensitiveParams, normalized) {
+		return true
+	}
+	for _, suffix := range sensitiveSuffixes {
+		if strings.HasSuffix(normalized, suffix) {
+			return true
+		}
+	}
+	return false
+}
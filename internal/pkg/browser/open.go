@@ -1,4 +1,5 @@
-// Package browser opens a local file in the user's default web browser.
+// Package browser opens a local file or URL in the user's default web
+// browser.
 package browser
 
 import (
@@ -12,7 +13,8 @@ import (
 // way to launch the default browser.
 var ErrUnsupportedOS = errors.New("unsupported OS for opening a browser")
 
-// Open launches the OS default handler for the given local file path.
+// Open launches the OS default handler for the given local file path or
+// URL.
 func Open(path string) error {
 	name, args, err := commandFor(runtime.GOOS, path)
 	if err != nil {
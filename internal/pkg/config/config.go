@@ -1,8 +1,10 @@
 package config
 
 import (
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -22,6 +24,31 @@ type Config struct {
 	// Optional plugin credentials (empty = unauthenticated requests)
 	GravatarAPIKey string
 	GitHubToken    string
+
+	// NotifyConfigPath is the notification sinks file; empty means
+	// ~/.deeper/notify.json.
+	NotifyConfigPath string
+
+	// RetentionConfigPath is the retention policy file; empty means
+	// ~/.deeper/retention.json.
+	RetentionConfigPath string
+
+	// DBPassphrase or DBKeyFile, if set, encrypt the database and stored
+	// graph reports at rest; see the seal package.
+	DBPassphrase string
+	DBKeyFile    string
+
+	// APITokens are the bearer tokens "deeper serve" accepts.
+	APITokens []string
+
+	// OTLPEndpoint is the OTLP/HTTP URL spans are exported to; empty means
+	// no OTLP export. OTLPHeaders go with every export, e.g. for auth.
+	OTLPEndpoint string
+	OTLPHeaders  map[string]string
+	// SpanFile is a file spans are appended to as OTLP JSON; empty means none.
+	SpanFile string
+	// ServiceName labels exported spans.
+	ServiceName string
 }
 
 // WorkerPoolConfig holds worker pool specific configuration
@@ -76,6 +103,7 @@ func DefaultConfig() *Config {
 		UserAgent:          "Deeper/1.0",
 		MaxRetries:         3,
 		RetryDelay:         1 * time.Second,
+		ServiceName:        "deeper",
 		WorkerPoolConfig: WorkerPoolConfig{
 			// 6 plugins register on entities.Username; a batch of MaxConcurrency
 			// traces that are all usernames can submit up to 10*6=60 tasks at
@@ -200,9 +228,68 @@ func LoadConfig() *Config {
 		config.GitHubToken = githubToken
 	}
 
+	if notifyConfig := os.Getenv("DEEPER_NOTIFY_CONFIG"); notifyConfig != "" {
+		config.NotifyConfigPath = notifyConfig
+	}
+
+	if retentionConfig := os.Getenv("DEEPER_RETENTION_CONFIG"); retentionConfig != "" {
+		config.RetentionConfigPath = retentionConfig
+	}
+
+	if passphrase := os.Getenv("DEEPER_DB_PASSPHRASE"); passphrase != "" {
+		config.DBPassphrase = passphrase
+	}
+
+	if keyFile := os.Getenv("DEEPER_DB_KEY_FILE"); keyFile != "" {
+		config.DBKeyFile = keyFile
+	}
+
+	if apiTokens := os.Getenv("DEEPER_API_TOKENS"); apiTokens != "" {
+		for _, token := range strings.Split(apiTokens, ",") {
+			if token = strings.TrimSpace(token); token != "" {
+				config.APITokens = append(config.APITokens, token)
+			}
+		}
+	}
+
+	loadTelemetryConfig(config)
+
 	return config
 }
 
+// loadTelemetryConfig reads span export settings from the standard
+// OpenTelemetry variables, plus DEEPER_SPAN_FILE for file export.
+func loadTelemetryConfig(config *Config) {
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"); endpoint != "" {
+		config.OTLPEndpoint = endpoint
+	} else if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		// The generic endpoint is a base URL that signal paths are appended to.
+		config.OTLPEndpoint = strings.TrimRight(endpoint, "/") + "/v1/traces"
+	}
+
+	if headers := os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"); headers != "" {
+		config.OTLPHeaders = make(map[string]string)
+		for _, pair := range strings.Split(headers, ",") {
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				continue
+			}
+			if unescaped, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil {
+				value = unescaped
+			}
+			config.OTLPHeaders[strings.TrimSpace(key)] = value
+		}
+	}
+
+	if spanFile := os.Getenv("DEEPER_SPAN_FILE"); spanFile != "" {
+		config.SpanFile = spanFile
+	}
+
+	if serviceName := os.Getenv("OTEL_SERVICE_NAME"); serviceName != "" {
+		config.ServiceName = serviceName
+	}
+}
+
 // loadWorkerPoolConfig loads worker pool configuration from environment variables
 func loadWorkerPoolConfig(config *Config) {
 	if maxWorkers := os.Getenv("DEEPER_WORKER_POOL_MAX_WORKERS"); maxWorkers != "" {
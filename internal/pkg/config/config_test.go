@@ -148,3 +148,39 @@ func TestLoadConfigPluginCredentials(t *testing.T) {
 		t.Errorf("Expected GitHubToken to be set, got %q", cfg.GitHubToken)
 	}
 }
+
+func TestLoadConfigAPITokens(t *testing.T) {
+	t.Setenv("DEEPER_API_TOKENS", "first, second,,")
+
+	cfg := LoadConfig()
+
+	if len(cfg.APITokens) != 2 || cfg.APITokens[0] != "first" || cfg.APITokens[1] != "second" {
+		t.Errorf("Expected APITokens [first second], got %q", cfg.APITokens)
+	}
+}
+
+func TestLoadConfigTelemetry(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318/")
+	t.Setenv("OTEL_EXPORTER_OTLP_HEADERS", "Authorization=Bearer%20abc, x-tenant = blue,broken")
+	t.Setenv("DEEPER_SPAN_FILE", "/tmp/spans.jsonl")
+
+	cfg := LoadConfig()
+
+	if cfg.OTLPEndpoint != "http://collector:4318/v1/traces" {
+		t.Errorf("Expected the traces path appended to the base endpoint, got %q", cfg.OTLPEndpoint)
+	}
+	if len(cfg.OTLPHeaders) != 2 || cfg.OTLPHeaders["Authorization"] != "Bearer abc" || cfg.OTLPHeaders["x-tenant"] != "blue" {
+		t.Errorf("Expected two decoded headers, got %q", cfg.OTLPHeaders)
+	}
+	if cfg.SpanFile != "/tmp/spans.jsonl" {
+		t.Errorf("Expected SpanFile to be set, got %q", cfg.SpanFile)
+	}
+	if cfg.ServiceName != "deeper" {
+		t.Errorf("Expected the default service name, got %q", cfg.ServiceName)
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "http://traces:4318/custom")
+	if cfg := LoadConfig(); cfg.OTLPEndpoint != "http://traces:4318/custom" {
+		t.Errorf("Expected the traces endpoint to be used as is, got %q", cfg.OTLPEndpoint)
+	}
+}
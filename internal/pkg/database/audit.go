@@ -0,0 +1,110 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/audit"
+)
+
+// AuditGenesis is the hash the scan's audit log chains onto (see
+// audit.Genesis).
+func (s *ScanSession) AuditGenesis() string {
+	return audit.Genesis(s.ID, s.Input, s.Operator, s.Engagement)
+}
+
+// AppendAuditEntry chains e onto the last entry of its scan's audit log,
+// or onto the scan's genesis hash if it is the first, and stores it as
+// the scan's audit head.
+func (r *Repository) AppendAuditEntry(e *audit.Entry) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	tx, err := r.db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var seq int64
+	var prev string
+	err = tx.QueryRow(
+		`SELECT seq, hash FROM audit_log WHERE scan_id = ? ORDER BY seq DESC LIMIT 1`, e.ScanID,
+	).Scan(&seq, &prev)
+	switch {
+	case err == sql.ErrNoRows:
+		session := ScanSession{ID: e.ScanID}
+		err = tx.QueryRow(
+			`SELECT input, operator, engagement FROM scan_sessions WHERE id = ?`, e.ScanID,
+		).Scan(&session.Input, &session.Operator, &session.Engagement)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("scan %d not found", e.ScanID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get scan session: %w", err)
+		}
+		prev = session.AuditGenesis()
+	case err != nil:
+		return fmt.Errorf("failed to get last audit entry: %w", err)
+	}
+
+	e.Chain(seq+1, prev)
+	if _, err := tx.Exec(`
+		INSERT INTO audit_log (scan_id, seq, contacted_at, plugin, kind, host, method, url, status,
+			bytes_sent, bytes_received, error, prev_hash, hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.ScanID, e.Seq, e.Time.Format(audit.TimeFormat), e.Plugin, string(e.Kind), e.Host, e.Method, e.URL, e.Status,
+		e.BytesSent, e.BytesReceived, e.Error, e.PrevHash, e.Hash,
+	); err != nil {
+		return fmt.Errorf("failed to insert audit entry: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE scan_sessions SET audit_entries = ?, audit_head = ? WHERE id = ?`,
+		e.Seq, e.Hash, e.ScanID); err != nil {
+		return fmt.Errorf("failed to record audit head: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// VerifyAuditLog checks entries, the scan's audit log, against its
+// genesis and recorded head.
+func (s *ScanSession) VerifyAuditLog(entries []audit.Entry) error {
+	return audit.VerifyHead(s.AuditGenesis(), entries, s.AuditEntries, s.AuditHead)
+}
+
+// GetAuditLog returns a scan's audit log in order.
+func (r *Repository) GetAuditLog(scanID int64) ([]audit.Entry, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query(`
+		SELECT scan_id, seq, contacted_at, plugin, kind, host, method, url, status,
+			bytes_sent, bytes_received, error, prev_hash, hash
+		FROM audit_log WHERE scan_id = ? ORDER BY seq`, scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	entries := []audit.Entry{}
+	for rows.Next() {
+		var e audit.Entry
+		var contactedAt, kind string
+		if err := rows.Scan(&e.ScanID, &e.Seq, &contactedAt, &e.Plugin, &kind, &e.Host, &e.Method, &e.URL, &e.Status,
+			&e.BytesSent, &e.BytesReceived, &e.Error, &e.PrevHash, &e.Hash); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		// An unparsable time is left zero; the entry then fails
+		// verification instead of hiding the damage.
+		e.Time, _ = time.Parse(audit.TimeFormat, contactedAt)
+		e.Kind = audit.Kind(kind)
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read audit log rows: %w", err)
+	}
+	return entries, nil
+}
@@ -0,0 +1,114 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/audit"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestRepository_AuditLogChainsAndDetectsTampering(t *testing.T) {
+	repo := newTestRepo(t)
+	session, err := repo.CreateScanSessionFor("jdoe@acme.com", "jsmith", "ENG-114")
+	require.NoError(t, err)
+	other := newTestScan(t, repo)
+
+	contacted := time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.FixedZone("CET", 3600))
+	for _, e := range []audit.Entry{
+		{ScanID: session.ID, Time: contacted, Plugin: "GravatarPlugin", Kind: audit.KindHTTP, Host: "gravatar.com",
+			Method: "GET", URL: "https://gravatar.com/x.json", Status: "200", BytesReceived: 512},
+		{ScanID: other, Time: contacted, Kind: audit.KindDNS, Host: "acme.com", Method: "TXT", Status: "ok"},
+		{ScanID: session.ID, Time: contacted.Add(time.Second), Plugin: "WhoisPlugin", Kind: audit.KindWHOIS,
+			Host: "whois.iana.org", Method: "QUERY", Status: "error", Error: "i/o timeout"},
+	} {
+		require.NoError(t, repo.AppendAuditEntry(&e))
+	}
+	assert.Error(t, repo.AppendAuditEntry(&audit.Entry{ScanID: 9999, Kind: audit.KindDNS}), "no such scan")
+
+	got, err := repo.GetScanSession(session.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "jsmith", got.Operator)
+	assert.Equal(t, "ENG-114", got.Engagement)
+
+	entries, err := repo.GetAuditLog(session.ID)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, []int64{1, 2}, []int64{entries[0].Seq, entries[1].Seq})
+	assert.Equal(t, got.AuditGenesis(), entries[0].PrevHash)
+	assert.True(t, contacted.Equal(entries[0].Time), "times round-trip to the nanosecond")
+	assert.Equal(t, int64(512), entries[0].BytesReceived)
+	require.NoError(t, audit.Verify(got.AuditGenesis(), entries))
+	assert.Equal(t, int64(2), got.AuditEntries)
+	assert.Equal(t, entries[1].Hash, got.AuditHead)
+	require.NoError(t, got.VerifyAuditLog(entries))
+
+	otherEntries, err := repo.GetAuditLog(other)
+	require.NoError(t, err)
+	require.Len(t, otherEntries, 1)
+	assert.Equal(t, int64(1), otherEntries[0].Seq, "each scan has its own chain")
+
+	_, err = repo.db.db.Exec(`UPDATE audit_log SET host = 'example.org' WHERE scan_id = ? AND seq = 1`, session.ID)
+	require.NoError(t, err)
+	entries, err = repo.GetAuditLog(session.ID)
+	require.NoError(t, err)
+	assert.ErrorContains(t, audit.Verify(got.AuditGenesis(), entries), "audit entry 1 was altered")
+
+	got.Engagement = "ENG-999"
+	assert.Error(t, audit.Verify(got.AuditGenesis(), entries), "a changed engagement breaks the chain")
+}
+
+func TestRepository_AuditLogDetectsTruncation(t *testing.T) {
+	repo := newTestRepo(t)
+	scanID := newTestScan(t, repo)
+	for _, host := range []string{"a.example", "b.example"} {
+		require.NoError(t, repo.AppendAuditEntry(&audit.Entry{ScanID: scanID, Time: time.Now(), Kind: audit.KindDNS, Host: host, Status: "ok"}))
+	}
+
+	_, err := repo.db.db.Exec(`DELETE FROM audit_log WHERE scan_id = ? AND seq = 2`, scanID)
+	require.NoError(t, err)
+	session, err := repo.GetScanSession(scanID)
+	require.NoError(t, err)
+	entries, err := repo.GetAuditLog(scanID)
+	require.NoError(t, err)
+	require.NoError(t, audit.Verify(session.AuditGenesis(), entries), "what is left is a valid chain")
+	assert.ErrorContains(t, session.VerifyAuditLog(entries), "the log was cut short")
+}
+
+func TestRepository_ScanQueriesReadAuthority(t *testing.T) {
+	repo := newTestRepo(t)
+	seed := entities.Trace{Value: "jdoe@acme.com", Type: entities.Email}
+	seedID, err := repo.GetOrCreateTrace(seed)
+	require.NoError(t, err)
+
+	var ids []int64
+	for range 2 {
+		session, err := repo.CreateScanSessionFor(seed.Value, "jsmith", "ENG-114")
+		require.NoError(t, err)
+		require.NoError(t, repo.InsertEdge(&TraceEdge{ChildTraceID: seedID, PluginName: SeedPluginName, ScanID: session.ID, DiscoveredAt: time.Now()}))
+		session.Status = "completed"
+		require.NoError(t, repo.UpdateScanSession(session))
+		ids = append(ids, session.ID)
+	}
+
+	authority := func(s *ScanSession) {
+		t.Helper()
+		require.NotNil(t, s)
+		assert.Equal(t, "jsmith", s.Operator)
+		assert.Equal(t, "ENG-114", s.Engagement)
+	}
+	previous, err := repo.GetPreviousScan(seed.Value, ids[1])
+	require.NoError(t, err)
+	authority(previous)
+	scans, err := repo.GetTraceScans(seedID)
+	require.NoError(t, err)
+	require.Len(t, scans, 2)
+	authority(&scans[0])
+	overlaps, err := repo.GetPriorOverlaps(ids[1])
+	require.NoError(t, err)
+	require.Len(t, overlaps, 1)
+	authority(&overlaps[0].Scan)
+}
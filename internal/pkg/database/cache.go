@@ -15,6 +15,18 @@ type Cache struct {
 	repo *Repository
 }
 
+// DeduplicationPlugin is the plugin name, and trace type, under which the
+// worker pool caches the tasks it has run.
+const DeduplicationPlugin = "deduplication"
+
+// DeduplicationMarker returns the trace the worker pool caches to record
+// that it ran the task with the given identity. A plugin's task on a trace
+// is identified as "<trace value>:<plugin>".
+func DeduplicationMarker(identity string) entities.Trace {
+	hash := sha256.Sum256([]byte(identity))
+	return entities.Trace{Value: hex.EncodeToString(hash[:8]), Type: DeduplicationPlugin}
+}
+
 // NewCache creates a new cache instance
 func NewCache(repo *Repository) *Cache {
 	return &Cache{repo: repo}
@@ -0,0 +1,321 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// CreateCase stores a new open case with its tags and seeds and fills in
+// its ID, status and creation time. Case names are unique.
+func (r *Repository) CreateCase(c *Case) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	tx, err := r.db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var existing int64
+	err = tx.QueryRow(`SELECT id FROM cases WHERE name = ?`, c.Name).Scan(&existing)
+	if err == nil {
+		return fmt.Errorf("case %q already exists", c.Name)
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to look up case: %w", err)
+	}
+
+	now := time.Now()
+	result, err := tx.Exec(
+		`INSERT INTO cases (name, owner, authorization_ref, status, created_at) VALUES (?, ?, ?, ?, ?)`,
+		c.Name, c.Owner, c.Authorization, CaseOpen, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create case: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get case ID: %w", err)
+	}
+	if err := insertCaseTags(tx, id, c.Tags); err != nil {
+		return err
+	}
+	if err := insertCaseSeeds(tx, id, c.Seeds, now); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	c.ID = id
+	c.Status = CaseOpen
+	c.CreatedAt = now
+	c.ClosedAt = nil
+	return nil
+}
+
+// GetCase returns the case with the given ID and everything in it, or nil
+// if there is none.
+func (r *Repository) GetCase(id int64) (*Case, error) {
+	return r.getCase(`WHERE id = ?`, id)
+}
+
+// GetCaseByName returns the case with the given name, or nil if there is
+// none.
+func (r *Repository) GetCaseByName(name string) (*Case, error) {
+	return r.getCase(`WHERE name = ?`, name)
+}
+
+// GetCases returns every case, newest first.
+func (r *Repository) GetCases() ([]Case, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query(
+		`SELECT id, name, owner, authorization_ref, status, created_at, closed_at FROM cases ORDER BY id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query cases: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var cases []Case
+	for rows.Next() {
+		var c Case
+		if err := rows.Scan(&c.ID, &c.Name, &c.Owner, &c.Authorization, &c.Status, &c.CreatedAt, &c.ClosedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan case: %w", err)
+		}
+		cases = append(cases, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read case rows: %w", err)
+	}
+	_ = rows.Close()
+
+	for i := range cases {
+		if err := r.loadCaseContents(&cases[i]); err != nil {
+			return nil, err
+		}
+	}
+	return cases, nil
+}
+
+// AddCaseScans attaches scans to an open case. Each scan's input is added
+// to the case's seeds too. Adding a scan twice is a no-op.
+func (r *Repository) AddCaseScans(caseID int64, scanIDs ...int64) error {
+	return r.updateOpenCase(caseID, func(tx *sql.Tx, now time.Time) error {
+		for _, scanID := range scanIDs {
+			var input string
+			err := tx.QueryRow(`SELECT input FROM scan_sessions WHERE id = ?`, scanID).Scan(&input)
+			if err == sql.ErrNoRows {
+				return fmt.Errorf("scan %d not found", scanID)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get scan session: %w", err)
+			}
+			if _, err := tx.Exec(
+				`INSERT OR IGNORE INTO case_scans (case_id, scan_id, added_at) VALUES (?, ?, ?)`,
+				caseID, scanID, now,
+			); err != nil {
+				return fmt.Errorf("failed to add scan to case: %w", err)
+			}
+			if err := insertCaseSeeds(tx, caseID, []string{input}, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddCaseSeeds adds seeds, inputs the investigation starts from, to an
+// open case.
+func (r *Repository) AddCaseSeeds(caseID int64, seeds ...string) error {
+	return r.updateOpenCase(caseID, func(tx *sql.Tx, now time.Time) error {
+		return insertCaseSeeds(tx, caseID, seeds, now)
+	})
+}
+
+// AddCaseTags tags a case. Tags it already has are ignored.
+func (r *Repository) AddCaseTags(caseID int64, tags ...string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	tx, err := r.db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := insertCaseTags(tx, caseID, tags); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// AddCaseNote adds a note to a case, open or closed.
+func (r *Repository) AddCaseNote(caseID int64, text string) (*CaseNote, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	result, err := r.db.db.Exec(
+		`INSERT INTO case_notes (case_id, text, created_at) VALUES (?, ?, ?)`, caseID, text, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add case note: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get case note ID: %w", err)
+	}
+	return &CaseNote{ID: id, CaseID: caseID, Text: text, CreatedAt: now}, nil
+}
+
+// CloseCase marks a case closed. Closing a closed case keeps its original
+// closing time.
+func (r *Repository) CloseCase(caseID int64) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	_, err := r.db.db.Exec(
+		`UPDATE cases SET status = ?, closed_at = COALESCE(closed_at, ?) WHERE id = ?`,
+		CaseClosed, time.Now(), caseID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to close case: %w", err)
+	}
+	return nil
+}
+
+// updateOpenCase runs fn in a transaction after checking that the case
+// exists and is still open.
+func (r *Repository) updateOpenCase(caseID int64, fn func(tx *sql.Tx, now time.Time) error) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	tx, err := r.db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var name, status string
+	err = tx.QueryRow(`SELECT name, status FROM cases WHERE id = ?`, caseID).Scan(&name, &status)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("case %d not found", caseID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get case: %w", err)
+	}
+	if status == CaseClosed {
+		return fmt.Errorf("case %q is closed", name)
+	}
+
+	if err := fn(tx, time.Now()); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) getCase(where string, arg interface{}) (*Case, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var c Case
+	err := r.db.db.QueryRow(
+		`SELECT id, name, owner, authorization_ref, status, created_at, closed_at FROM cases `+where, arg,
+	).Scan(&c.ID, &c.Name, &c.Owner, &c.Authorization, &c.Status, &c.CreatedAt, &c.ClosedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get case: %w", err)
+	}
+	if err := r.loadCaseContents(&c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// loadCaseContents fills in a case's tags, seeds, scans and notes. The
+// caller holds the read lock.
+func (r *Repository) loadCaseContents(c *Case) error {
+	c.Tags = []string{}
+	c.Seeds = []string{}
+	c.ScanIDs = []int64{}
+	c.Notes = []CaseNote{}
+
+	if err := r.queryCaseColumn(`SELECT tag FROM case_tags WHERE case_id = ? ORDER BY tag`, c.ID, func(rows *sql.Rows) error {
+		var tag string
+		err := rows.Scan(&tag)
+		c.Tags = append(c.Tags, tag)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to load case tags: %w", err)
+	}
+	if err := r.queryCaseColumn(`SELECT seed FROM case_seeds WHERE case_id = ? ORDER BY added_at, seed`, c.ID, func(rows *sql.Rows) error {
+		var seed string
+		err := rows.Scan(&seed)
+		c.Seeds = append(c.Seeds, seed)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to load case seeds: %w", err)
+	}
+	if err := r.queryCaseColumn(`SELECT scan_id FROM case_scans WHERE case_id = ? ORDER BY scan_id`, c.ID, func(rows *sql.Rows) error {
+		var id int64
+		err := rows.Scan(&id)
+		c.ScanIDs = append(c.ScanIDs, id)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to load case scans: %w", err)
+	}
+	if err := r.queryCaseColumn(`SELECT id, case_id, text, created_at FROM case_notes WHERE case_id = ? ORDER BY id`, c.ID, func(rows *sql.Rows) error {
+		var note CaseNote
+		err := rows.Scan(&note.ID, &note.CaseID, &note.Text, &note.CreatedAt)
+		c.Notes = append(c.Notes, note)
+		return err
+	}); err != nil {
+		return fmt.Errorf("failed to load case notes: %w", err)
+	}
+	return nil
+}
+
+func (r *Repository) queryCaseColumn(query string, caseID int64, scan func(rows *sql.Rows) error) error {
+	rows, err := r.db.db.Query(query, caseID)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func insertCaseTags(tx *sql.Tx, caseID int64, tags []string) error {
+	for _, tag := range tags {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO case_tags (case_id, tag) VALUES (?, ?)`, caseID, tag); err != nil {
+			return fmt.Errorf("failed to tag case: %w", err)
+		}
+	}
+	return nil
+}
+
+func insertCaseSeeds(tx *sql.Tx, caseID int64, seeds []string, now time.Time) error {
+	for _, seed := range seeds {
+		if _, err := tx.Exec(
+			`INSERT OR IGNORE INTO case_seeds (case_id, seed, added_at) VALUES (?, ?, ?)`, caseID, seed, now,
+		); err != nil {
+			return fmt.Errorf("failed to add case seed: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository_CaseLifecycle(t *testing.T) {
+	repo := newTestRepo(t)
+
+	c := &Case{Name: "acme-2026", Owner: "jsmith", Authorization: "ENG-114", Tags: []string{"phishing"}, Seeds: []string{"acme.com"}}
+	require.NoError(t, repo.CreateCase(c))
+	assert.NotZero(t, c.ID)
+	assert.Equal(t, CaseOpen, c.Status)
+	assert.Error(t, repo.CreateCase(&Case{Name: "acme-2026"}), "names are unique")
+
+	scanA, err := repo.CreateScanSession("jdoe@acme.com")
+	require.NoError(t, err)
+	scanB, err := repo.CreateScanSession("acme.com")
+	require.NoError(t, err)
+	require.NoError(t, repo.AddCaseScans(c.ID, scanA.ID, scanB.ID, scanA.ID))
+	assert.Error(t, repo.AddCaseScans(c.ID, 999))
+	require.NoError(t, repo.AddCaseSeeds(c.ID, "jdoe"))
+	require.NoError(t, repo.AddCaseTags(c.ID, "phishing", "apt"))
+	_, err = repo.AddCaseNote(c.ID, "MX points at a bulletproof host")
+	require.NoError(t, err)
+
+	got, err := repo.GetCaseByName("acme-2026")
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "jsmith", got.Owner)
+	assert.Equal(t, "ENG-114", got.Authorization)
+	assert.Equal(t, []int64{scanA.ID, scanB.ID}, got.ScanIDs)
+	assert.ElementsMatch(t, []string{"acme.com", "jdoe@acme.com", "jdoe"}, got.Seeds, "scan inputs become seeds")
+	assert.Equal(t, []string{"apt", "phishing"}, got.Tags)
+	require.Len(t, got.Notes, 1)
+	assert.Equal(t, "MX points at a bulletproof host", got.Notes[0].Text)
+
+	require.NoError(t, repo.CloseCase(c.ID))
+	assert.ErrorContains(t, repo.AddCaseScans(c.ID, scanA.ID), "closed")
+	assert.ErrorContains(t, repo.AddCaseSeeds(c.ID, "other"), "closed")
+	_, err = repo.AddCaseNote(c.ID, "closing note")
+	assert.NoError(t, err, "notes are still allowed")
+
+	got, err = repo.GetCase(c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, CaseClosed, got.Status)
+	assert.NotNil(t, got.ClosedAt)
+
+	missing, err := repo.GetCaseByName("nope")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	cases, err := repo.GetCases()
+	require.NoError(t, err)
+	require.Len(t, cases, 1)
+	assert.Len(t, cases[0].Notes, 2)
+}
@@ -2,6 +2,7 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,8 @@ import (
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/seal"
 )
 
 // Database represents the main database interface
@@ -16,6 +19,17 @@ type Database struct {
 	db   *sql.DB
 	mu   sync.RWMutex
 	path string
+
+	// secret seals an encrypted database, which is held in memory; see
+	// NewEncryptedDatabase. It is nil for a plaintext database.
+	secret *seal.Secret
+	stamp  fileStamp
+	synced int64
+	stop   chan struct{}
+	done   chan struct{}
+	// conflict is where an encrypted database is sealed once another
+	// process has changed the file at path.
+	conflict string
 }
 
 // NewDatabase creates a new database connection
@@ -26,6 +40,14 @@ func NewDatabase(dbPath string) (*Database, error) {
 		return nil, fmt.Errorf("failed to create database directory: %w", err)
 	}
 
+	sealed, err := seal.IsSealedFile(dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database: %w", err)
+	}
+	if sealed {
+		return nil, fmt.Errorf("%s: %w", dbPath, ErrEncrypted)
+	}
+
 	// Open database connection
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
@@ -56,11 +78,25 @@ func NewDatabase(dbPath string) (*Database, error) {
 	return database, nil
 }
 
-// Close closes the database connection
+// Close closes the database connection, sealing an encrypted database to
+// disk first.
 func (d *Database) Close() error {
+	if d.stop != nil {
+		close(d.stop)
+		<-d.done
+		d.stop = nil
+	}
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	return d.db.Close()
+	var syncErr error
+	if d.secret != nil {
+		// A conflict is reported on Close even if the background sync
+		// already saved the last changes to the conflict file.
+		if syncErr = d.syncChangesLocked(); syncErr == nil {
+			syncErr = d.conflictError()
+		}
+	}
+	return errors.Join(syncErr, d.db.Close())
 }
 
 // GetDB returns the underlying sql.DB instance
@@ -0,0 +1,339 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+	"github.com/rs/zerolog/log"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/seal"
+)
+
+// syncInterval is how often an encrypted database seals new changes back
+// to disk between explicit syncs.
+const syncInterval = 30 * time.Second
+
+// ErrNotEncrypted is returned when a key is given for a database that is
+// stored in plaintext.
+var ErrNotEncrypted = errors.New("database is not encrypted")
+
+// ErrEncrypted is returned when a database that is encrypted is opened
+// without a key.
+var ErrEncrypted = errors.New("database is encrypted and no key was given")
+
+// NewEncryptedDatabase opens the database sealed with secret at dbPath,
+// creating it if it does not exist.
+//
+// The database is decrypted into memory and never written to disk in
+// plaintext. Changes are sealed back to dbPath by Sync and Close, and in
+// the background every 30 seconds; a database that was only read is not
+// written back. If another process replaced the file in the meantime,
+// this process's copy is saved beside it as a conflict file rather than
+// overwriting the other's changes, and so are its later changes.
+func NewEncryptedDatabase(dbPath string, secret *seal.Secret) (*Database, error) {
+	dir := filepath.Dir(dbPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	image, err := readImage(dbPath, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// Each connection to :memory: is its own database, so there must be
+	// exactly one and it must never be recycled.
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
+
+	if image != nil {
+		if err := restoreImage(db, image); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to enable foreign keys: %w", err)
+	}
+	if err := runMigrations(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	database := &Database{
+		db:     db,
+		path:   dbPath,
+		secret: secret,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	database.stamp, err = statStamp(dbPath)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	if image == nil {
+		if err := database.Sync(); err != nil {
+			_ = db.Close()
+			return nil, err
+		}
+	}
+	go database.syncLoop()
+	return database, nil
+}
+
+// Open opens the database at dbPath, sealed with secret if it is not nil
+// (see NewEncryptedDatabase).
+func Open(dbPath string, secret *seal.Secret) (*Database, error) {
+	if secret == nil {
+		return NewDatabase(dbPath)
+	}
+	return NewEncryptedDatabase(dbPath, secret)
+}
+
+// Encrypted reports whether the database is sealed at rest.
+func (d *Database) Encrypted() bool {
+	return d.secret != nil
+}
+
+// Sync seals the database to disk. It does nothing for a plaintext
+// database, which SQLite writes as it goes.
+func (d *Database) Sync() error {
+	if d.secret == nil {
+		return nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.syncLocked()
+}
+
+// syncChangesLocked seals the database if it changed since the last sync,
+// so that reading it never replaces the file under another process.
+func (d *Database) syncChangesLocked() error {
+	changes, err := d.changes()
+	if err != nil {
+		return err
+	}
+	if changes == d.synced {
+		return nil
+	}
+	return d.syncLocked()
+}
+
+// conflictError reports where the database was saved if another process
+// changed its file, or nil.
+func (d *Database) conflictError() error {
+	if d.conflict == "" {
+		return nil
+	}
+	return fmt.Errorf("%s was changed by another process; saved this process's copy to %s", d.path, d.conflict)
+}
+
+func (d *Database) changes() (int64, error) {
+	var changes int64
+	if err := d.db.QueryRow(`SELECT total_changes()`).Scan(&changes); err != nil {
+		return 0, fmt.Errorf("failed to count changes: %w", err)
+	}
+	return changes, nil
+}
+
+func (d *Database) syncLocked() error {
+	changes, err := d.changes()
+	if err != nil {
+		return err
+	}
+	image, err := serializeImage(d.db)
+	if err != nil {
+		return err
+	}
+
+	if d.conflict == "" {
+		current, err := statStamp(d.path)
+		if err != nil {
+			return err
+		}
+		if current != d.stamp {
+			d.conflict = fmt.Sprintf("%s.conflict-%s", d.path, time.Now().Format("20060102T150405"))
+		}
+	}
+	// Once the file has changed under this process, it is never written
+	// again; its changes go on to the conflict file until it is reopened.
+	if d.conflict != "" {
+		if err := d.secret.WriteFile(d.conflict, image, 0600); err != nil {
+			return err
+		}
+		d.synced = changes
+		return d.conflictError()
+	}
+
+	if err := d.secret.WriteFile(d.path, image, 0600); err != nil {
+		return err
+	}
+	if d.stamp, err = statStamp(d.path); err != nil {
+		return err
+	}
+	d.synced = changes
+	return nil
+}
+
+// syncLoop seals new changes every syncInterval until Close.
+func (d *Database) syncLoop() {
+	defer close(d.done)
+	ticker := time.NewTicker(syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-d.stop:
+			return
+		case <-ticker.C:
+			d.mu.Lock()
+			err := d.syncChangesLocked()
+			d.mu.Unlock()
+			if err != nil {
+				log.Error().Err(err).Msg("Failed to sync encrypted database")
+			}
+		}
+	}
+}
+
+// fileStamp identifies a version of the database file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func statStamp(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fileStamp{}, nil
+	}
+	if err != nil {
+		return fileStamp{}, fmt.Errorf("failed to stat database: %w", err)
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// readImage returns the SQLite image stored at path, opening it with
+// secret if it is sealed, or nil if there is no database yet. A nil secret
+// reads a plaintext database.
+func readImage(path string, secret *seal.Secret) ([]byte, error) {
+	sealed, err := seal.IsSealedFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read database: %w", err)
+	}
+	switch {
+	case sealed && secret == nil:
+		return nil, fmt.Errorf("%s: %w", path, ErrEncrypted)
+	case sealed:
+		image, err := secret.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt database: %w", err)
+		}
+		return image, nil
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if secret != nil {
+		return nil, fmt.Errorf("%s: %w", path, ErrNotEncrypted)
+	}
+	// Go through SQLite rather than reading the file, so that a hot
+	// journal is rolled back first.
+	db, err := NewDatabase(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = db.Close() }()
+	return serializeImage(db.db)
+}
+
+// Rekey rewrites the database at path sealed with to, having opened it
+// with from. A nil from reads a plaintext database and a nil to writes
+// one, so Rekey also encrypts and decrypts databases. The database must
+// not be open.
+func Rekey(path string, from, to *seal.Secret) error {
+	image, err := readImage(path, from)
+	if err != nil {
+		return err
+	}
+	if image == nil {
+		return fmt.Errorf("database %s not found", path)
+	}
+	if err := to.WriteFile(path, image, 0600); err != nil {
+		return fmt.Errorf("failed to write database: %w", err)
+	}
+	return nil
+}
+
+func withConn(db *sql.DB, fn func(conn *sqlite3.SQLiteConn) error) error {
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get database connection: %w", err)
+	}
+	defer func() { _ = conn.Close() }()
+	return conn.Raw(func(driverConn any) error {
+		sqliteConn, ok := driverConn.(*sqlite3.SQLiteConn)
+		if !ok {
+			return fmt.Errorf("unexpected database driver connection %T", driverConn)
+		}
+		return fn(sqliteConn)
+	})
+}
+
+func serializeImage(db *sql.DB) ([]byte, error) {
+	var image []byte
+	err := withConn(db, func(conn *sqlite3.SQLiteConn) error {
+		var err error
+		image, err = conn.Serialize("main")
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize database: %w", err)
+	}
+	return image, nil
+}
+
+// restoreImage loads a SQLite image into db. The image is deserialized
+// into a scratch connection and copied with the backup API, because a
+// deserialized database cannot grow.
+func restoreImage(db *sql.DB, image []byte) error {
+	driverConn, err := (&sqlite3.SQLiteDriver{}).Open(":memory:")
+	if err != nil {
+		return fmt.Errorf("failed to open scratch database: %w", err)
+	}
+	src := driverConn.(*sqlite3.SQLiteConn)
+	defer func() { _ = src.Close() }()
+	if err := src.Deserialize(image, "main"); err != nil {
+		return fmt.Errorf("failed to load database: %w", err)
+	}
+
+	err = withConn(db, func(dest *sqlite3.SQLiteConn) error {
+		backup, err := dest.Backup("main", src, "main")
+		if err != nil {
+			return err
+		}
+		if _, err := backup.Step(-1); err != nil {
+			_ = backup.Finish()
+			return err
+		}
+		return backup.Finish()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/seal"
+)
+
+func newTestSecret(t *testing.T, material string) *seal.Secret {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "deeper.key")
+	require.NoError(t, os.WriteFile(path, []byte(material), 0o600))
+	secret, err := seal.KeyFile(path)
+	require.NoError(t, err)
+	return secret
+}
+
+func TestEncryptedDatabase_RoundTrip(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "deeper.db")
+	secret := newTestSecret(t, "0123456789abcdef0123456789abcdef")
+	trace := entities.Trace{Value: "jdoe@acme.com", Type: entities.Email}
+
+	db, err := NewEncryptedDatabase(dbPath, secret)
+	require.NoError(t, err)
+	assert.True(t, db.Encrypted())
+	repo := NewRepository(db)
+	_, err = repo.GetOrCreateTrace(trace)
+	require.NoError(t, err)
+	require.NoError(t, NewCache(repo).Set(trace, "p", []entities.Trace{{Value: "jdoe", Type: entities.Username}}, time.Hour))
+	require.NoError(t, db.Close())
+
+	data, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	assert.True(t, seal.IsSealed(data))
+	assert.NotContains(t, string(data), "jdoe")
+
+	_, err = NewDatabase(dbPath)
+	assert.ErrorIs(t, err, ErrEncrypted)
+	_, err = NewEncryptedDatabase(dbPath, newTestSecret(t, "fedcba9876543210fedcba9876543210"))
+	assert.ErrorIs(t, err, seal.ErrWrongKey)
+
+	db, err = NewEncryptedDatabase(dbPath, secret)
+	require.NoError(t, err)
+	repo = NewRepository(db)
+	stored, err := repo.GetTraceByValue(trace.Value, trace.Type)
+	require.NoError(t, err)
+	assert.NotNil(t, stored)
+	cached, err := NewCache(repo).Get(trace, "p")
+	require.NoError(t, err)
+	assert.Len(t, cached, 1)
+	require.NoError(t, db.Close())
+}
+
+func TestRekey(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "deeper.db")
+	trace := entities.Trace{Value: "jdoe@acme.com", Type: entities.Email}
+
+	db, err := NewDatabase(dbPath)
+	require.NoError(t, err)
+	_, err = NewRepository(db).GetOrCreateTrace(trace)
+	require.NoError(t, err)
+	require.NoError(t, db.Close())
+
+	oldSecret := newTestSecret(t, "0123456789abcdef0123456789abcdef")
+	_, err = NewEncryptedDatabase(dbPath, oldSecret)
+	assert.ErrorIs(t, err, ErrNotEncrypted)
+
+	require.NoError(t, Rekey(dbPath, nil, oldSecret))
+	newSecret := newTestSecret(t, "fedcba9876543210fedcba9876543210")
+	require.NoError(t, Rekey(dbPath, oldSecret, newSecret))
+	assert.Error(t, Rekey(dbPath, oldSecret, nil))
+
+	db, err = NewEncryptedDatabase(dbPath, newSecret)
+	require.NoError(t, err)
+	stored, err := NewRepository(db).GetTraceByValue(trace.Value, trace.Type)
+	require.NoError(t, err)
+	assert.NotNil(t, stored)
+	require.NoError(t, db.Close())
+
+	require.NoError(t, Rekey(dbPath, newSecret, nil))
+	db, err = NewDatabase(dbPath)
+	require.NoError(t, err)
+	stored, err = NewRepository(db).GetTraceByValue(trace.Value, trace.Type)
+	require.NoError(t, err)
+	assert.NotNil(t, stored)
+	require.NoError(t, db.Close())
+}
+
+func TestEncryptedDatabase_Conflict(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "deeper.db")
+	secret := newTestSecret(t, "0123456789abcdef0123456789abcdef")
+
+	first, err := NewEncryptedDatabase(dbPath, secret)
+	require.NoError(t, err)
+	second, err := NewEncryptedDatabase(dbPath, secret)
+	require.NoError(t, err)
+
+	_, err = NewRepository(second).CreateScanSession("second")
+	require.NoError(t, err)
+	require.NoError(t, second.Close())
+
+	_, err = NewRepository(first).CreateScanSession("first")
+	require.NoError(t, err)
+	err = first.Sync()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "changed by another process")
+
+	// Later changes keep going to the conflict file, not over the other
+	// process's.
+	_, err = NewRepository(first).CreateScanSession("first again")
+	require.NoError(t, err)
+	assert.ErrorContains(t, first.Sync(), "changed by another process")
+	assert.ErrorContains(t, first.Close(), "changed by another process")
+
+	conflicts, err := filepath.Glob(dbPath + ".conflict-*")
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	saved, err := NewEncryptedDatabase(conflicts[0], secret)
+	require.NoError(t, err)
+	savedSessions, err := NewRepository(saved).GetScanSessions(ScanQuery{Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, savedSessions, 2)
+	require.NoError(t, saved.Close())
+
+	db, err := NewEncryptedDatabase(dbPath, secret)
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+	sessions, err := NewRepository(db).GetScanSessions(ScanQuery{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, "second", sessions[0].Input)
+}
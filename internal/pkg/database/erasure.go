@@ -0,0 +1,352 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/state"
+)
+
+// Erasure is a request to erase a data subject's traces.
+type Erasure struct {
+	// Value is the subject's trace value, such as an email address or
+	// username.
+	Value string
+	// Type limits the erasure to traces of one type. Empty erases the value
+	// as every type it was found as.
+	Type entities.TraceType
+	// Reference identifies the request being answered, such as a ticket
+	// number. It is kept in the tombstone; Value is not.
+	Reference string
+	Operator  string
+}
+
+// EraseTrace erases the subject's traces and everything reachable only
+// through them, across all scans:
+//
+//   - scans of the subject, whose seeds are all the subject's traces or
+//     whose input is the value, are deleted entirely, like expired scans;
+//   - in other scans, the edges leading out of the subject are deleted
+//     unless their parent is still reachable from the scan's seeds without
+//     passing through the subject, along with every edge touching it;
+//   - traces left with no edges are deleted, with the verdicts, identity
+//     memberships and links and watch alerts that refer to them;
+//   - the subject's case seeds and watches, identity clusters labelled
+//     with the value, and the deduplication cache's record of the plugins
+//     run on the subject's traces are deleted.
+//
+// Audit logs of scans that are not deleted are kept as the record of what
+// was contacted. EraseTrace returns the tombstone it left; with dryRun set
+// nothing is deleted and the tombstone is what would be left. Run Vacuum
+// afterwards to remove the erased data from disk.
+func (r *Repository) EraseTrace(req Erasure, now time.Time, dryRun bool) (*Tombstone, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	tx, err := r.db.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	subject, err := subjectTraces(tx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(subject) == 0 {
+		if req.Type != "" {
+			return nil, fmt.Errorf("no %s trace %q found", req.Type, req.Value)
+		}
+		return nil, fmt.Errorf("no trace %q found", req.Value)
+	}
+
+	t := &Tombstone{Kind: TombstoneErasure, Reference: req.Reference, Operator: req.Operator, CreatedAt: now}
+
+	scans, err := subjectScans(tx, req.Value, subject)
+	if err != nil {
+		return nil, err
+	}
+	if err := purgeScans(tx, scans, t); err != nil {
+		return nil, err
+	}
+
+	graph, err := loadEdgeGraph(tx)
+	if err != nil {
+		return nil, err
+	}
+	edges, traces := graph.erase(subject)
+
+	n, err := execInChunks(tx, `DELETE FROM trace_edges WHERE id IN (%s)`, edges)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete edges: %w", err)
+	}
+	t.Edges += n
+	for _, step := range []struct{ what, query string }{
+		{"edges", `DELETE FROM trace_edges WHERE parent_trace_id IN (%s)`},
+		{"edges", `DELETE FROM trace_edges WHERE child_trace_id IN (%s)`},
+	} {
+		n, err := execInChunks(tx, step.query, traces)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete %s: %w", step.what, err)
+		}
+		t.Edges += n
+	}
+	for _, step := range []struct{ what, query string }{
+		{"identity memberships", `DELETE FROM identity_members WHERE trace_id IN (%s)`},
+		{"identity links", `DELETE FROM identity_links WHERE from_trace_id IN (%s)`},
+		{"identity links", `DELETE FROM identity_links WHERE to_trace_id IN (%s)`},
+		{"watch alerts", `DELETE FROM watch_alerts WHERE trace_id IN (%s)`},
+		{"plugin runs", `DELETE FROM plugin_runs WHERE trace_id IN (%s)`},
+	} {
+		if _, err := execInChunks(tx, step.query, traces); err != nil {
+			return nil, fmt.Errorf("failed to delete %s: %w", step.what, err)
+		}
+	}
+	if err := purgeOrphanTraces(tx, t); err != nil {
+		return nil, err
+	}
+
+	for _, step := range []struct {
+		what, query string
+		args        []interface{}
+	}{
+		{"identity clusters", `DELETE FROM identity_clusters WHERE label = ?`, []interface{}{req.Value}},
+		{"identity clusters", `DELETE FROM identity_clusters
+			WHERE NOT EXISTS (SELECT 1 FROM identity_members m WHERE m.cluster_id = identity_clusters.id)`, nil},
+		{"case seeds", `DELETE FROM case_seeds WHERE seed = ?`, []interface{}{req.Value}},
+		{"watches", `DELETE FROM watches WHERE input = ?`, []interface{}{req.Value}},
+	} {
+		if _, err := tx.Exec(step.query, step.args...); err != nil {
+			return nil, fmt.Errorf("failed to delete %s: %w", step.what, err)
+		}
+	}
+
+	n, err = purgeCachedTraces(tx, subject)
+	if err != nil {
+		return nil, err
+	}
+	t.CacheEntries += n
+
+	if dryRun {
+		return t, nil
+	}
+	if err := insertTombstone(tx, t); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return t, nil
+}
+
+// subjectTraces returns the stored traces an erasure names.
+func subjectTraces(tx *sql.Tx, req Erasure) (map[int64]entities.Trace, error) {
+	rows, err := tx.Query(
+		`SELECT id, value, type FROM traces WHERE value = ? AND (? = '' OR type = ?)`,
+		req.Value, req.Type, req.Type,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query traces: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	subject := make(map[int64]entities.Trace)
+	for rows.Next() {
+		var id int64
+		var trace entities.Trace
+		if err := rows.Scan(&id, &trace.Value, &trace.Type); err != nil {
+			return nil, fmt.Errorf("failed to scan trace: %w", err)
+		}
+		subject[id] = trace
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate traces: %w", err)
+	}
+	return subject, nil
+}
+
+// subjectScans returns the scans whose input is value or whose seeds are
+// all subject traces.
+func subjectScans(tx *sql.Tx, value string, subject map[int64]entities.Trace) ([]int64, error) {
+	rows, err := tx.Query(`
+		SELECT s.id, s.input, e.child_trace_id
+		FROM scan_sessions s
+		LEFT JOIN trace_edges e ON e.scan_id = s.id AND e.parent_trace_id IS NULL
+		ORDER BY s.id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scan seeds: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	type scanSeeds struct {
+		id    int64
+		input string
+		seeds []int64
+	}
+	var all []*scanSeeds
+	for rows.Next() {
+		var id int64
+		var input string
+		var seed sql.NullInt64
+		if err := rows.Scan(&id, &input, &seed); err != nil {
+			return nil, fmt.Errorf("failed to scan scan seed: %w", err)
+		}
+		if len(all) == 0 || all[len(all)-1].id != id {
+			all = append(all, &scanSeeds{id: id, input: input})
+		}
+		if seed.Valid {
+			all[len(all)-1].seeds = append(all[len(all)-1].seeds, seed.Int64)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate scan seeds: %w", err)
+	}
+
+	seededBySubject := func(seeds []int64) bool {
+		for _, seed := range seeds {
+			if _, isSubject := subject[seed]; !isSubject {
+				return false
+			}
+		}
+		return len(seeds) > 0
+	}
+	var scans []int64
+	for _, s := range all {
+		if s.input == value || seededBySubject(s.seeds) {
+			scans = append(scans, s.id)
+		}
+	}
+	return scans, nil
+}
+
+// edgeGraph is every edge left after deleting the subject's scans.
+type edgeGraph struct {
+	// seeds are each scan's seed traces.
+	seeds map[int64][]int64
+	// children are each scan's edges by parent trace.
+	children map[int64]map[int64][]graphEdge
+}
+
+type graphEdge struct {
+	id, child int64
+}
+
+func loadEdgeGraph(tx *sql.Tx) (*edgeGraph, error) {
+	rows, err := tx.Query(`SELECT id, scan_id, parent_trace_id, child_trace_id FROM trace_edges`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query edges: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	g := &edgeGraph{seeds: make(map[int64][]int64), children: make(map[int64]map[int64][]graphEdge)}
+	for rows.Next() {
+		var id, scanID, child int64
+		var parent sql.NullInt64
+		if err := rows.Scan(&id, &scanID, &parent, &child); err != nil {
+			return nil, fmt.Errorf("failed to scan edge: %w", err)
+		}
+		if !parent.Valid {
+			g.seeds[scanID] = append(g.seeds[scanID], child)
+			continue
+		}
+		if g.children[scanID] == nil {
+			g.children[scanID] = make(map[int64][]graphEdge)
+		}
+		g.children[scanID][parent.Int64] = append(g.children[scanID][parent.Int64], graphEdge{id: id, child: child})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate edges: %w", err)
+	}
+	return g, nil
+}
+
+// erase returns the edges reachable only through the subject in each scan
+// and the traces reachable only through it in every scan, including the
+// subject's own.
+func (g *edgeGraph) erase(subject map[int64]entities.Trace) (edges, traces []int64) {
+	from := make([]int64, 0, len(subject))
+	for id := range subject {
+		from = append(from, id)
+	}
+	kept := make(map[int64]bool)
+	reached := make(map[int64]bool)
+	for scanID, children := range g.children {
+		// Walk from the scan's seeds around the subject, then from the
+		// subject: what the second walk adds is reachable only through it.
+		keptHere := walk(children, g.seeds[scanID], func(id int64) bool {
+			_, isSubject := subject[id]
+			return !isSubject
+		})
+		for id := range keptHere {
+			kept[id] = true
+		}
+		for id := range walk(children, from, func(id int64) bool { return !keptHere[id] }) {
+			reached[id] = true
+			for _, e := range children[id] {
+				edges = append(edges, e.id)
+			}
+		}
+	}
+	for _, id := range from {
+		reached[id] = true
+	}
+	for id := range reached {
+		if !kept[id] {
+			traces = append(traces, id)
+		}
+	}
+	return edges, traces
+}
+
+// walk returns the traces reachable from start through children, entering
+// only traces that enter allows.
+func walk(children map[int64][]graphEdge, start []int64, enter func(int64) bool) map[int64]bool {
+	seen := make(map[int64]bool)
+	var stack []int64
+	for _, id := range start {
+		if enter(id) && !seen[id] {
+			seen[id] = true
+			stack = append(stack, id)
+		}
+	}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, e := range children[id] {
+			if enter(e.child) && !seen[e.child] {
+				seen[e.child] = true
+				stack = append(stack, e.child)
+			}
+		}
+	}
+	return seen
+}
+
+// purgeCachedTraces deletes the worker pool's record of having run the
+// registered plugins on the subject's traces, returning how many entries
+// it deleted. Left in place, it would tell a later scan that an erased
+// trace had already been processed.
+func purgeCachedTraces(tx *sql.Tx, subject map[int64]entities.Trace) (int, error) {
+	plugins := make(map[string]bool)
+	for _, registered := range state.ActivePlugins {
+		for _, plugin := range registered {
+			plugins[plugin.String()] = true
+		}
+	}
+
+	cache := NewCache(nil)
+	total := 0
+	for _, trace := range subject {
+		for plugin := range plugins {
+			marker := DeduplicationMarker(trace.Value + ":" + plugin)
+			result, err := tx.Exec(`DELETE FROM cache_entries WHERE key = ?`, cache.CacheKey(marker, DeduplicationPlugin))
+			if err != nil {
+				return total, fmt.Errorf("failed to delete cache entry: %w", err)
+			}
+			total += affected(result)
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestRepository_EraseTrace(t *testing.T) {
+	repo := newTestRepo(t)
+	now := time.Now()
+
+	subject := entities.Trace{Value: "jdoe", Type: entities.Username}
+	profile := entities.Trace{Value: "github.com/jdoe", Type: entities.Url}
+	email := entities.Trace{Value: "jdoe@corp.com", Type: entities.Email}
+	domain := entities.Trace{Value: "corp.com", Type: entities.Domain}
+	www := entities.Trace{Value: "www.corp.com", Type: entities.Subdomain}
+
+	// A scan of the subject, and a scan of their employer that found them.
+	subjectScan := completedScan(t, repo, subject, now,
+		entities.Discovery{Parent: subject, PluginName: "p", Child: profile},
+		entities.Discovery{Parent: profile, PluginName: "p", Child: email},
+	)
+	companyScan := completedScan(t, repo, domain, now,
+		entities.Discovery{Parent: domain, PluginName: "p", Child: www},
+		entities.Discovery{Parent: domain, PluginName: "p", Child: subject},
+		entities.Discovery{Parent: subject, PluginName: "p", Child: email},
+		entities.Discovery{Parent: email, PluginName: "p", Child: www},
+	)
+
+	c := &Case{Name: "corp", Seeds: []string{"jdoe", "corp.com"}}
+	require.NoError(t, repo.CreateCase(c))
+
+	_, err := repo.EraseTrace(Erasure{Value: "nobody"}, now, false)
+	assert.Error(t, err)
+
+	dry, err := repo.EraseTrace(Erasure{Value: "jdoe", Reference: "DSAR-7"}, now, true)
+	require.NoError(t, err)
+	assert.Len(t, dry.Scans, 1)
+	assert.True(t, traceExists(t, repo, subject), "dry run must not delete")
+
+	tomb, err := repo.EraseTrace(Erasure{Value: "jdoe", Reference: "DSAR-7", Operator: "dpo"}, now, false)
+	require.NoError(t, err)
+	require.Len(t, tomb.Scans, 1)
+	assert.Equal(t, subjectScan, tomb.Scans[0].ID)
+	assert.Equal(t, 3, tomb.Traces)
+
+	for _, trace := range []entities.Trace{subject, profile, email} {
+		assert.False(t, traceExists(t, repo, trace), trace.Value)
+	}
+	for _, trace := range []entities.Trace{domain, www} {
+		assert.True(t, traceExists(t, repo, trace), trace.Value)
+	}
+
+	session, err := repo.GetScanSession(companyScan)
+	require.NoError(t, err)
+	require.NotNil(t, session)
+	traces, edges, err := repo.GetScanGraph(companyScan)
+	require.NoError(t, err)
+	assert.Len(t, traces, 2)
+	assert.Len(t, edges, 2)
+
+	c, err = repo.GetCase(c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"corp.com"}, c.Seeds)
+
+	tombstones, err := repo.GetTombstones()
+	require.NoError(t, err)
+	require.Len(t, tombstones, 1)
+	assert.Equal(t, TombstoneErasure, tombstones[0].Kind)
+	assert.Equal(t, "DSAR-7", tombstones[0].Reference)
+	data, err := json.Marshal(tombstones[0])
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "jdoe", "the tombstone must not name the subject")
+	require.NoError(t, repo.Vacuum())
+}
+
+func TestRepository_EraseTrace_KeepsTracesReachableOtherwise(t *testing.T) {
+	repo := newTestRepo(t)
+	now := time.Now()
+
+	domain := entities.Trace{Value: "corp.com", Type: entities.Domain}
+	subject := entities.Trace{Value: "jdoe@corp.com", Type: entities.Email}
+	mx := entities.Trace{Value: "mx.corp.com", Type: entities.Subdomain}
+
+	// mx is found both through the subject and directly from the domain.
+	scanID := completedScan(t, repo, domain, now,
+		entities.Discovery{Parent: domain, PluginName: "p", Child: subject},
+		entities.Discovery{Parent: subject, PluginName: "p", Child: mx},
+		entities.Discovery{Parent: domain, PluginName: "q", Child: mx},
+	)
+
+	tomb, err := repo.EraseTrace(Erasure{Value: subject.Value, Type: entities.Email}, now, false)
+	require.NoError(t, err)
+	assert.Empty(t, tomb.Scans)
+	assert.Equal(t, 1, tomb.Traces)
+	assert.Equal(t, 2, tomb.Edges)
+	assert.True(t, traceExists(t, repo, mx))
+
+	_, edges, err := repo.GetScanGraph(scanID)
+	require.NoError(t, err)
+	assert.Len(t, edges, 2)
+}
@@ -284,7 +284,7 @@ func (r *Repository) GetScanGraph(scanID int64) ([]Trace, []TraceEdge, error) {
 	}
 
 	nodeRows, err := r.db.db.Query(`
-		SELECT id, value, type, discovered_at FROM traces WHERE id IN (
+		SELECT id, value, type, discovered_at, metadata FROM traces WHERE id IN (
 			SELECT parent_trace_id FROM trace_edges WHERE scan_id = ?
 			UNION
 			SELECT child_trace_id FROM trace_edges WHERE scan_id = ?
@@ -299,9 +299,15 @@ func (r *Repository) GetScanGraph(scanID int64) ([]Trace, []TraceEdge, error) {
 	var nodes []Trace
 	for nodeRows.Next() {
 		var node Trace
-		if err := nodeRows.Scan(&node.ID, &node.Value, &node.Type, &node.DiscoveredAt); err != nil {
+		var metadata sql.NullString
+		if err := nodeRows.Scan(&node.ID, &node.Value, &node.Type, &node.DiscoveredAt, &metadata); err != nil {
 			return nil, nil, fmt.Errorf("failed to scan node row: %w", err)
 		}
+		if metadata.Valid {
+			if err := node.UnmarshalMetadata(metadata.String); err != nil {
+				return nil, nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+			}
+		}
 		nodes = append(nodes, node)
 	}
 	if err := nodeRows.Err(); err != nil {
@@ -276,3 +276,29 @@ func TestRepository_GetReachableTraces_HandlesCycle(t *testing.T) {
 	assert.Equal(t, 0, hopsByID[aID])
 	assert.Equal(t, 1, hopsByID[bID])
 }
+
+func TestRepository_GetScanGraph_LoadsMetadata(t *testing.T) {
+	repo := newTestRepo(t)
+	scanID := newTestScan(t, repo)
+
+	require.NoError(t, repo.StoreTrace(&Trace{
+		Value:        "root.com",
+		Type:         entities.Domain,
+		DiscoveredAt: time.Now(),
+		Metadata:     map[string]interface{}{"registrar": "Example Registrar"},
+	}))
+	require.NoError(t, repo.PersistDiscoveries(scanID, []entities.Discovery{
+		{Parent: entities.Trace{Value: "root.com", Type: entities.Domain}, PluginName: "p1", Child: entities.Trace{Value: "www.root.com", Type: entities.Subdomain}},
+	}))
+
+	nodes, _, err := repo.GetScanGraph(scanID)
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	for _, n := range nodes {
+		if n.Value == "root.com" {
+			assert.Equal(t, "Example Registrar", n.Metadata["registrar"])
+		} else {
+			assert.Nil(t, n.Metadata)
+		}
+	}
+}
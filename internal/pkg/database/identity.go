@@ -0,0 +1,162 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// SaveIdentities replaces a scan's identity clusters and links in one
+// transaction, so re-running resolution never leaves stale clusters behind.
+// Cluster and link IDs are filled in on the passed values.
+func (r *Repository) SaveIdentities(scanID int64, clusters []IdentityCluster, links []IdentityLink) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	tx, err := r.db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(
+		`DELETE FROM identity_members WHERE cluster_id IN (SELECT id FROM identity_clusters WHERE scan_id = ?)`,
+		scanID,
+	); err != nil {
+		return fmt.Errorf("failed to clear identity members: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM identity_clusters WHERE scan_id = ?`, scanID); err != nil {
+		return fmt.Errorf("failed to clear identity clusters: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM identity_links WHERE scan_id = ?`, scanID); err != nil {
+		return fmt.Errorf("failed to clear identity links: %w", err)
+	}
+
+	now := time.Now()
+	for i := range clusters {
+		result, err := tx.Exec(
+			`INSERT INTO identity_clusters (scan_id, label, created_at) VALUES (?, ?, ?)`,
+			scanID, clusters[i].Label, now,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert identity cluster: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get identity cluster id: %w", err)
+		}
+		clusters[i].ID = id
+		clusters[i].ScanID = scanID
+		clusters[i].CreatedAt = now
+
+		for _, traceID := range clusters[i].MemberIDs {
+			if _, err := tx.Exec(
+				`INSERT OR IGNORE INTO identity_members (cluster_id, trace_id) VALUES (?, ?)`,
+				id, traceID,
+			); err != nil {
+				return fmt.Errorf("failed to insert identity member: %w", err)
+			}
+		}
+	}
+
+	for i := range links {
+		result, err := tx.Exec(
+			`INSERT INTO identity_links (scan_id, from_trace_id, to_trace_id, strength, reason, plugin_name)
+			 VALUES (?, ?, ?, ?, ?, ?)`,
+			scanID, links[i].FromTraceID, links[i].ToTraceID, links[i].Strength, links[i].Reason, links[i].PluginName,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert identity link: %w", err)
+		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("failed to get identity link id: %w", err)
+		}
+		links[i].ID = id
+		links[i].ScanID = scanID
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetIdentities returns a scan's stored identity clusters, each with its
+// member trace IDs, and every identity link recorded for the scan.
+func (r *Repository) GetIdentities(scanID int64) ([]IdentityCluster, []IdentityLink, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	clusterRows, err := r.db.db.Query(
+		`SELECT id, scan_id, label, created_at FROM identity_clusters WHERE scan_id = ? ORDER BY id`,
+		scanID,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query identity clusters: %w", err)
+	}
+	defer func() { _ = clusterRows.Close() }()
+
+	var clusters []IdentityCluster
+	index := make(map[int64]int)
+	for clusterRows.Next() {
+		var c IdentityCluster
+		if err := clusterRows.Scan(&c.ID, &c.ScanID, &c.Label, &c.CreatedAt); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan identity cluster row: %w", err)
+		}
+		index[c.ID] = len(clusters)
+		clusters = append(clusters, c)
+	}
+	if err := clusterRows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read identity cluster rows: %w", err)
+	}
+
+	memberRows, err := r.db.db.Query(`
+		SELECT m.cluster_id, m.trace_id
+		FROM identity_members m
+		JOIN identity_clusters c ON c.id = m.cluster_id
+		WHERE c.scan_id = ?
+		ORDER BY m.cluster_id, m.trace_id`,
+		scanID,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query identity members: %w", err)
+	}
+	defer func() { _ = memberRows.Close() }()
+
+	for memberRows.Next() {
+		var clusterID, traceID int64
+		if err := memberRows.Scan(&clusterID, &traceID); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan identity member row: %w", err)
+		}
+		if i, ok := index[clusterID]; ok {
+			clusters[i].MemberIDs = append(clusters[i].MemberIDs, traceID)
+		}
+	}
+	if err := memberRows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read identity member rows: %w", err)
+	}
+
+	linkRows, err := r.db.db.Query(
+		`SELECT id, scan_id, from_trace_id, to_trace_id, strength, reason, plugin_name
+		 FROM identity_links WHERE scan_id = ? ORDER BY id`,
+		scanID,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query identity links: %w", err)
+	}
+	defer func() { _ = linkRows.Close() }()
+
+	var links []IdentityLink
+	for linkRows.Next() {
+		var l IdentityLink
+		if err := linkRows.Scan(&l.ID, &l.ScanID, &l.FromTraceID, &l.ToTraceID, &l.Strength, &l.Reason, &l.PluginName); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan identity link row: %w", err)
+		}
+		links = append(links, l)
+	}
+	if err := linkRows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read identity link rows: %w", err)
+	}
+
+	return clusters, links, nil
+}
@@ -0,0 +1,50 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestRepository_SaveIdentities_RoundTripAndReplace(t *testing.T) {
+	repo := newTestRepo(t)
+	scanID := newTestScan(t, repo)
+
+	userID, err := repo.GetOrCreateTrace(entities.Trace{Value: "jdoe", Type: entities.Username})
+	require.NoError(t, err)
+	emailID, err := repo.GetOrCreateTrace(entities.Trace{Value: "jdoe@example.com", Type: entities.Email})
+	require.NoError(t, err)
+	otherID, err := repo.GetOrCreateTrace(entities.Trace{Value: "jdoe2", Type: entities.Username})
+	require.NoError(t, err)
+
+	clusters := []IdentityCluster{
+		{Label: "jdoe@example.com", MemberIDs: []int64{userID, emailID}},
+		{Label: "jdoe2", MemberIDs: []int64{otherID}},
+	}
+	links := []IdentityLink{
+		{FromTraceID: userID, ToTraceID: emailID, Strength: IdentityLinkStrong, Reason: "profile_listed_account", PluginName: "GitHubProfilePlugin"},
+		{FromTraceID: userID, ToTraceID: otherID, Strength: IdentityLinkWeak, Reason: "similar_username"},
+	}
+	require.NoError(t, repo.SaveIdentities(scanID, clusters, links))
+	assert.NotZero(t, clusters[0].ID)
+	assert.NotZero(t, links[0].ID)
+
+	gotClusters, gotLinks, err := repo.GetIdentities(scanID)
+	require.NoError(t, err)
+	require.Len(t, gotClusters, 2)
+	assert.Equal(t, "jdoe@example.com", gotClusters[0].Label)
+	assert.ElementsMatch(t, []int64{userID, emailID}, gotClusters[0].MemberIDs)
+	require.Len(t, gotLinks, 2)
+	assert.Equal(t, "GitHubProfilePlugin", gotLinks[0].PluginName)
+	assert.Equal(t, IdentityLinkWeak, gotLinks[1].Strength)
+
+	// Saving again replaces rather than appends.
+	require.NoError(t, repo.SaveIdentities(scanID, clusters[:1], links[:1]))
+	gotClusters, gotLinks, err = repo.GetIdentities(scanID)
+	require.NoError(t, err)
+	assert.Len(t, gotClusters, 1)
+	assert.Len(t, gotLinks, 1)
+}
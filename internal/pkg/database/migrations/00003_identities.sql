@@ -0,0 +1,41 @@
+-- +goose Up
+CREATE TABLE identity_clusters (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    scan_id INTEGER NOT NULL,
+    label TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (scan_id) REFERENCES scan_sessions(id)
+);
+
+CREATE TABLE identity_members (
+    cluster_id INTEGER NOT NULL,
+    trace_id INTEGER NOT NULL,
+    FOREIGN KEY (cluster_id) REFERENCES identity_clusters(id) ON DELETE CASCADE,
+    FOREIGN KEY (trace_id) REFERENCES traces(id),
+    PRIMARY KEY (cluster_id, trace_id)
+);
+
+CREATE TABLE identity_links (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    scan_id INTEGER NOT NULL,
+    from_trace_id INTEGER NOT NULL,
+    to_trace_id INTEGER NOT NULL,
+    strength TEXT NOT NULL,
+    reason TEXT NOT NULL,
+    plugin_name TEXT NOT NULL DEFAULT '',
+    FOREIGN KEY (scan_id) REFERENCES scan_sessions(id),
+    FOREIGN KEY (from_trace_id) REFERENCES traces(id),
+    FOREIGN KEY (to_trace_id) REFERENCES traces(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_identity_clusters_scan ON identity_clusters(scan_id);
+CREATE INDEX IF NOT EXISTS idx_identity_members_trace ON identity_members(trace_id);
+CREATE INDEX IF NOT EXISTS idx_identity_links_scan ON identity_links(scan_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_identity_links_scan;
+DROP INDEX IF EXISTS idx_identity_members_trace;
+DROP INDEX IF EXISTS idx_identity_clusters_scan;
+DROP TABLE IF EXISTS identity_links;
+DROP TABLE IF EXISTS identity_members;
+DROP TABLE IF EXISTS identity_clusters;
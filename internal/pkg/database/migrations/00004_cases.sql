@@ -0,0 +1,54 @@
+-- +goose Up
+CREATE TABLE cases (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL UNIQUE,
+    owner TEXT NOT NULL DEFAULT '',
+    authorization_ref TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL DEFAULT 'open',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    closed_at DATETIME
+);
+
+CREATE TABLE case_scans (
+    case_id INTEGER NOT NULL,
+    scan_id INTEGER NOT NULL,
+    added_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (case_id) REFERENCES cases(id) ON DELETE CASCADE,
+    FOREIGN KEY (scan_id) REFERENCES scan_sessions(id),
+    PRIMARY KEY (case_id, scan_id)
+);
+
+CREATE TABLE case_seeds (
+    case_id INTEGER NOT NULL,
+    seed TEXT NOT NULL,
+    added_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (case_id) REFERENCES cases(id) ON DELETE CASCADE,
+    PRIMARY KEY (case_id, seed)
+);
+
+CREATE TABLE case_tags (
+    case_id INTEGER NOT NULL,
+    tag TEXT NOT NULL,
+    FOREIGN KEY (case_id) REFERENCES cases(id) ON DELETE CASCADE,
+    PRIMARY KEY (case_id, tag)
+);
+
+CREATE TABLE case_notes (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    case_id INTEGER NOT NULL,
+    text TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (case_id) REFERENCES cases(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_case_scans_scan ON case_scans(scan_id);
+CREATE INDEX IF NOT EXISTS idx_case_notes_case ON case_notes(case_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_case_notes_case;
+DROP INDEX IF EXISTS idx_case_scans_scan;
+DROP TABLE IF EXISTS case_notes;
+DROP TABLE IF EXISTS case_tags;
+DROP TABLE IF EXISTS case_seeds;
+DROP TABLE IF EXISTS case_scans;
+DROP TABLE IF EXISTS cases;
@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE verdicts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    trace_id INTEGER NOT NULL,
+    plugin_name TEXT NOT NULL DEFAULT '',
+    verdict TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (trace_id) REFERENCES traces(id),
+    UNIQUE(trace_id, plugin_name)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS verdicts;
@@ -0,0 +1,35 @@
+-- +goose Up
+CREATE TABLE watches (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    input TEXT NOT NULL,
+    trace_type TEXT NOT NULL DEFAULT '',
+    interval_seconds INTEGER NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_run_at DATETIME,
+    last_scan_id INTEGER,
+    next_run_at DATETIME NOT NULL,
+    FOREIGN KEY (last_scan_id) REFERENCES scan_sessions(id),
+    UNIQUE(input, trace_type)
+);
+
+CREATE TABLE watch_alerts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    watch_id INTEGER NOT NULL,
+    scan_id INTEGER NOT NULL,
+    previous_scan_id INTEGER NOT NULL,
+    kind TEXT NOT NULL,
+    trace_id INTEGER NOT NULL,
+    value TEXT NOT NULL,
+    type TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (watch_id) REFERENCES watches(id) ON DELETE CASCADE,
+    FOREIGN KEY (scan_id) REFERENCES scan_sessions(id),
+    FOREIGN KEY (trace_id) REFERENCES traces(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_watches_next_run ON watches(next_run_at);
+CREATE INDEX IF NOT EXISTS idx_watch_alerts_watch ON watch_alerts(watch_id, id);
+
+-- +goose Down
+DROP TABLE IF EXISTS watch_alerts;
+DROP TABLE IF EXISTS watches;
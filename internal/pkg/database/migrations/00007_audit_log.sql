@@ -0,0 +1,27 @@
+-- +goose Up
+ALTER TABLE scan_sessions ADD COLUMN operator TEXT NOT NULL DEFAULT '';
+ALTER TABLE scan_sessions ADD COLUMN engagement TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE audit_log (
+    scan_id INTEGER NOT NULL,
+    seq INTEGER NOT NULL,
+    contacted_at TEXT NOT NULL,
+    plugin TEXT NOT NULL DEFAULT '',
+    kind TEXT NOT NULL,
+    host TEXT NOT NULL,
+    method TEXT NOT NULL DEFAULT '',
+    url TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    bytes_sent INTEGER NOT NULL DEFAULT 0,
+    bytes_received INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    prev_hash TEXT NOT NULL,
+    hash TEXT NOT NULL,
+    FOREIGN KEY (scan_id) REFERENCES scan_sessions(id),
+    PRIMARY KEY (scan_id, seq)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS audit_log;
+ALTER TABLE scan_sessions DROP COLUMN engagement;
+ALTER TABLE scan_sessions DROP COLUMN operator;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE tombstones (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    kind TEXT NOT NULL,
+    reference TEXT NOT NULL DEFAULT '',
+    operator TEXT NOT NULL DEFAULT '',
+    scans TEXT NOT NULL DEFAULT '[]',
+    traces INTEGER NOT NULL DEFAULT 0,
+    edges INTEGER NOT NULL DEFAULT 0,
+    cache_entries INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_scan_sessions_started_at ON scan_sessions(started_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_scan_sessions_started_at;
+DROP TABLE IF EXISTS tombstones;
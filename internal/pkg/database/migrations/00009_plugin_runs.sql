@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE plugin_runs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    scan_id INTEGER NOT NULL,
+    trace_id INTEGER NOT NULL,
+    plugin_name TEXT NOT NULL,
+    outcome TEXT NOT NULL,
+    error_type TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    children INTEGER NOT NULL DEFAULT 0,
+    duration_ms INTEGER NOT NULL DEFAULT 0,
+    started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (scan_id) REFERENCES scan_sessions(id),
+    FOREIGN KEY (trace_id) REFERENCES traces(id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_plugin_runs_scan ON plugin_runs(scan_id, outcome);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_plugin_runs_scan;
+DROP TABLE IF EXISTS plugin_runs;
@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE scan_sessions ADD COLUMN audit_entries INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scan_sessions ADD COLUMN audit_head TEXT NOT NULL DEFAULT '';
+
+UPDATE scan_sessions SET
+    audit_entries = (SELECT COUNT(*) FROM audit_log WHERE scan_id = scan_sessions.id),
+    audit_head = COALESCE((SELECT hash FROM audit_log WHERE scan_id = scan_sessions.id ORDER BY seq DESC LIMIT 1), '');
+
+-- +goose Down
+ALTER TABLE scan_sessions DROP COLUMN audit_head;
+ALTER TABLE scan_sessions DROP COLUMN audit_entries;
@@ -9,6 +9,26 @@ import (
 
 const SeedPluginName = "__seed__"
 
+// SeedGuessPluginName marks the seed edge of a guessed seed: the scan input
+// matched several trace types and was fanned out to one seed node per
+// plausible type (see entities.ParseSeeds).
+const SeedGuessPluginName = "__seed_guess__"
+
+// RegistrableParentPluginName labels the derived edge from a Subdomain trace
+// to its registrable Domain (see entities.RegistrableDomain). The engine adds
+// it itself; no plugin lookup is involved.
+const RegistrableParentPluginName = "__registrable_parent__"
+
+// MaltegoPluginName labels edges imported from a Maltego graph whose links
+// were drawn in Maltego rather than exported by a deeper plugin.
+const MaltegoPluginName = "__maltego__"
+
+// IsSeedPlugin reports whether pluginName is one of the pseudo-plugin names
+// used for a scan's seed edges rather than a real plugin.
+func IsSeedPlugin(pluginName string) bool {
+	return pluginName == SeedPluginName || pluginName == SeedGuessPluginName
+}
+
 // Trace represents a stored trace in the database
 type Trace struct {
 	ID           int64                  `json:"id" db:"id"`
@@ -36,6 +56,37 @@ type ReachableTrace struct {
 	Hops    int                `json:"hops"`
 }
 
+// Identity link strengths. Strong links merge traces into one identity
+// cluster; weak links only suggest that two clusters may be the same
+// person or organization.
+const (
+	IdentityLinkStrong = "strong"
+	IdentityLinkWeak   = "weak"
+)
+
+// IdentityCluster groups the traces of a scan that belong to one person or
+// organization.
+type IdentityCluster struct {
+	ID        int64     `json:"id" db:"id"`
+	ScanID    int64     `json:"scan_id" db:"scan_id"`
+	Label     string    `json:"label" db:"label"`
+	MemberIDs []int64   `json:"member_ids"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// IdentityLink is one piece of evidence that two traces share an identity.
+// PluginName is the plugin whose edge supports the link, or empty when the
+// link was inferred by comparing values.
+type IdentityLink struct {
+	ID          int64  `json:"id" db:"id"`
+	ScanID      int64  `json:"scan_id" db:"scan_id"`
+	FromTraceID int64  `json:"from_trace_id" db:"from_trace_id"`
+	ToTraceID   int64  `json:"to_trace_id" db:"to_trace_id"`
+	Strength    string `json:"strength" db:"strength"`
+	Reason      string `json:"reason" db:"reason"`
+	PluginName  string `json:"plugin_name" db:"plugin_name"`
+}
+
 // ScanSession represents a scan session in the database
 type ScanSession struct {
 	ID           int64      `json:"id" db:"id"`
@@ -46,6 +97,209 @@ type ScanSession struct {
 	TotalTraces  int        `json:"total_traces" db:"total_traces"`
 	UniqueTraces int        `json:"unique_traces" db:"unique_traces"`
 	Errors       int        `json:"errors" db:"errors"`
+	// Operator is who ran the scan and Engagement what authorized it, such
+	// as a ticket or engagement reference. Both anchor the scan's audit log.
+	Operator   string `json:"operator,omitempty" db:"operator"`
+	Engagement string `json:"engagement,omitempty" db:"engagement"`
+	// AuditEntries and AuditHead are the length and last hash of the
+	// scan's audit log, kept so that cutting entries from its end is
+	// detected (see audit.VerifyHead).
+	AuditEntries int64  `json:"audit_entries,omitempty" db:"audit_entries"`
+	AuditHead    string `json:"audit_head,omitempty" db:"audit_head"`
+}
+
+// Case statuses. A closed case takes no more scans or seeds; notes and
+// tags can still be added.
+const (
+	CaseOpen   = "open"
+	CaseClosed = "closed"
+)
+
+// Case is an investigation: the scans and seeds that belong to it, the
+// analyst's notes and tags, who owns it and what authorizes it.
+type Case struct {
+	ID    int64  `json:"id" db:"id"`
+	Name  string `json:"name" db:"name"`
+	Owner string `json:"owner" db:"owner"`
+	// Authorization references what permits the investigation, such as a
+	// ticket, engagement letter or warrant number.
+	Authorization string     `json:"authorization" db:"authorization_ref"`
+	Status        string     `json:"status" db:"status"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	ClosedAt      *time.Time `json:"closed_at" db:"closed_at"`
+	Tags          []string   `json:"tags"`
+	Seeds         []string   `json:"seeds"`
+	ScanIDs       []int64    `json:"scan_ids"`
+	Notes         []CaseNote `json:"notes"`
+}
+
+// CaseNote is a free-text note on a case.
+type CaseNote struct {
+	ID        int64     `json:"id" db:"id"`
+	CaseID    int64     `json:"case_id" db:"case_id"`
+	Text      string    `json:"text" db:"text"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Analyst verdicts. A false positive is suppressed from later scans; an
+// irrelevant trace is kept but not expanded further; a confirmed one is
+// only marked.
+const (
+	VerdictConfirmed     = "confirmed"
+	VerdictFalsePositive = "false_positive"
+	VerdictIrrelevant    = "irrelevant"
+)
+
+// IsVerdict reports whether v is one of the verdict constants.
+func IsVerdict(v string) bool {
+	return v == VerdictConfirmed || v == VerdictFalsePositive || v == VerdictIrrelevant
+}
+
+// Verdict is an analyst's judgement on a trace or, when PluginName is set,
+// on the edges that plugin draws into the trace. Value and Type are the
+// trace's, filled in when verdicts are read.
+type Verdict struct {
+	ID         int64              `json:"id" db:"id"`
+	TraceID    int64              `json:"trace_id" db:"trace_id"`
+	Value      string             `json:"value"`
+	Type       entities.TraceType `json:"type"`
+	PluginName string             `json:"plugin_name,omitempty" db:"plugin_name"`
+	Verdict    string             `json:"verdict" db:"verdict"`
+	Note       string             `json:"note,omitempty" db:"note"`
+	CreatedAt  time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at" db:"updated_at"`
+}
+
+// Watch is a seed rescanned on a schedule. Type is the trace type the
+// input is scanned as, or empty to infer it as "deeper scan" does.
+type Watch struct {
+	ID              int64              `json:"id" db:"id"`
+	Input           string             `json:"input" db:"input"`
+	Type            entities.TraceType `json:"type,omitempty" db:"trace_type"`
+	IntervalSeconds int64              `json:"interval_seconds" db:"interval_seconds"`
+	CreatedAt       time.Time          `json:"created_at" db:"created_at"`
+	LastRunAt       *time.Time         `json:"last_run_at" db:"last_run_at"`
+	LastScanID      *int64             `json:"last_scan_id" db:"last_scan_id"`
+	NextRunAt       time.Time          `json:"next_run_at" db:"next_run_at"`
+}
+
+// Interval returns how often the watch rescans its seed.
+func (w Watch) Interval() time.Duration {
+	return time.Duration(w.IntervalSeconds) * time.Second
+}
+
+// Watch alert kinds: what appeared in a watched seed's graph since the
+// previous scan.
+const (
+	AlertNewSubdomain = "new_subdomain"
+	AlertNewEmail     = "new_email"
+	AlertExposedKey   = "exposed_key"
+	AlertNewIdentity  = "new_identity"
+)
+
+// WatchAlert records one change a watch run found. For a new identity,
+// TraceID and Type are those of one of its members and Value is the
+// identity's label.
+type WatchAlert struct {
+	ID             int64              `json:"id" db:"id"`
+	WatchID        int64              `json:"watch_id" db:"watch_id"`
+	ScanID         int64              `json:"scan_id" db:"scan_id"`
+	PreviousScanID int64              `json:"previous_scan_id" db:"previous_scan_id"`
+	Kind           string             `json:"kind" db:"kind"`
+	TraceID        int64              `json:"trace_id" db:"trace_id"`
+	Value          string             `json:"value" db:"value"`
+	Type           entities.TraceType `json:"type" db:"type"`
+	CreatedAt      time.Time          `json:"created_at" db:"created_at"`
+}
+
+// Tombstone kinds: what removed the data a tombstone stands for.
+const (
+	TombstoneErasure   = "erasure"
+	TombstoneRetention = "retention"
+)
+
+// Tombstone is the minimal record left when data is deleted: when, why,
+// by whom and how much, but nothing about the data itself. An erasure
+// tombstone does not name the erased subject; Reference points to the
+// request it answered.
+type Tombstone struct {
+	ID           int64         `json:"id" db:"id"`
+	Kind         string        `json:"kind" db:"kind"`
+	Reference    string        `json:"reference,omitempty" db:"reference"`
+	Operator     string        `json:"operator,omitempty" db:"operator"`
+	Scans        []DeletedScan `json:"scans"`
+	Traces       int           `json:"traces" db:"traces"`
+	Edges        int           `json:"edges" db:"edges"`
+	CacheEntries int           `json:"cache_entries" db:"cache_entries"`
+	CreatedAt    time.Time     `json:"created_at" db:"created_at"`
+}
+
+// DeletedScan is a scan removed entirely, with the head of its audit log
+// so a copy exported earlier can still be matched to it.
+type DeletedScan struct {
+	ID           int64  `json:"id"`
+	AuditEntries int    `json:"audit_entries"`
+	AuditHead    string `json:"audit_head,omitempty"`
+}
+
+// Plugin run outcomes. A run is empty when the plugin answered with
+// nothing, skipped when the plugin's matcher turned the trace down, and
+// cached when an earlier run of the same plugin on the same trace stood in
+// for it.
+const (
+	RunOK          = "ok"
+	RunEmpty       = "empty"
+	RunError       = "error"
+	RunSkipped     = "skipped-by-matcher"
+	RunCircuitOpen = "circuit-open"
+	RunRateLimited = "rate-limited"
+	RunCached      = "cached"
+)
+
+// RunOutcomes lists the run outcomes in the order they are reported.
+var RunOutcomes = []string{RunOK, RunEmpty, RunError, RunCircuitOpen, RunRateLimited, RunSkipped, RunCached}
+
+// IsRunFailure reports whether a run with the outcome failed to get an
+// answer from its plugin, so that its "no results" cannot be trusted.
+func IsRunFailure(outcome string) bool {
+	return outcome == RunError || outcome == RunCircuitOpen || outcome == RunRateLimited
+}
+
+// PluginRun records one plugin's execution against one trace in a scan.
+// Value and Type are the trace's, filled in when runs are read.
+type PluginRun struct {
+	ID         int64              `json:"id" db:"id"`
+	ScanID     int64              `json:"scan_id" db:"scan_id"`
+	TraceID    int64              `json:"trace_id" db:"trace_id"`
+	Value      string             `json:"value"`
+	Type       entities.TraceType `json:"type"`
+	PluginName string             `json:"plugin_name" db:"plugin_name"`
+	Outcome    string             `json:"outcome" db:"outcome"`
+	ErrorType  string             `json:"error_type,omitempty" db:"error_type"`
+	Error      string             `json:"error,omitempty" db:"error"`
+	Children   int                `json:"children" db:"children"`
+	DurationMS int64              `json:"duration_ms" db:"duration_ms"`
+	StartedAt  time.Time          `json:"started_at" db:"started_at"`
+}
+
+// PluginCoverage sums up one plugin's runs in a scan.
+type PluginCoverage struct {
+	PluginName string         `json:"plugin_name"`
+	Runs       int            `json:"runs"`
+	Outcomes   map[string]int `json:"outcomes"`
+	Children   int            `json:"children"`
+	DurationMS int64          `json:"duration_ms"`
+}
+
+// Failures counts the plugin's failed runs.
+func (c PluginCoverage) Failures() int {
+	n := 0
+	for outcome, count := range c.Outcomes {
+		if IsRunFailure(outcome) {
+			n += count
+		}
+	}
+	return n
 }
 
 // CacheEntry represents a cached plugin result
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// scanTracesCTE lists which traces each scan reached, as the scan_traces
+// table expression. A trace belongs to a scan when any of the scan's edges
+// touches it, the same rule GetScanGraph uses for a scan's nodes.
+const scanTracesCTE = `
+	scan_traces(scan_id, trace_id) AS (
+		SELECT scan_id, child_trace_id FROM trace_edges
+		UNION
+		SELECT scan_id, parent_trace_id FROM trace_edges WHERE parent_trace_id IS NOT NULL
+	)`
+
+// ScanOverlap is another scan that reached some of the same traces.
+type ScanOverlap struct {
+	Scan ScanSession `json:"scan"`
+	// Shared lists the IDs of the traces both scans reached.
+	Shared []int64 `json:"shared"`
+}
+
+// GetTrace returns the trace with the given ID, or nil if there is none.
+func (r *Repository) GetTrace(id int64) (*Trace, error) {
+	traces, err := r.queryTraces(`SELECT id, value, type, discovered_at, metadata FROM traces WHERE id = ?`, id)
+	if err != nil || len(traces) == 0 {
+		return nil, err
+	}
+	return &traces[0], nil
+}
+
+// GetTracesByValue returns every trace with the given value, whatever its
+// type. When no value matches exactly, it matches ignoring case.
+func (r *Repository) GetTracesByValue(value string) ([]Trace, error) {
+	traces, err := r.queryTraces(
+		`SELECT id, value, type, discovered_at, metadata FROM traces WHERE value = ? ORDER BY type`, value)
+	if err != nil || len(traces) > 0 {
+		return traces, err
+	}
+	return r.queryTraces(
+		`SELECT id, value, type, discovered_at, metadata FROM traces WHERE value = ? COLLATE NOCASE ORDER BY type, value`, value)
+}
+
+// GetTraceScans returns every scan that reached a trace, oldest first.
+func (r *Repository) GetTraceScans(traceID int64) ([]ScanSession, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query(`
+		WITH`+scanTracesCTE+`
+		SELECT `+scanSessionColumns+`
+		FROM scan_sessions
+		WHERE id IN (SELECT scan_id FROM scan_traces WHERE trace_id = ?)
+		ORDER BY id`,
+		traceID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace scans: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var sessions []ScanSession
+	for rows.Next() {
+		session, err := scanScanSession(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read session rows: %w", err)
+	}
+	return sessions, nil
+}
+
+// GetSharedTraces returns the traces both scans reached, ordered by type
+// and value.
+func (r *Repository) GetSharedTraces(scanA, scanB int64) ([]Trace, error) {
+	return r.queryTraces(`
+		WITH`+scanTracesCTE+`
+		SELECT id, value, type, discovered_at, metadata FROM traces
+		WHERE id IN (SELECT trace_id FROM scan_traces WHERE scan_id = ?)
+		  AND id IN (SELECT trace_id FROM scan_traces WHERE scan_id = ?)
+		ORDER BY type, value`,
+		scanA, scanB,
+	)
+}
+
+// GetPriorOverlaps returns the scans started before scanID that reached at
+// least one of its traces, those sharing the most first.
+func (r *Repository) GetPriorOverlaps(scanID int64) ([]ScanOverlap, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query(`
+		WITH`+scanTracesCTE+`
+		SELECT prior.scan_id, prior.trace_id
+		FROM scan_traces prior
+		JOIN scan_traces this ON this.trace_id = prior.trace_id AND this.scan_id = ?
+		WHERE prior.scan_id < ?
+		ORDER BY prior.scan_id, prior.trace_id`,
+		scanID, scanID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query overlapping scans: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	shared := make(map[int64][]int64)
+	var order []int64
+	for rows.Next() {
+		var otherID, traceID int64
+		if err := rows.Scan(&otherID, &traceID); err != nil {
+			return nil, fmt.Errorf("failed to scan overlap row: %w", err)
+		}
+		if _, ok := shared[otherID]; !ok {
+			order = append(order, otherID)
+		}
+		shared[otherID] = append(shared[otherID], traceID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read overlap rows: %w", err)
+	}
+	_ = rows.Close()
+
+	overlaps := make([]ScanOverlap, 0, len(order))
+	for _, id := range order {
+		session, err := scanScanSession(r.db.db.QueryRow(`SELECT `+scanSessionColumns+` FROM scan_sessions WHERE id = ?`, id))
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get scan session: %w", err)
+		}
+		overlaps = append(overlaps, ScanOverlap{Scan: session, Shared: shared[id]})
+	}
+
+	// Stable, so scans sharing as many traces stay oldest first.
+	sort.SliceStable(overlaps, func(i, j int) bool {
+		return len(overlaps[i].Shared) > len(overlaps[j].Shared)
+	})
+	return overlaps, nil
+}
+
+func (r *Repository) queryTraces(query string, args ...interface{}) ([]Trace, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query traces: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var traces []Trace
+	for rows.Next() {
+		var trace Trace
+		var metadata sql.NullString
+		if err := rows.Scan(&trace.ID, &trace.Value, &trace.Type, &trace.DiscoveredAt, &metadata); err != nil {
+			return nil, fmt.Errorf("failed to scan trace: %w", err)
+		}
+		if metadata.Valid {
+			if err := trace.UnmarshalMetadata(metadata.String); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+			}
+		}
+		traces = append(traces, trace)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read trace rows: %w", err)
+	}
+	return traces, nil
+}
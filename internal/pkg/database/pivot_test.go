@@ -0,0 +1,99 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// seedScan records a scan rooted at seed with the given discoveries.
+func seedScan(t *testing.T, repo *Repository, seed entities.Trace, discoveries ...entities.Discovery) int64 {
+	t.Helper()
+	scanID := newTestScan(t, repo)
+	seedID, err := repo.GetOrCreateTrace(seed)
+	require.NoError(t, err)
+	require.NoError(t, repo.InsertEdge(&TraceEdge{ChildTraceID: seedID, PluginName: SeedPluginName, ScanID: scanID, DiscoveredAt: time.Now()}))
+	require.NoError(t, repo.PersistDiscoveries(scanID, discoveries))
+	return scanID
+}
+
+func TestRepository_CrossScanQueries(t *testing.T) {
+	repo := newTestRepo(t)
+
+	alice := entities.Trace{Value: "alice", Type: entities.Username}
+	bob := entities.Trace{Value: "bob", Type: entities.Username}
+	sharedEmail := entities.Trace{Value: "ops@example.com", Type: entities.Email}
+	analytics := entities.Trace{Value: "UA-12345678-1", Type: entities.AnalyticsID}
+
+	scanA := seedScan(t, repo, alice,
+		entities.Discovery{Parent: alice, PluginName: "GitHubProfilePlugin", Child: sharedEmail},
+		entities.Discovery{Parent: sharedEmail, PluginName: "ContactCrawlerPlugin", Child: analytics},
+	)
+	scanB := seedScan(t, repo, bob,
+		entities.Discovery{Parent: bob, PluginName: "GitHubProfilePlugin", Child: sharedEmail},
+	)
+	scanC := seedScan(t, repo, bob,
+		entities.Discovery{Parent: bob, PluginName: "KeybasePlugin", Child: analytics},
+	)
+
+	shared, err := repo.GetSharedTraces(scanA, scanB)
+	require.NoError(t, err)
+	require.Len(t, shared, 1)
+	assert.Equal(t, "ops@example.com", shared[0].Value)
+
+	emailID, err := repo.GetOrCreateTrace(sharedEmail)
+	require.NoError(t, err)
+	scans, err := repo.GetTraceScans(emailID)
+	require.NoError(t, err)
+	require.Len(t, scans, 2)
+	assert.Equal(t, scanA, scans[0].ID)
+	assert.Equal(t, scanB, scans[1].ID)
+
+	overlaps, err := repo.GetPriorOverlaps(scanC)
+	require.NoError(t, err)
+	require.Len(t, overlaps, 2)
+	assert.Equal(t, scanA, overlaps[0].Scan.ID, "shares the analytics ID; ties stay oldest first")
+	assert.Equal(t, scanB, overlaps[1].Scan.ID, "shares the seed")
+	assert.Len(t, overlaps[0].Shared, 1)
+
+	overlaps, err = repo.GetPriorOverlaps(scanB)
+	require.NoError(t, err)
+	require.Len(t, overlaps, 1)
+	assert.Equal(t, scanA, overlaps[0].Scan.ID)
+
+	overlaps, err = repo.GetPriorOverlaps(scanA)
+	require.NoError(t, err)
+	assert.Empty(t, overlaps, "later scans are not prior")
+}
+
+func TestRepository_GetTracesByValue(t *testing.T) {
+	repo := newTestRepo(t)
+	_, err := repo.GetOrCreateTrace(entities.Trace{Value: "jdoe", Type: entities.Username})
+	require.NoError(t, err)
+	_, err = repo.GetOrCreateTrace(entities.Trace{Value: "jdoe", Type: entities.Github})
+	require.NoError(t, err)
+	id, err := repo.GetOrCreateTrace(entities.Trace{Value: "Example.com", Type: entities.Domain})
+	require.NoError(t, err)
+
+	traces, err := repo.GetTracesByValue("jdoe")
+	require.NoError(t, err)
+	assert.Len(t, traces, 2)
+
+	traces, err = repo.GetTracesByValue("example.com")
+	require.NoError(t, err)
+	require.Len(t, traces, 1)
+	assert.Equal(t, id, traces[0].ID)
+
+	trace, err := repo.GetTrace(id)
+	require.NoError(t, err)
+	require.NotNil(t, trace)
+	assert.Equal(t, "Example.com", trace.Value)
+
+	trace, err = repo.GetTrace(id + 100)
+	require.NoError(t, err)
+	assert.Nil(t, trace)
+}
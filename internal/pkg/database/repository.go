@@ -162,15 +162,22 @@ func (r *Repository) GetTraceByValue(value string, traceType entities.TraceType)
 
 // CreateScanSession creates a new scan session
 func (r *Repository) CreateScanSession(input string) (*ScanSession, error) {
+	return r.CreateScanSessionFor(input, "", "")
+}
+
+// CreateScanSessionFor creates a new scan session run by operator under
+// the engagement reference that authorizes it. Both are fixed for the
+// life of the scan: its audit log chains onto them.
+func (r *Repository) CreateScanSessionFor(input, operator, engagement string) (*ScanSession, error) {
 	r.db.mu.Lock()
 	defer r.db.mu.Unlock()
 
 	query := `
-		INSERT INTO scan_sessions (input, started_at, status)
-		VALUES (?, ?, ?)
+		INSERT INTO scan_sessions (input, started_at, status, operator, engagement)
+		VALUES (?, ?, ?, ?, ?)
 	`
 
-	result, err := r.db.db.Exec(query, input, time.Now(), "running")
+	result, err := r.db.db.Exec(query, input, time.Now(), "running", operator, engagement)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create scan session: %w", err)
 	}
@@ -181,10 +188,12 @@ func (r *Repository) CreateScanSession(input string) (*ScanSession, error) {
 	}
 
 	return &ScanSession{
-		ID:        id,
-		Input:     input,
-		StartedAt: time.Now(),
-		Status:    "running",
+		ID:         id,
+		Input:      input,
+		StartedAt:  time.Now(),
+		Status:     "running",
+		Operator:   operator,
+		Engagement: engagement,
 	}, nil
 }
 
@@ -219,14 +228,30 @@ func (r *Repository) GetScanSession(id int64) (*ScanSession, error) {
 	r.db.mu.RLock()
 	defer r.db.mu.RUnlock()
 
-	query := `
-		SELECT id, input, started_at, completed_at, status, total_traces, unique_traces, errors
-		FROM scan_sessions
-		WHERE id = ?
-	`
+	session, err := scanScanSession(r.db.db.QueryRow(`SELECT `+scanSessionColumns+` FROM scan_sessions WHERE id = ?`, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get scan session: %w", err)
+	}
+
+	return &session, nil
+}
+
+// scanSessionColumns are the scan_sessions columns scanScanSession reads.
+const scanSessionColumns = `id, input, started_at, completed_at, status, total_traces, unique_traces, errors, operator, engagement,
+	audit_entries, audit_head`
 
+// rowScanner is a *sql.Row or *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanScanSession reads a scan session selected as scanSessionColumns.
+func scanScanSession(row rowScanner) (ScanSession, error) {
 	var session ScanSession
-	err := r.db.db.QueryRow(query, id).Scan(
+	err := row.Scan(
 		&session.ID,
 		&session.Input,
 		&session.StartedAt,
@@ -235,15 +260,12 @@ func (r *Repository) GetScanSession(id int64) (*ScanSession, error) {
 		&session.TotalTraces,
 		&session.UniqueTraces,
 		&session.Errors,
+		&session.Operator,
+		&session.Engagement,
+		&session.AuditEntries,
+		&session.AuditHead,
 	)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("failed to get scan session: %w", err)
-	}
-
-	return &session, nil
+	return session, err
 }
 
 // GetScanSessions retrieves scan sessions based on query parameters
@@ -271,12 +293,12 @@ func (r *Repository) GetScanSessions(query ScanQuery) ([]ScanSession, error) {
 
 	whereClause := strings.Join(conditions, " AND ")
 	sqlQuery := fmt.Sprintf(`
-		SELECT id, input, started_at, completed_at, status, total_traces, unique_traces, errors
+		SELECT %s
 		FROM scan_sessions
 		WHERE %s
 		ORDER BY started_at DESC
 		LIMIT ? OFFSET ?
-	`, whereClause)
+	`, scanSessionColumns, whereClause)
 
 	args = append(args, query.Limit, query.Offset)
 
@@ -288,17 +310,7 @@ func (r *Repository) GetScanSessions(query ScanQuery) ([]ScanSession, error) {
 
 	var sessions []ScanSession
 	for rows.Next() {
-		var session ScanSession
-		err := rows.Scan(
-			&session.ID,
-			&session.Input,
-			&session.StartedAt,
-			&session.CompletedAt,
-			&session.Status,
-			&session.TotalTraces,
-			&session.UniqueTraces,
-			&session.Errors,
-		)
+		session, err := scanScanSession(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan session: %w", err)
 		}
@@ -308,6 +320,29 @@ func (r *Repository) GetScanSessions(query ScanQuery) ([]ScanSession, error) {
 	return sessions, nil
 }
 
+// GetPreviousScan returns the latest completed scan of the same input
+// started before the given scan, or nil if there is none.
+func (r *Repository) GetPreviousScan(input string, beforeID int64) (*ScanSession, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	session, err := scanScanSession(r.db.db.QueryRow(`
+		SELECT `+scanSessionColumns+`
+		FROM scan_sessions
+		WHERE input = ? AND id < ? AND status = 'completed'
+		ORDER BY id DESC
+		LIMIT 1
+	`, input, beforeID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get previous scan: %w", err)
+	}
+
+	return &session, nil
+}
+
 // CacheRepository methods
 
 // StoreCacheEntry stores a cache entry
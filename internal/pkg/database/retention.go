@@ -0,0 +1,387 @@
+package database
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy keeps the scans of a case, or of every case with a tag,
+// for Days days. Exactly one of Case and Tag is set.
+type RetentionPolicy struct {
+	Case string `json:"case,omitempty"`
+	Tag  string `json:"tag,omitempty"`
+	Days int    `json:"days"`
+}
+
+// RetentionConfig says how long scans and cached plugin results are kept.
+// Days applies to scans no policy covers; CacheDays to the plugin cache and
+// defaults to Days. Zero keeps data forever.
+//
+// The scans of a case named by a policy are kept for that policy's period,
+// those of a case matched only by tags for the longest of the tags'
+// periods, and those of any other case for Days. A scan in several cases
+// is kept for the longest of their periods.
+type RetentionConfig struct {
+	Days      int               `json:"days"`
+	CacheDays int               `json:"cache_days,omitempty"`
+	Policies  []RetentionPolicy `json:"policies,omitempty"`
+}
+
+// LoadRetentionConfig reads a retention config from a JSON file. A missing
+// file is an empty config, which keeps everything.
+func LoadRetentionConfig(path string) (*RetentionConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &RetentionConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read retention config: %w", err)
+	}
+	var cfg RetentionConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse retention config %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid retention config %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Validate checks that periods are not negative and that each policy
+// names exactly one case or tag.
+func (c *RetentionConfig) Validate() error {
+	if c.Days < 0 || c.CacheDays < 0 {
+		return fmt.Errorf("retention days must not be negative")
+	}
+	for i, p := range c.Policies {
+		if (p.Case == "") == (p.Tag == "") {
+			return fmt.Errorf("policy %d must name exactly one case or tag", i+1)
+		}
+		if p.Days < 0 {
+			return fmt.Errorf("policy %d: retention days must not be negative", i+1)
+		}
+	}
+	return nil
+}
+
+// scanDays returns how many days to keep a scan in the given cases, or 0
+// to keep it forever.
+func (c *RetentionConfig) scanDays(cases []Case) int {
+	if len(cases) == 0 {
+		return c.Days
+	}
+	days := c.caseDays(cases[0])
+	for _, cs := range cases[1:] {
+		days = longer(days, c.caseDays(cs))
+	}
+	return days
+}
+
+// caseDays returns how many days to keep the scans of a case.
+func (c *RetentionConfig) caseDays(cs Case) int {
+	days, tagged := 0, false
+	for _, p := range c.Policies {
+		if p.Case != "" && p.Case == cs.Name {
+			return p.Days
+		}
+		if p.Tag == "" || !slices.Contains(cs.Tags, p.Tag) {
+			continue
+		}
+		if tagged {
+			days = longer(days, p.Days)
+		} else {
+			days, tagged = p.Days, true
+		}
+	}
+	if !tagged {
+		return c.Days
+	}
+	return days
+}
+
+// longer returns the longer of two retention periods, where 0 is forever.
+func longer(a, b int) int {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return max(a, b)
+}
+
+// ApplyRetention deletes the scans older than their retention period as of
+// now, along with their edges, identities, audit logs and alerts, the
+// traces no remaining scan reaches, and cache entries older than the cache
+// period. It returns the tombstone it left, or nil if nothing had expired.
+// With dryRun set nothing is deleted and the tombstone is what would be
+// left. Run Vacuum afterwards to remove the deleted data from disk.
+func (r *Repository) ApplyRetention(cfg *RetentionConfig, now time.Time, dryRun bool) (*Tombstone, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	tx, err := r.db.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	expired, err := expiredScans(tx, cfg, now)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Tombstone{Kind: TombstoneRetention, CreatedAt: now}
+	if err := purgeScans(tx, expired, t); err != nil {
+		return nil, err
+	}
+	if err := purgeOrphanTraces(tx, t); err != nil {
+		return nil, err
+	}
+
+	cacheDays := cfg.CacheDays
+	if cacheDays == 0 {
+		cacheDays = cfg.Days
+	}
+	if cacheDays > 0 {
+		result, err := tx.Exec(`DELETE FROM cache_entries WHERE created_at < ?`, now.AddDate(0, 0, -cacheDays))
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete expired cache entries: %w", err)
+		}
+		t.CacheEntries += affected(result)
+	}
+
+	if len(t.Scans) == 0 && t.Traces == 0 && t.Edges == 0 && t.CacheEntries == 0 {
+		return nil, nil
+	}
+	if dryRun {
+		return t, nil
+	}
+	if err := insertTombstone(tx, t); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return t, nil
+}
+
+// expiredScans returns the finished scans started before their retention
+// period.
+func expiredScans(tx *sql.Tx, cfg *RetentionConfig, now time.Time) ([]int64, error) {
+	memberships := make(map[int64][]Case)
+	rows, err := tx.Query(`
+		SELECT c.id, c.name, cs.scan_id, COALESCE(GROUP_CONCAT(t.tag, char(31)), '')
+		FROM cases c
+		JOIN case_scans cs ON cs.case_id = c.id
+		LEFT JOIN case_tags t ON t.case_id = c.id
+		GROUP BY c.id, cs.scan_id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query case scans: %w", err)
+	}
+	for rows.Next() {
+		var c Case
+		var scanID int64
+		var tags string
+		if err := rows.Scan(&c.ID, &c.Name, &scanID, &tags); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("failed to scan case scan: %w", err)
+		}
+		if tags != "" {
+			c.Tags = strings.Split(tags, "\x1f")
+		}
+		memberships[scanID] = append(memberships[scanID], c)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate case scans: %w", err)
+	}
+
+	rows, err = tx.Query(`SELECT id, started_at FROM scan_sessions WHERE status != 'running' ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query scan sessions: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var expired []int64
+	for rows.Next() {
+		var id int64
+		var startedAt time.Time
+		if err := rows.Scan(&id, &startedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan scan session: %w", err)
+		}
+		days := cfg.scanDays(memberships[id])
+		if days > 0 && startedAt.Before(now.AddDate(0, 0, -days)) {
+			expired = append(expired, id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate scan sessions: %w", err)
+	}
+	return expired, nil
+}
+
+// purgeScans deletes scans and everything recorded under them, noting
+// each in t with the head of its audit log.
+func purgeScans(tx *sql.Tx, scanIDs []int64, t *Tombstone) error {
+	for _, id := range scanIDs {
+		scan := DeletedScan{ID: id}
+		err := tx.QueryRow(
+			`SELECT COUNT(*), COALESCE((SELECT hash FROM audit_log WHERE scan_id = ? ORDER BY seq DESC LIMIT 1), '')
+			 FROM audit_log WHERE scan_id = ?`, id, id,
+		).Scan(&scan.AuditEntries, &scan.AuditHead)
+		if err != nil {
+			return fmt.Errorf("failed to get audit head of scan %d: %w", id, err)
+		}
+		t.Scans = append(t.Scans, scan)
+	}
+
+	for _, step := range []struct {
+		what, query string
+		edges       bool
+	}{
+		{what: "audit entries", query: `DELETE FROM audit_log WHERE scan_id IN (%s)`},
+		{what: "plugin runs", query: `DELETE FROM plugin_runs WHERE scan_id IN (%s)`},
+		{what: "identity links", query: `DELETE FROM identity_links WHERE scan_id IN (%s)`},
+		{what: "identity clusters", query: `DELETE FROM identity_clusters WHERE scan_id IN (%s)`},
+		{what: "case scans", query: `DELETE FROM case_scans WHERE scan_id IN (%s)`},
+		{what: "edges", query: `DELETE FROM trace_edges WHERE scan_id IN (%s)`, edges: true},
+		{what: "watch alerts", query: `DELETE FROM watch_alerts WHERE scan_id IN (%s)`},
+		{what: "watch alerts", query: `DELETE FROM watch_alerts WHERE previous_scan_id IN (%s)`},
+		{what: "watch scans", query: `UPDATE watches SET last_scan_id = NULL WHERE last_scan_id IN (%s)`},
+		{what: "scan sessions", query: `DELETE FROM scan_sessions WHERE id IN (%s)`},
+	} {
+		n, err := execInChunks(tx, step.query, scanIDs)
+		if err != nil {
+			return fmt.Errorf("failed to purge %s: %w", step.what, err)
+		}
+		if step.edges {
+			t.Edges += n
+		}
+	}
+	return nil
+}
+
+// orphanTraces matches traces nothing refers to any more.
+const orphanTraces = `
+	NOT EXISTS (SELECT 1 FROM trace_edges e WHERE e.child_trace_id = traces.id OR e.parent_trace_id = traces.id)
+	AND NOT EXISTS (SELECT 1 FROM identity_members m WHERE m.trace_id = traces.id)
+	AND NOT EXISTS (SELECT 1 FROM identity_links l WHERE l.from_trace_id = traces.id OR l.to_trace_id = traces.id)
+	AND NOT EXISTS (SELECT 1 FROM watch_alerts a WHERE a.trace_id = traces.id)
+	AND NOT EXISTS (SELECT 1 FROM plugin_runs p WHERE p.trace_id = traces.id)`
+
+// purgeOrphanTraces deletes traces no scan reaches any more, with their
+// verdicts.
+func purgeOrphanTraces(tx *sql.Tx, t *Tombstone) error {
+	if _, err := tx.Exec(`DELETE FROM verdicts WHERE trace_id IN (SELECT id FROM traces WHERE` + orphanTraces + `)`); err != nil {
+		return fmt.Errorf("failed to delete verdicts of orphaned traces: %w", err)
+	}
+	result, err := tx.Exec(`DELETE FROM traces WHERE` + orphanTraces)
+	if err != nil {
+		return fmt.Errorf("failed to delete orphaned traces: %w", err)
+	}
+	t.Traces += affected(result)
+	return nil
+}
+
+// maxChunk bounds the parameters of one IN list, well under SQLite's
+// limit on host parameters.
+const maxChunk = 500
+
+// execInChunks runs query, whose %s verb is an IN list, over ids in
+// chunks and returns the rows affected.
+func execInChunks(tx *sql.Tx, query string, ids []int64) (int, error) {
+	total := 0
+	for start := 0; start < len(ids); start += maxChunk {
+		chunk := ids[start:min(start+maxChunk, len(ids))]
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(chunk)), ",")
+		args := make([]interface{}, len(chunk))
+		for i, id := range chunk {
+			args[i] = id
+		}
+		result, err := tx.Exec(fmt.Sprintf(query, placeholders), args...)
+		if err != nil {
+			return total, err
+		}
+		total += affected(result)
+	}
+	return total, nil
+}
+
+func affected(result sql.Result) int {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+func insertTombstone(tx *sql.Tx, t *Tombstone) error {
+	if t.Scans == nil {
+		t.Scans = []DeletedScan{}
+	}
+	scans, err := json.Marshal(t.Scans)
+	if err != nil {
+		return fmt.Errorf("failed to marshal deleted scans: %w", err)
+	}
+	result, err := tx.Exec(
+		`INSERT INTO tombstones (kind, reference, operator, scans, traces, edges, cache_entries, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		t.Kind, t.Reference, t.Operator, string(scans), t.Traces, t.Edges, t.CacheEntries, t.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert tombstone: %w", err)
+	}
+	t.ID, err = result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get tombstone ID: %w", err)
+	}
+	return nil
+}
+
+// GetTombstones returns all tombstones, oldest first.
+func (r *Repository) GetTombstones() ([]Tombstone, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query(`
+		SELECT id, kind, reference, operator, scans, traces, edges, cache_entries, created_at
+		FROM tombstones ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tombstones: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	tombstones := []Tombstone{}
+	for rows.Next() {
+		var t Tombstone
+		var scans string
+		if err := rows.Scan(&t.ID, &t.Kind, &t.Reference, &t.Operator, &scans,
+			&t.Traces, &t.Edges, &t.CacheEntries, &t.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan tombstone: %w", err)
+		}
+		if err := json.Unmarshal([]byte(scans), &t.Scans); err != nil {
+			return nil, fmt.Errorf("failed to parse scans of tombstone %d: %w", t.ID, err)
+		}
+		tombstones = append(tombstones, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate tombstones: %w", err)
+	}
+	return tombstones, nil
+}
+
+// Vacuum rebuilds the database file so that deleted rows no longer remain
+// in free pages on disk.
+func (r *Repository) Vacuum() error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, err := r.db.db.Exec(`VACUUM`); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/audit"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// completedScan records a completed scan of seed started at startedAt with the
+// given discoveries.
+func completedScan(t *testing.T, repo *Repository, seed entities.Trace, startedAt time.Time, discoveries ...entities.Discovery) int64 {
+	t.Helper()
+	session, err := repo.CreateScanSession(seed.Value)
+	require.NoError(t, err)
+	seedID, err := repo.GetOrCreateTrace(seed)
+	require.NoError(t, err)
+	require.NoError(t, repo.InsertEdge(&TraceEdge{ChildTraceID: seedID, PluginName: SeedPluginName, ScanID: session.ID, DiscoveredAt: startedAt}))
+	require.NoError(t, repo.PersistDiscoveries(session.ID, discoveries))
+
+	now := time.Now()
+	session.Status = "completed"
+	session.CompletedAt = &now
+	require.NoError(t, repo.UpdateScanSession(session))
+	_, err = repo.db.db.Exec(`UPDATE scan_sessions SET started_at = ? WHERE id = ?`, startedAt, session.ID)
+	require.NoError(t, err)
+	return session.ID
+}
+
+func traceExists(t *testing.T, repo *Repository, trace entities.Trace) bool {
+	t.Helper()
+	stored, err := repo.GetTraceByValue(trace.Value, trace.Type)
+	require.NoError(t, err)
+	return stored != nil
+}
+
+func TestRetentionConfig_ScanDays(t *testing.T) {
+	cfg := &RetentionConfig{
+		Days: 30,
+		Policies: []RetentionPolicy{
+			{Case: "hold", Days: 0},
+			{Case: "short", Days: 7},
+			{Tag: "fraud", Days: 365},
+			{Tag: "hr", Days: 90},
+		},
+	}
+
+	assert.Equal(t, 30, cfg.scanDays(nil))
+	assert.Equal(t, 30, cfg.scanDays([]Case{{Name: "other"}}))
+	assert.Equal(t, 7, cfg.scanDays([]Case{{Name: "short", Tags: []string{"fraud"}}}))
+	assert.Equal(t, 365, cfg.scanDays([]Case{{Name: "other", Tags: []string{"hr", "fraud"}}}))
+	assert.Equal(t, 90, cfg.scanDays([]Case{{Name: "short"}, {Name: "other", Tags: []string{"hr"}}}))
+	assert.Equal(t, 0, cfg.scanDays([]Case{{Name: "hold"}, {Name: "short"}}))
+}
+
+func TestRetentionConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&RetentionConfig{Days: 30, Policies: []RetentionPolicy{{Tag: "x", Days: 1}}}).Validate())
+	assert.Error(t, (&RetentionConfig{Days: -1}).Validate())
+	assert.Error(t, (&RetentionConfig{Policies: []RetentionPolicy{{Days: 1}}}).Validate())
+	assert.Error(t, (&RetentionConfig{Policies: []RetentionPolicy{{Case: "a", Tag: "b", Days: 1}}}).Validate())
+}
+
+func TestLoadRetentionConfig_Missing(t *testing.T) {
+	cfg, err := LoadRetentionConfig(filepath.Join(t.TempDir(), "retention.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, cfg.Days)
+}
+
+func TestRepository_ApplyRetention(t *testing.T) {
+	repo := newTestRepo(t)
+	now := time.Now()
+
+	oldSeed := entities.Trace{Value: "old.com", Type: entities.Domain}
+	oldOnly := entities.Trace{Value: "www.old.com", Type: entities.Subdomain}
+	shared := entities.Trace{Value: "admin@shared.com", Type: entities.Email}
+	oldID := completedScan(t, repo, oldSeed, now.AddDate(0, 0, -40),
+		entities.Discovery{Parent: oldSeed, PluginName: "p", Child: oldOnly},
+		entities.Discovery{Parent: oldSeed, PluginName: "p", Child: shared},
+	)
+	heldID := completedScan(t, repo, entities.Trace{Value: "held.com", Type: entities.Domain}, now.AddDate(0, 0, -40))
+	newSeed := entities.Trace{Value: "new.com", Type: entities.Domain}
+	newID := completedScan(t, repo, newSeed, now.AddDate(0, 0, -1),
+		entities.Discovery{Parent: newSeed, PluginName: "p", Child: shared},
+	)
+
+	held := &Case{Name: "held", Tags: []string{"litigation"}}
+	require.NoError(t, repo.CreateCase(held))
+	require.NoError(t, repo.AddCaseScans(held.ID, heldID))
+
+	require.NoError(t, repo.AppendAuditEntry(&audit.Entry{ScanID: oldID, Time: now, Kind: audit.KindDNS, Host: "old.com", Status: "ok"}))
+	cfg := &RetentionConfig{Days: 30, Policies: []RetentionPolicy{{Tag: "litigation", Days: 0}}}
+
+	dry, err := repo.ApplyRetention(cfg, now, true)
+	require.NoError(t, err)
+	require.NotNil(t, dry)
+	assert.Len(t, dry.Scans, 1)
+	session, err := repo.GetScanSession(oldID)
+	require.NoError(t, err)
+	assert.NotNil(t, session, "dry run must not delete")
+
+	tomb, err := repo.ApplyRetention(cfg, now, false)
+	require.NoError(t, err)
+	require.NotNil(t, tomb)
+	require.Len(t, tomb.Scans, 1)
+	assert.Equal(t, oldID, tomb.Scans[0].ID)
+	assert.Equal(t, 1, tomb.Scans[0].AuditEntries)
+	assert.NotEmpty(t, tomb.Scans[0].AuditHead)
+	assert.Equal(t, 3, tomb.Edges)
+	assert.Equal(t, 2, tomb.Traces)
+
+	session, err = repo.GetScanSession(oldID)
+	require.NoError(t, err)
+	assert.Nil(t, session)
+	for _, id := range []int64{heldID, newID} {
+		session, err := repo.GetScanSession(id)
+		require.NoError(t, err)
+		assert.NotNil(t, session)
+	}
+	assert.False(t, traceExists(t, repo, oldSeed))
+	assert.False(t, traceExists(t, repo, oldOnly))
+	assert.True(t, traceExists(t, repo, shared))
+
+	tombstones, err := repo.GetTombstones()
+	require.NoError(t, err)
+	require.Len(t, tombstones, 1)
+	assert.Equal(t, TombstoneRetention, tombstones[0].Kind)
+	assert.Equal(t, tomb.Scans, tombstones[0].Scans)
+
+	again, err := repo.ApplyRetention(cfg, now, false)
+	require.NoError(t, err)
+	assert.Nil(t, again)
+	require.NoError(t, repo.Vacuum())
+}
+
+func TestRepository_ApplyRetention_Cache(t *testing.T) {
+	repo := newTestRepo(t)
+	now := time.Now()
+	for key, created := range map[string]time.Time{"old": now.AddDate(0, 0, -10), "new": now} {
+		require.NoError(t, repo.StoreCacheEntry(&CacheEntry{Key: key, Value: "[]", CreatedAt: created, PluginName: "p"}))
+	}
+
+	tomb, err := repo.ApplyRetention(&RetentionConfig{Days: 30, CacheDays: 7}, now, false)
+	require.NoError(t, err)
+	require.NotNil(t, tomb)
+	assert.Equal(t, 1, tomb.CacheEntries)
+
+	entry, err := repo.GetCacheEntry("old")
+	require.NoError(t, err)
+	assert.Nil(t, entry)
+	entry, err = repo.GetCacheEntry("new")
+	require.NoError(t, err)
+	assert.NotNil(t, entry)
+}
@@ -0,0 +1,164 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// RecordPluginRuns adds runs to scanID's ledger. Each run's trace is
+// looked up by its Value and Type, and created if the scan has not stored
+// it yet.
+func (r *Repository) RecordPluginRuns(scanID int64, runs []PluginRun) error {
+	if len(runs) == 0 {
+		return nil
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	tx, err := r.db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	now := time.Now()
+	traceIDs := make(map[entities.Trace]int64)
+	for _, run := range runs {
+		trace := entities.Trace{Value: run.Value, Type: run.Type}
+		traceID, ok := traceIDs[trace]
+		if !ok {
+			if _, err := tx.Exec(
+				`INSERT OR IGNORE INTO traces (value, type, discovered_at) VALUES (?, ?, ?)`,
+				trace.Value, trace.Type, now,
+			); err != nil {
+				return fmt.Errorf("failed to insert trace: %w", err)
+			}
+			if err := tx.QueryRow(
+				`SELECT id FROM traces WHERE value = ? AND type = ?`, trace.Value, trace.Type,
+			).Scan(&traceID); err != nil {
+				return fmt.Errorf("failed to lookup trace id: %w", err)
+			}
+			traceIDs[trace] = traceID
+		}
+
+		startedAt := run.StartedAt
+		if startedAt.IsZero() {
+			startedAt = now
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO plugin_runs (scan_id, trace_id, plugin_name, outcome, error_type, error, children, duration_ms, started_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			scanID, traceID, run.PluginName, run.Outcome, run.ErrorType, run.Error, run.Children, run.DurationMS, startedAt,
+		); err != nil {
+			return fmt.Errorf("failed to insert plugin run: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetPluginRuns returns scanID's plugin runs in the order they finished,
+// limited to the given outcomes if any are given.
+func (r *Repository) GetPluginRuns(scanID int64, outcomes ...string) ([]PluginRun, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	query := `
+		SELECT p.id, p.scan_id, p.trace_id, t.value, t.type, p.plugin_name, p.outcome, p.error_type, p.error,
+			p.children, p.duration_ms, p.started_at
+		FROM plugin_runs p
+		JOIN traces t ON t.id = p.trace_id
+		WHERE p.scan_id = ?`
+	args := []interface{}{scanID}
+	if len(outcomes) > 0 {
+		query += ` AND p.outcome IN (` + strings.TrimSuffix(strings.Repeat("?,", len(outcomes)), ",") + `)`
+		for _, outcome := range outcomes {
+			args = append(args, outcome)
+		}
+	}
+	query += ` ORDER BY p.id`
+
+	rows, err := r.db.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query plugin runs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var runs []PluginRun
+	for rows.Next() {
+		var run PluginRun
+		if err := rows.Scan(&run.ID, &run.ScanID, &run.TraceID, &run.Value, &run.Type, &run.PluginName,
+			&run.Outcome, &run.ErrorType, &run.Error, &run.Children, &run.DurationMS, &run.StartedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan plugin run: %w", err)
+		}
+		runs = append(runs, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read plugin run rows: %w", err)
+	}
+	return runs, nil
+}
+
+// GetPluginCoverage sums up scanID's plugin runs by plugin, ordered by
+// plugin name.
+func (r *Repository) GetPluginCoverage(scanID int64) ([]PluginCoverage, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query(`
+		SELECT plugin_name, outcome, COUNT(*), SUM(children), SUM(duration_ms)
+		FROM plugin_runs
+		WHERE scan_id = ?
+		GROUP BY plugin_name, outcome
+		ORDER BY plugin_name`, scanID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query plugin coverage: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var coverage []PluginCoverage
+	for rows.Next() {
+		var name, outcome string
+		var runs, children int
+		var durationMS int64
+		if err := rows.Scan(&name, &outcome, &runs, &children, &durationMS); err != nil {
+			return nil, fmt.Errorf("failed to scan plugin coverage: %w", err)
+		}
+		if len(coverage) == 0 || coverage[len(coverage)-1].PluginName != name {
+			coverage = append(coverage, PluginCoverage{PluginName: name, Outcomes: make(map[string]int)})
+		}
+		c := &coverage[len(coverage)-1]
+		c.Runs += runs
+		c.Outcomes[outcome] = runs
+		c.Children += children
+		c.DurationMS += durationMS
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read plugin coverage rows: %w", err)
+	}
+	return coverage, nil
+}
+
+// CountFailedPluginRuns counts scanID's runs that failed to get an answer
+// from their plugin (see IsRunFailure).
+func (r *Repository) CountFailedPluginRuns(scanID int64) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var n int
+	err := r.db.db.QueryRow(
+		`SELECT COUNT(*) FROM plugin_runs WHERE scan_id = ? AND outcome IN (?, ?, ?)`,
+		scanID, RunError, RunCircuitOpen, RunRateLimited,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count failed plugin runs: %w", err)
+	}
+	return n, nil
+}
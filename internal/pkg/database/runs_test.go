@@ -0,0 +1,78 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestRepository_PluginRuns(t *testing.T) {
+	repo := newTestRepo(t)
+	email := entities.Trace{Value: "jdoe@acme.com", Type: entities.Email}
+	username := entities.Trace{Value: "jdoe", Type: entities.Username}
+	scanID := seedScan(t, repo, email, entities.Discovery{Parent: email, PluginName: "GravatarPlugin", Child: username})
+
+	require.NoError(t, repo.RecordPluginRuns(scanID, []PluginRun{
+		{Value: email.Value, Type: email.Type, PluginName: "GravatarPlugin", Outcome: RunOK, Children: 1, DurationMS: 120},
+		{Value: email.Value, Type: email.Type, PluginName: "HIBPPlugin", Outcome: RunError, ErrorType: "http", Error: "status 401", DurationMS: 80},
+		{Value: username.Value, Type: username.Type, PluginName: "GravatarPlugin", Outcome: RunSkipped},
+		{Value: username.Value, Type: username.Type, PluginName: "GitHubProfilePlugin", Outcome: RunRateLimited, Error: "rate limit exceeded"},
+		{Value: username.Value, Type: username.Type, PluginName: "SherlockPlugin", Outcome: RunEmpty, DurationMS: 900, StartedAt: time.Now()},
+	}))
+
+	runs, err := repo.GetPluginRuns(scanID)
+	require.NoError(t, err)
+	require.Len(t, runs, 5)
+	assert.Equal(t, "jdoe@acme.com", runs[0].Value)
+	assert.Equal(t, entities.Email, runs[0].Type)
+	assert.Equal(t, 1, runs[0].Children)
+	assert.False(t, runs[0].StartedAt.IsZero())
+
+	failed, err := repo.GetPluginRuns(scanID, RunError, RunCircuitOpen, RunRateLimited)
+	require.NoError(t, err)
+	require.Len(t, failed, 2)
+	assert.Equal(t, "HIBPPlugin", failed[0].PluginName)
+	assert.Equal(t, "http", failed[0].ErrorType)
+	assert.Equal(t, "GitHubProfilePlugin", failed[1].PluginName)
+
+	n, err := repo.CountFailedPluginRuns(scanID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	coverage, err := repo.GetPluginCoverage(scanID)
+	require.NoError(t, err)
+	require.Len(t, coverage, 4)
+	gravatar := coverage[1]
+	assert.Equal(t, "GravatarPlugin", gravatar.PluginName)
+	assert.Equal(t, 2, gravatar.Runs)
+	assert.Equal(t, map[string]int{RunOK: 1, RunSkipped: 1}, gravatar.Outcomes)
+	assert.Equal(t, 1, gravatar.Children)
+	assert.Equal(t, 0, gravatar.Failures())
+	assert.Equal(t, 1, coverage[2].Failures())
+
+	other := newTestScan(t, repo)
+	runs, err = repo.GetPluginRuns(other)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+}
+
+func TestApplyRetention_PurgesPluginRuns(t *testing.T) {
+	repo := newTestRepo(t)
+	seed := entities.Trace{Value: "old.example", Type: entities.Domain}
+	scanID := completedScan(t, repo, seed, time.Now().AddDate(0, 0, -90))
+	require.NoError(t, repo.RecordPluginRuns(scanID, []PluginRun{
+		{Value: seed.Value, Type: seed.Type, PluginName: "WhoisPlugin", Outcome: RunError, Error: "timeout"},
+	}))
+
+	_, err := repo.ApplyRetention(&RetentionConfig{Days: 30}, time.Now(), false)
+	require.NoError(t, err)
+
+	runs, err := repo.GetPluginRuns(scanID)
+	require.NoError(t, err)
+	assert.Empty(t, runs)
+	assert.False(t, traceExists(t, repo, seed))
+}
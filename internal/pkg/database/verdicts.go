@@ -0,0 +1,138 @@
+package database
+
+import (
+	"fmt"
+	"time"
+)
+
+// SetVerdict records a verdict on a trace, or on one plugin's edges into
+// it, replacing any earlier verdict on the same target. ID and times are
+// filled in on v.
+func (r *Repository) SetVerdict(v *Verdict) error {
+	if !IsVerdict(v.Verdict) {
+		return fmt.Errorf("unknown verdict %q", v.Verdict)
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	now := time.Now()
+	_, err := r.db.db.Exec(`
+		INSERT INTO verdicts (trace_id, plugin_name, verdict, note, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(trace_id, plugin_name) DO UPDATE SET
+			verdict = excluded.verdict, note = excluded.note, updated_at = excluded.updated_at`,
+		v.TraceID, v.PluginName, v.Verdict, v.Note, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set verdict: %w", err)
+	}
+	err = r.db.db.QueryRow(
+		`SELECT id, created_at, updated_at FROM verdicts WHERE trace_id = ? AND plugin_name = ?`,
+		v.TraceID, v.PluginName,
+	).Scan(&v.ID, &v.CreatedAt, &v.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to read verdict: %w", err)
+	}
+	return nil
+}
+
+// ClearVerdict removes the verdict on a trace, or on one plugin's edges
+// into it, and reports whether there was one.
+func (r *Repository) ClearVerdict(traceID int64, pluginName string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	result, err := r.db.db.Exec(`DELETE FROM verdicts WHERE trace_id = ? AND plugin_name = ?`, traceID, pluginName)
+	if err != nil {
+		return false, fmt.Errorf("failed to clear verdict: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to read rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// GetVerdicts returns every verdict, ordered by trace type, value and
+// plugin, trace-wide verdicts first.
+func (r *Repository) GetVerdicts() ([]Verdict, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query(`
+		SELECT v.id, v.trace_id, t.value, t.type, v.plugin_name, v.verdict, v.note, v.created_at, v.updated_at
+		FROM verdicts v
+		JOIN traces t ON t.id = v.trace_id
+		ORDER BY t.type, t.value, v.plugin_name`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query verdicts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var verdicts []Verdict
+	for rows.Next() {
+		var v Verdict
+		if err := rows.Scan(&v.ID, &v.TraceID, &v.Value, &v.Type, &v.PluginName, &v.Verdict, &v.Note,
+			&v.CreatedAt, &v.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan verdict: %w", err)
+		}
+		verdicts = append(verdicts, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read verdict rows: %w", err)
+	}
+	return verdicts, nil
+}
+
+// HasEdgeFrom reports whether any scan recorded an edge from the plugin
+// into the trace.
+func (r *Repository) HasEdgeFrom(traceID int64, pluginName string) (bool, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var n int
+	err := r.db.db.QueryRow(
+		`SELECT COUNT(*) FROM trace_edges WHERE child_trace_id = ? AND plugin_name = ?`, traceID, pluginName,
+	).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to count edges: %w", err)
+	}
+	return n > 0, nil
+}
+
+// VerdictIndex looks verdicts up by trace ID and by (trace ID, plugin).
+type VerdictIndex struct {
+	traces map[int64]Verdict
+	edges  map[verdictEdge]Verdict
+}
+
+type verdictEdge struct {
+	traceID int64
+	plugin  string
+}
+
+// NewVerdictIndex indexes verdicts for lookup.
+func NewVerdictIndex(verdicts []Verdict) *VerdictIndex {
+	x := &VerdictIndex{traces: make(map[int64]Verdict), edges: make(map[verdictEdge]Verdict)}
+	for _, v := range verdicts {
+		if v.PluginName == "" {
+			x.traces[v.TraceID] = v
+		} else {
+			x.edges[verdictEdge{v.TraceID, v.PluginName}] = v
+		}
+	}
+	return x
+}
+
+// Trace returns the verdict on a trace itself.
+func (x *VerdictIndex) Trace(traceID int64) (Verdict, bool) {
+	v, ok := x.traces[traceID]
+	return v, ok
+}
+
+// Edge returns the verdict on a plugin's edges into a trace.
+func (x *VerdictIndex) Edge(traceID int64, pluginName string) (Verdict, bool) {
+	v, ok := x.edges[verdictEdge{traceID, pluginName}]
+	return v, ok
+}
@@ -0,0 +1,61 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestRepository_Verdicts(t *testing.T) {
+	repo := newTestRepo(t)
+	user := entities.Trace{Value: "admin", Type: entities.Username}
+	profile := entities.Trace{Value: "https://example.com/admin", Type: entities.SocialGeneric}
+	scanID := seedScan(t, repo, user, entities.Discovery{Parent: user, PluginName: "SherlockPlugin", Child: profile})
+
+	userID, err := repo.GetOrCreateTrace(user)
+	require.NoError(t, err)
+	profileID, err := repo.GetOrCreateTrace(profile)
+	require.NoError(t, err)
+	require.NotZero(t, scanID)
+
+	v := &Verdict{TraceID: userID, Verdict: VerdictIrrelevant, Note: "too common"}
+	require.NoError(t, repo.SetVerdict(v))
+	assert.NotZero(t, v.ID)
+	require.NoError(t, repo.SetVerdict(&Verdict{TraceID: profileID, PluginName: "SherlockPlugin", Verdict: VerdictFalsePositive}))
+	assert.Error(t, repo.SetVerdict(&Verdict{TraceID: userID, Verdict: "maybe"}))
+
+	// Setting again replaces the verdict on the same target.
+	require.NoError(t, repo.SetVerdict(&Verdict{TraceID: userID, Verdict: VerdictConfirmed, Note: "it is our admin"}))
+
+	verdicts, err := repo.GetVerdicts()
+	require.NoError(t, err)
+	require.Len(t, verdicts, 2)
+	index := NewVerdictIndex(verdicts)
+	got, ok := index.Trace(userID)
+	require.True(t, ok)
+	assert.Equal(t, VerdictConfirmed, got.Verdict)
+	assert.Equal(t, "it is our admin", got.Note)
+	assert.Equal(t, "admin", got.Value)
+	_, ok = index.Trace(profileID)
+	assert.False(t, ok, "an edge verdict is not a trace verdict")
+	got, ok = index.Edge(profileID, "SherlockPlugin")
+	require.True(t, ok)
+	assert.Equal(t, VerdictFalsePositive, got.Verdict)
+
+	has, err := repo.HasEdgeFrom(profileID, "SherlockPlugin")
+	require.NoError(t, err)
+	assert.True(t, has)
+	has, err = repo.HasEdgeFrom(profileID, "OtherPlugin")
+	require.NoError(t, err)
+	assert.False(t, has)
+
+	cleared, err := repo.ClearVerdict(userID, "")
+	require.NoError(t, err)
+	assert.True(t, cleared)
+	cleared, err = repo.ClearVerdict(userID, "")
+	require.NoError(t, err)
+	assert.False(t, cleared)
+}
@@ -0,0 +1,205 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const watchColumns = `id, input, trace_type, interval_seconds, created_at, last_run_at, last_scan_id, next_run_at`
+
+// CreateWatch stores a new watch, due to run at w.NextRunAt, and fills in
+// its ID and creation time. A seed can only be watched once per type.
+func (r *Repository) CreateWatch(w *Watch) error {
+	if w.IntervalSeconds <= 0 {
+		return fmt.Errorf("watch interval must be positive")
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var existing int64
+	err := r.db.db.QueryRow(`SELECT id FROM watches WHERE input = ? AND trace_type = ?`, w.Input, w.Type).Scan(&existing)
+	if err == nil {
+		return fmt.Errorf("%q is already watched (watch #%d)", w.Input, existing)
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to look up watch: %w", err)
+	}
+
+	now := time.Now()
+	result, err := r.db.db.Exec(
+		`INSERT INTO watches (input, trace_type, interval_seconds, created_at, next_run_at) VALUES (?, ?, ?, ?, ?)`,
+		w.Input, w.Type, w.IntervalSeconds, now, w.NextRunAt.UTC(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create watch: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get watch ID: %w", err)
+	}
+	w.ID = id
+	w.CreatedAt = now
+	return nil
+}
+
+// GetWatch returns the watch with the given ID, or nil if there is none.
+func (r *Repository) GetWatch(id int64) (*Watch, error) {
+	watches, err := r.queryWatches(`WHERE id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(watches) == 0 {
+		return nil, nil
+	}
+	return &watches[0], nil
+}
+
+// GetWatches returns every watch in the order they were added.
+func (r *Repository) GetWatches() ([]Watch, error) {
+	return r.queryWatches(`ORDER BY id`)
+}
+
+// GetDueWatches returns the watches due to run at now, longest overdue
+// first. Run times are stored in UTC so they compare as text.
+func (r *Repository) GetDueWatches(now time.Time) ([]Watch, error) {
+	return r.queryWatches(`WHERE next_run_at <= ? ORDER BY next_run_at, id`, now.UTC())
+}
+
+// RecordWatchRun stores when a watch last ran, the scan it ran and when it
+// runs next.
+func (r *Repository) RecordWatchRun(w *Watch) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	_, err := r.db.db.Exec(
+		`UPDATE watches SET last_run_at = ?, last_scan_id = ?, next_run_at = ? WHERE id = ?`,
+		w.LastRunAt, w.LastScanID, w.NextRunAt.UTC(), w.ID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record watch run: %w", err)
+	}
+	return nil
+}
+
+// DeleteWatch removes a watch and its alert history and reports whether
+// it existed. Its scans are kept.
+func (r *Repository) DeleteWatch(id int64) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	result, err := r.db.db.Exec(`DELETE FROM watches WHERE id = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete watch: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete watch: %w", err)
+	}
+	return n > 0, nil
+}
+
+// AddWatchAlerts stores alerts and fills in their IDs and creation times.
+func (r *Repository) AddWatchAlerts(alerts []WatchAlert) error {
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	tx, err := r.db.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO watch_alerts (watch_id, scan_id, previous_scan_id, kind, trace_id, value, type, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare alert insert: %w", err)
+	}
+	defer func() { _ = stmt.Close() }()
+
+	now := time.Now()
+	for i := range alerts {
+		a := &alerts[i]
+		result, err := stmt.Exec(a.WatchID, a.ScanID, a.PreviousScanID, a.Kind, a.TraceID, a.Value, a.Type, now)
+		if err != nil {
+			return fmt.Errorf("failed to insert alert: %w", err)
+		}
+		if a.ID, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("failed to get alert ID: %w", err)
+		}
+		a.CreatedAt = now
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// GetWatchAlerts returns the most recent alerts of a watch, or of every
+// watch when watchID is 0, newest first. A limit of 0 returns them all.
+func (r *Repository) GetWatchAlerts(watchID int64, limit int) ([]WatchAlert, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	query := `SELECT id, watch_id, scan_id, previous_scan_id, kind, trace_id, value, type, created_at FROM watch_alerts`
+	var args []interface{}
+	if watchID != 0 {
+		query += ` WHERE watch_id = ?`
+		args = append(args, watchID)
+	}
+	query += ` ORDER BY id DESC`
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alerts: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var alerts []WatchAlert
+	for rows.Next() {
+		var a WatchAlert
+		if err := rows.Scan(&a.ID, &a.WatchID, &a.ScanID, &a.PreviousScanID, &a.Kind, &a.TraceID, &a.Value, &a.Type, &a.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan alert: %w", err)
+		}
+		alerts = append(alerts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read alert rows: %w", err)
+	}
+	return alerts, nil
+}
+
+func (r *Repository) queryWatches(clause string, args ...interface{}) ([]Watch, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	rows, err := r.db.db.Query(`SELECT `+watchColumns+` FROM watches `+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watches: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var watches []Watch
+	for rows.Next() {
+		var w Watch
+		if err := rows.Scan(&w.ID, &w.Input, &w.Type, &w.IntervalSeconds, &w.CreatedAt, &w.LastRunAt, &w.LastScanID, &w.NextRunAt); err != nil {
+			return nil, fmt.Errorf("failed to scan watch: %w", err)
+		}
+		watches = append(watches, w)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read watch rows: %w", err)
+	}
+	return watches, nil
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestRepository_WatchLifecycle(t *testing.T) {
+	repo := newTestRepo(t)
+	now := time.Now()
+
+	w := &Watch{Input: "acme.com", IntervalSeconds: 3600, NextRunAt: now}
+	require.NoError(t, repo.CreateWatch(w))
+	assert.NotZero(t, w.ID)
+	assert.ErrorContains(t, repo.CreateWatch(&Watch{Input: "acme.com", IntervalSeconds: 60, NextRunAt: now}), "already watched")
+	require.NoError(t, repo.CreateWatch(&Watch{Input: "acme.com", Type: entities.Company, IntervalSeconds: 60, NextRunAt: now.Add(time.Hour)}))
+	assert.Error(t, repo.CreateWatch(&Watch{Input: "jdoe", NextRunAt: now}), "interval is required")
+
+	due, err := repo.GetDueWatches(now.Add(time.Minute))
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, w.ID, due[0].ID)
+	assert.Equal(t, time.Hour, due[0].Interval())
+
+	scanID := newTestScan(t, repo)
+	ranAt := now.Add(time.Minute)
+	w.LastRunAt, w.LastScanID, w.NextRunAt = &ranAt, &scanID, ranAt.Add(w.Interval())
+	require.NoError(t, repo.RecordWatchRun(w))
+	due, err = repo.GetDueWatches(now.Add(2 * time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, due, "the next run is an hour away")
+
+	got, err := repo.GetWatch(w.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.LastScanID)
+	assert.Equal(t, scanID, *got.LastScanID)
+
+	traceID, err := repo.GetOrCreateTrace(entities.Trace{Value: "dev.acme.com", Type: entities.Subdomain})
+	require.NoError(t, err)
+	alerts := []WatchAlert{{WatchID: w.ID, ScanID: scanID, PreviousScanID: scanID, Kind: AlertNewSubdomain, TraceID: traceID, Value: "dev.acme.com", Type: entities.Subdomain}}
+	require.NoError(t, repo.AddWatchAlerts(alerts))
+	assert.NotZero(t, alerts[0].ID)
+	history, err := repo.GetWatchAlerts(w.ID, 10)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, AlertNewSubdomain, history[0].Kind)
+
+	removed, err := repo.DeleteWatch(w.ID)
+	require.NoError(t, err)
+	assert.True(t, removed)
+	history, err = repo.GetWatchAlerts(0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, history, "alerts go with their watch")
+	removed, err = repo.DeleteWatch(w.ID)
+	require.NoError(t, err)
+	assert.False(t, removed)
+
+	watches, err := repo.GetWatches()
+	require.NoError(t, err)
+	assert.Len(t, watches, 1)
+}
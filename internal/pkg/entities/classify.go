@@ -0,0 +1,182 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// AllTraceTypes lists every declared TraceType in declaration order. It is
+// the source of truth for validating explicit "type:value" input and for
+// `deeper plugins types`.
+var AllTraceTypes = []TraceType{
+	Email, Phone, Address, IpAddr, Domain, Url, Username, Name, Company,
+	Alias, DateOfBirth, Gender, Nationality, MacAddr, SSHKey, PGPKey,
+	BitcoinAddress, PayPalAccount, MedicalRecordNumber, InsurancePolicy,
+	ExifData, FileTimestamp, Geolocation, ForumRegistrations, CommentsAndPosts,
+	NewsMentions, CourtRecords, Patents, Publications, EducationalInstitution,
+	Workplace, Certificates, ConferenceParticipation,
+	SocialGeneric, Twitter, Github, Linkedin, Instagram, Facebook, TikTok,
+	Reddit, YouTube, Pinterest, Snapchat, Tumblr,
+	Repository, AnalyticsID,
+	DnsRecordA, DnsRecordAAAA, DnsRecordMX, DnsRecordNS, DnsRecordTXT,
+	DnsRecordCNAME, DnsRecordSOA, DnsRecordPTR, DnsRecordSRV, DnsRecordCAA,
+	Whois,
+	Subdomain, ASN, Netblock, Host, IPRange,
+}
+
+// IsKnownTraceType reports whether t is one of AllTraceTypes.
+func IsKnownTraceType(t TraceType) bool {
+	for _, known := range AllTraceTypes {
+		if known == t {
+			return true
+		}
+	}
+	return false
+}
+
+// Classifier describes one shape check used to infer a TraceType from a raw
+// value. Description is shown by `deeper plugins types` so users can see why
+// a value was (or wasn't) given a particular type.
+type Classifier struct {
+	Type        TraceType
+	Description string
+	match       func(string) bool
+}
+
+// Matches reports whether value has this classifier's shape.
+func (c Classifier) Matches(value string) bool {
+	return c.match(value)
+}
+
+// classifiers is ordered from most to least specific, and CandidateTypes
+// lists matches in this order. Url and Subdomain are catch-alls that other
+// classifiers refine: CandidateTypes leaves them out when a more specific
+// type matches, so a LinkedIn profile URL is a Linkedin trace and
+// blog.tumblr.com a Tumblr one, not guesses among several types.
+var classifiers = []Classifier{
+	{Email, "local@domain.tld", isEmail},
+	{Phone, "digits with optional +country code, separators and parentheses", isPhone},
+	{IpAddr, "IPv4 or IPv6 address", isIpAddr},
+	{Netblock, "IPv4 or IPv6 CIDR prefix", isNetblock},
+	{Domain, "registrable hostname (public suffix plus one label)", isRegistrableDomain},
+	{Subdomain, "hostname below a registrable domain", isSubdomain},
+	{Linkedin, "https://linkedin.com/in/<profile>", isLinkedinProfile},
+	{Facebook, "https://facebook.com/<profile>", isFacebookProfile},
+	{YouTube, "https://youtube.com/channel/<id>", isYouTubeChannel},
+	{Pinterest, "https://pinterest.com/<profile>", isPinterestProfile},
+	{Url, "http(s)://host[:port][/path]", isUrl},
+	{Address, "house number followed by street words", isAddress},
+	{Twitter, "@handle, up to 15 word characters", isTwitterHandle},
+	{Instagram, "@handle, up to 30 word characters or dots", isInstagramHandle},
+	{TikTok, "@handle, up to 30 word characters or dots", isTikTokHandle},
+	{Snapchat, "@handle, up to 15 word characters, dots or dashes", isSnapchatHandle},
+	{Reddit, "u/<username>", isRedditUsername},
+	{Tumblr, "<blog>.tumblr.com", isTumblrBlog},
+	{MacAddr, "six colon- or dash-separated hex octets", isMacAddr},
+	{BitcoinAddress, "legacy P2PKH address starting with 1", isBitcoinAddress},
+	{AnalyticsID, "UA-<account>-<n>, G-<id> or GTM-<id> property ID", isAnalyticsID},
+}
+
+// Classifiers returns the ordered shape checks used for type inference.
+func Classifiers() []Classifier {
+	out := make([]Classifier, len(classifiers))
+	copy(out, classifiers)
+	return out
+}
+
+// catchAllTypes are the types whose shape other classifiers refine.
+var catchAllTypes = map[TraceType]bool{Url: true, Subdomain: true}
+
+// CandidateTypes returns every TraceType whose shape value matches, most
+// specific first, leaving out a catch-all type when a more specific one
+// matches. It returns nil when nothing matches; callers fall back to
+// Username in that case.
+func CandidateTypes(value string) []TraceType {
+	var specific, catchAll []TraceType
+	for _, c := range classifiers {
+		if !c.match(value) {
+			continue
+		}
+		if catchAllTypes[c.Type] {
+			catchAll = append(catchAll, c.Type)
+		} else {
+			specific = append(specific, c.Type)
+		}
+	}
+	if len(specific) > 0 {
+		return specific
+	}
+	return catchAll
+}
+
+func guessTraceType(value string) TraceType {
+	if candidates := CandidateTypes(value); len(candidates) > 0 {
+		return candidates[0]
+	}
+	log.Info().Msgf("Could not guess trace type for value %s, assuming it's username", value)
+	return Username
+}
+
+// Seed is a scan starting point. Guessed is set when the value matched more
+// than one classifier and the type is therefore one of several plausible
+// readings rather than a certainty.
+type Seed struct {
+	Trace
+	Guessed bool
+}
+
+// ParseSeeds turns raw scan input into seed traces. Input of the form
+// "type:value", where type is a known TraceType, is taken literally.
+// Otherwise the value is classified: an unambiguous match (or no match,
+// which falls back to Username) yields a single seed, and an ambiguous one
+// fans out to one guessed seed per plausible type.
+func ParseSeeds(input string) ([]Seed, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, fmt.Errorf("scan input must not be empty")
+	}
+
+	if prefix, value, ok := strings.Cut(input, ":"); ok && IsKnownTraceType(TraceType(prefix)) {
+		return TypedSeeds(TraceType(prefix), value)
+	}
+
+	candidates := CandidateTypes(input)
+	switch len(candidates) {
+	case 0:
+		return []Seed{{Trace: seedTrace(input, guessTraceType(input))}}, nil
+	case 1:
+		return []Seed{{Trace: seedTrace(input, candidates[0])}}, nil
+	}
+
+	seeds := make([]Seed, 0, len(candidates))
+	for _, t := range candidates {
+		seeds = append(seeds, Seed{Trace: seedTrace(input, t), Guessed: true})
+	}
+	return seeds, nil
+}
+
+// seedTrace canonicalizes values whose spelling varies between sources, so a
+// seed lands on the same trace node plugins would later produce for it.
+func seedTrace(value string, traceType TraceType) Trace {
+	if traceType == IpAddr {
+		if normalized, ok := NormalizeIpAddr(value); ok {
+			value = normalized
+		}
+	}
+	return Trace{Value: value, Type: traceType}
+}
+
+// TypedSeeds builds the single seed for an explicitly typed value, as given
+// by "type:value" input or `deeper scan --type`.
+func TypedSeeds(traceType TraceType, value string) ([]Seed, error) {
+	if !IsKnownTraceType(traceType) {
+		return nil, fmt.Errorf("unknown trace type %q", traceType)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, fmt.Errorf("scan input must not be empty")
+	}
+	return []Seed{{Trace: seedTrace(value, traceType)}}, nil
+}
@@ -0,0 +1,99 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCandidateTypes(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected []TraceType
+	}{
+		{"handle matches every @-platform", "@handle", []TraceType{Twitter, Instagram, TikTok, Snapchat}},
+		{"long handle rules out 15-char platforms", "@a_really_long_handle_name", []TraceType{Instagram, TikTok}},
+		{"url with path", "https://example.com/about", []TraceType{Url}},
+		{"linkedin profile is not also a url", "https://linkedin.com/in/someone", []TraceType{Linkedin}},
+		{"tumblr blog is not also a subdomain", "someone.tumblr.com", []TraceType{Tumblr}},
+		{"subdomain", "www.example.com", []TraceType{Subdomain}},
+		{"email only", "test@example.com", []TraceType{Email}},
+		{"nothing matches", "randomusername", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CandidateTypes(tt.value)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("CandidateTypes(%s) = %v, want %v", tt.value, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseSeeds(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []Seed
+	}{
+		{"explicit type prefix", "instagram:@handle", []Seed{{Trace: Trace{Value: "@handle", Type: Instagram}}}},
+		{"explicit type keeps colons in value", "mac_addr:00:11:22:33:44:55", []Seed{{Trace: Trace{Value: "00:11:22:33:44:55", Type: MacAddr}}}},
+		{"unknown prefix is part of the value", "https://example.com/about", []Seed{{Trace: Trace{Value: "https://example.com/about", Type: Url}}}},
+		{"unambiguous value", "test@example.com", []Seed{{Trace: Trace{Value: "test@example.com", Type: Email}}}},
+		{"profile url is one seed", "https://linkedin.com/in/someone", []Seed{{Trace: Trace{Value: "https://linkedin.com/in/someone", Type: Linkedin}}}},
+		{"unrecognized value falls back to username", "randomusername", []Seed{{Trace: Trace{Value: "randomusername", Type: Username}}}},
+		{"ambiguous value fans out as guesses", "@handle", []Seed{
+			{Trace: Trace{Value: "@handle", Type: Twitter}, Guessed: true},
+			{Trace: Trace{Value: "@handle", Type: Instagram}, Guessed: true},
+			{Trace: Trace{Value: "@handle", Type: TikTok}, Guessed: true},
+			{Trace: Trace{Value: "@handle", Type: Snapchat}, Guessed: true},
+		}},
+		{"surrounding whitespace trimmed", "  test@example.com  ", []Seed{{Trace: Trace{Value: "test@example.com", Type: Email}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSeeds(tt.input)
+			if err != nil {
+				t.Fatalf("ParseSeeds(%s) returned error: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("ParseSeeds(%s) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseSeeds_Errors(t *testing.T) {
+	for _, input := range []string{"", "   ", "email:", "email:   "} {
+		if _, err := ParseSeeds(input); err == nil {
+			t.Errorf("ParseSeeds(%q) expected error", input)
+		}
+	}
+}
+
+func TestTypedSeeds_UnknownType(t *testing.T) {
+	if _, err := TypedSeeds("not_a_type", "value"); err == nil {
+		t.Error("TypedSeeds with unknown type expected error")
+	}
+}
+
+func TestAllTraceTypes_CoversClassifiers(t *testing.T) {
+	for _, c := range Classifiers() {
+		if !IsKnownTraceType(c.Type) {
+			t.Errorf("classifier type %s missing from AllTraceTypes", c.Type)
+		}
+	}
+}
+
+func TestParseSeeds_NormalizesIpAddr(t *testing.T) {
+	seeds, err := ParseSeeds("2001:DB8::0:1")
+	if err != nil {
+		t.Fatalf("ParseSeeds returned error: %v", err)
+	}
+	expected := []Seed{{Trace: Trace{Value: "2001:db8::1", Type: IpAddr}}}
+	if !reflect.DeepEqual(seeds, expected) {
+		t.Errorf("ParseSeeds = %v, want %v", seeds, expected)
+	}
+}
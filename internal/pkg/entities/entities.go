@@ -1,10 +1,9 @@
 package entities
 
 import (
+	"net/netip"
 	"regexp"
 	"strings"
-
-	"github.com/rs/zerolog/log"
 )
 
 type TraceType string
@@ -59,6 +58,9 @@ const (
 	Tumblr        TraceType = "tumblr"
 	// Technical traces
 	Repository TraceType = "repository"
+	// AnalyticsID is a web analytics or tag manager property ID embedded in
+	// a site (UA-…, G-…, GTM-…). Sites sharing one are run by one owner.
+	AnalyticsID TraceType = "analytics_id"
 	// DNS traces
 	DnsRecordA     TraceType = "dns_record_a"
 	DnsRecordAAAA  TraceType = "dns_record_aaaa"
@@ -118,18 +120,31 @@ func isAddress(value string) bool {
 	return regexp.MustCompile(addressRegex).MatchString(value)
 }
 
+// isIpAddr accepts both address families. Zoned IPv6 addresses
+// ("fe80::1%eth0") are rejected: the zone is host-local and meaningless as
+// an OSINT trace.
 func isIpAddr(value string) bool {
-	ipAddrRegex := `^(\d{1,3}\.){3}\d{1,3}$`
-	return regexp.MustCompile(ipAddrRegex).MatchString(value)
+	addr, err := netip.ParseAddr(value)
+	return err == nil && addr.Zone() == ""
+}
+
+func isNetblock(value string) bool {
+	_, err := netip.ParsePrefix(value)
+	return err == nil
 }
 
+// isDomain is a pure hostname shape check; whether a hostname is a
+// registrable Domain or a Subdomain is decided by HostType.
 func isDomain(value string) bool {
-	domainRegex := `^([a-zA-Z0-9]+\.){1,}([a-zA-Z]{2,})$`
+	domainRegex := `^([a-zA-Z0-9]([a-zA-Z0-9-]*[a-zA-Z0-9])?\.){1,}([a-zA-Z]{2,})$`
 	return regexp.MustCompile(domainRegex).MatchString(value)
 }
 
+// isUrl accepts an optional port and path so that deep links (e.g.
+// "https://example.com/about") classify as Url rather than falling through
+// to the Username default.
 func isUrl(value string) bool {
-	urlRegex := `^https?://[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
+	urlRegex := `^https?://[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}(:\d{1,5})?(/\S*)?$`
 	return regexp.MustCompile(urlRegex).MatchString(value)
 }
 
@@ -193,6 +208,13 @@ func isBitcoinAddress(value string) bool {
 	return regexp.MustCompile(bitcoinRegex).MatchString(value)
 }
 
+// isAnalyticsID matches Google Analytics (Universal and GA4) and Google Tag
+// Manager property IDs.
+func isAnalyticsID(value string) bool {
+	analyticsRegex := `^(UA-\d{4,10}-\d{1,4}|G-[A-Z0-9]{10}|GTM-[A-Z0-9]{4,8})$`
+	return regexp.MustCompile(analyticsRegex).MatchString(value)
+}
+
 // IsEmail reports whether value is shaped like a real email address.
 // Exported for plugins outside this package that need to validate a value
 // before trusting it as an Email-typed trace (see contact_crawler, github_*).
@@ -206,11 +228,22 @@ func IsFacebookProfile(value string) bool {
 	return isFacebookProfile(value)
 }
 
-// IsIpAddr reports whether value is a clean, well-formed IPv4 address.
+// IsIpAddr reports whether value is a clean, well-formed IPv4 or IPv6 address.
 func IsIpAddr(value string) bool {
 	return isIpAddr(value)
 }
 
+// NormalizeIpAddr returns the canonical text form of an IP address --
+// IPv4-mapped IPv6 unmapped to dotted IPv4, IPv6 lowercased and compressed
+// -- so that one address always becomes the same (value, type) trace no
+// matter how a source happened to spell it.
+func NormalizeIpAddr(value string) (string, bool) {
+	if !isIpAddr(value) {
+		return "", false
+	}
+	return netip.MustParseAddr(value).Unmap().String(), true
+}
+
 // reservedEmailDomains are IANA-reserved for documentation (RFC 2606) and
 // can never be a real contact address.
 var reservedEmailDomains = map[string]bool{
@@ -230,47 +263,3 @@ func IsRealEmail(value string) bool {
 	domain := strings.ToLower(value[strings.LastIndex(value, "@")+1:])
 	return !reservedEmailDomains[domain]
 }
-
-func guessTraceType(value string) TraceType {
-	switch {
-	case isEmail(value):
-		return Email
-	case isPhone(value):
-		return Phone
-	case isIpAddr(value):
-		return IpAddr
-	case isDomain(value):
-		return Domain
-	case isUrl(value):
-		return Url
-	case isAddress(value):
-		return Address
-	case isTwitterHandle(value):
-		return Twitter
-	case isLinkedinProfile(value):
-		return Linkedin
-	case isInstagramHandle(value):
-		return Instagram
-	case isFacebookProfile(value):
-		return Facebook
-	case isTikTokHandle(value):
-		return TikTok
-	case isRedditUsername(value):
-		return Reddit
-	case isYouTubeChannel(value):
-		return YouTube
-	case isPinterestProfile(value):
-		return Pinterest
-	case isSnapchatHandle(value):
-		return Snapchat
-	case isTumblrBlog(value):
-		return Tumblr
-	case isMacAddr(value):
-		return MacAddr
-	case isBitcoinAddress(value):
-		return BitcoinAddress
-	default:
-		log.Info().Msgf("Could not guess trace type for value %s, assuming it's username", value)
-		return Username
-	}
-}
@@ -13,7 +13,11 @@ func TestNewTrace(t *testing.T) {
 		{"email", "test@example.com", Email},
 		{"phone", "+1-555-123-4567", Phone},
 		{"ip_addr", "192.168.1.1", IpAddr},
+		{"ipv6 ip_addr", "2001:db8::1", IpAddr},
+		{"netblock", "198.51.100.0/24", Netblock},
+		{"ipv6 netblock", "2001:db8::/32", Netblock},
 		{"domain", "example.com", Domain},
+		{"subdomain", "mail.corp.example.co.uk", Subdomain},
 		{"url", "https://example.com", Url},
 		{"address", "123 Main St", Address},
 		{"twitter", "@username", Twitter},
@@ -24,6 +28,9 @@ func TestNewTrace(t *testing.T) {
 		{"pinterest", "https://pinterest.com/username", Pinterest},
 		{"mac_addr", "00:11:22:33:44:55", MacAddr},
 		{"bitcoin", "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa", BitcoinAddress},
+		{"analytics_id", "UA-12345678-1", AnalyticsID},
+		{"ga4 analytics_id", "G-ABC123DEF4", AnalyticsID},
+		{"gtm analytics_id", "GTM-K9X2PQ", AnalyticsID},
 		{"username", "randomusername", Username},
 	}
 
@@ -109,6 +116,11 @@ func TestIsIpAddr(t *testing.T) {
 		{"valid IPv4", "192.168.1.1", true},
 		{"valid IPv4", "10.0.0.1", true},
 		{"valid IPv4", "172.16.0.1", true},
+		{"valid IPv6", "2001:db8::1", true},
+		{"valid IPv6 loopback", "::1", true},
+		{"valid IPv4-mapped IPv6", "::ffff:192.168.1.1", true},
+		{"zoned IPv6", "fe80::1%eth0", false},
+		{"octet out of range", "256.1.1.1", false},
 		{"invalid IP format", "192.168.1", false},
 		{"invalid IP format", "192.168.1", false},
 		{"invalid IP with letters", "192.168.1.a", false},
@@ -248,6 +260,8 @@ func TestIsUrl(t *testing.T) {
 		{"valid HTTP URL", "http://example.com", true},
 		{"valid HTTPS URL", "https://example.com", true},
 		{"valid URL with subdomain", "https://sub.example.com", true},
+		{"valid URL with path", "https://example.com/about/team", true},
+		{"valid URL with port", "http://example.com:8080/", true},
 		{"invalid URL no protocol", "example.com", false},
 		{"invalid URL wrong protocol", "ftp://example.com", false},
 		{"invalid URL no domain", "https://", false},
@@ -286,3 +300,26 @@ func TestIsBitcoinAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeIpAddr(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected string
+		ok       bool
+	}{
+		{"IPv4 unchanged", "192.168.1.1", "192.168.1.1", true},
+		{"IPv6 compressed and lowercased", "2001:0DB8:0000:0000::0001", "2001:db8::1", true},
+		{"IPv4-mapped unmapped", "::ffff:192.168.1.1", "192.168.1.1", true},
+		{"not an IP", "example.com", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := NormalizeIpAddr(tt.value)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("NormalizeIpAddr(%s) = (%s, %t), want (%s, %t)", tt.value, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package entities
+
+import (
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// RegistrableDomain returns host's registrable domain (eTLD+1) according to
+// the public suffix list, e.g. "mail.corp.example.co.uk" -> "example.co.uk".
+// It reports ok=false for IP literals, bare public suffixes and anything
+// else that has no registrable domain.
+func RegistrableDomain(host string) (string, bool) {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	if host == "" || isIpAddr(host) {
+		return "", false
+	}
+
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return "", false
+	}
+
+	return domain, true
+}
+
+// HostType classifies a hostname as a registrable Domain or a Subdomain of
+// one. It reports ok=false when host isn't hostname-shaped or has no
+// registrable domain.
+func HostType(host string) (TraceType, bool) {
+	if !isDomain(strings.TrimSuffix(host, ".")) {
+		return "", false
+	}
+	domain, ok := RegistrableDomain(host)
+	if !ok {
+		return "", false
+	}
+	if domain == strings.TrimSuffix(strings.ToLower(host), ".") {
+		return Domain, true
+	}
+	return Subdomain, true
+}
+
+// HostTrace builds a Domain or Subdomain trace for host as classified by
+// HostType, falling back to fallback when host can't be classified (e.g. a
+// crt.sh wildcard name). Plugins emitting hostnames use this so that every
+// source labels the same host the same way.
+func HostTrace(host string, fallback TraceType) Trace {
+	if t, ok := HostType(host); ok {
+		return Trace{Value: host, Type: t}
+	}
+	return Trace{Value: host, Type: fallback}
+}
+
+// IsHostTraceType reports whether t is one of the hostname trace types that
+// HostType distinguishes between.
+func IsHostTraceType(t TraceType) bool {
+	return t == Domain || t == Subdomain
+}
+
+func isRegistrableDomain(value string) bool {
+	t, ok := HostType(value)
+	return ok && t == Domain
+}
+
+func isSubdomain(value string) bool {
+	t, ok := HostType(value)
+	return ok && t == Subdomain
+}
@@ -0,0 +1,70 @@
+package entities
+
+import (
+	"testing"
+)
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want string
+		ok   bool
+	}{
+		{"subdomain", "registry.codescoring.ru", "codescoring.ru", true},
+		{"www", "www.codescoring.ru", "codescoring.ru", true},
+		{"uppercase", "WWW.CODESCORING.RU", "codescoring.ru", true},
+		{"bare domain", "codescoring.ru", "codescoring.ru", true},
+		{"multi-label public suffix", "mail.corp.example.co.uk", "example.co.uk", true},
+		{"private public suffix", "someone.github.io", "someone.github.io", true},
+		{"trailing dot", "www.example.org.", "example.org", true},
+		{"bare public suffix", "co.uk", "", false},
+		{"empty", "", "", false},
+		{"ip literal", "192.168.1.1", "", false},
+		{"ipv6 literal", "2001:db8::1", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := RegistrableDomain(tt.host)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("RegistrableDomain(%s) = (%s, %t), want (%s, %t)", tt.host, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestHostType(t *testing.T) {
+	tests := []struct {
+		name string
+		host string
+		want TraceType
+		ok   bool
+	}{
+		{"registrable domain", "example.com", Domain, true},
+		{"registrable under multi-label suffix", "example.co.uk", Domain, true},
+		{"subdomain under multi-label suffix", "mail.corp.example.co.uk", Subdomain, true},
+		{"hyphenated labels", "my-site.example-corp.com", Subdomain, true},
+		{"case-insensitive", "Example.COM", Domain, true},
+		{"bare public suffix", "co.uk", "", false},
+		{"wildcard", "*.example.com", "", false},
+		{"url", "https://example.com", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := HostType(tt.host)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("HostType(%s) = (%s, %t), want (%s, %t)", tt.host, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestHostTrace_FallsBackForUnclassifiable(t *testing.T) {
+	got := HostTrace("*.example.com", Subdomain)
+	want := Trace{Value: "*.example.com", Type: Subdomain}
+	if got != want {
+		t.Errorf("HostTrace = %v, want %v", got, want)
+	}
+}
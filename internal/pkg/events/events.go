@@ -0,0 +1,140 @@
+// Package events carries a scan's progress -- traces discovered, plugins
+// started, finished or failed -- from the engine to whoever is watching:
+// the CLI's live progress view, NDJSON output or an API stream.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// Type identifies what an Event reports.
+type Type string
+
+const (
+	ScanStarted     Type = "scan_started"
+	ScanFinished    Type = "scan_finished"
+	TraceDiscovered Type = "trace_discovered"
+	PluginStarted   Type = "plugin_started"
+	PluginFinished  Type = "plugin_finished"
+	PluginFailed    Type = "plugin_failed"
+	// BudgetExhausted is emitted once when the scan's context ends (the
+	// --timeout deadline passes or the scan is cancelled) or its trace
+	// budget runs out while traces are still queued; the scan stops
+	// expanding and returns what it has.
+	BudgetExhausted Type = "budget_exhausted"
+)
+
+// TraceRef is the JSON shape of a trace inside an event.
+type TraceRef struct {
+	Value string             `json:"value"`
+	Type  entities.TraceType `json:"type"`
+}
+
+// Ref converts a trace for embedding in an event.
+func Ref(t entities.Trace) *TraceRef {
+	return &TraceRef{Value: t.Value, Type: t.Type}
+}
+
+// Event is one step of a scan. Fields that don't apply to a Type are left
+// empty and omitted from JSON, so each event is a compact NDJSON line.
+type Event struct {
+	Type   Type      `json:"type"`
+	Time   time.Time `json:"time"`
+	ScanID int64     `json:"scan_id"`
+	// Trace is the discovered trace, or the trace a plugin ran against.
+	Trace *TraceRef `json:"trace,omitempty"`
+	// Parent is the trace Trace was discovered from; nil for seeds.
+	Parent *TraceRef `json:"parent,omitempty"`
+	Plugin string    `json:"plugin,omitempty"`
+	// Guessed marks a seed whose type was one of several plausible readings.
+	Guessed bool `json:"guessed,omitempty"`
+	// Count is the number of traces a plugin returned (plugin_finished),
+	// the traces left unexpanded (budget_exhausted) or the total unique
+	// traces found (scan_finished).
+	Count      int    `json:"count,omitempty"`
+	DurationMS int64  `json:"duration_ms,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Emitter receives events. Implementations must be safe for concurrent use:
+// plugin events are emitted from worker goroutines.
+type Emitter func(Event)
+
+type emitterKey struct{}
+
+// WithEmitter returns a context whose scan emits to emit. The engine
+// attaches one per scan; code deeper in the call chain (the processor, the
+// worker pool's task handler) picks it up with Emit.
+func WithEmitter(ctx context.Context, emit Emitter) context.Context {
+	return context.WithValue(ctx, emitterKey{}, emit)
+}
+
+// EmitterFrom returns ctx's emitter, or a no-op one.
+func EmitterFrom(ctx context.Context) Emitter {
+	if emit, ok := ctx.Value(emitterKey{}).(Emitter); ok && emit != nil {
+		return emit
+	}
+	return func(Event) {}
+}
+
+// Bus fans events out to any number of subscribers. Publish blocks until
+// every current subscriber has accepted the event, so subscribers must keep
+// draining their channel until they unsubscribe; in exchange no event is
+// ever dropped, which matters for NDJSON consumers.
+type Bus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]*subscriber
+}
+
+type subscriber struct {
+	ch   chan Event
+	done chan struct{}
+}
+
+// NewBus creates an empty bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[int]*subscriber)}
+}
+
+// Subscribe registers a subscriber with the given channel buffer. The
+// returned function unsubscribes and closes the channel; call it once.
+func (b *Bus) Subscribe(buffer int) (<-chan Event, func()) {
+	sub := &subscriber{ch: make(chan Event, buffer), done: make(chan struct{})}
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = sub
+	b.mu.Unlock()
+
+	return sub.ch, func() {
+		// Closing done first releases any Publish blocked on this
+		// subscriber, so taking the write lock below cannot deadlock.
+		close(sub.done)
+		b.mu.Lock()
+		delete(b.subs, id)
+		b.mu.Unlock()
+		close(sub.ch)
+	}
+}
+
+// Publish delivers ev to every subscriber, stamping Time if it is unset.
+func (b *Bus) Publish(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subs {
+		select {
+		case sub.ch <- ev:
+		case <-sub.done:
+		}
+	}
+}
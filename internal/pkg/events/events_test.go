@@ -0,0 +1,60 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus_DeliversToEverySubscriberInOrder(t *testing.T) {
+	bus := NewBus()
+	a, unsubA := bus.Subscribe(4)
+	b, unsubB := bus.Subscribe(4)
+
+	bus.Publish(Event{Type: ScanStarted})
+	bus.Publish(Event{Type: ScanFinished})
+	unsubA()
+	unsubB()
+
+	for _, ch := range []<-chan Event{a, b} {
+		var got []Type
+		for ev := range ch {
+			assert.False(t, ev.Time.IsZero(), "Publish stamps Time")
+			got = append(got, ev.Type)
+		}
+		assert.Equal(t, []Type{ScanStarted, ScanFinished}, got)
+	}
+}
+
+func TestBus_UnsubscribeReleasesBlockedPublisher(t *testing.T) {
+	bus := NewBus()
+	_, unsubscribe := bus.Subscribe(0)
+
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(Event{Type: ScanStarted}) // nobody is reading
+		close(published)
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	unsubscribe()
+
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("Publish stayed blocked after the subscriber left")
+	}
+}
+
+func TestEmitterFrom_DefaultsToNoop(t *testing.T) {
+	assert.NotPanics(t, func() { EmitterFrom(context.Background())(Event{Type: ScanStarted}) })
+
+	var got []Event
+	ctx := WithEmitter(context.Background(), func(ev Event) { got = append(got, ev) })
+	EmitterFrom(ctx)(Event{Type: PluginStarted, Plugin: "p"})
+	require.Len(t, got, 1)
+	assert.Equal(t, "p", got[0].Plugin)
+}
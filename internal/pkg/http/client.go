@@ -8,6 +8,7 @@ import (
 
 	"github.com/smirnoffmg/deeper/internal/pkg/config"
 	"github.com/smirnoffmg/deeper/internal/pkg/errors"
+	"github.com/smirnoffmg/deeper/internal/pkg/telemetry"
 )
 
 // Client interface for HTTP operations
@@ -28,11 +29,11 @@ type DefaultClient struct {
 func NewClient(cfg *config.Config) Client {
 	client := &http.Client{
 		Timeout: cfg.HTTPTimeout,
-		Transport: &http.Transport{
+		Transport: telemetry.Transport(&http.Transport{
 			MaxIdleConns:        100,
 			MaxIdleConnsPerHost: 10,
 			IdleConnTimeout:     90 * time.Second,
-		},
+		}),
 	}
 
 	return &DefaultClient{
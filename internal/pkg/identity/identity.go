@@ -0,0 +1,489 @@
+// Package identity resolves a scan's discovery graph into identity
+// clusters: groups of emails, usernames, profiles and keys that belong to
+// the same person or organization.
+//
+// Clusters are built only from strong links -- facts the account owner
+// asserted themselves, such as the email listed on their GitHub profile, a
+// Keybase proof, a Gravatar verified account or a published SSH key. Weak
+// signals (matching display names, similar usernames, the same username
+// existing on another site) never merge clusters; they are recorded as
+// links between clusters for an analyst to confirm.
+package identity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/texttheater/golang-levenshtein/levenshtein"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// Reasons recorded on identity links.
+const (
+	ReasonProfileListed    = "profile_listed_account"
+	ReasonKeybaseProof     = "keybase_proof"
+	ReasonGravatarVerified = "gravatar_verified_account"
+	ReasonPublishedKey     = "published_key"
+	ReasonKeyEmail         = "pgp_key_email"
+	ReasonSharedKey        = "shared_key"
+	ReasonDisplayName      = "matching_display_name"
+	ReasonSimilarUsername  = "similar_username"
+	ReasonUsernameReuse    = "username_reuse"
+)
+
+// usernameSimilarity is the minimum normalized Levenshtein similarity for
+// two handles to be flagged as a weak match. Shorter handles are compared
+// for equality only: at that length near-misses are mostly coincidence.
+const (
+	usernameSimilarity   = 0.85
+	minFuzzyHandleLength = 5
+)
+
+// identifierTypes are the trace types that identify an account or key and
+// can therefore be merged into a cluster.
+var identifierTypes = map[entities.TraceType]bool{
+	entities.Email:         true,
+	entities.Username:      true,
+	entities.Twitter:       true,
+	entities.Github:        true,
+	entities.Linkedin:      true,
+	entities.Instagram:     true,
+	entities.Facebook:      true,
+	entities.TikTok:        true,
+	entities.Reddit:        true,
+	entities.YouTube:       true,
+	entities.Pinterest:     true,
+	entities.Snapchat:      true,
+	entities.Tumblr:        true,
+	entities.SocialGeneric: true,
+	entities.SSHKey:        true,
+	entities.PGPKey:        true,
+}
+
+// handleTypes hold bare handles that can be compared for similarity.
+var handleTypes = map[entities.TraceType]bool{
+	entities.Username:  true,
+	entities.Twitter:   true,
+	entities.Instagram: true,
+	entities.TikTok:    true,
+	entities.Snapchat:  true,
+	entities.Reddit:    true,
+}
+
+// strongRule decides whether an edge from a given plugin is owner-asserted
+// evidence, returning the reason to record.
+type strongRule func(parent, child entities.TraceType) (string, bool)
+
+// strongRules is keyed by plugin name (DeeperPlugin.String()). Plugins not
+// listed here never produce strong links: e.g. GitHubIdentityPlugin mines
+// commit authors, who are often co-authors rather than the account owner.
+var strongRules = map[string]strongRule{
+	"GitHubProfilePlugin": func(_, child entities.TraceType) (string, bool) {
+		return ReasonProfileListed, identifierTypes[child]
+	},
+	"GitHubKeysPlugin": func(_, child entities.TraceType) (string, bool) {
+		switch child {
+		case entities.SSHKey, entities.PGPKey:
+			return ReasonPublishedKey, true
+		case entities.Email:
+			return ReasonKeyEmail, true
+		}
+		return "", false
+	},
+	"KeybaseProfilePlugin": func(_, child entities.TraceType) (string, bool) {
+		return ReasonKeybaseProof, identifierTypes[child]
+	},
+	"GravatarPlugin": func(_, child entities.TraceType) (string, bool) {
+		return ReasonGravatarVerified, identifierTypes[child]
+	},
+}
+
+// weakEdgePlugins are plugins whose edges are weak evidence on their own.
+var weakEdgePlugins = map[string]string{
+	// Sherlock-style existence checks: the same handle existing elsewhere
+	// is a hint, not proof -- common usernames collide constantly.
+	"SocialProfilesPlugin": ReasonUsernameReuse,
+}
+
+// Resolve groups a scan graph's identifier traces into clusters. Every
+// identifier trace ends up in exactly one cluster, possibly on its own.
+// The returned links carry the evidence: strong links inside clusters and
+// weak links between them. Both are sorted for stable output.
+func Resolve(nodes []database.Trace, edges []database.TraceEdge) ([]database.IdentityCluster, []database.IdentityLink) {
+	byID := make(map[int64]database.Trace, len(nodes))
+	for _, n := range nodes {
+		byID[n.ID] = n
+	}
+
+	uf := newUnionFind()
+	for _, n := range nodes {
+		if identifierTypes[n.Type] {
+			uf.add(n.ID)
+		}
+	}
+
+	var strong, weak []database.IdentityLink
+	names := make(map[int64][]string) // identifier trace ID -> display names
+	keyOwners := make(map[int64][]int64)
+
+	for _, e := range edges {
+		if e.ParentTraceID == nil {
+			continue
+		}
+		parent, okParent := byID[*e.ParentTraceID]
+		child, okChild := byID[e.ChildTraceID]
+		if !okParent || !okChild || !identifierTypes[parent.Type] {
+			continue
+		}
+
+		if child.Type == entities.Name {
+			names[parent.ID] = append(names[parent.ID], child.Value)
+			continue
+		}
+		if !identifierTypes[child.Type] {
+			continue
+		}
+
+		if rule, ok := strongRules[e.PluginName]; ok {
+			if reason, ok := rule(parent.Type, child.Type); ok {
+				uf.union(parent.ID, child.ID)
+				strong = append(strong, link(parent.ID, child.ID, database.IdentityLinkStrong, reason, e.PluginName))
+				if child.Type == entities.SSHKey || child.Type == entities.PGPKey {
+					keyOwners[child.ID] = appendUnique(keyOwners[child.ID], parent.ID)
+				}
+				continue
+			}
+		}
+		if reason, ok := weakEdgePlugins[e.PluginName]; ok {
+			weak = append(weak, link(parent.ID, child.ID, database.IdentityLinkWeak, reason, e.PluginName))
+		}
+	}
+
+	// A key published by several accounts ties those accounts together
+	// directly; record that explicitly rather than leaving it implied by
+	// both sharing the key node.
+	for _, owners := range keyOwners {
+		for i := 1; i < len(owners); i++ {
+			strong = append(strong, link(owners[0], owners[i], database.IdentityLinkStrong, ReasonSharedKey, ""))
+		}
+	}
+
+	clusters := uf.groups()
+	clusterOf := make(map[int64]int, len(byID))
+	for i, members := range clusters {
+		for _, id := range members {
+			clusterOf[id] = i
+		}
+	}
+
+	// Weak edges inside one cluster add nothing the strong links don't
+	// already establish.
+	filtered := weak[:0]
+	for _, l := range weak {
+		if clusterOf[l.FromTraceID] != clusterOf[l.ToTraceID] {
+			filtered = append(filtered, l)
+		}
+	}
+	weak = filtered
+
+	clusterNames := make([][]string, len(clusters))
+	for id, ns := range names {
+		if i, ok := clusterOf[id]; ok {
+			clusterNames[i] = append(clusterNames[i], ns...)
+		}
+	}
+
+	weak = append(weak, displayNameLinks(names, clusterOf)...)
+	weak = append(weak, similarHandleLinks(clusters, byID, clusterOf)...)
+
+	result := make([]database.IdentityCluster, 0, len(clusters))
+	for i, members := range clusters {
+		result = append(result, database.IdentityCluster{
+			Label:     clusterLabel(members, clusterNames[i], byID),
+			MemberIDs: members,
+		})
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if len(result[i].MemberIDs) != len(result[j].MemberIDs) {
+			return len(result[i].MemberIDs) > len(result[j].MemberIDs)
+		}
+		return result[i].Label < result[j].Label
+	})
+
+	links := append(dedupeLinks(strong), dedupeLinks(weak)...)
+	return result, links
+}
+
+// ClusterLinks returns the links touching any member of c: the strong
+// links that formed it and the weak links to other clusters.
+func ClusterLinks(c database.IdentityCluster, links []database.IdentityLink) []database.IdentityLink {
+	members := make(map[int64]bool, len(c.MemberIDs))
+	for _, id := range c.MemberIDs {
+		members[id] = true
+	}
+	var out []database.IdentityLink
+	for _, l := range links {
+		if members[l.FromTraceID] || members[l.ToTraceID] {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// DescribeLinks renders links as one human-readable line each, e.g.
+// "strong: jdoe -> jdoe@example.com (profile_listed_account via GitHubProfilePlugin)".
+func DescribeLinks(links []database.IdentityLink, traces map[int64]database.Trace) []string {
+	lines := make([]string, 0, len(links))
+	for _, l := range links {
+		reason := l.Reason
+		if l.PluginName != "" {
+			reason += " via " + l.PluginName
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s -> %s (%s)",
+			l.Strength, traces[l.FromTraceID].Value, traces[l.ToTraceID].Value, reason))
+	}
+	return lines
+}
+
+func link(from, to int64, strength, reason, plugin string) database.IdentityLink {
+	return database.IdentityLink{
+		FromTraceID: from,
+		ToTraceID:   to,
+		Strength:    strength,
+		Reason:      reason,
+		PluginName:  plugin,
+	}
+}
+
+// displayNameLinks links clusters whose display names match once case,
+// spacing and punctuation are ignored. Each pair of clusters is linked once,
+// between the identifiers that carried the names.
+func displayNameLinks(names map[int64][]string, clusterOf map[int64]int) []database.IdentityLink {
+	owners := make(map[string][]int64) // normalized name -> identifier IDs
+	for id, ns := range names {
+		for _, n := range ns {
+			if key := normalizeName(n); key != "" {
+				owners[key] = appendUnique(owners[key], id)
+			}
+		}
+	}
+
+	var links []database.IdentityLink
+	keys := make([]string, 0, len(owners))
+	for k := range owners {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		ids := owners[key]
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for i := 0; i < len(ids); i++ {
+			for j := i + 1; j < len(ids); j++ {
+				if clusterOf[ids[i]] == clusterOf[ids[j]] {
+					continue
+				}
+				links = append(links, link(ids[i], ids[j], database.IdentityLinkWeak, ReasonDisplayName, ""))
+			}
+		}
+	}
+	return links
+}
+
+// similarHandleLinks compares bare handles, and email local parts, across
+// clusters.
+func similarHandleLinks(clusters [][]int64, byID map[int64]database.Trace, clusterOf map[int64]int) []database.IdentityLink {
+	type handle struct {
+		id  int64
+		key string
+	}
+	var handles []handle
+	for _, members := range clusters {
+		for _, id := range members {
+			t := byID[id]
+			var key string
+			switch {
+			case handleTypes[t.Type]:
+				key = normalizeHandle(t.Value)
+			case t.Type == entities.Email:
+				if at := strings.LastIndex(t.Value, "@"); at > 0 {
+					key = normalizeHandle(t.Value[:at])
+				}
+			}
+			if key != "" {
+				handles = append(handles, handle{id: id, key: key})
+			}
+		}
+	}
+
+	var links []database.IdentityLink
+	for i := 0; i < len(handles); i++ {
+		for j := i + 1; j < len(handles); j++ {
+			a, b := handles[i], handles[j]
+			if clusterOf[a.id] == clusterOf[b.id] || !similarHandles(a.key, b.key) {
+				continue
+			}
+			links = append(links, link(a.id, b.id, database.IdentityLinkWeak, ReasonSimilarUsername, ""))
+		}
+	}
+	return links
+}
+
+func similarHandles(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if len(a) < minFuzzyHandleLength || len(b) < minFuzzyHandleLength {
+		return false
+	}
+	ra, rb := []rune(a), []rune(b)
+	distance := levenshtein.DistanceForStrings(ra, rb, levenshtein.DefaultOptions)
+	longest := max(len(ra), len(rb))
+	return 1-float64(distance)/float64(longest) >= usernameSimilarity
+}
+
+// normalizeHandle strips platform prefixes and separators so that
+// "@John.Doe", "u/john_doe" and "johndoe" compare equal.
+func normalizeHandle(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	value = strings.TrimPrefix(value, "@")
+	value = strings.TrimPrefix(value, "u/")
+	return strings.Map(func(r rune) rune {
+		if r == '.' || r == '_' || r == '-' {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+// normalizeName folds case and collapses punctuation and spacing, so
+// "John  Doe" and "john doe." match while "Doe, John" does not: word order
+// is kept.
+func normalizeName(value string) string {
+	fields := strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// clusterLabel prefers a display name, then an email, then a username, and
+// finally any member value, choosing the alphabetically first of each.
+func clusterLabel(members []int64, names []string, byID map[int64]database.Trace) string {
+	if len(names) > 0 {
+		sorted := append([]string(nil), names...)
+		sort.Strings(sorted)
+		return sorted[0]
+	}
+	for _, preferred := range []entities.TraceType{entities.Email, entities.Username} {
+		var candidates []string
+		for _, id := range members {
+			if byID[id].Type == preferred {
+				candidates = append(candidates, byID[id].Value)
+			}
+		}
+		if len(candidates) > 0 {
+			sort.Strings(candidates)
+			return candidates[0]
+		}
+	}
+	values := make([]string, 0, len(members))
+	for _, id := range members {
+		values = append(values, byID[id].Value)
+	}
+	sort.Strings(values)
+	return values[0]
+}
+
+func dedupeLinks(links []database.IdentityLink) []database.IdentityLink {
+	type key struct {
+		from, to int64
+		reason   string
+	}
+	seen := make(map[key]bool, len(links))
+	out := make([]database.IdentityLink, 0, len(links))
+	for _, l := range links {
+		from, to := l.FromTraceID, l.ToTraceID
+		if from > to {
+			from, to = to, from
+		}
+		k := key{from, to, l.Reason}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, l)
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].FromTraceID != out[j].FromTraceID {
+			return out[i].FromTraceID < out[j].FromTraceID
+		}
+		return out[i].ToTraceID < out[j].ToTraceID
+	})
+	return out
+}
+
+func appendUnique(ids []int64, id int64) []int64 {
+	for _, existing := range ids {
+		if existing == id {
+			return ids
+		}
+	}
+	return append(ids, id)
+}
+
+// unionFind is a minimal disjoint-set over trace IDs.
+type unionFind struct {
+	parent map[int64]int64
+}
+
+func newUnionFind() *unionFind {
+	return &unionFind{parent: make(map[int64]int64)}
+}
+
+func (u *unionFind) add(id int64) {
+	if _, ok := u.parent[id]; !ok {
+		u.parent[id] = id
+	}
+}
+
+func (u *unionFind) find(id int64) int64 {
+	for u.parent[id] != id {
+		u.parent[id] = u.parent[u.parent[id]]
+		id = u.parent[id]
+	}
+	return id
+}
+
+func (u *unionFind) union(a, b int64) {
+	u.add(a)
+	u.add(b)
+	ra, rb := u.find(a), u.find(b)
+	if ra == rb {
+		return
+	}
+	if ra < rb {
+		u.parent[rb] = ra
+	} else {
+		u.parent[ra] = rb
+	}
+}
+
+// groups returns every set's members sorted by ID, with sets ordered by
+// their smallest member.
+func (u *unionFind) groups() [][]int64 {
+	byRoot := make(map[int64][]int64)
+	for id := range u.parent {
+		root := u.find(id)
+		byRoot[root] = append(byRoot[root], id)
+	}
+	groups := make([][]int64, 0, len(byRoot))
+	for _, members := range byRoot {
+		sort.Slice(members, func(i, j int) bool { return members[i] < members[j] })
+		groups = append(groups, members)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i][0] < groups[j][0] })
+	return groups
+}
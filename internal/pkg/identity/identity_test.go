@@ -0,0 +1,155 @@
+package identity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/database"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+// graph builds nodes and edges from (parent, child, plugin) triples over
+// traces keyed by value.
+type graph struct {
+	nodes []database.Trace
+	edges []database.TraceEdge
+	ids   map[string]int64
+}
+
+func newGraph() *graph {
+	return &graph{ids: make(map[string]int64)}
+}
+
+func (g *graph) node(value string, t entities.TraceType) int64 {
+	if id, ok := g.ids[value]; ok {
+		return id
+	}
+	id := int64(len(g.nodes) + 1)
+	g.ids[value] = id
+	g.nodes = append(g.nodes, database.Trace{ID: id, Value: value, Type: t})
+	return id
+}
+
+func (g *graph) edge(parent, child int64, plugin string) {
+	g.edges = append(g.edges, database.TraceEdge{ParentTraceID: &parent, ChildTraceID: child, PluginName: plugin})
+}
+
+func clusterContaining(clusters []database.IdentityCluster, id int64) *database.IdentityCluster {
+	for i := range clusters {
+		for _, m := range clusters[i].MemberIDs {
+			if m == id {
+				return &clusters[i]
+			}
+		}
+	}
+	return nil
+}
+
+func linksWithReason(links []database.IdentityLink, reason string) []database.IdentityLink {
+	var out []database.IdentityLink
+	for _, l := range links {
+		if l.Reason == reason {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func TestResolve_StrongLinksMergeClusters(t *testing.T) {
+	g := newGraph()
+	user := g.node("jdoe", entities.Username)
+	email := g.node("jdoe@example.com", entities.Email)
+	name := g.node("John Doe", entities.Name)
+	twitter := g.node("johnd", entities.Twitter)
+	key := g.node("ssh-ed25519 AAAA", entities.SSHKey)
+	g.edge(user, email, "GitHubProfilePlugin")
+	g.edge(user, name, "GitHubProfilePlugin")
+	g.edge(email, twitter, "GravatarPlugin")
+	g.edge(user, key, "GitHubKeysPlugin")
+
+	clusters, links := Resolve(g.nodes, g.edges)
+
+	require.Len(t, clusters, 1)
+	assert.Equal(t, "John Doe", clusters[0].Label)
+	assert.ElementsMatch(t, []int64{user, email, twitter, key}, clusters[0].MemberIDs)
+	assert.Nil(t, clusterContaining(clusters, name), "names are attributes, not members")
+
+	assert.Len(t, linksWithReason(links, ReasonProfileListed), 1)
+	assert.Len(t, linksWithReason(links, ReasonGravatarVerified), 1)
+	assert.Len(t, linksWithReason(links, ReasonPublishedKey), 1)
+	for _, l := range links {
+		assert.Equal(t, database.IdentityLinkStrong, l.Strength)
+	}
+}
+
+func TestResolve_SharedKeyJoinsAccounts(t *testing.T) {
+	g := newGraph()
+	a := g.node("alice", entities.Username)
+	b := g.node("alice-work", entities.Username)
+	key := g.node("ssh-rsa AAAA", entities.SSHKey)
+	g.edge(a, key, "GitHubKeysPlugin")
+	g.edge(b, key, "GitHubKeysPlugin")
+
+	clusters, links := Resolve(g.nodes, g.edges)
+
+	require.Len(t, clusters, 1)
+	shared := linksWithReason(links, ReasonSharedKey)
+	require.Len(t, shared, 1)
+	assert.ElementsMatch(t, []int64{a, b}, []int64{shared[0].FromTraceID, shared[0].ToTraceID})
+}
+
+func TestResolve_WeakSignalsDoNotMerge(t *testing.T) {
+	g := newGraph()
+	u1 := g.node("john.doe", entities.Username)
+	u2 := g.node("johndoe", entities.Username)
+	n1 := g.node("John Doe", entities.Name)
+	e1 := g.node("jd@example.com", entities.Email)
+	n2 := g.node("john  doe", entities.Name)
+	profile := g.node("https://example.social/john.doe", entities.SocialGeneric)
+	g.edge(u1, n1, "GitHubProfilePlugin")
+	g.edge(e1, n2, "GravatarPlugin")
+	g.edge(u1, profile, "SocialProfilesPlugin")
+
+	clusters, links := Resolve(g.nodes, g.edges)
+
+	assert.Len(t, clusters, 4, "every identifier gets its own cluster")
+	assert.NotEqual(t, clusterContaining(clusters, u1), clusterContaining(clusters, u2))
+
+	for _, l := range links {
+		assert.Equal(t, database.IdentityLinkWeak, l.Strength)
+	}
+	assert.Len(t, linksWithReason(links, ReasonDisplayName), 1)
+	assert.Len(t, linksWithReason(links, ReasonUsernameReuse), 1)
+	similar := linksWithReason(links, ReasonSimilarUsername)
+	require.Len(t, similar, 1)
+	assert.ElementsMatch(t, []int64{u1, u2}, []int64{similar[0].FromTraceID, similar[0].ToTraceID})
+}
+
+func TestResolve_UnrelatedPluginsAreIgnored(t *testing.T) {
+	g := newGraph()
+	user := g.node("jdoe", entities.Username)
+	author := g.node("coauthor@example.com", entities.Email)
+	g.edge(user, author, "GitHubIdentityPlugin")
+
+	clusters, links := Resolve(g.nodes, g.edges)
+
+	assert.Len(t, clusters, 2)
+	assert.Empty(t, links)
+}
+
+func TestSimilarHandles(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"johndoe", "johndoe", true},
+		{"johndoe1", "johndoe", true},
+		{"jd", "jd1", false},
+		{"johndoe", "janedoe", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, similarHandles(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+	}
+}
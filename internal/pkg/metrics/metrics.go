@@ -28,6 +28,11 @@ type MetricsCollector struct {
 	// Plugin metrics
 	pluginMetrics map[string]*PluginMetrics
 
+	// Cumulative series for the Prometheus endpoint, which unlike the
+	// sliding windows above never drop observations
+	pluginRuns     map[pluginRunKey]*pluginRun
+	traceDurations map[entities.TraceType]*Histogram
+
 	// Mutex for complex data structures
 	mu sync.RWMutex
 
@@ -54,6 +59,19 @@ type PluginMetrics struct {
 	LastExecution time.Time
 }
 
+// pluginRunKey labels a plugin's runs by the trace type it followed
+type pluginRunKey struct {
+	plugin    string
+	traceType entities.TraceType
+}
+
+// pluginRun counts one plugin's runs against one trace type
+type pluginRun struct {
+	executions uint64
+	errors     uint64
+	duration   *Histogram
+}
+
 // Summary provides a comprehensive metrics summary
 type Summary struct {
 	Uptime            time.Duration                            `json:"uptime"`
@@ -77,6 +95,8 @@ func NewMetricsCollector() *MetricsCollector {
 		pluginResponseTimes: make(map[string][]time.Duration),
 		traceTypeMetrics:    make(map[entities.TraceType]*TraceTypeMetrics),
 		pluginMetrics:       make(map[string]*PluginMetrics),
+		pluginRuns:          make(map[pluginRunKey]*pluginRun),
+		traceDurations:      make(map[entities.TraceType]*Histogram),
 		startTime:           time.Now(),
 	}
 }
@@ -132,11 +152,29 @@ func (m *MetricsCollector) RecordProcessingTime(duration time.Duration) {
 	}
 }
 
-// RecordPluginExecution records metrics for a plugin execution
-func (m *MetricsCollector) RecordPluginExecution(pluginName string, duration time.Duration, success bool) {
+// RecordPluginExecution records metrics for a plugin execution against a
+// trace of the given type
+func (m *MetricsCollector) RecordPluginExecution(pluginName string, traceType entities.TraceType, duration time.Duration, success bool) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	atomic.AddUint64(&m.pluginExecutions, 1)
+	if !success {
+		atomic.AddUint64(&m.pluginErrors, 1)
+	}
+
+	key := pluginRunKey{plugin: pluginName, traceType: traceType}
+	run, exists := m.pluginRuns[key]
+	if !exists {
+		run = &pluginRun{duration: NewHistogram()}
+		m.pluginRuns[key] = run
+	}
+	run.executions++
+	if !success {
+		run.errors++
+	}
+	run.duration.ObserveDuration(duration)
+
 	// Initialize plugin metrics if not exists
 	if _, exists := m.pluginMetrics[pluginName]; !exists {
 		m.pluginMetrics[pluginName] = &PluginMetrics{
@@ -182,6 +220,10 @@ func (m *MetricsCollector) RecordTraceTypeMetrics(traceType entities.TraceType,
 
 	if processed {
 		metrics.Processed++
+		if _, exists := m.traceDurations[traceType]; !exists {
+			m.traceDurations[traceType] = NewHistogram()
+		}
+		m.traceDurations[traceType].ObserveDuration(duration)
 	}
 
 	metrics.Discovered += uint64(discovered)
@@ -203,8 +245,8 @@ func (m *MetricsCollector) GetSummary() *Summary {
 
 	// Calculate success rate
 	var successRate float64
-	if m.pluginExecutions > 0 {
-		successRate = float64(m.pluginExecutions-m.pluginErrors) / float64(m.pluginExecutions) * 100
+	if executions := atomic.LoadUint64(&m.pluginExecutions); executions > 0 {
+		successRate = float64(executions-atomic.LoadUint64(&m.pluginErrors)) / float64(executions) * 100
 	}
 
 	// Calculate average processing time
@@ -287,6 +329,8 @@ func (m *MetricsCollector) Reset() {
 	m.pluginResponseTimes = make(map[string][]time.Duration)
 	m.traceTypeMetrics = make(map[entities.TraceType]*TraceTypeMetrics)
 	m.pluginMetrics = make(map[string]*PluginMetrics)
+	m.pluginRuns = make(map[pluginRunKey]*pluginRun)
+	m.traceDurations = make(map[entities.TraceType]*Histogram)
 	m.startTime = time.Now()
 }
 
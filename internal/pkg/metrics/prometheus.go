@@ -0,0 +1,183 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrometheusContentType is the media type of the text exposition format.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Labels are the label pairs of one sample.
+type Labels map[string]string
+
+// Sample is one counter or gauge value.
+type Sample struct {
+	Labels Labels
+	Value  float64
+}
+
+// HistogramSample is one labelled histogram.
+type HistogramSample struct {
+	Labels Labels
+	HistogramSnapshot
+}
+
+// Collector writes its metrics into an exposition on every scrape.
+type Collector interface {
+	Collect(e *Exposition)
+}
+
+// Exposition builds a page in the Prometheus text exposition format.
+// Samples within a family are sorted by their labels, so identical state
+// always renders identically.
+type Exposition struct {
+	buf bytes.Buffer
+}
+
+// Counter writes a counter family.
+func (e *Exposition) Counter(name, help string, samples ...Sample) {
+	e.family(name, help, "counter", samples)
+}
+
+// Gauge writes a gauge family.
+func (e *Exposition) Gauge(name, help string, samples ...Sample) {
+	e.family(name, help, "gauge", samples)
+}
+
+// Histogram writes a histogram family as its _bucket, _sum and _count series.
+func (e *Exposition) Histogram(name, help string, samples ...HistogramSample) {
+	e.header(name, help, "histogram")
+	sort.Slice(samples, func(i, j int) bool {
+		return formatLabels(samples[i].Labels, "", 0) < formatLabels(samples[j].Labels, "", 0)
+	})
+	for _, sample := range samples {
+		for i, bound := range sample.Bounds {
+			e.line(name+"_bucket", formatLabels(sample.Labels, "le", bound), float64(sample.Counts[i]))
+		}
+		e.line(name+"_bucket", formatLabels(sample.Labels, "le", math.Inf(1)), float64(sample.Count))
+		e.line(name+"_sum", formatLabels(sample.Labels, "", 0), sample.Sum)
+		e.line(name+"_count", formatLabels(sample.Labels, "", 0), float64(sample.Count))
+	}
+}
+
+// Bytes returns the page written so far.
+func (e *Exposition) Bytes() []byte {
+	return e.buf.Bytes()
+}
+
+func (e *Exposition) family(name, help, kind string, samples []Sample) {
+	e.header(name, help, kind)
+	sort.Slice(samples, func(i, j int) bool {
+		return formatLabels(samples[i].Labels, "", 0) < formatLabels(samples[j].Labels, "", 0)
+	})
+	for _, sample := range samples {
+		e.line(name, formatLabels(sample.Labels, "", 0), sample.Value)
+	}
+}
+
+func (e *Exposition) header(name, help, kind string) {
+	help = strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+	fmt.Fprintf(&e.buf, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func (e *Exposition) line(name, labels string, value float64) {
+	fmt.Fprintf(&e.buf, "%s%s %s\n", name, labels, formatValue(value))
+}
+
+// formatLabels renders labels as {k="v",...} in key order. A non-empty le
+// appends that bucket bound last, as Prometheus expects.
+func formatLabels(labels Labels, le string, bound float64) string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys)+1)
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	for _, key := range keys {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, key, escape.Replace(labels[key])))
+	}
+	if le != "" {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, le, formatValue(bound)))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// Handler serves the collectors' metrics in the Prometheus text format.
+func Handler(collectors ...Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var e Exposition
+		for _, collector := range collectors {
+			collector.Collect(&e)
+		}
+		w.Header().Set("Content-Type", PrometheusContentType)
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write(e.Bytes())
+	})
+}
+
+// Collect writes the collector's plugin and trace metrics. Plugin series are
+// labelled by plugin and by the type of trace the plugin followed.
+func (m *MetricsCollector) Collect(e *Exposition) {
+	m.mu.RLock()
+	var processed, discovered []Sample
+	var durations []HistogramSample
+	for traceType, metrics := range m.traceTypeMetrics {
+		labels := Labels{"trace_type": string(traceType)}
+		processed = append(processed, Sample{Labels: labels, Value: float64(metrics.Processed)})
+		discovered = append(discovered, Sample{Labels: labels, Value: float64(metrics.Discovered)})
+	}
+	for traceType, histogram := range m.traceDurations {
+		durations = append(durations, HistogramSample{
+			Labels:            Labels{"trace_type": string(traceType)},
+			HistogramSnapshot: histogram.Snapshot(),
+		})
+	}
+
+	var executions, failures []Sample
+	var runs []HistogramSample
+	for key, run := range m.pluginRuns {
+		labels := Labels{"plugin": key.plugin, "trace_type": string(key.traceType)}
+		executions = append(executions, Sample{Labels: labels, Value: float64(run.executions)})
+		failures = append(failures, Sample{Labels: labels, Value: float64(run.errors)})
+		runs = append(runs, HistogramSample{Labels: labels, HistogramSnapshot: run.duration.Snapshot()})
+	}
+	started := m.startTime
+	m.mu.RUnlock()
+
+	e.Gauge("deeper_start_time_seconds", "Unix time the metrics collector started.",
+		Sample{Value: float64(started.UnixNano()) / 1e9})
+	e.Counter("deeper_traces_processed_total", "Traces whose plugins ran to completion.", processed...)
+	e.Counter("deeper_trace_discoveries_total", "Traces discovered, by the type of the trace they came from.", discovered...)
+	e.Histogram("deeper_trace_processing_seconds", "Time to run every plugin on one trace.", durations...)
+	e.Counter("deeper_plugin_executions_total", "Plugin runs.", executions...)
+	e.Counter("deeper_plugin_errors_total", "Plugin runs that returned an error.", failures...)
+	e.Histogram("deeper_plugin_duration_seconds", "Time one plugin spent on one trace.", runs...)
+}
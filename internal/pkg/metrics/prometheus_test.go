@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
+
+func TestHistogram_IsCumulative(t *testing.T) {
+	h := NewHistogram(1, 5)
+	h.Observe(0.5)
+	h.Observe(2)
+	h.Observe(10)
+
+	snapshot := h.Snapshot()
+	assert.Equal(t, []uint64{1, 2}, snapshot.Counts)
+	assert.Equal(t, uint64(3), snapshot.Count)
+	assert.InDelta(t, 12.5, snapshot.Sum, 1e-9)
+}
+
+func TestExposition_Format(t *testing.T) {
+	var e Exposition
+	e.Counter("deeper_things_total", "Things\nseen.",
+		Sample{Labels: Labels{"name": `b"\`}, Value: 2},
+		Sample{Labels: Labels{"name": "a"}, Value: 1})
+	e.Gauge("deeper_level", "Level.", Sample{Value: 0.5})
+	e.Histogram("deeper_wait_seconds", "Waits.",
+		HistogramSample{Labels: Labels{"kind": "x"}, HistogramSnapshot: HistogramSnapshot{
+			Bounds: []float64{0.1, 1}, Counts: []uint64{1, 3}, Count: 4, Sum: 7.25,
+		}})
+
+	assert.Equal(t, `# HELP deeper_things_total Things\nseen.
+# TYPE deeper_things_total counter
+deeper_things_total{name="a"} 1
+deeper_things_total{name="b\"\\"} 2
+# HELP deeper_level Level.
+# TYPE deeper_level gauge
+deeper_level 0.5
+# HELP deeper_wait_seconds Waits.
+# TYPE deeper_wait_seconds histogram
+deeper_wait_seconds_bucket{kind="x",le="0.1"} 1
+deeper_wait_seconds_bucket{kind="x",le="1"} 3
+deeper_wait_seconds_bucket{kind="x",le="+Inf"} 4
+deeper_wait_seconds_sum{kind="x"} 7.25
+deeper_wait_seconds_count{kind="x"} 4
+`, string(e.Bytes()))
+}
+
+func TestHandler_ServesCollectorMetrics(t *testing.T) {
+	collector := NewMetricsCollector()
+	collector.RecordPluginExecution("GithubPlugin", entities.Username, 300*time.Millisecond, true)
+	collector.RecordPluginExecution("GithubPlugin", entities.Username, 2*time.Second, false)
+	collector.RecordPluginExecution("GithubPlugin", entities.Email, time.Millisecond, true)
+	collector.RecordTraceTypeMetrics(entities.Username, true, 3, 2*time.Second)
+	collector.RecordTraceTypeMetrics(entities.Email, false, 0, 0)
+
+	rec := httptest.NewRecorder()
+	Handler(collector).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	resp := rec.Result()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, PrometheusContentType, resp.Header.Get("Content-Type"))
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	page := string(body)
+
+	for _, line := range []string{
+		`deeper_plugin_executions_total{plugin="GithubPlugin",trace_type="username"} 2`,
+		`deeper_plugin_executions_total{plugin="GithubPlugin",trace_type="email"} 1`,
+		`deeper_plugin_errors_total{plugin="GithubPlugin",trace_type="username"} 1`,
+		`deeper_plugin_duration_seconds_bucket{plugin="GithubPlugin",trace_type="username",le="0.25"} 0`,
+		`deeper_plugin_duration_seconds_bucket{plugin="GithubPlugin",trace_type="username",le="0.5"} 1`,
+		`deeper_plugin_duration_seconds_count{plugin="GithubPlugin",trace_type="username"} 2`,
+		`deeper_traces_processed_total{trace_type="username"} 1`,
+		`deeper_traces_processed_total{trace_type="email"} 0`,
+		`deeper_trace_discoveries_total{trace_type="username"} 3`,
+		`deeper_trace_processing_seconds_count{trace_type="username"} 1`,
+	} {
+		assert.Contains(t, page, line+"\n")
+	}
+	assert.NotContains(t, page, `deeper_trace_processing_seconds_count{trace_type="email"}`, "skipped traces are not timed")
+
+	summary := collector.GetSummary()
+	assert.Equal(t, uint64(3), summary.PluginExecutions)
+	assert.Equal(t, uint64(1), summary.PluginErrors)
+
+	rec = httptest.NewRecorder()
+	Handler(collector).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/metrics", strings.NewReader("")))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
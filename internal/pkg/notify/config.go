@@ -0,0 +1,149 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Config is the notification config file: a list of sinks, each with its
+// own filter and retry policy.
+//
+//	{
+//	  "sinks": [
+//	    {"type": "webhook", "url": "https://siem.example/hook", "secret": "s3cret"},
+//	    {"type": "slack", "url": "https://hooks.slack.com/services/...", "kinds": ["scan_finished", "watch_alert"]},
+//	    {"type": "email", "smtp": "smtp.example.com:587", "from": "deeper@example.com", "to": ["soc@example.com"],
+//	     "username": "deeper", "password": "...", "kinds": ["watch_alert"], "trace_types": ["ssh_key", "pgp_key"]},
+//	    {"type": "file", "path": "/var/log/deeper-notify.ndjson"},
+//	    {"type": "exec", "command": ["/usr/local/bin/page-oncall"], "kinds": ["scan_failed"], "retries": 5, "backoff": "10s"}
+//	  ]
+//	}
+type Config struct {
+	Sinks []SinkConfig `json:"sinks"`
+}
+
+// SinkConfig configures one sink. Which fields apply depends on Type:
+// webhook, slack, mattermost, email, file or exec.
+type SinkConfig struct {
+	Type string `json:"type"`
+	// Name identifies the sink; it defaults to the type and position.
+	Name string `json:"name,omitempty"`
+
+	URL     string `json:"url,omitempty"`
+	Secret  string `json:"secret,omitempty"`
+	Channel string `json:"channel,omitempty"`
+
+	SMTP     string   `json:"smtp,omitempty"`
+	From     string   `json:"from,omitempty"`
+	To       []string `json:"to,omitempty"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+
+	Path    string   `json:"path,omitempty"`
+	Command []string `json:"command,omitempty"`
+
+	Filter
+	// Retries is the number of delivery attempts, 3 by default.
+	Retries int `json:"retries,omitempty"`
+	// Backoff is the wait before the first retry, as a Go duration
+	// ("2s"), doubled after each failure. It defaults to 1s.
+	Backoff string `json:"backoff,omitempty"`
+	// Timeout bounds each HTTP delivery, 30s by default.
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// Load reads the config file at path and builds its notifier. A missing
+// file is no error: the notifier then has no sinks.
+func Load(path string) (*Notifier, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return New(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read notification config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse notification config %s: %w", path, err)
+	}
+	return FromConfig(cfg)
+}
+
+// FromConfig builds a notifier from a parsed config.
+func FromConfig(cfg Config) (*Notifier, error) {
+	routes := make([]Route, 0, len(cfg.Sinks))
+	for i, sc := range cfg.Sinks {
+		route, err := sc.route(i)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return New(routes...), nil
+}
+
+func (sc SinkConfig) route(i int) (Route, error) {
+	name := sc.Name
+	if name == "" {
+		name = fmt.Sprintf("%s#%d", sc.Type, i+1)
+	}
+	fail := func(format string, args ...interface{}) (Route, error) {
+		return Route{}, fmt.Errorf("notification sink %s: %s", name, fmt.Sprintf(format, args...))
+	}
+
+	route := Route{Filter: sc.Filter, Retry: DefaultRetry}
+	if sc.Retries > 0 {
+		route.Retry.Attempts = sc.Retries
+	}
+	if sc.Backoff != "" {
+		backoff, err := time.ParseDuration(sc.Backoff)
+		if err != nil {
+			return fail("invalid backoff %q", sc.Backoff)
+		}
+		route.Retry.Backoff = backoff
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	if sc.Timeout != "" {
+		timeout, err := time.ParseDuration(sc.Timeout)
+		if err != nil {
+			return fail("invalid timeout %q", sc.Timeout)
+		}
+		client.Timeout = timeout
+	}
+
+	switch sc.Type {
+	case "webhook":
+		if sc.URL == "" {
+			return fail("url is required")
+		}
+		route.Sink = &Webhook{SinkName: name, URL: sc.URL, Secret: sc.Secret, Client: client}
+	case FlavorSlack, FlavorMattermost:
+		if sc.URL == "" {
+			return fail("url is required")
+		}
+		route.Sink = &Chat{SinkName: name, Flavor: sc.Type, URL: sc.URL, Channel: sc.Channel, Client: client}
+	case "email":
+		if sc.SMTP == "" || sc.From == "" || len(sc.To) == 0 {
+			return fail("smtp, from and to are required")
+		}
+		route.Sink = &Email{SinkName: name, Addr: sc.SMTP, From: sc.From, To: sc.To, Username: sc.Username, Password: sc.Password}
+	case "file":
+		if sc.Path == "" {
+			return fail("path is required")
+		}
+		route.Sink = &File{SinkName: name, Path: sc.Path}
+	case "exec":
+		if len(sc.Command) == 0 {
+			return fail("command is required")
+		}
+		route.Sink = &Exec{SinkName: name, Command: sc.Command}
+	default:
+		return fail("unknown type %q (supported: webhook, slack, mattermost, email, file, exec)", sc.Type)
+	}
+	return route, nil
+}
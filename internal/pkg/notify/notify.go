@@ -0,0 +1,264 @@
+// Package notify pushes scan findings out to where analysts already look:
+// webhooks, Slack or Mattermost channels, email, a local file or a script.
+// A Notifier follows an engine's event bus, turns the events worth telling
+// someone about into notifications and hands each to every sink whose
+// filter accepts it, retrying failed deliveries.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
+)
+
+// Notification kinds.
+const (
+	ScanFinished    = "scan_finished"
+	ScanFailed      = "scan_failed"
+	BudgetExhausted = "budget_exhausted"
+	PluginFailed    = "plugin_failed"
+	// TraceFound is a new high-confidence trace, see HighConfidenceTypes.
+	TraceFound = "trace_found"
+	// WatchAlert is an alert raised by a watch run, see "deeper watch".
+	WatchAlert = "watch_alert"
+	// Test is sent by "deeper notify test".
+	Test = "test"
+)
+
+// HighConfidenceTypes are the trace types whose discovery is worth a
+// trace_found notification: identifiers that tie a subject to a person,
+// key, payment account or piece of infrastructure. Seeds and guessed
+// traces never notify.
+var HighConfidenceTypes = map[entities.TraceType]bool{
+	entities.Email: true, entities.Phone: true, entities.Name: true,
+	entities.Company: true, entities.SSHKey: true, entities.PGPKey: true,
+	entities.BitcoinAddress: true, entities.PayPalAccount: true,
+	entities.Domain: true, entities.Subdomain: true, entities.AnalyticsID: true,
+}
+
+// Notification is one thing worth telling someone about. Title is a one
+// line summary for chat and email; the other fields are for machines.
+type Notification struct {
+	Kind   string           `json:"kind"`
+	Time   time.Time        `json:"time"`
+	Title  string           `json:"title"`
+	ScanID int64            `json:"scan_id,omitempty"`
+	Trace  *events.TraceRef `json:"trace,omitempty"`
+	Parent *events.TraceRef `json:"parent,omitempty"`
+	Plugin string           `json:"plugin,omitempty"`
+	Count  int              `json:"count,omitempty"`
+	Error  string           `json:"error,omitempty"`
+	// WatchID and Alert identify a watch alert: the watch that raised it
+	// and the alert kind, such as new_subdomain.
+	WatchID int64  `json:"watch_id,omitempty"`
+	Alert   string `json:"alert,omitempty"`
+}
+
+// Sink delivers notifications somewhere.
+type Sink interface {
+	// Name identifies the sink in logs and "deeper notify test".
+	Name() string
+	Send(ctx context.Context, n Notification) error
+}
+
+// Filter selects the notifications a sink receives. Empty lists accept
+// everything; TraceTypes and Plugins only constrain notifications that
+// carry a trace or plugin.
+type Filter struct {
+	Kinds      []string `json:"kinds,omitempty"`
+	TraceTypes []string `json:"trace_types,omitempty"`
+	Plugins    []string `json:"plugins,omitempty"`
+}
+
+// Match reports whether the filter accepts n.
+func (f Filter) Match(n Notification) bool {
+	if len(f.Kinds) > 0 && !contains(f.Kinds, n.Kind) {
+		return false
+	}
+	if len(f.TraceTypes) > 0 && n.Trace != nil && !contains(f.TraceTypes, string(n.Trace.Type)) {
+		return false
+	}
+	if len(f.Plugins) > 0 && n.Plugin != "" && !contains(f.Plugins, n.Plugin) {
+		return false
+	}
+	return true
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Retry is how often and how patiently a failed delivery is retried. The
+// wait doubles after every failed attempt.
+type Retry struct {
+	Attempts int
+	Backoff  time.Duration
+}
+
+// DefaultRetry makes three attempts, a second and then two apart.
+var DefaultRetry = Retry{Attempts: 3, Backoff: time.Second}
+
+// Route is a sink with the filter and retry policy it is used with.
+type Route struct {
+	Sink   Sink
+	Filter Filter
+	Retry  Retry
+}
+
+// Notifier sends notifications to routes.
+type Notifier struct {
+	routes []Route
+}
+
+// New creates a notifier over routes.
+func New(routes ...Route) *Notifier {
+	return &Notifier{routes: routes}
+}
+
+// Routes returns the notifier's routes.
+func (n *Notifier) Routes() []Route {
+	return n.routes
+}
+
+// Notify delivers note to every route whose filter accepts it, and
+// returns the errors of the deliveries that failed every attempt.
+func (n *Notifier) Notify(ctx context.Context, note Notification) []error {
+	if note.Time.IsZero() {
+		note.Time = time.Now()
+	}
+	var errs []error
+	for _, route := range n.routes {
+		if !route.Filter.Match(note) {
+			continue
+		}
+		if err := deliver(ctx, route, note); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", route.Sink.Name(), err))
+		}
+	}
+	return errs
+}
+
+func deliver(ctx context.Context, route Route, note Notification) error {
+	attempts := route.Retry.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	wait := route.Retry.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = route.Sink.Send(ctx, note); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			return fmt.Errorf("giving up after %d attempts: %w", attempts, err)
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// Follow subscribes to bus and notifies of its events in the background,
+// so slow sinks never hold up the scan publishing them: once the queue of
+// undelivered notifications is full, new ones are dropped and logged. The
+// returned function unsubscribes and waits until every queued
+// notification has been delivered or given up on; failures are logged.
+func (n *Notifier) Follow(bus *events.Bus) func() {
+	if len(n.routes) == 0 {
+		return func() {}
+	}
+
+	ch, unsubscribe := bus.Subscribe(256)
+	queue := make(chan Notification, 1024)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer close(queue)
+		for ev := range ch {
+			note, ok := FromEvent(ev)
+			if !ok {
+				continue
+			}
+			select {
+			case queue <- note:
+			default:
+				log.Warn().Msgf("Notification queue is full; dropped %s notification", note.Kind)
+			}
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for note := range queue {
+			n.notifyAndLog(note)
+		}
+	}()
+
+	return func() {
+		unsubscribe()
+		wg.Wait()
+	}
+}
+
+// NotifyAll delivers notes in order, logging failed deliveries.
+func (n *Notifier) NotifyAll(notes []Notification) {
+	for _, note := range notes {
+		n.notifyAndLog(note)
+	}
+}
+
+func (n *Notifier) notifyAndLog(note Notification) {
+	for _, err := range n.Notify(context.Background(), note) {
+		log.Warn().Err(err).Msgf("Failed to deliver %s notification", note.Kind)
+	}
+}
+
+// FromEvent turns a scan event into a notification, or reports false for
+// events nobody is notified of.
+func FromEvent(ev events.Event) (Notification, bool) {
+	note := Notification{
+		Kind:   string(ev.Type),
+		Time:   ev.Time,
+		ScanID: ev.ScanID,
+		Trace:  ev.Trace,
+		Parent: ev.Parent,
+		Plugin: ev.Plugin,
+		Count:  ev.Count,
+		Error:  ev.Error,
+	}
+	switch ev.Type {
+	case events.ScanFinished:
+		note.Title = fmt.Sprintf("Scan %d finished with %d traces", ev.ScanID, ev.Count)
+	case events.BudgetExhausted:
+		note.Title = fmt.Sprintf("Scan %d ran out of time with %d traces left unexpanded", ev.ScanID, ev.Count)
+	case events.PluginFailed:
+		note.Title = fmt.Sprintf("%s failed in scan %d", ev.Plugin, ev.ScanID)
+		if ev.Trace != nil {
+			note.Title = fmt.Sprintf("%s failed on %s in scan %d", ev.Plugin, ev.Trace.Value, ev.ScanID)
+		}
+	case events.TraceDiscovered:
+		if ev.Parent == nil || ev.Guessed || ev.Trace == nil || !HighConfidenceTypes[ev.Trace.Type] {
+			return Notification{}, false
+		}
+		note.Kind = TraceFound
+		note.Title = fmt.Sprintf("New %s in scan %d: %s", ev.Trace.Type, ev.ScanID, ev.Trace.Value)
+	default:
+		return Notification{}, false
+	}
+	return note, true
+}
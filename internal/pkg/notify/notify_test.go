@@ -0,0 +1,256 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/events"
+)
+
+// recorder is a local stand-in for a webhook receiver. It fails the first
+// failures requests with a 503.
+type recorder struct {
+	mu       sync.Mutex
+	failures int
+	bodies   [][]byte
+	headers  []http.Header
+}
+
+func (rec *recorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.failures > 0 {
+		rec.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	rec.bodies = append(rec.bodies, body)
+	rec.headers = append(rec.headers, r.Header.Clone())
+}
+
+func (rec *recorder) received() [][]byte {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return rec.bodies
+}
+
+func TestWebhook_SignsBodyAndRetries(t *testing.T) {
+	rec := &recorder{failures: 2}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	n := New(Route{
+		Sink:  &Webhook{SinkName: "siem", URL: server.URL, Secret: "s3cret"},
+		Retry: Retry{Attempts: 3, Backoff: time.Millisecond},
+	})
+	errs := n.Notify(context.Background(), Notification{Kind: ScanFinished, ScanID: 7, Title: "Scan 7 finished", Count: 12})
+	require.Empty(t, errs)
+
+	require.Len(t, rec.bodies, 1, "delivered on the third attempt")
+	assert.Equal(t, Sign([]byte("s3cret"), rec.bodies[0]), rec.headers[0].Get(SignatureHeader))
+	assert.Equal(t, ScanFinished, rec.headers[0].Get(EventHeader))
+	var got Notification
+	require.NoError(t, json.Unmarshal(rec.bodies[0], &got))
+	assert.Equal(t, int64(7), got.ScanID)
+	assert.False(t, got.Time.IsZero())
+
+	rec.failures = 5
+	errs = n.Notify(context.Background(), Notification{Kind: ScanFinished})
+	require.Len(t, errs, 1)
+	assert.Contains(t, errs[0].Error(), "giving up after 3 attempts")
+}
+
+func TestChat_SlackAndMattermostPayloads(t *testing.T) {
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	note := Notification{Kind: PluginFailed, Title: "CrtShPlugin failed", Error: "timeout"}
+	for _, flavor := range []string{FlavorSlack, FlavorMattermost} {
+		sink := &Chat{SinkName: flavor, Flavor: flavor, URL: server.URL, Channel: "#osint"}
+		require.NoError(t, sink.Send(context.Background(), note))
+	}
+
+	var slack, mattermost map[string]string
+	require.NoError(t, json.Unmarshal(rec.bodies[0], &slack))
+	require.NoError(t, json.Unmarshal(rec.bodies[1], &mattermost))
+	assert.Equal(t, "*deeper* CrtShPlugin failed\n> timeout", slack["text"])
+	assert.Equal(t, "#osint", slack["channel"])
+	assert.Equal(t, "**deeper** CrtShPlugin failed\n> timeout", mattermost["text"])
+	assert.Equal(t, "deeper", mattermost["username"])
+}
+
+func TestFilter_Match(t *testing.T) {
+	email := Notification{Kind: TraceFound, Trace: &events.TraceRef{Value: "a@b.c", Type: entities.Email}, Plugin: "GitHubProfilePlugin"}
+	finished := Notification{Kind: ScanFinished}
+
+	assert.True(t, Filter{}.Match(email))
+	assert.False(t, Filter{Kinds: []string{ScanFinished}}.Match(email))
+	assert.True(t, Filter{TraceTypes: []string{"email"}}.Match(email))
+	assert.False(t, Filter{TraceTypes: []string{"ssh_key"}}.Match(email))
+	assert.True(t, Filter{TraceTypes: []string{"ssh_key"}}.Match(finished), "trace types only constrain notifications with a trace")
+	assert.False(t, Filter{Plugins: []string{"CrtShPlugin"}}.Match(email))
+}
+
+func TestFromEvent_OnlyNotableEvents(t *testing.T) {
+	parent := &events.TraceRef{Value: "jdoe", Type: entities.Username}
+	email := &events.TraceRef{Value: "jdoe@example.com", Type: entities.Email}
+	url := &events.TraceRef{Value: "https://example.com", Type: entities.Url}
+
+	note, ok := FromEvent(events.Event{Type: events.TraceDiscovered, ScanID: 3, Trace: email, Parent: parent, Plugin: "GitHubProfilePlugin"})
+	require.True(t, ok)
+	assert.Equal(t, TraceFound, note.Kind)
+	assert.Equal(t, "New email in scan 3: jdoe@example.com", note.Title)
+
+	for _, ev := range []events.Event{
+		{Type: events.TraceDiscovered, Trace: email},                                // seed
+		{Type: events.TraceDiscovered, Trace: email, Parent: parent, Guessed: true}, // guess
+		{Type: events.TraceDiscovered, Trace: url, Parent: parent},                  // low value
+		{Type: events.PluginStarted, Plugin: "GitHubProfilePlugin"},
+	} {
+		_, ok := FromEvent(ev)
+		assert.False(t, ok, "%+v", ev)
+	}
+
+	note, ok = FromEvent(events.Event{Type: events.BudgetExhausted, ScanID: 3, Count: 40})
+	require.True(t, ok)
+	assert.Contains(t, note.Title, "40 traces left unexpanded")
+}
+
+func TestNotifier_FollowsBus(t *testing.T) {
+	rec := &recorder{}
+	server := httptest.NewServer(rec)
+	defer server.Close()
+
+	n := New(Route{Sink: &Webhook{SinkName: "hook", URL: server.URL}, Filter: Filter{Kinds: []string{ScanFinished}}})
+	bus := events.NewBus()
+	stop := n.Follow(bus)
+	bus.Publish(events.Event{Type: events.ScanStarted, ScanID: 1})
+	bus.Publish(events.Event{Type: events.ScanFinished, ScanID: 1, Count: 5})
+	stop()
+
+	bodies := rec.received()
+	require.Len(t, bodies, 1, "stop waits for queued deliveries")
+	assert.Contains(t, string(bodies[0]), `"kind":"scan_finished"`)
+}
+
+// hangingSink blocks every send until released.
+type hangingSink struct {
+	release chan struct{}
+}
+
+func (s *hangingSink) Name() string { return "hanging" }
+
+func (s *hangingSink) Send(ctx context.Context, note Notification) error {
+	<-s.release
+	return nil
+}
+
+func TestNotifier_FollowNeverBlocksPublish(t *testing.T) {
+	sink := &hangingSink{release: make(chan struct{})}
+	n := New(Route{Sink: sink})
+	bus := events.NewBus()
+	stop := n.Follow(bus)
+
+	published := make(chan struct{})
+	go func() {
+		defer close(published)
+		for i := 0; i < 5000; i++ {
+			bus.Publish(events.Event{Type: events.ScanFinished, ScanID: int64(i)})
+		}
+	}()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked on a hanging sink")
+	}
+
+	close(sink.release)
+	stop()
+}
+
+func TestLocalSinks(t *testing.T) {
+	dir := t.TempDir()
+	note := Notification{Kind: WatchAlert, Title: "new_subdomain: dev.acme.com", Alert: "new_subdomain"}
+
+	file := &File{SinkName: "log", Path: filepath.Join(dir, "notify.ndjson")}
+	require.NoError(t, file.Send(context.Background(), note))
+	require.NoError(t, file.Send(context.Background(), note))
+	data, err := os.ReadFile(file.Path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(string(data), "\n"))
+
+	out := filepath.Join(dir, "exec.json")
+	x := &Exec{SinkName: "script", Command: []string{"sh", "-c", `cat > "$1"; echo "$DEEPER_NOTIFY_KIND" >> "$1"`, "sh", out}}
+	require.NoError(t, x.Send(context.Background(), note))
+	data, err = os.ReadFile(out)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"alert":"new_subdomain"`)
+	assert.Contains(t, string(data), "watch_alert\n")
+
+	assert.Error(t, (&Exec{SinkName: "fail", Command: []string{"false"}}).Send(context.Background(), note))
+}
+
+func TestEmail_ComposesMessage(t *testing.T) {
+	var gotAddr, gotFrom string
+	var gotTo []string
+	var gotMsg []byte
+	var gotAuth smtp.Auth
+	sink := &Email{SinkName: "mail", Addr: "smtp.example.com:587", From: "deeper@example.com", To: []string{"soc@example.com"}, Username: "deeper", Password: "pw",
+		send: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			gotAddr, gotAuth, gotFrom, gotTo, gotMsg = addr, a, from, to, msg
+			return nil
+		}}
+
+	require.NoError(t, sink.Send(context.Background(), Notification{Kind: ScanFinished, Title: "Scan 7 finished\nwith 12 traces", Time: time.Now()}))
+	assert.Equal(t, "smtp.example.com:587", gotAddr)
+	assert.NotNil(t, gotAuth)
+	assert.Equal(t, "deeper@example.com", gotFrom)
+	assert.Equal(t, []string{"soc@example.com"}, gotTo)
+	assert.Contains(t, string(gotMsg), "Subject: [deeper] Scan 7 finished with 12 traces\r\n")
+	assert.Contains(t, string(gotMsg), `"kind": "scan_finished"`)
+}
+
+func TestLoad_BuildsRoutesFromConfig(t *testing.T) {
+	dir := t.TempDir()
+	n, err := Load(filepath.Join(dir, "missing.json"))
+	require.NoError(t, err)
+	assert.Empty(t, n.Routes())
+
+	path := filepath.Join(dir, "notify.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"sinks": [
+		{"type": "webhook", "url": "http://127.0.0.1:9/hook", "secret": "x", "kinds": ["scan_finished"], "retries": 5, "backoff": "2s"},
+		{"type": "mattermost", "name": "ops", "url": "http://127.0.0.1:9/mm"},
+		{"type": "file", "path": "/tmp/deeper.ndjson", "trace_types": ["email"]}
+	]}`), 0o600))
+	n, err = Load(path)
+	require.NoError(t, err)
+	routes := n.Routes()
+	require.Len(t, routes, 3)
+	assert.Equal(t, "webhook#1", routes[0].Sink.Name())
+	assert.Equal(t, Retry{Attempts: 5, Backoff: 2 * time.Second}, routes[0].Retry)
+	assert.Equal(t, []string{ScanFinished}, routes[0].Filter.Kinds)
+	assert.Equal(t, "ops", routes[1].Sink.Name())
+	assert.Equal(t, DefaultRetry, routes[1].Retry)
+	assert.Equal(t, []string{"email"}, routes[2].Filter.TraceTypes)
+
+	_, err = FromConfig(Config{Sinks: []SinkConfig{{Type: "pager"}}})
+	assert.ErrorContains(t, err, "unknown type")
+	_, err = FromConfig(Config{Sinks: []SinkConfig{{Type: "email", SMTP: "localhost:25"}}})
+	assert.ErrorContains(t, err, "required")
+}
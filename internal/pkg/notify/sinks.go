@@ -0,0 +1,249 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/smtp"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SignatureHeader carries a webhook body's HMAC-SHA256 signature, as
+// "sha256=<hex>", when the webhook has a secret.
+const SignatureHeader = "X-Deeper-Signature"
+
+// EventHeader carries the notification kind on webhook requests.
+const EventHeader = "X-Deeper-Event"
+
+// Sign returns the signature of body under secret, as sent in
+// SignatureHeader. Receivers recompute it to verify a delivery.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Webhook POSTs each notification as JSON, signed with Secret if set.
+type Webhook struct {
+	SinkName string
+	URL      string
+	Secret   string
+	Client   *http.Client
+}
+
+// Name implements Sink.
+func (w *Webhook) Name() string { return w.SinkName }
+
+// Send implements Sink.
+func (w *Webhook) Send(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	header := http.Header{EventHeader: {n.Kind}}
+	if w.Secret != "" {
+		header.Set(SignatureHeader, Sign([]byte(w.Secret), body))
+	}
+	return postJSON(ctx, w.Client, w.URL, body, header)
+}
+
+// Chat flavours: the incoming-webhook payloads of Slack and Mattermost.
+const (
+	FlavorSlack      = "slack"
+	FlavorMattermost = "mattermost"
+)
+
+// Chat posts each notification's title to a Slack or Mattermost incoming
+// webhook. Other services that accept Slack's payload work with
+// FlavorSlack.
+type Chat struct {
+	SinkName string
+	Flavor   string
+	URL      string
+	// Channel overrides the webhook's default channel, if it allows that.
+	Channel string
+	Client  *http.Client
+}
+
+// Name implements Sink.
+func (c *Chat) Name() string { return c.SinkName }
+
+// Send implements Sink.
+func (c *Chat) Send(ctx context.Context, n Notification) error {
+	body, err := json.Marshal(c.payload(n))
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	return postJSON(ctx, c.Client, c.URL, body, nil)
+}
+
+func (c *Chat) payload(n Notification) map[string]interface{} {
+	// Slack's mrkdwn bolds with single asterisks, Mattermost's Markdown
+	// with double.
+	bold := "*"
+	if c.Flavor == FlavorMattermost {
+		bold = "**"
+	}
+	text := fmt.Sprintf("%sdeeper%s %s", bold, bold, n.Title)
+	if n.Error != "" {
+		text += "\n> " + n.Error
+	}
+
+	payload := map[string]interface{}{"text": text}
+	if c.Flavor == FlavorMattermost {
+		payload["username"] = "deeper"
+	}
+	if c.Channel != "" {
+		payload["channel"] = c.Channel
+	}
+	return payload
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, body []byte, header http.Header) error {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "deeper-notify")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post notification: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+	return nil
+}
+
+// Email sends each notification as a plain-text message over SMTP. Auth
+// is PLAIN when Username is set, which net/smtp only allows over TLS or
+// to localhost.
+type Email struct {
+	SinkName string
+	// Addr is the SMTP server as host:port.
+	Addr     string
+	From     string
+	To       []string
+	Username string
+	Password string
+
+	// send is smtp.SendMail; tests replace it.
+	send func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// Name implements Sink.
+func (e *Email) Name() string { return e.SinkName }
+
+// Send implements Sink.
+func (e *Email) Send(ctx context.Context, n Notification) error {
+	var auth smtp.Auth
+	if e.Username != "" {
+		host := e.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+	send := e.send
+	if send == nil {
+		send = smtp.SendMail
+	}
+	if err := send(e.Addr, auth, e.From, e.To, e.message(n)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+func (e *Email) message(n Notification) []byte {
+	details, _ := json.MarshalIndent(n, "", "  ")
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", e.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&b, "Subject: [deeper] %s\r\n", strings.NewReplacer("\r", " ", "\n", " ").Replace(n.Title))
+	fmt.Fprintf(&b, "Date: %s\r\n", n.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(n.Title + "\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(string(details), "\n", "\r\n") + "\r\n")
+	return []byte(b.String())
+}
+
+// File appends each notification to a file as one JSON line.
+type File struct {
+	SinkName string
+	Path     string
+
+	mu sync.Mutex
+}
+
+// Name implements Sink.
+func (f *File) Name() string { return f.SinkName }
+
+// Send implements Sink.
+func (f *File) Send(ctx context.Context, n Notification) error {
+	line, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	file, err := os.OpenFile(f.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", f.Path, err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write %s: %w", f.Path, err)
+	}
+	return file.Close()
+}
+
+// Exec runs a command for each notification, with the notification as
+// JSON on its standard input and its kind in DEEPER_NOTIFY_KIND. A
+// non-zero exit is a failed delivery.
+type Exec struct {
+	SinkName string
+	Command  []string
+}
+
+// Name implements Sink.
+func (x *Exec) Name() string { return x.SinkName }
+
+// Send implements Sink.
+func (x *Exec) Send(ctx context.Context, n Notification) error {
+	if len(x.Command) == 0 {
+		return fmt.Errorf("no command to run")
+	}
+	input, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, x.Command[0], x.Command[1:]...)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(), "DEEPER_NOTIFY_KIND="+n.Kind)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", x.Command[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
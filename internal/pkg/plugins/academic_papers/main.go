@@ -32,11 +32,15 @@ func (g *AcademicPapersPlugin) Register() error {
 }
 
 func (g *AcademicPapersPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return g.FollowTraceContext(context.Background(), trace)
+}
+
+func (g *AcademicPapersPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != entities.Username && trace.Type != entities.Name {
 		return nil, nil
 	}
 
-	urls, err := searchAuthorPapers(context.Background(), g.fetcher, trace.Value)
+	urls, err := searchAuthorPapers(ctx, g.fetcher, trace.Value)
 	if err != nil {
 		return nil, err
 	}
@@ -1,6 +1,10 @@
 package plugins
 
-import "github.com/smirnoffmg/deeper/internal/pkg/entities"
+import (
+	"context"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+)
 
 type DeeperPlugin interface {
 	Register() error
@@ -17,3 +21,13 @@ type DeeperPlugin interface {
 type TraceMatcher interface {
 	Matches(trace entities.Trace) bool
 }
+
+// ContextFollower lets a plugin take a context for the network calls it
+// makes. The processor calls FollowTraceContext instead of FollowTrace for
+// plugins implementing it, with a context carrying the plugin task's span,
+// so the plugin's HTTP, DNS and WHOIS calls appear beneath that task in a
+// scan's OpenTelemetry trace. The context carries no deadline; plugins keep
+// their own timeouts.
+type ContextFollower interface {
+	FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error)
+}
@@ -35,10 +35,14 @@ func (p *BlueskyProfilePlugin) Matches(trace entities.Trace) bool {
 }
 
 func (p *BlueskyProfilePlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *BlueskyProfilePlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if !p.Matches(trace) {
 		return nil, nil
 	}
-	return fetchProfile(context.Background(), p.fetcher, extractHandle(trace.Value))
+	return fetchProfile(ctx, p.fetcher, extractHandle(trace.Value))
 }
 
 func (p BlueskyProfilePlugin) String() string {
@@ -35,10 +35,14 @@ func (p *CodeforcesProfilePlugin) Matches(trace entities.Trace) bool {
 }
 
 func (p *CodeforcesProfilePlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *CodeforcesProfilePlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if !p.Matches(trace) {
 		return nil, nil
 	}
-	return fetchProfile(context.Background(), p.fetcher, extractHandle(trace.Value))
+	return fetchProfile(ctx, p.fetcher, extractHandle(trace.Value))
 }
 
 func (p CodeforcesProfilePlugin) String() string {
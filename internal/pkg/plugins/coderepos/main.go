@@ -1,13 +1,14 @@
 package coderepos
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"net/http"
 
 	"github.com/rs/zerolog/log"
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
 	"github.com/smirnoffmg/deeper/internal/pkg/state"
+	"github.com/smirnoffmg/deeper/internal/pkg/telemetry"
 )
 
 const InputTraceType = entities.Username
@@ -47,23 +48,27 @@ type GitLabRepo struct {
 }
 
 func (g *CodeRepositoriesPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return g.FollowTraceContext(context.Background(), trace)
+}
+
+func (g *CodeRepositoriesPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != InputTraceType {
 		return nil, nil
 	}
 
 	var newTraces []entities.Trace
 
-	githubRepos, err := fetchGitHubRepos(trace.Value)
+	githubRepos, err := fetchGitHubRepos(ctx, trace.Value)
 	if err == nil {
 		newTraces = append(newTraces, githubRepos...)
 	}
 
-	bitbucketRepos, err := fetchBitbucketRepos(trace.Value)
+	bitbucketRepos, err := fetchBitbucketRepos(ctx, trace.Value)
 	if err == nil {
 		newTraces = append(newTraces, bitbucketRepos...)
 	}
 
-	gitlabRepos, err := fetchGitLabRepos(trace.Value)
+	gitlabRepos, err := fetchGitLabRepos(ctx, trace.Value)
 	if err == nil {
 		newTraces = append(newTraces, gitlabRepos...)
 	}
@@ -71,9 +76,9 @@ func (g *CodeRepositoriesPlugin) FollowTrace(trace entities.Trace) ([]entities.T
 	return newTraces, nil
 }
 
-func fetchGitHubRepos(username string) ([]entities.Trace, error) {
+func fetchGitHubRepos(ctx context.Context, username string) ([]entities.Trace, error) {
 	url := fmt.Sprintf("https://api.github.com/users/%s/repos", username)
-	resp, err := http.Get(url)
+	resp, err := telemetry.Get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -94,9 +99,9 @@ func fetchGitHubRepos(username string) ([]entities.Trace, error) {
 	return traces, nil
 }
 
-func fetchBitbucketRepos(username string) ([]entities.Trace, error) {
+func fetchBitbucketRepos(ctx context.Context, username string) ([]entities.Trace, error) {
 	url := fmt.Sprintf("https://api.bitbucket.org/2.0/repositories/%s", username)
-	resp, err := http.Get(url)
+	resp, err := telemetry.Get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -119,9 +124,9 @@ func fetchBitbucketRepos(username string) ([]entities.Trace, error) {
 	return traces, nil
 }
 
-func fetchGitLabRepos(username string) ([]entities.Trace, error) {
+func fetchGitLabRepos(ctx context.Context, username string) ([]entities.Trace, error) {
 	url := fmt.Sprintf("https://gitlab.com/api/v4/users/%s/projects", username)
-	resp, err := http.Get(url)
+	resp, err := telemetry.Get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -31,10 +31,14 @@ func (p *CompanyRegistryPlugin) Register() error {
 }
 
 func (p *CompanyRegistryPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *CompanyRegistryPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != entities.Company {
 		return nil, nil
 	}
-	return searchCompany(context.Background(), p.fetcher, trace.Value)
+	return searchCompany(ctx, p.fetcher, trace.Value)
 }
 
 func (p CompanyRegistryPlugin) String() string {
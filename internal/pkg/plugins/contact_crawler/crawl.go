@@ -43,7 +43,7 @@ func newDomainBudget(maximum int) *domainBudget {
 }
 
 func (b *domainBudget) trySpend(host string) bool {
-	domain, ok := registrableDomain(host)
+	domain, ok := entities.RegistrableDomain(host)
 	if !ok {
 		return false
 	}
@@ -192,11 +192,22 @@ func parsePage(body []byte, baseURL, seedHost string) ([]entities.Trace, []strin
 		return nil, nil
 	}
 
-	doc.Find("script, style, noscript").Remove()
-
 	traces := make([]entities.Trace, 0)
 	links := make([]string, 0)
 
+	// Analytics IDs live in script bodies and script URLs, which the text
+	// scan below must not see.
+	var scripts strings.Builder
+	doc.Find("script").Each(func(_ int, sel *goquery.Selection) {
+		src, _ := sel.Attr("src")
+		scripts.WriteString(src + "\n" + sel.Text() + "\n")
+	})
+	for _, id := range extractAnalyticsIDs(scripts.String()) {
+		traces = append(traces, entities.Trace{Value: id, Type: entities.AnalyticsID})
+	}
+
+	doc.Find("script, style, noscript").Remove()
+
 	text := doc.Text()
 	for _, email := range extractEmails(text) {
 		traces = append(traces, entities.Trace{Value: email, Type: entities.Email})
@@ -148,6 +148,23 @@ func TestParsePage_ExtractsContactsAndLinks(t *testing.T) {
 	assert.Contains(t, links[0], "https://example.com/internal")
 }
 
+func TestParsePage_ExtractsAnalyticsIDsFromScripts(t *testing.T) {
+	body := []byte(`<html><head>
+		<script async src="https://www.googletagmanager.com/gtag/js?id=G-ABC123DEF4"></script>
+		<script>gtag('config', 'G-ABC123DEF4'); ga('create', 'UA-12345678-1', 'auto');</script>
+	</head><body><p>Call GTM-NOTANID today</p></body></html>`)
+
+	traces, _ := parsePage(body, "https://example.com/", "example.com")
+
+	var ids []string
+	for _, trace := range traces {
+		if trace.Type == entities.AnalyticsID {
+			ids = append(ids, trace.Value)
+		}
+	}
+	assert.ElementsMatch(t, []string{"G-ABC123DEF4", "UA-12345678-1"}, ids, "page text is not searched")
+}
+
 // TestParsePage_DropsUnrelatedGithubRepo is a regression test for a scope
 // bug found live against codescoring.ru: a page linking to an unrelated
 // open-source project's GitHub repo (pgbouncer/pgbouncer) caused
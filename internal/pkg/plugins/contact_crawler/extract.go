@@ -12,6 +12,9 @@ import (
 var (
 	emailPattern = regexp.MustCompile(`\b[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}\b`)
 	phonePattern = regexp.MustCompile(`(?:\+?\d{1,3}[-.\s]?)?\(?\d{3}\)?[-.\s]?\d{3}[-.\s]?\d{4}\b`)
+	// analyticsPattern finds Google Analytics and Tag Manager property IDs
+	// in inline scripts and script URLs.
+	analyticsPattern = regexp.MustCompile(`\b(?:UA-\d{4,10}-\d{1,4}|G-[A-Z0-9]{10}|GTM-[A-Z0-9]{4,8})\b`)
 )
 
 type socialMatcher struct {
@@ -39,6 +42,10 @@ func extractEmails(text string) []string {
 	return uniqueStrings(emailPattern.FindAllString(text, -1))
 }
 
+func extractAnalyticsIDs(text string) []string {
+	return uniqueStrings(analyticsPattern.FindAllString(text, -1))
+}
+
 func extractPhones(text string) []string {
 	candidates := phonePattern.FindAllString(text, -1)
 	matched := make([]string, 0, len(candidates))
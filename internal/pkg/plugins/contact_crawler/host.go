@@ -4,34 +4,20 @@ import (
 	"net/url"
 	"strings"
 
-	"golang.org/x/net/publicsuffix"
+	"github.com/smirnoffmg/deeper/internal/pkg/entities"
 )
 
-func registrableDomain(host string) (string, bool) {
-	host = strings.ToLower(strings.TrimSpace(host))
-	if host == "" {
-		return "", false
-	}
-
-	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
-	if err != nil {
-		return "", false
-	}
-
-	return domain, true
-}
-
 // sameSite reports whether candidateHost is in-scope for a crawl seeded at seedHost,
 // based purely on sharing a registrable domain. IP overlap is deliberately not used:
 // shared hosting/CDN infrastructure (e.g. Cloudflare) puts many unrelated tenants
 // behind the same edge IP, which would let the crawler walk onto unauthorized domains.
 func sameSite(seedHost, candidateHost string) bool {
-	seedDomain, ok := registrableDomain(seedHost)
+	seedDomain, ok := entities.RegistrableDomain(seedHost)
 	if !ok {
 		return false
 	}
 
-	candidateDomain, ok := registrableDomain(candidateHost)
+	candidateDomain, ok := entities.RegistrableDomain(candidateHost)
 	if !ok {
 		return false
 	}
@@ -53,7 +39,7 @@ func ownerMatchesTarget(owner, seedHost string) bool {
 		return false
 	}
 
-	domain, ok := registrableDomain(seedHost)
+	domain, ok := entities.RegistrableDomain(seedHost)
 	if !ok {
 		return false
 	}
@@ -6,30 +6,6 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestRegistrableDomain(t *testing.T) {
-	tests := []struct {
-		name string
-		host string
-		want string
-		ok   bool
-	}{
-		{name: "subdomain", host: "registry.codescoring.ru", want: "codescoring.ru", ok: true},
-		{name: "www", host: "www.codescoring.ru", want: "codescoring.ru", ok: true},
-		{name: "uppercase", host: "WWW.CODESCORING.RU", want: "codescoring.ru", ok: true},
-		{name: "bare domain", host: "codescoring.ru", want: "codescoring.ru", ok: true},
-		{name: "empty", host: "", want: "", ok: false},
-		{name: "ip literal", host: "192.168.1.1", want: "", ok: false},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			got, ok := registrableDomain(tt.host)
-			assert.Equal(t, tt.ok, ok)
-			assert.Equal(t, tt.want, got)
-		})
-	}
-}
-
 func TestSameSite(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -38,13 +38,17 @@ func (p *ContactCrawlerPlugin) Register() error {
 }
 
 func (p *ContactCrawlerPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *ContactCrawlerPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != entities.Domain && trace.Type != entities.Subdomain {
 		return nil, nil
 	}
 
 	seedURL := normalizeURL(trace.Value)
 	c := newCrawler(p.fetcher, trace.Value, p.domainBudget)
-	return c.crawl(context.Background(), seedURL)
+	return c.crawl(ctx, seedURL)
 }
 
 func (p *ContactCrawlerPlugin) String() string {
@@ -1,6 +1,7 @@
 package crtsh
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
 	"github.com/smirnoffmg/deeper/internal/pkg/state"
+	"github.com/smirnoffmg/deeper/internal/pkg/telemetry"
 )
 
 const InputTraceType = entities.Domain
@@ -22,13 +24,13 @@ func init() {
 }
 
 type certFetcher interface {
-	Get(url string) (*http.Response, error)
+	Get(ctx context.Context, url string) (*http.Response, error)
 }
 
 type httpCertFetcher struct{}
 
-func (httpCertFetcher) Get(url string) (*http.Response, error) {
-	return http.Get(url)
+func (httpCertFetcher) Get(ctx context.Context, url string) (*http.Response, error) {
+	return telemetry.Get(ctx, url)
 }
 
 type SubdomainPlugin struct {
@@ -49,12 +51,16 @@ type CrtShEntry struct {
 }
 
 func (g *SubdomainPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return g.FollowTraceContext(context.Background(), trace)
+}
+
+func (g *SubdomainPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != InputTraceType {
 		return nil, nil
 	}
 
 	url := fmt.Sprintf("https://crt.sh/?q=%%25.%s&output=json", trace.Value)
-	resp, err := g.fetcher.Get(url)
+	resp, err := g.fetcher.Get(ctx, url)
 	if err != nil {
 		log.Warn().Err(err).Str("domain", trace.Value).Msg("crt.sh request failed, skipping")
 		return nil, nil
@@ -76,10 +82,7 @@ func (g *SubdomainPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, e
 	for _, entry := range entries {
 		subdomains := parseSubdomains(entry.NameValue)
 		for _, subdomain := range subdomains {
-			newTraces = append(newTraces, entities.Trace{
-				Value: subdomain,
-				Type:  entities.Subdomain,
-			})
+			newTraces = append(newTraces, entities.HostTrace(subdomain, entities.Subdomain))
 		}
 	}
 
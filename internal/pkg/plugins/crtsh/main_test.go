@@ -1,6 +1,7 @@
 package crtsh
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"strings"
@@ -17,7 +18,7 @@ type fakeCertFetcher struct {
 	err        error
 }
 
-func (f *fakeCertFetcher) Get(url string) (*http.Response, error) {
+func (f *fakeCertFetcher) Get(_ context.Context, url string) (*http.Response, error) {
 	if f.err != nil {
 		return nil, f.err
 	}
@@ -35,6 +35,10 @@ func (p *DNSRecordsPlugin) Register() error {
 }
 
 func (p *DNSRecordsPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *DNSRecordsPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != entities.Domain && trace.Type != entities.Subdomain {
 		return nil, nil
 	}
@@ -43,7 +47,7 @@ func (p *DNSRecordsPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace,
 		return nil, nil
 	}
 
-	return lookupDoHRecords(context.Background(), trace.Value, p.doh), nil
+	return lookupDoHRecords(ctx, trace.Value, p.doh), nil
 }
 
 func (p *DNSRecordsPlugin) String() string {
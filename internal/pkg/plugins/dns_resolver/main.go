@@ -8,6 +8,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
 	"github.com/smirnoffmg/deeper/internal/pkg/state"
+	"github.com/smirnoffmg/deeper/internal/pkg/telemetry"
 )
 
 const InputTraceType = entities.Subdomain
@@ -29,7 +30,7 @@ type DNSResolverPlugin struct {
 }
 
 func NewPlugin() *DNSResolverPlugin {
-	return &DNSResolverPlugin{resolver: net.DefaultResolver}
+	return &DNSResolverPlugin{resolver: telemetry.NewResolver(net.DefaultResolver)}
 }
 
 func (p *DNSResolverPlugin) Register() error {
@@ -38,6 +39,10 @@ func (p *DNSResolverPlugin) Register() error {
 }
 
 func (p *DNSResolverPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *DNSResolverPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != InputTraceType {
 		return nil, nil
 	}
@@ -46,15 +51,22 @@ func (p *DNSResolverPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace,
 		return nil, nil
 	}
 
-	addrs, err := p.resolver.LookupIPAddr(context.Background(), trace.Value)
+	addrs, err := p.resolver.LookupIPAddr(ctx, trace.Value)
 	if err != nil {
 		return nil, err
 	}
 
+	// A and AAAA answers both become IpAddr traces so IPv6 hosts flow into
+	// ip_intel the same way IPv4 ones do. Zones are dropped: they only
+	// appear on link-local answers and are meaningless off this host.
 	newTraces := make([]entities.Trace, 0, len(addrs))
 	for _, addr := range addrs {
+		value, ok := entities.NormalizeIpAddr(addr.IP.String())
+		if !ok {
+			continue
+		}
 		newTraces = append(newTraces, entities.Trace{
-			Value: addr.IP.String(),
+			Value: value,
 			Type:  entities.IpAddr,
 		})
 	}
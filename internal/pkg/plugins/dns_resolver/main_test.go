@@ -46,6 +46,25 @@ func TestDNSResolverPlugin_FollowTrace_ResolvesAddresses(t *testing.T) {
 	assert.Equal(t, "2001:db8::1", traces[1].Value)
 }
 
+func TestDNSResolverPlugin_FollowTrace_NormalizesAddresses(t *testing.T) {
+	plugin := &DNSResolverPlugin{resolver: &fakeResolver{
+		addrs: []net.IPAddr{
+			{IP: net.ParseIP("::ffff:185.55.56.154")},
+			{IP: net.ParseIP("2001:0DB8:0000::0001")},
+		},
+	}}
+
+	traces, err := plugin.FollowTrace(entities.Trace{Value: "registry.codescoring.ru", Type: entities.Subdomain})
+
+	require.NoError(t, err)
+	require.Len(t, traces, 2)
+	assert.Equal(t, "185.55.56.154", traces[0].Value)
+	assert.Equal(t, "2001:db8::1", traces[1].Value)
+	for _, tr := range traces {
+		assert.Equal(t, tr, entities.NewTrace(tr.Value), "re-seeding an emitted address must keep it an IpAddr")
+	}
+}
+
 func TestDNSResolverPlugin_FollowTrace_LookupError(t *testing.T) {
 	plugin := &DNSResolverPlugin{resolver: &fakeResolver{err: errors.New("no such host")}}
 
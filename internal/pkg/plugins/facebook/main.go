@@ -32,11 +32,15 @@ func (g *FacebookPlugin) Register() error {
 }
 
 func (g *FacebookPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return g.FollowTraceContext(context.Background(), trace)
+}
+
+func (g *FacebookPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != entities.Username && trace.Type != entities.Name {
 		return nil, nil
 	}
 
-	profiles, err := searchFacebookProfiles(context.Background(), g.fetcher, trace.Value)
+	profiles, err := searchFacebookProfiles(ctx, g.fetcher, trace.Value)
 	if err != nil {
 		return nil, err
 	}
@@ -37,6 +37,10 @@ func (p *GitHubIdentityPlugin) Register() error {
 }
 
 func (p *GitHubIdentityPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *GitHubIdentityPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != entities.Github && trace.Type != entities.Repository {
 		return nil, nil
 	}
@@ -46,8 +50,6 @@ func (p *GitHubIdentityPlugin) FollowTrace(trace entities.Trace) ([]entities.Tra
 		return nil, nil
 	}
 
-	ctx := context.Background()
-
 	if isFork(ctx, p.fetcher, owner, repo, p.token) {
 		return nil, nil
 	}
@@ -34,11 +34,14 @@ func (p *GitHubKeysPlugin) Register() error {
 // limit, network error) must not block the other, same discipline as
 // dns_records' independent per-record-type lookups.
 func (p *GitHubKeysPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *GitHubKeysPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != entities.Username {
 		return nil, nil
 	}
 
-	ctx := context.Background()
 	var traces []entities.Trace
 
 	sshTraces, err := fetchSSHKeys(ctx, p.fetcher, trace.Value)
@@ -31,10 +31,14 @@ func (p *GitHubProfilePlugin) Register() error {
 }
 
 func (p *GitHubProfilePlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *GitHubProfilePlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != entities.Username {
 		return nil, nil
 	}
-	return fetchProfile(context.Background(), p.fetcher, trace.Value)
+	return fetchProfile(ctx, p.fetcher, trace.Value)
 }
 
 func (p GitHubProfilePlugin) String() string {
@@ -38,12 +38,16 @@ func (p *GravatarPlugin) Register() error {
 }
 
 func (p *GravatarPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *GravatarPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != InputTraceType {
 		return nil, nil
 	}
 
 	hash := emailHash(trace.Value)
-	profile, found, err := fetchProfile(context.Background(), p.fetcher, hash, p.apiKey)
+	profile, found, err := fetchProfile(ctx, p.fetcher, hash, p.apiKey)
 	if err != nil {
 		return nil, err
 	}
@@ -31,10 +31,14 @@ func (p *HabrProfilePlugin) Register() error {
 }
 
 func (p *HabrProfilePlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *HabrProfilePlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != entities.Username {
 		return nil, nil
 	}
-	return fetchProfile(context.Background(), p.fetcher, trace.Value)
+	return fetchProfile(ctx, p.fetcher, trace.Value)
 }
 
 func (p HabrProfilePlugin) String() string {
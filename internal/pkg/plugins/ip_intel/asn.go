@@ -3,7 +3,7 @@ package ip_intel
 import (
 	"context"
 	"fmt"
-	"net"
+	"net/netip"
 	"strings"
 
 	"github.com/rs/zerolog/log"
@@ -14,20 +14,15 @@ type txtLookup interface {
 	LookupTXT(ctx context.Context, name string) ([]string, error)
 }
 
-// lookupASN performs Team Cymru IP-to-ASN DNS lookups for IPv4 addresses.
-// IPv6 is not supported in v1; non-IPv4 addresses return nil.
+// lookupASN performs Team Cymru IP-to-ASN DNS lookups: origin.asn.cymru.com
+// for IPv4 and origin6.asn.cymru.com for IPv6. Both return the same
+// pipe-delimited format, so everything after the query name is shared.
 func lookupASN(ctx context.Context, ip string, lookups txtLookup) []entities.Trace {
-	parsed := net.ParseIP(ip)
-	if parsed == nil {
-		return nil
-	}
-	parsed = parsed.To4()
-	if parsed == nil {
-		// IPv6 ASN lookup deferred to a follow-up; dns_resolver output has been IPv4 so far.
+	originQuery, ok := originQueryName(ip)
+	if !ok {
 		return nil
 	}
 
-	originQuery := fmt.Sprintf("%s.origin.asn.cymru.com", reverseIPv4(parsed.String()))
 	txtRecords, err := lookups.LookupTXT(ctx, originQuery)
 	if err != nil {
 		// Every routable IP has an ASN, so a lookup error here (as opposed to a
@@ -59,6 +54,32 @@ func lookupASN(ctx context.Context, ip string, lookups txtLookup) []entities.Tra
 	return traces
 }
 
+// originQueryName builds the Team Cymru origin query for an IPv4 or IPv6
+// address, or reports ok=false for anything that isn't a plain IP.
+func originQueryName(ip string) (string, bool) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || addr.Zone() != "" {
+		return "", false
+	}
+	addr = addr.Unmap()
+	if addr.Is4() {
+		return fmt.Sprintf("%s.origin.asn.cymru.com", reverseIPv4(addr.String())), true
+	}
+	return fmt.Sprintf("%s.origin6.asn.cymru.com", reverseIPv6Nibbles(addr)), true
+}
+
+// reverseIPv6Nibbles renders all 32 nibbles of an IPv6 address in reverse
+// order, dot-separated -- the same layout ip6.arpa uses.
+func reverseIPv6Nibbles(addr netip.Addr) string {
+	const hexDigits = "0123456789abcdef"
+	b := addr.As16()
+	nibbles := make([]string, 0, 32)
+	for i := len(b) - 1; i >= 0; i-- {
+		nibbles = append(nibbles, string(hexDigits[b[i]&0x0f]), string(hexDigits[b[i]>>4]))
+	}
+	return strings.Join(nibbles, ".")
+}
+
 func reverseIPv4(ip string) string {
 	octets := strings.Split(ip, ".")
 	if len(octets) != 4 {
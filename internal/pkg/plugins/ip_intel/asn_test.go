@@ -95,12 +95,44 @@ func TestLookupASN_ChainedLookupFails(t *testing.T) {
 	assert.Equal(t, entities.Netblock, traces[1].Type)
 }
 
-func TestLookupASN_NonIPv4(t *testing.T) {
-	lookups := &fakeTXTLookup{}
+func TestLookupASN_IPv6UsesOrigin6(t *testing.T) {
+	lookups := &fakeTXTLookup{
+		responses: map[string][]string{
+			"1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.origin6.asn.cymru.com": {
+				"64496 | 2001:db8::/32 | ZZ | other | 2001-01-01",
+			},
+			"AS64496.asn.cymru.com": {
+				"64496 | ZZ | other | 2001-01-01 | EXAMPLE-AS, ZZ",
+			},
+		},
+	}
 
 	traces := lookupASN(context.Background(), "2001:db8::1", lookups)
 
-	assert.Empty(t, traces)
+	require.Len(t, traces, 3)
+	assert.Equal(t, entities.Trace{Type: entities.ASN, Value: "AS64496"}, traces[0])
+	assert.Equal(t, entities.Trace{Type: entities.Netblock, Value: "2001:db8::/32"}, traces[1])
+	assert.Equal(t, entities.Trace{Type: entities.Company, Value: "EXAMPLE-AS, ZZ"}, traces[2])
+}
+
+func TestLookupASN_IPv4MappedUsesIPv4Origin(t *testing.T) {
+	lookups := &fakeTXTLookup{
+		responses: map[string][]string{
+			"5.100.51.198.origin.asn.cymru.com": {
+				"24940 | 198.51.100.0/24 | DE | ripencc | 2003-03-17",
+			},
+		},
+	}
+
+	traces := lookupASN(context.Background(), "::ffff:198.51.100.5", lookups)
+
+	require.Len(t, traces, 2)
+	assert.Equal(t, "AS24940", traces[0].Value)
+}
+
+func TestOriginQueryName_RejectsZonedAddress(t *testing.T) {
+	_, ok := originQueryName("fe80::1%eth0")
+	assert.False(t, ok)
 }
 
 func TestLookupASN_InvalidIP(t *testing.T) {
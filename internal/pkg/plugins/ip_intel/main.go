@@ -7,6 +7,7 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
 	"github.com/smirnoffmg/deeper/internal/pkg/state"
+	"github.com/smirnoffmg/deeper/internal/pkg/telemetry"
 )
 
 func init() {
@@ -22,7 +23,7 @@ type IPIntelPlugin struct {
 }
 
 func NewPlugin() *IPIntelPlugin {
-	resolver := net.DefaultResolver
+	resolver := telemetry.NewResolver(net.DefaultResolver)
 	return &IPIntelPlugin{
 		txt:  resolver,
 		addr: resolver,
@@ -35,12 +36,14 @@ func (p *IPIntelPlugin) Register() error {
 }
 
 func (p *IPIntelPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *IPIntelPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != entities.IpAddr {
 		return nil, nil
 	}
 
-	ctx := context.Background()
-
 	var traces []entities.Trace
 	traces = append(traces, lookupASN(ctx, trace.Value, p.txt)...)
 	traces = append(traces, lookupPTR(ctx, trace.Value, p.addr)...)
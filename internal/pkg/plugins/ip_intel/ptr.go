@@ -11,8 +11,17 @@ type addrLookup interface {
 	LookupAddr(ctx context.Context, addr string) ([]string, error)
 }
 
+// lookupPTR resolves reverse DNS for an IPv4 or IPv6 address. LookupAddr
+// builds the in-addr.arpa or ip6.arpa query name itself; the address is
+// canonicalized first so an IPv4-mapped IPv6 spelling is looked up under
+// in-addr.arpa, where its PTR records actually live.
 func lookupPTR(ctx context.Context, ip string, lookups addrLookup) []entities.Trace {
-	names, err := lookups.LookupAddr(ctx, ip)
+	normalized, ok := entities.NormalizeIpAddr(ip)
+	if !ok {
+		return nil
+	}
+
+	names, err := lookups.LookupAddr(ctx, normalized)
 	if err != nil || len(names) == 0 {
 		return nil
 	}
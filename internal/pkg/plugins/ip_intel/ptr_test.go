@@ -11,14 +11,35 @@ import (
 )
 
 type fakeAddrLookup struct {
-	names []string
-	err   error
+	names   []string
+	err     error
+	queried string
 }
 
 func (f *fakeAddrLookup) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	f.queried = addr
 	return f.names, f.err
 }
 
+func TestLookupPTR_IPv6CanonicalizedBeforeLookup(t *testing.T) {
+	lookups := &fakeAddrLookup{names: []string{"host6.example.com."}}
+
+	traces := lookupPTR(context.Background(), "2001:DB8:0:0::1", lookups)
+
+	require.Len(t, traces, 1)
+	assert.Equal(t, "2001:db8::1", lookups.queried)
+	assert.Equal(t, entities.DnsRecordPTR, traces[0].Type)
+}
+
+func TestLookupPTR_InvalidIPSkipsLookup(t *testing.T) {
+	lookups := &fakeAddrLookup{names: []string{"never.example.com."}}
+
+	traces := lookupPTR(context.Background(), "not-an-ip", lookups)
+
+	assert.Empty(t, traces)
+	assert.Empty(t, lookups.queried)
+}
+
 func TestLookupPTR_KnownHostnames(t *testing.T) {
 	lookups := &fakeAddrLookup{
 		names: []string{
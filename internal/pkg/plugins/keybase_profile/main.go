@@ -41,10 +41,14 @@ func (p *KeybaseProfilePlugin) Matches(trace entities.Trace) bool {
 }
 
 func (p *KeybaseProfilePlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *KeybaseProfilePlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if !p.Matches(trace) {
 		return nil, nil
 	}
-	return fetchProfile(context.Background(), p.fetcher, extractHandle(trace.Value))
+	return fetchProfile(ctx, p.fetcher, extractHandle(trace.Value))
 }
 
 func (p KeybaseProfilePlugin) String() string {
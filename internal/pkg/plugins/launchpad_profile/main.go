@@ -35,10 +35,14 @@ func (p *LaunchpadProfilePlugin) Matches(trace entities.Trace) bool {
 }
 
 func (p *LaunchpadProfilePlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *LaunchpadProfilePlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if !p.Matches(trace) {
 		return nil, nil
 	}
-	return fetchProfile(context.Background(), p.fetcher, extractHandle(trace.Value))
+	return fetchProfile(ctx, p.fetcher, extractHandle(trace.Value))
 }
 
 func (p LaunchpadProfilePlugin) String() string {
@@ -1,10 +1,13 @@
 package social_profiles
 
 import (
+	"context"
 	"io"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/telemetry"
 )
 
 const (
@@ -19,7 +22,7 @@ const (
 // checks the un-followed status instead of the (possibly 200) page it
 // redirects to. Every other errorType follows redirects normally.
 func newProbeClient(errorType string) *http.Client {
-	client := &http.Client{Timeout: 5 * time.Second}
+	client := &http.Client{Timeout: 5 * time.Second, Transport: telemetry.Transport(http.DefaultTransport)}
 	if errorType == errorTypeResponseURL {
 		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
@@ -67,10 +70,10 @@ func decideExistence(entry SherlockEntry, status int, body []byte) bool {
 	}
 }
 
-func (e SherlockEntry) CheckUrl(username string) bool {
+func (e SherlockEntry) CheckUrl(ctx context.Context, username string) bool {
 	url := e.BuildUrl(username)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return false
 	}
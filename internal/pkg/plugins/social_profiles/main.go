@@ -1,6 +1,7 @@
 package social_profiles
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -72,12 +73,14 @@ const maxConcurrentChecks = 30
 
 type SocialProfilesPlugin struct {
 	entries map[string]SherlockEntry
-	checkFn func(entry SherlockEntry, username string) bool
+	checkFn func(ctx context.Context, entry SherlockEntry, username string) bool
 }
 
 func NewSocialProfilesPlugin() *SocialProfilesPlugin {
 	return &SocialProfilesPlugin{
-		checkFn: func(entry SherlockEntry, username string) bool { return entry.CheckUrl(username) },
+		checkFn: func(ctx context.Context, entry SherlockEntry, username string) bool {
+			return entry.CheckUrl(ctx, username)
+		},
 	}
 }
 
@@ -134,6 +137,10 @@ func (g *SocialProfilesPlugin) Register() error {
 }
 
 func (g *SocialProfilesPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return g.FollowTraceContext(context.Background(), trace)
+}
+
+func (g *SocialProfilesPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != InputTraceType {
 		return nil, nil
 	}
@@ -153,7 +160,7 @@ func (g *SocialProfilesPlugin) FollowTrace(trace entities.Trace) ([]entities.Tra
 			defer wg.Done()
 			defer func() { <-sem }()
 
-			if g.checkFn(entry, trace.Value) {
+			if g.checkFn(ctx, entry, trace.Value) {
 				mu.Lock()
 				newTraces = append(newTraces, entities.Trace{
 					Value: entry.BuildUrl(trace.Value),
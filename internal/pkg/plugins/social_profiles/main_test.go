@@ -1,6 +1,7 @@
 package social_profiles
 
 import (
+	"context"
 	"fmt"
 	"sync/atomic"
 	"testing"
@@ -73,7 +74,7 @@ func manyEntries(n int) map[string]SherlockEntry {
 }
 
 func TestFollowTrace_CollectsAllMatchesWithoutDataRace(t *testing.T) {
-	checkFn := func(entry SherlockEntry, username string) bool { return true }
+	checkFn := func(_ context.Context, entry SherlockEntry, username string) bool { return true }
 	p := &SocialProfilesPlugin{entries: manyEntries(50), checkFn: checkFn}
 
 	traces, err := p.FollowTrace(entities.Trace{Type: InputTraceType, Value: "alsmirn"})
@@ -84,7 +85,7 @@ func TestFollowTrace_CollectsAllMatchesWithoutDataRace(t *testing.T) {
 
 func TestFollowTrace_BoundsConcurrency(t *testing.T) {
 	var current, maxSeen int32
-	checkFn := func(entry SherlockEntry, username string) bool {
+	checkFn := func(_ context.Context, entry SherlockEntry, username string) bool {
 		n := atomic.AddInt32(&current, 1)
 		for {
 			old := atomic.LoadInt32(&maxSeen)
@@ -1,6 +1,7 @@
 package subdomains
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -8,18 +9,19 @@ import (
 
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
 	"github.com/smirnoffmg/deeper/internal/pkg/state"
+	"github.com/smirnoffmg/deeper/internal/pkg/telemetry"
 )
 
 const InputTraceType = entities.Domain
 
 type hostSearchFetcher interface {
-	Get(url string) (*http.Response, error)
+	Get(ctx context.Context, url string) (*http.Response, error)
 }
 
 type httpHostSearchFetcher struct{}
 
-func (httpHostSearchFetcher) Get(url string) (*http.Response, error) {
-	return http.Get(url)
+func (httpHostSearchFetcher) Get(ctx context.Context, url string) (*http.Response, error) {
+	return telemetry.Get(ctx, url)
 }
 
 type SubdomainPlugin struct {
@@ -43,12 +45,16 @@ func (p *SubdomainPlugin) Register() error {
 }
 
 func (p *SubdomainPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *SubdomainPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != InputTraceType {
 		return nil, nil
 	}
 
 	url := fmt.Sprintf("https://api.hackertarget.com/hostsearch/?q=%s", trace.Value)
-	resp, err := p.fetcher.Get(url)
+	resp, err := p.fetcher.Get(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -82,7 +88,7 @@ func parseHostSearchCSV(csv string) []entities.Trace {
 		if subdomain == "" || !entities.IsIpAddr(ipAddr) {
 			continue
 		}
-		traces = append(traces, entities.Trace{Value: subdomain, Type: entities.Subdomain})
+		traces = append(traces, entities.HostTrace(subdomain, entities.Subdomain))
 		traces = append(traces, entities.Trace{Value: ipAddr, Type: entities.IpAddr})
 	}
 	return traces
@@ -1,6 +1,7 @@
 package subdomains
 
 import (
+	"context"
 	"errors"
 	"io"
 	"net/http"
@@ -62,7 +63,7 @@ type fakeHostSearchFetcher struct {
 	lastURL string
 }
 
-func (f *fakeHostSearchFetcher) Get(url string) (*http.Response, error) {
+func (f *fakeHostSearchFetcher) Get(_ context.Context, url string) (*http.Response, error) {
 	f.lastURL = url
 	if f.err != nil {
 		return nil, f.err
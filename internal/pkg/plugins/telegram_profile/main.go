@@ -35,10 +35,14 @@ func (p *TelegramProfilePlugin) Matches(trace entities.Trace) bool {
 }
 
 func (p *TelegramProfilePlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *TelegramProfilePlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if !p.Matches(trace) {
 		return nil, nil
 	}
-	return fetchProfile(context.Background(), p.fetcher, extractChannel(trace.Value))
+	return fetchProfile(ctx, p.fetcher, extractChannel(trace.Value))
 }
 
 func (p TelegramProfilePlugin) String() string {
@@ -46,7 +46,7 @@ func (p *URLResolverPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace,
 		return nil, nil
 	}
 
-	return []entities.Trace{{Type: entities.Domain, Value: host}}, nil
+	return []entities.Trace{entities.HostTrace(host, entities.Domain)}, nil
 }
 
 func (p *URLResolverPlugin) String() string {
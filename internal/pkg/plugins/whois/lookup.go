@@ -7,7 +7,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/smirnoffmg/deeper/internal/pkg/audit"
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/telemetry"
 )
 
 const ianaWhoisServer = "whois.iana.org:43"
@@ -22,7 +24,21 @@ type tcpWhoisClient struct {
 	timeout time.Duration
 }
 
-func (c *tcpWhoisClient) Query(ctx context.Context, address, term string) (string, error) {
+func (c *tcpWhoisClient) Query(ctx context.Context, address, term string) (response string, err error) {
+	ctx, span := telemetry.Start(ctx, "WHOIS", telemetry.WithKind(telemetry.KindClient), telemetry.WithAttributes(
+		telemetry.String("server.address", address),
+		telemetry.String("whois.query", term),
+	))
+	contact := audit.Entry{Time: time.Now(), Kind: audit.KindWHOIS, Method: "QUERY", URL: "whois://" + address + "/" + term}
+	contact.Host, _, _ = net.SplitHostPort(address)
+	defer func() {
+		span.RecordError(err)
+		span.End()
+		contact.BytesReceived = int64(len(response))
+		contact.Status, contact.Error = audit.Outcome(err)
+		audit.Record(ctx, contact)
+	}()
+
 	dialer := net.Dialer{Timeout: c.timeout}
 	conn, err := dialer.DialContext(ctx, "tcp", address)
 	if err != nil {
@@ -32,7 +48,9 @@ func (c *tcpWhoisClient) Query(ctx context.Context, address, term string) (strin
 
 	_ = conn.SetDeadline(time.Now().Add(c.timeout))
 
-	if _, err := conn.Write([]byte(term + "\r\n")); err != nil {
+	sent, err := conn.Write([]byte(term + "\r\n"))
+	contact.BytesSent = int64(sent)
+	if err != nil {
 		return "", err
 	}
 
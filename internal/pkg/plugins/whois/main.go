@@ -27,16 +27,22 @@ func NewPlugin() *WhoisPlugin {
 // Register only covers Domain — unlike most other plugins in this codebase,
 // WHOIS is a registration-level lookup keyed to the registrable domain, not
 // meaningful per-subdomain (most registries just return "not found").
+// Domain traces are public-suffix-classified (see entities.HostType), so a
+// subdomain never reaches this plugin; its registrable parent does instead.
 func (p *WhoisPlugin) Register() error {
 	state.RegisterPlugin(entities.Domain, p)
 	return nil
 }
 
 func (p *WhoisPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) {
+	return p.FollowTraceContext(context.Background(), trace)
+}
+
+func (p *WhoisPlugin) FollowTraceContext(ctx context.Context, trace entities.Trace) ([]entities.Trace, error) {
 	if trace.Type != entities.Domain {
 		return nil, nil
 	}
-	return lookupWhois(context.Background(), p.client, trace.Value)
+	return lookupWhois(ctx, p.client, trace.Value)
 }
 
 func (p WhoisPlugin) String() string {
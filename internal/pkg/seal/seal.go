@@ -0,0 +1,311 @@
+// Package seal encrypts data at rest with a key derived from a passphrase
+// or a key file.
+//
+// Sealed data is AES-256-GCM ciphertext behind a short header naming how
+// the key was derived: PBKDF2-HMAC-SHA256 for passphrases, HKDF-SHA256 for
+// key files, each with a random salt. The header is authenticated with the
+// ciphertext, so neither can be altered without Open failing.
+package seal
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// magic starts all sealed data.
+var magic = []byte("DPRSEAL1")
+
+const (
+	kdfPassphrase byte = 1
+	kdfKeyFile    byte = 2
+
+	saltSize  = 16
+	nonceSize = 12
+	keySize   = 32
+	// headerSize is the magic, KDF, iteration count, salt and nonce.
+	headerSize = 8 + 1 + 4 + saltSize + nonceSize
+
+	// minKeyFileSize is the least key material a key file must hold.
+	minKeyFileSize = 32
+)
+
+// defaultIterations is the PBKDF2 work factor deeper seals with.
+const defaultIterations = 600_000
+
+// maxIterations is the largest work factor Open accepts, so that data
+// claiming an absurd one cannot tie up the CPU before it fails to open.
+const maxIterations = 10 * defaultIterations
+
+// iterations is the PBKDF2 work factor for new passphrase-sealed data.
+// Data records its own, so raising it later keeps old data readable.
+var iterations uint32 = defaultIterations
+
+// ErrWrongKey is returned by Open when the secret does not match the one
+// the data was sealed with, or the data was altered.
+var ErrWrongKey = errors.New("wrong passphrase or key file, or the data is corrupt")
+
+// Secret is what sealing keys are derived from. It seals with one salt,
+// so the costly derivation runs once per process: the salt of the first
+// data it opened, or a fresh one.
+type Secret struct {
+	kdf      byte
+	material []byte
+
+	mu   sync.Mutex
+	salt []byte
+	keys map[string][]byte
+}
+
+// Passphrase returns a secret derived from a passphrase.
+func Passphrase(passphrase string) (*Secret, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase is empty")
+	}
+	return &Secret{kdf: kdfPassphrase, material: []byte(passphrase)}, nil
+}
+
+// KeyFile returns a secret derived from the contents of a key file, which
+// must hold at least 32 bytes, such as the output of
+// "head -c 32 /dev/urandom".
+func KeyFile(path string) (*Secret, error) {
+	material, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	if len(material) < minKeyFileSize {
+		return nil, fmt.Errorf("key file %s holds %d bytes, need at least %d", path, len(material), minKeyFileSize)
+	}
+	return &Secret{kdf: kdfKeyFile, material: material}, nil
+}
+
+// Load returns the secret given by a passphrase or a key file path, or
+// nil if neither is set.
+func Load(passphrase, keyFile string) (*Secret, error) {
+	switch {
+	case passphrase != "" && keyFile != "":
+		return nil, fmt.Errorf("give a passphrase or a key file, not both")
+	case passphrase != "":
+		return Passphrase(passphrase)
+	case keyFile != "":
+		return KeyFile(keyFile)
+	}
+	return nil, nil
+}
+
+// key derives the key for salt with the given work factor.
+func (s *Secret) key(salt []byte, iter uint32) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := fmt.Sprintf("%x:%d", salt, iter)
+	if key, ok := s.keys[id]; ok {
+		return key, nil
+	}
+	var key []byte
+	var err error
+	switch s.kdf {
+	case kdfPassphrase:
+		key, err = pbkdf2.Key(sha256.New, string(s.material), salt, int(iter), keySize)
+	default:
+		key, err = hkdf.Key(sha256.New, s.material, salt, "deeper seal", keySize)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	if s.keys == nil {
+		s.keys = make(map[string][]byte)
+	}
+	s.keys[id] = key
+	if s.salt == nil {
+		s.salt = salt
+	}
+	return key, nil
+}
+
+// sealingSalt returns the salt s seals with.
+func (s *Secret) sealingSalt() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+		s.salt = salt
+	}
+	return s.salt, nil
+}
+
+// Seal encrypts plaintext with a key derived from s.
+func (s *Secret) Seal(plaintext []byte) ([]byte, error) {
+	salt, err := s.sealingSalt()
+	if err != nil {
+		return nil, err
+	}
+	var iter uint32
+	if s.kdf == kdfPassphrase {
+		iter = iterations
+	}
+	key, err := s.key(salt, iter)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, headerSize)
+	header = append(header, magic...)
+	header = append(header, s.kdf)
+	header = binary.BigEndian.AppendUint32(header, iter)
+	header = append(header, salt...)
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	header = append(header, nonce...)
+	return aead.Seal(header, nonce, plaintext, header), nil
+}
+
+// Open decrypts data sealed with s.
+func (s *Secret) Open(data []byte) ([]byte, error) {
+	if !IsSealed(data) || len(data) < headerSize {
+		return nil, fmt.Errorf("data is not sealed")
+	}
+	header := data[:headerSize]
+	kdf := header[len(magic)]
+	if kdf != s.kdf {
+		if kdf == kdfPassphrase {
+			return nil, fmt.Errorf("data is sealed with a passphrase, not a key file")
+		}
+		return nil, fmt.Errorf("data is sealed with a key file, not a passphrase")
+	}
+	iter := binary.BigEndian.Uint32(header[len(magic)+1:])
+	if iter > maxIterations {
+		return nil, fmt.Errorf("data claims a work factor of %d iterations, more than the %d allowed", iter, maxIterations)
+	}
+	salt := header[len(magic)+5 : len(magic)+5+saltSize]
+	nonce := header[headerSize-nonceSize:]
+
+	key, err := s.key(bytes.Clone(salt), iter)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, data[headerSize:], header)
+	if err != nil {
+		return nil, ErrWrongKey
+	}
+	return plaintext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return aead, nil
+}
+
+// IsSealed reports whether data looks sealed.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, magic)
+}
+
+// IsSealedFile reports whether the file at path is sealed. A missing file
+// is not.
+func IsSealedFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = f.Close() }()
+
+	head := make([]byte, len(magic))
+	if _, err := io.ReadFull(f, head); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return false, nil
+		}
+		return false, err
+	}
+	return IsSealed(head), nil
+}
+
+// ReadFile reads and opens a sealed file.
+func (s *Secret) ReadFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := s.Open(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	return plaintext, nil
+}
+
+// WriteFile seals plaintext and writes it to path. A nil secret writes
+// plaintext as is.
+func (s *Secret) WriteFile(path string, plaintext []byte, perm os.FileMode) error {
+	data := plaintext
+	if s != nil {
+		var err error
+		if data, err = s.Seal(plaintext); err != nil {
+			return err
+		}
+	}
+	return WriteFileAtomic(path, data, perm)
+}
+
+// WriteFileAtomic replaces the file at path with data, so that a crash
+// leaves either the old file or the new one.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmp := f.Name()
+	defer func() { _ = os.Remove(tmp) }()
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		return fmt.Errorf("failed to set permissions of %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package seal
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	// Keep passphrase derivation fast in tests.
+	iterations = 1000
+}
+
+func TestSecret_SealOpen(t *testing.T) {
+	s, err := Passphrase("correct horse")
+	require.NoError(t, err)
+
+	sealed, err := s.Seal([]byte("jdoe@acme.com"))
+	require.NoError(t, err)
+	assert.True(t, IsSealed(sealed))
+	assert.NotContains(t, string(sealed), "jdoe")
+
+	// A fresh secret with the same passphrase derives the same key.
+	again, err := Passphrase("correct horse")
+	require.NoError(t, err)
+	plaintext, err := again.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "jdoe@acme.com", string(plaintext))
+
+	wrong, err := Passphrase("battery staple")
+	require.NoError(t, err)
+	_, err = wrong.Open(sealed)
+	assert.ErrorIs(t, err, ErrWrongKey)
+
+	sealed[len(sealed)-1] ^= 1
+	_, err = s.Open(sealed)
+	assert.ErrorIs(t, err, ErrWrongKey)
+
+	_, err = Passphrase("")
+	assert.Error(t, err)
+}
+
+func TestSecret_OpenRejectsExcessiveWorkFactor(t *testing.T) {
+	s, err := Passphrase("correct horse")
+	require.NoError(t, err)
+	sealed, err := s.Seal([]byte("jdoe@acme.com"))
+	require.NoError(t, err)
+
+	binary.BigEndian.PutUint32(sealed[len(magic)+1:], maxIterations+1)
+	_, err = s.Open(sealed)
+	assert.ErrorContains(t, err, "work factor")
+}
+
+func TestKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	short := filepath.Join(dir, "short.key")
+	require.NoError(t, os.WriteFile(short, []byte("too short"), 0o600))
+	_, err := KeyFile(short)
+	assert.Error(t, err)
+
+	path := filepath.Join(dir, "deeper.key")
+	require.NoError(t, os.WriteFile(path, []byte("0123456789abcdef0123456789abcdef"), 0o600))
+	s, err := KeyFile(path)
+	require.NoError(t, err)
+
+	dataPath := filepath.Join(dir, "data")
+	require.NoError(t, s.WriteFile(dataPath, []byte("secret"), 0o600))
+	sealed, err := IsSealedFile(dataPath)
+	require.NoError(t, err)
+	assert.True(t, sealed)
+
+	plaintext, err := s.ReadFile(dataPath)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+
+	p, err := Passphrase("correct horse")
+	require.NoError(t, err)
+	_, err = p.ReadFile(dataPath)
+	assert.ErrorContains(t, err, "sealed with a key file")
+}
+
+func TestIsSealedFile_Plain(t *testing.T) {
+	dir := t.TempDir()
+	sealed, err := IsSealedFile(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.False(t, sealed)
+
+	path := filepath.Join(dir, "plain")
+	var nilSecret *Secret
+	require.NoError(t, nilSecret.WriteFile(path, []byte("SQLite format 3\x00"), 0o600))
+	sealed, err = IsSealedFile(path)
+	require.NoError(t, err)
+	assert.False(t, sealed)
+}
@@ -0,0 +1,342 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// batchSize is how many spans are exported in one request at most.
+	batchSize = 512
+	// queueSize bounds spans waiting for export; spans beyond it are
+	// dropped rather than blocking the scan that produced them.
+	queueSize = 4096
+	// flushInterval is how long a finished span waits for its batch to fill.
+	flushInterval = 5 * time.Second
+	// exportTimeout bounds one export call.
+	exportTimeout = 10 * time.Second
+)
+
+// Exporter delivers encoded spans. The payload is an OTLP
+// ExportTraceServiceRequest in its JSON encoding.
+type Exporter interface {
+	Export(ctx context.Context, payload []byte) error
+	Close() error
+}
+
+// Provider batches finished spans in the background and hands them to its
+// exporters.
+type Provider struct {
+	service   string
+	exporters []Exporter
+	queue     chan *Span
+	done      chan struct{}
+
+	mu      sync.RWMutex
+	closed  bool
+	dropped atomic.Int64
+}
+
+// NewProvider starts a provider exporting spans, labelled with the service
+// name, to every exporter.
+func NewProvider(service string, exporters ...Exporter) *Provider {
+	p := &Provider{
+		service:   service,
+		exporters: exporters,
+		queue:     make(chan *Span, queueSize),
+		done:      make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Shutdown exports the spans still queued and closes the exporters. Spans
+// ended afterwards are discarded.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	close(p.queue)
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush spans: %w", ctx.Err())
+	}
+	if dropped := p.dropped.Load(); dropped > 0 {
+		log.Warn().Msgf("Dropped %d spans because the export queue was full", dropped)
+	}
+
+	var firstErr error
+	for _, exporter := range p.exporters {
+		if err := exporter.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close span exporter: %w", err)
+		}
+	}
+	return firstErr
+}
+
+func (p *Provider) enqueue(span *Span) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return
+	}
+	select {
+	case p.queue <- span:
+	default:
+		p.dropped.Add(1)
+	}
+}
+
+func (p *Provider) run() {
+	defer close(p.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case span, ok := <-p.queue:
+			if !ok {
+				p.flush(batch)
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) >= batchSize {
+				p.flush(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			p.flush(batch)
+			batch = nil
+		}
+	}
+}
+
+func (p *Provider) flush(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+	payload, err := encodeSpans(p.service, batch)
+	if err != nil {
+		log.Warn().Err(err).Msg("Failed to encode spans")
+		return
+	}
+	for _, exporter := range p.exporters {
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		if err := exporter.Export(ctx, payload); err != nil {
+			log.Warn().Err(err).Msgf("Failed to export %d spans", len(batch))
+		}
+		cancel()
+	}
+}
+
+// OTLP/JSON shapes, trimmed to the fields deeper sets. IDs are hex and
+// 64-bit integers are strings, as the OTLP JSON mapping requires.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpAttribute `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string          `json:"traceId"`
+		SpanID            string          `json:"spanId"`
+		ParentSpanID      string          `json:"parentSpanId,omitempty"`
+		Name              string          `json:"name"`
+		Kind              Kind            `json:"kind"`
+		StartTimeUnixNano string          `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+		Attributes        []otlpAttribute `json:"attributes,omitempty"`
+		Status            otlpStatus      `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpAttribute struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+)
+
+// statusError is OTLP's STATUS_CODE_ERROR.
+const statusError = 2
+
+func encodeSpans(service string, spans []*Span) ([]byte, error) {
+	encoded := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		span.mu.Lock()
+		s := otlpSpan{
+			TraceID:           hex.EncodeToString(span.traceID[:]),
+			SpanID:            hex.EncodeToString(span.spanID[:]),
+			Name:              span.name,
+			Kind:              span.kind,
+			StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+			Attributes:        encodeAttributes(span.attributes),
+		}
+		if span.parentID != [8]byte{} {
+			s.ParentSpanID = hex.EncodeToString(span.parentID[:])
+		}
+		if span.failed {
+			s.Status = otlpStatus{Code: statusError, Message: span.message}
+		}
+		span.mu.Unlock()
+		encoded = append(encoded, s)
+	}
+
+	return json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: encodeAttributes([]Attribute{String("service.name", service)})},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: "github.com/smirnoffmg/deeper"},
+			Spans: encoded,
+		}},
+	}}})
+}
+
+func encodeAttributes(attributes []Attribute) []otlpAttribute {
+	encoded := make([]otlpAttribute, 0, len(attributes))
+	for _, attribute := range attributes {
+		var value otlpValue
+		switch v := attribute.Value.(type) {
+		case string:
+			value.StringValue = &v
+		case int64:
+			s := strconv.FormatInt(v, 10)
+			value.IntValue = &s
+		case float64:
+			value.DoubleValue = &v
+		case bool:
+			value.BoolValue = &v
+		default:
+			s := fmt.Sprint(v)
+			value.StringValue = &s
+		}
+		encoded = append(encoded, otlpAttribute{Key: attribute.Key, Value: value})
+	}
+	return encoded
+}
+
+// OTLPExporter posts spans to an OTLP/HTTP collector, such as Jaeger or the
+// OpenTelemetry Collector, in the JSON encoding.
+type OTLPExporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// NewOTLPExporter returns an exporter posting to endpoint. An endpoint
+// without a path, like http://localhost:4318, gets the standard /v1/traces.
+func NewOTLPExporter(endpoint string, headers map[string]string) (*OTLPExporter, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: want an http or https URL", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/traces"
+	}
+	return &OTLPExporter{
+		endpoint: u.String(),
+		headers:  headers,
+		// A plain client: exports must not be traced themselves.
+		client: &http.Client{Timeout: exportTimeout},
+	}, nil
+}
+
+// Export posts one batch.
+func (e *OTLPExporter) Export(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post spans to %s: %w", e.endpoint, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("OTLP collector at %s answered %s", e.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// Close does nothing; the exporter holds no resources.
+func (e *OTLPExporter) Close() error {
+	return nil
+}
+
+// FileExporter appends each batch to a file as one line of OTLP JSON, the
+// format the OpenTelemetry Collector's otlpjsonfile receiver reads.
+type FileExporter struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFileExporter opens path for appending, creating it readable by the
+// owner only: spans carry the values of the traces a scan followed.
+func NewFileExporter(path string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open span file: %w", err)
+	}
+	return &FileExporter{file: file}, nil
+}
+
+// Export appends one batch.
+func (e *FileExporter) Export(_ context.Context, payload []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, err := e.file.Write(append(payload, '\n')); err != nil {
+		return fmt.Errorf("failed to write spans: %w", err)
+	}
+	return nil
+}
+
+// Close closes the file.
+func (e *FileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
@@ -0,0 +1,178 @@
+package telemetry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/audit"
+)
+
+// Transport wraps base so every request gets a client span under the span
+// in its context and, inside a scan, an entry in the scan's audit log.
+// Nothing is added to the outgoing request: trace context is not
+// propagated to the sites deeper investigates.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	_, span := Start(req.Context(), "HTTP "+req.Method, WithKind(KindClient), WithAttributes(
+		String("http.request.method", req.Method),
+		String("server.address", req.URL.Hostname()),
+		String("url.full", redactURL(req.URL)),
+	))
+	defer span.End()
+	contact := audit.Entry{
+		Time:      time.Now(),
+		Kind:      audit.KindHTTP,
+		Host:      req.URL.Hostname(),
+		Method:    req.Method,
+		URL:       audit.RedactURL(req.URL),
+		BytesSent: max(req.ContentLength, 0),
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		contact.Status, contact.Error = audit.Outcome(err)
+		audit.Record(req.Context(), contact)
+		return nil, err
+	}
+	span.SetAttributes(Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= 500 {
+		span.RecordError(fmt.Errorf("server answered %s", resp.Status))
+	}
+	contact.Status = audit.HTTPStatus(resp.StatusCode)
+	if audit.LogFrom(req.Context()) != nil {
+		resp.Body = &auditedBody{ReadCloser: resp.Body, ctx: req.Context(), contact: contact}
+	}
+	return resp, nil
+}
+
+// auditedBody counts the bytes read from a response and records the
+// request in the audit log once the body is drained or closed, whichever
+// comes first.
+type auditedBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	contact audit.Entry
+	once    sync.Once
+}
+
+func (b *auditedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.contact.BytesReceived += int64(n)
+	if err != nil {
+		b.record()
+	}
+	return n, err
+}
+
+func (b *auditedBody) Close() error {
+	b.record()
+	return b.ReadCloser.Close()
+}
+
+func (b *auditedBody) record() {
+	b.once.Do(func() { audit.Record(b.ctx, b.contact) })
+}
+
+// Get is http.Get bound to ctx, with the request traced like Transport's.
+func Get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return defaultClient.Do(req)
+}
+
+var defaultClient = &http.Client{Transport: Transport(http.DefaultTransport)}
+
+// redactURL drops the parts of u that may hold credentials: user info and
+// the query string, where some APIs take their keys.
+func redactURL(u *url.URL) string {
+	redacted := *u
+	redacted.User = nil
+	redacted.RawQuery = ""
+	redacted.Fragment = ""
+	return redacted.String()
+}
+
+// Resolver wraps a net.Resolver so each lookup gets a client span and,
+// inside a scan, an entry in the scan's audit log.
+type Resolver struct {
+	resolver *net.Resolver
+}
+
+// NewResolver returns a traced resolver over r, or over
+// net.DefaultResolver when r is nil.
+func NewResolver(r *net.Resolver) *Resolver {
+	if r == nil {
+		r = net.DefaultResolver
+	}
+	return &Resolver{resolver: r}
+}
+
+// LookupIPAddr looks up host's addresses.
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	ctx, l := startLookup(ctx, "A/AAAA", host)
+	addrs, err := r.resolver.LookupIPAddr(ctx, host)
+	l.end(len(addrs), err)
+	return addrs, err
+}
+
+// LookupTXT looks up name's TXT records.
+func (r *Resolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	ctx, l := startLookup(ctx, "TXT", name)
+	records, err := r.resolver.LookupTXT(ctx, name)
+	l.end(len(records), err)
+	return records, err
+}
+
+// LookupAddr looks up the names mapping to addr.
+func (r *Resolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	ctx, l := startLookup(ctx, "PTR", addr)
+	names, err := r.resolver.LookupAddr(ctx, addr)
+	l.end(len(names), err)
+	return names, err
+}
+
+// lookup is one traced and audited DNS lookup.
+type lookup struct {
+	ctx     context.Context
+	span    *Span
+	contact audit.Entry
+}
+
+func startLookup(ctx context.Context, recordType, name string) (context.Context, *lookup) {
+	ctx, span := Start(ctx, "DNS "+recordType, WithKind(KindClient), WithAttributes(
+		String("dns.question.type", recordType),
+		String("dns.question.name", name),
+	))
+	return ctx, &lookup{
+		ctx:     ctx,
+		span:    span,
+		contact: audit.Entry{Time: time.Now(), Kind: audit.KindDNS, Host: name, Method: recordType},
+	}
+}
+
+func (l *lookup) end(answers int, err error) {
+	l.span.SetAttributes(Int("dns.answer.count", answers))
+	l.span.RecordError(err)
+	l.span.End()
+	l.contact.Status, l.contact.Error = audit.Outcome(err)
+	audit.Record(l.ctx, l.contact)
+}
@@ -0,0 +1,193 @@
+// Package telemetry records OpenTelemetry spans for scans, the traces they
+// process, plugin tasks and the network calls plugins make, and exports them
+// over OTLP/HTTP or to a local file.
+//
+// "Trace" is already taken in deeper for the things a scan discovers, so
+// this package talks about spans; an OpenTelemetry trace is just the tree
+// of spans below one scan.
+//
+// Until a Provider is installed with SetProvider, Start returns a nil *Span
+// and every Span method is a no-op, so instrumented code costs next to
+// nothing when tracing is off.
+package telemetry
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kind is a span's OpenTelemetry span kind.
+type Kind int
+
+const (
+	// KindInternal marks work inside deeper, such as a scan or a plugin task.
+	KindInternal Kind = 1
+	// KindClient marks an outbound call to another service.
+	KindClient Kind = 3
+)
+
+// Attribute is a key/value pair attached to a span. Value is a string,
+// int64, float64 or bool.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// String returns a string attribute.
+func String(key, value string) Attribute { return Attribute{Key: key, Value: value} }
+
+// Int returns an integer attribute.
+func Int(key string, value int) Attribute { return Attribute{Key: key, Value: int64(value)} }
+
+// Int64 returns an integer attribute.
+func Int64(key string, value int64) Attribute { return Attribute{Key: key, Value: value} }
+
+// Float64 returns a floating point attribute.
+func Float64(key string, value float64) Attribute { return Attribute{Key: key, Value: value} }
+
+// Bool returns a boolean attribute.
+func Bool(key string, value bool) Attribute { return Attribute{Key: key, Value: value} }
+
+// Milliseconds returns a duration attribute in fractional milliseconds.
+func Milliseconds(key string, value time.Duration) Attribute {
+	return Float64(key, float64(value)/float64(time.Millisecond))
+}
+
+// Span is one timed operation. A nil *Span is valid and does nothing.
+type Span struct {
+	provider *Provider
+	traceID  [16]byte
+	spanID   [8]byte
+	parentID [8]byte
+	name     string
+	kind     Kind
+	start    time.Time
+
+	mu         sync.Mutex
+	end        time.Time
+	attributes []Attribute
+	failed     bool
+	message    string
+	ended      bool
+}
+
+// SpanOption configures a span started by Start.
+type SpanOption func(*Span)
+
+// WithKind sets the span kind; spans are KindInternal by default.
+func WithKind(kind Kind) SpanOption {
+	return func(s *Span) { s.kind = kind }
+}
+
+// WithStartTime backdates the span, for work that began before the code
+// that records it ran, such as a task waiting in a queue.
+func WithStartTime(start time.Time) SpanOption {
+	return func(s *Span) { s.start = start }
+}
+
+// WithAttributes sets attributes on the new span.
+func WithAttributes(attributes ...Attribute) SpanOption {
+	return func(s *Span) { s.attributes = append(s.attributes, attributes...) }
+}
+
+var current atomic.Pointer[Provider]
+
+// SetProvider installs the provider spans are exported through; nil turns
+// tracing off.
+func SetProvider(p *Provider) {
+	current.Store(p)
+}
+
+// Enabled reports whether spans are being recorded.
+func Enabled() bool {
+	return current.Load() != nil
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns ctx carrying span as the parent of spans started
+// from it.
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// SpanFromContext returns the span ctx carries, or nil.
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// Start begins a span named name as a child of the span in ctx, or as the
+// root of a new trace when ctx has none. The returned context carries the
+// new span. End must be called on it.
+func Start(ctx context.Context, name string, opts ...SpanOption) (context.Context, *Span) {
+	p := current.Load()
+	if p == nil {
+		return ctx, nil
+	}
+
+	span := &Span{
+		provider: p,
+		name:     name,
+		kind:     KindInternal,
+		start:    time.Now(),
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		binary.BigEndian.PutUint64(span.traceID[:8], rand.Uint64())
+		binary.BigEndian.PutUint64(span.traceID[8:], rand.Uint64())
+	}
+	binary.BigEndian.PutUint64(span.spanID[:], rand.Uint64()|1)
+	for _, opt := range opts {
+		opt(span)
+	}
+	return ContextWithSpan(ctx, span), span
+}
+
+// SetAttributes adds attributes to the span.
+func (s *Span) SetAttributes(attributes ...Attribute) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, attributes...)
+}
+
+// RecordError marks the span failed with err's message. A nil err is
+// ignored.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = true
+	s.message = err.Error()
+}
+
+// End finishes the span and hands it to the provider for export. Calls
+// after the first are ignored.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+	s.provider.enqueue(s)
+}
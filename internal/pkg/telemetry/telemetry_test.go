@@ -0,0 +1,258 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/audit"
+)
+
+// recordingExporter keeps every payload it is given.
+type recordingExporter struct {
+	mu       sync.Mutex
+	payloads [][]byte
+}
+
+func (e *recordingExporter) Export(_ context.Context, payload []byte) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.payloads = append(e.payloads, payload)
+	return nil
+}
+
+func (e *recordingExporter) Close() error { return nil }
+
+func (e *recordingExporter) spans(t *testing.T) []otlpSpan {
+	t.Helper()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var spans []otlpSpan
+	for _, payload := range e.payloads {
+		var req otlpRequest
+		require.NoError(t, json.Unmarshal(payload, &req))
+		for _, rs := range req.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+	return spans
+}
+
+// install sets a provider exporting to a fresh recordingExporter and
+// returns a func that flushes it.
+func install(t *testing.T) (*recordingExporter, func()) {
+	t.Helper()
+	exporter := &recordingExporter{}
+	provider := NewProvider("deeper-test", exporter)
+	SetProvider(provider)
+	t.Cleanup(func() { SetProvider(nil) })
+	return exporter, func() {
+		SetProvider(nil)
+		require.NoError(t, provider.Shutdown(context.Background()))
+	}
+}
+
+func attribute(span otlpSpan, key string) *otlpValue {
+	for _, a := range span.Attributes {
+		if a.Key == key {
+			return &a.Value
+		}
+	}
+	return nil
+}
+
+func TestStart_DisabledIsNoop(t *testing.T) {
+	SetProvider(nil)
+	ctx, span := Start(context.Background(), "scan")
+
+	assert.Nil(t, span)
+	assert.False(t, Enabled())
+	assert.Nil(t, SpanFromContext(ctx))
+	span.SetAttributes(String("k", "v"))
+	span.RecordError(errors.New("boom"))
+	span.End()
+}
+
+func TestStart_ChildSharesTrace(t *testing.T) {
+	exporter, flush := install(t)
+
+	ctx, root := Start(context.Background(), "scan", WithAttributes(String("deeper.scan.id", "s1")))
+	submitted := time.Now().Add(-time.Second)
+	_, child := Start(ctx, "plugin X", WithStartTime(submitted), WithKind(KindClient))
+	child.SetAttributes(Int("deeper.plugin.discoveries", 3), Bool("ok", true), Float64("ratio", 0.5))
+	child.RecordError(nil)
+	child.RecordError(errors.New("boom"))
+	child.End()
+	child.End()
+	root.End()
+	flush()
+
+	spans := exporter.spans(t)
+	require.Len(t, spans, 2)
+	byName := map[string]otlpSpan{}
+	for _, s := range spans {
+		byName[s.Name] = s
+	}
+	rootSpan, childSpan := byName["scan"], byName["plugin X"]
+
+	assert.Len(t, rootSpan.TraceID, 32)
+	assert.Len(t, rootSpan.SpanID, 16)
+	assert.Empty(t, rootSpan.ParentSpanID)
+	assert.Equal(t, rootSpan.TraceID, childSpan.TraceID)
+	assert.Equal(t, rootSpan.SpanID, childSpan.ParentSpanID)
+	assert.Equal(t, KindInternal, rootSpan.Kind)
+	assert.Equal(t, KindClient, childSpan.Kind)
+	assert.Equal(t, "s1", *attribute(rootSpan, "deeper.scan.id").StringValue)
+	assert.Equal(t, "3", *attribute(childSpan, "deeper.plugin.discoveries").IntValue)
+	assert.True(t, *attribute(childSpan, "ok").BoolValue)
+	assert.InDelta(t, 0.5, *attribute(childSpan, "ratio").DoubleValue, 1e-9)
+	assert.Equal(t, otlpStatus{Code: statusError, Message: "boom"}, childSpan.Status)
+	assert.Equal(t, otlpStatus{}, rootSpan.Status)
+	assert.Less(t, childSpan.StartTimeUnixNano, rootSpan.StartTimeUnixNano, "child is backdated to submission")
+}
+
+func TestOTLPExporter_PostsJSON(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		path   string
+		header http.Header
+		body   []byte
+	)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		path, header = r.URL.Path, r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer collector.Close()
+
+	exporter, err := NewOTLPExporter(collector.URL, map[string]string{"Authorization": "Bearer t"})
+	require.NoError(t, err)
+	require.NoError(t, exporter.Export(context.Background(), []byte(`{"resourceSpans":[]}`)))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "/v1/traces", path)
+	assert.Equal(t, "application/json", header.Get("Content-Type"))
+	assert.Equal(t, "Bearer t", header.Get("Authorization"))
+	assert.JSONEq(t, `{"resourceSpans":[]}`, string(body))
+
+	_, err = NewOTLPExporter("localhost:4318", nil)
+	assert.Error(t, err)
+}
+
+func TestFileExporter_AppendsLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := NewFileExporter(path)
+	require.NoError(t, err)
+	SetProvider(NewProvider("deeper-test", exporter))
+	t.Cleanup(func() { SetProvider(nil) })
+	provider := current.Load()
+
+	_, span := Start(context.Background(), "scan")
+	span.End()
+	SetProvider(nil)
+	require.NoError(t, provider.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 1)
+	assert.Contains(t, lines[0], `"name":"scan"`)
+	assert.Contains(t, lines[0], `{"key":"service.name","value":{"stringValue":"deeper-test"}}`)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func TestTransport_RedactsURL(t *testing.T) {
+	exporter, flush := install(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Traceparent"), "trace context must not reach targets")
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	ctx, root := Start(context.Background(), "plugin X")
+	resp, err := Get(ctx, server.URL+"/users/alice?api_key=secret#frag")
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+	root.End()
+	flush()
+
+	spans := exporter.spans(t)
+	require.Len(t, spans, 2)
+	var call otlpSpan
+	for _, s := range spans {
+		if s.Name == "HTTP GET" {
+			call = s
+		}
+	}
+	assert.Equal(t, KindClient, call.Kind)
+	assert.Equal(t, server.URL+"/users/alice", *attribute(call, "url.full").StringValue)
+	assert.Equal(t, "502", *attribute(call, "http.response.status_code").IntValue)
+	assert.Equal(t, statusError, call.Status.Code)
+}
+
+// auditStore keeps the audit entries it is given, unchained.
+type auditStore struct {
+	mu      sync.Mutex
+	entries []audit.Entry
+}
+
+func (s *auditStore) AppendAuditEntry(e *audit.Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, *e)
+	return nil
+}
+
+func TestTransport_RecordsAuditEntry(t *testing.T) {
+	SetProvider(nil)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	store := &auditStore{}
+	ctx := audit.WithPlugin(audit.WithLog(context.Background(), audit.NewLog(3, store)), "GravatarPlugin")
+	resp, err := Get(ctx, server.URL+"/avatar?api_key=secret")
+	require.NoError(t, err)
+	_, err = io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.NoError(t, resp.Body.Close())
+
+	_, err = NewResolver(nil).LookupIPAddr(ctx, "localhost")
+	require.NoError(t, err)
+
+	require.Len(t, store.entries, 2, "the body is recorded once, on EOF or close")
+	call := store.entries[0]
+	assert.Equal(t, int64(3), call.ScanID)
+	assert.Equal(t, "GravatarPlugin", call.Plugin)
+	assert.Equal(t, audit.KindHTTP, call.Kind)
+	assert.Equal(t, "127.0.0.1", call.Host)
+	assert.Equal(t, server.URL+"/avatar?api_key=REDACTED", call.URL)
+	assert.Equal(t, "200", call.Status)
+	assert.Equal(t, int64(5), call.BytesReceived)
+
+	lookup := store.entries[1]
+	assert.Equal(t, audit.KindDNS, lookup.Kind)
+	assert.Equal(t, "A/AAAA", lookup.Method)
+	assert.Equal(t, "localhost", lookup.Host)
+	assert.Equal(t, "ok", lookup.Status)
+}
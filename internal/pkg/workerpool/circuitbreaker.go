@@ -15,6 +15,20 @@ const (
 	StateHalfOpen
 )
 
+// String returns the state's name as used in metric labels
+func (s CircuitBreakerState) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return "unknown"
+	}
+}
+
 // CircuitBreaker implements the circuit breaker pattern
 type CircuitBreaker struct {
 	config CircuitBreakerConfig
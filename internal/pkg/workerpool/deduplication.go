@@ -3,8 +3,6 @@ package workerpool
 import (
 	"container/list"
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"fmt"
 	"sync"
 	"sync/atomic"
@@ -173,9 +171,7 @@ func (dc *DeduplicationCache) MarkProcessed(ctx context.Context, task *Task) {
 // instead; plain values (e.g. bare strings in tests) keep the previous
 // stringify behavior, which is already stable for non-pointer content.
 func (dc *DeduplicationCache) generateTaskID(task *Task) string {
-	content := dc.payloadIdentity(task.Payload)
-	hash := sha256.Sum256([]byte(content))
-	return hex.EncodeToString(hash[:8])
+	return database.DeduplicationMarker(dc.payloadIdentity(task.Payload)).Value
 }
 
 // taskIdentifier is satisfied by *tasks.TraceProcessingTask. Defined
@@ -197,7 +193,7 @@ func (dc *DeduplicationCache) checkPersistentCache(ctx context.Context, task *Ta
 	// Create a trace for cache lookup
 	trace := entities.Trace{
 		Value: taskID,
-		Type:  entities.TraceType("deduplication"),
+		Type:  database.DeduplicationPlugin,
 	}
 
 	// Check if we have cached results for this task. The marker stored by
@@ -206,7 +202,7 @@ func (dc *DeduplicationCache) checkPersistentCache(ctx context.Context, task *Ta
 	// database.Cache.Get only returns nil on miss (json.Unmarshal turns a
 	// stored "[]" into a non-nil, zero-length slice). Checking non-nil
 	// distinguishes the two; len() cannot.
-	results, err := dc.dbCache.Get(trace, "deduplication")
+	results, err := dc.dbCache.Get(trace, database.DeduplicationPlugin)
 	if err != nil {
 		return false, fmt.Errorf("failed to get from persistent cache: %w", err)
 	}
@@ -219,15 +215,15 @@ func (dc *DeduplicationCache) storeInPersistentCache(ctx context.Context, task *
 	// Create a trace for cache storage
 	trace := entities.Trace{
 		Value: taskID,
-		Type:  entities.TraceType("deduplication"),
+		Type:  database.DeduplicationPlugin,
 	}
 
 	// Store empty result to mark as processed
 	results := []entities.Trace{}
-	return dc.dbCache.Set(trace, "deduplication", results, dc.config.CacheTTL)
+	return dc.dbCache.Set(trace, database.DeduplicationPlugin, results, dc.config.CacheTTL)
 }
 
-// GetMetrics returns current deduplication metrics
+// GetMetrics returns a snapshot of the current deduplication metrics
 func (dc *DeduplicationCache) GetMetrics() *DeduplicationMetrics {
 	dc.mutex.RLock()
 	defer dc.mutex.RUnlock()
@@ -235,21 +231,25 @@ func (dc *DeduplicationCache) GetMetrics() *DeduplicationMetrics {
 	// MemoryHits/CacheHits/CacheMisses are updated via atomic.AddInt64 from
 	// concurrent callers outside this mutex, so they must be read atomically
 	// too, or the mutex here does nothing to synchronize with those writes.
-	memoryHits := atomic.LoadInt64(&dc.metrics.MemoryHits)
-	cacheHits := atomic.LoadInt64(&dc.metrics.CacheHits)
-	cacheMisses := atomic.LoadInt64(&dc.metrics.CacheMisses)
+	// The result is a copy so callers can read it while lookups continue.
+	snapshot := &DeduplicationMetrics{
+		MemoryHits:  atomic.LoadInt64(&dc.metrics.MemoryHits),
+		CacheHits:   atomic.LoadInt64(&dc.metrics.CacheHits),
+		CacheMisses: atomic.LoadInt64(&dc.metrics.CacheMisses),
+		CacheSize:   dc.metrics.CacheSize,
+	}
 
-	totalRequests := memoryHits + cacheHits + cacheMisses
+	totalRequests := snapshot.MemoryHits + snapshot.CacheHits + snapshot.CacheMisses
 	if totalRequests > 0 {
-		dc.metrics.HitRate = float64(memoryHits+cacheHits) / float64(totalRequests)
+		snapshot.HitRate = float64(snapshot.MemoryHits+snapshot.CacheHits) / float64(totalRequests)
 	}
 
 	// Get memory cache metrics
 	lruMetrics := dc.memoryCache.GetMetrics()
-	dc.metrics.Evictions = lruMetrics.Evictions
-	dc.metrics.MemoryUsage = lruMetrics.Size
+	snapshot.Evictions = atomic.LoadInt64(&lruMetrics.Evictions)
+	snapshot.MemoryUsage = atomic.LoadInt64(&lruMetrics.Size)
 
-	return dc.metrics
+	return snapshot
 }
 
 // cleanupRoutine periodically cleans up expired entries
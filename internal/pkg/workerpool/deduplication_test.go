@@ -10,6 +10,8 @@ import (
 	"github.com/smirnoffmg/deeper/internal/app/deeper/processor/tasks"
 	"github.com/smirnoffmg/deeper/internal/pkg/database"
 	"github.com/smirnoffmg/deeper/internal/pkg/entities"
+	"github.com/smirnoffmg/deeper/internal/pkg/plugins"
+	"github.com/smirnoffmg/deeper/internal/pkg/state"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -401,6 +403,61 @@ func TestDeduplicationCache_MarkProcessed_PersistsAcrossInstances(t *testing.T)
 	}, time.Second, 10*time.Millisecond, "successful task was never persisted")
 }
 
+type namedPlugin string
+
+func (p namedPlugin) Register() error { return nil }
+
+func (p namedPlugin) FollowTrace(trace entities.Trace) ([]entities.Trace, error) { return nil, nil }
+
+func (p namedPlugin) String() string { return string(p) }
+
+func TestDeduplicationCache_ForgetsErasedTraces(t *testing.T) {
+	original := state.ActivePlugins[entities.Username]
+	t.Cleanup(func() { state.ActivePlugins[entities.Username] = original })
+	state.ActivePlugins[entities.Username] = []plugins.DeeperPlugin{namedPlugin("GitHubProfilePlugin")}
+
+	db, err := database.NewDatabase(filepath.Join(t.TempDir(), "test.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	repo := database.NewRepository(db)
+	dbCache := database.NewCache(repo)
+
+	config := &DeduplicationConfig{
+		EnableCache:     true,
+		CacheTTL:        1 * time.Hour,
+		MaxMemorySize:   10,
+		PersistentCache: true,
+	}
+	ctx := context.Background()
+	task := func(value string) *Task {
+		return &Task{Payload: &tasks.TraceProcessingTask{
+			Trace:     entities.Trace{Value: value, Type: entities.Username},
+			PluginKey: "GitHubProfilePlugin",
+		}}
+	}
+	processed := func(value string) bool {
+		isDuplicate, err := NewDeduplicationCache(config, dbCache).IsDuplicate(ctx, task(value))
+		require.NoError(t, err)
+		return isDuplicate
+	}
+
+	dc := NewDeduplicationCache(config, dbCache)
+	for _, value := range []string{"jdoe", "asmith"} {
+		_, err := repo.GetOrCreateTrace(entities.Trace{Value: value, Type: entities.Username})
+		require.NoError(t, err)
+		dc.MarkProcessed(ctx, task(value))
+	}
+	require.Eventually(t, func() bool {
+		return processed("jdoe") && processed("asmith")
+	}, time.Second, 10*time.Millisecond, "successful tasks were never persisted")
+
+	tomb, err := repo.EraseTrace(database.Erasure{Value: "jdoe"}, time.Now(), false)
+	require.NoError(t, err)
+	assert.Equal(t, 1, tomb.CacheEntries)
+	assert.False(t, processed("jdoe"), "an erased trace must be processed afresh")
+	assert.True(t, processed("asmith"))
+}
+
 func TestLRUCache_Clear(t *testing.T) {
 	lru := NewLRUCache(5)
 
@@ -8,6 +8,8 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"golang.org/x/time/rate"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/metrics"
 )
 
 // DomainRateConfig holds rate limiting configuration for a specific domain
@@ -28,6 +30,7 @@ type DomainRateLimiter struct {
 	mux             sync.RWMutex
 	defaultConfig   *DomainRateConfig
 	domainExtractor *DomainExtractor
+	waitTimes       *metrics.Histogram
 }
 
 // BackoffTracker tracks backoff state for a domain
@@ -57,6 +60,7 @@ func NewDomainRateLimiter(defaultConfig *DomainRateConfig) *DomainRateLimiter {
 		backoffTrackers: make(map[string]*BackoffTracker),
 		defaultConfig:   defaultConfig,
 		domainExtractor: NewDomainExtractor(),
+		waitTimes:       metrics.NewHistogram(),
 	}
 
 	// Initialize default limiter
@@ -130,6 +134,8 @@ func (drl *DomainRateLimiter) Allow(domain string) bool {
 func (drl *DomainRateLimiter) Wait(ctx context.Context, domain string) error {
 	config := drl.GetDomainConfig(domain)
 	backoffTracker := drl.getBackoffTracker(domain)
+	start := time.Now()
+	defer func() { drl.waitTimes.ObserveDuration(time.Since(start)) }()
 
 	// Check if we're in backoff period
 	if backoffTracker.isInBackoff() {
@@ -217,6 +223,12 @@ func (drl *DomainRateLimiter) GetMetrics() map[string]DomainRateMetrics {
 	return metrics
 }
 
+// WaitTimes returns how long Wait calls have held tasks back, across all
+// domains: backoff periods plus time spent waiting for rate limit tokens
+func (drl *DomainRateLimiter) WaitTimes() metrics.HistogramSnapshot {
+	return drl.waitTimes.Snapshot()
+}
+
 // getBackoffTracker gets or creates a backoff tracker for a domain
 func (drl *DomainRateLimiter) getBackoffTracker(domain string) *BackoffTracker {
 	drl.mux.RLock()
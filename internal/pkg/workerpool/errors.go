@@ -14,6 +14,10 @@ var (
 	// ErrTaskTimeout is returned when a task processing times out
 	ErrTaskTimeout = errors.New("task processing timeout")
 
+	// ErrRateLimited is returned when a task's domain rate limit could not
+	// be waited out
+	ErrRateLimited = errors.New("rate limit exceeded")
+
 	// ErrQueueFull is returned when the task queue is full
 	ErrQueueFull = errors.New("task queue is full")
 )
@@ -0,0 +1,60 @@
+package workerpool
+
+import (
+	"github.com/smirnoffmg/deeper/internal/pkg/metrics"
+)
+
+// Collect writes the pool's queue, circuit breaker, rate limiter and
+// deduplication metrics. Rate limiter series are not labelled by domain:
+// domains come from the traces under investigation, so they would leak
+// scan targets to anyone who can scrape, and grow without bound.
+func (wp *WorkerPool) Collect(e *metrics.Exposition) {
+	m := wp.GetMetrics()
+
+	e.Gauge("deeper_workerpool_queue_depth", "Tasks waiting for a worker.",
+		metrics.Sample{Value: float64(m.QueueSize)})
+	e.Gauge("deeper_workerpool_queue_capacity", "Tasks the queue holds before submissions fail.",
+		metrics.Sample{Value: float64(m.QueueCapacity)})
+	e.Gauge("deeper_workerpool_active_workers", "Workers currently running a task.",
+		metrics.Sample{Value: float64(m.ActiveWorkers)})
+	e.Counter("deeper_workerpool_tasks_processed_total", "Tasks a worker finished.",
+		metrics.Sample{Value: float64(m.ProcessedTasks)})
+	e.Counter("deeper_workerpool_tasks_failed_total", "Tasks that finished with an error.",
+		metrics.Sample{Value: float64(m.FailedTasks)})
+
+	states := make([]metrics.Sample, 0, len(m.CircuitBreakerStates))
+	for state, count := range m.CircuitBreakerStates {
+		states = append(states, metrics.Sample{Labels: metrics.Labels{"state": state.String()}, Value: float64(count)})
+	}
+	e.Gauge("deeper_circuit_breakers", "Circuit breakers by state.", states...)
+	e.Counter("deeper_circuit_breaker_rejections_total", "Tasks rejected because their circuit breaker was open.",
+		metrics.Sample{Value: float64(m.CircuitBreakerTrips)})
+
+	inBackoff := 0
+	for _, domain := range m.DomainRateMetrics {
+		if domain.IsInBackoff {
+			inBackoff++
+		}
+	}
+	e.Histogram("deeper_domain_limiter_wait_seconds", "Time tasks waited for a domain's rate limit or backoff.",
+		metrics.HistogramSample{HistogramSnapshot: m.DomainWaitTimes})
+	e.Counter("deeper_domain_limiter_rejections_total", "Tasks rejected because a domain's rate limit could not be met.",
+		metrics.Sample{Value: float64(m.RateLimitHits)})
+	e.Gauge("deeper_domain_limiter_backoff_domains", "Domains currently backing off after rate limit failures.",
+		metrics.Sample{Value: float64(inBackoff)})
+
+	if dedup := m.DeduplicationMetrics; dedup != nil {
+		e.Counter("deeper_dedup_lookups_total", "Deduplication lookups by where they were answered.",
+			metrics.Sample{Labels: metrics.Labels{"result": "memory_hit"}, Value: float64(dedup.MemoryHits)},
+			metrics.Sample{Labels: metrics.Labels{"result": "cache_hit"}, Value: float64(dedup.CacheHits)},
+			metrics.Sample{Labels: metrics.Labels{"result": "miss"}, Value: float64(dedup.CacheMisses)})
+		e.Gauge("deeper_dedup_hit_ratio", "Share of deduplication lookups that found the task already done.",
+			metrics.Sample{Value: dedup.HitRate})
+		e.Counter("deeper_dedup_skipped_tasks_total", "Tasks skipped because they were already done.",
+			metrics.Sample{Value: float64(m.DeduplicationHits)})
+		e.Counter("deeper_dedup_evictions_total", "Entries evicted from the in-memory deduplication cache.",
+			metrics.Sample{Value: float64(dedup.Evictions)})
+		e.Gauge("deeper_dedup_memory_entries", "Entries in the in-memory deduplication cache.",
+			metrics.Sample{Value: float64(dedup.MemoryUsage)})
+	}
+}
@@ -9,6 +9,8 @@ import (
 
 	"github.com/rs/zerolog/log"
 	"golang.org/x/time/rate"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/metrics"
 )
 
 // Task represents a unit of work to be processed
@@ -18,12 +20,20 @@ type Task struct {
 	Priority int
 	Created  time.Time
 
+	// RateLimitWait is how long Submit held the task for its domain's rate
+	// limit and backoff before queueing it.
+	RateLimitWait time.Duration
+
 	// ReplyTo, if set, receives this task's result directly instead of the
 	// pool-wide result queue. Callers that submit a batch of tasks and expect
 	// to collect exactly their own results (e.g. Processor.ProcessTrace) must
 	// set this — GetResult()'s shared queue has no per-caller correlation, so
 	// concurrent submitters would otherwise consume each other's results.
 	ReplyTo chan *TaskResult
+
+	// SkipDeduplication runs the task even if the deduplication cache has
+	// seen it done, and leaves the cache untouched when it completes.
+	SkipDeduplication bool
 }
 
 // TaskResult represents the result of processing a task
@@ -32,6 +42,10 @@ type TaskResult struct {
 	Result   interface{}
 	Error    error
 	Duration time.Duration
+
+	// Deduplicated is set when Submit answered the task from the
+	// deduplication cache instead of running it.
+	Deduplicated bool
 }
 
 // WorkerPool manages a pool of workers for concurrent task processing
@@ -89,7 +103,9 @@ type Metrics struct {
 	RateLimitHits        int64
 	DeduplicationHits    int64
 	CircuitBreakerTrips  int64
+	CircuitBreakerStates map[CircuitBreakerState]int
 	DomainRateMetrics    map[string]DomainRateMetrics
+	DomainWaitTimes      metrics.HistogramSnapshot
 	DeduplicationMetrics *DeduplicationMetrics
 }
 
@@ -164,7 +180,7 @@ func (wp *WorkerPool) Submit(ctx context.Context, task *Task) error {
 	}
 
 	// Check deduplication if enabled
-	if wp.config.EnableDeduplication && wp.deduplicationCache != nil {
+	if wp.config.EnableDeduplication && wp.deduplicationCache != nil && !task.SkipDeduplication {
 		isDuplicate, err := wp.deduplicationCache.IsDuplicate(ctx, task)
 		if err != nil {
 			log.Warn().Err(err).Str("taskID", task.ID).Msg("Failed to check deduplication")
@@ -177,7 +193,7 @@ func (wp *WorkerPool) Submit(ctx context.Context, task *Task) error {
 			// block forever -- the same class of hang as worker starvation,
 			// just triggered by a dedup hit instead.
 			if task.ReplyTo != nil {
-				task.ReplyTo <- &TaskResult{TaskID: task.ID}
+				task.ReplyTo <- &TaskResult{TaskID: task.ID, Deduplicated: true}
 			}
 			return nil
 		}
@@ -187,15 +203,17 @@ func (wp *WorkerPool) Submit(ctx context.Context, task *Task) error {
 	if cb := wp.getCircuitBreaker(task.ID); cb != nil && cb.IsOpen() {
 		log.Warn().Str("taskID", task.ID).Msg("Circuit breaker is open, rejecting task")
 		atomic.AddInt64(&wp.metrics.CircuitBreakerTrips, 1)
-		return fmt.Errorf("circuit breaker is open for task %s", task.ID)
+		return fmt.Errorf("%w for task %s", ErrCircuitBreakerOpen, task.ID)
 	}
 
 	// Apply domain-specific rate limiting with backoff
+	waitStart := time.Now()
 	domain, err := wp.domainRateLimiter.ExtractDomainAndWait(ctx, task)
+	task.RateLimitWait = time.Since(waitStart)
 	if err != nil {
 		log.Debug().Str("taskID", task.ID).Str("domain", domain).Msg("Rate limit exceeded")
 		atomic.AddInt64(&wp.metrics.RateLimitHits, 1)
-		return fmt.Errorf("rate limit exceeded for domain %s: %w", domain, err)
+		return fmt.Errorf("%w for domain %s: %w", ErrRateLimited, domain, err)
 	}
 
 	// Set creation time
@@ -222,19 +240,41 @@ func (wp *WorkerPool) GetResult(ctx context.Context) (*TaskResult, error) {
 	}
 }
 
-// GetMetrics returns current worker pool metrics
+// GetMetrics returns a snapshot of the current worker pool metrics. It is a
+// copy, so callers can read it while workers keep updating the counters.
 func (wp *WorkerPool) GetMetrics() *Metrics {
-	wp.metrics.ActiveWorkers = atomic.LoadInt64(&wp.activeWorkers)
-	wp.metrics.QueueSize = len(wp.taskQueue)
-	wp.metrics.QueueCapacity = cap(wp.taskQueue)
-	wp.metrics.DomainRateMetrics = wp.domainRateLimiter.GetMetrics()
+	snapshot := &Metrics{
+		ActiveWorkers:        atomic.LoadInt64(&wp.activeWorkers),
+		ProcessedTasks:       atomic.LoadInt64(&wp.processedTasks),
+		FailedTasks:          atomic.LoadInt64(&wp.failedTasks),
+		QueueSize:            len(wp.taskQueue),
+		QueueCapacity:        cap(wp.taskQueue),
+		RateLimitHits:        atomic.LoadInt64(&wp.metrics.RateLimitHits),
+		DeduplicationHits:    atomic.LoadInt64(&wp.metrics.DeduplicationHits),
+		CircuitBreakerTrips:  atomic.LoadInt64(&wp.metrics.CircuitBreakerTrips),
+		CircuitBreakerStates: wp.circuitBreakerStates(),
+		DomainRateMetrics:    wp.domainRateLimiter.GetMetrics(),
+		DomainWaitTimes:      wp.domainRateLimiter.WaitTimes(),
+	}
 
 	// Get deduplication metrics if available
 	if wp.deduplicationCache != nil {
-		wp.metrics.DeduplicationMetrics = wp.deduplicationCache.GetMetrics()
+		snapshot.DeduplicationMetrics = wp.deduplicationCache.GetMetrics()
 	}
 
-	return wp.metrics
+	return snapshot
+}
+
+// circuitBreakerStates counts the pool's circuit breakers by state
+func (wp *WorkerPool) circuitBreakerStates() map[CircuitBreakerState]int {
+	wp.circuitMux.RLock()
+	defer wp.circuitMux.RUnlock()
+
+	states := map[CircuitBreakerState]int{StateClosed: 0, StateOpen: 0, StateHalfOpen: 0}
+	for _, cb := range wp.circuitBreakers {
+		states[cb.GetState()]++
+	}
+	return states
 }
 
 // ConfigureDomainRateLimit configures rate limiting for a specific domain
@@ -308,7 +348,7 @@ func (wp *WorkerPool) recordTaskResult(task *Task, result *TaskResult) {
 	atomic.AddInt64(&wp.processedTasks, 1)
 	if result.Error != nil {
 		atomic.AddInt64(&wp.failedTasks, 1)
-	} else if wp.config.EnableDeduplication && wp.deduplicationCache != nil {
+	} else if wp.config.EnableDeduplication && wp.deduplicationCache != nil && !task.SkipDeduplication {
 		wp.deduplicationCache.MarkProcessed(wp.ctx, task)
 	}
 
@@ -11,6 +11,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/time/rate"
+
+	"github.com/smirnoffmg/deeper/internal/pkg/metrics"
 )
 
 func TestNewWorkerPool(t *testing.T) {
@@ -167,13 +169,18 @@ func TestWorkerPool_Deduplication_RepliesInsteadOfHanging(t *testing.T) {
 	require.NoError(t, wp.Submit(ctx, task1))
 	require.NoError(t, wp.Submit(ctx, task2))
 
+	deduplicated := 0
 	for i := 0; i < 2; i++ {
 		select {
-		case <-replyTo:
+		case result := <-replyTo:
+			if result.Deduplicated {
+				deduplicated++
+			}
 		case <-time.After(2 * time.Second):
 			t.Fatal("deduplicated task's ReplyTo never received a result — a waiting caller would hang forever")
 		}
 	}
+	assert.Equal(t, 1, deduplicated)
 }
 
 func TestWorkerPool_RateLimiting(t *testing.T) {
@@ -249,7 +256,7 @@ func TestWorkerPool_CircuitBreaker(t *testing.T) {
 		Payload: "another-failing-payload",
 	}
 	err := wp.Submit(ctx, task)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrCircuitBreakerOpen)
 	assert.Contains(t, err.Error(), "circuit breaker is open")
 
 	// Wait a bit for metrics to update
@@ -480,3 +487,44 @@ func TestWorkerPool_ReplyTo_SlowTaskStillDelivers(t *testing.T) {
 		t.Fatal("result was never delivered to ReplyTo — regressed to the dropped-result bug")
 	}
 }
+
+func TestWorkerPool_Collect(t *testing.T) {
+	config := &Config{
+		MaxWorkers:       1,
+		QueueSize:        10,
+		DefaultRateLimit: rate.Limit(100),
+		DefaultBurst:     10,
+		TaskTimeout:      1 * time.Second,
+		CircuitBreakerConfig: CircuitBreakerConfig{
+			FailureThreshold: 1,
+			RecoveryTimeout:  time.Minute,
+			HalfOpenMaxCalls: 1,
+			WindowSize:       time.Minute,
+		},
+	}
+
+	wp := NewWorkerPool(config)
+	defer func() { _ = wp.Shutdown(5 * time.Second) }()
+
+	ctx := context.Background()
+	require.NoError(t, wp.Submit(ctx, &Task{ID: "ok-task", Payload: "ok"}))
+	require.NoError(t, wp.Submit(ctx, &Task{ID: "failing-task", Payload: "fail"}))
+	require.Eventually(t, func() bool { return wp.GetMetrics().ProcessedTasks == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	snapshot := wp.GetMetrics()
+	assert.Equal(t, int64(1), snapshot.FailedTasks)
+	assert.Equal(t, 1, snapshot.CircuitBreakerStates[StateOpen])
+	assert.Equal(t, 1, snapshot.CircuitBreakerStates[StateClosed])
+	assert.Equal(t, uint64(2), snapshot.DomainWaitTimes.Count)
+
+	var e metrics.Exposition
+	wp.Collect(&e)
+	page := string(e.Bytes())
+	assert.Contains(t, page, "deeper_workerpool_queue_capacity 10\n")
+	assert.Contains(t, page, "deeper_workerpool_tasks_processed_total 2\n")
+	assert.Contains(t, page, "deeper_workerpool_tasks_failed_total 1\n")
+	assert.Contains(t, page, `deeper_circuit_breakers{state="open"} 1`+"\n")
+	assert.Contains(t, page, `deeper_circuit_breakers{state="half_open"} 0`+"\n")
+	assert.Contains(t, page, `deeper_domain_limiter_wait_seconds_bucket{le="+Inf"} 2`+"\n")
+	assert.NotContains(t, page, "deeper_dedup_", "no deduplication cache is set")
+}